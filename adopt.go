@@ -0,0 +1,104 @@
+package glambda
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// UnmanagedFunctionError is returned when a deploy would update a function
+// that glambda didn't create, such as one owned by CloudFormation or
+// Terraform. See [WithAdopt].
+type UnmanagedFunctionError struct {
+	Name string
+}
+
+func (e *UnmanagedFunctionError) Error() string {
+	return fmt.Sprintf("lambda function %s exists but wasn't deployed by glambda, it may be managed by another tool; pass --adopt to deploy over it anyway", e.Name)
+}
+
+// OwnedResourceError is returned when a deploy would change a function or
+// role that another tool manages, such as a CloudFormation stack. Changes
+// made behind that tool's back are reverted, or cause drift, the next time it
+// runs, so they belong in the tool instead.
+type OwnedResourceError struct {
+	// Kind is "function" or "role".
+	Kind  string
+	Name  string
+	Owner string
+	// Override is the flag that makes the change anyway.
+	Override string
+}
+
+func (e *OwnedResourceError) Error() string {
+	return fmt.Sprintf("%s %s is managed by %s, make the change there instead, or pass %s to override", e.Kind, e.Name, e.Owner, e.Override)
+}
+
+// terraformTagKeys are the tags conventionally used to mark resources as
+// managed by Terraform, which doesn't tag resources itself.
+var terraformTagKeys = []string{"managedby", "managed-by", "managed_by", "terraform"}
+
+// ResourceOwner describes the infrastructure as code tool that manages a
+// resource with the given tags, such as the CloudFormation stack it belongs
+// to. It returns "" if the tags don't name an owner.
+func ResourceOwner(tags map[string]string) string {
+	if stack := tags["aws:cloudformation:stack-name"]; stack != "" {
+		return "CloudFormation stack " + stack
+	}
+	if stack := tags["aws:cloudformation:stack-id"]; stack != "" {
+		return "CloudFormation stack " + stack
+	}
+	for k, v := range tags {
+		for _, key := range terraformTagKeys {
+			if !strings.EqualFold(k, key) {
+				continue
+			}
+			if strings.EqualFold(v, "terraform") || (key == "terraform" && strings.EqualFold(v, "true")) {
+				return fmt.Sprintf("Terraform (tagged %s=%s)", k, v)
+			}
+		}
+	}
+	return ""
+}
+
+func roleTags(tags []iTypes.Tag) map[string]string {
+	m := map[string]string{}
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m
+}
+
+// WithAdopt is a deploy option that allows updating an existing function
+// that glambda didn't create. The function is tagged with [ManagedTagKey], so
+// later deploys don't need the option, unless the function or its role are
+// managed by another tool (see [ResourceOwner]), which the option also
+// overrides.
+func WithAdopt() DeployOptions {
+	return func(l *Lambda) error {
+		l.adopt = true
+		return nil
+	}
+}
+
+// IsManagedFunction reports whether a function was deployed by glambda. Along
+// with [ManagedTagKey], functions deployed by older versions of glambda are
+// recognised by their module tags or their default execution role.
+func IsManagedFunction(fn *lambda.GetFunctionOutput) bool {
+	if fn == nil {
+		return false
+	}
+	for k := range fn.Tags {
+		if k == ManagedTagKey || strings.HasPrefix(k, ModuleTagPrefix) {
+			return true
+		}
+	}
+	if fn.Configuration == nil {
+		return false
+	}
+	role := aws.ToString(fn.Configuration.Role)
+	return strings.HasPrefix(role[strings.LastIndex(role, "/")+1:], legacyRolePrefix)
+}
@@ -0,0 +1,131 @@
+package glambda_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func foreignFunction(recorder *glambdatest.Recorder, tags map[string]string) glambdatest.DummyLambdaClient {
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String("owned"),
+			Role:         aws.String("arn:aws:iam::123456789012:role/hand-made"),
+		},
+		Tags: tags,
+	})
+	return glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, ConsistantAfterXRetries: new(int)}
+}
+
+func TestDeploy_RefusesToUpdateFunctionNotDeployedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	err := glambda.Deploy("owned", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(foreignFunction(recorder, map[string]string{"team": "payments"})),
+	)
+	var unmanaged *glambda.UnmanagedFunctionError
+	if !errors.As(err, &unmanaged) || unmanaged.Name != "owned" {
+		t.Fatalf("expected UnmanagedFunctionError, got %v", err)
+	}
+	if calls := recorder.Calls("UpdateFunctionCode"); len(calls) != 0 {
+		t.Errorf("expected no code update, got %d", len(calls))
+	}
+}
+
+func TestDeploy_AdoptsFunctionNotDeployedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	err := glambda.Deploy("owned", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(foreignFunction(recorder, map[string]string{"team": "payments"})),
+		glambda.WithAdopt(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := recorder.Calls("TagResource")
+	if len(tag) != 1 {
+		t.Fatalf("expected 1 TagResource call, got %d", len(tag))
+	}
+	if got := tag[0].Input.(*lambda.TagResourceInput).Tags[glambda.ManagedTagKey]; got != "true" {
+		t.Errorf("expected adopted function to be tagged as managed, got %q", got)
+	}
+}
+
+func TestIsManagedFunction_RecognisesFunctionsFromOlderVersions(t *testing.T) {
+	t.Parallel()
+	cases := map[string]*lambda.GetFunctionOutput{
+		"managed tag": {Tags: map[string]string{glambda.ManagedTagKey: "true"}},
+		"module tags": {Tags: map[string]string{glambda.ModuleTagPrefix + "0": "github.com/aws/aws-lambda-go@v1.47.0"}},
+		"legacy role": {Configuration: &types.FunctionConfiguration{
+			Role: aws.String("arn:aws:iam::123456789012:role/glambda_exec_role_fn"),
+		}},
+	}
+	for name, fn := range cases {
+		if !glambda.IsManagedFunction(fn) {
+			t.Errorf("%s: expected function to be managed", name)
+		}
+	}
+	if glambda.IsManagedFunction(&lambda.GetFunctionOutput{}) {
+		t.Error("expected untagged function to be unmanaged")
+	}
+}
+
+func TestDeploy_RefusesToUpdateFunctionOwnedByCloudFormation(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	err := glambda.Deploy("owned", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(foreignFunction(recorder, map[string]string{
+			glambda.ManagedTagKey:           "true",
+			"aws:cloudformation:stack-name": "orders-prod",
+		})),
+	)
+	var owned *glambda.OwnedResourceError
+	if !errors.As(err, &owned) || owned.Owner != "CloudFormation stack orders-prod" {
+		t.Fatalf("expected OwnedResourceError naming the stack, got %v", err)
+	}
+	if calls := recorder.Calls("UpdateFunctionCode"); len(calls) != 0 {
+		t.Errorf("expected no code update, got %d", len(calls))
+	}
+}
+
+func TestDeploy_RefusesToUpdateRoleOwnedByTerraform(t *testing.T) {
+	t.Parallel()
+	err := glambda.Deploy("owned", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithIAMClient(glambdatest.DummyIAMClient{
+			RoleExists: true,
+			RoleName:   "shared",
+			RoleTags:   map[string]string{"ManagedBy": "Terraform"},
+		}),
+	)
+	var owned *glambda.OwnedResourceError
+	if !errors.As(err, &owned) || owned.Kind != "role" {
+		t.Fatalf("expected OwnedResourceError for the role, got %v", err)
+	}
+}
+
+func TestResourceOwner_NamesTheOwningTool(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		tags map[string]string
+		want string
+	}{
+		{map[string]string{"aws:cloudformation:stack-id": "arn:aws:cloudformation:us-east-1:123456789012:stack/orders/1"}, "CloudFormation stack arn:aws:cloudformation:us-east-1:123456789012:stack/orders/1"},
+		{map[string]string{"managed_by": "terraform"}, "Terraform (tagged managed_by=terraform)"},
+		{map[string]string{"Terraform": "true"}, "Terraform (tagged Terraform=true)"},
+		{map[string]string{"ManagedBy": "pulumi", glambda.ManagedTagKey: "true"}, ""},
+	}
+	for _, tc := range cases {
+		if got := glambda.ResourceOwner(tc.tags); got != tc.want {
+			t.Errorf("%v: want %q, got %q", tc.tags, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// AliasConfig is an alias that each deploy points at the function's new code,
+// with settings of its own, so that one function can serve, say, a "batch"
+// alias with more memory and an "api" alias with provisioned concurrency.
+//
+// Lambda keeps memory and environment variables on the published version,
+// not the alias, so an alias that overrides either is pointed at a version
+// of its own, published from the same code with its settings. The function's
+// own settings are restored afterwards.
+type AliasConfig struct {
+	Name string `yaml:"name"`
+	// Memory is in MB, and overrides the function's.
+	Memory int `yaml:"memory"`
+	// Environment is merged over the function's environment.
+	Environment map[string]string `yaml:"environment"`
+	// ProvisionedConcurrency keeps that many instances of the alias
+	// initialised. Zero removes any the alias has.
+	ProvisionedConcurrency int `yaml:"provisioned_concurrency"`
+}
+
+// overrides reports whether the alias needs a version of its own.
+func (a AliasConfig) overrides() bool {
+	return a.Memory != 0 || len(a.Environment) > 0
+}
+
+// WithAlias is a deploy option that points an alias at each new version of
+// the function, with the alias's own memory, environment and provisioned
+// concurrency, see [AliasConfig]. The [LiveAlias] is moved by canaries and
+// releases, so it can't be given.
+func WithAlias(a AliasConfig) DeployOptions {
+	return func(l *Lambda) error {
+		if a.Name == "" || a.Name == "$LATEST" {
+			return fmt.Errorf("invalid alias name %q", a.Name)
+		}
+		if a.Name == LiveAlias {
+			return fmt.Errorf("the %s alias is moved by canaries and releases, so it can't have settings of its own", LiveAlias)
+		}
+		if a.Memory != 0 && (a.Memory < 128 || a.Memory > 10240) {
+			return fmt.Errorf("alias %s: memory must be between 128 and 10240 MB, got %d", a.Name, a.Memory)
+		}
+		if a.ProvisionedConcurrency < 0 {
+			return fmt.Errorf("alias %s: provisioned concurrency must not be negative, got %d", a.Name, a.ProvisionedConcurrency)
+		}
+		err := ValidateEnvironment(a.Environment)
+		if err != nil {
+			return fmt.Errorf("alias %s: %w", a.Name, err)
+		}
+		for _, existing := range l.Aliases {
+			if existing.Name == a.Name {
+				return fmt.Errorf("alias %s is given more than once", a.Name)
+			}
+		}
+		l.Aliases = append(l.Aliases, a)
+		return nil
+	}
+}
+
+// checkAliases rejects provisioned concurrency given twice for one alias, by
+// [WithProvisionedConcurrency] and [WithAlias].
+func (l Lambda) checkAliases() error {
+	p := l.ProvisionedConcurrency
+	if p == nil {
+		return nil
+	}
+	for _, a := range l.Aliases {
+		if a.Name == p.Alias {
+			return fmt.Errorf("alias %s has provisioned concurrency from both WithProvisionedConcurrency and WithAlias, give it once", a.Name)
+		}
+	}
+	return nil
+}
+
+// AliasAction is an [Action] that points a function's aliases at a newly
+// published version, publishing a version of their own for aliases that
+// override its configuration, and applies their provisioned concurrency.
+type AliasAction struct {
+	client  LambdaClient
+	Name    string
+	Version string
+	Aliases []AliasConfig
+}
+
+// NewAliasAction is a constructor function that creates a new [AliasAction].
+func NewAliasAction(client LambdaClient, l Lambda, version string) AliasAction {
+	return AliasAction{client: client, Name: l.Name, Version: version, Aliases: l.Aliases}
+}
+
+// Client returns the required client type. In this case [LambdaClient].
+func (a AliasAction) Client() LambdaClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface.
+func (a AliasAction) Do(ctx context.Context) (err error) {
+	client := a.Client()
+	var base *lambda.GetFunctionConfigurationOutput
+	for _, alias := range a.Aliases {
+		version := a.Version
+		if alias.overrides() {
+			if base == nil {
+				base, err = client.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{
+					FunctionName: aws.String(a.Name),
+					Qualifier:    aws.String(a.Version),
+				})
+				if err != nil {
+					return err
+				}
+				// However the aliases fare, the function is left with the
+				// configuration of the version that was deployed.
+				defer func() {
+					err = errors.Join(err, a.configure(ctx, base.MemorySize, environment(base)))
+				}()
+			}
+			version, err = a.publishOverride(ctx, base, alias)
+			if err != nil {
+				return fmt.Errorf("alias %s: %w", alias.Name, err)
+			}
+		}
+		_, err = pointAlias(ctx, client, a.Name, alias.Name, version)
+		if err != nil {
+			return fmt.Errorf("alias %s: %w", alias.Name, err)
+		}
+		err = a.provision(ctx, alias)
+		if err != nil {
+			return fmt.Errorf("alias %s: %w", alias.Name, err)
+		}
+	}
+	return nil
+}
+
+// publishOverride publishes a version with the alias's settings over the
+// deployed version's.
+func (a AliasAction) publishOverride(ctx context.Context, base *lambda.GetFunctionConfigurationOutput, alias AliasConfig) (string, error) {
+	memory := base.MemorySize
+	if alias.Memory != 0 {
+		memory = aws.Int32(int32(alias.Memory))
+	}
+	env := environment(base)
+	maps.Copy(env, alias.Environment)
+	err := a.configure(ctx, memory, env)
+	if err != nil {
+		return "", err
+	}
+	return WaitForConsistency(ctx, a.Client(), a.Name)
+}
+
+// configure sets the function's memory and environment, waiting for the
+// change to be applied.
+func (a AliasAction) configure(ctx context.Context, memory *int32, env map[string]string) error {
+	client := a.Client()
+	err := WaitForUpdate(ctx, client, a.Name)
+	if err != nil {
+		return err
+	}
+	_, err = client.UpdateFunctionConfiguration(ctx, &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(a.Name),
+		MemorySize:   memory,
+		Environment:  &types.Environment{Variables: env},
+	})
+	if err != nil {
+		return err
+	}
+	return WaitForUpdate(ctx, client, a.Name)
+}
+
+// provision applies the alias's provisioned concurrency, removing any it has
+// when none is given.
+func (a AliasAction) provision(ctx context.Context, alias AliasConfig) error {
+	client := a.Client()
+	if alias.ProvisionedConcurrency == 0 {
+		_, err := client.DeleteProvisionedConcurrencyConfig(ctx, &lambda.DeleteProvisionedConcurrencyConfigInput{
+			FunctionName: aws.String(a.Name),
+			Qualifier:    aws.String(alias.Name),
+		})
+		var notFound *types.ProvisionedConcurrencyConfigNotFoundException
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+		return nil
+	}
+	_, err := client.PutProvisionedConcurrencyConfig(ctx, &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    aws.String(a.Name),
+		Qualifier:                       aws.String(alias.Name),
+		ProvisionedConcurrentExecutions: aws.Int32(int32(alias.ProvisionedConcurrency)),
+	})
+	if err != nil {
+		return err
+	}
+	return ConcurrencyAction{client: client, Name: a.Name}.waitForProvisioned(ctx, alias.Name)
+}
+
+// environment is a copy of the function's environment variables.
+func environment(config *lambda.GetFunctionConfigurationOutput) map[string]string {
+	env := map[string]string{}
+	if config.Environment != nil {
+		maps.Copy(env, config.Environment.Variables)
+	}
+	return env
+}
+
+func describeAliases(name string, aliases []AliasConfig) []Change {
+	var changes []Change
+	for _, a := range aliases {
+		details := []string{"pointed at the new version"}
+		if a.overrides() {
+			var settings []string
+			if a.Memory != 0 {
+				settings = append(settings, fmt.Sprintf("memory %d MB", a.Memory))
+			}
+			if len(a.Environment) > 0 {
+				settings = append(settings, "environment "+strings.Join(sortedKeys(a.Environment), ", "))
+			}
+			details = []string{"pointed at a version of its own, with " + strings.Join(settings, " and ")}
+		}
+		if a.ProvisionedConcurrency > 0 {
+			details = append(details, fmt.Sprintf("provisioned concurrency: %d", a.ProvisionedConcurrency))
+		}
+		changes = append(changes, Change{
+			Operation:    "UpdateAlias",
+			ResourceType: "lambda_alias",
+			Resource:     name + ":" + a.Name,
+			Action:       "update",
+			After: map[string]any{
+				"memory":                            a.Memory,
+				"environment":                       sortedKeys(a.Environment),
+				"provisioned_concurrent_executions": a.ProvisionedConcurrency,
+			},
+			Details: details,
+		})
+	}
+	return changes
+}
@@ -0,0 +1,135 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestAliasActionDo_PublishesAVersionForAliasesWithSettingsOfTheirOwn(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunctionConfiguration", &lambda.GetFunctionConfigurationOutput{
+		MemorySize:       aws.Int32(256),
+		Environment:      &types.EnvironmentResponse{Variables: map[string]string{"LOG_LEVEL": "info"}},
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	})
+	recorder.Respond("PublishVersion", &lambda.PublishVersionOutput{Version: aws.String("8")})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	l := glambda.Lambda{Name: "orders", Aliases: []glambda.AliasConfig{
+		{Name: "api", ProvisionedConcurrency: 2},
+		{Name: "batch", Memory: 3008, Environment: map[string]string{"MODE": "batch"}},
+	}}
+	err := glambda.NewAliasAction(client, l, "7").Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	aliases := map[string]string{}
+	for _, c := range recorder.Calls("CreateAlias") {
+		input := c.Input.(*lambda.CreateAliasInput)
+		aliases[aws.ToString(input.Name)] = aws.ToString(input.FunctionVersion)
+	}
+	if want := map[string]string{"api": "7", "batch": "8"}; !cmp.Equal(want, aliases) {
+		t.Error(cmp.Diff(want, aliases))
+	}
+	updates := recorder.Calls("UpdateFunctionConfiguration")
+	if len(updates) != 2 {
+		t.Fatalf("want the batch settings applied and then undone, got %v", recorder.Operations())
+	}
+	batch := updates[0].Input.(*lambda.UpdateFunctionConfigurationInput)
+	if aws.ToInt32(batch.MemorySize) != 3008 || !cmp.Equal(map[string]string{"LOG_LEVEL": "info", "MODE": "batch"}, batch.Environment.Variables) {
+		t.Errorf("want batch's memory and environment over the function's, got %d %v", aws.ToInt32(batch.MemorySize), batch.Environment.Variables)
+	}
+	restore := updates[1].Input.(*lambda.UpdateFunctionConfigurationInput)
+	if aws.ToInt32(restore.MemorySize) != 256 || !cmp.Equal(map[string]string{"LOG_LEVEL": "info"}, restore.Environment.Variables) {
+		t.Errorf("want the function's own settings restored, got %d %v", aws.ToInt32(restore.MemorySize), restore.Environment.Variables)
+	}
+	provisioned := recorder.Calls("PutProvisionedConcurrencyConfig")
+	if len(provisioned) != 1 || aws.ToString(provisioned[0].Input.(*lambda.PutProvisionedConcurrencyConfigInput).Qualifier) != "api" {
+		t.Errorf("want provisioned concurrency on api only, got %v", provisioned)
+	}
+	removed := recorder.Calls("DeleteProvisionedConcurrencyConfig")
+	if len(removed) != 1 || aws.ToString(removed[0].Input.(*lambda.DeleteProvisionedConcurrencyConfigInput).Qualifier) != "batch" {
+		t.Errorf("want any provisioned concurrency removed from batch, got %v", removed)
+	}
+}
+
+func TestAliasActionDo_RestoresTheFunctionsSettingsWhenAnAliasFails(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunctionConfiguration", &lambda.GetFunctionConfigurationOutput{
+		MemorySize:       aws.Int32(256),
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	})
+	recorder.Respond("PublishVersion", &lambda.PublishVersionOutput{Version: aws.String("8")})
+	recorder.FailNext("CreateAlias", &types.TooManyRequestsException{}, 1)
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	l := glambda.Lambda{Name: "orders", Aliases: []glambda.AliasConfig{{Name: "batch", Memory: 3008}}}
+	err := glambda.NewAliasAction(client, l, "7").Do(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "alias batch") {
+		t.Fatalf("want the alias's failure, got %v", err)
+	}
+	updates := recorder.Calls("UpdateFunctionConfiguration")
+	if len(updates) != 2 || aws.ToInt32(updates[1].Input.(*lambda.UpdateFunctionConfigurationInput).MemorySize) != 256 {
+		t.Errorf("want the function's memory restored, got %v", recorder.Operations())
+	}
+}
+
+func TestWithAlias_RejectsInvalidAliases(t *testing.T) {
+	t.Parallel()
+	tc := map[string][]glambda.DeployOptions{
+		"no name":   {glambda.WithAlias(glambda.AliasConfig{})},
+		"live":      {glambda.WithAlias(glambda.AliasConfig{Name: "live"})},
+		"memory":    {glambda.WithAlias(glambda.AliasConfig{Name: "batch", Memory: 64})},
+		"env":       {glambda.WithAlias(glambda.AliasConfig{Name: "batch", Environment: map[string]string{"AWS_REGION": "x"}})},
+		"duplicate": {glambda.WithAlias(glambda.AliasConfig{Name: "api"}), glambda.WithAlias(glambda.AliasConfig{Name: "api"})},
+		"provisioned twice": {
+			glambda.WithAlias(glambda.AliasConfig{Name: "api", ProvisionedConcurrency: 2}),
+			glambda.WithProvisionedConcurrency("api", 2),
+		},
+	}
+	for description, opts := range tc {
+		_, err := glambda.NewLambda("orders", "", append([]glambda.DeployOptions{glambdatest.Sandbox()}, opts...)...)
+		if err == nil {
+			t.Errorf("%s: want an error", description)
+		}
+	}
+}
+
+func TestPlan_DescribesAliases(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.ParseConfig(strings.NewReader(`functions:
+  - name: orders
+    handler: testdata/correct_test_handler/main.go
+    aliases:
+      - name: api
+        provisioned_concurrency: 2
+      - name: batch
+        memory: 3008
+        environment:
+          MODE: batch
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans, err := glambda.PlanConfig(context.Background(), cfg, glambdatest.Sandbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := plans[0].String()
+	for _, want := range []string{
+		"UpdateAlias orders:api\n      pointed at the new version\n      provisioned concurrency: 2\n",
+		"UpdateAlias orders:batch\n      pointed at a version of its own, with memory 3008 MB and environment MODE\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in the plan, got\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package glambda
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithAssets is a deploy option that adds files the handler reads at run
+// time, such as templates, certificates or data files, to the deployment zip
+// next to bootstrap. A directory adds every file beneath it. Each file keeps
+// its path relative to the working directory, so --include templates puts
+// templates/index.html at /var/task/templates/index.html, where the
+// handler's working directory is /var/task.
+func WithAssets(paths ...string) DeployOptions {
+	return withAssetsIn("", paths)
+}
+
+// withAssetsIn is [WithAssets] for paths relative to dir, such as the
+// directory of a config file.
+func withAssetsIn(dir string, paths []string) DeployOptions {
+	return func(l *Lambda) error {
+		for _, p := range paths {
+			if p == "" {
+				return fmt.Errorf("asset path must not be empty")
+			}
+		}
+		l.Assets = append(l.Assets, paths...)
+		l.assetDir = dir
+		return nil
+	}
+}
+
+// WithSensitiveAssets is a deploy option that allows assets which usually
+// hold secrets or repository metadata, .env files and .git directories, to be
+// added to the zip. Without it they are an error, as they are more often
+// swept up by including a whole directory than wanted.
+func WithSensitiveAssets() DeployOptions {
+	return func(l *Lambda) error {
+		l.sensitiveAssets = true
+		return nil
+	}
+}
+
+const (
+	// maxAssetSize is the largest file that can be added as an asset.
+	maxAssetSize = 50 << 20
+	// maxAssetsSize is the most all assets together can add up to, Lambda's
+	// limit on the unzipped size of a function's code.
+	maxAssetsSize = 250 << 20
+)
+
+// assetFile is a file to add to the deployment zip, and its name there.
+type assetFile struct {
+	name string
+	path string
+	mode fs.FileMode
+	size int64
+}
+
+// assetFiles lists the files the asset paths add to the zip, in order, named
+// by their path relative to the asset directory, or the working directory if
+// it is empty. Files matching an exclude pattern, or the [IgnoreFile] at the
+// root of the handler if there is one, are left out. Assets outside that directory, including through a symbolic
+// link, a file named bootstrap, sensitive files that aren't allowed, and
+// assets over the size limits are errors.
+func assetFiles(opts BuildOptions, handler string) ([]assetFile, error) {
+	dir := opts.AssetDir
+	if dir == "" {
+		dir = "."
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Symbolic links are compared with the root they resolve against, which
+	// may itself be reached through a link, such as /tmp on macOS.
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("error reading asset directory, %w", err)
+	}
+	excludes, err := parsePatterns(opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	var ignore ignoreRules
+	if handler != "" && len(opts.Assets) > 0 {
+		ignore, err = readIgnoreFile(handler)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s, %w", IgnoreFile, err)
+		}
+	}
+	var files []assetFile
+	var total int64
+	seen := map[string]bool{}
+	for _, p := range opts.Assets {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil || outside(rel) {
+				return fmt.Errorf("asset %s is outside %s, assets keep their path relative to it in the zip", path, root)
+			}
+			name := filepath.ToSlash(rel)
+			if name != "." && (excluded(excludes, name, d.IsDir()) || ignore.excludes(path, d.IsDir())) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" && !opts.AllowSensitiveAssets {
+					return fmt.Errorf("asset %s is a git repository, see WithSensitiveAssets", path)
+				}
+				return nil
+			}
+			if name == "bootstrap" {
+				return fmt.Errorf("asset %s would replace the handler's bootstrap executable", path)
+			}
+			if strings.HasPrefix(d.Name(), ".env") && !opts.AllowSensitiveAssets {
+				return fmt.Errorf("asset %s may hold secrets, see WithSensitiveAssets", path)
+			}
+			info, err := os.Lstat(path)
+			if err != nil {
+				return err
+			}
+			src := path
+			if info.Mode()&fs.ModeSymlink != 0 {
+				src, err = filepath.EvalSymlinks(path)
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(realRoot, src)
+				if err != nil || outside(rel) {
+					return fmt.Errorf("asset %s links to %s, outside %s", path, src, root)
+				}
+				info, err = os.Stat(src)
+				if err != nil {
+					return err
+				}
+			}
+			if info.IsDir() {
+				return fmt.Errorf("asset %s links to the directory %s, include the directory instead", path, src)
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			// A file included twice, e.g. by itself and through its
+			// directory, is only added once.
+			if seen[name] {
+				return nil
+			}
+			seen[name] = true
+			if info.Size() > maxAssetSize {
+				return fmt.Errorf("asset %s is %d bytes, over the limit of %d bytes for a single asset", path, info.Size(), maxAssetSize)
+			}
+			total += info.Size()
+			if total > maxAssetsSize {
+				return fmt.Errorf("assets add up to more than %d bytes, Lambda's limit for unzipped code, at %s", maxAssetsSize, path)
+			}
+			files = append(files, assetFile{name: name, path: src, mode: info.Mode().Perm(), size: info.Size()})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading asset, %w", err)
+		}
+	}
+	return files, nil
+}
+
+// outside reports whether a path relative to a directory leaves it.
+func outside(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel)
+}
+
+// reportAssets lists the assets being added to the zip.
+func (l Lambda) reportAssets(assets []assetFile) {
+	if len(assets) == 0 {
+		return
+	}
+	var total int64
+	for _, a := range assets {
+		total += a.size
+		l.detail("build", "adding asset %s, %d bytes", a.name, a.size)
+	}
+	l.report("build", "adding %d assets, %d bytes", len(assets), total)
+}
@@ -0,0 +1,399 @@
+package glambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AuditRecord is what an [AuditLog] keeps about a command that changed AWS
+// resources: who ran it, what it changed, when, and how it ended.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Actor is the ARN of the identity the command ran as, or empty if STS
+	// couldn't say.
+	Actor   string `json:"actor"`
+	Account string `json:"account"`
+	Region  string `json:"region"`
+	// Command is the glambda command, such as "deploy" or "delete".
+	Command   string   `json:"command"`
+	Functions []string `json:"functions"`
+	// PlanSHA256 identifies the plan that was approved, see [PlanSHA256].
+	PlanSHA256 string `json:"plan_sha256,omitempty"`
+	DeployID   string `json:"deploy_id,omitempty"`
+	// Sandbox marks commands that ran against glambdatest's fake clients,
+	// and so changed nothing.
+	Sandbox bool   `json:"sandbox,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// VersionARNs are the function versions the command published.
+	VersionARNs []string `json:"version_arns,omitempty"`
+}
+
+// AuditLog keeps [AuditRecord]s somewhere that outlives the command, see
+// [OpenAuditLog].
+type AuditLog interface {
+	Append(ctx context.Context, r AuditRecord) error
+	// Tail returns the last n records, oldest first.
+	Tail(ctx context.Context, n int) ([]AuditRecord, error)
+}
+
+// AuditLogStream is the CloudWatch Logs stream that a
+// [CloudWatchLogsAuditLog] writes to.
+const AuditLogStream = "glambda-audit"
+
+// auditTimeFormat has a fixed width, so that records keyed by their time
+// sort in the order they were made.
+const auditTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// OpenAuditLog opens the audit log at location, which is one of:
+//
+//   - a file path, or file:///path, appended to as JSON lines
+//   - s3://bucket/prefix, one JSON object per record
+//   - dynamodb://table, a table whose partition key "log" and sort key "time"
+//     are both strings
+//   - logs://group, the [AuditLogStream] of a CloudWatch Logs log group
+//
+// Logs kept in AWS are reached with the AWS config and clients that opts
+// give, as for [NewLambda].
+func OpenAuditLog(location string, opts ...DeployOptions) (AuditLog, error) {
+	scheme, rest, ok := strings.Cut(location, "://")
+	if !ok {
+		return FileAuditLog{Path: location}, nil
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("audit log %q is missing a path, bucket, table or log group", location)
+	}
+	switch scheme {
+	case "file":
+		return FileAuditLog{Path: rest}, nil
+	case "s3", "dynamodb", "logs":
+	default:
+		return nil, fmt.Errorf("unknown audit log %q, expected a file path, s3://, dynamodb:// or logs://", location)
+	}
+	l, err := NewLambda("glambda-audit", "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case "s3":
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		return S3AuditLog{Client: l.s3API(), Bucket: bucket, Prefix: prefix}, nil
+	case "dynamodb":
+		return DynamoDBAuditLog{Client: l.dynamoDBAPI(), Table: rest}, nil
+	}
+	return CloudWatchLogsAuditLog{Client: l.cloudWatchLogsAPI(), Group: rest}, nil
+}
+
+// NewAuditRecord starts the audit record of a command that is about to
+// change functions, filling in who is running it and where.
+func (l Lambda) NewAuditRecord(ctx context.Context, command string, functions []string) AuditRecord {
+	r := AuditRecord{
+		Time:      time.Now().UTC(),
+		Account:   l.AWSAccountID,
+		Region:    l.cfg.Region,
+		Command:   command,
+		Functions: functions,
+		DeployID:  l.deployID,
+	}
+	client := l.stsClient
+	if client == nil {
+		client = sts.NewFromConfig(l.cfg)
+	}
+	resp, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err == nil {
+		r.Actor = aws.ToString(resp.Arn)
+	}
+	return r
+}
+
+// PlanSHA256 hashes the JSON form of plans, so that an audit record shows
+// exactly which plan was approved.
+func PlanSHA256(plans ...Plan) (string, error) {
+	data, err := json.Marshal(plans)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// FileAuditLog keeps records as lines of JSON in a local file.
+type FileAuditLog struct {
+	Path string
+}
+
+func (f FileAuditLog) Append(ctx context.Context, r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f FileAuditLog) Tail(ctx context.Context, n int) ([]AuditRecord, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return decodeAuditRecords(lastN(lines, n))
+}
+
+// S3AuditLog keeps each record as an object under Prefix, named after its
+// time so that listing the prefix lists records in order.
+type S3AuditLog struct {
+	Client S3Client
+	Bucket string
+	Prefix string
+}
+
+func (s S3AuditLog) Append(ctx context.Context, r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	key := path.Join(s.Prefix, r.Time.UTC().Format(auditTimeFormat)+"-"+auditID(r)+".json")
+	_, err = s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s S3AuditLog) Tail(ctx context.Context, n int) ([]AuditRecord, error) {
+	prefix := s.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	var keys []string
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(s.Bucket), Prefix: aws.String(prefix)}
+	for {
+		resp, err := s.Client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range resp.Contents {
+			keys = append(keys, aws.ToString(o.Key))
+		}
+		if !aws.ToBool(resp.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = resp.NextContinuationToken
+	}
+	slices.Sort(keys)
+	var objects [][]byte
+	for _, key := range lastN(keys, n) {
+		resp, err := s.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(key)})
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, data)
+	}
+	return decodeAuditRecords(objects)
+}
+
+// DynamoDBAuditLog keeps records as items of a table, all under one
+// partition so that they can be read back in order.
+type DynamoDBAuditLog struct {
+	Client DynamoDBClient
+	Table  string
+}
+
+// auditPartition is the "log" key of every item a [DynamoDBAuditLog] writes.
+const auditPartition = "glambda"
+
+func (d DynamoDBAuditLog) Append(ctx context.Context, r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = d.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.Table),
+		Item: map[string]dTypes.AttributeValue{
+			"log":    &dTypes.AttributeValueMemberS{Value: auditPartition},
+			"time":   &dTypes.AttributeValueMemberS{Value: r.Time.UTC().Format(auditTimeFormat) + "#" + auditID(r)},
+			"record": &dTypes.AttributeValueMemberS{Value: string(data)},
+		},
+	})
+	return err
+}
+
+func (d DynamoDBAuditLog) Tail(ctx context.Context, n int) ([]AuditRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(d.Table),
+		KeyConditionExpression:    aws.String("#log = :log"),
+		ExpressionAttributeNames:  map[string]string{"#log": "log"},
+		ExpressionAttributeValues: map[string]dTypes.AttributeValue{":log": &dTypes.AttributeValueMemberS{Value: auditPartition}},
+		ScanIndexForward:          aws.Bool(false),
+	}
+	// The newest records come first, a page at a time.
+	var newest []map[string]dTypes.AttributeValue
+	for len(newest) < n {
+		input.Limit = aws.Int32(int32(min(n-len(newest), 1000)))
+		resp, err := d.Client.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		newest = append(newest, resp.Items...)
+		if len(resp.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = resp.LastEvaluatedKey
+	}
+	var items [][]byte
+	for i := len(newest) - 1; i >= 0; i-- {
+		record, ok := newest[i]["record"].(*dTypes.AttributeValueMemberS)
+		if !ok {
+			return nil, fmt.Errorf("audit log item in %s has no record", d.Table)
+		}
+		items = append(items, []byte(record.Value))
+	}
+	return decodeAuditRecords(items)
+}
+
+// CloudWatchLogsAuditLog keeps records as events of the [AuditLogStream] in
+// Group, creating the stream when it is first written to.
+type CloudWatchLogsAuditLog struct {
+	Client CloudWatchLogsClient
+	Group  string
+}
+
+func (c CloudWatchLogsAuditLog) Append(ctx context.Context, r AuditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	put := func() error {
+		_, err := c.Client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+			LogGroupName:  aws.String(c.Group),
+			LogStreamName: aws.String(AuditLogStream),
+			LogEvents: []lTypes.InputLogEvent{{
+				Timestamp: aws.Int64(r.Time.UnixMilli()),
+				Message:   aws.String(string(data)),
+			}},
+		})
+		return err
+	}
+	err = put()
+	var notFound *lTypes.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return err
+	}
+	_, err = c.Client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(c.Group),
+		LogStreamName: aws.String(AuditLogStream),
+	})
+	var exists *lTypes.ResourceAlreadyExistsException
+	if err != nil && !errors.As(err, &exists) {
+		return err
+	}
+	return put()
+}
+
+func (c CloudWatchLogsAuditLog) Tail(ctx context.Context, n int) ([]AuditRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(c.Group),
+		LogStreamName: aws.String(AuditLogStream),
+		StartFromHead: aws.Bool(false),
+	}
+	// Each page holds the events before the last, oldest first within the
+	// page. The stream has been read back to its start when the token
+	// stops changing.
+	var pages [][]lTypes.OutputLogEvent
+	count := 0
+	for count < n {
+		input.Limit = aws.Int32(int32(min(n-count, 10000)))
+		resp, err := c.Client.GetLogEvents(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, resp.Events)
+		count += len(resp.Events)
+		token := aws.ToString(resp.NextBackwardToken)
+		if len(resp.Events) == 0 || token == "" || token == aws.ToString(input.NextToken) {
+			break
+		}
+		input.NextToken = aws.String(token)
+	}
+	var events [][]byte
+	for i := len(pages) - 1; i >= 0; i-- {
+		for _, e := range pages[i] {
+			events = append(events, []byte(aws.ToString(e.Message)))
+		}
+	}
+	return decodeAuditRecords(lastN(events, n))
+}
+
+// auditID tells apart records made at the same instant.
+func auditID(r AuditRecord) string {
+	if r.DeployID != "" {
+		return r.DeployID
+	}
+	return UUID()
+}
+
+func lastN[T any](items []T, n int) []T {
+	if n <= 0 {
+		return nil
+	}
+	return items[max(len(items)-n, 0):]
+}
+
+func decodeAuditRecords(raw [][]byte) ([]AuditRecord, error) {
+	records := make([]AuditRecord, 0, len(raw))
+	for _, data := range raw {
+		var r AuditRecord
+		err := json.Unmarshal(data, &r)
+		if err != nil {
+			return nil, fmt.Errorf("malformed audit record %q: %w", data, err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
@@ -0,0 +1,187 @@
+package glambda_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func auditRecords(n int) []glambda.AuditRecord {
+	var records []glambda.AuditRecord
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range n {
+		records = append(records, glambda.AuditRecord{
+			Time:      start.Add(time.Duration(i) * time.Minute),
+			Command:   "deploy",
+			Functions: []string{fmt.Sprintf("fn%d", i)},
+			DeployID:  fmt.Sprintf("deploy-%d", i),
+			Success:   true,
+		})
+	}
+	return records
+}
+
+func TestFileAuditLog_TailReturnsTheLastRecordsOldestFirst(t *testing.T) {
+	t.Parallel()
+	log := glambda.FileAuditLog{Path: filepath.Join(t.TempDir(), "audit.jsonl")}
+	records := auditRecords(3)
+	for _, r := range records {
+		err := log.Append(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := log.Tail(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(records[1:], got) {
+		t.Error(cmp.Diff(records[1:], got))
+	}
+}
+
+func TestOpenAuditLog_ChoosesTheSinkFromTheLocation(t *testing.T) {
+	t.Parallel()
+	cases := map[string]any{
+		"audit.jsonl":            glambda.FileAuditLog{},
+		"file:///var/audit.json": glambda.FileAuditLog{},
+		"s3://bucket/prefix":     glambda.S3AuditLog{},
+		"dynamodb://table":       glambda.DynamoDBAuditLog{},
+		"logs://group":           glambda.CloudWatchLogsAuditLog{},
+	}
+	for location, want := range cases {
+		log, err := glambda.OpenAuditLog(location, glambdatest.Sandbox())
+		if err != nil {
+			t.Errorf("%s: %v", location, err)
+			continue
+		}
+		if fmt.Sprintf("%T", log) != fmt.Sprintf("%T", want) {
+			t.Errorf("%s: expected %T, got %T", location, want, log)
+		}
+	}
+	for _, location := range []string{"ftp://host/audit", "s3://"} {
+		_, err := glambda.OpenAuditLog(location, glambdatest.Sandbox())
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", location)
+		}
+	}
+}
+
+func TestS3AuditLog_KeysRecordsByTimeUnderThePrefix(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	log := glambda.S3AuditLog{Client: glambdatest.DummyS3Client{Recorder: recorder}, Bucket: "audit", Prefix: "glambda"}
+	record := auditRecords(1)[0]
+	err := log.Append(context.Background(), record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := recorder.Calls("PutObject")[0].Input.(*s3.PutObjectInput)
+	want := "glambda/2026-01-02T03:04:05.000000000Z-deploy-0.json"
+	if aws.ToString(put.Key) != want {
+		t.Errorf("expected key %s, got %s", want, aws.ToString(put.Key))
+	}
+	data, _ := io.ReadAll(put.Body)
+	recorder.Respond("ListObjectsV2", &s3.ListObjectsV2Output{Contents: []sTypes.Object{{Key: put.Key}}})
+	recorder.Respond("GetObject", &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))})
+	got, err := log.Tail(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal([]glambda.AuditRecord{record}, got) {
+		t.Error(cmp.Diff([]glambda.AuditRecord{record}, got))
+	}
+}
+
+// pagedQuery answers each Query with one page of items, newest first.
+type pagedQuery struct {
+	glambdatest.DummyDynamoDBClient
+	pages [][]map[string]dTypes.AttributeValue
+	calls int
+}
+
+func (p *pagedQuery) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	page := p.pages[p.calls]
+	p.calls++
+	out := &dynamodb.QueryOutput{Items: page}
+	if p.calls < len(p.pages) {
+		out.LastEvaluatedKey = map[string]dTypes.AttributeValue{"log": &dTypes.AttributeValueMemberS{Value: "glambda"}}
+	}
+	return out, nil
+}
+
+func TestDynamoDBAuditLog_TailPagesThroughMoreRecordsThanOneQueryReturns(t *testing.T) {
+	t.Parallel()
+	records := auditRecords(3)
+	item := func(r glambda.AuditRecord) map[string]dTypes.AttributeValue {
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return map[string]dTypes.AttributeValue{"record": &dTypes.AttributeValueMemberS{Value: string(data)}}
+	}
+	client := &pagedQuery{pages: [][]map[string]dTypes.AttributeValue{
+		{item(records[2]), item(records[1])},
+		{item(records[0])},
+	}}
+	got, err := glambda.DynamoDBAuditLog{Client: client, Table: "audit"}.Tail(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.calls != 2 {
+		t.Errorf("expected 2 queries, got %d", client.calls)
+	}
+	if !cmp.Equal(records, got) {
+		t.Error(cmp.Diff(records, got))
+	}
+}
+
+func TestCloudWatchLogsAuditLog_CreatesTheStreamOnFirstAppend(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("PutLogEvents", &lTypes.ResourceNotFoundException{Message: aws.String("stream does not exist")}, 1)
+	log := glambda.CloudWatchLogsAuditLog{Client: glambdatest.DummyCloudWatchLogsClient{Recorder: recorder}, Group: "audit"}
+	err := log.Append(context.Background(), auditRecords(1)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOps := []string{"PutLogEvents", "CreateLogStream", "PutLogEvents"}
+	if !cmp.Equal(wantOps, recorder.Operations()) {
+		t.Error(cmp.Diff(wantOps, recorder.Operations()))
+	}
+	create := recorder.Calls("CreateLogStream")[0].Input.(*cloudwatchlogs.CreateLogStreamInput)
+	if aws.ToString(create.LogStreamName) != glambda.AuditLogStream {
+		t.Errorf("expected stream %s, got %s", glambda.AuditLogStream, aws.ToString(create.LogStreamName))
+	}
+}
+
+func TestPlanSHA256_IsStableForTheSamePlan(t *testing.T) {
+	t.Parallel()
+	plan := glambda.Plan{FormatVersion: glambda.PlanFormatVersion, Function: "fn", Changes: []glambda.Change{{Operation: "CreateFunction"}}}
+	first, err := glambda.PlanSHA256(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := glambda.PlanSHA256(plan)
+	plan.Function = "other"
+	other, _ := glambda.PlanSHA256(plan)
+	if first != second || first == other {
+		t.Errorf("expected the hash to follow the plan, got %s, %s and %s", first, second, other)
+	}
+}
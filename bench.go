@@ -0,0 +1,204 @@
+package glambda
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// BenchColdStartVariable is the environment variable that is toggled between
+// benchmark runs. Changing any part of a function's configuration causes Lambda
+// to discard existing execution environments, forcing the next invocation to
+// be a cold start.
+const BenchColdStartVariable = "GLAMBDA_BENCH_NONCE"
+
+var reportFieldRegex = regexp.MustCompile(`(Duration|Billed Duration|Memory Size|Max Memory Used|Init Duration): ([0-9.]+)`)
+var reportRequestIDRegex = regexp.MustCompile(`REPORT RequestId: (\S+)`)
+
+// InvocationReport is the parsed form of the REPORT line that Lambda writes to
+// the function's log at the end of every invocation.
+type InvocationReport struct {
+	RequestID      string
+	Duration       time.Duration
+	BilledDuration time.Duration
+	InitDuration   time.Duration
+	MemorySize     int
+	MaxMemoryUsed  int
+}
+
+// ParseReport finds the REPORT line in a block of lambda log output and parses
+// it into an [InvocationReport]. The boolean result is false if no REPORT line
+// was present.
+func ParseReport(log string) (InvocationReport, bool) {
+	var report InvocationReport
+	idMatch := reportRequestIDRegex.FindStringSubmatch(log)
+	if idMatch == nil {
+		return report, false
+	}
+	report.RequestID = idMatch[1]
+	line := log[reportRequestIDRegex.FindStringIndex(log)[0]:]
+	for _, m := range reportFieldRegex.FindAllStringSubmatch(line, -1) {
+		value, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		switch m[1] {
+		case "Duration":
+			report.Duration = milliseconds(value)
+		case "Billed Duration":
+			report.BilledDuration = milliseconds(value)
+		case "Init Duration":
+			report.InitDuration = milliseconds(value)
+		case "Memory Size":
+			report.MemorySize = int(value)
+		case "Max Memory Used":
+			report.MaxMemoryUsed = int(value)
+		}
+	}
+	return report, true
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// BenchResult holds the reports gathered from a benchmark run, split into
+// cold and warm invocations.
+type BenchResult struct {
+	Cold []InvocationReport
+	Warm []InvocationReport
+}
+
+// Percentile returns the p-th percentile (0-100) of the given durations using
+// the nearest rank method. It returns zero for an empty slice.
+func Percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	rank := int(float64(len(sorted))*p/100+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+// Durations extracts the handler durations from a set of reports.
+func Durations(reports []InvocationReport) []time.Duration {
+	var d []time.Duration
+	for _, r := range reports {
+		d = append(d, r.Duration)
+	}
+	return d
+}
+
+// InitDurations extracts the init durations from a set of reports.
+func InitDurations(reports []InvocationReport) []time.Duration {
+	var d []time.Duration
+	for _, r := range reports {
+		d = append(d, r.InitDuration)
+	}
+	return d
+}
+
+// BenchFunction measures the cold and warm start latency of a deployed lambda function.
+//
+// Each cold invocation is preceded by a configuration update that toggles
+// [BenchColdStartVariable], which forces Lambda to create a fresh execution
+// environment. Warm invocations are made back to back afterwards. The function's
+// original environment is restored once the benchmark is complete, and a
+// failure to restore it is returned along with any other error.
+func BenchFunction(ctx context.Context, c LambdaClient, name string, payload []byte, cold, warm int) (result BenchResult, err error) {
+	cfg, err := c.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		return result, err
+	}
+	original := map[string]string{}
+	if cfg.Environment != nil && cfg.Environment.Variables != nil {
+		original = cfg.Environment.Variables
+	}
+	if cold > 0 {
+		defer func() {
+			// The environment is restored even if ctx was cancelled.
+			restoreErr := setEnvironment(context.WithoutCancel(ctx), c, name, original)
+			if restoreErr != nil {
+				err = errors.Join(err, fmt.Errorf("unable to restore the environment of %s, %w", name, restoreErr))
+			}
+		}()
+	}
+	for i := 0; i < cold; i++ {
+		env := maps.Clone(original)
+		env[BenchColdStartVariable] = UUID()
+		err = setEnvironment(ctx, c, name, env)
+		if err != nil {
+			return result, err
+		}
+		report, err := invokeForReport(ctx, c, name, payload)
+		if err != nil {
+			return result, err
+		}
+		result.Cold = append(result.Cold, report)
+	}
+	for i := 0; i < warm; i++ {
+		report, err := invokeForReport(ctx, c, name, payload)
+		if err != nil {
+			return result, err
+		}
+		result.Warm = append(result.Warm, report)
+	}
+	return result, nil
+}
+
+func setEnvironment(ctx context.Context, c LambdaClient, name string, env map[string]string) error {
+	_, err := c.UpdateFunctionConfiguration(ctx, &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(name),
+		Environment:  &types.Environment{Variables: env},
+	})
+	if err != nil {
+		return err
+	}
+	return WaitForUpdate(ctx, c, name)
+}
+
+func invokeForReport(ctx context.Context, c LambdaClient, name string, payload []byte) (InvocationReport, error) {
+	resp, err := c.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName: aws.String(name),
+		Payload:      payload,
+		LogType:      types.LogTypeTail,
+	})
+	if err != nil {
+		return InvocationReport{}, err
+	}
+	if resp.FunctionError != nil {
+		return InvocationReport{}, fmt.Errorf("function returned an error during benchmark: %s: %s", *resp.FunctionError, resp.Payload)
+	}
+	if resp.LogResult == nil {
+		return InvocationReport{}, fmt.Errorf("no log output returned for invocation of %s", name)
+	}
+	log, err := base64.StdEncoding.DecodeString(*resp.LogResult)
+	if err != nil {
+		return InvocationReport{}, fmt.Errorf("unable to decode log output: %w", err)
+	}
+	report, ok := ParseReport(string(log))
+	if !ok {
+		return InvocationReport{}, fmt.Errorf("no REPORT line found in log output for invocation of %s", name)
+	}
+	return report, nil
+}
+
+// Bench is a method on the [Lambda] struct that benchmarks the deployed lambda
+// function. See [BenchFunction] for how cold starts are forced.
+func (l Lambda) Bench(ctx context.Context, payload []byte, cold, warm int) (BenchResult, error) {
+	return BenchFunction(ctx, l.lambdaAPI(), l.Name, payload, cold, warm)
+}
@@ -0,0 +1,113 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestParseReport_ParsesColdStartReportLine(t *testing.T) {
+	t.Parallel()
+	log := "START RequestId: abc-123 Version: $LATEST\n" +
+		"Hello, World!\n" +
+		"END RequestId: abc-123\n" +
+		"REPORT RequestId: abc-123\tDuration: 12.34 ms\tBilled Duration: 13 ms\tMemory Size: 128 MB\tMax Memory Used: 21 MB\tInit Duration: 85.50 ms\t\n"
+	got, ok := glambda.ParseReport(log)
+	if !ok {
+		t.Fatal("expected REPORT line to be found")
+	}
+	want := glambda.InvocationReport{
+		RequestID:      "abc-123",
+		Duration:       12340 * time.Microsecond,
+		BilledDuration: 13 * time.Millisecond,
+		InitDuration:   85500 * time.Microsecond,
+		MemorySize:     128,
+		MaxMemoryUsed:  21,
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestParseReport_ReportsMissingReportLine(t *testing.T) {
+	t.Parallel()
+	_, ok := glambda.ParseReport("START RequestId: abc-123 Version: $LATEST\n")
+	if ok {
+		t.Error("expected no REPORT line to be found")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+	var durations []time.Duration
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	if got := glambda.Percentile(durations, 50); got != 10*time.Millisecond {
+		t.Errorf("expected p50 of 10ms, got %s", got)
+	}
+	if got := glambda.Percentile(durations, 95); got != 19*time.Millisecond {
+		t.Errorf("expected p95 of 19ms, got %s", got)
+	}
+	if got := glambda.Percentile(nil, 95); got != 0 {
+		t.Errorf("expected 0 for no durations, got %s", got)
+	}
+}
+
+func TestBenchFunction_TogglesConfigurationForEachColdStart(t *testing.T) {
+	t.Parallel()
+	var updates int32
+	client := glambdatest.DummyLambdaClient{
+		FuncExists:    true,
+		Environment:   map[string]string{"EXISTING": "value"},
+		ConfigUpdates: &updates,
+	}
+	result, err := glambda.BenchFunction(context.Background(), client, "testLambda", []byte(`{}`), 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Cold) != 3 {
+		t.Errorf("expected 3 cold invocations, got %d", len(result.Cold))
+	}
+	if len(result.Warm) != 5 {
+		t.Errorf("expected 5 warm invocations, got %d", len(result.Warm))
+	}
+	// one update per cold start, plus one to restore the original environment
+	if updates != 4 {
+		t.Errorf("expected 4 configuration updates, got %d", updates)
+	}
+}
+
+// unrestorableClient fails to put back an environment without
+// [glambda.BenchColdStartVariable].
+type unrestorableClient struct {
+	glambdatest.DummyLambdaClient
+}
+
+func (c unrestorableClient) UpdateFunctionConfiguration(ctx context.Context, input *lambda.UpdateFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	if _, ok := input.Environment.Variables[glambda.BenchColdStartVariable]; !ok {
+		return nil, errors.New("throttled")
+	}
+	return c.DummyLambdaClient.UpdateFunctionConfiguration(ctx, input, opts...)
+}
+
+func TestBenchFunction_ReportsAFailureToRestoreTheEnvironment(t *testing.T) {
+	t.Parallel()
+	var updates int32
+	client := unrestorableClient{glambdatest.DummyLambdaClient{
+		FuncExists:    true,
+		Environment:   map[string]string{"EXISTING": "value"},
+		ConfigUpdates: &updates,
+	}}
+	_, err := glambda.BenchFunction(context.Background(), client, "testLambda", []byte(`{}`), 1, 0)
+	if err == nil || !strings.Contains(err.Error(), "unable to restore the environment of testLambda, throttled") {
+		t.Errorf("want the failed restore reported, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package glambda
+
+import (
+	"bytes"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// WithBootstrap is a deploy option that deploys a prebuilt executable instead
+// of building a Go handler, so that functions written in Rust, Zig or any
+// other language that targets the provided.al2023 runtime can be deployed
+// with glambda's roles, triggers and publishing. The handler path given to
+// [NewLambda] is ignored, and may be empty.
+//
+// The executable must be a Linux binary for the function's architecture, see
+// [WithArchitecture]. Go specific checks, such as [WithVulnCheck] and
+// handler templates, can't be used with it.
+func WithBootstrap(path string) DeployOptions {
+	return func(l *Lambda) error {
+		if path == "" {
+			return fmt.Errorf("bootstrap path must not be empty")
+		}
+		l.Bootstrap = path
+		return nil
+	}
+}
+
+// PackageBootstrap zips a prebuilt executable as the bootstrap of a
+// provided.al2023 function, after checking that it is a Linux executable
+// built for arch.
+func PackageBootstrap(path string, arch types.Architecture) ([]byte, error) {
+	return packageBootstrap(path, arch, nil)
+}
+
+func packageBootstrap(path string, arch types.Architecture, assets []assetFile) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading bootstrap, %w", err)
+	}
+	err = checkBootstrap(data, arch)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return zipCode(data, assets...)
+}
+
+func checkBootstrap(data []byte, arch types.Architecture) error {
+	f, err := elf.NewFile(bytes.NewReader(data))
+	var formatErr *elf.FormatError
+	if errors.As(err, &formatErr) {
+		return fmt.Errorf("not a Linux executable, Lambda can only run ELF binaries")
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if f.Type != elf.ET_EXEC && f.Type != elf.ET_DYN {
+		return fmt.Errorf("not an executable, got ELF type %s", f.Type)
+	}
+	want := map[types.Architecture]elf.Machine{
+		types.ArchitectureArm64: elf.EM_AARCH64,
+		types.ArchitectureX8664: elf.EM_X86_64,
+	}[arch]
+	if f.Machine != want {
+		return fmt.Errorf("built for %s, but the function runs on %s, see WithArchitecture", f.Machine, arch)
+	}
+	return nil
+}
+
+// packageHandler builds the Go handler, or packages the prebuilt bootstrap,
+// along with any assets.
+func (l Lambda) packageHandler() ([]byte, error) {
+	if l.Bootstrap != "" && (l.vulnCheck || l.TemplateData != nil || len(l.BuildFlags) > 0 || l.CGO) {
+		return nil, fmt.Errorf("vulnerability checks, handler templates, build flags and cgo need Go source, not a prebuilt bootstrap")
+	}
+	opts := BuildOptions{
+		Architecture:         l.architecture(),
+		TemplateData:         l.TemplateData,
+		StrictTemplates:      l.StrictTemplates,
+		KeepBuildDir:         l.keepBuildDir,
+		Assets:               l.Assets,
+		AssetDir:             l.assetDir,
+		AllowSensitiveAssets: l.sensitiveAssets,
+		Exclude:              l.assetExcludes,
+		Flags:                l.BuildFlags,
+		CGO:                  l.CGO,
+		CC:                   l.CC,
+	}
+	assets, err := assetFiles(opts, l.HandlerPath)
+	if err != nil {
+		return nil, err
+	}
+	l.reportAssets(assets)
+	if l.Bootstrap != "" {
+		return packageBootstrap(l.Bootstrap, l.architecture(), assets)
+	}
+	return packageWith(l.HandlerPath, opts, assets)
+}
@@ -0,0 +1,148 @@
+package glambda_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+// writeExecutable writes the header of a 64 bit Linux executable for machine,
+// which is all that glambda inspects, standing in for a Rust or Zig build.
+func writeExecutable(t *testing.T, machine elf.Machine) string {
+	t.Helper()
+	header := elf.Header64{
+		Type:      uint16(elf.ET_EXEC),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Ehsize:    64,
+		Phentsize: 56,
+		Shentsize: 64,
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, binary.LittleEndian, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bootstrap")
+	err = os.WriteFile(path, buf.Bytes(), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPackageBootstrap_ZipsExecutableAsBootstrap(t *testing.T) {
+	t.Parallel()
+	path := writeExecutable(t, elf.EM_AARCH64)
+	pkg, err := glambda.PackageBootstrap(path, types.ArchitectureArm64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 1 || r.File[0].Name != "bootstrap" || r.File[0].Mode().Perm() != 0o755 {
+		t.Fatalf("want a single executable bootstrap, got %+v", r.File)
+	}
+	f, err := r.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("want the executable packaged unchanged")
+	}
+}
+
+func TestPackageBootstrap_RejectsExecutableForOtherArchitecture(t *testing.T) {
+	t.Parallel()
+	path := writeExecutable(t, elf.EM_X86_64)
+	_, err := glambda.PackageBootstrap(path, types.ArchitectureArm64)
+	if err == nil || !strings.Contains(err.Error(), "runs on arm64") {
+		t.Fatalf("want an architecture mismatch error, got %v", err)
+	}
+	_, err = glambda.PackageBootstrap(path, types.ArchitectureX8664)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackageBootstrap_RejectsFilesThatArentLinuxExecutables(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "bootstrap")
+	err := os.WriteFile(path, []byte("#!/bin/sh\necho hello\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = glambda.PackageBootstrap(path, types.ArchitectureArm64)
+	if err == nil || !strings.Contains(err.Error(), "not a Linux executable") {
+		t.Fatalf("want an error for a shell script, got %v", err)
+	}
+}
+
+func TestDeploy_WithBootstrapDeploysThePrebuiltExecutable(t *testing.T) {
+	t.Parallel()
+	path := writeExecutable(t, elf.EM_AARCH64)
+	want, err := glambda.PackageBootstrap(path, types.ArchitectureArm64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("rusty", "", glambdatest.SandboxWithRecorder(recorder), glambda.WithBootstrap(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("CreateFunction")
+	if len(calls) != 1 {
+		t.Fatalf("want the function created, got %v", recorder.Operations())
+	}
+	cmd := calls[0].Input.(*lambda.CreateFunctionInput)
+	if !bytes.Equal(cmd.Code.ZipFile, want) {
+		t.Error("want the prebuilt executable deployed")
+	}
+	if cmd.Runtime != types.RuntimeProvidedal2023 {
+		t.Errorf("want the provided.al2023 runtime, got %s", cmd.Runtime)
+	}
+}
+
+func TestBuild_WithBootstrapRejectsGoOnlyChecks(t *testing.T) {
+	t.Parallel()
+	path := writeExecutable(t, elf.EM_AARCH64)
+	l, err := glambda.NewLambda("rusty", "", glambdatest.Sandbox(), glambda.WithBootstrap(path), glambda.WithVulnCheck())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Build()
+	if err == nil || !strings.Contains(err.Error(), "need Go source") {
+		t.Fatalf("want an error for a vulnerability check of a bootstrap, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package glambda
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WithBuildFlags is a deploy option that passes extra flags to go build, such
+// as "-ldflags", "-X main.version=1.2.3" to stamp a version into the handler,
+// or "-tags", "prod" to select build tags. Tags are added to the
+// lambda.norpc tag glambda always builds with. The -o and -overlay flags are
+// glambda's own and can't be given.
+func WithBuildFlags(flags ...string) DeployOptions {
+	return func(l *Lambda) error {
+		_, err := goBuildArgs("bootstrap", flags)
+		if err != nil {
+			return err
+		}
+		l.BuildFlags = append(l.BuildFlags, flags...)
+		return nil
+	}
+}
+
+// goBuildValueFlags are the go build flags that take a value, which may be
+// given as the next argument rather than after an equals sign.
+var goBuildValueFlags = []string{
+	"asmflags", "buildmode", "compiler", "covermode", "coverpkg",
+	"gccgoflags", "gcflags", "installsuffix", "ldflags", "mod", "modfile",
+	"o", "overlay", "p", "pgo", "pkgdir", "tags", "toolexec",
+}
+
+// goBuildArgs are the arguments to go that build a handler into output, with
+// the given extra flags. Tags in the flags are merged into one -tags flag
+// with lambda.norpc, as go build only honours the last -tags it is given.
+// Builds are always -trimpath, so that the binary doesn't depend on the
+// directory it was built in, which for a handler outside any module is a new
+// temporary one each time, and unchanged code keeps its CodeSha256.
+func goBuildArgs(output string, flags []string) ([]string, error) {
+	tags := []string{"lambda.norpc"}
+	var rest []string
+	for i := 0; i < len(flags); i++ {
+		flag := flags[i]
+		if !strings.HasPrefix(flag, "-") {
+			return nil, fmt.Errorf("invalid build flag %q, expected a flag such as -ldflags", flag)
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+		if !hasValue && slices.Contains(goBuildValueFlags, name) {
+			if i+1 == len(flags) {
+				return nil, fmt.Errorf("build flag %s needs a value", flag)
+			}
+			i++
+			value = flags[i]
+		}
+		switch name {
+		case "o", "overlay":
+			return nil, fmt.Errorf("build flag -%s is set by glambda and can't be given", name)
+		case "tags":
+			for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			continue
+		}
+		if !hasValue && slices.Contains(goBuildValueFlags, name) {
+			rest = append(rest, "-"+name, value)
+			continue
+		}
+		rest = append(rest, flag)
+	}
+	args := append([]string{"build", "-trimpath", "-tags", strings.Join(tags, ",")}, rest...)
+	return append(args, "-o", output), nil
+}
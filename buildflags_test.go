@@ -0,0 +1,64 @@
+package glambda_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestPackageWith_FlagsStampVersionAndAddBuildTags(t *testing.T) {
+	t.Parallel()
+	log := new(bytes.Buffer)
+	data, err := glambda.PackageWith("testdata/tagged_handler", glambda.BuildOptions{
+		Flags:        []string{"-ldflags", "-X main.version=stamped-1.2.3", "-tags=prod"},
+		KeepBuildDir: log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := strings.Cut(log.String(), "\n")
+	t.Cleanup(func() { os.RemoveAll(strings.TrimPrefix(first, "keeping build directory ")) })
+	if !strings.Contains(log.String(), "go build -trimpath -tags lambda.norpc,prod -ldflags '-X main.version=stamped-1.2.3' -o ") {
+		t.Errorf("expected tags merged with lambda.norpc, got %q", log.String())
+	}
+	f, err := zipEntries(t, data)["bootstrap"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	bootstrap, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(bootstrap, []byte("stamped-1.2.3")) {
+		t.Error("expected the version to be stamped into the executable")
+	}
+}
+
+func TestPackageWith_FailsWithoutTheBuildTagsAHandlerNeeds(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.PackageWith("testdata/tagged_handler", glambda.BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "undefined: greeting") {
+		t.Errorf("expected build to fail without the prod tag, got %v", err)
+	}
+}
+
+func TestWithBuildFlags_RejectsFlagsGlambdaSets(t *testing.T) {
+	t.Parallel()
+	for _, flags := range [][]string{
+		{"-o", "elsewhere"},
+		{"-overlay=overlay.json"},
+		{"-ldflags"},
+		{"main.version=1.2.3"},
+	} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithBuildFlags(flags...))
+		if err == nil {
+			t.Errorf("expected error for build flags %q, got nil", flags)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// LiveAlias is the alias that canary deployments shift traffic on. Callers
+// should invoke the function through it, rather than the unqualified ARN,
+// for a canary to protect them.
+const LiveAlias = "live"
+
+// CanaryPollInterval is how often a canary's error metrics are checked
+// during its bake time.
+var CanaryPollInterval = time.Minute
+
+// CanaryWait pauses between checks of a canary's error metrics, returning
+// early if ctx is done. Tests replace it to bake canaries instantly.
+var CanaryWait = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Canary describes a traffic shifted deployment. The new version takes
+// Percent of the traffic on [LiveAlias] for BakeTime, and is then promoted to
+// all of it, or rolled back if it reported any errors.
+type Canary struct {
+	Percent  int
+	BakeTime time.Duration
+}
+
+// WithCanary is a deploy option that releases the new version to percent of
+// the traffic on the [LiveAlias] alias, watches its CloudWatch error metrics
+// for bakeTime, and then either promotes it or rolls the alias back to the
+// previous version. The first deploy has nothing to compare against, so it
+// points the alias straight at the new version.
+func WithCanary(percent int, bakeTime time.Duration) DeployOptions {
+	return func(l *Lambda) error {
+		if percent < 1 || percent > 99 {
+			return fmt.Errorf("invalid canary percentage %d, must be between 1 and 99", percent)
+		}
+		if bakeTime <= 0 {
+			return fmt.Errorf("invalid canary bake time %s, must be positive", bakeTime)
+		}
+		l.Canary = &Canary{Percent: percent, BakeTime: bakeTime}
+		return nil
+	}
+}
+
+// CanaryRollbackError is returned when a canary reported errors during its
+// bake time, and traffic was moved back to the stable version.
+type CanaryRollbackError struct {
+	Version string
+	Stable  string
+	Errors  float64
+}
+
+func (e *CanaryRollbackError) Error() string {
+	return fmt.Sprintf("canary version %s reported %v errors, traffic was rolled back to version %s", e.Version, e.Errors, e.Stable)
+}
+
+// CanaryAction is an [Action] that shifts traffic on the [LiveAlias] alias to
+// a newly published version, as described by a [Canary].
+type CanaryAction struct {
+	client  LambdaClient
+	metrics CloudWatchClient
+	Name    string
+	Version string
+	Canary  Canary
+}
+
+// NewCanaryAction is a constructor function that creates a new [CanaryAction].
+func NewCanaryAction(client LambdaClient, metrics CloudWatchClient, name, version string, canary Canary) CanaryAction {
+	return CanaryAction{client: client, metrics: metrics, Name: name, Version: version, Canary: canary}
+}
+
+// Client returns the required client type. In this case [LambdaClient].
+func (a CanaryAction) Client() LambdaClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface.
+func (a CanaryAction) Do(ctx context.Context) error {
+	alias, err := a.Client().GetAlias(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(a.Name),
+		Name:         aws.String(LiveAlias),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		_, err = a.Client().CreateAlias(ctx, &lambda.CreateAliasInput{
+			FunctionName:    aws.String(a.Name),
+			Name:            aws.String(LiveAlias),
+			FunctionVersion: aws.String(a.Version),
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	stable := aws.ToString(alias.FunctionVersion)
+	if stable == a.Version {
+		return nil
+	}
+	err = a.route(ctx, stable, map[string]float64{a.Version: float64(a.Canary.Percent) / 100})
+	if err != nil {
+		return err
+	}
+	// An interrupted canary still rolls back, so traffic isn't left split.
+	rollback := func() error {
+		return a.route(context.WithoutCancel(ctx), stable, nil)
+	}
+	start := time.Now()
+	for baked := time.Duration(0); baked < a.Canary.BakeTime; {
+		step := min(CanaryPollInterval, a.Canary.BakeTime-baked)
+		err = CanaryWait(ctx, step)
+		if err != nil {
+			return errors.Join(err, rollback())
+		}
+		baked += step
+		errs, err := a.versionErrors(ctx, start)
+		if err != nil {
+			return errors.Join(err, rollback())
+		}
+		if errs > 0 {
+			return errors.Join(&CanaryRollbackError{Version: a.Version, Stable: stable, Errors: errs}, rollback())
+		}
+	}
+	return a.route(ctx, a.Version, nil)
+}
+
+// route points the alias at version, with weights sending a share of its
+// traffic elsewhere. No weights clears any previous traffic split.
+func (a CanaryAction) route(ctx context.Context, version string, weights map[string]float64) error {
+	if weights == nil {
+		weights = map[string]float64{}
+	}
+	_, err := a.Client().UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(a.Name),
+		Name:            aws.String(LiveAlias),
+		FunctionVersion: aws.String(version),
+		RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: weights},
+	})
+	return err
+}
+
+// versionErrors sums the errors reported by the canary version through the
+// alias since start.
+func (a CanaryAction) versionErrors(ctx context.Context, start time.Time) (float64, error) {
+	resp, err := a.metrics.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(start.Add(-time.Minute)),
+		EndTime:   aws.Time(time.Now()),
+		MetricDataQueries: []cwTypes.MetricDataQuery{{
+			Id: aws.String("errors"),
+			MetricStat: &cwTypes.MetricStat{
+				Metric: &cwTypes.Metric{
+					Namespace:  aws.String("AWS/Lambda"),
+					MetricName: aws.String("Errors"),
+					Dimensions: []cwTypes.Dimension{
+						{Name: aws.String("FunctionName"), Value: aws.String(a.Name)},
+						{Name: aws.String("Resource"), Value: aws.String(a.Name + ":" + LiveAlias)},
+						{Name: aws.String("ExecutedVersion"), Value: aws.String(a.Version)},
+					},
+				},
+				Period: aws.Int32(60),
+				Stat:   aws.String("Sum"),
+			},
+		}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, result := range resp.MetricDataResults {
+		for _, v := range result.Values {
+			total += v
+		}
+	}
+	return total, nil
+}
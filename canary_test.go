@@ -0,0 +1,139 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestWithCanary_RejectsInvalidPercentAndBakeTime(t *testing.T) {
+	t.Parallel()
+	for _, opt := range []glambda.DeployOptions{
+		glambda.WithCanary(0, time.Minute),
+		glambda.WithCanary(100, time.Minute),
+		glambda.WithCanary(10, 0),
+	} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), opt)
+		if err == nil {
+			t.Error("expected error, got nil")
+		}
+	}
+}
+
+func TestCanaryAction_FirstDeployPointsAliasAtNewVersion(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	metrics := glambdatest.DummyCloudWatchClient{Recorder: recorder}
+	err := glambda.NewCanaryAction(client, metrics, "fn", "1", glambda.Canary{Percent: 10, BakeTime: time.Minute}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetAlias", "CreateAlias"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Error(cmp.Diff(want, recorder.Operations()))
+	}
+}
+
+func TestCanaryAction_PromotesHealthyVersionAfterBaking(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetAlias", &lambda.GetAliasOutput{FunctionVersion: aws.String("3")})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	metrics := glambdatest.DummyCloudWatchClient{Recorder: recorder}
+	err := glambda.NewCanaryAction(client, metrics, "fn", "4", glambda.Canary{Percent: 10, BakeTime: 3 * time.Minute}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetAlias", "UpdateAlias", "GetMetricData", "GetMetricData", "GetMetricData", "UpdateAlias"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Fatal(cmp.Diff(want, recorder.Operations()))
+	}
+	updates := recorder.Calls("UpdateAlias")
+	shift := updates[0].Input.(*lambda.UpdateAliasInput)
+	if aws.ToString(shift.FunctionVersion) != "3" || shift.RoutingConfig.AdditionalVersionWeights["4"] != 0.1 {
+		t.Errorf("expected 10%% of version 3's traffic on version 4, got %+v", shift)
+	}
+	promote := updates[1].Input.(*lambda.UpdateAliasInput)
+	if aws.ToString(promote.FunctionVersion) != "4" || len(promote.RoutingConfig.AdditionalVersionWeights) != 0 {
+		t.Errorf("expected all traffic on version 4, got %+v", promote)
+	}
+}
+
+func TestCanaryAction_RollsBackVersionThatReportsErrors(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetAlias", &lambda.GetAliasOutput{FunctionVersion: aws.String("3")})
+	recorder.Respond("GetMetricData", &cloudwatch.GetMetricDataOutput{
+		MetricDataResults: []cwTypes.MetricDataResult{{Values: []float64{0, 2}}},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	metrics := glambdatest.DummyCloudWatchClient{Recorder: recorder}
+	err := glambda.NewCanaryAction(client, metrics, "fn", "4", glambda.Canary{Percent: 10, BakeTime: 10 * time.Minute}).Do(context.Background())
+	var rollback *glambda.CanaryRollbackError
+	if !errors.As(err, &rollback) || rollback.Errors != 2 {
+		t.Fatalf("expected rollback after 2 errors, got %v", err)
+	}
+	updates := recorder.Calls("UpdateAlias")
+	restore := updates[len(updates)-1].Input.(*lambda.UpdateAliasInput)
+	if aws.ToString(restore.FunctionVersion) != "3" || len(restore.RoutingConfig.AdditionalVersionWeights) != 0 {
+		t.Errorf("expected all traffic back on version 3, got %+v", restore)
+	}
+}
+
+// interruptingMetrics cancels the deploy the first time the canary's metrics
+// are read, as a Ctrl-C during the bake time would.
+type interruptingMetrics struct {
+	glambdatest.DummyCloudWatchClient
+	cancel context.CancelFunc
+}
+
+func (m interruptingMetrics) GetMetricData(ctx context.Context, input *cloudwatch.GetMetricDataInput, opts ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	m.cancel()
+	return m.DummyCloudWatchClient.GetMetricData(ctx, input, opts...)
+}
+
+func TestCanaryAction_RollsBackWhenInterrupted(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetAlias", &lambda.GetAliasOutput{FunctionVersion: aws.String("3")})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	metrics := interruptingMetrics{glambdatest.DummyCloudWatchClient{Recorder: recorder}, cancel}
+	err := glambda.NewCanaryAction(client, metrics, "fn", "4", glambda.Canary{Percent: 10, BakeTime: 10 * time.Minute}).Do(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	updates := recorder.Calls("UpdateAlias")
+	if len(updates) != 2 {
+		t.Fatalf("expected traffic to be shifted and rolled back, got %d alias updates", len(updates))
+	}
+	restore := updates[1].Input.(*lambda.UpdateAliasInput)
+	if aws.ToString(restore.FunctionVersion) != "3" || len(restore.RoutingConfig.AdditionalVersionWeights) != 0 {
+		t.Errorf("expected all traffic back on version 3, got %+v", restore)
+	}
+}
+
+func TestDeployAndPublish_ReportsCanaryAlias(t *testing.T) {
+	t.Parallel()
+	got, err := glambda.DeployAndPublish(context.Background(), "canaried", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithCanary(10, time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "arn:aws:lambda:us-east-1:123456789012:function:canaried:live"; got.AliasARN != want {
+		t.Errorf("want alias ARN %s, got %s", want, got.AliasARN)
+	}
+}
@@ -0,0 +1,53 @@
+package glambda
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// WithCGO is a deploy option that builds the handler with cgo, for handlers
+// that need C libraries such as SQLite or ONNX Runtime. Cross-compiling C
+// needs a C compiler for the function's architecture, given as cc, e.g.
+// "zig cc -target aarch64-linux-gnu". If cc is empty, zig is used with the
+// target matching the function's architecture, see [WithArchitecture].
+//
+// The handler is linked against the C library of the compiler's target, so
+// it must be no newer than the glibc of the provided.al2023 runtime.
+func WithCGO(cc string) DeployOptions {
+	return func(l *Lambda) error {
+		l.CGO = true
+		l.CC = cc
+		return nil
+	}
+}
+
+// cCompiler is the C compiler command a cgo build for goarch uses, cc if it
+// is set, or zig targeting goarch otherwise. It fails if the compiler isn't
+// installed, rather than leaving go build to fail obscurely.
+func cCompiler(cc, goarch string) (string, error) {
+	if cc == "" {
+		target := map[string]string{"arm64": "aarch64", "amd64": "x86_64"}[goarch]
+		cc = "zig cc -target " + target + "-linux-gnu"
+	}
+	fields := strings.Fields(cc)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("C compiler command is empty")
+	}
+	_, err := exec.LookPath(fields[0])
+	if err != nil {
+		return "", fmt.Errorf("cgo builds need the C compiler %q, which can't be found: %w; install it, or give another with --cc", fields[0], err)
+	}
+	return cc, nil
+}
+
+// cgoVars are the variables a cgo build runs with, turning cgo on and
+// setting the C compiler. A zig C compiler also gets the matching C++
+// compiler, for cgo packages that include C++.
+func cgoVars(cc string) []string {
+	vars := []string{"CGO_ENABLED=1", "CC=" + cc}
+	if args, ok := strings.CutPrefix(cc, "zig cc "); ok {
+		vars = append(vars, "CXX=zig c++ "+args)
+	}
+	return vars
+}
@@ -0,0 +1,63 @@
+package glambda_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestPackageWith_CGOBuildsHandlersThatImportC(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("needs a linux/amd64 host, to build with its own C compiler")
+	}
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("needs gcc")
+	}
+	log := new(bytes.Buffer)
+	data, err := glambda.PackageWith("testdata/import_c.go", glambda.BuildOptions{
+		Architecture: types.ArchitectureX8664,
+		CGO:          true,
+		CC:           "gcc",
+		KeepBuildDir: log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := strings.Cut(log.String(), "\n")
+	t.Cleanup(func() { os.RemoveAll(strings.TrimPrefix(first, "keeping build directory ")) })
+	if !strings.Contains(log.String(), "CGO_ENABLED=1 CC=gcc ") {
+		t.Errorf("expected cgo on with the given compiler, got %q", log.String())
+	}
+	if zipEntries(t, data)["bootstrap"] == nil {
+		t.Error("expected a bootstrap in the zip")
+	}
+}
+
+func TestPackageWith_CGOFailsEarlyWithoutTheCCompiler(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.PackageWith("testdata/import_c.go", glambda.BuildOptions{
+		CGO: true,
+		CC:  "no-such-cc -target aarch64-linux-gnu",
+	})
+	if err == nil || !strings.Contains(err.Error(), `C compiler "no-such-cc"`) {
+		t.Errorf("expected an error naming the missing compiler, got %v", err)
+	}
+}
+
+func TestPackageWith_CGODefaultsToZigForTheArchitecture(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("zig"); err == nil {
+		t.Skip("zig is installed")
+	}
+	_, err := glambda.PackageWith("testdata/import_c.go", glambda.BuildOptions{CGO: true})
+	if err == nil || !strings.Contains(err.Error(), `C compiler "zig"`) {
+		t.Errorf("expected an error naming zig, got %v", err)
+	}
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"errors"
 	"os"
 
+	"github.com/mr-joshcrane/glambda"
 	"github.com/mr-joshcrane/glambda/command"
 )
 
+// exitNoGoToolchain is the exit status when a handler couldn't be built
+// because go isn't on PATH, so that CI scripts can fall back to another
+// way of building it.
+const exitNoGoToolchain = 3
+
 func main() {
 	err := command.Main(os.Args[1:])
+	var toolchainErr *glambda.GoToolchainError
+	if errors.As(err, &toolchainErr) {
+		os.Exit(exitNoGoToolchain)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+	"github.com/spf13/cobra"
+)
+
+// AuditLogEnv names the environment variable that turns on the audit log.
+// When set, deploy, up and delete each append a [glambda.AuditRecord] to the
+// log it names, see [glambda.OpenAuditLog]. The --audit-log flag takes
+// precedence.
+const AuditLogEnv = "GLAMBDA_AUDIT_LOG"
+
+// audit is the audit record of the running command, which is only started
+// once the command is about to change something.
+type audit struct {
+	record *glambda.AuditRecord
+	opts   []glambda.DeployOptions
+}
+
+type auditKey struct{}
+
+func auditLocation(cmd *cobra.Command) string {
+	if location, _ := cmd.Flags().GetString("audit-log"); location != "" {
+		return location
+	}
+	return os.Getenv(AuditLogEnv)
+}
+
+func commandAudit(cmd *cobra.Command) *audit {
+	a, _ := cmd.Context().Value(auditKey{}).(*audit)
+	if a == nil || auditLocation(cmd) == "" {
+		return nil
+	}
+	return a
+}
+
+// startAudit starts the command's audit record, once the user has confirmed
+// the plans and before anything is changed, so that the command is recorded
+// however it ends.
+func startAudit(cmd *cobra.Command, l *glambda.Lambda, functions []string, plans []glambda.Plan) error {
+	a := commandAudit(cmd)
+	if a == nil {
+		return nil
+	}
+	record := l.NewAuditRecord(cmd.Context(), cmd.Name(), functions)
+	if len(plans) > 0 {
+		hash, err := glambda.PlanSHA256(plans...)
+		if err != nil {
+			return err
+		}
+		record.PlanSHA256 = hash
+	}
+	a.opts = accountOptions(cmd)
+	if sandbox, _ := cmd.Flags().GetBool("sandbox"); sandbox {
+		record.Sandbox = true
+		a.opts = append(a.opts, glambdatest.Sandbox())
+	}
+	a.record = &record
+	return nil
+}
+
+// startConfigAudit starts the audit record of a deploy of every function in
+// cfg, identified by the plans the deploy is about to apply.
+func startConfigAudit(cmd *cobra.Command, cfg glambda.Config, plans []glambda.Plan, opts []glambda.DeployOptions) error {
+	if commandAudit(cmd) == nil {
+		return nil
+	}
+	l, err := glambda.NewLambdaWithContext(cmd.Context(), cfg.Functions[0].Name, "", opts...)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, fn := range cfg.Functions {
+		names = append(names, fn.Name)
+	}
+	return startAudit(cmd, l, names, plans)
+}
+
+// auditResults adds the versions that were published to the audit record.
+func auditResults(cmd *cobra.Command, results []glambda.DeployResult) {
+	a := commandAudit(cmd)
+	if a == nil || a.record == nil {
+		return
+	}
+	for _, r := range results {
+		if r.VersionARN != "" {
+			a.record.VersionARNs = append(a.record.VersionARNs, r.VersionARN)
+		}
+	}
+}
+
+// recordAudit appends the command's audit record to the log, if it got as far
+// as changing anything. Unlike metrics, a record that can't be kept fails the
+// command, since teams with change management obligations need the log to
+// be complete.
+func recordAudit(cmd *cobra.Command, a *audit, err error) error {
+	if cmd == nil || a.record == nil {
+		return nil
+	}
+	a.record.Success = err == nil
+	if err != nil {
+		a.record.Error = err.Error()
+	}
+	log, err := glambda.OpenAuditLog(auditLocation(cmd), a.opts...)
+	if err == nil {
+		// An interrupted command is recorded too.
+		err = log.Append(context.WithoutCancel(cmd.Context()), *a.record)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to record the command in the audit log: %w", err)
+	}
+	return nil
+}
+
+func AuditCommand() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Read the audit log of commands that changed AWS resources.",
+	}
+	tailCmd := &cobra.Command{
+		Use:          "tail",
+		Short:        "Print the most recent records of the audit log.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example: `GLAMBDA_AUDIT_LOG=s3://audit-bucket/glambda glambda audit tail
+glambda audit tail --audit-log dynamodb://glambda-audit -n 50 --output-format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			location := auditLocation(cmd)
+			if location == "" {
+				return fmt.Errorf("no audit log to read, set %s or pass --audit-log", AuditLogEnv)
+			}
+			n, _ := cmd.Flags().GetInt("lines")
+			log, err := glambda.OpenAuditLog(location, accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			records, err := log.Tail(cmd.Context(), n)
+			if err != nil {
+				return err
+			}
+			format, _ := cmd.Flags().GetString("output-format")
+			return printAuditRecords(cmd, format, records)
+		},
+	}
+	tailCmd.Flags().IntP("lines", "n", 20, "How many of the most recent records to print.")
+	tailCmd.Flags().String("output-format", "text", "Format of the records, text or json.")
+	auditCmd.AddCommand(tailCmd)
+	return auditCmd
+}
+
+func printAuditRecords(cmd *cobra.Command, format string, records []glambda.AuditRecord) error {
+	if format == "json" {
+		data, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	for _, r := range records {
+		result := "ok"
+		if !r.Success {
+			result = "failed: " + r.Error
+		}
+		if r.Sandbox {
+			result += " (sandbox)"
+		}
+		plan := r.PlanSHA256
+		if len(plan) > 12 {
+			plan = plan[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Time.Format("2006-01-02T15:04:05Z"), r.Command, strings.Join(r.Functions, ","), r.Actor, plan, result)
+	}
+	return w.Flush()
+}
@@ -1,13 +1,28 @@
 package command
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 type CommandOptions func(*cobra.Command) error
@@ -19,6 +34,16 @@ func WithOutput(w io.Writer) CommandOptions {
 	}
 }
 
+// WithInput sets where commands read answers to confirmation prompts, and
+// payloads piped to invoke, from. Input that isn't a terminal, other than
+// that given here, is never prompted.
+func WithInput(r io.Reader) CommandOptions {
+	return func(cmd *cobra.Command) error {
+		cmd.SetIn(r)
+		return nil
+	}
+}
+
 func WithPackagePath(path string) CommandOptions {
 	return func(cmd *cobra.Command) error {
 		return cmd.Flags().Set("output", path)
@@ -31,10 +56,29 @@ func Main(args []string, opts ...CommandOptions) error {
 		Short: "A tool for deploying Go binaries as AWS Lambda functions.",
 	}
 	rootCmd.SetArgs(args)
+	rootCmd.PersistentFlags().String("region", "", "AWS region to use, instead of the one from the environment or the profile.")
+	rootCmd.PersistentFlags().String("profile", "", "Named profile from the shared AWS config and credentials files to use.")
+	rootCmd.PersistentFlags().String("audit-log", "", "Audit log to record commands that change AWS resources in: a file, s3://bucket/prefix, dynamodb://table or logs://group. Overrides "+AuditLogEnv+".")
+	rootCmd.PersistentFlags().String("assume-role", "", "ARN of an IAM role to assume, e.g. to deploy into another account.")
+	rootCmd.PersistentFlags().String("external-id", "", "External ID to pass when assuming the --assume-role role, if its trust policy requires one.")
 	commands := []*cobra.Command{
 		DeployCommand(),
+		UpCommand(),
 		DeleteCommand(),
 		PackageCommand(),
+		CheckCommand(),
+		BenchCommand(),
+		QueryCommand(),
+		DepsCommand(),
+		DashboardCommand(),
+		DescribeCommand(),
+		HealthCheckCommand(),
+		UpgradeCommand(),
+		VersionCommand(),
+		AuditCommand(),
+		EnvCommand(),
+		InvokeCommand(),
+		LogsCommand(),
 	}
 	for _, opt := range opts {
 		err := opt(rootCmd)
@@ -54,47 +98,1020 @@ func Main(args []string, opts ...CommandOptions) error {
 	}
 	rootCmd.SetHelpCommand(&cobra.Command{Use: "no-help", Run: func(cmd *cobra.Command, args []string) {}})
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// After the first interrupt, a second one kills the process as usual.
+		<-ctx.Done()
+		stop()
+	}()
+	auditing := &audit{}
+	rootCmd.SetContext(context.WithValue(ctx, auditKey{}, auditing))
+	start := time.Now()
+	executed, err := rootCmd.ExecuteC()
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		err = fmt.Errorf("interrupted, resources changed before the interrupt were left in place: %w", err)
+	}
+	recordMetrics(executed, start, err)
+	return errors.Join(err, recordAudit(executed, auditing, err))
 }
 
 func DeployCommand() *cobra.Command {
 	var deployCmd = &cobra.Command{
 		Use:          "deploy functionName sourceCodePath",
 		Short:        "Package a Go binary and upload it as a lambda function.",
-		Args:         cobra.ExactArgs(2),
+		Args:         deployArgs,
 		SilenceUsage: true,
-		Example:      `glambda deploy myFunctionName /path/to/sourceCode.go`,
+		Example: `glambda deploy myFunctionName /path/to/sourceCode.go
+glambda deploy myFunctionName ./... --select cmd/worker
+glambda deploy --all ./functions/...
+glambda deploy --interactive
+glambda deploy --config glambda.yaml`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			planFormat, _ := cmd.Flags().GetString("plan-format")
+			outputFormat, _ := cmd.Flags().GetString("output-format")
+			policyBundle, _ := cmd.Flags().GetString("policy-bundle")
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("invalid --output-format %q, expected text or json", outputFormat)
+			}
+			if configPath, _ := cmd.Flags().GetString("config"); configPath != "" {
+				sandbox, _ := cmd.Flags().GetBool("sandbox")
+				return deployConfig(cmd, configPath, sandbox, dryRun, planFormat, outputFormat, policyBundle)
+			}
+			if all, _ := cmd.Flags().GetBool("all"); all {
+				return deployAll(cmd, args[0], dryRun, planFormat, outputFormat, policyBundle)
+			}
+			wizard, _ := cmd.Flags().GetBool("interactive")
+			if wizard {
+				var err error
+				args, err = runWizard(cmd, args)
+				if err != nil {
+					return err
+				}
+			}
 			functionName := args[0]
-			sourceCodePath := args[1]
-			managedPolicies, _ := cmd.Flags().GetString("managed-policies")
-			inlinePolicy, _ := cmd.Flags().GetString("inline-policy")
-			resourcePolicy, _ := cmd.Flags().GetString("resource-policy")
-			return glambda.Deploy(functionName, sourceCodePath,
-				glambda.WithManagedPolicies(managedPolicies),
-				glambda.WithInlinePolicy(inlinePolicy),
-				glambda.WithResourcePolicy(resourcePolicy),
-			)
+			vulnCheck, _ := cmd.Flags().GetString("vuln-check")
+			var sourceCodePath string
+			if bootstrap, _ := cmd.Flags().GetString("bootstrap"); bootstrap != "" {
+				if vulnCheck != "" {
+					return fmt.Errorf("--vuln-check needs Go source, it can't check a prebuilt --bootstrap")
+				}
+			} else {
+				sourceCodePath = args[1]
+			}
+			if glambda.IsHandlerPattern(sourceCodePath) {
+				selection, _ := cmd.Flags().GetString("select")
+				var err error
+				sourceCodePath, err = selectHandler(cmd, sourceCodePath, selection)
+				if err != nil {
+					return err
+				}
+			}
+			sandbox, _ := cmd.Flags().GetBool("sandbox")
+			if vulnCheck != "fail" {
+				err := checkVulnerabilities(cmd, sourceCodePath, vulnCheck)
+				if err != nil {
+					return err
+				}
+			}
+			opts, err := deployOptions(cmd, outputFormat)
+			if err != nil {
+				return err
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, sourceCodePath, opts...)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				plan, err := l.Plan(cmd.Context())
+				if err != nil {
+					return err
+				}
+				err = printPlans(cmd, planFormat, []glambda.Plan{plan})
+				if err != nil || policyBundle == "" {
+					return err
+				}
+				return glambda.CheckPolicies(policyBundle, plan)
+			}
+			var plans []glambda.Plan
+			summaryFile, _ := cmd.Flags().GetString("summary-file")
+			if wizard || commandAudit(cmd) != nil || summaryFile != "" {
+				plan, err := l.Plan(cmd.Context())
+				if err != nil {
+					return err
+				}
+				plans = []glambda.Plan{plan}
+				if policyBundle != "" {
+					err = glambda.CheckPolicies(policyBundle, plan)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			if wizard {
+				err = printPlans(cmd, "text", plans)
+				if err != nil {
+					return err
+				}
+			}
+			yes, _ := cmd.Flags().GetBool("yes")
+			err = confirm(cmd, fmt.Sprintf("deploying %s to %s", functionName, l.Destination(cmd.Context())), yes)
+			if err != nil {
+				return err
+			}
+			err = startAudit(cmd, l, []string{functionName}, plans)
+			if err != nil {
+				return err
+			}
+			start := time.Now()
+			var results []glambda.DeployResult
+			if plans != nil {
+				// The reviewed plan is applied as it is, rather than built again.
+				results, err = glambda.ApplyAll(cmd.Context(), plans, 1)
+			} else {
+				err = l.Deploy(cmd.Context())
+				if err != nil {
+					return err
+				}
+				var result glambda.DeployResult
+				result, err = l.Publish(cmd.Context())
+				results = []glambda.DeployResult{result}
+			}
+			auditResults(cmd, results)
+			summaryErr := writeSummary(cmd, sandbox, start, plans, results, err)
+			var failed *glambda.SmokeTestError
+			if err != nil && !errors.As(err, &failed) {
+				return errors.Join(err, summaryErr)
+			}
+			return errors.Join(printResults(cmd, outputFormat, sandbox, results), err, summaryErr)
 		},
 	}
 	deployCmd.Flags().String("managed-policies", "", "Managed policies to attach to the lambda function.")
 	deployCmd.Flags().String("inline-policy", "", "Inline policy to attach to the lambda function.")
 	deployCmd.Flags().String("resource-policy", "", "Resource policy to attach to the lambda function.")
+	deployCmd.Flags().String("role-name-template", "", "Template for the execution role name, e.g. '{{.Function | lower}}-exec'.")
+	deployCmd.Flags().String("policy-name-template", "", "Template for the inline policy name, e.g. '{{.Function}}-inline-{{.Hash}}'.")
+	deployCmd.Flags().String("statement-id-template", "", "Template for the resource policy statement ID.")
+	deployCmd.Flags().String("role-description", "", "Description of the execution role, if glambda creates it. Defaults to naming the function and date.")
+	deployCmd.Flags().String("deploy-id", "", "ID sent in the user agent of every AWS call, to find the deploy's events in CloudTrail. Generated if not set.")
+	deployCmd.Flags().Bool("sandbox", false, "Run the full deploy against mocked AWS clients, without credentials or changes.")
+	deployCmd.Flags().StringArray("env", nil, "Environment variable to set on the lambda function, as KEY=VALUE. May be repeated.")
+	deployCmd.Flags().String("env-file", "", "File of KEY=VALUE lines to set as the lambda function's environment.")
+	deployCmd.Flags().String("kms-key", "", "ARN of a customer managed KMS key to encrypt the lambda function's environment variables with.")
+	deployCmd.Flags().Bool("kms-grants", false, "Let the lambda function use the KMS keys encrypting the S3 buckets and SQS queues its policies name.")
+	deployCmd.Flags().StringSlice("subnets", nil, "IDs of the subnets to connect the lambda function to a VPC through. Comma separated, needs --security-groups.")
+	deployCmd.Flags().StringSlice("security-groups", nil, "IDs of the security groups of the lambda function in a VPC. Comma separated, needs --subnets.")
+	deployCmd.Flags().StringToString("tags", nil, "Tags for the lambda function and its execution role, as KEY=VALUE. May be repeated or comma separated.")
+	deployCmd.Flags().String("arch", "arm64", "Architecture to build for and run the lambda function on, arm64 or x86_64.")
+	deployCmd.Flags().String("bootstrap", "", "Deploy a prebuilt provided.al2023 executable, e.g. from Rust or Zig, instead of building Go source.")
+	deployCmd.Flags().Int("memory", 0, "Memory in MB available to the lambda function, between 128 and 10240. Defaults to 128 on create.")
+	deployCmd.Flags().Duration("timeout", 0, "Maximum run time of each invocation, e.g. 30s, up to 15m. Defaults to 3s on create.")
+	deployCmd.Flags().Int("max-payload-kb", 0, "Size in KB of the largest request or message the lambda function expects. The plan warns when a trigger can't deliver it.")
+	addIncludeFlag(deployCmd)
+	addBuildFlags(deployCmd)
+	deployCmd.Flags().StringArray("template-var", nil, "Render the handler source as a Go template with this KEY=VALUE. May be repeated.")
+	deployCmd.Flags().Bool("strict-templates", false, "Fail if the handler template refers to a variable not given with --template-var.")
+	deployCmd.Flags().String("function-url", "", "Give the lambda function an HTTPS endpoint, with auth type NONE (public) or AWS_IAM.")
+	deployCmd.Flags().Lookup("function-url").NoOptDefVal = "NONE"
+	deployCmd.Flags().StringSlice("cors-origins", nil, "Origins allowed to call the function URL from a browser, e.g. https://example.com.")
+	deployCmd.Flags().String("invoke-mode", "", "How the function URL returns responses, BUFFERED (the default) or RESPONSE_STREAM.")
+	deployCmd.Flags().Bool("http-api", false, "Route requests to the lambda function through an API Gateway HTTP API.")
+	deployCmd.Flags().StringArray("route", nil, "HTTP API route to send to the lambda function, e.g. 'GET /orders'. May be repeated. Defaults to every request.")
+	deployCmd.Flags().String("s3-trigger", "", "Invoke the lambda function on events in this S3 bucket.")
+	deployCmd.Flags().StringSlice("s3-events", nil, "S3 events that invoke the lambda function. Defaults to s3:ObjectCreated:*.")
+	deployCmd.Flags().String("s3-prefix", "", "Only invoke the lambda function for object keys with this prefix, e.g. uploads/.")
+	deployCmd.Flags().String("s3-suffix", "", "Only invoke the lambda function for object keys with this suffix, e.g. .jpg.")
+	deployCmd.Flags().String("sqs-trigger", "", "ARN of an SQS queue whose messages invoke the lambda function.")
+	deployCmd.Flags().Int("sqs-batch-size", 0, "Most SQS messages sent to the lambda function in one invocation. Defaults to 10.")
+	deployCmd.Flags().Int("sqs-batching-window", 0, "Seconds, up to 300, to gather SQS messages before invoking the lambda function with a batch that isn't full.")
+	deployCmd.Flags().Int("sqs-max-concurrency", 0, "Most instances of the lambda function the SQS trigger invokes at once, between 2 and 1000.")
+	deployCmd.Flags().Bool("report-batch-item-failures", false, "Let the lambda function report which records of an SQS or stream batch failed, so only those are retried.")
+	deployCmd.Flags().StringArray("event-filter", nil, "Filter pattern, e.g. '{\"body\":{\"type\":[\"order\"]}}', that SQS or stream records must match to invoke the lambda function. May be repeated.")
+	deployCmd.Flags().Bool("fix-queue", false, "Raise the --sqs-trigger queue's visibility timeout to six times the lambda function's timeout, if it is shorter.")
+	deployCmd.Flags().String("dynamodb-trigger", "", "ARN of a DynamoDB stream whose records invoke the lambda function.")
+	deployCmd.Flags().String("kinesis-trigger", "", "ARN of a Kinesis data stream whose records invoke the lambda function.")
+	deployCmd.Flags().String("starting-position", "LATEST", "Where to start reading a stream trigger, TRIM_HORIZON or LATEST.")
+	deployCmd.Flags().Int("stream-batch-size", 0, "Most stream records sent to the lambda function in one invocation. Defaults to 100.")
+	deployCmd.Flags().Int("parallelization-factor", 0, "Batches processed concurrently from each shard of a stream trigger, between 1 and 10.")
+	deployCmd.Flags().String("on-failure", "", "ARN of an SQS queue or SNS topic told about stream batches that fail to process.")
+	deployCmd.Flags().Int("reserved-concurrency", 0, "Concurrent executions reserved for the lambda function, also the most it can run at once. 0 stops all invocations.")
+	deployCmd.Flags().Int("provisioned-concurrency", 0, "Instances of the new version to keep initialised behind --provisioned-alias, so they never cold start.")
+	deployCmd.Flags().String("provisioned-alias", glambda.LiveAlias, "Alias to provision concurrency on, pointed at each new version.")
+	deployCmd.Flags().String("dead-letter-queue", "", "ARN of an SQS queue or SNS topic sent asynchronous events that still fail after retries.")
+	deployCmd.Flags().String("async-on-success", "", "ARN of a queue, topic, function or event bus sent the result of each successful asynchronous invocation.")
+	deployCmd.Flags().String("async-on-failure", "", "ARN of a queue, topic, function or event bus sent the error of each failed asynchronous invocation.")
+	deployCmd.Flags().Int("canary", 0, "Percentage of traffic on the live alias to send to the new version while it bakes.")
+	deployCmd.Flags().Duration("bake-time", 10*time.Minute, "How long a canary runs before it is promoted, or rolled back if it reported errors.")
+	deployCmd.Flags().String("s3-upload", "", "Upload the package to this S3 bucket, rather than inline, for packages over 50MB.")
+	deployCmd.Flags().String("s3-upload-prefix", "", "Prefix for the keys of packages uploaded with --s3-upload, e.g. lambda/.")
+	deployCmd.Flags().String("select", "", "Handler to deploy when the source path is a pattern such as ./... matching several, e.g. cmd/worker.")
+	addVulnCheckFlag(deployCmd)
+	addKeepBuildDirFlag(deployCmd)
+	addAdoptFlag(deployCmd)
+	addAllowDowngradeFlag(deployCmd)
+	deployCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before deploying.")
+	deployCmd.Flags().Bool("dry-run", false, "Show the changes the deploy would make to AWS, without making them.")
+	deployCmd.Flags().String("runtime-calendar", "", "JSON runtime deprecation calendar to check against, instead of the one bundled with glambda.")
+	deployCmd.Flags().Int("runtime-warning-days", glambda.RuntimeWarningDays, "Warn in the plan when the lambda function's runtime is deprecated within this many days.")
+	addPlanFormatFlag(deployCmd)
+	addOutputFormatFlag(deployCmd)
+	addSummaryFileFlag(deployCmd)
+	addVerbosityFlags(deployCmd)
+	addPolicyBundleFlag(deployCmd)
+	addSmokeTestFlags(deployCmd)
+	deployCmd.Flags().String("config", "", "Deploy every function described in a glambda.yaml config file, instead of a single function.")
+	deployCmd.Flags().Bool("all", false, "Deploy every handler matched by a pattern such as ./functions/..., each named after its directory.")
+	deployCmd.Flags().BoolP("interactive", "i", false, "Answer questions about the function and its triggers, rather than passing flags, then review the plan before deploying.")
+	deployCmd.MarkFlagsMutuallyExclusive("all", "config", "interactive", "bootstrap")
+	addConcurrencyFlag(deployCmd)
+	deployCmd.Flags().StringSlice("fault-injection", nil, "Make an AWS operation fail transiently in the sandbox, as Operation=count (e.g. CreateRole=2).")
 	return deployCmd
 }
 
+// ErrCancelled is returned when the user declines a confirmation prompt.
+var ErrCancelled = errors.New("cancelled, nothing was changed")
+
+// confirm shows which account and region a command is about to change. When
+// the user is at a terminal, and hasn't passed --yes, they must also agree
+// before it goes ahead. Scripts and CI, which have no terminal, just get the
+// description.
+func confirm(cmd *cobra.Command, description string, yes bool) error {
+	if yes || !interactive(cmd.InOrStdin()) {
+		cmd.Println(description)
+		return nil
+	}
+	cmd.Printf("%s - continue? [y/N] ", description)
+	answer, err := readLine(cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return ErrCancelled
+}
+
+// selectHandler resolves a ./... pattern to one handler. When the pattern
+// matches several and none was selected, a user at a terminal picks one from
+// the list; anywhere else the candidates are listed in the error.
+func selectHandler(cmd *cobra.Command, pattern, selection string) (string, error) {
+	handler, err := glambda.SelectHandler(pattern, selection)
+	var ambiguous *glambda.AmbiguousHandlerError
+	if !errors.As(err, &ambiguous) || !interactive(cmd.InOrStdin()) {
+		return handler, err
+	}
+	cmd.Printf("%s matches several handlers:\n", pattern)
+	for i, c := range ambiguous.Candidates {
+		cmd.Printf("  %d) %s\n", i+1, c)
+	}
+	cmd.Print("deploy which? ")
+	answer, err := readLine(cmd.InOrStdin())
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(ambiguous.Candidates) {
+		return "", ErrCancelled
+	}
+	return ambiguous.Candidates[n-1], nil
+}
+
+// readLine reads up to the next newline a byte at a time, rather than through
+// a buffer, so that several prompts can share the same input.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func interactive(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return true
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Environment builds a lambda function's environment from an optional env
+// file and a list of KEY=VALUE pairs. Pairs take precedence over the file.
+func Environment(envFile string, pairs []string) (map[string]string, error) {
+	env := map[string]string{}
+	if envFile != "" {
+		f, err := os.Open(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading env file, %w", err)
+		}
+		defer f.Close()
+		env, err = ParseEnvFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading env file %s, %w", envFile, err)
+		}
+	}
+	err := parsePairs(env, pairs, "environment variable")
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// parsePairs adds KEY=VALUE pairs to m, overriding any existing keys.
+func parsePairs(m map[string]string, pairs []string, kind string) error {
+	for _, pair := range pairs {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || k == "" {
+			return fmt.Errorf("invalid %s %q, expected KEY=VALUE", kind, pair)
+		}
+		m[k] = v
+	}
+	return nil
+}
+
+// ParseEnvFile reads environment variables in the common .env format: one
+// KEY=VALUE per line, with blank lines and lines starting with # ignored. An
+// optional leading "export " is allowed, and values may be wrapped in single or
+// double quotes.
+func ParseEnvFile(r io.Reader) (map[string]string, error) {
+	env := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		k, v, found := strings.Cut(text, "=")
+		k = strings.TrimSpace(k)
+		if !found || k == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE, got %q", line, scanner.Text())
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		env[k] = v
+	}
+	return env, scanner.Err()
+}
+
+// accountOptions applies --region and --profile, and assumes the role given
+// with --assume-role, for every command that talks to AWS.
+func accountOptions(cmd *cobra.Command) []glambda.DeployOptions {
+	var opts []glambda.DeployOptions
+	if loadOpts := awsConfigOptions(cmd); len(loadOpts) > 0 {
+		opts = append(opts, func(l *glambda.Lambda) error {
+			cfg, err := config.LoadDefaultConfig(cmd.Context(), loadOpts...)
+			if err != nil {
+				return err
+			}
+			return glambda.WithAWSConfig(cfg)(l)
+		})
+	}
+	role, _ := cmd.Flags().GetString("assume-role")
+	externalID, _ := cmd.Flags().GetString("external-id")
+	if role != "" || externalID != "" {
+		opts = append(opts, glambda.WithAssumeRole(role, externalID))
+	}
+	return opts
+}
+
+// awsConfigOptions turns --region and --profile into options for loading the
+// AWS config, which is otherwise read from the environment alone.
+func awsConfigOptions(cmd *cobra.Command) []func(*config.LoadOptions) error {
+	var opts []func(*config.LoadOptions) error
+	if region, _ := cmd.Flags().GetString("region"); region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	return opts
+}
+
+// deployOptions translates the deploy command's flags into options for every
+// function it deploys.
+func deployOptions(cmd *cobra.Command, outputFormat string) ([]glambda.DeployOptions, error) {
+	managedPolicies, _ := cmd.Flags().GetString("managed-policies")
+	inlinePolicy, _ := cmd.Flags().GetString("inline-policy")
+	resourcePolicy, _ := cmd.Flags().GetString("resource-policy")
+	sandbox, _ := cmd.Flags().GetBool("sandbox")
+	vulnCheck, _ := cmd.Flags().GetString("vuln-check")
+	opts := append(accountOptions(cmd),
+		glambda.WithManagedPolicies(managedPolicies),
+		glambda.WithInlinePolicy(inlinePolicy),
+		glambda.WithResourcePolicy(resourcePolicy),
+	)
+	naming := glambda.NamingConvention{}
+	naming.Role, _ = cmd.Flags().GetString("role-name-template")
+	naming.Policy, _ = cmd.Flags().GetString("policy-name-template")
+	naming.Statement, _ = cmd.Flags().GetString("statement-id-template")
+	if naming != (glambda.NamingConvention{}) {
+		opts = append(opts, glambda.WithNamingConvention(naming))
+	}
+	envFile, _ := cmd.Flags().GetString("env-file")
+	envVars, _ := cmd.Flags().GetStringArray("env")
+	if envFile != "" || len(envVars) > 0 {
+		env, err := Environment(envFile, envVars)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, glambda.WithEnvironment(env))
+	}
+	if kmsKey, _ := cmd.Flags().GetString("kms-key"); kmsKey != "" {
+		opts = append(opts, glambda.WithKMSKey(kmsKey))
+	}
+	if kmsGrants, _ := cmd.Flags().GetBool("kms-grants"); kmsGrants {
+		opts = append(opts, glambda.WithKMSGrants())
+	}
+	subnets, _ := cmd.Flags().GetStringSlice("subnets")
+	securityGroups, _ := cmd.Flags().GetStringSlice("security-groups")
+	if len(subnets) > 0 || len(securityGroups) > 0 {
+		opts = append(opts, glambda.WithVPCConfig(subnets, securityGroups))
+	}
+	if description, _ := cmd.Flags().GetString("role-description"); description != "" {
+		opts = append(opts, glambda.WithDescription(description))
+	}
+	opts = append(opts, smokeTestOptions(cmd)...)
+	if bootstrap, _ := cmd.Flags().GetString("bootstrap"); bootstrap != "" {
+		opts = append(opts, glambda.WithBootstrap(bootstrap))
+	}
+	if deployID, _ := cmd.Flags().GetString("deploy-id"); deployID != "" {
+		opts = append(opts, glambda.WithDeployID(deployID))
+	}
+	if cmd.Flags().Changed("reserved-concurrency") {
+		n, _ := cmd.Flags().GetInt("reserved-concurrency")
+		opts = append(opts, glambda.WithReservedConcurrency(n))
+	}
+	if cmd.Flags().Changed("provisioned-concurrency") {
+		n, _ := cmd.Flags().GetInt("provisioned-concurrency")
+		alias, _ := cmd.Flags().GetString("provisioned-alias")
+		opts = append(opts, glambda.WithProvisionedConcurrency(alias, n))
+	}
+	if dlq, _ := cmd.Flags().GetString("dead-letter-queue"); dlq != "" {
+		opts = append(opts, glambda.WithDeadLetterQueue(dlq))
+	}
+	onSuccess, _ := cmd.Flags().GetString("async-on-success")
+	onFailure, _ := cmd.Flags().GetString("async-on-failure")
+	if onSuccess != "" || onFailure != "" {
+		opts = append(opts, glambda.WithDestinations(onSuccess, onFailure))
+	}
+	if calendar, _ := cmd.Flags().GetString("runtime-calendar"); calendar != "" {
+		opts = append(opts, glambda.WithRuntimeCalendar(calendar))
+	}
+	if cmd.Flags().Changed("runtime-warning-days") {
+		days, _ := cmd.Flags().GetInt("runtime-warning-days")
+		opts = append(opts, glambda.WithRuntimeWarningDays(days))
+	}
+	arch, _ := cmd.Flags().GetString("arch")
+	opts = append(opts, glambda.WithArchitecture(arch))
+	if include, _ := cmd.Flags().GetStringArray("include"); len(include) > 0 {
+		opts = append(opts, glambda.WithAssets(include...))
+	}
+	if sensitive, _ := cmd.Flags().GetBool("include-sensitive"); sensitive {
+		opts = append(opts, glambda.WithSensitiveAssets())
+	}
+	if exclude, _ := cmd.Flags().GetStringSlice("exclude"); len(exclude) > 0 {
+		opts = append(opts, glambda.WithExcludes(exclude...))
+	}
+	if flags := buildFlags(cmd); len(flags) > 0 {
+		opts = append(opts, glambda.WithBuildFlags(flags...))
+	}
+	if cgo, cc := cgoFlags(cmd); cgo {
+		opts = append(opts, glambda.WithCGO(cc))
+	}
+	templateVars, _ := cmd.Flags().GetStringArray("template-var")
+	strictTemplates, _ := cmd.Flags().GetBool("strict-templates")
+	if len(templateVars) > 0 || strictTemplates {
+		data := map[string]string{}
+		err := parsePairs(data, templateVars, "template variable")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, glambda.WithTemplateData(data, strictTemplates))
+	}
+	if cmd.Flags().Changed("memory") {
+		memory, _ := cmd.Flags().GetInt("memory")
+		opts = append(opts, glambda.WithMemory(memory))
+	}
+	if cmd.Flags().Changed("timeout") {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		opts = append(opts, glambda.WithTimeout(timeout))
+	}
+	if cmd.Flags().Changed("max-payload-kb") {
+		kb, _ := cmd.Flags().GetInt("max-payload-kb")
+		opts = append(opts, glambda.WithMaxPayload(kb))
+	}
+	if cmd.Flags().Changed("tags") {
+		tags, _ := cmd.Flags().GetStringToString("tags")
+		opts = append(opts, glambda.WithTags(tags))
+	}
+	if vulnCheck == "fail" {
+		opts = append(opts, glambda.WithVulnCheck())
+	}
+	if adopt, _ := cmd.Flags().GetBool("adopt"); adopt {
+		opts = append(opts, glambda.WithAdopt())
+	}
+	if allow, _ := cmd.Flags().GetBool("allow-downgrade"); allow {
+		opts = append(opts, glambda.WithAllowDowngrade())
+	}
+	if keep, _ := cmd.Flags().GetBool("keep-build-dir"); keep {
+		opts = append(opts, glambda.WithKeepBuildDir(cmd.ErrOrStderr()))
+	}
+	if reporter := progressReporter(cmd, outputFormat); reporter != nil {
+		opts = append(opts, glambda.WithProgress(reporter))
+	}
+	if bucket, _ := cmd.Flags().GetString("s3-upload"); bucket != "" {
+		prefix, _ := cmd.Flags().GetString("s3-upload-prefix")
+		opts = append(opts, glambda.WithS3Upload(bucket, prefix))
+	}
+	if sandbox {
+		opts = append(opts, glambdatest.Sandbox())
+	}
+	faults, _ := cmd.Flags().GetStringSlice("fault-injection")
+	for _, fault := range faults {
+		op, n, err := ParseFault(fault)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, glambda.WithFaultInjection(op, n))
+	}
+	if policyBundle, _ := cmd.Flags().GetString("policy-bundle"); policyBundle != "" {
+		opts = append(opts, glambda.WithPolicyBundle(policyBundle))
+	}
+	if cmd.Flags().Changed("function-url") {
+		authType, _ := cmd.Flags().GetString("function-url")
+		origins, _ := cmd.Flags().GetStringSlice("cors-origins")
+		opts = append(opts, glambda.WithFunctionURL(authType, origins...))
+	}
+	if mode, _ := cmd.Flags().GetString("invoke-mode"); mode != "" {
+		opts = append(opts, glambda.WithInvokeMode(mode))
+	}
+	if httpAPI, _ := cmd.Flags().GetBool("http-api"); httpAPI {
+		routes, _ := cmd.Flags().GetStringArray("route")
+		opts = append(opts, glambda.WithHTTPAPI(routes...))
+	}
+	if queue, _ := cmd.Flags().GetString("sqs-trigger"); queue != "" {
+		batchSize, _ := cmd.Flags().GetInt("sqs-batch-size")
+		opts = append(opts, glambda.WithSQSTrigger(queue, batchSize, sqsOptions(cmd)...))
+		if fix, _ := cmd.Flags().GetBool("fix-queue"); fix {
+			opts = append(opts, glambda.WithFixQueue())
+		}
+	}
+	streamOpts := streamOptions(cmd)
+	if stream, _ := cmd.Flags().GetString("dynamodb-trigger"); stream != "" {
+		position, _ := cmd.Flags().GetString("starting-position")
+		batchSize, _ := cmd.Flags().GetInt("stream-batch-size")
+		opts = append(opts, glambda.WithDynamoStreamTrigger(stream, position, batchSize, streamOpts...))
+	}
+	if stream, _ := cmd.Flags().GetString("kinesis-trigger"); stream != "" {
+		position, _ := cmd.Flags().GetString("starting-position")
+		batchSize, _ := cmd.Flags().GetInt("stream-batch-size")
+		opts = append(opts, glambda.WithKinesisTrigger(stream, position, batchSize, streamOpts...))
+	}
+	if bucket, _ := cmd.Flags().GetString("s3-trigger"); bucket != "" {
+		events, _ := cmd.Flags().GetStringSlice("s3-events")
+		prefix, _ := cmd.Flags().GetString("s3-prefix")
+		suffix, _ := cmd.Flags().GetString("s3-suffix")
+		opts = append(opts, glambda.WithS3Trigger(bucket, events, prefix, suffix))
+	}
+	if cmd.Flags().Changed("canary") {
+		percent, _ := cmd.Flags().GetInt("canary")
+		bakeTime, _ := cmd.Flags().GetDuration("bake-time")
+		opts = append(opts, glambda.WithCanary(percent, bakeTime))
+	}
+	return opts, nil
+}
+
+// deployArgs requires a function name and source path, unless the functions
+// to deploy come from a config file, or from a pattern with --all. The
+// interactive wizard asks for any that are missing.
+func deployArgs(cmd *cobra.Command, args []string) error {
+	if configPath, _ := cmd.Flags().GetString("config"); configPath != "" {
+		return cobra.NoArgs(cmd, args)
+	}
+	if all, _ := cmd.Flags().GetBool("all"); all {
+		return cobra.ExactArgs(1)(cmd, args)
+	}
+	if wizard, _ := cmd.Flags().GetBool("interactive"); wizard {
+		return cobra.MaximumNArgs(2)(cmd, args)
+	}
+	if bootstrap, _ := cmd.Flags().GetString("bootstrap"); bootstrap != "" {
+		return cobra.ExactArgs(1)(cmd, args)
+	}
+	return cobra.ExactArgs(2)(cmd, args)
+}
+
+func UpCommand() *cobra.Command {
+	var upCmd = &cobra.Command{
+		Use:          "up",
+		Short:        "Deploy every lambda function described in a glambda.yaml config file.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example:      `glambda up --config path/to/glambda.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, _ := cmd.Flags().GetString("config")
+			sandbox, _ := cmd.Flags().GetBool("sandbox")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			planFormat, _ := cmd.Flags().GetString("plan-format")
+			outputFormat, _ := cmd.Flags().GetString("output-format")
+			policyBundle, _ := cmd.Flags().GetString("policy-bundle")
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("invalid --output-format %q, expected text or json", outputFormat)
+			}
+			return deployConfig(cmd, configPath, sandbox, dryRun, planFormat, outputFormat, policyBundle)
+		},
+	}
+	upCmd.Flags().String("config", glambda.DefaultConfigFile, "Path to the config file.")
+	upCmd.Flags().Bool("dry-run", false, "Show the changes the deploy would make to AWS, without making them.")
+	addPlanFormatFlag(upCmd)
+	addOutputFormatFlag(upCmd)
+	addSummaryFileFlag(upCmd)
+	addVerbosityFlags(upCmd)
+	addPolicyBundleFlag(upCmd)
+	addSmokeTestFlags(upCmd)
+	addAdoptFlag(upCmd)
+	addAllowDowngradeFlag(upCmd)
+	addConcurrencyFlag(upCmd)
+	upCmd.Flags().Bool("sandbox", false, "Run the full deploy against mocked AWS clients, without credentials or changes.")
+	upCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before deploying.")
+	return upCmd
+}
+
+func deployConfig(cmd *cobra.Command, path string, sandbox, dryRun bool, planFormat, outputFormat, policyBundle string) error {
+	cfg, err := glambda.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	opts := accountOptions(cmd)
+	if sandbox {
+		opts = append(opts, glambdatest.Sandbox())
+	}
+	if adopt, _ := cmd.Flags().GetBool("adopt"); adopt {
+		opts = append(opts, glambda.WithAdopt())
+	}
+	if allow, _ := cmd.Flags().GetBool("allow-downgrade"); allow {
+		opts = append(opts, glambda.WithAllowDowngrade())
+	}
+	if policyBundle != "" {
+		opts = append(opts, glambda.WithPolicyBundle(policyBundle))
+	}
+	if reporter := progressReporter(cmd, outputFormat); reporter != nil {
+		opts = append(opts, glambda.WithProgress(reporter))
+	}
+	return deployFunctions(cmd, cfg, opts, sandbox, dryRun, planFormat, outputFormat, policyBundle)
+}
+
+// deployAll deploys every handler matched by a ./... style pattern, each named
+// after its directory, with the deploy command's flags applied to all of them.
+func deployAll(cmd *cobra.Command, pattern string, dryRun bool, planFormat, outputFormat, policyBundle string) error {
+	cfg, err := glambda.HandlerConfig(pattern)
+	if err != nil {
+		return err
+	}
+	vulnCheck, _ := cmd.Flags().GetString("vuln-check")
+	if vulnCheck != "fail" {
+		for _, fn := range cfg.Functions {
+			err := checkVulnerabilities(cmd, fn.Handler, vulnCheck)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fn.Name, err)
+			}
+		}
+	}
+	opts, err := deployOptions(cmd, outputFormat)
+	if err != nil {
+		return err
+	}
+	sandbox, _ := cmd.Flags().GetBool("sandbox")
+	return deployFunctions(cmd, cfg, opts, sandbox, dryRun, planFormat, outputFormat, policyBundle)
+}
+
+// confirmFunctions asks once, unless --yes is given, before every function in
+// cfg is deployed to the account and region opts resolve to.
+func confirmFunctions(cmd *cobra.Command, cfg glambda.Config, opts []glambda.DeployOptions) error {
+	if len(cfg.Functions) == 0 {
+		return nil
+	}
+	l, err := glambda.NewLambdaWithContext(cmd.Context(), cfg.Functions[0].Name, "", opts...)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, fn := range cfg.Functions {
+		names = append(names, fn.Name)
+	}
+	yes, _ := cmd.Flags().GetBool("yes")
+	return confirm(cmd, fmt.Sprintf("deploying %d functions (%s) to %s", len(names), strings.Join(names, ", "), l.Destination(cmd.Context())), yes)
+}
+
+// deployFunctions deploys every function in cfg, --concurrency at a time,
+// once the user confirms, or with --dry-run shows their plans. The functions
+// that deployed are reported even when others failed.
+func deployFunctions(cmd *cobra.Command, cfg glambda.Config, opts []glambda.DeployOptions, sandbox, dryRun bool, planFormat, outputFormat, policyBundle string) error {
+	if dryRun {
+		plans, err := glambda.PlanConfig(cmd.Context(), cfg, opts...)
+		printErr := printPlans(cmd, planFormat, plans)
+		if err != nil || printErr != nil || policyBundle == "" {
+			return errors.Join(err, printErr)
+		}
+		return glambda.CheckPolicies(policyBundle, plans...)
+	}
+	err := confirmFunctions(cmd, cfg, opts)
+	if err != nil {
+		return err
+	}
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	plans, err := glambda.PlanAll(cmd.Context(), cfg.Functions, concurrency, opts...)
+	if err != nil {
+		return err
+	}
+	err = startConfigAudit(cmd, cfg, plans, opts)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	results, err := glambda.ApplyAll(cmd.Context(), plans, concurrency)
+	auditResults(cmd, results)
+	return errors.Join(err, printResults(cmd, outputFormat, sandbox, results), writeSummary(cmd, sandbox, start, plans, results, err))
+}
+
+func addPlanFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("plan-format", "text", "Format of the --dry-run plan, text or json. JSON is always a list of plans, one per function.")
+}
+
+func addOutputFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("output-format", "text", "Format of the deploy result, text or json. JSON is always a list of results, one per function, with fully qualified ARNs.")
+}
+
+func addSummaryFileFlag(cmd *cobra.Command) {
+	cmd.Flags().String("summary-file", "", "Write a markdown summary of the deploy to this file, e.g. deploy-summary.md or $GITHUB_STEP_SUMMARY.")
+}
+
+// writeSummary writes the markdown summary of a deploy that started at start
+// to --summary-file, if it was given. Failed deploys are summarised too, so
+// that CI can show what went wrong.
+func writeSummary(cmd *cobra.Command, sandbox bool, start time.Time, plans []glambda.Plan, results []glambda.DeployResult, err error) error {
+	path, _ := cmd.Flags().GetString("summary-file")
+	if path == "" {
+		return nil
+	}
+	summary := glambda.DeploySummary{
+		Results:  results,
+		Plans:    plans,
+		Duration: time.Since(start),
+		Sandbox:  sandbox,
+		Err:      err,
+	}
+	err = os.WriteFile(path, []byte(summary.Markdown()), 0o644)
+	if err != nil {
+		return fmt.Errorf("unable to write the deploy summary: %w", err)
+	}
+	return nil
+}
+
+func addConcurrencyFlag(cmd *cobra.Command) {
+	cmd.Flags().Int("concurrency", glambda.DefaultConcurrency, "How many functions to deploy at once, from a config file or with --all.")
+}
+
+func addAdoptFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("adopt", false, "Deploy over existing functions that glambda didn't create, such as ones managed by CloudFormation or Terraform.")
+}
+
+func addAllowDowngradeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("allow-downgrade", false, "Deploy code built from an older git commit than the one that is live, e.g. to roll back on purpose.")
+}
+
+func addVerbosityFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("verbose", "v", false, "Report every step of the deploy in detail.")
+	cmd.Flags().BoolP("quiet", "q", false, "Don't report the steps of the deploy, only the result.")
+	cmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+}
+
+// progressReporter reports deploy steps on stderr, as text or, when the
+// result is JSON, as JSON lines for CI systems to follow. Quiet deploys
+// get no reporter at all. JSON always includes verbose events, for tools
+// to filter as they see fit.
+func progressReporter(cmd *cobra.Command, format string) glambda.Reporter {
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		return nil
+	}
+	if format == "json" {
+		return glambda.JSONReporter(cmd.ErrOrStderr())
+	}
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	return glambda.TextReporter(cmd.ErrOrStderr(), verbose)
+}
+
+// printResults reports what was deployed. The JSON form is written to stdout
+// on its own, so that tools can read the ARNs of published versions from it.
+func printResults(cmd *cobra.Command, format string, sandbox bool, results []glambda.DeployResult) error {
+	if format == "json" {
+		if results == nil {
+			results = []glambda.DeployResult{}
+		}
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+	for _, r := range results {
+		if r.FunctionURL != "" {
+			cmd.Printf("%s is available at %s\n", r.FunctionName, r.FunctionURL)
+		}
+		if r.HTTPAPIEndpoint != "" {
+			cmd.Printf("%s HTTP API is available at %s\n", r.FunctionName, r.HTTPAPIEndpoint)
+		}
+		if sandbox {
+			cmd.Printf("sandbox deploy of %s succeeded, no AWS resources were changed\n", r.FunctionName)
+		} else {
+			cmd.Printf("deployed %s version %s\n", r.FunctionName, r.VersionARN)
+		}
+	}
+	return nil
+}
+
+func addSmokeTestFlags(cmd *cobra.Command) {
+	cmd.Flags().String("smoke-test", "dry-run", "How to check the published version: dry-run checks it may be invoked, invoke runs it with --smoke-test-payload.")
+	cmd.Flags().String("smoke-test-payload", "", "JSON payload for an invoke smoke test. Defaults to {}.")
+	cmd.Flags().Bool("skip-test", false, "Don't smoke test the published version.")
+	cmd.MarkFlagsMutuallyExclusive("smoke-test", "skip-test")
+}
+
+// smokeTestOptions translates the smoke test flags, if any were given.
+func smokeTestOptions(cmd *cobra.Command) []glambda.DeployOptions {
+	if skip, _ := cmd.Flags().GetBool("skip-test"); skip {
+		return []glambda.DeployOptions{glambda.WithSmokeTest(glambda.SmokeTestSkip, nil)}
+	}
+	if !cmd.Flags().Changed("smoke-test") && !cmd.Flags().Changed("smoke-test-payload") {
+		return nil
+	}
+	mode, _ := cmd.Flags().GetString("smoke-test")
+	var payload []byte
+	if p, _ := cmd.Flags().GetString("smoke-test-payload"); p != "" {
+		payload = []byte(p)
+	}
+	return []glambda.DeployOptions{glambda.WithSmokeTest(glambda.SmokeTest(mode), payload)}
+}
+
+func addPolicyBundleFlag(cmd *cobra.Command) {
+	cmd.Flags().String("policy-bundle", "", "Directory of Rego policies the plan must pass, checked with conftest before anything is changed.")
+}
+
+// sqsOptions collects the settings of the SQS trigger.
+func sqsOptions(cmd *cobra.Command) []glambda.TriggerOption {
+	var opts []glambda.TriggerOption
+	if cmd.Flags().Changed("sqs-batching-window") {
+		seconds, _ := cmd.Flags().GetInt("sqs-batching-window")
+		opts = append(opts, glambda.WithBatchingWindow(seconds))
+	}
+	if cmd.Flags().Changed("sqs-max-concurrency") {
+		n, _ := cmd.Flags().GetInt("sqs-max-concurrency")
+		opts = append(opts, glambda.WithMaximumConcurrency(n))
+	}
+	if report, _ := cmd.Flags().GetBool("report-batch-item-failures"); report {
+		opts = append(opts, glambda.WithReportBatchItemFailures())
+	}
+	return append(opts, eventFilters(cmd)...)
+}
+
+// eventFilters are the filter patterns of both SQS and stream triggers.
+func eventFilters(cmd *cobra.Command) []glambda.TriggerOption {
+	var opts []glambda.TriggerOption
+	patterns, _ := cmd.Flags().GetStringArray("event-filter")
+	for _, pattern := range patterns {
+		opts = append(opts, glambda.WithEventFilter(pattern))
+	}
+	return opts
+}
+
+// streamOptions collects the settings shared by the DynamoDB and Kinesis
+// stream triggers.
+func streamOptions(cmd *cobra.Command) []glambda.TriggerOption {
+	var opts []glambda.TriggerOption
+	if cmd.Flags().Changed("parallelization-factor") {
+		n, _ := cmd.Flags().GetInt("parallelization-factor")
+		opts = append(opts, glambda.WithParallelizationFactor(n))
+	}
+	if dest, _ := cmd.Flags().GetString("on-failure"); dest != "" {
+		opts = append(opts, glambda.WithOnFailureDestination(dest))
+	}
+	if report, _ := cmd.Flags().GetBool("report-batch-item-failures"); report {
+		opts = append(opts, glambda.WithReportBatchItemFailures())
+	}
+	return append(opts, eventFilters(cmd)...)
+}
+
+// printPlans writes plans for humans, or as a JSON list for tools such as
+// policy engines to check.
+func printPlans(cmd *cobra.Command, format string, plans []glambda.Plan) error {
+	switch format {
+	case "text":
+		for _, plan := range plans {
+			cmd.Print(plan)
+		}
+		return nil
+	case "json":
+		if plans == nil {
+			plans = []glambda.Plan{}
+		}
+		data, err := json.MarshalIndent(plans, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(data))
+		return nil
+	}
+	return fmt.Errorf("invalid plan format %q, expected text or json", format)
+}
+
+// ParseFault parses a fault injection flag value of the form Operation=count.
+func ParseFault(s string) (string, int, error) {
+	op, count, found := strings.Cut(s, "=")
+	if !found || op == "" {
+		return "", 0, fmt.Errorf("invalid fault injection %q, expected Operation=count", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return "", 0, fmt.Errorf("invalid fault injection count in %q, expected a positive integer", s)
+	}
+	return op, n, nil
+}
+
 func DeleteCommand() *cobra.Command {
 	var deleteCmd = &cobra.Command{
 		Use:          "delete functionName",
-		Short:        "Delete a lambda function.",
+		Short:        "Delete a lambda function, its triggers and logs, and its execution role if glambda created it.",
 		Args:         cobra.ExactArgs(1),
 		SilenceUsage: true,
-		Example:      `glambda delete myFunctionName`,
+		Example:      `glambda delete myFunctionName --dry-run`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			functionName := args[0]
-			return glambda.Delete(functionName)
+			force, _ := cmd.Flags().GetBool("force")
+			yes, _ := cmd.Flags().GetBool("yes")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			planFormat, _ := cmd.Flags().GetString("plan-format")
+			opts := accountOptions(cmd)
+			if keepLogs, _ := cmd.Flags().GetBool("keep-logs"); keepLogs {
+				opts = append(opts, glambda.WithKeepLogs())
+			}
+			if deleteRole, _ := cmd.Flags().GetBool("delete-role"); deleteRole {
+				opts = append(opts, glambda.WithDeleteUnmanagedRole())
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, "", opts...)
+			if err != nil {
+				return err
+			}
+			plan, err := l.DeletePlan(cmd.Context(), force)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				return printPlans(cmd, planFormat, []glambda.Plan{plan})
+			}
+			err = printPlans(cmd, "text", []glambda.Plan{plan})
+			if err != nil {
+				return err
+			}
+			err = confirm(cmd, fmt.Sprintf("deleting %s from %s", functionName, l.Destination(cmd.Context())), yes)
+			if err != nil {
+				return err
+			}
+			err = startAudit(cmd, l, []string{functionName}, []glambda.Plan{plan})
+			if err != nil {
+				return err
+			}
+			err = l.Delete(cmd.Context(), force)
+			if errors.Is(err, glambda.ErrRoleNotManaged) {
+				cmd.Printf("deleted %s, but %v. Use --delete-role to delete it anyway.\n", functionName, err)
+				return nil
+			}
+			return err
 		},
 	}
+	deleteCmd.Flags().Bool("force", false, "Delete the function even if CloudFormation or Terraform manages it.")
+	deleteCmd.Flags().Bool("delete-role", false, "Also delete an execution role that glambda didn't create.")
+	deleteCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before deleting.")
+	deleteCmd.Flags().Bool("keep-logs", false, "Keep the lambda function's CloudWatch log group.")
+	deleteCmd.Flags().Bool("dry-run", false, "Show what would be deleted, without deleting it.")
+	addPlanFormatFlag(deleteCmd)
 	return deleteCmd
 }
 
@@ -104,7 +1121,8 @@ func PackageCommand() *cobra.Command {
 		Short:        "Package a Go binary as a ZIP'd bundle ready to upload to AWS.",
 		Args:         cobra.ExactArgs(1),
 		SilenceUsage: true,
-		Example:      `glambda package /path/to/sourceCode.go`,
+		Example: `glambda package /path/to/sourceCode.go
+glambda package /path/to/sourceCode.go --emit-module ./out`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			sourceCodePath := args[0]
 			sourceCodePath, err := filepath.Abs(sourceCodePath)
@@ -115,7 +1133,23 @@ func PackageCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("error getting output path, %w", err)
 			}
-			data, err := glambda.Package(sourceCodePath)
+			vulnCheck, _ := cmd.Flags().GetString("vuln-check")
+			err = checkVulnerabilities(cmd, sourceCodePath, vulnCheck)
+			if err != nil {
+				return err
+			}
+			arch, _ := cmd.Flags().GetString("arch")
+			include, _ := cmd.Flags().GetStringArray("include")
+			buildOpts := glambda.BuildOptions{Architecture: types.Architecture(arch), Assets: include}
+			buildOpts.AllowSensitiveAssets, _ = cmd.Flags().GetBool("include-sensitive")
+			buildOpts.Exclude, _ = cmd.Flags().GetStringSlice("exclude")
+			buildOpts.EmitModule, _ = cmd.Flags().GetString("emit-module")
+			buildOpts.Flags = buildFlags(cmd)
+			buildOpts.CGO, buildOpts.CC = cgoFlags(cmd)
+			if keep, _ := cmd.Flags().GetBool("keep-build-dir"); keep {
+				buildOpts.KeepBuildDir = cmd.ErrOrStderr()
+			}
+			data, err := glambda.PackageWith(sourceCodePath, buildOpts)
 			if err != nil {
 				return fmt.Errorf("error packaging lambda function, %w", err)
 			}
@@ -131,5 +1165,372 @@ func PackageCommand() *cobra.Command {
 		},
 	}
 	packageCmd.Flags().String("output", "package.zip", "Path to write the packaged lambda function.")
+	packageCmd.Flags().String("arch", "arm64", "Architecture to build for, arm64 or x86_64.")
+	packageCmd.Flags().String("emit-module", "", "Directory to copy the go.mod and go.sum the handler was built with to, for auditing dependency versions.")
+	addVulnCheckFlag(packageCmd)
+	addKeepBuildDirFlag(packageCmd)
+	addIncludeFlag(packageCmd)
+	addBuildFlags(packageCmd)
 	return packageCmd
 }
+
+func CheckCommand() *cobra.Command {
+	var checkCmd = &cobra.Command{
+		Use:          "check sourceCodePath",
+		Short:        "Check that a handler builds for Lambda, without packaging or deploying it.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `glambda check /path/to/sourceCode.go
+glambda check ./cmd/worker --arch x86_64`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			arch, _ := cmd.Flags().GetString("arch")
+			buildOpts := glambda.BuildOptions{Architecture: types.Architecture(arch)}
+			buildOpts.Flags = buildFlags(cmd)
+			buildOpts.CGO, buildOpts.CC = cgoFlags(cmd)
+			err := glambda.CheckHandler(args[0], buildOpts)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("%s builds for Lambda\n", args[0])
+			return nil
+		},
+	}
+	checkCmd.Flags().String("arch", "arm64", "Architecture to build for, arm64 or x86_64.")
+	addBuildFlags(checkCmd)
+	return checkCmd
+}
+
+func addBuildFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ldflags", "", "Linker flags for go build, e.g. '-X main.version=1.2.3'.")
+	cmd.Flags().StringSlice("build-tags", nil, "Build tags for go build, added to lambda.norpc. Comma separated.")
+	cmd.Flags().Bool("cgo", false, "Build with cgo, cross-compiling C with zig unless --cc is given.")
+	cmd.Flags().String("cc", "", "C compiler for a cgo build, e.g. 'zig cc -target aarch64-linux-gnu'. Implies --cgo.")
+}
+
+// cgoFlags reports whether --cgo or --cc asked for a cgo build, and the C
+// compiler to use.
+func cgoFlags(cmd *cobra.Command) (bool, string) {
+	cgo, _ := cmd.Flags().GetBool("cgo")
+	cc, _ := cmd.Flags().GetString("cc")
+	return cgo || cc != "", cc
+}
+
+// buildFlags turns --ldflags and --build-tags into go build flags.
+func buildFlags(cmd *cobra.Command) []string {
+	var flags []string
+	if ldflags, _ := cmd.Flags().GetString("ldflags"); ldflags != "" {
+		flags = append(flags, "-ldflags", ldflags)
+	}
+	if tags, _ := cmd.Flags().GetStringSlice("build-tags"); len(tags) > 0 {
+		flags = append(flags, "-tags", strings.Join(tags, ","))
+	}
+	return flags
+}
+
+func addIncludeFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("include", nil, "File or directory to add to the zip next to bootstrap, keeping its relative path. May be repeated.")
+	cmd.Flags().Bool("include-sensitive", false, "Allow .env files and .git directories among the included files, which are refused by default.")
+	cmd.Flags().StringSlice("exclude", nil, "Patterns for included files to leave out, such as '*.md,testdata/**'. A pattern without a slash matches a name at any depth.")
+}
+
+func addKeepBuildDirFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("keep-build-dir", false, "Keep the build directory for debugging, printing its path and the go build command used.")
+}
+
+func addVulnCheckFlag(cmd *cobra.Command) {
+	cmd.Flags().String("vuln-check", "", "Run govulncheck against the handler first, and either 'warn' about or 'fail' on reachable vulnerabilities.")
+	cmd.Flags().Lookup("vuln-check").NoOptDefVal = "fail"
+}
+
+// checkVulnerabilities runs govulncheck against the handler when asked to. In
+// warn mode findings are printed and packaging carries on, in fail mode they
+// are returned as an error.
+func checkVulnerabilities(cmd *cobra.Command, path, mode string) error {
+	switch mode {
+	case "":
+		return nil
+	case "warn", "fail":
+	default:
+		return fmt.Errorf("invalid --vuln-check mode %q, expected warn or fail", mode)
+	}
+	findings, err := glambda.VulnCheck(path)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	vulnErr := &glambda.VulnerabilityError{Findings: findings}
+	if mode == "fail" {
+		return vulnErr
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", vulnErr)
+	return nil
+}
+
+func BenchCommand() *cobra.Command {
+	var benchCmd = &cobra.Command{
+		Use:          "bench functionName",
+		Short:        "Measure cold and warm start latency of a deployed lambda function.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example:      `glambda bench myFunctionName --cold 10 --warm 50`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionName := args[0]
+			cold, _ := cmd.Flags().GetInt("cold")
+			warm, _ := cmd.Flags().GetInt("warm")
+			payload, _ := cmd.Flags().GetString("payload")
+			source, _ := cmd.Flags().GetString("source")
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, source, accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			err = checkFreshness(cmd, l)
+			if err != nil {
+				return err
+			}
+			result, err := l.Bench(cmd.Context(), []byte(payload), cold, warm)
+			if err != nil {
+				return fmt.Errorf("error benchmarking lambda function, %w", err)
+			}
+			PrintBenchResult(cmd.OutOrStdout(), result)
+			return nil
+		},
+	}
+	benchCmd.Flags().Int("cold", 10, "Number of forced cold start invocations.")
+	benchCmd.Flags().Int("warm", 50, "Number of warm invocations.")
+	benchCmd.Flags().String("payload", "{}", "JSON payload to invoke the function with.")
+	addSourceFlag(benchCmd)
+	return benchCmd
+}
+
+func addSourceFlag(cmd *cobra.Command) {
+	cmd.Flags().String("source", "", "Path to the handler, to warn if it differs from the deployed code.")
+}
+
+// checkFreshness fails early if the function isn't deployed, and warns when
+// the local handler has changed since it was, so that nobody spends time
+// debugging a stale deployment.
+func checkFreshness(cmd *cobra.Command, l *glambda.Lambda) error {
+	f, err := l.Freshness(cmd.Context())
+	if err != nil {
+		return err
+	}
+	if f.Stale() {
+		fmt.Fprintf(cmd.ErrOrStderr(), "notice: local code is newer than deployed (last deployed %s), run glambda deploy %s %s to update it\n", f.LastModified, l.Name, l.HandlerPath)
+	}
+	return nil
+}
+
+// PrintBenchResult writes a summary table of p50 and p95 latencies.
+func PrintBenchResult(w io.Writer, result glambda.BenchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tinvocations\tp50\tp95")
+	rows := []struct {
+		label     string
+		durations []time.Duration
+	}{
+		{"cold init", glambda.InitDurations(result.Cold)},
+		{"cold handler", glambda.Durations(result.Cold)},
+		{"warm handler", glambda.Durations(result.Warm)},
+	}
+	for _, r := range rows {
+		if len(r.durations) == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", r.label, len(r.durations),
+			glambda.Percentile(r.durations, 50).Round(10*time.Microsecond),
+			glambda.Percentile(r.durations, 95).Round(10*time.Microsecond),
+		)
+	}
+	tw.Flush()
+}
+
+func QueryCommand() *cobra.Command {
+	var queryCmd = &cobra.Command{
+		Use:          "query functionName",
+		Short:        "Run a curated CloudWatch Logs Insights query against a lambda function's logs.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example:      `glambda query myFunctionName --errors --since 24h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionName := args[0]
+			since, _ := cmd.Flags().GetDuration("since")
+			var query string
+			for _, name := range []string{"errors", "slowest", "memory"} {
+				if set, _ := cmd.Flags().GetBool(name); set {
+					query = name
+				}
+			}
+			source, _ := cmd.Flags().GetString("source")
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, source, accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			err = checkFreshness(cmd, l)
+			if err != nil {
+				return err
+			}
+			result, err := l.Query(cmd.Context(), query, since)
+			if err != nil {
+				return fmt.Errorf("error querying logs, %w", err)
+			}
+			PrintQueryResult(cmd.OutOrStdout(), result)
+			return nil
+		},
+	}
+	queryCmd.Flags().Bool("errors", false, "Show the most frequent error messages.")
+	queryCmd.Flags().Bool("slowest", false, "Show the slowest invocations.")
+	queryCmd.Flags().Bool("memory", false, "Show provisioned versus used memory.")
+	queryCmd.Flags().Duration("since", 24*time.Hour, "How far back to query.")
+	addSourceFlag(queryCmd)
+	queryCmd.MarkFlagsOneRequired("errors", "slowest", "memory")
+	queryCmd.MarkFlagsMutuallyExclusive("errors", "slowest", "memory")
+	return queryCmd
+}
+
+func DepsCommand() *cobra.Command {
+	var depsCmd = &cobra.Command{
+		Use:          "deps functionName",
+		Short:        "List the Go modules compiled into a deployed lambda function.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example:      `glambda deps myFunctionName`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionName := args[0]
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, "", accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			modules, err := l.Modules(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading modules for %s, %w", functionName, err)
+			}
+			PrintModules(cmd.OutOrStdout(), modules)
+			return nil
+		},
+	}
+	return depsCmd
+}
+
+func DescribeCommand() *cobra.Command {
+	var describeCmd = &cobra.Command{
+		Use:          "describe functionName",
+		Short:        "Report a lambda function's configuration, resource policy and execution role in one place.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example:      `glambda describe myFunctionName --output-format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionName := args[0]
+			outputFormat, _ := cmd.Flags().GetString("output-format")
+			if outputFormat != "text" && outputFormat != "json" {
+				return fmt.Errorf("invalid --output-format %q, expected text or json", outputFormat)
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, "", accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			d, err := l.Describe(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error describing %s, %w", functionName, err)
+			}
+			if outputFormat == "json" {
+				data, err := json.MarshalIndent(d, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return nil
+			}
+			PrintDescription(cmd.OutOrStdout(), d)
+			return nil
+		},
+	}
+	describeCmd.Flags().String("output-format", "text", "Format of the report, text or json.")
+	return describeCmd
+}
+
+// PrintDescription renders a [glambda.Description] as a human readable report.
+func PrintDescription(w io.Writer, d glambda.Description) {
+	managedBy := "glambda"
+	switch {
+	case d.Owner != "":
+		managedBy = d.Owner
+	case !d.Managed:
+		managedBy = "not glambda"
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "function\t%s\n", d.FunctionARN)
+	fmt.Fprintf(tw, "managed by\t%s\n", managedBy)
+	fmt.Fprintf(tw, "state\t%s\n", d.State)
+	fmt.Fprintf(tw, "runtime\t%s %s, handler %s\n", d.Runtime, d.Architecture, d.Handler)
+	fmt.Fprintf(tw, "memory\t%d MB\n", d.MemoryMB)
+	fmt.Fprintf(tw, "timeout\t%ds\n", d.TimeoutSecs)
+	fmt.Fprintf(tw, "code\t%d bytes, sha256 %s\n", d.CodeSize, d.CodeSHA256)
+	fmt.Fprintf(tw, "last modified\t%s\n", d.LastModified)
+	fmt.Fprintf(tw, "environment\t%s\n", listOrNone(d.Environment))
+	var tags []string
+	for k, v := range d.Tags {
+		tags = append(tags, k+"="+v)
+	}
+	slices.Sort(tags)
+	fmt.Fprintf(tw, "tags\t%s\n", listOrNone(tags))
+	if u := d.FunctionURL; u != nil {
+		fmt.Fprintf(tw, "function url\t%s, auth %s, invoke mode %s\n", u.URL, u.AuthType, u.InvokeMode)
+	}
+	tw.Flush()
+	roleManagedBy := "glambda"
+	if !d.Role.Managed {
+		roleManagedBy = "not glambda"
+	}
+	fmt.Fprintf(w, "\nexecution role %s, managed by %s\n", d.Role.ARN, roleManagedBy)
+	for _, p := range d.Role.AttachedPolicies {
+		fmt.Fprintf(w, "  attached %s\n", p)
+	}
+	for _, p := range d.Role.InlinePolicies {
+		fmt.Fprintf(w, "  inline %s\n", p)
+	}
+	fmt.Fprintln(w, "\nresource policy")
+	if len(d.ResourcePolicy) == 0 {
+		fmt.Fprintln(w, "  none, only the account can invoke the function")
+	}
+	for _, s := range d.ResourcePolicy {
+		fmt.Fprintf(w, "  %s %s %s to %s", s.Sid, s.Effect, s.Action, s.Principal)
+		if len(s.Condition) > 0 {
+			fmt.Fprintf(w, " when %s", s.Condition)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func listOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+// PrintModules renders a module list, one module@version per line.
+func PrintModules(w io.Writer, modules []glambda.Module) {
+	if len(modules) == 0 {
+		fmt.Fprintln(w, "no module information recorded, redeploy the function with this version of glambda")
+		return
+	}
+	for _, m := range modules {
+		fmt.Fprintln(w, m)
+	}
+}
+
+// PrintQueryResult renders a Logs Insights query result as a table.
+func PrintQueryResult(w io.Writer, result glambda.QueryResult) {
+	if len(result.Rows) == 0 {
+		fmt.Fprintln(w, "no results")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(result.Fields, "\t"))
+	for _, row := range result.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
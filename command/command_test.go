@@ -1,12 +1,23 @@
 package command_test
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
 	"github.com/mr-joshcrane/glambda/command"
 )
 
@@ -62,3 +73,945 @@ func TestMain_SuccessfullyPackagesALambdaWithThePackageCommand(t *testing.T) {
 		t.Fatalf("Failed to find package.zip: %v", err)
 	}
 }
+
+func TestMain_CheckBuildsHandlerWithoutPackagingIt(t *testing.T) {
+	t.Parallel()
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"check", handler}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), handler+" builds for Lambda") {
+		t.Errorf("expected the check to pass, got %q", buf.String())
+	}
+	missing, err := filepath.Abs("../testdata/missing_main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = command.Main([]string{"check", missing}, command.WithOutput(new(bytes.Buffer)))
+	if err == nil || !strings.Contains(err.Error(), "main function not found") {
+		t.Errorf("expected the check to fail validation, got %v", err)
+	}
+}
+
+func TestMain_VersionPrintsToolVersion(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"version"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "glambda " + command.ReadBuildInfo().Version
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("expected output to start with %q, got %q", want, buf.String())
+	}
+}
+
+func TestPrintBenchResult_SummarisesColdAndWarmLatency(t *testing.T) {
+	t.Parallel()
+	result := glambda.BenchResult{
+		Cold: []glambda.InvocationReport{
+			{Duration: 10 * time.Millisecond, InitDuration: 80 * time.Millisecond},
+		},
+		Warm: []glambda.InvocationReport{
+			{Duration: 2 * time.Millisecond},
+			{Duration: 3 * time.Millisecond},
+		},
+	}
+	buf := new(bytes.Buffer)
+	command.PrintBenchResult(buf, result)
+	for _, want := range []string{"cold init", "80ms", "warm handler", "3ms"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestPrintQueryResult_RendersTable(t *testing.T) {
+	t.Parallel()
+	result := glambda.QueryResult{
+		Fields: []string{"@message", "occurrences"},
+		Rows:   [][]string{{"panic: boom", "7"}},
+	}
+	buf := new(bytes.Buffer)
+	command.PrintQueryResult(buf, result)
+	want := "@message     occurrences\npanic: boom  7\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestPrintDashboard_ShowsConfigurationSparklinesInvocationsAndLogs(t *testing.T) {
+	t.Parallel()
+	logged := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	d := glambda.Dashboard{
+		Configuration: types.FunctionConfiguration{
+			State:         types.StateActive,
+			Runtime:       types.RuntimeProvidedal2023,
+			Architectures: []types.Architecture{types.ArchitectureArm64},
+			MemorySize:    aws.Int32(256),
+			Timeout:       aws.Int32(30),
+		},
+		InvocationsPerMinute: []float64{0, 4, 8},
+		ErrorsPerMinute:      []float64{0, 0, 2},
+		Recent: []glambda.Invocation{{
+			Time:             logged,
+			InvocationReport: glambda.InvocationReport{RequestID: "abc", Duration: 12 * time.Millisecond, MaxMemoryUsed: 31},
+		}},
+		Logs: []glambda.LogEvent{{Time: logged, Message: "hello\tworld"}},
+	}
+	buf := new(bytes.Buffer)
+	command.PrintDashboard(buf, "watched", d)
+	for _, want := range []string{
+		"watched  Active  provided.al2023 arm64  256 MB  30s timeout",
+		"invocations  ▁▄█  12 in the last 3m",
+		"error rate   ▁▁█  2 errors, 16.7%",
+		"12:30:00  abc         12ms      31 MB       -",
+		"  12:30:00 hello world",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestHealthMonitor_AlarmsOnceAfterConsecutiveFailuresAndNotesRecovery(t *testing.T) {
+	t.Parallel()
+	checked := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	healthy := glambda.HealthCheckResult{Time: checked, Latency: 40 * time.Millisecond, Response: glambda.HealthCheckResponse{Status: "ok"}}
+	failing := glambda.HealthCheckResult{Time: checked, Latency: 40 * time.Millisecond, Err: errors.New("timed out")}
+	m := &command.HealthMonitor{Name: "orders", Failures: 2}
+	buf := new(bytes.Buffer)
+	for _, r := range []glambda.HealthCheckResult{failing, healthy, failing, failing, failing, healthy} {
+		m.Record(buf, r)
+	}
+	want := `2024-05-01T12:30:00Z  FAIL  40ms  timed out
+2024-05-01T12:30:00Z  ok    40ms
+2024-05-01T12:30:00Z  FAIL  40ms  timed out
+2024-05-01T12:30:00Z  FAIL  40ms  timed out
+ALARM orders has failed 2 health checks in a row: timed out
+2024-05-01T12:30:00Z  FAIL  40ms  timed out
+2024-05-01T12:30:00Z  ok    40ms
+RECOVERED orders is healthy again after 3 failed checks
+`
+	if buf.String() != want {
+		t.Error(cmp.Diff(want, buf.String()))
+	}
+}
+
+func TestMain_HealthCheckScaffoldPrintsHandlerWithoutFunctionName(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"healthcheck", "--scaffold"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != glambda.HealthCheckScaffold {
+		t.Errorf("expected scaffold, got:\n%s", buf.String())
+	}
+	err = command.Main([]string{"healthcheck"}, command.WithOutput(new(bytes.Buffer)))
+	if err == nil {
+		t.Error("expected error checking health without a function name, got nil")
+	}
+}
+
+func TestPrintDescription_ReportsOwnershipRoleAndResourcePolicy(t *testing.T) {
+	t.Parallel()
+	d := glambda.Description{
+		FunctionARN: "arn:aws:lambda:us-east-1:123456789012:function:audited",
+		Managed:     true,
+		Environment: []string{"API_KEY", "TABLE"},
+		Tags:        map[string]string{"team": "orders", "glambda:managed": "true"},
+		Role: glambda.RoleDescription{
+			ARN:              "arn:aws:iam::123456789012:role/shared",
+			AttachedPolicies: []string{"arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess"},
+		},
+		ResourcePolicy: []glambda.PolicyStatement{
+			{Sid: "s3", Effect: "Allow", Action: "lambda:InvokeFunction", Principal: "s3.amazonaws.com"},
+		},
+	}
+	buf := new(bytes.Buffer)
+	command.PrintDescription(buf, d)
+	for _, want := range []string{
+		"managed by     glambda\n",
+		"environment    API_KEY, TABLE\n",
+		"tags           glambda:managed=true, team=orders\n",
+		"execution role arn:aws:iam::123456789012:role/shared, managed by not glambda\n",
+		"  attached arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess\n",
+		"  s3 Allow lambda:InvokeFunction to s3.amazonaws.com\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestMain_RejectsUnknownVulnCheckMode(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"package", "main.go", "--vuln-check=sometimes"}, command.WithOutput(buf))
+	if err == nil || !strings.Contains(err.Error(), "invalid --vuln-check mode") {
+		t.Errorf("expected invalid mode error, got %v", err)
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	t.Parallel()
+	input := "# comment\n\nLOG_LEVEL=debug\nexport TABLE_NAME = orders\nGREETING=\"hello, world\"\nEMPTY=\nURL='https://example.com/?a=b'\n"
+	got, err := command.ParseEnvFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"LOG_LEVEL":  "debug",
+		"TABLE_NAME": "orders",
+		"GREETING":   "hello, world",
+		"EMPTY":      "",
+		"URL":        "https://example.com/?a=b",
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestParseEnvFile_RejectsLinesWithoutEquals(t *testing.T) {
+	t.Parallel()
+	_, err := command.ParseEnvFile(strings.NewReader("LOG_LEVEL=debug\nnonsense\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error for line 2, got %v", err)
+	}
+}
+
+func TestEnvironment_FlagsOverrideEnvFile(t *testing.T) {
+	t.Parallel()
+	envFile := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(envFile, []byte("LOG_LEVEL=info\nTABLE_NAME=orders\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := command.Environment(envFile, []string{"LOG_LEVEL=debug", "QUERY=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"LOG_LEVEL": "debug", "TABLE_NAME": "orders", "QUERY": "a=b"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestPrintModules_ListsModulesOnePerLine(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	command.PrintModules(buf, []glambda.Module{
+		{Path: "github.com/aws/aws-lambda-go", Version: "v1.47.0"},
+		{Path: "golang.org/x/net", Version: "v0.25.0"},
+	})
+	want := "github.com/aws/aws-lambda-go@v1.47.0\ngolang.org/x/net@v0.25.0\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestMain_UpDeploysEveryFunctionInConfig(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	config, err := filepath.Abs("../testdata/glambda.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"up", "--config", config, "--sandbox"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatalf("expected sandbox deploy of config to succeed, got %v", err)
+	}
+	for _, name := range []string{"greeter", "multiFile"} {
+		if !strings.Contains(buf.String(), "sandbox deploy of "+name+" succeeded") {
+			t.Errorf("expected %s to be deployed, got %q", name, buf.String())
+		}
+	}
+}
+
+func TestMain_UpAsksOnceForConfirmationUnlessYes(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	config, err := filepath.Abs("../testdata/glambda.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"up", "--config", config, "--sandbox"}, command.WithOutput(buf), command.WithInput(strings.NewReader("n\n")))
+	if !errors.Is(err, command.ErrCancelled) {
+		t.Errorf("want the deploy cancelled, got %v", err)
+	}
+	if n := strings.Count(buf.String(), "continue? [y/N]"); n != 1 || !strings.Contains(buf.String(), "deploying 2 functions (greeter, multiFile) to 123456789012 in us-east-1") {
+		t.Errorf("want one prompt naming both functions, got %q", buf.String())
+	}
+	buf.Reset()
+	err = command.Main([]string{"deploy", "--config", config, "--sandbox", "--yes"}, command.WithOutput(buf), command.WithInput(strings.NewReader("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "continue?") {
+		t.Errorf("want no prompt with --yes, got %q", buf.String())
+	}
+}
+
+func TestMain_DryRunPrintsPlan(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "planned", handler, "--sandbox", "--dry-run"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "+ CreateFunction planned") {
+		t.Errorf("expected plan output, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "succeeded") {
+		t.Errorf("expected dry run not to deploy, got %q", buf.String())
+	}
+}
+
+func TestMain_DeployWithFunctionURLPrintsEndpoint(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "webhook", handler, "--sandbox", "--function-url"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "webhook is available at https://webhook.lambda-url.us-east-1.on.aws/"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestMain_DeployWithBootstrapSkipsTheGoBuild(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skip("needs a Linux test binary to stand in for a prebuilt bootstrap")
+	}
+	// The test binary stands in for an executable built by another toolchain.
+	bootstrap, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	arch := map[string]string{"amd64": "x86_64", "arm64": "arm64"}[runtime.GOARCH]
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "rusty", "--bootstrap", bootstrap, "--arch", arch, "--sandbox"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "rusty: packaging prebuilt "+bootstrap) {
+		t.Errorf("want the bootstrap packaged, got %q", buf.String())
+	}
+}
+
+func TestMain_DeployWithHTTPAPIPrintsEndpoint(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	args := []string{"deploy", "orders", handler, "--sandbox", "--http-api", "--route", "GET /orders"}
+	err = command.Main(args, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "orders HTTP API is available at https://apiorders.execute-api.us-east-1.amazonaws.com"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestMain_DeployAsksForConfirmation(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for answer, wantErr := range map[string]error{"y\n": nil, "n\n": command.ErrCancelled, "": command.ErrCancelled} {
+		buf := new(bytes.Buffer)
+		err = command.Main([]string{"deploy", "confirmed", handler, "--sandbox"}, command.WithOutput(buf), command.WithInput(strings.NewReader(answer)))
+		if !errors.Is(err, wantErr) {
+			t.Errorf("answer %q: want error %v, got %v", answer, wantErr, err)
+		}
+		if !strings.Contains(buf.String(), "deploying confirmed to 123456789012 in us-east-1 - continue? [y/N]") {
+			t.Errorf("answer %q: expected prompt, got %q", answer, buf.String())
+		}
+	}
+}
+
+func TestMain_DeploySelectsHandlerFromPattern(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	root, err := filepath.Abs("../testdata/multi_handler_repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := root + "/..."
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "worker", pattern, "--sandbox", "--select", "cmd/worker"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	err = command.Main([]string{"deploy", "worker", pattern, "--sandbox"}, command.WithOutput(buf), command.WithInput(strings.NewReader("2\ny\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "2) "+filepath.Join(root, "cmd", "worker")) {
+		t.Errorf("expected numbered candidates, got %q", buf.String())
+	}
+}
+
+func TestMain_DeployAllDeploysEveryHandlerInAPattern(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	root, err := filepath.Abs("../testdata/multi_handler_repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "--all", root + "/...", "--sandbox", "--yes", "--concurrency", "2"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"cmd-api", "cmd-worker"} {
+		if !strings.Contains(buf.String(), "sandbox deploy of "+name+" succeeded") {
+			t.Errorf("expected %s to be deployed, got %q", name, buf.String())
+		}
+	}
+}
+
+func TestMain_DeployInteractiveAsksQuestionsAndShowsPlan(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	answers := strings.Join([]string{"guided", handler, "256", "", "2", "", "1", "y"}, "\n") + "\n"
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "--interactive", "--sandbox", "--quiet"}, command.WithOutput(buf), command.WithInput(strings.NewReader(answers)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Function name: ",
+		"1) Read DynamoDB tables (AmazonDynamoDBReadOnlyAccess)",
+		"+ AttachRolePolicy glambda_exec_role_guided\n      policy: arn:aws:iam::aws:policy/AmazonDynamoDBFullAccess",
+		"memory: 256 MB",
+		"sandbox deploy of guided succeeded",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestMain_DeployInteractiveRejectsUnknownChoice(t *testing.T) {
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	answers := strings.Join([]string{"guided", handler, "", "", "9"}, "\n") + "\n"
+	err = command.Main([]string{"deploy", "--interactive", "--sandbox"}, command.WithOutput(new(bytes.Buffer)), command.WithInput(strings.NewReader(answers)))
+	if err == nil || !strings.Contains(err.Error(), "invalid choice") {
+		t.Errorf("expected invalid choice error, got %v", err)
+	}
+}
+
+func TestMain_DryRunPrintsJSONPlan(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	args := []string{"deploy", "planned", handler, "--sandbox", "--dry-run", "--plan-format", "json"}
+	err = command.Main(args, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plans []glambda.Plan
+	err = json.Unmarshal(buf.Bytes(), &plans)
+	if err != nil {
+		t.Fatalf("expected a JSON list of plans, got %q: %v", buf.String(), err)
+	}
+	if len(plans) != 1 || plans[0].Function != "planned" {
+		t.Errorf("unexpected plans %+v", plans)
+	}
+}
+
+func TestMain_DeployPrintsJSONResult(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	args := []string{"deploy", "released", handler, "--sandbox", "--output-format", "json", "--quiet"}
+	err = command.Main(args, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The confirmation description comes first, on what is stderr outside of tests.
+	_, output, _ := strings.Cut(buf.String(), "\n")
+	var results []glambda.DeployResult
+	err = json.Unmarshal([]byte(output), &results)
+	if err != nil {
+		t.Fatalf("expected JSON result, got %q: %v", output, err)
+	}
+	want := "arn:aws:lambda:us-east-1:123456789012:function:released:1"
+	if len(results) != 1 || results[0].VersionARN != want {
+		t.Errorf("want version ARN %s, got %+v", want, results)
+	}
+}
+
+func TestMain_DeployWritesMarkdownSummary(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := filepath.Join(t.TempDir(), "deploy-summary.md")
+	args := []string{"deploy", "summarised", handler, "--sandbox", "--quiet", "--summary-file", summary}
+	err = command.Main(args, command.WithOutput(new(bytes.Buffer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Sandbox deploy of 1 functions",
+		"| summarised | 1 | `arn:aws:lambda:us-east-1:123456789012:function:summarised:1` |",
+		"<details><summary>summarised: ",
+		"create lambda_function `summarised` (CreateFunction)",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestMain_DeployReportsProgressUnlessQuiet(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, quiet := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		args := []string{"deploy", "chatty", handler, "--sandbox"}
+		if quiet {
+			args = append(args, "-q")
+		}
+		err = command.Main(args, command.WithOutput(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reported := strings.Contains(buf.String(), "chatty: building "+handler)
+		if reported == quiet {
+			t.Errorf("quiet %v: unexpected progress output %q", quiet, buf.String())
+		}
+	}
+}
+
+func TestMain_DeployReportsTheDeployID(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "traced", handler, "--sandbox", "--deploy-id", "ci-4521"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "traced: deploy ID ci-4521") {
+		t.Errorf("want the deploy ID reported, got %q", buf.String())
+	}
+}
+
+func TestMain_RejectsAnAssumeRoleThatIsntARoleARN(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"describe", "orders", "--external-id", "ci"}, command.WithOutput(buf))
+	if err == nil || !strings.Contains(err.Error(), "needs the role's ARN") {
+		t.Errorf("want an error for --external-id without --assume-role, got %v", err)
+	}
+}
+
+func TestMain_ProfileFlagLoadsNamedProfile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(configFile, []byte("[profile staging]\nregion = eu-west-2\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", configFile)
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"describe", "orders", "--profile", "production"}, command.WithOutput(buf))
+	if err == nil || !strings.Contains(err.Error(), "production") {
+		t.Errorf("want an error naming the missing profile, got %v", err)
+	}
+}
+
+func TestMain_DeployRejectsArgumentsWithConfig(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"deploy", "fn", "main.go", "--config", "glambda.yaml"}, command.WithOutput(buf))
+	if err == nil {
+		t.Error("expected error when combining arguments with --config, got nil")
+	}
+}
+
+func TestMain_SandboxDeployRunsWithoutAWSCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"deploy", "sandboxed", handler, "--sandbox"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatalf("expected sandbox deploy to succeed, got %v", err)
+	}
+	if !strings.Contains(buf.String(), "no AWS resources were changed") {
+		t.Errorf("expected sandbox confirmation, got %q", buf.String())
+	}
+}
+
+func TestMain_SandboxDeployReportsInjectedFaults(t *testing.T) {
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	args := []string{"deploy", "sandboxed", handler, "--sandbox", "--fault-injection", "CreateFunction=1"}
+	err = command.Main(args, command.WithOutput(buf))
+	if !errors.Is(err, glambda.ErrInjectedFault) {
+		t.Errorf("expected injected fault error, got %v", err)
+	}
+}
+
+func TestParseFault_RejectsMalformedValues(t *testing.T) {
+	t.Parallel()
+	for _, value := range []string{"CreateRole", "=2", "CreateRole=zero", "CreateRole=0"} {
+		_, _, err := command.ParseFault(value)
+		if err == nil {
+			t.Errorf("expected error for %q, got nil", value)
+		}
+	}
+}
+
+func TestMain_DeployReportsSmokeTestUnlessSkipped(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, skip := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		args := []string{"deploy", "smoked", handler, "--sandbox", "--output-format", "json", "--quiet"}
+		if skip {
+			args = append(args, "--skip-test")
+		} else {
+			args = append(args, "--smoke-test", "invoke", "--smoke-test-payload", `{"ping": true}`)
+		}
+		err = command.Main(args, command.WithOutput(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, output, _ := strings.Cut(buf.String(), "\n")
+		var results []glambda.DeployResult
+		err = json.Unmarshal([]byte(output), &results)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("expected one JSON result, got %q: %v", output, err)
+		}
+		got := results[0].SmokeTest
+		if skip && got != nil {
+			t.Errorf("want no smoke test with --skip-test, got %+v", got)
+		}
+		if !skip && (got == nil || got.Mode != glambda.SmokeTestInvoke || !got.Passed) {
+			t.Errorf("want a passed invoke smoke test, got %+v", got)
+		}
+	}
+}
+
+func TestMain_SkipTestConflictsWithSmokeTest(t *testing.T) {
+	buf := new(bytes.Buffer)
+	args := []string{"deploy", "smoked", "handler.go", "--sandbox", "--skip-test", "--smoke-test", "invoke"}
+	err := command.Main(args, command.WithOutput(buf))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMain_AuditLogRecordsDeploysForAuditTail(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv(command.AuditLogEnv, log)
+	err = command.Main([]string{"deploy", "audited", handler, "--sandbox"}, command.WithOutput(new(bytes.Buffer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"deploy", "audited", handler, "--sandbox", "--fault-injection", "CreateFunction=1"}
+	err = command.Main(args, command.WithOutput(new(bytes.Buffer)))
+	if !errors.Is(err, glambda.ErrInjectedFault) {
+		t.Fatalf("expected injected fault error, got %v", err)
+	}
+	buf := new(bytes.Buffer)
+	err = command.Main([]string{"audit", "tail", "--output-format", "json"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []glambda.AuditRecord
+	err = json.Unmarshal(buf.Bytes(), &records)
+	if err != nil {
+		t.Fatalf("expected JSON records, got %q: %v", buf.String(), err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	ok, failed := records[0], records[1]
+	if ok.Command != "deploy" || !ok.Success || !ok.Sandbox || ok.PlanSHA256 == "" || len(ok.VersionARNs) != 1 {
+		t.Errorf("unexpected record of the successful deploy %+v", ok)
+	}
+	if failed.Success || !strings.Contains(failed.Error, "injected") {
+		t.Errorf("expected the failed deploy to be recorded with its error, got %+v", failed)
+	}
+	if ok.Actor == "" || !cmp.Equal(ok.Functions, []string{"audited"}) {
+		t.Errorf("expected the record to say who deployed what, got %+v", ok)
+	}
+}
+
+func TestMain_AuditLogRecordsConfigDeploysWithTheirPlan(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	config, err := filepath.Abs("../testdata/glambda.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(t.TempDir(), "audit.jsonl")
+	err = command.Main([]string{"up", "--config", config, "--sandbox", "--audit-log", log}, command.WithOutput(new(bytes.Buffer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := glambda.FileAuditLog{Path: log}.Tail(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Command != "up" || len(records[0].Functions) != 2 || records[0].PlanSHA256 == "" {
+		t.Errorf("expected one record of the up command and its plan, got %+v", records)
+	}
+}
+
+func TestMain_AuditTailNeedsALog(t *testing.T) {
+	t.Setenv(command.AuditLogEnv, "")
+	err := command.Main([]string{"audit", "tail"}, command.WithOutput(new(bytes.Buffer)))
+	if err == nil {
+		t.Error("expected error without an audit log, got nil")
+	}
+}
+
+func TestWriteEnvFile_RoundTripsThroughParseEnvFile(t *testing.T) {
+	t.Parallel()
+	env := map[string]string{
+		"LOG_LEVEL": "debug",
+		"EMPTY":     "",
+		"PADDED":    "  spaced  ",
+		"QUOTED":    `"already quoted"`,
+		"URL":       "https://example.com/?a=b",
+	}
+	buf := new(bytes.Buffer)
+	err := command.WriteEnvFile(buf, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "EMPTY=\nLOG_LEVEL=debug\n") {
+		t.Errorf("expected variables sorted by name, got %q", buf.String())
+	}
+	got, err := command.ParseEnvFile(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(env, got) {
+		t.Error(cmp.Diff(env, got))
+	}
+}
+
+func TestWriteEnvFile_RejectsMultilineValues(t *testing.T) {
+	t.Parallel()
+	err := command.WriteEnvFile(io.Discard, map[string]string{"KEY": "line one\nline two"})
+	if err == nil || !strings.Contains(err.Error(), "KEY") {
+		t.Errorf("expected error naming KEY, got %v", err)
+	}
+}
+
+func TestMain_EnvPushRejectsAnUnknownFormat(t *testing.T) {
+	err := command.Main([]string{"env", "push", "fn", "--format", "yaml"}, command.WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "yaml") {
+		t.Errorf("expected error for an unknown format, got %v", err)
+	}
+}
+
+func TestMain_EnvPushRejectsPruneWithUnset(t *testing.T) {
+	err := command.Main([]string{"env", "push", "fn", "--prune", "--unset", "KEY"}, command.WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "--prune") {
+		t.Errorf("expected error for --prune with --unset, got %v", err)
+	}
+}
+
+func TestMain_InvokePrintsTheResponseAndLogTail(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	buf := new(bytes.Buffer)
+	args := []string{"invoke", "sandboxed", "--payload-file", "-", "--sandbox"}
+	err := command.Main(args, command.WithOutput(buf), command.WithInput(strings.NewReader(`{"name": "ada"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "all good") {
+		t.Errorf("expected the response, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "REPORT RequestId: 8f5d0a3c") {
+		t.Errorf("expected the log tail, got %q", buf.String())
+	}
+}
+
+func TestMain_InvokeRejectsAPayloadThatIsntJSON(t *testing.T) {
+	err := command.Main([]string{"invoke", "sandboxed", "{name", "--sandbox"}, command.WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "JSON") {
+		t.Errorf("expected error for invalid JSON, got %v", err)
+	}
+}
+
+func TestMain_LogsRejectsANonPositiveSince(t *testing.T) {
+	err := command.Main([]string{"logs", "fn", "--since", "-5m"}, command.WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "--since") {
+		t.Errorf("expected error for a negative --since, got %v", err)
+	}
+}
+
+func TestMain_LogsExportRejectsARateAboveTheCloudWatchLimit(t *testing.T) {
+	err := command.Main([]string{"logs", "export", "fn", "--since", "7d", "--rate", "10"}, command.WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "--rate") {
+		t.Errorf("expected error for a --rate above 5, got %v", err)
+	}
+}
+
+func TestMain_LogsExportRejectsAnInvalidNumberOfDays(t *testing.T) {
+	err := command.Main([]string{"logs", "export", "fn", "--since", "sevend"}, command.WithOutput(io.Discard))
+	if err == nil || !strings.Contains(err.Error(), "days") {
+		t.Errorf("expected error for an invalid --since, got %v", err)
+	}
+}
+
+func TestPrintLogEvent_PrefixesTheTime(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	command.PrintLogEvent(buf, glambda.LogEvent{Time: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Message: "hello"})
+	want := "2026-01-02T03:04:05Z hello\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+// Not parallel, as assets are read relative to the working directory, which
+// is changed for the duration of the test.
+func TestMain_PackageLeavesOutExcludedFiles(t *testing.T) {
+	handler, err := filepath.Abs("../testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	for _, name := range []string{"site/index.html", "site/README.md", "site/testdata/golden.html"} {
+		err = os.MkdirAll(filepath.Dir(name), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(name, []byte("<p>\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	args := []string{"package", handler, "--output", "package.zip", "--include", "site", "--exclude", "*.md,site/testdata/**"}
+	err = command.Main(args, command.WithOutput(new(bytes.Buffer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader("package.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	want := []string{"bootstrap", "site/index.html"}
+	if !cmp.Equal(want, names) {
+		t.Error(cmp.Diff(want, names))
+	}
+}
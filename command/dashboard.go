@@ -0,0 +1,149 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// dashboardInvocations is how many recent invocations the dashboard lists.
+const dashboardInvocations = 10
+
+func DashboardCommand() *cobra.Command {
+	var dashboardCmd = &cobra.Command{
+		Use:          "dashboard functionName",
+		Short:        "Watch a lambda function's invocations, errors, logs and configuration in one live view.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example:      `glambda dashboard myFunctionName --window 3h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionName := args[0]
+			window, _ := cmd.Flags().GetDuration("window")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			lines, _ := cmd.Flags().GetInt("lines")
+			once, _ := cmd.Flags().GetBool("once")
+			source, _ := cmd.Flags().GetString("source")
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, source, accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			err = checkFreshness(cmd, l)
+			if err != nil {
+				return err
+			}
+			ctx := cmd.Context()
+			w := cmd.OutOrStdout()
+			redraw := !once && isTerminal(w)
+			since := time.Now().Add(-window)
+			var logs []glambda.LogEvent
+			var recent []glambda.Invocation
+			for {
+				d, err := l.Dashboard(ctx, window, since)
+				if ctx.Err() != nil {
+					// Interrupting is how the dashboard is closed.
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				if len(d.Logs) > 0 {
+					since = d.Logs[len(d.Logs)-1].Time.Add(time.Millisecond)
+				}
+				logs = lastN(append(logs, d.Logs...), lines)
+				recent = lastN(append(recent, d.Recent...), dashboardInvocations)
+				d.Logs, d.Recent = logs, recent
+				if redraw {
+					fmt.Fprint(w, "\x1b[H\x1b[2J")
+				}
+				PrintDashboard(w, functionName, d)
+				if once {
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	dashboardCmd.Flags().Duration("window", time.Hour, "How far back the invocation and error sparklines reach.")
+	dashboardCmd.Flags().Duration("interval", 5*time.Second, "How often to refresh.")
+	dashboardCmd.Flags().Int("lines", 15, "How many of the latest log lines to show.")
+	dashboardCmd.Flags().Bool("once", false, "Print the dashboard once and exit, rather than refreshing until interrupted.")
+	addSourceFlag(dashboardCmd)
+	return dashboardCmd
+}
+
+// PrintDashboard renders a single frame of the glambda dashboard command.
+func PrintDashboard(w io.Writer, name string, d glambda.Dashboard) {
+	c := d.Configuration
+	var archs []string
+	for _, a := range c.Architectures {
+		archs = append(archs, string(a))
+	}
+	fmt.Fprintf(w, "%s  %s  %s %s  %d MB  %ds timeout  version %s  modified %s\n\n",
+		name, c.State, c.Runtime, strings.Join(archs, ","),
+		aws.ToInt32(c.MemorySize), aws.ToInt32(c.Timeout),
+		aws.ToString(c.Version), aws.ToString(c.LastModified),
+	)
+	var invocations, errs float64
+	for i := range d.InvocationsPerMinute {
+		invocations += d.InvocationsPerMinute[i]
+	}
+	for i := range d.ErrorsPerMinute {
+		errs += d.ErrorsPerMinute[i]
+	}
+	rate := 0.0
+	if invocations > 0 {
+		rate = errs / invocations * 100
+	}
+	minutes := len(d.InvocationsPerMinute)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "invocations\t%s\t%.0f in the last %dm\n", glambda.Sparkline(d.InvocationsPerMinute), invocations, minutes)
+	fmt.Fprintf(tw, "error rate\t%s\t%.0f errors, %.1f%%\n", glambda.Sparkline(d.ErrorRate()), errs, rate)
+	tw.Flush()
+	fmt.Fprintln(w, "\nrecent invocations")
+	if len(d.Recent) == 0 {
+		fmt.Fprintln(w, "  none")
+	} else {
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  TIME\tREQUEST ID\tDURATION\tMAX MEMORY\tINIT")
+		for _, inv := range d.Recent {
+			init := "-"
+			if inv.InitDuration > 0 {
+				init = inv.InitDuration.Round(time.Millisecond).String()
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%d MB\t%s\n", inv.Time.Format(time.TimeOnly), inv.RequestID,
+				inv.Duration.Round(10*time.Microsecond), inv.MaxMemoryUsed, init)
+		}
+		tw.Flush()
+	}
+	fmt.Fprintln(w, "\nlogs")
+	if len(d.Logs) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, e := range d.Logs {
+		fmt.Fprintf(w, "  %s %s\n", e.Time.Format(time.TimeOnly), strings.ReplaceAll(e.Message, "\t", " "))
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func lastN[T any](s []T, n int) []T {
+	if len(s) > n {
+		return s[len(s)-n:]
+	}
+	return s
+}
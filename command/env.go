@@ -0,0 +1,223 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/spf13/cobra"
+)
+
+func EnvCommand() *cobra.Command {
+	envCmd := &cobra.Command{
+		Use:   "env",
+		Short: "Sync a lambda function's environment variables with a local .env or JSON file.",
+	}
+	pullCmd := &cobra.Command{
+		Use:          "pull functionName",
+		Short:        "Write a deployed lambda function's environment variables to a file.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `glambda env pull myFunctionName --out .env
+glambda env pull myFunctionName --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, _ := cmd.Flags().GetString("out")
+			format, err := envFormat(cmd, out)
+			if err != nil {
+				return err
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), args[0], "", accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			env, _, err := l.DeployedEnvironment(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading environment of %s, %w", args[0], err)
+			}
+			if out == "" {
+				return writeEnv(cmd.OutOrStdout(), format, env)
+			}
+			f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			err = writeEnv(f, format, env)
+			if err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		},
+	}
+	pullCmd.Flags().String("out", "", "File to write the environment to. Defaults to stdout.")
+	addEnvFormatFlag(pullCmd)
+	pushCmd := &cobra.Command{
+		Use:          "push functionName",
+		Short:        "Add or update a deployed lambda function's environment variables from a file, after showing what changes.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `glambda env push myFunctionName --file .env
+glambda env push myFunctionName --unset OLD_SETTING
+glambda env push myFunctionName --file env.json --prune --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			functionName := args[0]
+			file, _ := cmd.Flags().GetString("file")
+			unset, _ := cmd.Flags().GetStringArray("unset")
+			prune, _ := cmd.Flags().GetBool("prune")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			if prune && len(unset) > 0 {
+				return fmt.Errorf("--prune already removes every variable not in the file, it can't be combined with --unset")
+			}
+			set := map[string]string{}
+			// With only --unset given, there's no file to read.
+			if len(unset) == 0 || cmd.Flags().Changed("file") {
+				format, err := envFormat(cmd, file)
+				if err != nil {
+					return err
+				}
+				set, err = readEnv(file, format)
+				if err != nil {
+					return err
+				}
+				err = glambda.ValidateEnvironment(set)
+				if err != nil {
+					return fmt.Errorf("%s: %w", file, err)
+				}
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, "", accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			current, revisionID, err := l.DeployedEnvironment(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("error reading environment of %s, %w", functionName, err)
+			}
+			desired := set
+			if !prune {
+				desired = glambda.MergeEnvironment(current, set, unset)
+			}
+			changes := glambda.EnvironmentChanges(current, desired)
+			if len(changes) == 0 {
+				cmd.Printf("%s: environment is already up to date\n", functionName)
+				return nil
+			}
+			cmd.Printf("%s: %s\n", functionName, summariseEnvironmentChanges(changes))
+			for _, c := range changes {
+				cmd.Printf("  %s\n", c)
+			}
+			if dryRun {
+				return nil
+			}
+			err = confirm(cmd, fmt.Sprintf("updating the environment of %s in %s", functionName, l.Destination(cmd.Context())), yes)
+			if err != nil {
+				return err
+			}
+			return l.PushEnvironment(cmd.Context(), desired, revisionID)
+		},
+	}
+	pushCmd.Flags().String("file", ".env", "File of environment variables to add or update.")
+	pushCmd.Flags().StringArray("unset", nil, "Name of an environment variable to remove. Can be repeated.")
+	pushCmd.Flags().Bool("prune", false, "Also remove every variable that isn't in the file, so the environment matches it exactly.")
+	pushCmd.Flags().Bool("dry-run", false, "Show the changes to the environment, without making them.")
+	pushCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before updating the environment.")
+	addEnvFormatFlag(pushCmd)
+	envCmd.AddCommand(pullCmd, pushCmd)
+	return envCmd
+}
+
+// summariseEnvironmentChanges counts the additions, updates and removals in a
+// list from [glambda.EnvironmentChanges].
+func summariseEnvironmentChanges(changes []string) string {
+	var add, update, remove int
+	for _, c := range changes {
+		switch c[0] {
+		case '+':
+			add++
+		case '~':
+			update++
+		case '-':
+			remove++
+		}
+	}
+	return fmt.Sprintf("%d to add, %d to update, %d to remove", add, update, remove)
+}
+
+func addEnvFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("format", "", "Format of the file, env or json. Defaults to json for .json files and env otherwise.")
+}
+
+// envFormat is the --format flag, or else the format that the file name
+// suggests.
+func envFormat(cmd *cobra.Command, path string) (string, error) {
+	format, _ := cmd.Flags().GetString("format")
+	switch format {
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			return "json", nil
+		}
+		return "env", nil
+	case "env", "json":
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid --format %q, expected env or json", format)
+}
+
+func readEnv(path, format string) (map[string]string, error) {
+	if format == "env" {
+		return Environment(path, nil)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file, %w", err)
+	}
+	env := map[string]string{}
+	err = json.Unmarshal(data, &env)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file %s, expected a JSON object of strings, %w", path, err)
+	}
+	return env, nil
+}
+
+func writeEnv(w io.Writer, format string, env map[string]string) error {
+	if format == "env" {
+		return WriteEnvFile(w, env)
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// WriteEnvFile writes environment variables in the format read by
+// [ParseEnvFile], one KEY=VALUE per line, sorted by name. Values that
+// ParseEnvFile would otherwise trim or unquote are wrapped in double quotes.
+// Values spanning several lines can't be written, use JSON for those.
+func WriteEnvFile(w io.Writer, env map[string]string) error {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		v := env[k]
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("environment variable %s spans several lines, which a .env file can't hold, use --format json", k)
+		}
+		if v != strings.TrimSpace(v) || (len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0]) {
+			v = `"` + v + `"`
+		}
+		_, err := fmt.Fprintf(w, "%s=%s\n", k, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
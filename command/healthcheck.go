@@ -0,0 +1,114 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/spf13/cobra"
+)
+
+func HealthCheckCommand() *cobra.Command {
+	var healthCheckCmd = &cobra.Command{
+		Use:          "healthcheck functionName",
+		Short:        "Invoke a lambda function's health check on an interval, and alarm when it keeps failing.",
+		SilenceUsage: true,
+		Example: `glambda healthcheck myFunctionName --interval 60s --failures 3
+glambda healthcheck --scaffold > cmd/myFunction/main.go`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if scaffold, _ := cmd.Flags().GetBool("scaffold"); scaffold {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if scaffold, _ := cmd.Flags().GetBool("scaffold"); scaffold {
+				fmt.Fprint(cmd.OutOrStdout(), glambda.HealthCheckScaffold)
+				return nil
+			}
+			functionName := args[0]
+			interval, _ := cmd.Flags().GetDuration("interval")
+			failures, _ := cmd.Flags().GetInt("failures")
+			once, _ := cmd.Flags().GetBool("once")
+			qualifier, _ := cmd.Flags().GetString("qualifier")
+			source, _ := cmd.Flags().GetString("source")
+			if interval <= 0 {
+				return fmt.Errorf("interval must be positive, got %s", interval)
+			}
+			if failures < 1 {
+				return fmt.Errorf("failures must be at least 1, got %d", failures)
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), functionName, source, accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			err = checkFreshness(cmd, l)
+			if err != nil {
+				return err
+			}
+			var opts []glambda.InvokeOption
+			if qualifier != "" {
+				opts = append(opts, glambda.WithQualifier(qualifier))
+			}
+			ctx := cmd.Context()
+			monitor := &HealthMonitor{Name: functionName, Failures: failures}
+			for {
+				result := l.HealthCheck(ctx, opts...)
+				if ctx.Err() != nil {
+					// Interrupting is how the monitor is stopped.
+					return nil
+				}
+				monitor.Record(cmd.OutOrStdout(), result)
+				if once {
+					if !result.Healthy() {
+						return fmt.Errorf("%s is unhealthy, %s", functionName, result.Problem())
+					}
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	healthCheckCmd.Flags().Duration("interval", time.Minute, "How often to check the function.")
+	healthCheckCmd.Flags().Int("failures", 3, "Consecutive failed checks before alarming.")
+	healthCheckCmd.Flags().Bool("once", false, "Check once and exit, non-zero if unhealthy, rather than checking until interrupted.")
+	healthCheckCmd.Flags().String("qualifier", "", "Version or alias to check, e.g. live. Defaults to $LATEST.")
+	healthCheckCmd.Flags().Bool("scaffold", false, "Print the source of a handler that answers health checks, instead of checking a function.")
+	addSourceFlag(healthCheckCmd)
+	return healthCheckCmd
+}
+
+// HealthMonitor tracks consecutive health check failures of a function, so
+// that the glambda healthcheck command alarms once when a function keeps
+// failing, rather than on every blip, and notes when it recovers.
+type HealthMonitor struct {
+	Name string
+	// Failures is how many consecutive failed checks raise the alarm.
+	Failures int
+	failed   int
+}
+
+// Record prints a line for the result, and an ALARM or RECOVERED line when the
+// function's state changes.
+func (m *HealthMonitor) Record(w io.Writer, result glambda.HealthCheckResult) {
+	stamp := result.Time.UTC().Format(time.RFC3339)
+	latency := result.Latency.Round(time.Millisecond)
+	if result.Healthy() {
+		fmt.Fprintf(w, "%s  ok    %s\n", stamp, latency)
+		if m.failed >= m.Failures {
+			fmt.Fprintf(w, "RECOVERED %s is healthy again after %d failed checks\n", m.Name, m.failed)
+		}
+		m.failed = 0
+		return
+	}
+	m.failed++
+	fmt.Fprintf(w, "%s  FAIL  %s  %s\n", stamp, latency, result.Problem())
+	if m.failed == m.Failures {
+		fmt.Fprintf(w, "ALARM %s has failed %d health checks in a row: %s\n", m.Name, m.failed, result.Problem())
+	}
+}
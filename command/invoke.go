@@ -0,0 +1,131 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+	"github.com/spf13/cobra"
+)
+
+func InvokeCommand() *cobra.Command {
+	invokeCmd := &cobra.Command{
+		Use:          "invoke functionName [payload]",
+		Short:        "Invoke a deployed lambda function with a JSON payload, printing its response and the tail of its log.",
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		Example: `glambda invoke myFunctionName '{"name": "ada"}'
+glambda invoke myFunctionName --payload-file event.json
+echo '{"name": "ada"}' | glambda invoke myFunctionName --payload-file -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			payloadFile, _ := cmd.Flags().GetString("payload-file")
+			qualifier, _ := cmd.Flags().GetString("qualifier")
+			noLogs, _ := cmd.Flags().GetBool("no-logs")
+			sandbox, _ := cmd.Flags().GetBool("sandbox")
+			logWait, _ := cmd.Flags().GetDuration("log-wait")
+			payload, err := invokePayload(cmd, args[1:], payloadFile)
+			if err != nil {
+				return err
+			}
+			opts := accountOptions(cmd)
+			if sandbox {
+				opts = append(opts, glambdatest.Sandbox())
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), args[0], "", opts...)
+			if err != nil {
+				return err
+			}
+			var invokeOpts []glambda.InvokeOption
+			if qualifier != "" {
+				invokeOpts = append(invokeOpts, glambda.WithQualifier(qualifier))
+			}
+			var response []byte
+			result, err := l.InvokeWithLog(cmd.Context(), payload, &response, invokeOpts...)
+			if !noLogs {
+				printInvocationLog(cmd, l, result, logWait)
+			}
+			var fnErr *glambda.FunctionError
+			if errors.As(err, &fnErr) {
+				cmd.Println(formatResponse(fnErr.Payload))
+			}
+			if err != nil {
+				return err
+			}
+			cmd.Println(formatResponse(response))
+			return nil
+		},
+	}
+	invokeCmd.Flags().String("payload-file", "", "File holding the JSON payload, or - to read it from stdin.")
+	invokeCmd.Flags().String("qualifier", "", "Version or alias of the function to invoke, instead of $LATEST.")
+	invokeCmd.Flags().Bool("no-logs", false, "Don't print the invocation's log.")
+	invokeCmd.Flags().Duration("log-wait", 30*time.Second, "How long to wait for the whole log of an invocation that logged more than its tail holds.")
+	invokeCmd.Flags().Bool("sandbox", false, "Invoke a mocked function, without credentials.")
+	return invokeCmd
+}
+
+// printInvocationLog prints the invocation's log tail, or, when the tail was
+// cut short, the invocation's whole log, read from CloudWatch Logs by its
+// request ID.
+func printInvocationLog(cmd *cobra.Command, l *glambda.Lambda, result glambda.InvokeResult, wait time.Duration) {
+	if !result.TailTruncated() {
+		if result.LogTail != "" {
+			cmd.PrintErrln(strings.TrimRight(result.LogTail, "\n"))
+		}
+		return
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), wait)
+	defer cancel()
+	events, err := l.InvocationLogs(ctx, result, time.Second)
+	if err != nil {
+		cmd.PrintErrf("can't read the whole log of request %s, %v, showing its tail\n", result.RequestID, err)
+		cmd.PrintErrln(strings.TrimRight(result.LogTail, "\n"))
+		return
+	}
+	for _, e := range events {
+		cmd.PrintErrln(e.Message)
+	}
+}
+
+// invokePayload is the payload given as an argument or read from a file or
+// stdin, which must be valid JSON. No payload at all sends nothing.
+func invokePayload(cmd *cobra.Command, args []string, file string) ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case len(args) > 0 && file != "":
+		return nil, fmt.Errorf("give the payload as an argument or with --payload-file, not both")
+	case len(args) > 0:
+		data = []byte(args[0])
+	case file == "-":
+		data, err = io.ReadAll(cmd.InOrStdin())
+	case file != "":
+		data, err = os.ReadFile(file)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading payload, %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("payload is not valid JSON")
+	}
+	return data, nil
+}
+
+// formatResponse indents a JSON response for reading. Anything else is
+// printed as it is.
+func formatResponse(data []byte) string {
+	var buf bytes.Buffer
+	if json.Indent(&buf, data, "", "  ") != nil {
+		return string(data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,133 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/spf13/cobra"
+)
+
+func LogsCommand() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:          "logs functionName",
+		Short:        "Print a lambda function's recent log events from CloudWatch Logs, optionally following new ones.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `glambda logs myFunctionName --since 1h
+glambda logs myFunctionName --follow`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			since, _ := cmd.Flags().GetDuration("since")
+			follow, _ := cmd.Flags().GetBool("follow")
+			interval, _ := cmd.Flags().GetDuration("interval")
+			if since <= 0 {
+				return fmt.Errorf("invalid --since %s, must be positive", since)
+			}
+			if interval <= 0 {
+				return fmt.Errorf("invalid --interval %s, must be positive", interval)
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), args[0], "", accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			start := time.Now().Add(-since)
+			if follow {
+				// Interrupting is how following is stopped.
+				return l.FollowLogs(cmd.Context(), start, interval, func(e glambda.LogEvent) {
+					PrintLogEvent(w, e)
+				})
+			}
+			events, err := l.Logs(cmd.Context(), start)
+			if err != nil {
+				return err
+			}
+			for _, e := range events {
+				PrintLogEvent(w, e)
+			}
+			return nil
+		},
+	}
+	logsCmd.Flags().Duration("since", 10*time.Minute, "How far back to start, e.g. 30s, 10m or 2h.")
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep printing new log events as they are written, until interrupted.")
+	logsCmd.Flags().Duration("interval", 2*time.Second, "How often to check for new log events when following.")
+	logsCmd.AddCommand(logsExportCommand())
+	return logsCmd
+}
+
+func logsExportCommand() *cobra.Command {
+	since := dayDuration(24 * time.Hour)
+	exportCmd := &cobra.Command{
+		Use:          "export functionName",
+		Short:        "Write a lambda function's log events over a longer period to files, for offline analysis.",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Example: `glambda logs export myFunctionName --since 7d --out ./logs/
+glambda logs export myFunctionName --since 36h --rate 1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out, _ := cmd.Flags().GetString("out")
+			rate, _ := cmd.Flags().GetFloat64("rate")
+			if since <= 0 {
+				return fmt.Errorf("invalid --since %s, must be positive", since.String())
+			}
+			if rate <= 0 || rate > 5 {
+				return fmt.Errorf("invalid --rate %v, must be more than 0 and at most 5, the CloudWatch Logs limit", rate)
+			}
+			l, err := glambda.NewLambdaWithContext(cmd.Context(), args[0], "", accountOptions(cmd)...)
+			if err != nil {
+				return err
+			}
+			export, err := l.ExportLogs(cmd.Context(), time.Now().Add(-time.Duration(since)), out, rate)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("exported %d log events of %s to %d files in %s\n", export.Events, args[0], len(export.Files), out)
+			return nil
+		},
+	}
+	exportCmd.Flags().Var(&since, "since", "How far back to export, e.g. 12h or 7d.")
+	exportCmd.Flags().String("out", "logs", "Directory to write a file of JSON lines to for each day.")
+	exportCmd.Flags().Float64("rate", glambda.DefaultLogExportRate, "Most requests a second to make to CloudWatch Logs, leaving the rest of the account's limit of 5 to others.")
+	return exportCmd
+}
+
+// dayDuration is a duration flag that also accepts a number of days, such
+// as 7d, as logs are often kept for weeks.
+type dayDuration time.Duration
+
+func (d *dayDuration) Set(s string) error {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return fmt.Errorf("invalid number of days %q", s)
+		}
+		*d = dayDuration(time.Duration(n) * 24 * time.Hour)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = dayDuration(v)
+	return nil
+}
+
+func (d dayDuration) String() string {
+	v := time.Duration(d)
+	if v > 0 && v%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", v/(24*time.Hour))
+	}
+	return v.String()
+}
+
+func (d dayDuration) Type() string {
+	return "duration"
+}
+
+// PrintLogEvent writes a log event on one line, after its time.
+func PrintLogEvent(w io.Writer, e glambda.LogEvent) {
+	fmt.Fprintf(w, "%s %s\n", e.Time.Format(time.RFC3339), e.Message)
+}
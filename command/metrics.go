@@ -0,0 +1,114 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// MetricsFileEnv names the environment variable that opts in to usage
+	// metrics. When set, a [MetricsEvent] is appended to the file it names
+	// as a line of JSON after every command.
+	MetricsFileEnv = "GLAMBDA_METRICS_FILE"
+	// MetricsEndpointEnv names the environment variable that opts in to
+	// sending each [MetricsEvent] as a JSON POST to an HTTP endpoint, such as
+	// an organisation's internal collector.
+	MetricsEndpointEnv = "GLAMBDA_METRICS_ENDPOINT"
+)
+
+// MetricsTimeout bounds how long a command waits to send its metrics, so that
+// an unreachable endpoint never holds up the user for long.
+var MetricsTimeout = 2 * time.Second
+
+// MetricsEvent is the anonymous record of a single command. It says which
+// command and flags were used, but never flag values, function names, paths
+// or error messages.
+type MetricsEvent struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Flags      []string  `json:"flags"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	Version    string    `json:"version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+}
+
+func newMetricsEvent(cmd *cobra.Command, start time.Time, err error) MetricsEvent {
+	flags := []string{}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags = append(flags, f.Name)
+	})
+	slices.Sort(flags)
+	return MetricsEvent{
+		Time:       start.UTC(),
+		Command:    cmd.Name(),
+		Flags:      flags,
+		DurationMS: time.Since(start).Milliseconds(),
+		Success:    err == nil,
+		Version:    ReadBuildInfo().Version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+	}
+}
+
+// recordMetrics writes the command's [MetricsEvent] wherever the user has
+// opted in to. Metrics are best effort, a failure to record them is reported
+// but never fails the command.
+func recordMetrics(cmd *cobra.Command, start time.Time, err error) {
+	file, endpoint := os.Getenv(MetricsFileEnv), os.Getenv(MetricsEndpointEnv)
+	if file == "" && endpoint == "" {
+		return
+	}
+	data, mErr := json.Marshal(newMetricsEvent(cmd, start, err))
+	if mErr != nil {
+		return
+	}
+	if file != "" {
+		wErr := appendLine(file, data)
+		if wErr != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "unable to record metrics: %v\n", wErr)
+		}
+	}
+	if endpoint != "" {
+		pErr := postMetrics(endpoint, data)
+		if pErr != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "unable to send metrics: %v\n", pErr)
+		}
+	}
+}
+
+func appendLine(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func postMetrics(endpoint string, data []byte) error {
+	client := &http.Client{Timeout: MetricsTimeout}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("metrics endpoint responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda/command"
+)
+
+func TestMain_RecordsMetricsWhenOptedIn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+	t.Setenv(command.MetricsFileEnv, path)
+	t.Setenv(command.MetricsEndpointEnv, "")
+	err := command.Main([]string{"version"}, command.WithOutput(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = command.Main([]string{"deploy", "fn", "--dry-run"}, command.WithOutput(io.Discard))
+	if err == nil {
+		t.Fatal("expected error for missing source path, got nil")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per command, got %q", data)
+	}
+	var events [2]command.MetricsEvent
+	for i, line := range lines {
+		err = json.Unmarshal([]byte(line), &events[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if events[0].Command != "version" || !events[0].Success {
+		t.Errorf("expected successful version event, got %+v", events[0])
+	}
+	if events[1].Command != "deploy" || events[1].Success || !cmp.Equal([]string{"dry-run"}, events[1].Flags) {
+		t.Errorf("expected failed deploy event with its flags, got %+v", events[1])
+	}
+}
+
+func TestMain_SendsMetricsToEndpoint(t *testing.T) {
+	received := make(chan command.MetricsEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event command.MetricsEvent
+		err := json.NewDecoder(r.Body).Decode(&event)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+	t.Setenv(command.MetricsFileEnv, "")
+	t.Setenv(command.MetricsEndpointEnv, server.URL)
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"deploy", "fn"}, command.WithOutput(buf))
+	if err == nil {
+		t.Fatal("expected error for missing source path, got nil")
+	}
+	select {
+	case event := <-received:
+		if event.Command != "deploy" || event.Success {
+			t.Errorf("expected failed deploy event, got %+v", event)
+		}
+	default:
+		t.Error("expected metrics to be sent")
+	}
+	if strings.Contains(buf.String(), "unable to send metrics") {
+		t.Errorf("unexpected metrics error %q", buf.String())
+	}
+}
@@ -0,0 +1,335 @@
+package command
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// LatestReleaseURL is the GitHub API endpoint used to discover the most recent
+// published release of glambda. It is a variable so that tests can point it at
+// a local server.
+var LatestReleaseURL = "https://api.github.com/repos/mr-joshcrane/glambda/releases/latest"
+
+// ExecutablePath returns the path of the running glambda binary, which is the
+// file that will be replaced during an upgrade.
+var ExecutablePath = os.Executable
+
+// ReleasePublicKey is the base64 encoded Ed25519 public key that signs the
+// checksums.txt of every release. Release binaries have it set at build time
+// with -ldflags, see .github/workflows/release.yml.
+var ReleasePublicKey = ""
+
+// Release is the subset of the GitHub release API response that glambda needs
+// in order to find and verify a binary for the current platform.
+type Release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a single downloadable file attached to a [Release].
+type ReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// AssetName is the name of the release asset expected for a given platform.
+// Release binaries are published as glambda_<os>_<arch>, with the usual
+// .exe suffix on Windows.
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("glambda_%s_%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Asset finds a release asset by name.
+func (r Release) Asset(name string) (ReleaseAsset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return ReleaseAsset{}, false
+}
+
+// LatestRelease fetches the latest release metadata from the GitHub API.
+func LatestRelease(url string) (Release, error) {
+	var release Release
+	data, err := download(url)
+	if err != nil {
+		return release, fmt.Errorf("error checking for latest release, %w", err)
+	}
+	err = json.Unmarshal(data, &release)
+	if err != nil {
+		return release, fmt.Errorf("error decoding release metadata, %w", err)
+	}
+	if release.TagName == "" {
+		return release, fmt.Errorf("release metadata did not contain a tag name")
+	}
+	return release, nil
+}
+
+// ParseChecksums reads a checksum file in the format produced by sha256sum,
+// returning a map of file name to hex encoded SHA256 digest.
+func ParseChecksums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed checksum line %q", scanner.Text())
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
+
+// VerifySignature checks that sig is the signature of data by the key in
+// [ReleasePublicKey].
+func VerifySignature(data, sig []byte) error {
+	if ReleasePublicKey == "" {
+		return fmt.Errorf("this glambda was not built with a release signing key, so releases can't be verified, install it with go install github.com/mr-joshcrane/glambda/cmd/glambda@latest instead")
+	}
+	key, err := base64.StdEncoding.DecodeString(ReleasePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid release signing key %q", ReleasePublicKey)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return fmt.Errorf("signature of checksums.txt is not valid, refusing to install an unverified binary")
+	}
+	return nil
+}
+
+// CompareVersions compares two semantic versions such as v1.2.3 or
+// v1.3.0-rc.1, returning -1, 0 or 1 as a is older than, the same as or newer
+// than b. Pre-releases are older than the release they precede, and are
+// compared to each other as strings.
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range va.numbers {
+		if va.numbers[i] != vb.numbers[i] {
+			if va.numbers[i] < vb.numbers[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	switch {
+	case va.pre == vb.pre:
+		return 0, nil
+	case va.pre == "":
+		return 1, nil
+	case vb.pre == "":
+		return -1, nil
+	}
+	return strings.Compare(va.pre, vb.pre), nil
+}
+
+type version struct {
+	numbers [3]int
+	pre     string
+}
+
+func parseVersion(s string) (version, error) {
+	var v version
+	core, ok := strings.CutPrefix(s, "v")
+	if !ok {
+		return v, fmt.Errorf("%q is not a semantic version", s)
+	}
+	core, _, _ = strings.Cut(core, "+")
+	core, v.pre, _ = strings.Cut(core, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("%q is not a semantic version", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%q is not a semantic version", s)
+		}
+		v.numbers[i] = n
+	}
+	return v, nil
+}
+
+// SelfUpdate downloads the binary for the current platform from the given
+// release, verifies the signature of the release's checksums.txt and the
+// binary against it, and atomically replaces the file at path with it.
+func SelfUpdate(release Release, path string) error {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	asset, ok := release.Asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	checksumAsset, ok := release.Asset("checksums.txt")
+	if !ok {
+		return fmt.Errorf("release %s has no checksums.txt, refusing to install an unverified binary", release.TagName)
+	}
+	signatureAsset, ok := release.Asset("checksums.txt.sig")
+	if !ok {
+		return fmt.Errorf("release %s has no checksums.txt.sig, refusing to install an unverified binary", release.TagName)
+	}
+	checksumData, err := download(checksumAsset.URL)
+	if err != nil {
+		return fmt.Errorf("error downloading checksums, %w", err)
+	}
+	signature, err := download(signatureAsset.URL)
+	if err != nil {
+		return fmt.Errorf("error downloading checksums signature, %w", err)
+	}
+	err = VerifySignature(checksumData, signature)
+	if err != nil {
+		return err
+	}
+	sums, err := ParseChecksums(strings.NewReader(string(checksumData)))
+	if err != nil {
+		return err
+	}
+	want, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("checksums.txt has no entry for %s", name)
+	}
+	binary, err := download(asset.URL)
+	if err != nil {
+		return fmt.Errorf("error downloading %s, %w", name, err)
+	}
+	digest := sha256.Sum256(binary)
+	got := hex.EncodeToString(digest[:])
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+	return replaceExecutable(path, binary)
+}
+
+// replaceExecutable writes the new binary next to the old one and renames it
+// into place. The old binary is moved aside first, as Windows will not allow
+// a running executable to be overwritten.
+func replaceExecutable(path string, binary []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".glambda-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file for upgrade, %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(binary)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0755)
+	if err != nil {
+		return err
+	}
+	old := path + ".old"
+	err = os.Rename(path, old)
+	if err != nil {
+		return fmt.Errorf("error moving current binary aside, %w", err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		// put the original binary back so the user isn't left without one
+		_ = os.Rename(old, path)
+		return fmt.Errorf("error installing new binary, %w", err)
+	}
+	_ = os.Remove(old)
+	return nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func UpgradeCommand() *cobra.Command {
+	var upgradeCmd = &cobra.Command{
+		Use:          "upgrade",
+		Short:        "Upgrade glambda to the latest released version.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example:      `glambda upgrade --check`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checkOnly, _ := cmd.Flags().GetBool("check")
+			release, err := LatestRelease(LatestReleaseURL)
+			if err != nil {
+				return err
+			}
+			current := currentVersion()
+			// Development builds can't be ordered against releases, so
+			// they are only told what the latest release is.
+			devel := current == "(devel)"
+			if devel && !checkOnly {
+				return fmt.Errorf("glambda was built from source, so it can't tell whether %s is newer, install it with go install github.com/mr-joshcrane/glambda/cmd/glambda@%s", release.TagName, release.TagName)
+			}
+			if !devel {
+				order, err := CompareVersions(current, release.TagName)
+				if err != nil {
+					return err
+				}
+				if order == 0 {
+					cmd.Printf("glambda %s is already the latest version\n", current)
+					return nil
+				}
+				if order > 0 {
+					cmd.Printf("glambda %s is newer than the latest release %s, not downgrading\n", current, release.TagName)
+					return nil
+				}
+			}
+			if checkOnly {
+				cmd.Printf("glambda %s is available (current version %s)\n", release.TagName, current)
+				return nil
+			}
+			path, err := ExecutablePath()
+			if err != nil {
+				return fmt.Errorf("error locating the glambda binary, %w", err)
+			}
+			path, err = filepath.EvalSymlinks(path)
+			if err != nil {
+				return fmt.Errorf("error locating the glambda binary, %w", err)
+			}
+			err = SelfUpdate(release, path)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("upgraded glambda from %s to %s\n", current, release.TagName)
+			return nil
+		},
+	}
+	upgradeCmd.Flags().Bool("check", false, "Only report whether a newer version is available.")
+	return upgradeCmd
+}
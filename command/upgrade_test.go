@@ -0,0 +1,216 @@
+package command_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda/command"
+)
+
+// releaseKey signs the checksums served by releaseServer, in place of the
+// key release binaries are built with.
+var releaseKey = func() ed25519.PrivateKey {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		panic(err)
+	}
+	command.ReleasePublicKey = base64.StdEncoding.EncodeToString(public)
+	return private
+}()
+
+func releaseServer(t *testing.T, binary []byte, checksum string) *httptest.Server {
+	t.Helper()
+	return signedReleaseServer(t, binary, checksum, releaseKey)
+}
+
+func signedReleaseServer(t *testing.T, binary []byte, checksum string, key ed25519.PrivateKey) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	name := command.AssetName(runtime.GOOS, runtime.GOARCH)
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		release := command.Release{
+			TagName: "v9.9.9",
+			Assets: []command.ReleaseAsset{
+				{Name: name, URL: server.URL + "/binary"},
+				{Name: "checksums.txt", URL: server.URL + "/checksums"},
+				{Name: "checksums.txt.sig", URL: server.URL + "/signature"},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(release)
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	checksums := fmt.Sprintf("%s  %s\n", checksum, name)
+	mux.HandleFunc("/checksums", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(checksums))
+	})
+	mux.HandleFunc("/signature", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(ed25519.Sign(key, []byte(checksums)))
+	})
+	return server
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSelfUpdate_ReplacesBinaryWhenChecksumMatches(t *testing.T) {
+	t.Parallel()
+	binary := []byte("new glambda binary")
+	server := releaseServer(t, binary, sha256Hex(binary))
+	release, err := command.LatestRelease(server.URL + "/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "glambda")
+	err = os.WriteFile(path, []byte("old glambda binary"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = command.SelfUpdate(release, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, binary) {
+		t.Errorf("expected binary to be replaced, got %q", got)
+	}
+}
+
+func TestSelfUpdate_RefusesBinaryWithBadChecksum(t *testing.T) {
+	t.Parallel()
+	server := releaseServer(t, []byte("tampered binary"), sha256Hex([]byte("genuine binary")))
+	release, err := command.LatestRelease(server.URL + "/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "glambda")
+	err = os.WriteFile(path, []byte("old glambda binary"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = command.SelfUpdate(release, path)
+	if err == nil {
+		t.Fatal("expected checksum error, got nil")
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "old glambda binary" {
+		t.Errorf("expected original binary to be untouched, got %q", got)
+	}
+}
+
+func TestSelfUpdate_RefusesChecksumsSignedByAnotherKey(t *testing.T) {
+	t.Parallel()
+	_, forger, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("forged binary")
+	server := signedReleaseServer(t, binary, sha256Hex(binary), forger)
+	release, err := command.LatestRelease(server.URL + "/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "glambda")
+	err = os.WriteFile(path, []byte("old glambda binary"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = command.SelfUpdate(release, path)
+	if err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Fatalf("expected signature error, got %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	if string(got) != "old glambda binary" {
+		t.Errorf("expected original binary to be untouched, got %q", got)
+	}
+}
+
+func TestCompareVersions_OrdersSemanticVersions(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "v1.10.0", -1},
+		{"v2.0.0", "v1.9.9", 1},
+		{"v1.3.0-rc.1", "v1.3.0", -1},
+		{"v1.3.0", "v1.3.0-rc.1", 1},
+		{"v1.3.0-rc.1", "v1.3.0-rc.2", -1},
+		{"v1.2.3+dirty", "v1.2.3", 0},
+	} {
+		got, err := command.CompareVersions(tc.a, tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("CompareVersions(%s, %s): want %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+	_, err := command.CompareVersions("(devel)", "v1.0.0")
+	if err == nil {
+		t.Error("expected an error comparing (devel)")
+	}
+}
+
+func TestParseChecksums_ReadsSha256sumFormat(t *testing.T) {
+	t.Parallel()
+	input := "ABC123  glambda_linux_amd64\ndef456 *glambda_windows_amd64.exe\n\n"
+	got, err := command.ParseChecksums(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["glambda_linux_amd64"] != "abc123" {
+		t.Errorf("expected abc123, got %q", got["glambda_linux_amd64"])
+	}
+	if got["glambda_windows_amd64.exe"] != "def456" {
+		t.Errorf("expected def456, got %q", got["glambda_windows_amd64.exe"])
+	}
+}
+
+func TestMain_UpgradeCheckReportsAvailableVersion(t *testing.T) {
+	server := releaseServer(t, nil, "")
+	command.LatestReleaseURL = server.URL + "/latest"
+	buf := new(bytes.Buffer)
+	err := command.Main([]string{"upgrade", "--check"}, command.WithOutput(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "v9.9.9 is available") {
+		t.Errorf("expected new version to be reported, got %q", buf.String())
+	}
+}
+
+func TestMain_UpgradeRefusesToReplaceADevelopmentBuild(t *testing.T) {
+	server := releaseServer(t, nil, "")
+	command.LatestReleaseURL = server.URL + "/latest"
+	command.ExecutablePath = func() (string, error) {
+		t.Fatal("expected the binary not to be located")
+		return "", nil
+	}
+	t.Cleanup(func() { command.ExecutablePath = os.Executable })
+	err := command.Main([]string{"upgrade"}, command.WithOutput(new(bytes.Buffer)))
+	if err == nil || !strings.Contains(err.Error(), "built from source") {
+		t.Errorf("expected development builds to be left alone, got %v", err)
+	}
+}
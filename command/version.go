@@ -0,0 +1,128 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/spf13/cobra"
+)
+
+// BuildInfo describes how the running glambda binary was built, as recorded
+// by the Go toolchain at build time.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildTime string
+	Modified  bool
+	GoVersion string
+}
+
+// ReadBuildInfo collects the version and VCS metadata embedded in the running
+// binary. Binaries installed with `go install pkg@version` report that version,
+// local builds report (devel) along with the commit they were built from.
+func ReadBuildInfo() BuildInfo {
+	b := BuildInfo{
+		Version:   "(devel)",
+		Commit:    "unknown",
+		BuildTime: "unknown",
+		GoVersion: runtime.Version(),
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	if info.Main.Version != "" {
+		b.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Commit = s.Value
+		case "vcs.time":
+			b.BuildTime = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
+
+func currentVersion() string {
+	return ReadBuildInfo().Version
+}
+
+// goToolchain reports the output of `go version` for the toolchain on PATH,
+// which is the one that will be used to build lambda handlers.
+func goToolchain() string {
+	out, err := exec.Command("go", "version").Output()
+	if err != nil {
+		return "not found on PATH"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// awsEnvironment resolves the region and account ID, honouring --region and
+// --profile, returning a human readable reason in place of either if it
+// cannot be determined.
+func awsEnvironment(cmd *cobra.Command) (region, account string) {
+	ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Second)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(ctx, awsConfigOptions(cmd)...)
+	if err != nil {
+		return "unresolved (" + err.Error() + ")", "unresolved"
+	}
+	region = cfg.Region
+	if region == "" {
+		region = "not set"
+	}
+	account, err = glambda.AWSAccountID(ctx, sts.NewFromConfig(cfg))
+	var credsErr *glambda.CredentialsError
+	if errors.As(err, &credsErr) {
+		return region, "unresolved (no usable credentials from " + credsErr.Source + ")"
+	}
+	if err != nil {
+		return region, "unresolved (no usable credentials)"
+	}
+	return region, account
+}
+
+func VersionCommand() *cobra.Command {
+	var versionCmd = &cobra.Command{
+		Use:          "version",
+		Short:        "Print the glambda version.",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Example:      `glambda version --verbose`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			info := ReadBuildInfo()
+			if !verbose {
+				cmd.Printf("glambda %s\n", info.Version)
+				return nil
+			}
+			commit := info.Commit
+			if info.Modified {
+				commit += " (modified)"
+			}
+			region, account := awsEnvironment(cmd)
+			cmd.Printf("glambda %s\n", info.Version)
+			cmd.Printf("  commit:       %s\n", commit)
+			cmd.Printf("  built:        %s\n", info.BuildTime)
+			cmd.Printf("  go:           %s %s/%s\n", info.GoVersion, runtime.GOOS, runtime.GOARCH)
+			cmd.Printf("  toolchain:    %s\n", goToolchain())
+			cmd.Printf("  aws region:   %s\n", region)
+			cmd.Printf("  aws account:  %s\n", account)
+			return nil
+		},
+	}
+	versionCmd.Flags().BoolP("verbose", "v", false, "Include build metadata and a summary of the AWS environment.")
+	return versionCmd
+}
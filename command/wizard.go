@@ -0,0 +1,200 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// policyPresets are the managed policies offered by the deploy wizard, for
+// the things lambda functions most often need to reach.
+var policyPresets = []struct {
+	Label  string
+	Policy string
+}{
+	{"Read DynamoDB tables", "AmazonDynamoDBReadOnlyAccess"},
+	{"Read and write DynamoDB tables", "AmazonDynamoDBFullAccess"},
+	{"Read S3 buckets", "AmazonS3ReadOnlyAccess"},
+	{"Send and receive SQS messages", "AmazonSQSFullAccess"},
+	{"Publish to SNS topics", "AmazonSNSFullAccess"},
+	{"Send traces to X-Ray", "AWSXRayDaemonWriteAccess"},
+}
+
+var triggerChoices = []string{
+	"None, invoke it directly",
+	"Function URL, a public HTTPS endpoint",
+	"HTTP API through API Gateway",
+	"Objects created in an S3 bucket",
+	"Messages in an SQS queue",
+}
+
+// runWizard walks a user at a terminal through the settings of a deploy, one
+// question at a time, and sets the deploy command's flags from the answers so
+// the deploy carries on exactly as if they had been typed. Any arguments
+// given are offered as the defaults. It returns the function name and source
+// path to deploy.
+func runWizard(cmd *cobra.Command, args []string) ([]string, error) {
+	if !interactive(cmd.InOrStdin()) {
+		return nil, errors.New("--interactive needs a terminal to ask questions at")
+	}
+	defaultName, defaultSource := "", "."
+	if len(args) > 0 {
+		defaultName = args[0]
+	}
+	if len(args) > 1 {
+		defaultSource = args[1]
+	}
+	name, err := ask(cmd, "Function name", defaultName)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, errors.New("a function name is required")
+	}
+	source, err := ask(cmd, "Handler source, a file, directory or ./... pattern", defaultSource)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := ask(cmd, "Memory in MB, blank for the default of 128", "")
+	if err != nil {
+		return nil, err
+	}
+	if memory != "" {
+		err = cmd.Flags().Set("memory", memory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory %q: %w", memory, err)
+		}
+	}
+	timeout, err := ask(cmd, "Timeout such as 30s, blank for the default of 3s", "")
+	if err != nil {
+		return nil, err
+	}
+	if timeout != "" {
+		err = cmd.Flags().Set("timeout", timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %w", timeout, err)
+		}
+	}
+	err = askPolicies(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = askTrigger(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return []string{name, source}, nil
+}
+
+func askPolicies(cmd *cobra.Command) error {
+	labels := make([]string, len(policyPresets))
+	for i, p := range policyPresets {
+		labels[i] = fmt.Sprintf("%s (%s)", p.Label, p.Policy)
+	}
+	picked, err := chooseMany(cmd, "What else does the function need access to?", labels)
+	if err != nil {
+		return err
+	}
+	var policies []string
+	for _, i := range picked {
+		policies = append(policies, policyPresets[i].Policy)
+	}
+	other, err := ask(cmd, "Any other managed policies, by name or ARN, comma separated", "")
+	if err != nil {
+		return err
+	}
+	if other != "" {
+		policies = append(policies, other)
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	return cmd.Flags().Set("managed-policies", strings.Join(policies, ","))
+}
+
+func askTrigger(cmd *cobra.Command) error {
+	choice, err := choose(cmd, "What invokes the function?", triggerChoices)
+	if err != nil {
+		return err
+	}
+	switch choice {
+	case 1:
+		return cmd.Flags().Set("function-url", "NONE")
+	case 2:
+		return cmd.Flags().Set("http-api", "true")
+	case 3:
+		bucket, err := ask(cmd, "Bucket name", "")
+		if err != nil || bucket == "" {
+			return errors.Join(err, errors.New("a bucket name is required"))
+		}
+		return cmd.Flags().Set("s3-trigger", bucket)
+	case 4:
+		queue, err := ask(cmd, "Queue ARN", "")
+		if err != nil || queue == "" {
+			return errors.Join(err, errors.New("a queue ARN is required"))
+		}
+		return cmd.Flags().Set("sqs-trigger", queue)
+	}
+	return nil
+}
+
+// ask prompts for a single answer, returning def when the answer is blank.
+func ask(cmd *cobra.Command, question, def string) (string, error) {
+	if def != "" {
+		cmd.Printf("%s [%s]: ", question, def)
+	} else {
+		cmd.Printf("%s: ", question)
+	}
+	answer, err := readLine(cmd.InOrStdin())
+	if err != nil {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// choose prompts for one of a numbered list of options, defaulting to the
+// first, and returns its index.
+func choose(cmd *cobra.Command, question string, options []string) (int, error) {
+	cmd.Println(question)
+	for i, o := range options {
+		cmd.Printf("  %d) %s\n", i+1, o)
+	}
+	answer, err := ask(cmd, "choose one", "1")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(options) {
+		return 0, fmt.Errorf("invalid choice %q, expected a number from 1 to %d", answer, len(options))
+	}
+	return n - 1, nil
+}
+
+// chooseMany is [choose] for any number of options, picked as a comma
+// separated list of numbers. A blank answer picks none.
+func chooseMany(cmd *cobra.Command, question string, options []string) ([]int, error) {
+	cmd.Println(question)
+	for i, o := range options {
+		cmd.Printf("  %d) %s\n", i+1, o)
+	}
+	answer, err := ask(cmd, "choose any, e.g. 1,3", "")
+	if err != nil || answer == "" {
+		return nil, err
+	}
+	var picked []int
+	for _, field := range strings.Split(answer, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 || n > len(options) {
+			return nil, fmt.Errorf("invalid choice %q, expected numbers from 1 to %d", field, len(options))
+		}
+		picked = append(picked, n-1)
+	}
+	return picked, nil
+}
@@ -0,0 +1,179 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// ProvisionedConcurrencyPollInterval is how often provisioned concurrency is
+// checked while waiting for it to be ready.
+var ProvisionedConcurrencyPollInterval = 5 * time.Second
+
+// ProvisionedConcurrencyTimeout is how long to wait for provisioned
+// concurrency to be ready. Large allocations can take several minutes.
+var ProvisionedConcurrencyTimeout = 15 * time.Minute
+
+// ProvisionedConcurrency keeps Executions instances of the version an alias
+// points at initialised, so that requests they serve never cold start.
+type ProvisionedConcurrency struct {
+	Alias      string
+	Executions int
+}
+
+// WithReservedConcurrency is a deploy option that reserves n concurrent
+// executions of the account's pool for the function, which is also the most
+// it can run at once. Zero stops the function being invoked at all.
+func WithReservedConcurrency(n int) DeployOptions {
+	return func(l *Lambda) error {
+		if n < 0 {
+			return fmt.Errorf("reserved concurrency must not be negative, got %d", n)
+		}
+		l.ReservedConcurrency = aws.Int(n)
+		return nil
+	}
+}
+
+// WithProvisionedConcurrency is a deploy option that keeps n instances of the
+// function initialised behind alias. After each deploy the alias is pointed
+// at the new version, unless a [WithCanary] deploy is shifting it there, and
+// publishing waits until the instances are ready.
+func WithProvisionedConcurrency(alias string, n int) DeployOptions {
+	return func(l *Lambda) error {
+		if alias == "" || alias == "$LATEST" {
+			return fmt.Errorf("provisioned concurrency needs an alias, got %q", alias)
+		}
+		if n < 1 {
+			return fmt.Errorf("provisioned concurrency must be at least 1, got %d", n)
+		}
+		l.ProvisionedConcurrency = &ProvisionedConcurrency{Alias: alias, Executions: n}
+		return nil
+	}
+}
+
+// ConcurrencyAction is an [Action] that applies a function's reserved and
+// provisioned concurrency once a version has been published.
+type ConcurrencyAction struct {
+	client      LambdaClient
+	Name        string
+	Version     string
+	Reserved    *int
+	Provisioned *ProvisionedConcurrency
+	// MoveAlias points the provisioned concurrency alias at Version first.
+	MoveAlias bool
+}
+
+// NewConcurrencyAction is a constructor function that creates a new [ConcurrencyAction].
+func NewConcurrencyAction(client LambdaClient, l Lambda, version string) ConcurrencyAction {
+	a := ConcurrencyAction{
+		client:      client,
+		Name:        l.Name,
+		Version:     version,
+		Reserved:    l.ReservedConcurrency,
+		Provisioned: l.ProvisionedConcurrency,
+	}
+	if p := l.ProvisionedConcurrency; p != nil {
+		a.MoveAlias = l.Canary == nil || p.Alias != LiveAlias
+	}
+	return a
+}
+
+// Client returns the required client type. In this case [LambdaClient].
+func (a ConcurrencyAction) Client() LambdaClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface.
+func (a ConcurrencyAction) Do(ctx context.Context) error {
+	client := a.Client()
+	if a.Reserved != nil {
+		_, err := client.PutFunctionConcurrency(ctx, &lambda.PutFunctionConcurrencyInput{
+			FunctionName:                 aws.String(a.Name),
+			ReservedConcurrentExecutions: aws.Int32(int32(*a.Reserved)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	p := a.Provisioned
+	if p == nil {
+		return nil
+	}
+	if a.MoveAlias {
+		_, err := pointAlias(ctx, client, a.Name, p.Alias, a.Version)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := client.PutProvisionedConcurrencyConfig(ctx, &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    aws.String(a.Name),
+		Qualifier:                       aws.String(p.Alias),
+		ProvisionedConcurrentExecutions: aws.Int32(int32(p.Executions)),
+	})
+	if err != nil {
+		return err
+	}
+	return a.waitForProvisioned(ctx, p.Alias)
+}
+
+func (a ConcurrencyAction) waitForProvisioned(ctx context.Context, alias string) error {
+	deadline := time.Now().Add(ProvisionedConcurrencyTimeout)
+	for {
+		resp, err := a.Client().GetProvisionedConcurrencyConfig(ctx, &lambda.GetProvisionedConcurrencyConfigInput{
+			FunctionName: aws.String(a.Name),
+			Qualifier:    aws.String(alias),
+		})
+		if err != nil {
+			return err
+		}
+		switch resp.Status {
+		case types.ProvisionedConcurrencyStatusEnumReady:
+			return nil
+		case types.ProvisionedConcurrencyStatusEnumFailed:
+			return fmt.Errorf("provisioned concurrency on %s:%s failed, %s", a.Name, alias, aws.ToString(resp.StatusReason))
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("provisioned concurrency on %s:%s wasn't ready after %s, %d of %d instances allocated",
+				a.Name, alias, ProvisionedConcurrencyTimeout,
+				aws.ToInt32(resp.AllocatedProvisionedConcurrentExecutions), aws.ToInt32(resp.RequestedProvisionedConcurrentExecutions))
+		}
+		timer := time.NewTimer(ProvisionedConcurrencyPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func describeConcurrency(name string, reserved *int, provisioned *ProvisionedConcurrency) []Change {
+	var changes []Change
+	if reserved != nil {
+		changes = append(changes, Change{
+			Operation:    "PutFunctionConcurrency",
+			ResourceType: "lambda_function_concurrency",
+			Resource:     name,
+			Action:       "update",
+			After:        map[string]any{"reserved_concurrent_executions": *reserved},
+			Details:      []string{fmt.Sprintf("reserved concurrency: %d", *reserved)},
+		})
+	}
+	if provisioned != nil {
+		changes = append(changes, Change{
+			Operation:    "PutProvisionedConcurrencyConfig",
+			ResourceType: "lambda_provisioned_concurrency_config",
+			Resource:     name + ":" + provisioned.Alias,
+			Action:       "update",
+			After:        map[string]any{"provisioned_concurrent_executions": provisioned.Executions},
+			Details: []string{
+				fmt.Sprintf("provisioned concurrency: %d on alias %s, pointed at the new version", provisioned.Executions, provisioned.Alias),
+			},
+		})
+	}
+	return changes
+}
@@ -0,0 +1,96 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestConcurrencyOptions_RejectInvalidSettings(t *testing.T) {
+	t.Parallel()
+	opts := map[string]glambda.DeployOptions{
+		"negative reserved":   glambda.WithReservedConcurrency(-1),
+		"no alias":            glambda.WithProvisionedConcurrency("", 5),
+		"unqualified alias":   glambda.WithProvisionedConcurrency("$LATEST", 5),
+		"too few provisioned": glambda.WithProvisionedConcurrency(glambda.LiveAlias, 0),
+	}
+	for name, opt := range opts {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), opt)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestDeployAndPublish_AppliesConcurrencyAfterPublishing(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	result, err := glambda.DeployAndPublish(context.Background(), "concurrent", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithReservedConcurrency(0),
+		glambda.WithProvisionedConcurrency("warm", 5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved := recorder.Calls("PutFunctionConcurrency")
+	if len(reserved) != 1 || aws.ToInt32(reserved[0].Input.(*lambda.PutFunctionConcurrencyInput).ReservedConcurrentExecutions) != 0 {
+		t.Errorf("expected reserved concurrency of 0 to be set, got %v", recorder.Operations())
+	}
+	alias := recorder.Calls("CreateAlias")
+	if len(alias) != 1 || aws.ToString(alias[0].Input.(*lambda.CreateAliasInput).FunctionVersion) != result.Version {
+		t.Errorf("expected alias to be created at the new version, got %v", recorder.Operations())
+	}
+	provisioned := recorder.Calls("PutProvisionedConcurrencyConfig")
+	if len(provisioned) != 1 {
+		t.Fatalf("expected provisioned concurrency to be set, got %v", recorder.Operations())
+	}
+	input := provisioned[0].Input.(*lambda.PutProvisionedConcurrencyConfigInput)
+	if aws.ToString(input.Qualifier) != "warm" || aws.ToInt32(input.ProvisionedConcurrentExecutions) != 5 {
+		t.Errorf("expected 5 instances on warm, got %+v", input)
+	}
+	if len(recorder.Calls("GetProvisionedConcurrencyConfig")) == 0 {
+		t.Error("expected to wait for provisioned concurrency to be ready")
+	}
+	if result.Alias != "warm" {
+		t.Errorf("expected result to report the warm alias, got %q", result.Alias)
+	}
+}
+
+func TestConcurrencyAction_FailsWhenProvisioningFails(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetProvisionedConcurrencyConfig", &lambda.GetProvisionedConcurrencyConfigOutput{
+		Status:       types.ProvisionedConcurrencyStatusEnumFailed,
+		StatusReason: aws.String("account concurrency limit exceeded"),
+	})
+	l, err := glambda.NewLambda("fn", "", glambdatest.SandboxWithRecorder(recorder), glambda.WithProvisionedConcurrency(glambda.LiveAlias, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewConcurrencyAction(glambdatest.DummyLambdaClient{Recorder: recorder}, *l, "3").Do(context.Background())
+	if err == nil {
+		t.Fatal("expected error for failed provisioning, got nil")
+	}
+}
+
+func TestConcurrencyAction_LeavesCanaryAliasToTheCanary(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithCanary(10, time.Minute),
+		glambda.WithProvisionedConcurrency(glambda.LiveAlias, 2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if glambda.NewConcurrencyAction(glambdatest.DummyLambdaClient{}, *l, "3").MoveAlias {
+		t.Error("expected the canary, not the concurrency action, to move the live alias")
+	}
+}
@@ -0,0 +1,218 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultConcurrency is how many functions the CLI deploys at once from a
+// config file or a ./... pattern.
+const DefaultConcurrency = 4
+
+// DeployAllAndPublish deploys several functions, at most concurrency of them
+// at a time, and reports what was published for each function that deployed
+// successfully, including those whose version failed its smoke test. The
+// given options apply to every function, before its own settings.
+//
+// Every function is planned with [PlanAll] first, so that a handler that
+// doesn't build, or a plan that breaks a policy, fails the whole deploy
+// before anything in AWS is changed. After that, a failure to deploy one
+// function doesn't stop the others; it is reported as a "failed" [Event] as
+// soon as it happens, and all failures are returned together. Results and
+// errors are in the order the functions were given, however the deploys
+// finish. A function isn't deployed until the functions it depends on, see
+// [FunctionConfig.DependsOn], have been, nor at all if one of them fails.
+func DeployAllAndPublish(ctx context.Context, functions []FunctionConfig, concurrency int, opts ...DeployOptions) ([]DeployResult, error) {
+	plans, err := PlanAll(ctx, functions, concurrency, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyAll(ctx, plans, concurrency)
+}
+
+// PlanAll builds the handler of every function and reads the live state of
+// its role and function, at most concurrency at a time, returning a [Plan] for
+// each in the order given. Nothing is changed. Plans are checked against the
+// policy bundle given to [WithPolicyBundle], if any. Every function is
+// planned even when some fail, so that all the problems are reported at once.
+// The plans can be carried out with [ApplyAll].
+func PlanAll(ctx context.Context, functions []FunctionConfig, concurrency int, opts ...DeployOptions) ([]Plan, error) {
+	plans := make([]Plan, len(functions))
+	err := forEach(len(functions), concurrency, func(i int) error {
+		fn := functions[i]
+		plan, err := planFunction(ctx, fn, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fn.Name, err)
+		}
+		if bundle := plan.prepared.lambda.policyBundle; bundle != "" {
+			err = CheckPolicies(bundle, plan)
+			if err != nil {
+				plan.prepared.lambda.report("failed", "plan failed: %v", err)
+				return fmt.Errorf("%s: %w", fn.Name, err)
+			}
+		}
+		plan.dependsOn = fn.DependsOn
+		if fn.Release != "" {
+			err = plan.prepared.lambda.checkRelease(fn.Release)
+			if err != nil {
+				plan.prepared.lambda.report("failed", "plan failed: %v", err)
+				return fmt.Errorf("%s: %w", fn.Name, err)
+			}
+			plan.release = fn.Release
+			plan.Changes = append(plan.Changes, describeRelease(fn.Name, fn.Release))
+		}
+		plans[i] = plan
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// ApplyAll carries out plans returned by [PlanAll], at most concurrency at a
+// time, publishing a new version of each function. Plans are applied in the
+// order given, except that a function's plan waits for the plans of the
+// functions it depends on. A failure to apply one plan doesn't stop the
+// others, apart from those that depend on it; all failures are returned
+// together, along with the results of the functions that were published.
+//
+// Functions in a release group, see [FunctionConfig.Release], are published
+// without moving their [LiveAlias]. Once every function in the group has
+// been, their aliases are switched to the new versions one after another, so
+// that interdependent functions run mismatched versions for as short a time as
+// possible. If any function in the group fails, no alias is switched, and if
+// switching one fails, those already switched are switched back.
+func ApplyAll(ctx context.Context, plans []Plan, concurrency int) ([]DeployResult, error) {
+	index := map[string]int{}
+	for i, p := range plans {
+		index[p.Function] = i
+	}
+	// Dependencies that aren't being deployed are taken to be in place.
+	after := make([][]int, len(plans))
+	for i, p := range plans {
+		for _, name := range p.dependsOn {
+			if d, ok := index[name]; ok {
+				after[i] = append(after[i], d)
+			}
+		}
+	}
+	results := make([]*DeployResult, len(plans))
+	applied := make([]bool, len(plans))
+	err := forEachAfter(len(plans), concurrency, after, func(i int) error {
+		p := plans[i].prepared
+		if p == nil {
+			return fmt.Errorf("plan for %s can't be applied, only plans returned by PlanAll can", plans[i].Function)
+		}
+		for _, d := range after[i] {
+			if !applied[d] {
+				p.lambda.report("failed", "not deployed, as %s failed to deploy", plans[d].Function)
+				return fmt.Errorf("%s: not deployed, as %s failed to deploy", plans[i].Function, plans[d].Function)
+			}
+		}
+		result, err := p.apply(ctx)
+		if result.Version != "" {
+			results[i] = &result
+		}
+		var failed *SmokeTestError
+		if err != nil && !errors.As(err, &failed) {
+			p.lambda.report("failed", "deploy failed: %v", err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", plans[i].Function, err)
+		}
+		applied[i] = true
+		return nil
+	})
+	groups := map[string][]int{}
+	for i, p := range plans {
+		if p.release != "" {
+			groups[p.release] = append(groups[p.release], i)
+		}
+	}
+	for _, group := range sortedKeys(groups) {
+		var members []releaseMember
+		var failed []string
+		for _, i := range groups[group] {
+			if !applied[i] {
+				failed = append(failed, plans[i].Function)
+				continue
+			}
+			members = append(members, releaseMember{lambda: plans[i].prepared.lambda, version: results[i].Version})
+		}
+		if len(failed) > 0 {
+			err = errors.Join(err, fmt.Errorf("release %s: %s aliases left as they were, as %s failed to deploy", group, LiveAlias, strings.Join(failed, ", ")))
+			continue
+		}
+		releaseErr := switchRelease(ctx, group, members)
+		if releaseErr != nil {
+			err = errors.Join(err, releaseErr)
+			continue
+		}
+		for _, i := range groups[group] {
+			results[i].Alias = LiveAlias
+			results[i].AliasARN = QualifiedARN(plans[i].prepared.lambda.functionARN(), LiveAlias)
+		}
+	}
+	var published []DeployResult
+	for _, result := range results {
+		if result != nil {
+			published = append(published, *result)
+		}
+	}
+	return published, err
+}
+
+// forEach calls f with each index below n, at most concurrency at a time,
+// returning the errors in index order.
+func forEach(n, concurrency int, f func(i int) error) error {
+	return forEachAfter(n, concurrency, nil, f)
+}
+
+// forEachAfter is [forEach], only calling f with an index once it has
+// returned for every index in after[i]. Of the indexes that are ready, the
+// lowest is started first, so that with a concurrency of 1 the calls are in
+// index order wherever after allows.
+func forEachAfter(n, concurrency int, after [][]int, f func(i int) error) error {
+	if concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d, must be at least 1", concurrency)
+	}
+	errs := make([]error, n)
+	started := make([]bool, n)
+	finished := make([]bool, n)
+	done := make(chan int)
+	running := 0
+	for remaining := n; remaining > 0; remaining-- {
+		for i := 0; i < n && running < concurrency; i++ {
+			if started[i] || !allFinished(after, i, finished) {
+				continue
+			}
+			started[i] = true
+			running++
+			go func() {
+				errs[i] = f(i)
+				done <- i
+			}()
+		}
+		if running == 0 {
+			return fmt.Errorf("dependency cycle between %d functions", remaining)
+		}
+		finished[<-done] = true
+		running--
+	}
+	return errors.Join(errs...)
+}
+
+func allFinished(after [][]int, i int, finished []bool) bool {
+	if after == nil {
+		return true
+	}
+	for _, d := range after[i] {
+		if !finished[d] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestDeployAllAndPublish_DeploysEveryFunctionConcurrently(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.HandlerConfig("testdata/multi_handler_repo/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := glambdatest.NewRecorder()
+	results, err := glambda.DeployAllAndPublish(context.Background(), cfg.Functions, 2, glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].FunctionName != "cmd-api" || results[1].FunctionName != "cmd-worker" {
+		t.Errorf("expected results in the order given, got %+v", results)
+	}
+	if calls := recorder.Calls("CreateFunction"); len(calls) != 2 {
+		t.Errorf("expected 2 functions created, got %d", len(calls))
+	}
+}
+
+func TestDeployAllAndPublish_ChangesNothingWhenAnyHandlerFailsToBuild(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "fine", Handler: "testdata/correct_test_handler/main.go"},
+		{Name: "broken", Handler: "testdata/invalid_go_source.go"},
+	}
+	recorder := glambdatest.NewRecorder()
+	var mu sync.Mutex
+	var failed []string
+	results, err := glambda.DeployAllAndPublish(context.Background(), functions, 2,
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithProgress(func(e glambda.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			if e.Step == "failed" {
+				failed = append(failed, e.Function)
+			}
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected error for the broken function, got nil")
+	}
+	if len(results) != 0 {
+		t.Errorf("expected nothing deployed, got %+v", results)
+	}
+	for _, op := range []string{"CreateRole", "CreateFunction", "UpdateFunctionCode", "AttachRolePolicy"} {
+		if calls := recorder.Calls(op); len(calls) != 0 {
+			t.Errorf("expected no %s calls before every handler built, got %d", op, len(calls))
+		}
+	}
+	if len(failed) != 1 || failed[0] != "broken" {
+		t.Errorf("expected a failed event for broken, got %v", failed)
+	}
+}
+
+func TestDeployAllAndPublish_ReportsDeployFailuresWithoutStoppingOthers(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "first", Handler: "testdata/correct_test_handler/main.go"},
+		{Name: "second", Handler: "testdata/correct_test_handler/main.go"},
+	}
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", errors.New("access denied"), 1)
+	results, err := glambda.DeployAllAndPublish(context.Background(), functions, 1, glambdatest.SandboxWithRecorder(recorder))
+	if err == nil {
+		t.Fatal("expected error for the function that failed to deploy, got nil")
+	}
+	if len(results) != 1 || results[0].FunctionName != "second" {
+		t.Errorf("expected the second function to deploy, got %+v", results)
+	}
+}
+
+func TestDeployAllAndPublish_DeploysDependenciesFirst(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "api", Handler: "testdata/correct_test_handler/main.go", DependsOn: []string{"authorizer"}},
+		{Name: "authorizer", Handler: "testdata/correct_test_handler/main.go"},
+		{Name: "worker", Handler: "testdata/correct_test_handler/main.go"},
+	}
+	recorder := glambdatest.NewRecorder()
+	_, err := glambda.DeployAllAndPublish(context.Background(), functions, 1, glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, call := range recorder.Calls("CreateFunction") {
+		order = append(order, aws.ToString(call.Input.(*lambda.CreateFunctionInput).FunctionName))
+	}
+	if !slices.Equal(order, []string{"authorizer", "api", "worker"}) {
+		t.Errorf("want authorizer deployed before api, otherwise in the order given, got %v", order)
+	}
+}
+
+func TestDeployAllAndPublish_SkipsFunctionsWhoseDependencyFailed(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "authorizer", Handler: "testdata/correct_test_handler/main.go"},
+		{Name: "api", Handler: "testdata/correct_test_handler/main.go", DependsOn: []string{"authorizer"}},
+		{Name: "worker", Handler: "testdata/correct_test_handler/main.go"},
+	}
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", errors.New("access denied"), 1)
+	results, err := glambda.DeployAllAndPublish(context.Background(), functions, 1, glambdatest.SandboxWithRecorder(recorder))
+	if err == nil || !strings.Contains(err.Error(), "api: not deployed, as authorizer failed to deploy") {
+		t.Fatalf("want api skipped as its authorizer failed, got %v", err)
+	}
+	if len(results) != 1 || results[0].FunctionName != "worker" {
+		t.Errorf("want only worker deployed, got %+v", results)
+	}
+}
+
+func TestDeployAllAndPublish_SwitchesReleaseAliasesOnceEveryFunctionIsPublished(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "api", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+		{Name: "worker", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+	}
+	recorder := glambdatest.NewRecorder()
+	results, err := glambda.DeployAllAndPublish(context.Background(), functions, 2, glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := recorder.Operations()
+	published := 0
+	for _, op := range ops {
+		if op == "PublishVersion" {
+			published++
+		}
+		if op == "CreateAlias" && published < 2 {
+			t.Fatalf("want aliases switched after both functions were published, got %v", ops)
+		}
+	}
+	if calls := recorder.Calls("CreateAlias"); len(calls) != 2 {
+		t.Errorf("want the live alias of both functions created, got %d", len(calls))
+	}
+	for _, r := range results {
+		if r.Alias != glambda.LiveAlias {
+			t.Errorf("%s: want result for alias %s, got %q", r.FunctionName, glambda.LiveAlias, r.Alias)
+		}
+	}
+}
+
+func TestDeployAllAndPublish_LeavesReleaseAliasesWhenAFunctionFails(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "api", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+		{Name: "worker", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+	}
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", errors.New("access denied"), 1)
+	_, err := glambda.DeployAllAndPublish(context.Background(), functions, 1, glambdatest.SandboxWithRecorder(recorder))
+	if err == nil || !strings.Contains(err.Error(), "release checkout: live aliases left as they were, as api failed to deploy") {
+		t.Fatalf("want release checkout left unswitched, got %v", err)
+	}
+	if calls := recorder.Calls("CreateAlias", "UpdateAlias"); len(calls) != 0 {
+		t.Errorf("want no alias switched, got %d", len(calls))
+	}
+}
+
+func TestDeployAllAndPublish_SwitchesReleaseBackWhenAnAliasFails(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "api", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+		{Name: "worker", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+	}
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("GetAlias", &types.ResourceNotFoundException{Message: aws.String("no alias")}, 1)
+	recorder.Respond("GetAlias", &lambda.GetAliasOutput{FunctionVersion: aws.String("41")})
+	recorder.FailNext("UpdateAlias", errors.New("throttled"), 1)
+	_, err := glambda.DeployAllAndPublish(context.Background(), functions, 1, glambdatest.SandboxWithRecorder(recorder))
+	if err == nil || !strings.Contains(err.Error(), "worker: switching release checkout: throttled") {
+		t.Fatalf("want the failed switch reported, got %v", err)
+	}
+	deleted := recorder.Calls("DeleteAlias")
+	if len(deleted) != 1 || aws.ToString(deleted[0].Input.(*lambda.DeleteAliasInput).FunctionName) != "api" {
+		t.Errorf("want the alias created for api deleted again, got %+v", deleted)
+	}
+}
+
+func TestPlanAll_RejectsCanariesInAReleaseGroup(t *testing.T) {
+	t.Parallel()
+	functions := []glambda.FunctionConfig{
+		{Name: "api", Handler: "testdata/correct_test_handler/main.go", Release: "checkout"},
+	}
+	_, err := glambda.PlanAll(context.Background(), functions, 1, glambdatest.Sandbox(), glambda.WithCanary(10, time.Minute))
+	if err == nil || !strings.Contains(err.Error(), "can't be part of release checkout") {
+		t.Errorf("want canary rejected in a release group, got %v", err)
+	}
+}
+
+func TestApplyAll_RejectsPlansNotFromPlanAll(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.ApplyAll(context.Background(), []glambda.Plan{{Function: "decoded"}}, 1)
+	if err == nil {
+		t.Error("expected error applying a plan without prepared actions, got nil")
+	}
+}
+
+func TestDeployAllAndPublish_RejectsInvalidConcurrency(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.DeployAllAndPublish(context.Background(), nil, 0)
+	if err == nil {
+		t.Error("expected invalid concurrency error, got nil")
+	}
+}
@@ -0,0 +1,382 @@
+package glambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the name of the project config file read by
+// `glambda up` when no other file is given.
+const DefaultConfigFile = "glambda.yaml"
+
+// Config is a project level description of the lambda functions to deploy,
+// usually kept in a glambda.yaml file alongside the source code.
+type Config struct {
+	Functions []FunctionConfig `yaml:"functions"`
+}
+
+// FunctionConfig describes a single lambda function in a [Config]. Apart from
+// Name and Handler every field is optional, and mirrors one of the
+// [DeployOptions].
+type FunctionConfig struct {
+	Name    string `yaml:"name"`
+	Handler string `yaml:"handler"`
+	// Memory is in MB, see [WithMemory].
+	Memory int `yaml:"memory"`
+	// Timeout is a duration such as 30s, see [WithTimeout].
+	Timeout time.Duration `yaml:"timeout"`
+	// Architecture is arm64 or x86_64, see [WithArchitecture].
+	Architecture    string            `yaml:"architecture"`
+	ManagedPolicies []string          `yaml:"managed_policies"`
+	Environment     map[string]string `yaml:"environment"`
+	// KMSKey encrypts the environment, see [WithKMSKey].
+	KMSKey string `yaml:"kms_key"`
+	// KMSGrants grants use of the keys encrypting the function's buckets
+	// and queues, see [WithKMSGrants].
+	KMSGrants bool `yaml:"kms_grants"`
+	// VPC connects the function to a VPC, see [WithVPCConfig].
+	VPC *VPCConfig `yaml:"vpc"`
+	// Tags apply to the function and its role, see [WithTags].
+	Tags map[string]string `yaml:"tags"`
+	// RoleDescription describes a role glambda creates, see [WithDescription].
+	RoleDescription string `yaml:"role_description"`
+	// InlinePolicy and ResourcePolicy may be given either as a JSON string or
+	// as a YAML mapping with the same structure.
+	InlinePolicy   any `yaml:"inline_policy"`
+	ResourcePolicy any `yaml:"resource_policy"`
+	// Include lists files and directories to add to the zip, relative to
+	// the config file, see [WithAssets].
+	Include []string `yaml:"include"`
+	// SensitiveAssets allows .env files and .git directories among the
+	// included files, see [WithSensitiveAssets].
+	SensitiveAssets bool `yaml:"sensitive_assets"`
+	// Exclude lists patterns for included files to leave out, such as
+	// "*.md", matched relative to the config file, see [WithExcludes].
+	Exclude []string `yaml:"exclude"`
+	// DependsOn names functions in the same config that must be deployed
+	// before this one, such as an authorizer the function's API uses.
+	DependsOn []string `yaml:"depends_on"`
+	// S3Triggers invoke the function on events in S3 buckets, see
+	// [WithS3Trigger].
+	S3Triggers []S3TriggerConfig `yaml:"s3_triggers"`
+	// EventSources are SQS queues and DynamoDB or Kinesis streams whose
+	// records invoke the function.
+	EventSources []EventSourceConfig `yaml:"event_sources"`
+	// FunctionURL gives the function an HTTPS endpoint of its own, see
+	// [WithFunctionURL].
+	FunctionURL *FunctionURLConfig `yaml:"function_url"`
+	// HTTPAPI puts an API Gateway HTTP API in front of the function, see
+	// [WithHTTPAPI].
+	HTTPAPI *HTTPAPI `yaml:"http_api"`
+	// Naming overrides the names of the role, inline policy and resource
+	// policy statement glambda creates, see [WithNamingConvention].
+	Naming *NamingConvention `yaml:"naming"`
+	// Aliases are pointed at each new version, with memory, environment and
+	// provisioned concurrency of their own, see [WithAlias].
+	Aliases []AliasConfig `yaml:"aliases"`
+	// Release names a group of functions whose [LiveAlias] aliases are
+	// switched to their new versions together, once every function in the
+	// group has been published, see [ApplyAll].
+	Release string `yaml:"release"`
+	// Dir is the directory of the config file the function was loaded from,
+	// which Include is relative to. It is set by [LoadConfig].
+	Dir string `yaml:"-"`
+}
+
+// S3TriggerConfig is an S3 trigger in a [FunctionConfig], see
+// [WithS3Trigger]. Events default to [DefaultS3Events].
+type S3TriggerConfig struct {
+	Bucket string   `yaml:"bucket"`
+	Events []string `yaml:"events"`
+	Prefix string   `yaml:"prefix"`
+	Suffix string   `yaml:"suffix"`
+}
+
+// EventSourceConfig is a trigger in a [FunctionConfig] that invokes the
+// function with records from an SQS queue, see [WithSQSTrigger], or from a
+// DynamoDB or Kinesis stream, see [WithDynamoStreamTrigger] and
+// [WithKinesisTrigger]. Which it is comes from the ARN.
+type EventSourceConfig struct {
+	ARN string `yaml:"arn"`
+	// StartingPosition is TRIM_HORIZON or LATEST, and only applies to
+	// streams.
+	StartingPosition string `yaml:"starting_position"`
+	BatchSize        int    `yaml:"batch_size"`
+	// BatchingWindow is in seconds, see [WithBatchingWindow].
+	BatchingWindow          *int `yaml:"batching_window"`
+	ReportBatchItemFailures bool `yaml:"report_batch_item_failures"`
+	// Filters are event filter patterns, see [WithEventFilter].
+	Filters []string `yaml:"filters"`
+	// MaximumConcurrency only applies to queues, see
+	// [WithMaximumConcurrency].
+	MaximumConcurrency int `yaml:"maximum_concurrency"`
+	// ParallelizationFactor and OnFailure only apply to streams, see
+	// [WithParallelizationFactor] and [WithOnFailureDestination].
+	ParallelizationFactor int    `yaml:"parallelization_factor"`
+	OnFailure             string `yaml:"on_failure"`
+}
+
+// option translates the event source into the deploy option for its kind.
+func (e EventSourceConfig) option() (DeployOptions, error) {
+	var opts []TriggerOption
+	if e.BatchingWindow != nil {
+		opts = append(opts, WithBatchingWindow(*e.BatchingWindow))
+	}
+	if e.ReportBatchItemFailures {
+		opts = append(opts, WithReportBatchItemFailures())
+	}
+	for _, f := range e.Filters {
+		opts = append(opts, WithEventFilter(f))
+	}
+	if e.MaximumConcurrency != 0 {
+		opts = append(opts, WithMaximumConcurrency(e.MaximumConcurrency))
+	}
+	if e.ParallelizationFactor != 0 {
+		opts = append(opts, WithParallelizationFactor(e.ParallelizationFactor))
+	}
+	if e.OnFailure != "" {
+		opts = append(opts, WithOnFailureDestination(e.OnFailure))
+	}
+	switch {
+	case strings.Contains(e.ARN, ":sqs:"):
+		if e.StartingPosition != "" {
+			return nil, fmt.Errorf("event source %s is a queue, starting_position only applies to streams", e.ARN)
+		}
+		return WithSQSTrigger(e.ARN, e.BatchSize, opts...), nil
+	case strings.Contains(e.ARN, ":dynamodb:"):
+		return WithDynamoStreamTrigger(e.ARN, e.StartingPosition, e.BatchSize, opts...), nil
+	case strings.Contains(e.ARN, ":kinesis:"):
+		return WithKinesisTrigger(e.ARN, e.StartingPosition, e.BatchSize, opts...), nil
+	}
+	return nil, fmt.Errorf("event source %q is not the ARN of an SQS queue, DynamoDB stream or Kinesis stream", e.ARN)
+}
+
+// FunctionURLConfig is a function URL in a [FunctionConfig], see
+// [WithFunctionURL]. Auth is NONE or AWS_IAM, and InvokeMode is BUFFERED, the
+// default, or RESPONSE_STREAM, see [WithInvokeMode].
+type FunctionURLConfig struct {
+	Auth         string   `yaml:"auth"`
+	AllowOrigins []string `yaml:"allow_origins"`
+	InvokeMode   string   `yaml:"invoke_mode"`
+}
+
+// LoadConfig reads a [Config] from a YAML file. Relative handler paths are
+// resolved against the directory containing the file, so a config behaves the
+// same wherever glambda is run from.
+func LoadConfig(path string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading config file, %w", err)
+	}
+	defer f.Close()
+	cfg, err := ParseConfig(f)
+	if err != nil {
+		return Config{}, fmt.Errorf("error in config file %s, %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for i, fn := range cfg.Functions {
+		if !filepath.IsAbs(fn.Handler) {
+			cfg.Functions[i].Handler = filepath.Join(dir, filepath.FromSlash(fn.Handler))
+		}
+		cfg.Functions[i].Dir = dir
+	}
+	return cfg, nil
+}
+
+// ParseConfig decodes and validates a [Config]. Unknown keys are rejected, so
+// that a misspelt setting is an error rather than being silently ignored.
+func ParseConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	err := dec.Decode(&cfg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, err
+	}
+	if len(cfg.Functions) == 0 {
+		return Config{}, fmt.Errorf("no functions defined")
+	}
+	seen := map[string]bool{}
+	for i, fn := range cfg.Functions {
+		if fn.Name == "" {
+			return Config{}, fmt.Errorf("function %d has no name", i+1)
+		}
+		if fn.Handler == "" {
+			return Config{}, fmt.Errorf("function %s has no handler", fn.Name)
+		}
+		if seen[fn.Name] {
+			return Config{}, fmt.Errorf("function %s is defined more than once", fn.Name)
+		}
+		seen[fn.Name] = true
+	}
+	for _, fn := range cfg.Functions {
+		for _, dep := range fn.DependsOn {
+			if !seen[dep] {
+				return Config{}, fmt.Errorf("function %s depends on %s, which isn't defined", fn.Name, dep)
+			}
+		}
+	}
+	cycle := dependencyCycle(cfg.Functions)
+	if cycle != nil {
+		return Config{}, fmt.Errorf("functions depend on each other in a cycle, %s", strings.Join(cycle, " -> "))
+	}
+	return cfg, nil
+}
+
+// dependencyCycle returns the names of functions that depend on each other in
+// a cycle, starting and ending with the same function, or nil if there is
+// none.
+func dependencyCycle(functions []FunctionConfig) []string {
+	deps := map[string][]string{}
+	for _, fn := range functions {
+		deps[fn.Name] = fn.DependsOn
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			start := slices.Index(path, name)
+			return append(slices.Clone(path[start:]), name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, fn := range functions {
+		if cycle := visit(fn.Name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// Options translates the function's settings into [DeployOptions].
+func (f FunctionConfig) Options() ([]DeployOptions, error) {
+	var opts []DeployOptions
+	if len(f.ManagedPolicies) > 0 {
+		opts = append(opts, WithManagedPolicies(strings.Join(f.ManagedPolicies, ",")))
+	}
+	inline, err := policyJSON(f.InlinePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("inline_policy: %w", err)
+	}
+	resource, err := policyJSON(f.ResourcePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("resource_policy: %w", err)
+	}
+	opts = append(opts, WithInlinePolicy(inline), WithResourcePolicy(resource))
+	if f.Environment != nil {
+		opts = append(opts, WithEnvironment(f.Environment))
+	}
+	if f.KMSKey != "" {
+		opts = append(opts, WithKMSKey(f.KMSKey))
+	}
+	if f.KMSGrants {
+		opts = append(opts, WithKMSGrants())
+	}
+	if len(f.Include) > 0 {
+		opts = append(opts, withAssetsIn(f.Dir, f.Include))
+	}
+	if f.SensitiveAssets {
+		opts = append(opts, WithSensitiveAssets())
+	}
+	if len(f.Exclude) > 0 {
+		opts = append(opts, WithExcludes(f.Exclude...))
+	}
+	if f.VPC != nil {
+		opts = append(opts, WithVPCConfig(f.VPC.SubnetIDs, f.VPC.SecurityGroupIDs))
+	}
+	if f.RoleDescription != "" {
+		opts = append(opts, WithDescription(f.RoleDescription))
+	}
+	if f.Tags != nil {
+		opts = append(opts, WithTags(f.Tags))
+	}
+	if f.Memory != 0 {
+		opts = append(opts, WithMemory(f.Memory))
+	}
+	if f.Timeout != 0 {
+		opts = append(opts, WithTimeout(f.Timeout))
+	}
+	if f.Architecture != "" {
+		opts = append(opts, WithArchitecture(f.Architecture))
+	}
+	if f.Naming != nil {
+		opts = append(opts, WithNamingConvention(*f.Naming))
+	}
+	for _, t := range f.S3Triggers {
+		opts = append(opts, WithS3Trigger(t.Bucket, t.Events, t.Prefix, t.Suffix))
+	}
+	for _, e := range f.EventSources {
+		opt, err := e.option()
+		if err != nil {
+			return nil, fmt.Errorf("event_sources: %w", err)
+		}
+		opts = append(opts, opt)
+	}
+	if f.FunctionURL != nil {
+		opts = append(opts, WithFunctionURL(f.FunctionURL.Auth, f.FunctionURL.AllowOrigins...))
+		if f.FunctionURL.InvokeMode != "" {
+			opts = append(opts, WithInvokeMode(f.FunctionURL.InvokeMode))
+		}
+	}
+	if f.HTTPAPI != nil {
+		opts = append(opts, WithHTTPAPI(f.HTTPAPI.Routes...))
+	}
+	for _, a := range f.Aliases {
+		opts = append(opts, WithAlias(a))
+	}
+	return opts, nil
+}
+
+func policyJSON(policy any) (string, error) {
+	switch p := policy.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return p, nil
+	}
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(policy)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// DeployConfig deploys every function in the [Config], in the order they are
+// listed, apart from functions listed before those they depend on. The given options apply to every function, before its own settings.
+// A failure to deploy one function doesn't stop the others being deployed;
+// all failures are returned together.
+func DeployConfig(ctx context.Context, cfg Config, opts ...DeployOptions) error {
+	_, err := DeployConfigAndPublish(ctx, cfg, opts...)
+	return err
+}
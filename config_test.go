@@ -0,0 +1,202 @@
+package glambda_test
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestLoadConfig_ReadsFunctionsAndResolvesHandlerPaths(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.LoadConfig(filepath.Join("testdata", "glambda.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Functions) != 2 {
+		t.Fatalf("expected 2 functions, got %d", len(cfg.Functions))
+	}
+	greeter := cfg.Functions[0]
+	want := glambda.FunctionConfig{
+		Name:        "greeter",
+		Handler:     filepath.Join("testdata", "correct_test_handler", "main.go"),
+		Memory:      256,
+		Timeout:     10 * time.Second,
+		Environment: map[string]string{"LOG_LEVEL": "debug"},
+		Dir:         "testdata",
+	}
+	if !cmp.Equal(want, greeter) {
+		t.Error(cmp.Diff(want, greeter))
+	}
+}
+
+func TestFunctionConfig_OptionsAcceptYAMLPolicies(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.LoadConfig(filepath.Join("testdata", "glambda.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := cfg.Functions[1].Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := glambda.NewLambda("multiFile", "", append([]glambda.DeployOptions{glambdatest.Sandbox()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPolicy := `{"Statement":[{"Action":"s3:GetObject","Effect":"Allow","Resource":"arn:aws:s3:::my-bucket/*"}],"Version":"2012-10-17"}`
+	if l.ExecutionRole.InLinePolicy != wantPolicy {
+		t.Errorf("expected inline policy %s, got %s", wantPolicy, l.ExecutionRole.InLinePolicy)
+	}
+	wantManaged := []string{"arn:aws:iam::aws:policy/AmazonDynamoDBReadOnlyAccess"}
+	if !cmp.Equal(wantManaged, l.ExecutionRole.ManagedPolicies) {
+		t.Error(cmp.Diff(wantManaged, l.ExecutionRole.ManagedPolicies))
+	}
+	if l.Architecture != "x86_64" {
+		t.Errorf("expected x86_64 architecture, got %q", l.Architecture)
+	}
+}
+
+func TestParseConfig_RejectsInvalidConfigs(t *testing.T) {
+	t.Parallel()
+	tc := map[string]string{
+		"no functions":    "functions: []\n",
+		"missing name":    "functions:\n  - handler: main.go\n",
+		"missing handler": "functions:\n  - name: a\n",
+		"duplicate name":  "functions:\n  - name: a\n    handler: a.go\n  - name: a\n    handler: b.go\n",
+		"unknown key":     "functions:\n  - name: a\n    handler: a.go\n    memroy: 256\n",
+		"unknown depends": "functions:\n  - name: a\n    handler: a.go\n    depends_on: [b]\n",
+		"depends on self": "functions:\n  - name: a\n    handler: a.go\n    depends_on: [a]\n",
+		"dependency cycle": "functions:\n  - name: a\n    handler: a.go\n    depends_on: [b]\n" +
+			"  - name: b\n    handler: b.go\n    depends_on: [c]\n  - name: c\n    handler: c.go\n    depends_on: [a]\n",
+	}
+	for description, config := range tc {
+		_, err := glambda.ParseConfig(strings.NewReader(config))
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", description)
+		}
+	}
+}
+
+func TestDeployConfig_DeploysEveryFunctionInSandbox(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.LoadConfig(filepath.Join("testdata", "glambda.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := glambdatest.NewRecorder()
+	err = glambda.DeployConfig(context.Background(), cfg, glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(recorder.Calls("CreateFunction")); got != 2 {
+		t.Errorf("expected 2 functions to be created, got %d", got)
+	}
+}
+
+const triggersConfig = `functions:
+  - name: orders
+    handler: testdata/correct_test_handler/main.go
+    s3_triggers:
+      - bucket: order-uploads
+        prefix: incoming/
+        suffix: .csv
+    event_sources:
+      - arn: arn:aws:sqs:us-east-1:123456789012:orders
+        batch_size: 50
+        batching_window: 5
+        report_batch_item_failures: true
+        filters:
+          - '{"body":{"type":["order"]}}'
+      - arn: arn:aws:kinesis:us-east-1:123456789012:stream/clicks
+        starting_position: LATEST
+        parallelization_factor: 2
+    function_url:
+      auth: AWS_IAM
+      allow_origins: [https://example.com]
+      invoke_mode: RESPONSE_STREAM
+    http_api:
+      routes: ["GET /orders/{id}"]
+    naming:
+      role: "{{.Function}}-exec"
+`
+
+func TestFunctionConfig_OptionsConfigureTriggersEndpointsAndNaming(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.ParseConfig(strings.NewReader(triggersConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := cfg.Functions[0].Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := glambda.NewLambda("orders", "", append([]glambda.DeployOptions{glambdatest.Sandbox()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantS3 := []glambda.S3Trigger{{Bucket: "order-uploads", Events: glambda.DefaultS3Events, Prefix: "incoming/", Suffix: ".csv"}}
+	if !cmp.Equal(wantS3, l.S3Triggers) {
+		t.Error(cmp.Diff(wantS3, l.S3Triggers))
+	}
+	if len(l.EventSources) != 2 {
+		t.Fatalf("want a queue and a stream, got %v", l.EventSources)
+	}
+	queue, stream := l.EventSources[0], l.EventSources[1]
+	if queue.BatchSize != 50 || queue.BatchingWindowSeconds != 5 || !queue.ReportBatchItemFailures || len(queue.Filters) != 1 {
+		t.Errorf("want the queue's settings applied, got %+v", queue)
+	}
+	if stream.StartingPosition != "LATEST" || stream.ParallelizationFactor != 2 {
+		t.Errorf("want the stream's settings applied, got %+v", stream)
+	}
+	if l.FunctionURL == nil || l.FunctionURL.AuthType != "AWS_IAM" || l.FunctionURL.Cors == nil || l.FunctionURL.InvokeMode != "RESPONSE_STREAM" {
+		t.Errorf("want a streaming IAM function URL with CORS, got %+v", l.FunctionURL)
+	}
+	if l.HTTPAPI == nil || !cmp.Equal([]string{"GET /orders/{id}"}, l.HTTPAPI.Routes) {
+		t.Errorf("want the HTTP API's route, got %+v", l.HTTPAPI)
+	}
+	if l.ExecutionRole.RoleName != "orders-exec" {
+		t.Errorf("want the role named by the naming template, got %q", l.ExecutionRole.RoleName)
+	}
+}
+
+func TestPlanConfig_IncludesTriggersAndEndpointsFromTheConfig(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.ParseConfig(strings.NewReader(triggersConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans, err := glambda.PlanConfig(context.Background(), cfg, glambdatest.Sandbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range plans[0].Changes {
+		got = append(got, c.Operation)
+	}
+	for _, want := range []string{"PutBucketNotificationConfiguration", "CreateEventSourceMapping", "CreateFunctionUrlConfig", "CreateApi"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("want %s planned, got %v", want, got)
+		}
+	}
+}
+
+func TestFunctionConfig_OptionsRejectUnknownEventSources(t *testing.T) {
+	t.Parallel()
+	for _, source := range []glambda.EventSourceConfig{
+		{ARN: "arn:aws:sns:us-east-1:123456789012:orders"},
+		{ARN: "arn:aws:sqs:us-east-1:123456789012:orders", StartingPosition: "LATEST"},
+	} {
+		fn := glambda.FunctionConfig{Name: "orders", Handler: "main.go", EventSources: []glambda.EventSourceConfig{source}}
+		_, err := fn.Options()
+		if err == nil {
+			t.Errorf("%+v: want an error", source)
+		}
+	}
+}
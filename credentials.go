@@ -0,0 +1,140 @@
+package glambda
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// CredentialsError is returned when glambda can't identify the AWS account
+// it is deploying to, which nearly always means the credentials it found
+// can't be used. Source says where the AWS SDK looked for credentials, and
+// Hint what usually goes wrong there.
+type CredentialsError struct {
+	Source string
+	Hint   string
+	Err    error
+}
+
+func (e *CredentialsError) Error() string {
+	return fmt.Sprintf("unable to use AWS credentials from %s, %v. %s", e.Source, e.Err, e.Hint)
+}
+
+func (e *CredentialsError) Unwrap() error {
+	return e.Err
+}
+
+// WithCredentialsProvider is a deploy option that makes glambda use p for
+// AWS credentials, in place of the SDK's default chain. It is for programs
+// that embed glambda and already manage credentials, such as a pipeline
+// assuming a role per environment. The region is still resolved as usual
+// unless [WithAWSConfig] is also given.
+func WithCredentialsProvider(p aws.CredentialsProvider) DeployOptions {
+	return func(l *Lambda) error {
+		if p == nil {
+			return fmt.Errorf("credentials provider must not be nil")
+		}
+		l.credentials = p
+		return nil
+	}
+}
+
+var roleARNRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
+
+// assumeRole is a role to deploy as, see [WithAssumeRole].
+type assumeRole struct {
+	arn        string
+	externalID string
+}
+
+// WithAssumeRole is a deploy option that deploys as roleARN, assuming it with
+// the credentials glambda would otherwise use, so that a CI system can deploy
+// into other accounts without a credentials file for each. externalID is
+// passed on if the role's trust policy requires one, and may be empty.
+//
+// The role session is named after the [Lambda.DeployID], so the role's
+// CloudTrail events show which deploy assumed it.
+func WithAssumeRole(roleARN, externalID string) DeployOptions {
+	return func(l *Lambda) error {
+		if roleARN == "" {
+			return fmt.Errorf("assuming a role needs the role's ARN")
+		}
+		if !roleARNRegex.MatchString(roleARN) {
+			return fmt.Errorf("%q is not an IAM role ARN, expected arn:aws:iam::<account>:role/<name>", roleARN)
+		}
+		l.assumeRole = &assumeRole{arn: roleARN, externalID: externalID}
+		return nil
+	}
+}
+
+// provider assumes the role with the credentials in cfg.
+func (r assumeRole) provider(cfg aws.Config, deployID string) aws.CredentialsProvider {
+	return stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), r.arn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName(deployID)
+		if r.externalID != "" {
+			o.ExternalID = aws.String(r.externalID)
+		}
+	})
+}
+
+// explain adds the role to a [CredentialsError], since failing to assume it
+// is the likeliest reason that the credentials can't be used.
+func (r *assumeRole) explain(err error) error {
+	var e *CredentialsError
+	if r == nil || !errors.As(err, &e) {
+		return err
+	}
+	e.Source = fmt.Sprintf("%s, assuming %s", e.Source, r.arn)
+	e.Hint = "Check the role's trust policy allows the identity deploying to assume it, with the same external ID if it requires one."
+	return err
+}
+
+// sessionName makes a role session name, which may only use a few
+// punctuation characters and be at most 64 long, from a deploy ID.
+func sessionName(deployID string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', strings.ContainsRune("+=,.@_-", r):
+			return r
+		}
+		return '-'
+	}, "glambda-"+deployID)
+	return name[:min(len(name), 64)]
+}
+
+// credentialsError explains err in terms of where the default credential
+// chain will have found credentials, following the chain's own order of
+// precedence. The environment is read with getenv.
+func credentialsError(err error, getenv func(string) string) *CredentialsError {
+	e := &CredentialsError{Err: err}
+	switch {
+	case getenv("AWS_ACCESS_KEY_ID") != "":
+		e.Source = "the AWS_ACCESS_KEY_ID environment variable"
+		e.Hint = "Check the keys haven't expired, and that AWS_SESSION_TOKEN is set for temporary credentials."
+	case getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "":
+		e.Source = fmt.Sprintf("EKS IAM roles for service accounts, assuming %s", getenv("AWS_ROLE_ARN"))
+		e.Hint = "Check the service account is annotated with eks.amazonaws.com/role-arn, " +
+			"and that the role's trust policy allows the cluster's OIDC provider and this service account."
+	case getenv("AWS_PROFILE") != "":
+		e.Source = fmt.Sprintf("the %s profile", getenv("AWS_PROFILE"))
+		e.Hint = "Check the profile exists, or sign in again with aws sso login if it uses IAM Identity Center."
+	case getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" && getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE") != "":
+		e.Source = "EKS Pod Identity"
+		e.Hint = "Check the cluster runs the EKS Pod Identity Agent, and that a pod identity association exists for this service account."
+	case getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "":
+		e.Source = "the container credentials endpoint at " + getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+		e.Hint = "Check the endpoint is reachable from the container."
+	case getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "":
+		e.Source = "the ECS task role"
+		e.Hint = "Check the task definition sets a taskRoleArn, not just an executionRoleArn."
+	default:
+		e.Source = "the shared config files or EC2 instance metadata"
+		e.Hint = "Configure credentials with aws configure or aws sso login, or set AWS_PROFILE."
+	}
+	return e
+}
@@ -0,0 +1,203 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+var credentialEnv = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_WEB_IDENTITY_TOKEN_FILE",
+	"AWS_ROLE_ARN",
+	"AWS_PROFILE",
+	"AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE",
+	"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI",
+}
+
+// Not parallel, as these tests set credential environment variables.
+func TestGetAWSAccountID_ExplainsWhereCredentialsCameFrom(t *testing.T) {
+	tcs := map[string]struct {
+		env    map[string]string
+		source string
+		hint   string
+	}{
+		"ECS task role": {
+			env:    map[string]string{"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/credentials/abc"},
+			source: "the ECS task role",
+			hint:   "taskRoleArn",
+		},
+		"EKS IRSA": {
+			env: map[string]string{
+				"AWS_WEB_IDENTITY_TOKEN_FILE": "/var/run/secrets/eks.amazonaws.com/serviceaccount/token",
+				"AWS_ROLE_ARN":                "arn:aws:iam::123456789012:role/deployer",
+			},
+			source: "EKS IAM roles for service accounts, assuming arn:aws:iam::123456789012:role/deployer",
+			hint:   "eks.amazonaws.com/role-arn",
+		},
+		"EKS Pod Identity": {
+			env: map[string]string{
+				"AWS_CONTAINER_CREDENTIALS_FULL_URI":     "http://169.254.170.23/v1/credentials",
+				"AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE": "/var/run/secrets/pods.eks.amazonaws.com/serviceaccount/eks-pod-identity-token",
+			},
+			source: "EKS Pod Identity",
+			hint:   "Pod Identity Agent",
+		},
+		"static keys win over the container": {
+			env: map[string]string{
+				"AWS_ACCESS_KEY_ID":                      "AKIAEXAMPLE",
+				"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "/v2/credentials/abc",
+			},
+			source: "the AWS_ACCESS_KEY_ID environment variable",
+			hint:   "AWS_SESSION_TOKEN",
+		},
+		"nothing set": {
+			source: "the shared config files or EC2 instance metadata",
+			hint:   "aws configure",
+		},
+	}
+	for name, tc := range tcs {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range credentialEnv {
+				t.Setenv(k, tc.env[k])
+			}
+			cause := errors.New("no EC2 IMDS role found")
+			_, err := glambda.GetAWSAccountID(context.Background(), glambdatest.DummySTSClient{Err: cause})
+			var credsErr *glambda.CredentialsError
+			if !errors.As(err, &credsErr) {
+				t.Fatalf("expected CredentialsError, got %v", err)
+			}
+			if credsErr.Source != tc.source || !strings.Contains(credsErr.Hint, tc.hint) {
+				t.Errorf("unexpected source %q and hint %q", credsErr.Source, credsErr.Hint)
+			}
+			if !errors.Is(err, cause) {
+				t.Errorf("expected error to wrap %v", cause)
+			}
+		})
+	}
+}
+
+func TestWithCredentialsProvider_IsUsedForAWSCalls(t *testing.T) {
+	t.Parallel()
+	refused := errors.New("pipeline credentials refused")
+	var calls int
+	provider := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		calls++
+		return aws.Credentials{}, refused
+	})
+	l, err := glambda.NewLambda("fn", "",
+		glambda.WithAWSConfig(aws.Config{Region: glambdatest.Region}),
+		glambda.WithCredentialsProvider(provider),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Describe(context.Background())
+	if !errors.Is(err, refused) || calls == 0 {
+		t.Errorf("expected AWS calls to use the credentials provider, got %v", err)
+	}
+}
+
+func TestWithCredentialsProvider_RejectsNil(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithCredentialsProvider(nil))
+	if err == nil {
+		t.Error("expected error for nil credentials provider, got nil")
+	}
+}
+
+// assumingService answers STS AssumeRole with temporary credentials, and any
+// other request with an empty success, recording what it was sent.
+type assumingService struct {
+	mu             sync.Mutex
+	assumeRole     url.Values
+	authorizations []string
+}
+
+func (s *assumingService) Do(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body := "{}"
+	if strings.HasPrefix(req.URL.Host, "sts.") {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		s.assumeRole, err = url.ParseQuery(string(data))
+		if err != nil {
+			return nil, err
+		}
+		body = `<AssumeRoleResponse><AssumeRoleResult><Credentials>
+			<AccessKeyId>ASIAASSUMEDROLE</AccessKeyId>
+			<SecretAccessKey>secret</SecretAccessKey>
+			<SessionToken>token</SessionToken>
+			<Expiration>2099-01-01T00:00:00Z</Expiration>
+		</Credentials></AssumeRoleResult></AssumeRoleResponse>`
+	} else {
+		s.authorizations = append(s.authorizations, req.Header.Get("Authorization"))
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestWithAssumeRole_DeploysWithTheAssumedRolesCredentials(t *testing.T) {
+	t.Parallel()
+	service := &assumingService{}
+	l, err := glambda.NewLambda("fn", "",
+		glambda.WithAWSConfig(aws.Config{
+			Region:      glambdatest.Region,
+			Credentials: credentials.NewStaticCredentialsProvider("AKIAPIPELINE", "secret", ""),
+			HTTPClient:  service,
+		}),
+		glambda.WithSTSClient(glambdatest.DummySTSClient{AccountID: glambdatest.AccountID}),
+		glambda.WithAssumeRole("arn:aws:iam::210987654321:role/deployer", "ci-external-id"),
+		glambda.WithDeployID("build-42"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Invoke(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {"arn:aws:iam::210987654321:role/deployer"},
+		"RoleSessionName": {"glambda-build-42"},
+		"ExternalId":      {"ci-external-id"},
+		"DurationSeconds": {"900"},
+	}
+	if !cmp.Equal(want, service.assumeRole) {
+		t.Error(cmp.Diff(want, service.assumeRole))
+	}
+	if len(service.authorizations) != 1 || !strings.Contains(service.authorizations[0], "Credential=ASIAASSUMEDROLE/") {
+		t.Errorf("want the invoke signed with the assumed role's credentials, got %q", service.authorizations)
+	}
+}
+
+func TestWithAssumeRole_RejectsWhatIsntARoleARN(t *testing.T) {
+	t.Parallel()
+	for _, arn := range []string{"", "deployer", "arn:aws:iam::210987654321:user/deployer"} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithAssumeRole(arn, ""))
+		if err == nil {
+			t.Errorf("want an error for %q", arn)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// DashboardLogLimit caps how many log events a single [Lambda.Dashboard]
+// call reads, keeping the most recent.
+var DashboardLogLimit = 100
+
+// Dashboard is a snapshot of a deployed function's health, as shown by the
+// glambda dashboard command.
+type Dashboard struct {
+	Configuration types.FunctionConfiguration
+	// InvocationsPerMinute and ErrorsPerMinute cover the dashboard window one
+	// minute at a time, oldest first.
+	InvocationsPerMinute []float64
+	ErrorsPerMinute      []float64
+	// Logs are the function's log events since the time asked for, oldest
+	// first, and Recent the invocations reported in them.
+	Logs   []LogEvent
+	Recent []Invocation
+}
+
+// LogEvent is a single line written to a function's log group.
+type LogEvent struct {
+	Time    time.Time
+	Message string
+	// Stream is the log stream the event was written to, one for each
+	// instance of the function.
+	Stream string
+}
+
+// Invocation is an [InvocationReport] found in a function's logs, along with
+// the time it was logged.
+type Invocation struct {
+	Time time.Time
+	InvocationReport
+}
+
+// ErrorRate returns the fraction of invocations that failed in each minute of
+// the dashboard window. Minutes without invocations have a rate of zero.
+func (d Dashboard) ErrorRate() []float64 {
+	rates := make([]float64, len(d.InvocationsPerMinute))
+	for i, n := range d.InvocationsPerMinute {
+		if n > 0 && i < len(d.ErrorsPerMinute) {
+			rates[i] = d.ErrorsPerMinute[i] / n
+		}
+	}
+	return rates
+}
+
+// Dashboard is a method on the [Lambda] struct that gathers the function's
+// current configuration, its invocation and error metrics over the last
+// window, and its log events since the given time. Calling it again with the
+// time of the last event seen tails the logs.
+func (l Lambda) Dashboard(ctx context.Context, window time.Duration, logsSince time.Time) (Dashboard, error) {
+	fn, err := l.lambdaAPI().GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(l.Name),
+	})
+	if err != nil {
+		return Dashboard{}, err
+	}
+	d := Dashboard{}
+	if fn.Configuration != nil {
+		d.Configuration = *fn.Configuration
+	}
+	d.InvocationsPerMinute, d.ErrorsPerMinute, err = l.perMinuteMetrics(ctx, window)
+	if err != nil {
+		return Dashboard{}, fmt.Errorf("error reading metrics, %w", err)
+	}
+	d.Logs, err = l.logEvents(ctx, logsSince)
+	if err != nil {
+		return Dashboard{}, fmt.Errorf("error reading logs, %w", err)
+	}
+	for _, e := range d.Logs {
+		if report, ok := ParseReport(e.Message); ok {
+			d.Recent = append(d.Recent, Invocation{Time: e.Time, InvocationReport: report})
+		}
+	}
+	return d, nil
+}
+
+func (l Lambda) perMinuteMetrics(ctx context.Context, window time.Duration) (invocations, errs []float64, err error) {
+	end := time.Now().Truncate(time.Minute).Add(time.Minute)
+	minutes := int(window / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	start := end.Add(-time.Duration(minutes) * time.Minute)
+	query := func(name string) cwTypes.MetricDataQuery {
+		return cwTypes.MetricDataQuery{
+			Id: aws.String(strings.ToLower(name)),
+			MetricStat: &cwTypes.MetricStat{
+				Metric: &cwTypes.Metric{
+					Namespace:  aws.String("AWS/Lambda"),
+					MetricName: aws.String(name),
+					Dimensions: []cwTypes.Dimension{
+						{Name: aws.String("FunctionName"), Value: aws.String(l.Name)},
+					},
+				},
+				Period: aws.Int32(60),
+				Stat:   aws.String("Sum"),
+			},
+		}
+	}
+	resp, err := l.cloudWatchAPI().GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		StartTime:         aws.Time(start),
+		EndTime:           aws.Time(end),
+		MetricDataQueries: []cwTypes.MetricDataQuery{query("Invocations"), query("Errors")},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	invocations, errs = make([]float64, minutes), make([]float64, minutes)
+	for _, result := range resp.MetricDataResults {
+		series := invocations
+		if aws.ToString(result.Id) == "errors" {
+			series = errs
+		}
+		// Minutes without data points are left out of the results entirely.
+		for i, ts := range result.Timestamps {
+			minute := int(ts.Sub(start) / time.Minute)
+			if minute >= 0 && minute < minutes && i < len(result.Values) {
+				series[minute] += result.Values[i]
+			}
+		}
+	}
+	return invocations, errs, nil
+}
+
+func (l Lambda) logEvents(ctx context.Context, since time.Time) ([]LogEvent, error) {
+	var events []LogEvent
+	err := l.eachLogEvent(ctx, since, func(e LogEvent) {
+		events = append(events, e)
+		if len(events) > DashboardLogLimit {
+			events = events[1:]
+		}
+	})
+	return events, err
+}
+
+// eachLogEvent calls fn with each event in the function's log group since
+// the given time, oldest first. A function that hasn't been invoked yet has
+// no log group, and so no events.
+func (l Lambda) eachLogEvent(ctx context.Context, since time.Time, fn func(LogEvent)) error {
+	return l.pacedLogEvents(ctx, since, 0, func(e LogEvent) error {
+		fn(e)
+		return nil
+	})
+}
+
+// pacedLogEvents is [Lambda.eachLogEvent], waiting at least every between
+// requests for pages of events, and stopping at the first error from fn.
+func (l Lambda) pacedLogEvents(ctx context.Context, since time.Time, every time.Duration, fn func(LogEvent) error) error {
+	return l.filterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(LogGroupName(l.Name)),
+		StartTime:    aws.Int64(since.UnixMilli()),
+	}, every, fn)
+}
+
+// filterLogEvents calls fn with each event matching the input, a page at a
+// time, as [Lambda.pacedLogEvents] does.
+func (l Lambda) filterLogEvents(ctx context.Context, input *cloudwatchlogs.FilterLogEventsInput, every time.Duration, fn func(LogEvent) error) error {
+	var last time.Time
+	for {
+		if wait := time.Until(last.Add(every)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		last = time.Now()
+		resp, err := l.cloudWatchLogsAPI().FilterLogEvents(ctx, input)
+		var notFound *lTypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			// The log group is created on the first invocation.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range resp.Events {
+			err = fn(LogEvent{
+				Time:    time.UnixMilli(aws.ToInt64(e.Timestamp)),
+				Message: strings.TrimRight(aws.ToString(e.Message), "\n"),
+				Stream:  aws.ToString(e.LogStreamName),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if resp.NextToken == nil || aws.ToString(resp.NextToken) == aws.ToString(input.NextToken) {
+			return nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a line of block characters, scaled so that the
+// largest value is a full block. Only zero is drawn as the lowest block, so
+// that small values still stand out.
+func Sparkline(values []float64) string {
+	var largest float64
+	for _, v := range values {
+		largest = max(largest, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if largest > 0 && v > 0 {
+			i = max(1, int(v/largest*float64(len(sparks)-1)))
+		}
+		b.WriteRune(sparks[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestDashboard_GathersMetricsLogsAndRecentInvocations(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String("watched"),
+			MemorySize:   aws.Int32(256),
+		},
+	})
+	now := time.Now()
+	recorder.Respond("GetMetricData", &cloudwatch.GetMetricDataOutput{
+		MetricDataResults: []cwTypes.MetricDataResult{
+			{Id: aws.String("invocations"), Timestamps: []time.Time{now.Add(-2 * time.Minute), now}, Values: []float64{4, 8}},
+			{Id: aws.String("errors"), Timestamps: []time.Time{now}, Values: []float64{2}},
+		},
+	})
+	logged := now.Truncate(time.Millisecond)
+	recorder.Respond("FilterLogEvents", &cloudwatchlogs.FilterLogEventsOutput{
+		Events: []lTypes.FilteredLogEvent{
+			{Timestamp: aws.Int64(logged.UnixMilli()), Message: aws.String("START RequestId: abc Version: $LATEST\n")},
+			{Timestamp: aws.Int64(logged.UnixMilli()), Message: aws.String("REPORT RequestId: abc\tDuration: 12.50 ms\tBilled Duration: 13 ms\tMemory Size: 256 MB\tMax Memory Used: 31 MB\t\n")},
+		},
+	})
+	l, err := glambda.NewLambda("watched", "", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := l.Dashboard(context.Background(), 5*time.Minute, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToInt32(d.Configuration.MemorySize) != 256 {
+		t.Errorf("expected configuration of the function, got %+v", d.Configuration)
+	}
+	if want := []float64{0, 0, 4, 0, 8}; !cmp.Equal(want, d.InvocationsPerMinute) {
+		t.Error(cmp.Diff(want, d.InvocationsPerMinute))
+	}
+	if want := []float64{0, 0, 0, 0, 0.25}; !cmp.Equal(want, d.ErrorRate()) {
+		t.Error(cmp.Diff(want, d.ErrorRate()))
+	}
+	if len(d.Logs) != 2 || d.Logs[0].Message != "START RequestId: abc Version: $LATEST" {
+		t.Errorf("expected both log events, trimmed, got %+v", d.Logs)
+	}
+	want := []glambda.Invocation{{
+		Time: logged,
+		InvocationReport: glambda.InvocationReport{
+			RequestID:      "abc",
+			Duration:       12500 * time.Microsecond,
+			BilledDuration: 13 * time.Millisecond,
+			MemorySize:     256,
+			MaxMemoryUsed:  31,
+		},
+	}}
+	if !cmp.Equal(want, d.Recent) {
+		t.Error(cmp.Diff(want, d.Recent))
+	}
+}
+
+func TestDashboard_TreatsMissingLogGroupAsNoLogs(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{FunctionName: aws.String("quiet")},
+	})
+	recorder.FailNext("FilterLogEvents", &lTypes.ResourceNotFoundException{Message: aws.String("log group does not exist")}, 1)
+	l, err := glambda.NewLambda("quiet", "", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := l.Dashboard(context.Background(), time.Hour, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Logs) != 0 || len(d.InvocationsPerMinute) != 60 {
+		t.Errorf("expected an hour of empty metrics and no logs, got %+v", d)
+	}
+}
+
+func TestSparkline_ScalesToLargestValueAndKeepsSmallValuesVisible(t *testing.T) {
+	t.Parallel()
+	got := glambda.Sparkline([]float64{0, 1, 2, 4, 8})
+	want := "▁▂▂▄█"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,493 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrRoleNotManaged is returned by [Delete] when the function was deleted but
+// its execution role was kept, because glambda didn't create the role.
+var ErrRoleNotManaged = errors.New("execution role was not created by glambda")
+
+// legacyRolePrefix is the default role name used before roles were tagged
+// with [ManagedTagKey]. Roles with this name are still treated as managed, so
+// that functions deployed by older versions of glambda clean up fully.
+const legacyRolePrefix = "glambda_exec_role_"
+
+// IsManagedRole reports whether a role was created by glambda.
+func IsManagedRole(role *iTypes.Role) bool {
+	if role == nil {
+		return false
+	}
+	for _, tag := range role.Tags {
+		if aws.ToString(tag.Key) == ManagedTagKey && aws.ToString(tag.Value) == "true" {
+			return true
+		}
+	}
+	return strings.HasPrefix(aws.ToString(role.RoleName), legacyRolePrefix)
+}
+
+func roleNameFromARN(roleArn string) (string, error) {
+	_, roleName, found := strings.Cut(roleArn, "/")
+	if !found {
+		return "", fmt.Errorf("unable to determine execution role from %q", roleArn)
+	}
+	// Role paths add extra segments, the name is always the last one.
+	return roleName[strings.LastIndex(roleName, "/")+1:], nil
+}
+
+// WithKeepLogs is a deploy option that keeps the function's log group when
+// it is deleted with [Lambda.Delete], for example to investigate it later.
+func WithKeepLogs() DeployOptions {
+	return func(l *Lambda) error {
+		l.keepLogs = true
+		return nil
+	}
+}
+
+// WithDeleteUnmanagedRole is a deploy option that makes [Lambda.Delete]
+// delete the function's execution role even when glambda didn't create it.
+// Such a role may be shared with other functions, so it is kept by default.
+func WithDeleteUnmanagedRole() DeployOptions {
+	return func(l *Lambda) error {
+		l.deleteUnmanagedRole = true
+		return nil
+	}
+}
+
+// deletion is everything [Lambda.Delete] removes, found before anything is
+// removed so that [Lambda.DeletePlan] can show exactly the same.
+type deletion struct {
+	function string
+	hasURL   bool
+	mappings []types.EventSourceMappingConfiguration
+	// permissions are the resource policy statement IDs, which go with the
+	// function.
+	permissions []string
+	// notifications are the IDs of the S3 notifications glambda added for
+	// the function, by bucket.
+	notifications map[string][]string
+	// httpAPI is the ID of the HTTP API glambda created for the function.
+	httpAPI string
+	// warnings are about what is left behind, other than the role.
+	warnings []string
+	// logGroup is empty when the log group is kept, see [WithKeepLogs].
+	logGroup string
+	// role is empty when the function's role no longer exists.
+	role string
+	// keepRole is set when the role wasn't created by glambda.
+	keepRole        bool
+	inlinePolicies  []string
+	managedPolicies []string
+}
+
+// Delete is a method on the [Lambda] struct that deletes the lambda function
+// and its execution role.
+//
+// Everything glambda attaches to the function goes with it: its function URL,
+// its HTTP API, its S3 notifications and event source mappings, which would
+// otherwise outlive it, and its log group, unless [WithKeepLogs] is given. An
+// HTTP API of the same name that glambda didn't create is kept. The role's inline policies are
+// deleted and its managed policies detached before the role itself. To see
+// what would be deleted without deleting it, use [Lambda.DeletePlan].
+//
+// The role is found from the function's configuration, not from the [Lambda]
+// struct, and is only deleted if glambda created it (see [IsManagedRole]). A
+// role that glambda didn't create may be shared with other functions, so it is
+// kept and [ErrRoleNotManaged] is returned once the function is deleted,
+// unless [WithDeleteUnmanagedRole] is given. A role that no longer exists is
+// skipped. A function managed by another tool, see [ResourceOwner], isn't
+// deleted at all and the error is an [*OwnedResourceError]. Set force to
+// delete such a function regardless.
+func (l Lambda) Delete(ctx context.Context, force bool) error {
+	d, err := l.prepareDelete(ctx, force)
+	if err != nil {
+		return err
+	}
+	return l.delete(ctx, d)
+}
+
+// DeletePlan is a method on the [Lambda] struct that finds everything
+// [Lambda.Delete] would remove, with the same force, and returns it as a
+// [Plan] of "delete" changes without removing anything. A role that would be
+// kept is mentioned in the plan's warnings.
+func (l Lambda) DeletePlan(ctx context.Context, force bool) (Plan, error) {
+	d, err := l.prepareDelete(ctx, force)
+	if err != nil {
+		return Plan{}, err
+	}
+	return d.describe(), nil
+}
+
+func (l Lambda) prepareDelete(ctx context.Context, force bool) (deletion, error) {
+	lambdaClient := l.lambdaAPI()
+	iamClient := l.iamAPI()
+	fnInfo, err := lambdaClient.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(l.Name),
+	})
+	if err != nil {
+		return deletion{}, err
+	}
+	if owner := ResourceOwner(fnInfo.Tags); owner != "" && !force {
+		return deletion{}, &OwnedResourceError{Kind: "function", Name: l.Name, Owner: owner, Override: "--force"}
+	}
+	roleName, err := roleNameFromARN(aws.ToString(fnInfo.Configuration.Role))
+	if err != nil {
+		return deletion{}, err
+	}
+	d := deletion{function: l.Name}
+	role, err := iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	var noRole *iTypes.NoSuchEntityException
+	switch {
+	case errors.As(err, &noRole):
+		d.warnings = append(d.warnings, fmt.Sprintf("role %s no longer exists, so there is no role to delete", roleName))
+	case err != nil:
+		return deletion{}, err
+	default:
+		d.role = roleName
+		d.keepRole = !l.deleteUnmanagedRole && !IsManagedRole(role.Role)
+	}
+	if !l.keepLogs {
+		d.logGroup = LogGroupName(l.Name)
+	}
+	var notFound *types.ResourceNotFoundException
+	_, err = lambdaClient.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String(l.Name),
+	})
+	if err != nil && !errors.As(err, &notFound) {
+		return deletion{}, err
+	}
+	d.hasURL = err == nil
+	d.mappings, err = eventSourceMappings(ctx, lambdaClient, l.Name)
+	if err != nil {
+		return deletion{}, err
+	}
+	policy, err := lambdaClient.GetPolicy(ctx, &lambda.GetPolicyInput{
+		FunctionName: aws.String(l.Name),
+	})
+	if err != nil && !errors.As(err, &notFound) {
+		return deletion{}, err
+	}
+	var buckets []string
+	if err == nil {
+		statements, err := policyStatements(aws.ToString(policy.Policy))
+		if err != nil {
+			return deletion{}, err
+		}
+		for _, s := range statements {
+			d.permissions = append(d.permissions, s.Sid)
+			if bucket := s3TriggerBucket(s); bucket != "" {
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+	d.notifications, err = l.s3Notifications(ctx, buckets)
+	if err != nil {
+		return deletion{}, err
+	}
+	api, err := findHTTPAPI(ctx, l.apiGatewayAPI(), l.Name)
+	if err != nil {
+		return deletion{}, err
+	}
+	switch {
+	case api == nil:
+	case api.Tags[ManagedTagKey] == "true":
+		d.httpAPI = aws.ToString(api.ApiId)
+	default:
+		d.warnings = append(d.warnings, fmt.Sprintf("HTTP API %s (%s) was not created by glambda and would be kept", l.Name, aws.ToString(api.ApiId)))
+	}
+	if d.role == "" || d.keepRole {
+		return d, nil
+	}
+	d.inlinePolicies, err = inlinePolicyNames(ctx, iamClient, roleName)
+	if err != nil {
+		return deletion{}, err
+	}
+	attachedPolicies, err := iamClient.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil {
+		return deletion{}, err
+	}
+	for _, policy := range attachedPolicies.AttachedPolicies {
+		d.managedPolicies = append(d.managedPolicies, aws.ToString(policy.PolicyArn))
+	}
+	return d, nil
+}
+
+// delete removes what prepareDelete found, in an order AWS accepts: the
+// function's attachments, the function, then the role's policies and role.
+func (l Lambda) delete(ctx context.Context, d deletion) error {
+	lambdaClient := l.lambdaAPI()
+	iamClient := l.iamAPI()
+	var notFound *types.ResourceNotFoundException
+	for _, w := range d.warnings {
+		l.report("delete", "%s", w)
+	}
+	for _, bucket := range sortedKeys(d.notifications) {
+		err := l.removeS3Notifications(ctx, bucket, d.notifications[bucket])
+		if err != nil {
+			return err
+		}
+	}
+	if d.httpAPI != "" {
+		_, err := l.apiGatewayAPI().DeleteApi(ctx, &apigatewayv2.DeleteApiInput{
+			ApiId: aws.String(d.httpAPI),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if d.hasURL {
+		_, err := lambdaClient.DeleteFunctionUrlConfig(ctx, &lambda.DeleteFunctionUrlConfigInput{
+			FunctionName: aws.String(d.function),
+		})
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	for _, mapping := range d.mappings {
+		_, err := lambdaClient.DeleteEventSourceMapping(ctx, &lambda.DeleteEventSourceMappingInput{
+			UUID: mapping.UUID,
+		})
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	_, err := lambdaClient.DeleteFunction(ctx, &lambda.DeleteFunctionInput{
+		FunctionName: aws.String(d.function),
+	})
+	if err != nil {
+		return err
+	}
+	if d.logGroup != "" {
+		_, err = l.cloudWatchLogsAPI().DeleteLogGroup(ctx, &cloudwatchlogs.DeleteLogGroupInput{
+			LogGroupName: aws.String(d.logGroup),
+		})
+		var logsNotFound *lTypes.ResourceNotFoundException
+		if err != nil && !errors.As(err, &logsNotFound) {
+			return err
+		}
+	}
+	if d.role == "" {
+		return nil
+	}
+	if d.keepRole {
+		return fmt.Errorf("%w, kept role %s", ErrRoleNotManaged, d.role)
+	}
+	for _, name := range d.inlinePolicies {
+		_, err = iamClient.DeleteRolePolicy(ctx, &iam.DeleteRolePolicyInput{
+			RoleName:   aws.String(d.role),
+			PolicyName: aws.String(name),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, arn := range d.managedPolicies {
+		_, err = iamClient.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{
+			PolicyArn: aws.String(arn),
+			RoleName:  aws.String(d.role),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = iamClient.DeleteRole(ctx, &iam.DeleteRoleInput{
+		RoleName: aws.String(d.role),
+	})
+	return err
+}
+
+func (d deletion) describe() Plan {
+	plan := Plan{FormatVersion: PlanFormatVersion, Function: d.function, Changes: []Change{}, Warnings: d.warnings}
+	change := func(operation, resourceType, resource string, details ...string) {
+		plan.Changes = append(plan.Changes, Change{
+			Operation:    operation,
+			ResourceType: resourceType,
+			Resource:     resource,
+			Action:       "delete",
+			Details:      details,
+		})
+	}
+	for _, bucket := range sortedKeys(d.notifications) {
+		change("PutBucketNotificationConfiguration", "s3_bucket_notification", bucket,
+			fmt.Sprintf("notifications: %s", strings.Join(d.notifications[bucket], ", ")))
+	}
+	if d.httpAPI != "" {
+		change("DeleteApi", "apigatewayv2_api", d.function, "api: "+d.httpAPI)
+	}
+	if d.hasURL {
+		change("DeleteFunctionUrlConfig", "lambda_function_url_config", d.function)
+	}
+	for _, mapping := range d.mappings {
+		change("DeleteEventSourceMapping", "lambda_event_source_mapping", aws.ToString(mapping.UUID),
+			fmt.Sprintf("events from %s", aws.ToString(mapping.EventSourceArn)))
+	}
+	var permissions []string
+	for _, sid := range d.permissions {
+		permissions = append(permissions, "resource policy statement "+sid)
+	}
+	change("DeleteFunction", "lambda_function", d.function, permissions...)
+	if d.logGroup != "" {
+		change("DeleteLogGroup", "cloudwatch_log_group", d.logGroup)
+	}
+	if d.role == "" {
+		return plan
+	}
+	if d.keepRole {
+		plan.Warnings = append(plan.Warnings,
+			fmt.Sprintf("role %s was not created by glambda and would be kept, use --delete-role to delete it", d.role))
+		return plan
+	}
+	for _, name := range d.inlinePolicies {
+		change("DeleteRolePolicy", "iam_role_policy", d.role+"/"+name)
+	}
+	for _, arn := range d.managedPolicies {
+		change("DetachRolePolicy", "iam_role_policy_attachment", d.role+"/"+arn)
+	}
+	change("DeleteRole", "iam_role", d.role)
+	return plan
+}
+
+// s3TriggerBucket is the bucket a resource policy statement added by
+// [S3TriggerAction] lets invoke the function, or empty for any other
+// statement.
+func s3TriggerBucket(s PolicyStatement) string {
+	if !strings.HasPrefix(s.Sid, "glambda_s3_") {
+		return ""
+	}
+	var conditions map[string]map[string]string
+	if json.Unmarshal(s.Condition, &conditions) != nil {
+		return ""
+	}
+	for _, condition := range conditions {
+		for key, value := range condition {
+			if strings.EqualFold(key, "AWS:SourceArn") {
+				return strings.TrimPrefix(value, "arn:aws:s3:::")
+			}
+		}
+	}
+	return ""
+}
+
+// s3Notifications finds the notifications glambda added to the buckets for
+// the function, by bucket.
+func (l Lambda) s3Notifications(ctx context.Context, buckets []string) (map[string][]string, error) {
+	found := map[string][]string{}
+	for _, bucket := range buckets {
+		config, err := l.s3API().GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{
+			Bucket: aws.String(bucket),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range config.LambdaFunctionConfigurations {
+			if ownsNotification(l.Name, aws.ToString(n.Id)) {
+				found[bucket] = append(found[bucket], aws.ToString(n.Id))
+			}
+		}
+	}
+	return found, nil
+}
+
+// removeS3Notifications removes the notifications with the given IDs from the
+// bucket's notification configuration, leaving everything else as it is.
+func (l Lambda) removeS3Notifications(ctx context.Context, bucket string, ids []string) error {
+	current, err := l.s3API().GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return err
+	}
+	var functions []sTypes.LambdaFunctionConfiguration
+	for _, f := range current.LambdaFunctionConfigurations {
+		if !slices.Contains(ids, aws.ToString(f.Id)) {
+			functions = append(functions, f)
+		}
+	}
+	_, err = l.s3API().PutBucketNotificationConfiguration(ctx, &s3.PutBucketNotificationConfigurationInput{
+		Bucket: aws.String(bucket),
+		NotificationConfiguration: &sTypes.NotificationConfiguration{
+			EventBridgeConfiguration:     current.EventBridgeConfiguration,
+			LambdaFunctionConfigurations: functions,
+			QueueConfigurations:          current.QueueConfigurations,
+			TopicConfigurations:          current.TopicConfigurations,
+		},
+	})
+	return err
+}
+
+func eventSourceMappings(ctx context.Context, client LambdaClient, function string) ([]types.EventSourceMappingConfiguration, error) {
+	var mappings []types.EventSourceMappingConfiguration
+	var marker *string
+	for {
+		resp, err := client.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+			FunctionName: aws.String(function),
+			Marker:       marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, resp.EventSourceMappings...)
+		marker = resp.NextMarker
+		if marker == nil {
+			return mappings, nil
+		}
+	}
+}
+
+func inlinePolicyNames(ctx context.Context, client IAMClient, roleName string) ([]string, error) {
+	var names []string
+	var marker *string
+	for {
+		resp, err := client.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{
+			RoleName: aws.String(roleName),
+			Marker:   marker,
+		})
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, resp.PolicyNames...)
+		if !resp.IsTruncated {
+			return names, nil
+		}
+		marker = resp.Marker
+	}
+}
+
+// Delete is a convenience function that will delete a lambda function and the
+// associated IAM Role. Deletion is actually more complex than it might seem at
+// first glance and requires a specific unwinding of various resources.
+//
+// It should be noted that it is a destructive operation. The execution role is
+// only deleted if glambda created it, see [Lambda.Delete] for details, and
+// [WithDeleteUnmanagedRole] to delete it anyway.
+//
+// It will also delete the role's inline policies and detach any managed
+// policies that were attached to the role. It is a high level abstraction that should represent the majority
+// of use cases for this library.
+func Delete(ctx context.Context, name string, opts ...DeployOptions) error {
+	l, err := NewLambdaWithContext(ctx, name, "", opts...)
+	if err != nil {
+		return err
+	}
+	return l.Delete(ctx, false)
+}
@@ -0,0 +1,362 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	gTypes "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func deletableLambda(t *testing.T, recorder *glambdatest.Recorder, roleName string, roleTags map[string]string, opts ...glambda.DeployOptions) *glambda.Lambda {
+	t.Helper()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String("doomed"),
+			Role:         aws.String("arn:aws:iam::123456789012:role/" + roleName),
+		},
+	})
+	opts = append([]glambda.DeployOptions{
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithIAMClient(glambdatest.DummyIAMClient{
+			Recorder:   recorder,
+			RoleExists: true,
+			RoleName:   roleName,
+			RoleTags:   roleTags,
+		}),
+	}, opts...)
+	l, err := glambda.NewLambda("doomed", "", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestDelete_RemovesRoleCreatedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"})
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("DeleteFunction")) != 1 || len(recorder.Calls("DeleteRole")) != 1 {
+		t.Errorf("expected function and role to be deleted, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_KeepsRoleNotCreatedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "shared-role", nil)
+	err := l.Delete(context.Background(), false)
+	if !errors.Is(err, glambda.ErrRoleNotManaged) {
+		t.Fatalf("expected ErrRoleNotManaged, got %v", err)
+	}
+	if len(recorder.Calls("DeleteFunction")) != 1 {
+		t.Errorf("expected function to be deleted, got %v", recorder.Operations())
+	}
+	if calls := recorder.Calls("DetachRolePolicy", "DeleteRole"); len(calls) != 0 {
+		t.Errorf("expected role to be left alone, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_WithDeleteUnmanagedRoleRemovesRoleNotCreatedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "shared-role", nil, glambda.WithDeleteUnmanagedRole())
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("DeleteRole")) != 1 {
+		t.Errorf("expected role to be deleted, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_ForceAloneKeepsRoleNotCreatedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "shared-role", nil)
+	err := l.Delete(context.Background(), true)
+	if !errors.Is(err, glambda.ErrRoleNotManaged) {
+		t.Fatalf("expected ErrRoleNotManaged, got %v", err)
+	}
+	if calls := recorder.Calls("DeleteRole"); len(calls) != 0 {
+		t.Errorf("expected role to be left alone, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_SkipsARoleThatNoLongerExists(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "glambda_exec_role_doomed", nil)
+	recorder.FailNext("GetRole", &iTypes.NoSuchEntityException{}, 1)
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("DeleteFunction")) != 1 {
+		t.Errorf("expected function to be deleted, got %v", recorder.Operations())
+	}
+	if calls := recorder.Calls("ListRolePolicies", "DeleteRolePolicy", "DetachRolePolicy", "DeleteRole"); len(calls) != 0 {
+		t.Errorf("expected no role to be touched, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_TreatsUntaggedDefaultRoleAsManaged(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "glambda_exec_role_doomed", nil)
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("DeleteRole")) != 1 {
+		t.Errorf("expected role to be deleted, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_RefusesFunctionOwnedByCloudFormation(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"})
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String("doomed"),
+			Role:         aws.String("arn:aws:iam::123456789012:role/custom-role"),
+		},
+		Tags: map[string]string{"aws:cloudformation:stack-name": "orders-prod"},
+	})
+	err := l.Delete(context.Background(), false)
+	var owned *glambda.OwnedResourceError
+	if !errors.As(err, &owned) || owned.Override != "--force" {
+		t.Fatalf("expected OwnedResourceError, got %v", err)
+	}
+	if len(recorder.Calls("DeleteFunction")) != 0 {
+		t.Errorf("expected function to be kept, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_RemovesEverythingAttachedToTheFunction(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"})
+	recorder.Respond("ListEventSourceMappings", &lambda.ListEventSourceMappingsOutput{
+		EventSourceMappings: []types.EventSourceMappingConfiguration{
+			{UUID: aws.String("queue-mapping")},
+			{UUID: aws.String("stream-mapping")},
+		},
+	})
+	recorder.Respond("ListRolePolicies", &iam.ListRolePoliciesOutput{
+		PolicyNames: []string{"glambda_inline_policy", "glambda_event_sources"},
+	})
+	recorder.Respond("GetFunctionUrlConfig", &lambda.GetFunctionUrlConfigOutput{FunctionUrl: aws.String("https://doomed.lambda-url.us-east-1.on.aws/")})
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mappings []string
+	for _, call := range recorder.Calls("DeleteEventSourceMapping") {
+		mappings = append(mappings, aws.ToString(call.Input.(*lambda.DeleteEventSourceMappingInput).UUID))
+	}
+	if !slices.Equal(mappings, []string{"queue-mapping", "stream-mapping"}) {
+		t.Errorf("want both event source mappings deleted, got %v", mappings)
+	}
+	var policies []string
+	for _, call := range recorder.Calls("DeleteRolePolicy") {
+		policies = append(policies, aws.ToString(call.Input.(*iam.DeleteRolePolicyInput).PolicyName))
+	}
+	if !slices.Equal(policies, []string{"glambda_inline_policy", "glambda_event_sources"}) {
+		t.Errorf("want both inline policies deleted, got %v", policies)
+	}
+	logs := recorder.Calls("DeleteLogGroup")
+	if len(logs) != 1 || aws.ToString(logs[0].Input.(*cloudwatchlogs.DeleteLogGroupInput).LogGroupName) != "/aws/lambda/doomed" {
+		t.Errorf("want the log group deleted, got %v", recorder.Operations())
+	}
+	if len(recorder.Calls("DeleteFunctionUrlConfig")) != 1 {
+		t.Errorf("want the function URL deleted, got %v", recorder.Operations())
+	}
+	ops := recorder.Operations()
+	if slices.Index(ops, "DeleteRolePolicy") > slices.Index(ops, "DeleteRole") {
+		t.Errorf("want inline policies deleted before the role, got %v", ops)
+	}
+}
+
+func TestDelete_RemovesHTTPAPIAndS3NotificationsOfTheFunction(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	apis := map[string]string{"doomed": "apidoomed"}
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"},
+		glambda.WithAPIGatewayClient(glambdatest.DummyAPIGatewayClient{Recorder: recorder, APIs: apis}))
+	recorder.Respond("GetPolicy", &lambda.GetPolicyOutput{
+		Policy: aws.String(`{"Statement":[{"Sid":"glambda_s3_uploads","Effect":"Allow","Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:s3:::uploads"}}}]}`),
+	})
+	recorder.Respond("GetBucketNotificationConfiguration", &s3.GetBucketNotificationConfigurationOutput{
+		LambdaFunctionConfigurations: []sTypes.LambdaFunctionConfiguration{
+			{Id: aws.String("glambda-doomed"), LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:doomed")},
+			{Id: aws.String("glambda-doomed2"), LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:doomed2")},
+			{Id: aws.String("hand-made"), LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:doomed")},
+		},
+	})
+	plan, err := l.DeletePlan(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `  - PutBucketNotificationConfiguration uploads
+      notifications: glambda-doomed
+  - DeleteApi doomed
+      api: apidoomed
+`
+	if got := plan.String(); !strings.Contains(got, want) {
+		t.Errorf("want plan to contain:\n%s\ngot:\n%s", want, got)
+	}
+	err = l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	puts := recorder.Calls("PutBucketNotificationConfiguration")
+	if len(puts) != 1 {
+		t.Fatalf("want the bucket's notifications updated once, got %v", recorder.Operations())
+	}
+	var kept []string
+	for _, n := range puts[0].Input.(*s3.PutBucketNotificationConfigurationInput).NotificationConfiguration.LambdaFunctionConfigurations {
+		kept = append(kept, aws.ToString(n.Id))
+	}
+	if !slices.Equal(kept, []string{"glambda-doomed2", "hand-made"}) {
+		t.Errorf("want only the function's glambda notification removed, got %v", kept)
+	}
+	if len(recorder.Calls("DeleteApi")) != 1 || len(apis) != 0 {
+		t.Errorf("want the HTTP API deleted, got %v", recorder.Operations())
+	}
+}
+
+func TestDeletePlan_WarnsThatAnHTTPAPINotCreatedByGlambdaIsKept(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"})
+	recorder.Respond("GetApis", &apigatewayv2.GetApisOutput{
+		Items: []gTypes.Api{{ApiId: aws.String("handmade"), Name: aws.String("doomed"), ProtocolType: gTypes.ProtocolTypeHttp}},
+	})
+	plan, err := l.DeletePlan(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "! warning: HTTP API doomed (handmade) was not created by glambda and would be kept"
+	if got := plan.String(); !strings.Contains(got, want) {
+		t.Errorf("want plan to contain %q, got:\n%s", want, got)
+	}
+}
+
+func TestDelete_KeepsLogsWhenAsked(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"}, glambda.WithKeepLogs())
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("DeleteLogGroup")) != 0 {
+		t.Errorf("want the log group kept, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_ToleratesMissingURLAndLogGroup(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"})
+	recorder.FailNext("DeleteLogGroup", &lTypes.ResourceNotFoundException{Message: aws.String("log group does not exist")}, 1)
+	err := l.Delete(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("DeleteRole")) != 1 {
+		t.Errorf("want the role deleted, got %v", recorder.Operations())
+	}
+}
+
+func TestDelete_KeepsInlinePoliciesOfRoleNotCreatedByGlambda(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "shared-role", nil)
+	recorder.Respond("ListRolePolicies", &iam.ListRolePoliciesOutput{PolicyNames: []string{"shared"}})
+	err := l.Delete(context.Background(), false)
+	if !errors.Is(err, glambda.ErrRoleNotManaged) {
+		t.Fatalf("expected ErrRoleNotManaged, got %v", err)
+	}
+	if len(recorder.Calls("DeleteRolePolicy")) != 0 {
+		t.Errorf("want the shared role's policies kept, got %v", recorder.Operations())
+	}
+	if len(recorder.Calls("DeleteLogGroup")) != 1 {
+		t.Errorf("want the function's log group deleted, got %v", recorder.Operations())
+	}
+}
+
+func TestDeletePlan_ListsWhatDeleteWouldRemoveWithoutRemovingIt(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "custom-role", map[string]string{"glambda:managed": "true"})
+	recorder.Respond("ListEventSourceMappings", &lambda.ListEventSourceMappingsOutput{
+		EventSourceMappings: []types.EventSourceMappingConfiguration{
+			{UUID: aws.String("queue-mapping"), EventSourceArn: aws.String("arn:aws:sqs:us-east-1:123456789012:orders")},
+		},
+	})
+	recorder.Respond("GetPolicy", &lambda.GetPolicyOutput{
+		Policy: aws.String(`{"Statement":[{"Sid":"glambda_invoke_permission_070fef59","Effect":"Allow"}]}`),
+	})
+	recorder.Respond("ListRolePolicies", &iam.ListRolePoliciesOutput{PolicyNames: []string{"glambda_inline_policy"}})
+	plan, err := l.DeletePlan(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `doomed: 6 changes
+  - DeleteEventSourceMapping queue-mapping
+      events from arn:aws:sqs:us-east-1:123456789012:orders
+  - DeleteFunction doomed
+      resource policy statement glambda_invoke_permission_070fef59
+  - DeleteLogGroup /aws/lambda/doomed
+  - DeleteRolePolicy custom-role/glambda_inline_policy
+  - DetachRolePolicy custom-role/arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole
+  - DeleteRole custom-role
+`
+	if got := plan.String(); got != want {
+		t.Errorf("want plan:\n%s\ngot:\n%s", want, got)
+	}
+	for _, op := range recorder.Operations() {
+		if strings.HasPrefix(op, "Delete") || strings.HasPrefix(op, "Detach") {
+			t.Errorf("want nothing deleted, got %v", recorder.Operations())
+			break
+		}
+	}
+}
+
+func TestDeletePlan_WarnsThatARoleNotCreatedByGlambdaIsKept(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := deletableLambda(t, recorder, "shared-role", nil, glambda.WithKeepLogs())
+	plan, err := l.DeletePlan(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `doomed: 1 changes
+  - DeleteFunction doomed
+  ! warning: role shared-role was not created by glambda and would be kept, use --delete-role to delete it
+`
+	if got := plan.String(); got != want {
+		t.Errorf("want plan:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,248 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// Description is an audit of a deployed function and the resources that
+// glambda created alongside it, gathered by [Lambda.Describe].
+type Description struct {
+	FunctionName string `json:"function_name"`
+	FunctionARN  string `json:"function_arn"`
+	State        string `json:"state"`
+	Runtime      string `json:"runtime"`
+	Architecture string `json:"architecture"`
+	Handler      string `json:"handler"`
+	MemoryMB     int32  `json:"memory_mb"`
+	TimeoutSecs  int32  `json:"timeout_seconds"`
+	CodeSize     int64  `json:"code_size"`
+	CodeSHA256   string `json:"code_sha256"`
+	LastModified string `json:"last_modified"`
+	// Environment holds the names of the function's environment variables.
+	// Their values may be secrets, so they are left out.
+	Environment []string          `json:"environment"`
+	Tags        map[string]string `json:"tags"`
+	// Managed reports whether glambda deployed the function, see
+	// [IsManagedFunction], and Owner names any other tool that manages it,
+	// see [ResourceOwner].
+	Managed        bool              `json:"managed"`
+	Owner          string            `json:"owner,omitempty"`
+	Role           RoleDescription   `json:"role"`
+	ResourcePolicy []PolicyStatement `json:"resource_policy"`
+	// FunctionURL is the function's Function URL, or nil if it has none.
+	FunctionURL *FunctionURLDescription `json:"function_url,omitempty"`
+}
+
+// FunctionURLDescription is the part of a [Description] covering the
+// function's Function URL.
+type FunctionURLDescription struct {
+	URL        string `json:"url"`
+	AuthType   string `json:"auth_type"`
+	InvokeMode string `json:"invoke_mode"`
+}
+
+// RoleDescription is the part of a [Description] covering the function's
+// execution role.
+type RoleDescription struct {
+	Name             string   `json:"name"`
+	ARN              string   `json:"arn"`
+	Managed          bool     `json:"managed"`
+	AttachedPolicies []string `json:"attached_policies"`
+	InlinePolicies   []string `json:"inline_policies"`
+}
+
+// PolicyStatement is a single statement of a function's resource policy,
+// saying who may invoke it.
+type PolicyStatement struct {
+	Sid       string          `json:"sid"`
+	Effect    string          `json:"effect"`
+	Principal string          `json:"principal"`
+	Action    string          `json:"action"`
+	Condition json.RawMessage `json:"condition,omitempty"`
+}
+
+// Describe is a method on the [Lambda] struct that gathers the function's
+// configuration, its resource policy and its execution role's policies into a
+// single [Description], so that what glambda created can be audited in one
+// place.
+func (l Lambda) Describe(ctx context.Context) (Description, error) {
+	lambdaClient := l.lambdaAPI()
+	fn, err := lambdaClient.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(l.Name),
+	})
+	if err != nil {
+		return Description{}, err
+	}
+	d := describeFunction(fn)
+	policy, err := lambdaClient.GetPolicy(ctx, &lambda.GetPolicyInput{
+		FunctionName: aws.String(l.Name),
+	})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		// Functions without triggers have no resource policy at all.
+	case err != nil:
+		return Description{}, fmt.Errorf("error reading resource policy, %w", err)
+	default:
+		d.ResourcePolicy, err = policyStatements(aws.ToString(policy.Policy))
+		if err != nil {
+			return Description{}, err
+		}
+	}
+	url, err := lambdaClient.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String(l.Name),
+	})
+	switch {
+	case errors.As(err, &notFound):
+	case err != nil:
+		return Description{}, fmt.Errorf("error reading function URL, %w", err)
+	default:
+		d.FunctionURL = &FunctionURLDescription{
+			URL:        aws.ToString(url.FunctionUrl),
+			AuthType:   string(url.AuthType),
+			InvokeMode: string(url.InvokeMode),
+		}
+	}
+	d.Role, err = l.describeRole(ctx, d.Role.ARN)
+	if err != nil {
+		return Description{}, fmt.Errorf("error reading execution role, %w", err)
+	}
+	return d, nil
+}
+
+func describeFunction(fn *lambda.GetFunctionOutput) Description {
+	d := Description{
+		Tags:    fn.Tags,
+		Managed: IsManagedFunction(fn),
+		Owner:   ResourceOwner(fn.Tags),
+	}
+	c := fn.Configuration
+	if c == nil {
+		return d
+	}
+	d.FunctionName = aws.ToString(c.FunctionName)
+	d.FunctionARN = aws.ToString(c.FunctionArn)
+	d.State = string(c.State)
+	d.Runtime = string(c.Runtime)
+	if len(c.Architectures) > 0 {
+		d.Architecture = string(c.Architectures[0])
+	}
+	d.Handler = aws.ToString(c.Handler)
+	d.MemoryMB = aws.ToInt32(c.MemorySize)
+	d.TimeoutSecs = aws.ToInt32(c.Timeout)
+	d.CodeSize = c.CodeSize
+	d.CodeSHA256 = aws.ToString(c.CodeSha256)
+	d.LastModified = aws.ToString(c.LastModified)
+	if c.Environment != nil {
+		d.Environment = sortedKeys(c.Environment.Variables)
+	}
+	d.Role.ARN = aws.ToString(c.Role)
+	return d
+}
+
+func (l Lambda) describeRole(ctx context.Context, roleARN string) (RoleDescription, error) {
+	name, err := roleNameFromARN(roleARN)
+	if err != nil {
+		return RoleDescription{}, err
+	}
+	iamClient := l.iamAPI()
+	role, err := iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return RoleDescription{}, err
+	}
+	r := RoleDescription{
+		Name:    name,
+		ARN:     roleARN,
+		Managed: IsManagedRole(role.Role),
+	}
+	attached, err := iamClient.ListAttachedRolePolicies(ctx, &iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return RoleDescription{}, err
+	}
+	for _, p := range attached.AttachedPolicies {
+		r.AttachedPolicies = append(r.AttachedPolicies, aws.ToString(p.PolicyArn))
+	}
+	inline, err := iamClient.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return RoleDescription{}, err
+	}
+	r.InlinePolicies = inline.PolicyNames
+	return r, nil
+}
+
+// policyStatements splits the policy document returned by the Lambda GetPolicy
+// API into its statements.
+func policyStatements(document string) ([]PolicyStatement, error) {
+	var policy struct {
+		Statement []struct {
+			Sid       string
+			Effect    string
+			Principal json.RawMessage
+			Action    json.RawMessage
+			Condition json.RawMessage
+		}
+	}
+	err := json.Unmarshal([]byte(document), &policy)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing resource policy, %w", err)
+	}
+	var statements []PolicyStatement
+	for _, s := range policy.Statement {
+		statements = append(statements, PolicyStatement{
+			Sid:       s.Sid,
+			Effect:    s.Effect,
+			Principal: flattenPolicyValue(s.Principal),
+			Action:    flattenPolicyValue(s.Action),
+			Condition: s.Condition,
+		})
+	}
+	return statements, nil
+}
+
+// flattenPolicyValue renders a policy element that may be a string, a list of
+// strings, or an object of either keyed by type, such as {"Service": "..."},
+// as a single comma separated string.
+func flattenPolicyValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return strings.Join(list, ",")
+	}
+	var keyed map[string]json.RawMessage
+	if json.Unmarshal(raw, &keyed) == nil {
+		var values []string
+		for _, k := range sortedKeys(keyed) {
+			values = append(values, flattenPolicyValue(keyed[k]))
+		}
+		return strings.Join(values, ",")
+	}
+	return string(raw)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
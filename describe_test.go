@@ -0,0 +1,129 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestDescribe_AggregatesFunctionResourcePolicyAndRole(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName:  aws.String("audited"),
+			FunctionArn:   aws.String("arn:aws:lambda:us-east-1:123456789012:function:audited"),
+			Role:          aws.String("arn:aws:iam::123456789012:role/service/audited-role"),
+			Runtime:       types.RuntimeProvidedal2023,
+			Architectures: []types.Architecture{types.ArchitectureArm64},
+			MemorySize:    aws.Int32(256),
+			Environment: &types.EnvironmentResponse{
+				Variables: map[string]string{"TABLE": "orders", "API_KEY": "secret"},
+			},
+		},
+		Tags: map[string]string{glambda.ManagedTagKey: "true"},
+	})
+	recorder.Respond("GetPolicy", &lambda.GetPolicyOutput{
+		Policy: aws.String(`{"Version":"2012-10-17","Statement":[{"Sid":"api","Effect":"Allow","Principal":{"Service":"apigateway.amazonaws.com"},"Action":"lambda:InvokeFunction","Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:execute-api:us-east-1:123456789012:abc/*"}}}]}`),
+	})
+	recorder.Respond("ListRolePolicies", &iam.ListRolePoliciesOutput{PolicyNames: []string{"inline-orders"}})
+	l, err := glambda.NewLambda("audited", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithIAMClient(glambdatest.DummyIAMClient{
+			Recorder:   recorder,
+			RoleExists: true,
+			RoleName:   "audited-role",
+			RoleTags:   map[string]string{glambda.ManagedTagKey: "true"},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := l.Describe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Managed || got.MemoryMB != 256 || got.Architecture != "arm64" {
+		t.Errorf("expected function configuration, got %+v", got)
+	}
+	if want := []string{"API_KEY", "TABLE"}; !cmp.Equal(want, got.Environment) {
+		t.Error(cmp.Diff(want, got.Environment))
+	}
+	wantRole := glambda.RoleDescription{
+		Name:             "audited-role",
+		ARN:              "arn:aws:iam::123456789012:role/service/audited-role",
+		Managed:          true,
+		AttachedPolicies: []string{"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+		InlinePolicies:   []string{"inline-orders"},
+	}
+	if !cmp.Equal(wantRole, got.Role) {
+		t.Error(cmp.Diff(wantRole, got.Role))
+	}
+	if len(got.ResourcePolicy) != 1 {
+		t.Fatalf("expected one resource policy statement, got %+v", got.ResourcePolicy)
+	}
+	s := got.ResourcePolicy[0]
+	if s.Sid != "api" || s.Principal != "apigateway.amazonaws.com" || s.Action != "lambda:InvokeFunction" {
+		t.Errorf("unexpected statement %+v", s)
+	}
+	if calls := recorder.Calls("GetRole"); len(calls) != 1 || aws.ToString(calls[0].Input.(*iam.GetRoleInput).RoleName) != "audited-role" {
+		t.Errorf("expected role to be looked up by name, got %v", calls)
+	}
+}
+
+func TestDescribe_ReportsNoResourcePolicyWhenFunctionHasNone(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("plain", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}),
+		glambda.WithIAMClient(glambdatest.DummyIAMClient{Recorder: recorder, RoleExists: true, RoleName: "glambda_exec_role_plain"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := l.Describe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ResourcePolicy != nil || !got.Role.Managed || got.FunctionURL != nil {
+		t.Errorf("expected no resource policy or function URL and a managed role, got %+v", got)
+	}
+}
+
+func TestDescribe_ReportsTheFunctionURLAndItsInvokeMode(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunctionUrlConfig", &lambda.GetFunctionUrlConfigOutput{
+		FunctionUrl: aws.String("https://streamer.lambda-url.us-east-1.on.aws/"),
+		AuthType:    types.FunctionUrlAuthTypeNone,
+		InvokeMode:  types.InvokeModeResponseStream,
+	})
+	l, err := glambda.NewLambda("streamer", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}),
+		glambda.WithIAMClient(glambdatest.DummyIAMClient{Recorder: recorder, RoleExists: true, RoleName: "glambda_exec_role_streamer"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := l.Describe(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &glambda.FunctionURLDescription{
+		URL:        "https://streamer.lambda-url.us-east-1.on.aws/",
+		AuthType:   "NONE",
+		InvokeMode: "RESPONSE_STREAM",
+	}
+	if !cmp.Equal(want, got.FunctionURL) {
+		t.Error(cmp.Diff(want, got.FunctionURL))
+	}
+}
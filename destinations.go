@@ -0,0 +1,227 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// DestinationPolicyName is the name of the inline policy that lets the
+// execution role send to the function's dead-letter queue and asynchronous
+// invocation destinations.
+const DestinationPolicyName = "glambda_async_destinations"
+
+// Destinations are where Lambda sends the result of each asynchronous
+// invocation of a function. Either may be empty.
+type Destinations struct {
+	OnSuccess string
+	OnFailure string
+}
+
+// WithDeadLetterQueue is a deploy option that sends asynchronous events the
+// function couldn't process, after Lambda's retries, to an SQS queue or SNS
+// topic. The execution role is given permission to send to it.
+func WithDeadLetterQueue(arn string) DeployOptions {
+	return func(l *Lambda) error {
+		if destinationActions(arn) == nil {
+			return fmt.Errorf("invalid dead-letter queue %q, must be an SQS queue or SNS topic ARN", arn)
+		}
+		l.DeadLetterQueue = arn
+		return l.updateDestinationPolicy()
+	}
+}
+
+// WithDestinations is a deploy option that sends a record of each
+// asynchronous invocation, with its result or error, to onSuccess or
+// onFailure. Each is an SQS queue, SNS topic, lambda function or EventBridge
+// event bus ARN, or empty to send nothing. The execution role is given
+// permission to send to both. Unlike a dead-letter queue, destinations
+// receive the function's response, not just the event.
+func WithDestinations(onSuccess, onFailure string) DeployOptions {
+	return func(l *Lambda) error {
+		if onSuccess == "" && onFailure == "" {
+			return fmt.Errorf("at least one of the on-success and on-failure destinations must be given")
+		}
+		for _, arn := range []string{onSuccess, onFailure} {
+			if arn != "" && asyncDestinationActions(arn) == nil {
+				return fmt.Errorf("invalid destination %q, must be an SQS queue, SNS topic, lambda function or EventBridge event bus ARN", arn)
+			}
+		}
+		l.Destinations = &Destinations{OnSuccess: onSuccess, OnFailure: onFailure}
+		return l.updateDestinationPolicy()
+	}
+}
+
+// asyncDestinationActions returns the permission needed to send to an
+// asynchronous invocation destination, which unlike an event source's
+// on-failure destination may also be a function or event bus.
+func asyncDestinationActions(arn string) []string {
+	if actions := destinationActions(arn); actions != nil {
+		return actions
+	}
+	switch {
+	case !strings.HasPrefix(arn, "arn:"):
+		return nil
+	case strings.Contains(arn, ":lambda:") && strings.Contains(arn, ":function:"):
+		return []string{"lambda:InvokeFunction"}
+	case strings.Contains(arn, ":events:") && strings.Contains(arn, ":event-bus/"):
+		return []string{"events:PutEvents"}
+	}
+	return nil
+}
+
+func (l *Lambda) updateDestinationPolicy() error {
+	targets := []string{l.DeadLetterQueue}
+	if l.Destinations != nil {
+		targets = append(targets, l.Destinations.OnSuccess, l.Destinations.OnFailure)
+	}
+	policy, err := DestinationPolicy(targets...)
+	if err != nil {
+		return err
+	}
+	policy, err = withStatementIDs(policy, l.Name, DestinationPolicyName)
+	if err != nil {
+		return err
+	}
+	l.ExecutionRole.DestinationPolicy = policy
+	return nil
+}
+
+// DestinationPolicy returns an IAM policy document granting permission to
+// send to each of the given queues, topics, functions or event buses alone.
+// Empty and repeated ARNs are skipped.
+func DestinationPolicy(arns ...string) (string, error) {
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource string
+	}
+	doc := struct {
+		Version   string
+		Statement []statement
+	}{Version: "2012-10-17"}
+	var seen []string
+	for _, arn := range arns {
+		if arn == "" || slices.Contains(seen, arn) {
+			continue
+		}
+		seen = append(seen, arn)
+		doc.Statement = append(doc.Statement, statement{
+			Effect:   "Allow",
+			Action:   asyncDestinationActions(arn),
+			Resource: arn,
+		})
+	}
+	if len(doc.Statement) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func deadLetterConfig(l Lambda) *types.DeadLetterConfig {
+	if l.DeadLetterQueue == "" {
+		return nil
+	}
+	return &types.DeadLetterConfig{TargetArn: aws.String(l.DeadLetterQueue)}
+}
+
+// EventInvokeConfigAction is an [Action] that sets where Lambda sends the
+// results of the function's asynchronous invocations.
+type EventInvokeConfigAction struct {
+	client       LambdaClient
+	Name         string
+	Destinations Destinations
+}
+
+// NewEventInvokeConfigAction is a constructor function that creates a new [EventInvokeConfigAction].
+func NewEventInvokeConfigAction(client LambdaClient, name string, destinations Destinations) EventInvokeConfigAction {
+	return EventInvokeConfigAction{client: client, Name: name, Destinations: destinations}
+}
+
+// Client returns the required client type. In this case [LambdaClient].
+func (a EventInvokeConfigAction) Client() LambdaClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface. The configuration is
+// replaced as a whole, so a destination no longer given is removed. As with
+// event source mappings, Lambda checks the execution role can send to the
+// destinations, so the call is retried while a freshly added role policy
+// propagates.
+func (a EventInvokeConfigAction) Do(ctx context.Context) error {
+	retryLimit := 10
+	for i := 0; ; i++ {
+		_, err := a.Client().PutFunctionEventInvokeConfig(ctx, &lambda.PutFunctionEventInvokeConfigInput{
+			FunctionName:      aws.String(a.Name),
+			DestinationConfig: a.destinationConfig(),
+		})
+		if !isRolePermissionPending(err) || i == retryLimit || ctx.Err() != nil {
+			return err
+		}
+		DefaultRetryWaitingPeriod()
+	}
+}
+
+func (a EventInvokeConfigAction) destinationConfig() *types.DestinationConfig {
+	config := &types.DestinationConfig{}
+	if a.Destinations.OnSuccess != "" {
+		config.OnSuccess = &types.OnSuccess{Destination: aws.String(a.Destinations.OnSuccess)}
+	}
+	if a.Destinations.OnFailure != "" {
+		config.OnFailure = &types.OnFailure{Destination: aws.String(a.Destinations.OnFailure)}
+	}
+	return config
+}
+
+func describeEventInvokeConfig(ctx context.Context, c LambdaClient, name string, d Destinations) (Change, error) {
+	after := map[string]any{}
+	var details []string
+	if d.OnSuccess != "" {
+		after["on_success"] = d.OnSuccess
+		details = append(details, "on success: "+d.OnSuccess)
+	}
+	if d.OnFailure != "" {
+		after["on_failure"] = d.OnFailure
+		details = append(details, "on failure: "+d.OnFailure)
+	}
+	change := Change{
+		Operation:    "PutFunctionEventInvokeConfig",
+		ResourceType: "lambda_function_event_invoke_config",
+		Resource:     name,
+		Action:       "create",
+		After:        after,
+		Details:      details,
+	}
+	existing, err := c.GetFunctionEventInvokeConfig(ctx, &lambda.GetFunctionEventInvokeConfigInput{
+		FunctionName: aws.String(name),
+	})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		return change, nil
+	case err != nil:
+		return Change{}, err
+	}
+	change.Action = "update"
+	change.Before = map[string]any{}
+	if c := existing.DestinationConfig; c != nil {
+		if c.OnSuccess != nil && c.OnSuccess.Destination != nil {
+			change.Before["on_success"] = aws.ToString(c.OnSuccess.Destination)
+		}
+		if c.OnFailure != nil && c.OnFailure.Destination != nil {
+			change.Before["on_failure"] = aws.ToString(c.OnFailure.Destination)
+		}
+	}
+	return change, nil
+}
@@ -0,0 +1,156 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+const (
+	topicARN = "arn:aws:sns:us-east-1:123456789012:alerts"
+	busARN   = "arn:aws:events:us-east-1:123456789012:event-bus/orders"
+)
+
+func TestWithDeadLetterQueue_RejectsOtherResources(t *testing.T) {
+	t.Parallel()
+	for _, arn := range []string{"orders", busARN, "arn:aws:lambda:us-east-1:123456789012:function:retry"} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithDeadLetterQueue(arn))
+		if err == nil {
+			t.Errorf("expected error for dead-letter queue %q, got nil", arn)
+		}
+	}
+}
+
+func TestWithDestinations_RejectsInvalidDestinations(t *testing.T) {
+	t.Parallel()
+	opts := map[string]glambda.DeployOptions{
+		"neither given":  glambda.WithDestinations("", ""),
+		"not an ARN":     glambda.WithDestinations("orders", ""),
+		"unsupported":    glambda.WithDestinations("", "arn:aws:s3:::bucket"),
+		"bus rule ARN":   glambda.WithDestinations("arn:aws:events:us-east-1:123456789012:rule/orders", ""),
+		"function layer": glambda.WithDestinations("arn:aws:lambda:us-east-1:123456789012:layer:shared", ""),
+	}
+	for name, opt := range opts {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), opt)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestWithDeadLetterQueue_SetOnCreateAndUpdate(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithDeadLetterQueue(queueARN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data")).CreateLambdaCommand
+	if create.DeadLetterConfig == nil || aws.ToString(create.DeadLetterConfig.TargetArn) != queueARN {
+		t.Errorf("expected dead-letter queue on create, got %+v", create.DeadLetterConfig)
+	}
+	update := glambda.UpdateConfigurationCommand(*l)
+	if update == nil || update.DeadLetterConfig == nil || aws.ToString(update.DeadLetterConfig.TargetArn) != queueARN {
+		t.Errorf("expected dead-letter queue on update, got %+v", update)
+	}
+}
+
+func TestWithDestinations_GrantsRoleSendOnEachTargetOnce(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithDeadLetterQueue(queueARN),
+		glambda.WithDestinations(busARN, queueARN),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range glambda.PutRolePolicyCommand(l.ExecutionRole) {
+		names = append(names, aws.ToString(p.PolicyName))
+	}
+	if len(names) != 1 || names[0] != glambda.DestinationPolicyName {
+		t.Fatalf("expected destination policy only, got %v", names)
+	}
+	want := `{"Version":"2012-10-17","Statement":[` +
+		`{"Sid":"GlambdaFnAsyncDestinations1","Effect":"Allow","Action":["sqs:SendMessage"],"Resource":"` + queueARN + `"},` +
+		`{"Sid":"GlambdaFnAsyncDestinations2","Effect":"Allow","Action":["events:PutEvents"],"Resource":"` + busARN + `"}]}`
+	if l.ExecutionRole.DestinationPolicy != want {
+		t.Errorf("want %s, got %s", want, l.ExecutionRole.DestinationPolicy)
+	}
+}
+
+func TestDeploy_ConfiguresAsyncDestinations(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithDestinations("", topicARN),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("PutFunctionEventInvokeConfig")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 PutFunctionEventInvokeConfig call, got %v", recorder.Operations())
+	}
+	config := calls[0].Input.(*lambda.PutFunctionEventInvokeConfigInput).DestinationConfig
+	if config.OnSuccess != nil || config.OnFailure == nil || aws.ToString(config.OnFailure.Destination) != topicARN {
+		t.Errorf("expected on-failure destination only, got %+v", config)
+	}
+}
+
+func TestEventInvokeConfigAction_RetriesWhileRolePermissionsPropagate(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	pending := &types.InvalidParameterValueException{Message: aws.String("The function execution role does not have permissions to call SendMessage on SQS")}
+	recorder.FailNext("PutFunctionEventInvokeConfig", pending, 2)
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	action := glambda.NewEventInvokeConfigAction(client, "fn", glambda.Destinations{OnFailure: queueARN})
+	err := action.Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(recorder.Calls("PutFunctionEventInvokeConfig")); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+}
+
+func TestPlan_DescribesDeadLetterQueueAndDestinations(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithDeadLetterQueue(topicARN),
+		glambda.WithDestinations(queueARN, ""),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, c := range plan.Changes {
+		if c.Operation == "CreateFunction" && c.After["dead_letter_queue"] != topicARN {
+			t.Errorf("expected dead-letter queue in CreateFunction, got %v", c.After)
+		}
+		if c.Operation == "PutFunctionEventInvokeConfig" {
+			found = true
+			if c.Action != "create" || c.After["on_success"] != queueARN {
+				t.Errorf("unexpected destinations change %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected PutFunctionEventInvokeConfig change, got %+v", plan.Changes)
+	}
+}
@@ -0,0 +1,113 @@
+package glambda
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CommitTagKey is the tag that records the git commit a function's code was
+// built from, when its handler is in a git repository.
+const CommitTagKey = "glambda:commit"
+
+// BuiltTagKey is the tag that records when a function's code was built, in
+// RFC 3339 format.
+const BuiltTagKey = "glambda:built"
+
+// DowngradeError is returned when a deploy would replace a function's code
+// with code built from an older commit than the one that is live, such as
+// when a CI job for an earlier commit finishes after a later one. See
+// [WithAllowDowngrade].
+type DowngradeError struct {
+	Name     string
+	Commit   string
+	Deployed string
+}
+
+func (e *DowngradeError) Error() string {
+	return fmt.Sprintf("lambda function %s is running commit %s, which is newer than commit %s being deployed; pass --allow-downgrade to deploy it anyway", e.Name, shortCommit(e.Deployed), shortCommit(e.Commit))
+}
+
+// WithAllowDowngrade is a deploy option that deploys code built from an
+// older commit than the one that is live, reporting it as a warning instead
+// of failing with a [DowngradeError], e.g. to roll back on purpose.
+func WithAllowDowngrade() DeployOptions {
+	return func(l *Lambda) error {
+		l.allowDowngrade = true
+		return nil
+	}
+}
+
+// sourceTags are the [CommitTagKey] and [BuiltTagKey] tags for a deploy of
+// the handler at builtAt. The commit is left out when the handler isn't in a
+// git repository, or is a prebuilt [WithBootstrap] executable.
+func (l Lambda) sourceTags(builtAt time.Time) map[string]string {
+	tags := map[string]string{BuiltTagKey: builtAt.UTC().Format(time.RFC3339)}
+	if l.Bootstrap == "" && l.HandlerPath != "" {
+		if commit := gitCommit(filepath.Dir(l.HandlerPath)); commit != "" {
+			tags[CommitTagKey] = commit
+		}
+	}
+	return tags
+}
+
+// checkDowngrade compares the commit being deployed with the one recorded on
+// the live function. Deploying an ancestor of the live commit is a downgrade,
+// which fails unless [WithAllowDowngrade] was given. If either commit is
+// unknown, or the live commit isn't in the local repository, the deploy goes
+// ahead, as there is nothing to compare.
+func (l Lambda) checkDowngrade(commit string, deployedTags map[string]string) error {
+	deployed := deployedTags[CommitTagKey]
+	if commit == "" || deployed == "" || commit == deployed {
+		return nil
+	}
+	older, err := isAncestor(filepath.Dir(l.HandlerPath), commit, deployed)
+	if err != nil {
+		l.detail("downgrade", "can't tell whether %s is older than the live commit %s: %v", shortCommit(commit), shortCommit(deployed), err)
+		return nil
+	}
+	if !older {
+		return nil
+	}
+	if !l.allowDowngrade {
+		return &DowngradeError{Name: l.Name, Commit: commit, Deployed: deployed}
+	}
+	l.report("downgrade", "deploying commit %s over the newer commit %s", shortCommit(commit), shortCommit(deployed))
+	return nil
+}
+
+// gitCommit is the commit checked out in the git repository containing dir,
+// or "" if dir isn't in one or git isn't installed.
+func gitCommit(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// isAncestor reports whether commit is an ancestor of other in the git
+// repository containing dir. It returns an error if either commit isn't in
+// the repository.
+func isAncestor(dir, commit, other string) (bool, error) {
+	cmd := exec.Command("git", "-C", dir, "merge-base", "--is-ancestor", commit, other)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return true, nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
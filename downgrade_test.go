@@ -0,0 +1,120 @@
+package glambda_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+// gitRepo makes a git repository holding the test handler as a module of its
+// own, with two commits, and checks out the first of them. It returns the
+// handler's path and the two commits, oldest first.
+func gitRepo(t *testing.T) (handler, older, newer string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v, %s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	data, err := os.ReadFile("testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums, err := os.ReadFile("go.sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"main.go": data,
+		"go.mod":  []byte("module handler\n\ngo 1.22\n\nrequire github.com/aws/aws-lambda-go v1.47.0\n"),
+		"go.sum":  sums,
+	}
+	for name, data := range files {
+		err = os.WriteFile(filepath.Join(dir, name), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler = filepath.Join(dir, "main.go")
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	older = git("rev-parse", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	newer = git("rev-parse", "HEAD")
+	git("checkout", "-q", older)
+	return handler, older, newer
+}
+
+func liveFunction(recorder *glambdatest.Recorder, commit string) glambdatest.DummyLambdaClient {
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String("live"),
+			FunctionArn:  aws.String("arn:aws:lambda:us-east-1:123456789012:function:live"),
+		},
+		Tags: map[string]string{glambda.ManagedTagKey: "true", glambda.CommitTagKey: commit},
+	})
+	return glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, ConsistantAfterXRetries: new(int)}
+}
+
+func TestDeploy_RefusesCodeFromAnOlderCommitThanTheLiveCode(t *testing.T) {
+	t.Parallel()
+	handler, older, newer := gitRepo(t)
+	recorder := glambdatest.NewRecorder()
+	err := glambda.Deploy("live", handler,
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(liveFunction(recorder, newer)),
+	)
+	var downgrade *glambda.DowngradeError
+	if !errors.As(err, &downgrade) {
+		t.Fatalf("expected DowngradeError, got %v", err)
+	}
+	if downgrade.Commit != older || downgrade.Deployed != newer {
+		t.Errorf("expected downgrade from %s to %s, got %+v", newer, older, downgrade)
+	}
+	if calls := recorder.Calls("UpdateFunctionCode"); len(calls) != 0 {
+		t.Errorf("expected no code update, got %d", len(calls))
+	}
+}
+
+func TestDeploy_AllowsADowngradeOnRequestAndRecordsTheCommit(t *testing.T) {
+	t.Parallel()
+	handler, older, newer := gitRepo(t)
+	recorder := glambdatest.NewRecorder()
+	err := glambda.Deploy("live", handler,
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(liveFunction(recorder, newer)),
+		glambda.WithAllowDowngrade(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := recorder.Calls("TagResource")
+	if len(tag) != 1 {
+		t.Fatalf("expected 1 TagResource call, got %d", len(tag))
+	}
+	tags := tag[0].Input.(*lambda.TagResourceInput).Tags
+	if tags[glambda.CommitTagKey] != older {
+		t.Errorf("expected the function to be tagged with commit %s, got %q", older, tags[glambda.CommitTagKey])
+	}
+	if tags[glambda.BuiltTagKey] == "" {
+		t.Error("expected the function to be tagged with its build time")
+	}
+}
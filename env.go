@@ -0,0 +1,106 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// DeployedEnvironment is a method on the [Lambda] struct that reads the
+// environment variables of the deployed function, along with the revision of
+// the function they were read from. A function without any has an empty
+// environment.
+func (l Lambda) DeployedEnvironment(ctx context.Context) (env map[string]string, revisionID string, err error) {
+	cfg, err := l.lambdaAPI().GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(l.Name),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	env = map[string]string{}
+	if cfg.Environment != nil {
+		maps.Copy(env, cfg.Environment.Variables)
+	}
+	return env, aws.ToString(cfg.RevisionId), nil
+}
+
+// PushEnvironment is a method on the [Lambda] struct that replaces the
+// environment variables of the deployed function with env, leaving its code
+// and the rest of its configuration alone. It returns once the function has
+// been updated.
+//
+// If revisionID isn't empty, the update is only made if the function is still
+// at that revision, as returned by [Lambda.DeployedEnvironment]. That way a
+// variable set by other tooling after env was worked out isn't dropped.
+func (l Lambda) PushEnvironment(ctx context.Context, env map[string]string, revisionID string) error {
+	err := ValidateEnvironment(env)
+	if err != nil {
+		return err
+	}
+	input := &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(l.Name),
+		Environment:  &types.Environment{Variables: env},
+	}
+	if revisionID != "" {
+		input.RevisionId = aws.String(revisionID)
+	}
+	client := l.lambdaAPI()
+	_, err = client.UpdateFunctionConfiguration(ctx, input)
+	var changed *types.PreconditionFailedException
+	if errors.As(err, &changed) {
+		return fmt.Errorf("%s was changed by someone else while its environment was being updated, try again: %w", l.Name, err)
+	}
+	if err != nil {
+		return err
+	}
+	return WaitForUpdate(ctx, client, l.Name)
+}
+
+// MergeEnvironment returns a copy of current with the variables in set added
+// or updated, and those named in unset removed. Any other variables, such as
+// those set by other tooling, are kept.
+func MergeEnvironment(current, set map[string]string, unset []string) map[string]string {
+	merged := maps.Clone(current)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, set)
+	for _, k := range unset {
+		delete(merged, k)
+	}
+	return merged
+}
+
+// EnvironmentChanges lists the variables that change between two
+// environments, sorted by name: "+ KEY" for added, "~ KEY" for changed and
+// "- KEY" for removed variables. Values are left out, as they are often
+// secrets.
+func EnvironmentChanges(before, after map[string]string) []string {
+	keys := sortedKeys(before)
+	for k := range after {
+		if _, ok := before[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	var changes []string
+	for _, k := range keys {
+		old, hadOld := before[k]
+		updated, hasNew := after[k]
+		switch {
+		case !hadOld:
+			changes = append(changes, "+ "+k)
+		case !hasNew:
+			changes = append(changes, "- "+k)
+		case old != updated:
+			changes = append(changes, "~ "+k)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,112 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestEnvironmentChanges_ListsAddedChangedAndRemovedVariablesByName(t *testing.T) {
+	t.Parallel()
+	before := map[string]string{"KEEP": "1", "CHANGE": "old", "REMOVE": "x"}
+	after := map[string]string{"KEEP": "1", "CHANGE": "new", "ADD": "y"}
+	want := []string{"+ ADD", "~ CHANGE", "- REMOVE"}
+	got := glambda.EnvironmentChanges(before, after)
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	if got := glambda.EnvironmentChanges(before, before); len(got) != 0 {
+		t.Errorf("expected no changes, got %v", got)
+	}
+}
+
+func TestPushEnvironment_UpdatesOnlyTheEnvironment(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, Environment: map[string]string{"OLD": "1"}}
+	l, err := glambda.NewLambda("testLambda", "", glambda.WithLambdaClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, _, err := l.DeployedEnvironment(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(map[string]string{"OLD": "1"}, env) {
+		t.Errorf("expected the deployed environment, got %v", env)
+	}
+	want := map[string]string{"NEW": "2"}
+	err = l.PushEnvironment(context.Background(), want, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := recorder.Calls("UpdateFunctionConfiguration")[0].Input.(*lambda.UpdateFunctionConfigurationInput)
+	if !cmp.Equal(want, update.Environment.Variables) {
+		t.Error(cmp.Diff(want, update.Environment.Variables))
+	}
+	if update.Role != nil || update.Timeout != nil || update.MemorySize != nil || update.RevisionId != nil {
+		t.Errorf("expected only the environment to be updated, got %+v", update)
+	}
+}
+
+func TestPushEnvironment_RejectsReservedVariables(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("testLambda", "", glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.PushEnvironment(context.Background(), map[string]string{"AWS_REGION": "us-east-1"}, "")
+	if err == nil {
+		t.Fatal("expected error for a reserved variable, got nil")
+	}
+	if len(recorder.Calls("UpdateFunctionConfiguration")) != 0 {
+		t.Error("expected no update")
+	}
+}
+
+func TestPushEnvironment_FailsIfTheFunctionChangedSinceItsRevision(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunctionConfiguration", &lambda.GetFunctionConfigurationOutput{
+		RevisionId:       aws.String("rev-1"),
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	})
+	l, err := glambda.NewLambda("testLambda", "", glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, revisionID, err := l.DeployedEnvironment(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.FailNext("UpdateFunctionConfiguration", &types.PreconditionFailedException{Message: aws.String("revision changed")}, 1)
+	err = l.PushEnvironment(context.Background(), map[string]string{"KEY": "value"}, revisionID)
+	if err == nil {
+		t.Fatal("expected error for a changed revision, got nil")
+	}
+	update := recorder.Calls("UpdateFunctionConfiguration")[0].Input.(*lambda.UpdateFunctionConfigurationInput)
+	if aws.ToString(update.RevisionId) != "rev-1" {
+		t.Errorf("expected the update to be made at revision rev-1, got %q", aws.ToString(update.RevisionId))
+	}
+}
+
+func TestMergeEnvironment_KeepsVariablesNotMentioned(t *testing.T) {
+	t.Parallel()
+	current := map[string]string{"OTHER_TOOL": "keep", "LOG_LEVEL": "info", "OLD": "x"}
+	got := glambda.MergeEnvironment(current, map[string]string{"LOG_LEVEL": "debug", "NEW": "y"}, []string{"OLD"})
+	want := map[string]string{"OTHER_TOOL": "keep", "LOG_LEVEL": "debug", "NEW": "y"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	if current["LOG_LEVEL"] != "info" {
+		t.Error("expected the current environment to be left alone")
+	}
+}
@@ -0,0 +1,519 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// EventSourcePolicyName is the name of the inline policy that gives the
+// execution role access to the function's event sources.
+const EventSourcePolicyName = "glambda_event_sources"
+
+// EventSource describes a queue or stream that Lambda polls, invoking the
+// function with batches of its records through an event source mapping.
+type EventSource struct {
+	// ARN is the ARN of the queue or stream.
+	ARN string
+	// BatchSize is the most records sent to the function in one invocation.
+	// Zero leaves it to Lambda's default for the source.
+	BatchSize int32
+	// BatchingWindowSeconds is how long Lambda gathers records before
+	// invoking the function, when a batch isn't yet full.
+	BatchingWindowSeconds int32
+	// Actions are the IAM actions the execution role needs on the source.
+	Actions []string
+	// StartingPosition is where Lambda starts reading a stream. It is unset
+	// for queues, and can't be changed once the mapping exists.
+	StartingPosition types.EventSourcePosition
+	// ParallelizationFactor is how many batches from each shard of a stream
+	// are processed at once. Zero leaves it to Lambda's default of 1.
+	ParallelizationFactor int32
+	// OnFailure is the ARN of an SQS queue or SNS topic that is sent details
+	// of stream batches the function failed to process.
+	OnFailure string
+	// ReportBatchItemFailures lets the function report which records of a
+	// batch failed, so that only those are retried.
+	ReportBatchItemFailures bool
+	// MaximumConcurrency is the most instances of the function an SQS
+	// queue's poller invokes at once. Zero leaves it unlimited.
+	MaximumConcurrency int32
+	// Filters are event filter patterns, see [WithEventFilter].
+	Filters []string
+}
+
+// sqsActions are the permissions Lambda's poller needs to consume an SQS queue
+// on the function's behalf.
+var sqsActions = []string{"sqs:ReceiveMessage", "sqs:DeleteMessage", "sqs:GetQueueAttributes"}
+
+// WithSQSTrigger is a deploy option that invokes the lambda function with
+// messages from an SQS queue. The execution role is given permission to
+// consume from the queue. A batchSize of zero uses Lambda's default of 10.
+// Batches larger than 10 are gathered for up to a second, unless
+// [WithBatchingWindow] says otherwise.
+func WithSQSTrigger(queueARN string, batchSize int, opts ...TriggerOption) DeployOptions {
+	return func(l *Lambda) error {
+		if !strings.HasPrefix(queueARN, "arn:") || !strings.Contains(queueARN, ":sqs:") {
+			return fmt.Errorf("invalid SQS queue ARN %q", queueARN)
+		}
+		if batchSize < 0 || batchSize > 10000 {
+			return fmt.Errorf("invalid SQS batch size %d, must be between 1 and 10000", batchSize)
+		}
+		source := EventSource{
+			ARN:       queueARN,
+			BatchSize: int32(batchSize),
+			Actions:   sqsActions,
+		}
+		if batchSize > 10 {
+			source.BatchingWindowSeconds = 1
+		}
+		for _, opt := range opts {
+			err := opt(&source)
+			if err != nil {
+				return err
+			}
+		}
+		return l.addEventSource(source)
+	}
+}
+
+// TriggerOption configures a trigger added by [WithSQSTrigger],
+// [WithDynamoStreamTrigger] or [WithKinesisTrigger].
+type TriggerOption func(*EventSource) error
+
+// StreamOption is the name [TriggerOption] had when only stream triggers
+// took options.
+type StreamOption = TriggerOption
+
+// WithBatchingWindow is a [TriggerOption] that gathers records for up to the
+// given number of seconds, between 0 and 300, before invoking the function
+// with a batch that isn't yet full.
+func WithBatchingWindow(seconds int) TriggerOption {
+	return func(s *EventSource) error {
+		if seconds < 0 || seconds > 300 {
+			return fmt.Errorf("invalid batching window %ds, must be between 0 and 300 seconds", seconds)
+		}
+		s.BatchingWindowSeconds = int32(seconds)
+		return nil
+	}
+}
+
+// WithReportBatchItemFailures is a [TriggerOption] that lets the function
+// return the IDs of the records it failed to process, in a batchItemFailures
+// list, so that only those are retried rather than the whole batch.
+func WithReportBatchItemFailures() TriggerOption {
+	return func(s *EventSource) error {
+		s.ReportBatchItemFailures = true
+		return nil
+	}
+}
+
+// MaxEventFilters is the most filter patterns Lambda accepts on one event
+// source mapping.
+const MaxEventFilters = 5
+
+// WithEventFilter is a [TriggerOption] that only invokes the function with
+// records matching the filter pattern, such as {"body":{"type":["order"]}},
+// so that the function isn't invoked, or billed, for records it would ignore.
+// A record matching any of a trigger's patterns is sent. Records that don't
+// match are dropped, not retried. The pattern's syntax is checked here,
+// rather than when the mapping is created.
+func WithEventFilter(pattern string) TriggerOption {
+	return func(s *EventSource) error {
+		err := validateFilterPattern(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid event filter %s: %w", pattern, err)
+		}
+		if len(s.Filters) == MaxEventFilters {
+			return fmt.Errorf("too many event filters, a trigger can have at most %d", MaxEventFilters)
+		}
+		s.Filters = append(s.Filters, pattern)
+		return nil
+	}
+}
+
+// filterOperators are the keys of the objects that match a field's value in
+// a filter pattern, rather than naming a field.
+var filterOperators = []string{"prefix", "suffix", "anything-but", "numeric", "exists", "equals-ignore-case"}
+
+// validateFilterPattern checks a filter pattern against the syntax Lambda
+// accepts: an object whose fields are either nested objects, or lists of
+// values and matching operators.
+func validateFilterPattern(pattern string) error {
+	if len(pattern) > 4096 {
+		return fmt.Errorf("longer than 4096 characters")
+	}
+	var doc map[string]any
+	err := json.Unmarshal([]byte(pattern), &doc)
+	if err != nil {
+		return fmt.Errorf("not a JSON object, %w", err)
+	}
+	return validateFilterFields(doc)
+}
+
+func validateFilterFields(fields map[string]any) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("empty object")
+	}
+	for name, value := range fields {
+		switch v := value.(type) {
+		case map[string]any:
+			err := validateFilterFields(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		case []any:
+			if len(v) == 0 {
+				return fmt.Errorf("%s: empty list of values", name)
+			}
+			for _, match := range v {
+				err := validateFilterMatch(name, match)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+		default:
+			return fmt.Errorf("%s: values must be in a list, e.g. [%v]", name, value)
+		}
+	}
+	return nil
+}
+
+func validateFilterMatch(name string, match any) error {
+	object, ok := match.(map[string]any)
+	if !ok {
+		return nil
+	}
+	// $or combines whole patterns rather than matching a value.
+	if name == "$or" {
+		return validateFilterFields(object)
+	}
+	if len(object) != 1 {
+		return fmt.Errorf("an operator object must have exactly one of %s", strings.Join(filterOperators, ", "))
+	}
+	for operator, arg := range object {
+		if !slices.Contains(filterOperators, operator) {
+			return fmt.Errorf("unknown operator %q, expected one of %s", operator, strings.Join(filterOperators, ", "))
+		}
+		if _, ok := arg.(bool); operator == "exists" && !ok {
+			return fmt.Errorf("exists takes true or false")
+		}
+		if _, ok := arg.([]any); operator == "numeric" && !ok {
+			return fmt.Errorf(`numeric takes a list of comparisons, e.g. [">", 0, "<=", 5]`)
+		}
+	}
+	return nil
+}
+
+// WithMaximumConcurrency is a [TriggerOption] that limits an SQS trigger to
+// invoking n instances of the function at once, between 2 and 1000, so that a
+// backlog of messages can't use up the account's concurrency.
+func WithMaximumConcurrency(n int) TriggerOption {
+	return func(s *EventSource) error {
+		if n < 2 || n > 1000 {
+			return fmt.Errorf("invalid maximum concurrency %d, must be between 2 and 1000", n)
+		}
+		s.MaximumConcurrency = int32(n)
+		return nil
+	}
+}
+
+// WithParallelizationFactor is a [StreamOption] that processes up to n
+// batches from each shard concurrently, between 1 and 10.
+func WithParallelizationFactor(n int) StreamOption {
+	return func(s *EventSource) error {
+		if n < 1 || n > 10 {
+			return fmt.Errorf("invalid parallelization factor %d, must be between 1 and 10", n)
+		}
+		s.ParallelizationFactor = int32(n)
+		return nil
+	}
+}
+
+// WithOnFailureDestination is a [StreamOption] that sends details of batches
+// the function couldn't process to an SQS queue or SNS topic, rather than
+// dropping them when their records expire. The execution role is given
+// permission to send to the destination.
+func WithOnFailureDestination(arn string) StreamOption {
+	return func(s *EventSource) error {
+		if destinationActions(arn) == nil {
+			return fmt.Errorf("invalid on-failure destination %q, must be an SQS queue or SNS topic ARN", arn)
+		}
+		s.OnFailure = arn
+		return nil
+	}
+}
+
+// dynamoStreamActions and kinesisActions are the permissions Lambda's poller
+// needs to read a stream on the function's behalf.
+var (
+	dynamoStreamActions = []string{"dynamodb:DescribeStream", "dynamodb:GetRecords", "dynamodb:GetShardIterator", "dynamodb:ListStreams"}
+	kinesisActions      = []string{"kinesis:DescribeStream", "kinesis:DescribeStreamSummary", "kinesis:GetRecords", "kinesis:GetShardIterator", "kinesis:ListShards", "kinesis:ListStreams", "kinesis:SubscribeToShard"}
+)
+
+// WithDynamoStreamTrigger is a deploy option that invokes the lambda function
+// with records from a DynamoDB stream, starting at TRIM_HORIZON (the oldest
+// record) or LATEST. The execution role is given permission to read the
+// stream. A batchSize of zero uses Lambda's default of 100.
+func WithDynamoStreamTrigger(streamARN, startingPosition string, batchSize int, opts ...StreamOption) DeployOptions {
+	return func(l *Lambda) error {
+		if !strings.HasPrefix(streamARN, "arn:") || !strings.Contains(streamARN, ":dynamodb:") || !strings.Contains(streamARN, "/stream/") {
+			return fmt.Errorf("invalid DynamoDB stream ARN %q", streamARN)
+		}
+		source, err := streamSource(streamARN, startingPosition, batchSize, dynamoStreamActions, opts)
+		if err != nil {
+			return err
+		}
+		return l.addEventSource(source)
+	}
+}
+
+// WithKinesisTrigger is a deploy option that invokes the lambda function with
+// records from a Kinesis data stream, starting at TRIM_HORIZON (the oldest
+// record) or LATEST. The execution role is given permission to read the
+// stream. A batchSize of zero uses Lambda's default of 100.
+func WithKinesisTrigger(streamARN, startingPosition string, batchSize int, opts ...StreamOption) DeployOptions {
+	return func(l *Lambda) error {
+		if !strings.HasPrefix(streamARN, "arn:") || !strings.Contains(streamARN, ":kinesis:") || !strings.Contains(streamARN, ":stream/") {
+			return fmt.Errorf("invalid Kinesis stream ARN %q", streamARN)
+		}
+		source, err := streamSource(streamARN, startingPosition, batchSize, kinesisActions, opts)
+		if err != nil {
+			return err
+		}
+		return l.addEventSource(source)
+	}
+}
+
+func streamSource(arn, startingPosition string, batchSize int, actions []string, opts []StreamOption) (EventSource, error) {
+	position := types.EventSourcePosition(strings.ToUpper(startingPosition))
+	if position != types.EventSourcePositionTrimHorizon && position != types.EventSourcePositionLatest {
+		return EventSource{}, fmt.Errorf("invalid starting position %q, must be TRIM_HORIZON or LATEST", startingPosition)
+	}
+	if batchSize < 0 || batchSize > 10000 {
+		return EventSource{}, fmt.Errorf("invalid stream batch size %d, must be between 1 and 10000", batchSize)
+	}
+	source := EventSource{
+		ARN:              arn,
+		BatchSize:        int32(batchSize),
+		Actions:          actions,
+		StartingPosition: position,
+	}
+	for _, opt := range opts {
+		err := opt(&source)
+		if err != nil {
+			return EventSource{}, err
+		}
+	}
+	if source.MaximumConcurrency != 0 {
+		return EventSource{}, fmt.Errorf("maximum concurrency only applies to SQS triggers, not stream %s", arn)
+	}
+	return source, nil
+}
+
+// destinationActions returns the permission needed to send to an on-failure
+// destination, or nil if the ARN isn't a queue or topic.
+func destinationActions(arn string) []string {
+	if !strings.HasPrefix(arn, "arn:") {
+		return nil
+	}
+	switch {
+	case strings.Contains(arn, ":sqs:"):
+		return []string{"sqs:SendMessage"}
+	case strings.Contains(arn, ":sns:"):
+		return []string{"sns:Publish"}
+	}
+	return nil
+}
+
+func (l *Lambda) addEventSource(source EventSource) error {
+	l.EventSources = append(l.EventSources, source)
+	policy, err := EventSourcePolicy(l.EventSources)
+	if err != nil {
+		return err
+	}
+	policy, err = withStatementIDs(policy, l.Name, EventSourcePolicyName)
+	if err != nil {
+		return err
+	}
+	l.ExecutionRole.EventSourcePolicy = policy
+	return nil
+}
+
+// EventSourcePolicy returns an IAM policy document granting each source's
+// actions on that source alone, and the right to send to its on-failure
+// destination, if any.
+func EventSourcePolicy(sources []EventSource) (string, error) {
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource string
+	}
+	doc := struct {
+		Version   string
+		Statement []statement
+	}{Version: "2012-10-17"}
+	for _, s := range sources {
+		doc.Statement = append(doc.Statement, statement{
+			Effect:   "Allow",
+			Action:   s.Actions,
+			Resource: s.ARN,
+		})
+		if s.OnFailure != "" {
+			doc.Statement = append(doc.Statement, statement{
+				Effect:   "Allow",
+				Action:   destinationActions(s.OnFailure),
+				Resource: s.OnFailure,
+			})
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// EventSourceMappingAction is an [Action] that creates the event source
+// mapping between a source and a lambda function, or updates the existing
+// one to match the [EventSource].
+type EventSourceMappingAction struct {
+	client LambdaClient
+	Name   string
+	Source EventSource
+}
+
+// NewEventSourceMappingAction is a constructor function that creates a new [EventSourceMappingAction].
+func NewEventSourceMappingAction(client LambdaClient, name string, source EventSource) EventSourceMappingAction {
+	return EventSourceMappingAction{client: client, Name: name, Source: source}
+}
+
+// Client returns the required client type. In this case [LambdaClient].
+func (a EventSourceMappingAction) Client() LambdaClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface. Lambda checks that the
+// execution role can read from the source when the mapping is created, and a
+// freshly added role policy can take a few seconds to become visible to it, so
+// creation is retried while the role lacks permissions.
+func (a EventSourceMappingAction) Do(ctx context.Context) error {
+	existing, err := findEventSourceMapping(ctx, a.Client(), a.Name, a.Source.ARN)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		_, err = a.Client().UpdateEventSourceMapping(ctx, &lambda.UpdateEventSourceMappingInput{
+			UUID:                           existing.UUID,
+			FunctionName:                   aws.String(a.Name),
+			BatchSize:                      optionalInt32(a.Source.BatchSize),
+			MaximumBatchingWindowInSeconds: optionalInt32(a.Source.BatchingWindowSeconds),
+			ParallelizationFactor:          optionalInt32(a.Source.ParallelizationFactor),
+			DestinationConfig:              a.destinationConfig(),
+			FunctionResponseTypes:          a.functionResponseTypes(true),
+			ScalingConfig:                  a.scalingConfig(true),
+			FilterCriteria:                 a.filterCriteria(true),
+		})
+		return err
+	}
+	retryLimit := 10
+	for i := 0; ; i++ {
+		_, err = a.Client().CreateEventSourceMapping(ctx, &lambda.CreateEventSourceMappingInput{
+			FunctionName:                   aws.String(a.Name),
+			EventSourceArn:                 aws.String(a.Source.ARN),
+			BatchSize:                      optionalInt32(a.Source.BatchSize),
+			MaximumBatchingWindowInSeconds: optionalInt32(a.Source.BatchingWindowSeconds),
+			StartingPosition:               a.Source.StartingPosition,
+			ParallelizationFactor:          optionalInt32(a.Source.ParallelizationFactor),
+			DestinationConfig:              a.destinationConfig(),
+			FunctionResponseTypes:          a.functionResponseTypes(false),
+			ScalingConfig:                  a.scalingConfig(false),
+			FilterCriteria:                 a.filterCriteria(false),
+		})
+		if !isRolePermissionPending(err) || i == retryLimit || ctx.Err() != nil {
+			return err
+		}
+		DefaultRetryWaitingPeriod()
+	}
+}
+
+func (a EventSourceMappingAction) destinationConfig() *types.DestinationConfig {
+	if a.Source.OnFailure == "" {
+		return nil
+	}
+	return &types.DestinationConfig{
+		OnFailure: &types.OnFailure{Destination: aws.String(a.Source.OnFailure)},
+	}
+}
+
+// functionResponseTypes is an empty list for an update, so that it turns off
+// reporting of batch item failures when it is no longer wanted.
+func (a EventSourceMappingAction) functionResponseTypes(update bool) []types.FunctionResponseType {
+	switch {
+	case a.Source.ReportBatchItemFailures:
+		return []types.FunctionResponseType{types.FunctionResponseTypeReportBatchItemFailures}
+	case update:
+		return []types.FunctionResponseType{}
+	}
+	return nil
+}
+
+// scalingConfig only applies to SQS queues. An empty one removes the maximum
+// concurrency of an existing mapping.
+func (a EventSourceMappingAction) scalingConfig(update bool) *types.ScalingConfig {
+	switch {
+	case !strings.Contains(a.Source.ARN, ":sqs:"):
+		return nil
+	case a.Source.MaximumConcurrency != 0:
+		return &types.ScalingConfig{MaximumConcurrency: aws.Int32(a.Source.MaximumConcurrency)}
+	case update:
+		return &types.ScalingConfig{}
+	}
+	return nil
+}
+
+// filterCriteria has an empty list of filters for an update, which removes
+// the filters of an existing mapping.
+func (a EventSourceMappingAction) filterCriteria(update bool) *types.FilterCriteria {
+	if len(a.Source.Filters) == 0 && !update {
+		return nil
+	}
+	criteria := &types.FilterCriteria{Filters: []types.Filter{}}
+	for _, pattern := range a.Source.Filters {
+		criteria.Filters = append(criteria.Filters, types.Filter{Pattern: aws.String(pattern)})
+	}
+	return criteria
+}
+
+func isRolePermissionPending(err error) bool {
+	var invalid *types.InvalidParameterValueException
+	return errors.As(err, &invalid) && strings.Contains(aws.ToString(invalid.Message), "execution role does not have permissions")
+}
+
+func optionalInt32(n int32) *int32 {
+	if n == 0 {
+		return nil
+	}
+	return aws.Int32(n)
+}
+
+func findEventSourceMapping(ctx context.Context, c LambdaClient, name, sourceARN string) (*types.EventSourceMappingConfiguration, error) {
+	resp, err := c.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+		FunctionName:   aws.String(name),
+		EventSourceArn: aws.String(sourceARN),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.EventSourceMappings) == 0 {
+		return nil, nil
+	}
+	return &resp.EventSourceMappings[0], nil
+}
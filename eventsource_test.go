@@ -0,0 +1,274 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+const queueARN = "arn:aws:sqs:us-east-1:123456789012:orders"
+
+func TestWithSQSTrigger_RejectsInvalidQueueAndBatchSize(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithSQSTrigger("orders", 10))
+	if err == nil {
+		t.Error("expected error for queue name instead of ARN, got nil")
+	}
+	_, err = glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithSQSTrigger(queueARN, 10001))
+	if err == nil {
+		t.Error("expected error for oversized batch, got nil")
+	}
+}
+
+func TestWithSQSTrigger_GrantsRoleAccessToQueueOnly(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`),
+		glambda.WithSQSTrigger(queueARN, 0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range glambda.PutRolePolicyCommand(l.ExecutionRole) {
+		names = append(names, aws.ToString(p.PolicyName))
+	}
+	if len(names) != 2 || names[1] != glambda.EventSourcePolicyName {
+		t.Fatalf("expected user and event source policies, got %v", names)
+	}
+	want := `{"Version":"2012-10-17","Statement":[{"Sid":"GlambdaFnEventSources1","Effect":"Allow","Action":["sqs:ReceiveMessage","sqs:DeleteMessage","sqs:GetQueueAttributes"],"Resource":"` + queueARN + `"}]}`
+	if l.ExecutionRole.EventSourcePolicy != want {
+		t.Errorf("want %s, got %s", want, l.ExecutionRole.EventSourcePolicy)
+	}
+}
+
+func TestEventSourceMappingAction_RetriesWhileRolePermissionsPropagate(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateEventSourceMapping", &types.InvalidParameterValueException{
+		Message: aws.String("The provided execution role does not have permissions to call ReceiveMessage on SQS"),
+	}, 2)
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	err := glambda.NewEventSourceMappingAction(client, "fn", glambda.EventSource{ARN: queueARN, BatchSize: 5}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ListEventSourceMappings", "CreateEventSourceMapping", "CreateEventSourceMapping", "CreateEventSourceMapping"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Error(cmp.Diff(want, recorder.Operations()))
+	}
+}
+
+func TestEventSourceMappingAction_UpdatesExistingMapping(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("ListEventSourceMappings", &lambda.ListEventSourceMappingsOutput{
+		EventSourceMappings: []types.EventSourceMappingConfiguration{{
+			UUID:           aws.String("existing-uuid"),
+			EventSourceArn: aws.String(queueARN),
+		}},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	err := glambda.NewEventSourceMappingAction(client, "fn", glambda.EventSource{ARN: queueARN, BatchSize: 5}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ListEventSourceMappings", "UpdateEventSourceMapping"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Fatal(cmp.Diff(want, recorder.Operations()))
+	}
+	update := recorder.Calls("UpdateEventSourceMapping")[0].Input.(*lambda.UpdateEventSourceMappingInput)
+	if aws.ToString(update.UUID) != "existing-uuid" || aws.ToInt32(update.BatchSize) != 5 {
+		t.Errorf("unexpected update %+v", update)
+	}
+}
+
+func TestEventSourceMappingAction_TunesSQSMapping(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithSQSTrigger(queueARN, 100, glambda.WithBatchingWindow(20), glambda.WithReportBatchItemFailures(), glambda.WithMaximumConcurrency(5)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewEventSourceMappingAction(client, "fn", l.EventSources[0]).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recorder.Calls("CreateEventSourceMapping")[0].Input.(*lambda.CreateEventSourceMappingInput)
+	want := &lambda.CreateEventSourceMappingInput{
+		FunctionName:                   aws.String("fn"),
+		EventSourceArn:                 aws.String(queueARN),
+		BatchSize:                      aws.Int32(100),
+		MaximumBatchingWindowInSeconds: aws.Int32(20),
+		FunctionResponseTypes:          []types.FunctionResponseType{types.FunctionResponseTypeReportBatchItemFailures},
+		ScalingConfig:                  &types.ScalingConfig{MaximumConcurrency: aws.Int32(5)},
+	}
+	ignore := cmpopts.IgnoreUnexported(lambda.CreateEventSourceMappingInput{}, types.ScalingConfig{})
+	if !cmp.Equal(want, got, ignore) {
+		t.Error(cmp.Diff(want, got, ignore))
+	}
+}
+
+func TestEventSourceMappingAction_UpdateRemovesSQSTuningNoLongerWanted(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("ListEventSourceMappings", &lambda.ListEventSourceMappingsOutput{
+		EventSourceMappings: []types.EventSourceMappingConfiguration{{UUID: aws.String("existing-uuid"), EventSourceArn: aws.String(queueARN)}},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	err := glambda.NewEventSourceMappingAction(client, "fn", glambda.EventSource{ARN: queueARN}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := recorder.Calls("UpdateEventSourceMapping")[0].Input.(*lambda.UpdateEventSourceMappingInput)
+	if update.FunctionResponseTypes == nil || len(update.FunctionResponseTypes) != 0 {
+		t.Errorf("want an empty list of response types to turn reporting off, got %v", update.FunctionResponseTypes)
+	}
+	if update.ScalingConfig == nil || update.ScalingConfig.MaximumConcurrency != nil {
+		t.Errorf("want an empty scaling config to remove the maximum concurrency, got %+v", update.ScalingConfig)
+	}
+}
+
+const streamARN = "arn:aws:dynamodb:us-east-1:123456789012:table/orders/stream/2024-01-01T00:00:00.000"
+
+func TestWithDynamoStreamTrigger_RejectsInvalidSettings(t *testing.T) {
+	t.Parallel()
+	tests := map[string]glambda.DeployOptions{
+		"table ARN":           glambda.WithDynamoStreamTrigger("arn:aws:dynamodb:us-east-1:123456789012:table/orders", "LATEST", 0),
+		"starting position":   glambda.WithDynamoStreamTrigger(streamARN, "AT_TIMESTAMP", 0),
+		"parallelization":     glambda.WithDynamoStreamTrigger(streamARN, "LATEST", 0, glambda.WithParallelizationFactor(11)),
+		"failure destination": glambda.WithDynamoStreamTrigger(streamARN, "LATEST", 0, glambda.WithOnFailureDestination("arn:aws:s3:::bucket")),
+		"kinesis with dynamo": glambda.WithKinesisTrigger(streamARN, "LATEST", 0),
+		"kinesis batch size":  glambda.WithKinesisTrigger("arn:aws:kinesis:us-east-1:123456789012:stream/clicks", "LATEST", 10001),
+		"stream concurrency":  glambda.WithDynamoStreamTrigger(streamARN, "LATEST", 0, glambda.WithMaximumConcurrency(5)),
+		"batching window":     glambda.WithDynamoStreamTrigger(streamARN, "LATEST", 0, glambda.WithBatchingWindow(301)),
+	}
+	for name, opt := range tests {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), opt)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestWithDynamoStreamTrigger_GrantsRoleAccessToStreamAndDestination(t *testing.T) {
+	t.Parallel()
+	dlq := "arn:aws:sqs:us-east-1:123456789012:orders-dlq"
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithDynamoStreamTrigger(streamARN, "trim_horizon", 50, glambda.WithOnFailureDestination(dlq)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Version":"2012-10-17","Statement":[` +
+		`{"Sid":"GlambdaFnEventSources1","Effect":"Allow","Action":["dynamodb:DescribeStream","dynamodb:GetRecords","dynamodb:GetShardIterator","dynamodb:ListStreams"],"Resource":"` + streamARN + `"},` +
+		`{"Sid":"GlambdaFnEventSources2","Effect":"Allow","Action":["sqs:SendMessage"],"Resource":"` + dlq + `"}]}`
+	if l.ExecutionRole.EventSourcePolicy != want {
+		t.Errorf("want %s, got %s", want, l.ExecutionRole.EventSourcePolicy)
+	}
+}
+
+func TestEventSourceMappingAction_ConfiguresStreamMapping(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	stream := "arn:aws:kinesis:us-east-1:123456789012:stream/clicks"
+	topic := "arn:aws:sns:us-east-1:123456789012:failures"
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithKinesisTrigger(stream, "LATEST", 200, glambda.WithParallelizationFactor(4), glambda.WithOnFailureDestination(topic)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewEventSourceMappingAction(client, "fn", l.EventSources[0]).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recorder.Calls("CreateEventSourceMapping")[0].Input.(*lambda.CreateEventSourceMappingInput)
+	want := &lambda.CreateEventSourceMappingInput{
+		FunctionName:          aws.String("fn"),
+		EventSourceArn:        aws.String(stream),
+		BatchSize:             aws.Int32(200),
+		StartingPosition:      types.EventSourcePositionLatest,
+		ParallelizationFactor: aws.Int32(4),
+		DestinationConfig: &types.DestinationConfig{
+			OnFailure: &types.OnFailure{Destination: aws.String(topic)},
+		},
+	}
+	if !cmp.Equal(want, got, cmpopts.IgnoreUnexported(lambda.CreateEventSourceMappingInput{}, types.DestinationConfig{}, types.OnFailure{})) {
+		t.Error(cmp.Diff(want, got, cmpopts.IgnoreUnexported(lambda.CreateEventSourceMappingInput{}, types.DestinationConfig{}, types.OnFailure{})))
+	}
+}
+
+func TestWithEventFilter_ChecksPatternSyntax(t *testing.T) {
+	t.Parallel()
+	for _, pattern := range []string{
+		`{"body":{"type":["order"]}}`,
+		`{"body":{"total":[{"numeric":[">",100]}],"region":[{"prefix":"eu-"}]}}`,
+		`{"dynamodb":{"NewImage":{"status":{"S":[{"anything-but":["draft"]}]}}}}`,
+		`{"$or":[{"body":{"a":["x"]}},{"body":{"b":[{"exists":true}]}}]}`,
+	} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithSQSTrigger(queueARN, 0, glambda.WithEventFilter(pattern)))
+		if err != nil {
+			t.Errorf("%s: %v", pattern, err)
+		}
+	}
+	for pattern, want := range map[string]string{
+		`["order"]`:                              "not a JSON object",
+		`{}`:                                     "empty object",
+		`{"body":{"type":"order"}}`:              "must be in a list",
+		`{"body":{"type":[]}}`:                   "empty list",
+		`{"body":{"type":[{"startswith":"o"}]}}`: `unknown operator "startswith"`,
+		`{"body":{"type":[{"exists":"yes"}]}}`:   "exists takes true or false",
+	} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithSQSTrigger(queueARN, 0, glambda.WithEventFilter(pattern)))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want error containing %q, got %v", pattern, want, err)
+		}
+	}
+}
+
+func TestEventSourceMappingAction_AppliesAndRemovesEventFilters(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	pattern := `{"body":{"type":["order"]}}`
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithSQSTrigger(queueARN, 0, glambda.WithEventFilter(pattern)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewEventSourceMappingAction(client, "fn", l.EventSources[0]).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := recorder.Calls("CreateEventSourceMapping")[0].Input.(*lambda.CreateEventSourceMappingInput)
+	if create.FilterCriteria == nil || len(create.FilterCriteria.Filters) != 1 || aws.ToString(create.FilterCriteria.Filters[0].Pattern) != pattern {
+		t.Errorf("want the filter on the new mapping, got %+v", create.FilterCriteria)
+	}
+	recorder.Respond("ListEventSourceMappings", &lambda.ListEventSourceMappingsOutput{
+		EventSourceMappings: []types.EventSourceMappingConfiguration{{UUID: aws.String("existing-uuid"), EventSourceArn: aws.String(queueARN)}},
+	})
+	err = glambda.NewEventSourceMappingAction(client, "fn", glambda.EventSource{ARN: queueARN}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := recorder.Calls("UpdateEventSourceMapping")[0].Input.(*lambda.UpdateEventSourceMappingInput)
+	if update.FilterCriteria == nil || update.FilterCriteria.Filters == nil || len(update.FilterCriteria.Filters) != 0 {
+		t.Errorf("want an empty list of filters to remove them, got %+v", update.FilterCriteria)
+	}
+}
@@ -0,0 +1,212 @@
+package glambda
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithExcludes is a deploy option that leaves files matching any of the
+// patterns out of the assets added by [WithAssets], such as "*.md" or
+// "testdata/**". A pattern without a slash matches a file or directory of
+// that name at any depth. A pattern with a slash matches paths relative to
+// the asset directory, where ** stands for any number of directories.
+// Excluding a directory excludes everything beneath it. Patterns follow the
+// same rules as a .lambdaignore file, see [IgnoreFile].
+func WithExcludes(patterns ...string) DeployOptions {
+	return func(l *Lambda) error {
+		for _, p := range patterns {
+			_, err := parsePattern(p)
+			if err != nil {
+				return err
+			}
+		}
+		l.assetExcludes = append(l.assetExcludes, patterns...)
+		return nil
+	}
+}
+
+// IgnoreFile is the name of the file, kept at the root of a handler, that
+// lists assets to leave out of its zip in gitignore syntax. Each line is a
+// pattern as for [WithExcludes]. Blank lines and lines starting with # are
+// skipped, a pattern ending in a slash only matches directories, and a
+// pattern starting with ! includes again what an earlier pattern excluded.
+// As with git, a file can't be included again once a directory it is in has
+// been excluded.
+const IgnoreFile = ".lambdaignore"
+
+// pathPattern is a pattern matched against slash separated paths relative
+// to a directory.
+type pathPattern struct {
+	segments []string
+	// anchored patterns contain a slash, and match from the directory rather
+	// than at any depth.
+	anchored bool
+	// negate patterns include again what an earlier pattern excluded.
+	negate bool
+	// dirOnly patterns end in a slash and only match directories.
+	dirOnly bool
+}
+
+func parsePattern(p string) (pathPattern, error) {
+	if p == "" {
+		return pathPattern{}, fmt.Errorf("exclude pattern must not be empty")
+	}
+	var pattern pathPattern
+	body := p
+	if strings.HasPrefix(body, "!") {
+		pattern.negate = true
+		body = body[1:]
+	} else if strings.HasPrefix(body, `\!`) || strings.HasPrefix(body, `\#`) {
+		body = body[1:]
+	}
+	if strings.HasSuffix(body, "/") {
+		pattern.dirOnly = true
+		body = strings.TrimSuffix(body, "/")
+	}
+	if body == "" {
+		return pathPattern{}, fmt.Errorf("invalid exclude pattern %q", p)
+	}
+	pattern.anchored = strings.Contains(body, "/")
+	pattern.segments = strings.Split(strings.TrimPrefix(body, "/"), "/")
+	for _, s := range pattern.segments {
+		_, err := path.Match(s, "")
+		if err != nil {
+			return pathPattern{}, fmt.Errorf("invalid exclude pattern %q, %w", p, err)
+		}
+	}
+	return pattern, nil
+}
+
+// parsePatterns parses each of the patterns.
+func parsePatterns(patterns []string) ([]pathPattern, error) {
+	parsed := make([]pathPattern, 0, len(patterns))
+	for _, p := range patterns {
+		pattern, err := parsePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, pattern)
+	}
+	return parsed, nil
+}
+
+// match reports whether the pattern matches the path, which is a directory
+// if dir is true, or one of the directories it is in.
+func (p pathPattern) match(name string, dir bool) bool {
+	parts := strings.Split(name, "/")
+	last := len(parts)
+	if p.dirOnly && !dir {
+		last--
+	}
+	if !p.anchored {
+		for _, part := range parts[:last] {
+			if ok, _ := path.Match(p.segments[0], part); ok {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 1; i <= last; i++ {
+		if matchSegments(p.segments, parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		// A trailing ** matches what is inside a directory, not the
+		// directory itself.
+		if len(pattern) == 1 {
+			return len(parts) > 0
+		}
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], parts[0])
+	return ok && matchSegments(pattern[1:], parts[1:])
+}
+
+// excluded reports whether the path, a directory if dir is true, is left out
+// by the patterns. The last pattern to match decides.
+func excluded(patterns []pathPattern, name string, dir bool) bool {
+	out := false
+	for _, p := range patterns {
+		if p.match(name, dir) {
+			out = !p.negate
+		}
+	}
+	return out
+}
+
+// ignoreRules are the patterns of an [IgnoreFile], which apply to paths
+// relative to the directory it is in.
+type ignoreRules struct {
+	dir      string
+	patterns []pathPattern
+}
+
+// readIgnoreFile reads the [IgnoreFile] at the root of the handler, the
+// handler's directory or the directory its file is in. A missing file gives
+// no rules.
+func readIgnoreFile(handler string) (ignoreRules, error) {
+	dir := handler
+	if info, err := os.Stat(handler); err != nil || !info.IsDir() {
+		dir = filepath.Dir(handler)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ignoreRules{}, err
+	}
+	file := filepath.Join(dir, IgnoreFile)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ignoreRules{}, nil
+	}
+	if err != nil {
+		return ignoreRules{}, err
+	}
+	rules := ignoreRules{dir: dir}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := parsePattern(line)
+		if err != nil {
+			return ignoreRules{}, fmt.Errorf("%s:%d: %w", file, i+1, err)
+		}
+		rules.patterns = append(rules.patterns, p)
+	}
+	return rules, nil
+}
+
+// excludes reports whether the rules leave out the file or directory at the
+// absolute path. Paths outside the ignore file's directory are never left
+// out.
+func (r ignoreRules) excludes(path string, dir bool) bool {
+	if len(r.patterns) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(r.dir, path)
+	if err != nil || rel == "." || outside(rel) {
+		return false
+	}
+	return excluded(r.patterns, filepath.ToSlash(rel), dir)
+}
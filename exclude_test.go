@@ -0,0 +1,179 @@
+package glambda_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+// writeFiles creates each of the files, named by slash separated paths
+// relative to dir.
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(name+"\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPackageWith_LeavesOutExcludedAssets(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir,
+		"site/index.html",
+		"site/README.md",
+		"site/css/main.css",
+		"site/css/NOTES.md",
+		"site/testdata/golden.html",
+		"site/img/raw/logo.psd",
+		"site/img/logo.png",
+		"site/.git/HEAD",
+	)
+	data, err := glambda.PackageWith("testdata/correct_test_handler/main.go", glambda.BuildOptions{
+		Assets:   []string{"site"},
+		AssetDir: dir,
+		Exclude:  []string{"*.md", "site/testdata/**", "site/**/raw", ".git"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for name := range zipEntries(t, data) {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	want := []string{"bootstrap", "site/css/main.css", "site/img/logo.png", "site/index.html"}
+	if !slices.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestWithExcludes_RejectsInvalidPatterns(t *testing.T) {
+	t.Parallel()
+	for _, pattern := range []string{"", "[a-"} {
+		_, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+			glambdatest.Sandbox(),
+			glambda.WithExcludes(pattern),
+		)
+		if err == nil {
+			t.Errorf("pattern %q: want an error", pattern)
+		}
+	}
+}
+
+func TestLoadConfig_ExcludeIsRelativeToTheConfigFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, "templates/index.html", "templates/drafts/next.html")
+	handler, err := filepath.Abs("testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := "functions:\n  - name: site\n    handler: " + handler + "\n    include: [templates]\n    exclude: [templates/drafts]\n"
+	err = os.WriteFile(filepath.Join(dir, "glambda.yaml"), []byte(config), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := glambda.LoadConfig(filepath.Join(dir, "glambda.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := cfg.Functions[0].Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := glambda.NewLambda("site", handler, append([]glambda.DeployOptions{glambdatest.Sandbox()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := l.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := zipEntries(t, data)
+	if len(entries) != 2 || entries["templates/index.html"] == nil {
+		t.Errorf("want bootstrap and templates/index.html, got %v", entries)
+	}
+}
+
+func TestPackageWith_HonoursTheHandlersIgnoreFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	data, err := os.ReadFile("testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sums, err := os.ReadFile("go.sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignore := "# Sources of the generated images\n*.psd\n!keep.psd\ndrafts/\n/static/tmp\n"
+	for name, data := range map[string][]byte{
+		"main.go":         data,
+		"go.mod":          []byte("module handler\n\ngo 1.22\n\nrequire github.com/aws/aws-lambda-go v1.47.0\n"),
+		"go.sum":          sums,
+		".lambdaignore":   []byte(ignore),
+		"static/logo.png": []byte("png\n"),
+	} {
+		err = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, name), data, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFiles(t, dir,
+		"static/logo.psd",
+		"static/keep.psd",
+		"static/drafts/next.html",
+		"static/tmp/upload.bin",
+		"static/css/tmp/main.css",
+	)
+	data, err = glambda.PackageWith(filepath.Join(dir, "main.go"), glambda.BuildOptions{
+		Assets:   []string{"static"},
+		AssetDir: dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for name := range zipEntries(t, data) {
+		got = append(got, name)
+	}
+	slices.Sort(got)
+	want := []string{"bootstrap", "static/css/tmp/main.css", "static/keep.psd", "static/logo.png"}
+	if !slices.Equal(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestPackageWith_ReportsTheLineOfABadIgnorePattern(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, "static/logo.png")
+	err := os.WriteFile(filepath.Join(dir, ".lambdaignore"), []byte("*.psd\n[a-\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = glambda.PackageWith(dir, glambda.BuildOptions{
+		Assets:   []string{"static"},
+		AssetDir: dir,
+	})
+	if err == nil || !strings.Contains(err.Error(), ".lambdaignore:2:") {
+		t.Errorf("want an error naming line 2 of the ignore file, got %v", err)
+	}
+}
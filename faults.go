@@ -0,0 +1,39 @@
+package glambda
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInjectedFault is the error returned by operations made to fail with
+// [WithFaultInjection].
+var ErrInjectedFault = errors.New("injected fault")
+
+// FaultInjector is implemented by fake clients, such as those in the
+// glambdatest package, that are able to simulate an AWS operation failing.
+// InjectFault reports whether the client serves the operation and has
+// arranged for it to fail.
+type FaultInjector interface {
+	InjectFault(operation string, err error, times int) bool
+}
+
+// WithFaultInjection is a deploy option that makes the named AWS operation
+// (e.g. "CreateRole" or "PublishVersion") fail transiently for its first failN
+// calls, so that glambda's retry and error handling can be exercised without
+// depending on real AWS misbehaving.
+//
+// It only works with injected clients that implement [FaultInjector], so it
+// must be given after an option such as glambdatest.Sandbox(). It refuses to
+// run against real AWS clients.
+func WithFaultInjection(op string, failN int) DeployOptions {
+	return func(l *Lambda) error {
+		err := fmt.Errorf("%w: %s", ErrInjectedFault, op)
+		for _, c := range []any{l.lambdaClient, l.iamClient, l.stsClient, l.apiClient, l.s3Client, l.metricsClient, l.sqsClient} {
+			injector, ok := c.(FaultInjector)
+			if ok && injector.InjectFault(op, err, failN) {
+				return nil
+			}
+		}
+		return fmt.Errorf("unable to inject fault into %s, fault injection requires sandbox or test clients that serve the operation", op)
+	}
+}
@@ -0,0 +1,90 @@
+package glambda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// FunctionNotFoundError is returned when a command needs a deployed function
+// that doesn't exist.
+type FunctionNotFoundError struct {
+	Name string
+}
+
+func (e *FunctionNotFoundError) Error() string {
+	return fmt.Sprintf("lambda function %s doesn't exist, deploy it first with glambda deploy", e.Name)
+}
+
+// Freshness compares the code of a deployed function with a package built
+// from the local handler.
+type Freshness struct {
+	// DeployedSHA256 is the base64 encoded SHA-256 of the deployed package,
+	// as Lambda reports it.
+	DeployedSHA256 string
+	// LocalSHA256 is the same for the local package. It is empty when there
+	// was no local handler to compare against.
+	LocalSHA256 string
+	// LastModified is when the function was last updated.
+	LastModified string
+}
+
+// Stale reports whether the local handler builds to different code than is
+// deployed.
+func (f Freshness) Stale() bool {
+	return f.LocalSHA256 != "" && f.LocalSHA256 != f.DeployedSHA256
+}
+
+// CodeSHA256 hashes a deployment package the way Lambda does for the
+// CodeSha256 of a function.
+func CodeSHA256(pkg []byte) string {
+	sum := sha256.Sum256(pkg)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// CheckFreshness fetches the deployed function and compares its code with
+// pkg, which may be nil to only check that the function exists. A missing
+// function is a [*FunctionNotFoundError].
+func CheckFreshness(ctx context.Context, c LambdaClient, name string, pkg []byte) (Freshness, error) {
+	resp, err := c.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return Freshness{}, &FunctionNotFoundError{Name: name}
+	}
+	if err != nil {
+		return Freshness{}, err
+	}
+	var f Freshness
+	if resp.Configuration != nil {
+		f.DeployedSHA256 = aws.ToString(resp.Configuration.CodeSha256)
+		f.LastModified = aws.ToString(resp.Configuration.LastModified)
+	}
+	if pkg != nil {
+		f.LocalSHA256 = CodeSHA256(pkg)
+	}
+	return f, nil
+}
+
+// Freshness is a method on the [Lambda] struct that checks the function is
+// deployed and, when it has a handler path or bootstrap, whether the handler
+// builds to the deployed code. Builds are reproducible, so unchanged source
+// gives an identical package.
+func (l Lambda) Freshness(ctx context.Context) (Freshness, error) {
+	var pkg []byte
+	if l.HandlerPath != "" || l.Bootstrap != "" {
+		var err error
+		pkg, err = l.packageHandler()
+		if err != nil {
+			return Freshness{}, err
+		}
+	}
+	return CheckFreshness(ctx, l.lambdaAPI(), l.Name, pkg)
+}
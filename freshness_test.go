@@ -0,0 +1,62 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestCheckFreshness_ReportsLocalCodeDiffersFromDeployed(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			CodeSha256:   aws.String(glambda.CodeSHA256([]byte("deployed"))),
+			LastModified: aws.String("2024-01-01T00:00:00.000+0000"),
+		},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	f, err := glambda.CheckFreshness(context.Background(), client, "fn", []byte("local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Stale() {
+		t.Errorf("expected stale deployment, got %+v", f)
+	}
+}
+
+func TestCheckFreshness_MatchingOrMissingLocalCodeIsNotStale(t *testing.T) {
+	t.Parallel()
+	for _, local := range [][]byte{[]byte("deployed"), nil} {
+		recorder := glambdatest.NewRecorder()
+		recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+			Configuration: &types.FunctionConfiguration{
+				CodeSha256: aws.String(glambda.CodeSHA256([]byte("deployed"))),
+			},
+		})
+		client := glambdatest.DummyLambdaClient{Recorder: recorder}
+		f, err := glambda.CheckFreshness(context.Background(), client, "fn", local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Stale() {
+			t.Errorf("local %q: expected fresh deployment, got %+v", local, f)
+		}
+	}
+}
+
+func TestCheckFreshness_ErrorsWhenFunctionDoesNotExist(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyLambdaClient{FuncExists: false}
+	_, err := glambda.CheckFreshness(context.Background(), client, "missing", nil)
+	var notFound *glambda.FunctionNotFoundError
+	if !errors.As(err, &notFound) || notFound.Name != "missing" {
+		t.Errorf("expected FunctionNotFoundError, got %v", err)
+	}
+}
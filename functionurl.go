@@ -0,0 +1,168 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// FunctionURLStatementID is the resource policy statement that allows anyone
+// to invoke a function through a Function URL with an auth type of NONE.
+const FunctionURLStatementID = "glambda_function_url_public"
+
+// FunctionURL describes the HTTPS endpoint that Lambda can expose directly
+// for a function, without API Gateway.
+type FunctionURL struct {
+	AuthType types.FunctionUrlAuthType
+	Cors     *types.Cors
+	// InvokeMode is BUFFERED, the default, or RESPONSE_STREAM for handlers
+	// that stream their response.
+	InvokeMode types.InvokeMode
+}
+
+// WithFunctionURL is a deploy option that gives the lambda function a Function
+// URL. authType is either "NONE", for a public endpoint, or "AWS_IAM", to
+// require SigV4 signed requests. If any allowOrigins are given, CORS is enabled
+// for those origins and all methods.
+func WithFunctionURL(authType string, allowOrigins ...string) DeployOptions {
+	return func(l *Lambda) error {
+		auth := types.FunctionUrlAuthType(authType)
+		switch auth {
+		case types.FunctionUrlAuthTypeNone, types.FunctionUrlAuthTypeAwsIam:
+		default:
+			return fmt.Errorf("invalid function URL auth type %q, expected NONE or AWS_IAM", authType)
+		}
+		url := &FunctionURL{AuthType: auth}
+		if len(allowOrigins) > 0 {
+			url.Cors = &types.Cors{
+				AllowOrigins: allowOrigins,
+				AllowMethods: []string{"*"},
+			}
+		}
+		l.FunctionURL = url
+		return nil
+	}
+}
+
+// WithInvokeMode is a deploy option that sets how the function's Function URL,
+// given by [WithFunctionURL] before or after it, returns responses:
+// "BUFFERED", the default, or "RESPONSE_STREAM", for handlers that stream
+// responses of up to 20MB.
+func WithInvokeMode(mode string) DeployOptions {
+	return func(l *Lambda) error {
+		invokeMode := types.InvokeMode(mode)
+		switch invokeMode {
+		case types.InvokeModeBuffered, types.InvokeModeResponseStream:
+		default:
+			return fmt.Errorf("invalid function URL invoke mode %q, expected BUFFERED or RESPONSE_STREAM", mode)
+		}
+		l.invokeMode = invokeMode
+		return nil
+	}
+}
+
+// applyInvokeMode sets the invoke mode given by [WithInvokeMode] on the
+// Function URL, which may have been given after it.
+func (l *Lambda) applyInvokeMode() error {
+	if l.invokeMode == "" {
+		return nil
+	}
+	if l.FunctionURL == nil {
+		return errors.New("an invoke mode needs a function URL, see WithFunctionURL")
+	}
+	l.FunctionURL.InvokeMode = l.invokeMode
+	return nil
+}
+
+// invokeMode is the URL's invoke mode, defaulting to BUFFERED so that
+// deploying without one switches a streaming URL back.
+func (u FunctionURL) invokeMode() types.InvokeMode {
+	if u.InvokeMode == "" {
+		return types.InvokeModeBuffered
+	}
+	return u.InvokeMode
+}
+
+// FunctionURLAction is an [Action] that creates the Function URL of a lambda
+// function, or brings an existing one in line with the [FunctionURL].
+type FunctionURLAction struct {
+	client LambdaClient
+	Name   string
+	URL    FunctionURL
+}
+
+// NewFunctionURLAction is a constructor function that creates a new [FunctionURLAction].
+func NewFunctionURLAction(client LambdaClient, name string, url FunctionURL) FunctionURLAction {
+	return FunctionURLAction{client: client, Name: name, URL: url}
+}
+
+// Client returns the required client type. In this case [LambdaClient].
+func (a FunctionURLAction) Client() LambdaClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface. A public (NONE) URL also
+// needs a resource policy statement allowing anyone to invoke it, which is
+// added once and left in place on later deploys.
+func (a FunctionURLAction) Do(ctx context.Context) error {
+	client := a.Client()
+	_, err := client.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String(a.Name),
+	})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		_, err = client.CreateFunctionUrlConfig(ctx, &lambda.CreateFunctionUrlConfigInput{
+			FunctionName: aws.String(a.Name),
+			AuthType:     a.URL.AuthType,
+			Cors:         a.URL.Cors,
+			InvokeMode:   a.URL.invokeMode(),
+		})
+	case err == nil:
+		_, err = client.UpdateFunctionUrlConfig(ctx, &lambda.UpdateFunctionUrlConfigInput{
+			FunctionName: aws.String(a.Name),
+			AuthType:     a.URL.AuthType,
+			Cors:         a.URL.Cors,
+			InvokeMode:   a.URL.invokeMode(),
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if a.URL.AuthType != types.FunctionUrlAuthTypeNone {
+		return nil
+	}
+	_, err = client.AddPermission(ctx, &lambda.AddPermissionInput{
+		FunctionName:        aws.String(a.Name),
+		StatementId:         aws.String(FunctionURLStatementID),
+		Action:              aws.String("lambda:InvokeFunctionUrl"),
+		Principal:           aws.String("*"),
+		FunctionUrlAuthType: types.FunctionUrlAuthTypeNone,
+	})
+	var conflict *types.ResourceConflictException
+	if errors.As(err, &conflict) {
+		return nil
+	}
+	return err
+}
+
+// GetFunctionURL returns the HTTPS endpoint of a function's Function URL.
+func GetFunctionURL(ctx context.Context, c LambdaClient, name string) (string, error) {
+	resp, err := c.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(resp.FunctionUrl), nil
+}
+
+// URL is a method on the [Lambda] struct that returns the HTTPS endpoint of
+// the deployed function's Function URL.
+func (l Lambda) URL(ctx context.Context) (string, error) {
+	return GetFunctionURL(ctx, l.lambdaAPI(), l.Name)
+}
@@ -0,0 +1,112 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestWithFunctionURL_RejectsUnknownAuthType(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("fn", "", glambda.WithFunctionURL("PUBLIC"))
+	if err == nil {
+		t.Error("expected error for invalid auth type, got nil")
+	}
+}
+
+func TestFunctionURLAction_CreatesPublicURLWithPermission(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder, FunctionURLs: map[string]string{}}
+	url := glambda.FunctionURL{
+		AuthType: types.FunctionUrlAuthTypeNone,
+		Cors:     &types.Cors{AllowOrigins: []string{"https://example.com"}},
+	}
+	err := glambda.NewFunctionURLAction(client, "public", url).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetFunctionUrlConfig", "CreateFunctionUrlConfig", "AddPermission"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Fatal(cmp.Diff(want, recorder.Operations()))
+	}
+	create := recorder.Calls("CreateFunctionUrlConfig")[0].Input.(*lambda.CreateFunctionUrlConfigInput)
+	if create.Cors == nil || create.Cors.AllowOrigins[0] != "https://example.com" {
+		t.Errorf("expected CORS origins to be set, got %+v", create.Cors)
+	}
+	permission := recorder.Calls("AddPermission")[0].Input.(*lambda.AddPermissionInput)
+	if aws.ToString(permission.Action) != "lambda:InvokeFunctionUrl" || permission.FunctionUrlAuthType != types.FunctionUrlAuthTypeNone {
+		t.Errorf("expected public invoke permission, got %+v", permission)
+	}
+	got, err := glambda.GetFunctionURL(context.Background(), client, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "https://public.lambda-url.us-east-1.on.aws/" {
+		t.Errorf("unexpected URL %q", got)
+	}
+}
+
+func TestFunctionURLAction_UpdatesExistingIAMURL(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{
+		Recorder:     recorder,
+		FunctionURLs: map[string]string{"private": "https://private.lambda-url.us-east-1.on.aws/"},
+	}
+	err := glambda.NewFunctionURLAction(client, "private", glambda.FunctionURL{AuthType: types.FunctionUrlAuthTypeAwsIam}).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetFunctionUrlConfig", "UpdateFunctionUrlConfig"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Error(cmp.Diff(want, recorder.Operations()))
+	}
+}
+
+func TestWithInvokeMode_StreamsResponsesThroughTheFunctionURL(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "", glambda.WithFunctionURL("AWS_IAM"), glambda.WithInvokeMode("RESPONSE_STREAM"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder, FunctionURLs: map[string]string{}}
+	err = glambda.NewFunctionURLAction(client, "fn", *l.FunctionURL).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := recorder.Calls("CreateFunctionUrlConfig")[0].Input.(*lambda.CreateFunctionUrlConfigInput)
+	if create.InvokeMode != types.InvokeModeResponseStream {
+		t.Errorf("want invoke mode RESPONSE_STREAM, got %q", create.InvokeMode)
+	}
+}
+
+func TestWithInvokeMode_CanBeGivenBeforeTheFunctionURL(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithInvokeMode("RESPONSE_STREAM"), glambda.WithFunctionURL("NONE"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.FunctionURL.InvokeMode != types.InvokeModeResponseStream {
+		t.Errorf("want invoke mode RESPONSE_STREAM, got %q", l.FunctionURL.InvokeMode)
+	}
+}
+
+func TestWithInvokeMode_RejectsUnknownModesAndMissingURL(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("fn", "", glambda.WithFunctionURL("NONE"), glambda.WithInvokeMode("CHUNKED"))
+	if err == nil {
+		t.Error("expected error for invalid invoke mode, got nil")
+	}
+	_, err = glambda.NewLambda("fn", "", glambda.WithInvokeMode("RESPONSE_STREAM"))
+	if err == nil {
+		t.Error("expected error for an invoke mode without a function URL, got nil")
+	}
+}
@@ -4,14 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
@@ -20,12 +31,101 @@ import (
 // AWS Lambda API, or any of the concrete AWS artifacts, and should be thought
 // of as a higher level abstraction of convenience.
 type Lambda struct {
-	Name           string
-	HandlerPath    string
+	Name        string
+	HandlerPath string
+	// Bootstrap is a prebuilt executable deployed in place of building
+	// HandlerPath, see [WithBootstrap].
+	Bootstrap      string
 	ExecutionRole  ExecutionRole
 	AWSAccountID   string
 	ResourcePolicy ResourcePolicy
-	cfg            aws.Config
+	Environment    map[string]string
+	KMSKeyARN      string
+	Tags           map[string]string
+	MemorySize     int
+	Timeout        time.Duration
+	Architecture   types.Architecture
+	// MaxPayloadKB is the largest request the function expects, checked
+	// against the limits of its triggers, see [WithMaxPayload].
+	MaxPayloadKB int
+	// TemplateData, when set, is used to render the handler source before it
+	// is built, see [WithTemplateData].
+	TemplateData    map[string]string
+	StrictTemplates bool
+	// Assets are added to the zip next to bootstrap, see [WithAssets].
+	Assets []string
+	// BuildFlags are passed to go build, see [WithBuildFlags].
+	BuildFlags []string
+	// CGO builds the handler with cgo, using the C compiler CC, see
+	// [WithCGO].
+	CGO             bool
+	CC              string
+	FunctionURL     *FunctionURL
+	HTTPAPI         *HTTPAPI
+	S3Triggers      []S3Trigger
+	EventSources    []EventSource
+	DeadLetterQueue string
+	// VPC connects the function to a VPC, see [WithVPCConfig].
+	VPC          *VPCConfig
+	Destinations *Destinations
+	Canary       *Canary
+	S3Upload     *S3Upload
+	// ReservedConcurrency and ProvisionedConcurrency are applied after
+	// publishing, see [WithReservedConcurrency] and [WithProvisionedConcurrency].
+	ReservedConcurrency    *int
+	ProvisionedConcurrency *ProvisionedConcurrency
+	// Aliases are pointed at each published version, with settings of their
+	// own, see [WithAlias].
+	Aliases []AliasConfig
+
+	cfg           aws.Config
+	lambdaClient  LambdaClient
+	iamClient     IAMClient
+	stsClient     STSClient
+	apiClient     APIGatewayClient
+	s3Client      S3Client
+	metricsClient CloudWatchClient
+	logsClient    CloudWatchLogsClient
+	dynamoClient  DynamoDBClient
+	sqsClient     SQSClient
+	naming        *NamingConvention
+	vulnCheck     bool
+	adopt         bool
+	// allowDowngrade deploys code older than the live code, see
+	// [WithAllowDowngrade].
+	allowDowngrade bool
+	// kmsGrants grants use of the keys encrypting the function's buckets and
+	// queues, see [WithKMSGrants].
+	kmsGrants bool
+	// fixQueue raises the visibility timeout of SQS triggers' queues, see
+	// [WithFixQueue].
+	fixQueue bool
+	// assetDir is the directory relative Assets are read from.
+	assetDir string
+	// sensitiveAssets allows .env files and .git directories among the
+	// assets, see [WithSensitiveAssets].
+	sensitiveAssets bool
+	// deleteUnmanagedRole deletes a role glambda didn't create along with
+	// the function, see [WithDeleteUnmanagedRole].
+	deleteUnmanagedRole bool
+	// assetExcludes are patterns for assets to leave out, see
+	// [WithExcludes].
+	assetExcludes []string
+	// invokeMode is applied to the FunctionURL once every option has been
+	// given, see [WithInvokeMode].
+	invokeMode types.InvokeMode
+	policyBundle  string
+	keepBuildDir  io.Writer
+	progress      Reporter
+
+	runtimeCalendar    RuntimeCalendar
+	runtimeWarningDays *int
+	credentials        aws.CredentialsProvider
+	deployID           string
+	keepLogs           bool
+	assumeRole         *assumeRole
+	smokeTest          SmokeTest
+	smokeTestPayload   []byte
 }
 
 // ResourcePolicy is a struct that represents the policy that will be attached
@@ -37,6 +137,7 @@ type ResourcePolicy struct {
 	SourceAccountCondition  *string
 	SourceArnCondition      *string
 	PrincipalOrgIdCondition *string
+	StatementID             string
 }
 
 // ExecutionRole is a struct that attempts to encapsulate all the information
@@ -49,55 +150,142 @@ type ExecutionRole struct {
 	AssumeRolePolicyDocument string
 	ManagedPolicies          []string
 	InLinePolicy             string
+	InlinePolicyName         string
+	// EventSourcePolicy grants access to the function's event sources. It is
+	// kept apart from InLinePolicy so that it never replaces the user's policy.
+	EventSourcePolicy string
+	// KMSPolicy grants decryption with the key given to [WithKMSKey].
+	KMSPolicy string
+	// KMSGrantsPolicy grants use of the keys encrypting the buckets and
+	// queues the function uses, see [WithKMSGrants].
+	KMSGrantsPolicy string
+	// VPCAccess attaches [VPCAccessPolicyARN], for a function given
+	// [WithVPCConfig].
+	VPCAccess bool
+	// DestinationPolicy grants sending to the function's dead-letter queue
+	// and asynchronous invocation destinations.
+	DestinationPolicy string
+	// Tags are applied to the role when glambda manages it, see [WithTags].
+	Tags map[string]string
+	// Function is the name of the function the role is for, recorded in the
+	// role's [FunctionTagKey] tag and description when it is created.
+	Function string
+	// Description is set on the role when it is created, see [WithDescription].
+	Description string
 }
 
 // NewLambda is a constructor function that creates a new Lambda struct. It
 // requires a friendly name for the lambda function to be created, and the path
 // to the handler code that will be executed when the lambda function is invoked.
-// It assumes the environment is configured with the necessary AWS credentials can
-// be found in the enviroment. It also assumes that a default AWS region is set.
-// Finally it assumes that the current AWS credentials can perform an
+// Any [DeployOptions] provided are applied before AWS configuration is resolved.
+//
+// Unless an AWS Config is provided with [WithAWSConfig], it assumes the environment
+// is configured with the necessary AWS credentials and that a default AWS region is
+// set. Credentials are found by the AWS SDK's default chain, which includes ECS
+// task roles, EKS IAM roles for service accounts and EKS Pod Identity, or given
+// with [WithCredentialsProvider]. Finally it assumes that the current AWS credentials can perform an
 // sts:GetCallerIdentity identity call in order to determine the AWS account ID.
-func NewLambda(name, handlerPath string) (*Lambda, error) {
-	awsConfig, err := config.LoadDefaultConfig(
-		context.Background(),
-		config.WithRetryer(customRetryer),
-	)
+// To deploy into another account, see [WithAssumeRole].
+// Every AWS call the lambda makes carries its [Lambda.DeployID].
+func NewLambda(name, handlerPath string, opts ...DeployOptions) (*Lambda, error) {
+	return NewLambdaWithContext(context.Background(), name, handlerPath, opts...)
+}
+
+// NewLambdaWithContext is [NewLambda], with a context that bounds loading
+// the AWS configuration and looking up the account ID, so that a caller can
+// cancel them or give them a deadline.
+func NewLambdaWithContext(ctx context.Context, name, handlerPath string, opts ...DeployOptions) (*Lambda, error) {
+	l, err := NewOfflineLambda(name, handlerPath, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if awsConfig.Region == "" {
+	if l.cfg.Region == "" && l.cfg.Credentials == nil {
+		awsConfig, err := config.LoadDefaultConfig(
+			ctx,
+			config.WithRetryer(customRetryer),
+		)
+		if err != nil {
+			return nil, err
+		}
+		l.cfg = awsConfig
+	}
+	if l.credentials != nil {
+		l.cfg.Credentials = aws.NewCredentialsCache(l.credentials)
+	}
+	if l.deployID == "" {
+		l.deployID = UUID()
+	}
+	l.traceRequests()
+	if l.assumeRole != nil {
+		l.cfg.Credentials = aws.NewCredentialsCache(l.assumeRole.provider(l.cfg, l.deployID))
+	}
+	if l.cfg.Region == "" {
 		return nil, fmt.Errorf("unable to determine AWS region. Try setting the AWS_DEFAULT_REGION environment variable")
 	}
-
-	accountID, err := AWSAccountID(sts.NewFromConfig(awsConfig))
+	err = ValidateRegion(l.cfg.Region)
 	if err != nil {
 		return nil, err
 	}
+	if l.AWSAccountID == "" {
+		stsClient := l.stsClient
+		if stsClient == nil {
+			stsClient = sts.NewFromConfig(l.cfg)
+		}
+		accountID, err := AWSAccountID(ctx, stsClient)
+		if err != nil {
+			return nil, l.assumeRole.explain(err)
+		}
+		l.AWSAccountID = accountID
+	}
+	l.ExecutionRole.RoleARN = "arn:aws:iam::" + l.AWSAccountID + ":role/" + l.ExecutionRole.RoleName
+	return l, nil
+}
+
+// NewOfflineLambda is [NewLambda] without resolving AWS configuration or the
+// account ID, so it needs no credentials. The result can be built with
+// [Lambda.Build] and its settings read, as package infra does, but it can't
+// be deployed and its ExecutionRole has no RoleARN.
+func NewOfflineLambda(name, handlerPath string, opts ...DeployOptions) (*Lambda, error) {
 	roleName := "glambda_exec_role_" + strings.ToLower(name)
-	roleARN := "arn:aws:iam::" + accountID + ":role/" + roleName
-	return &Lambda{
+	l := &Lambda{
 		Name:           name,
 		HandlerPath:    handlerPath,
 		ResourcePolicy: ResourcePolicy{},
 		ExecutionRole: ExecutionRole{
+			Function:                 name,
 			RoleName:                 roleName,
-			RoleARN:                  roleARN,
 			AssumeRolePolicyDocument: DefaultAssumeRolePolicy,
 			ManagedPolicies: []string{
 				"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
 			},
 		},
-		cfg:          awsConfig,
-		AWSAccountID: accountID,
-	}, nil
+	}
+	for _, opt := range opts {
+		err := opt(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := l.applyNamingConvention()
+	if err != nil {
+		return nil, err
+	}
+	err = l.checkAliases()
+	if err != nil {
+		return nil, err
+	}
+	err = l.applyInvokeMode()
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
 }
 
 // Actions are at a high level a way to organise a set of operations that need
 // to be performed with the AWS SDK and in which order. Operations might depend
 // on the result of a previous operation.
 type Action interface {
-	Do() error
+	Do(ctx context.Context) error
 }
 
 // LambdaActions are any set of operations that requires the AWS Lambda service.
@@ -114,15 +302,41 @@ type LambdaCreateAction struct {
 	client                LambdaClient
 	CreateLambdaCommand   *lambda.CreateFunctionInput
 	ResourcePolicyCommand *lambda.AddPermissionInput
+	// Upload stages the package in S3 first, when [WithS3Upload] was given.
+	Upload *PackageUpload
 }
 
 // NewLambdaCreateAction is a constructor function that creates a new [LambdaCreateAction].
+// The function is tagged with [ManagedTagKey] and any tags given to [WithTags],
+// and the Go modules compiled into the package are recorded as tags on it, see
+// [ModuleTags].
 func NewLambdaCreateAction(client LambdaClient, l Lambda, pkg []byte) LambdaCreateAction {
-	return LambdaCreateAction{
+	cmd := CreateLambdaCommand(l.Name, l.ExecutionRole.RoleARN, pkg)
+	cmd.Tags = functionTags(pkg, l.Tags)
+	if len(l.Environment) > 0 {
+		cmd.Environment = &types.Environment{Variables: l.Environment}
+	}
+	if l.KMSKeyARN != "" {
+		cmd.KMSKeyArn = aws.String(l.KMSKeyARN)
+	}
+	cmd.DeadLetterConfig = deadLetterConfig(l)
+	cmd.VpcConfig = vpcConfig(l)
+	cmd.MemorySize = memorySize(l)
+	cmd.Timeout = timeoutSeconds(l)
+	cmd.Architectures = []types.Architecture{l.architecture()}
+	action := LambdaCreateAction{
 		client:                client,
-		CreateLambdaCommand:   CreateLambdaCommand(l.Name, l.ExecutionRole.RoleARN, pkg),
+		CreateLambdaCommand:   cmd,
 		ResourcePolicyCommand: l.CreateLambdaResourcePolicy(),
 	}
+	if l.S3Upload != nil {
+		action.Upload = NewPackageUpload(l.s3API(), *l.S3Upload, l.Name, pkg)
+		cmd.Code = &types.FunctionCode{
+			S3Bucket: aws.String(action.Upload.Bucket),
+			S3Key:    aws.String(action.Upload.Key),
+		}
+	}
+	return action
 }
 
 // Client returns the required client type. In this case [LambdaClient].
@@ -132,16 +346,32 @@ func (a LambdaCreateAction) Client() LambdaClient {
 
 // Do is the implementation of the [Action] interface. It will create the lambda
 // function and attach the resource policy if it was provided, returning any error.
-func (a LambdaCreateAction) Do() error {
+func (a LambdaCreateAction) Do(ctx context.Context) error {
 	client := a.Client()
-	_, err := client.CreateFunction(context.Background(), a.CreateLambdaCommand)
+	if a.Upload != nil {
+		err := a.Upload.Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	pkg := a.CreateLambdaCommand.Code.ZipFile
+	if a.Upload != nil {
+		pkg = a.Upload.Package
+	}
+	err := retryUpload(ctx, func() error {
+		_, err := client.CreateFunction(ctx, a.CreateLambdaCommand)
+		return err
+	}, func() (bool, error) {
+		_, ok, err := codeLanded(ctx, client, aws.ToString(a.CreateLambdaCommand.FunctionName), CodeSHA256(pkg))
+		return ok, err
+	})
 	if err != nil {
 		return err
 	}
 	if a.ResourcePolicyCommand == nil {
 		return nil
 	}
-	_, err = client.AddPermission(context.Background(), a.ResourcePolicyCommand)
+	_, err = client.AddPermission(ctx, a.ResourcePolicyCommand)
 	return err
 }
 
@@ -150,15 +380,48 @@ type LambdaUpdateAction struct {
 	client                LambdaClient
 	UpdateLambdaCommand   *lambda.UpdateFunctionCodeInput
 	ResourcePolicyCommand *lambda.AddPermissionInput
+	// Tags are applied to the function, replacing any stale module tags and
+	// removing tags no longer given to [WithTags].
+	Tags map[string]string
+	// Upload stages the package in S3 first, when [WithS3Upload] was given.
+	Upload *PackageUpload
+	// UpdateConfigurationCommand is nil when the deploy doesn't change any of
+	// the function's configuration, leaving the existing configuration alone.
+	UpdateConfigurationCommand *lambda.UpdateFunctionConfigurationInput
+	// StatementPrefix starts the IDs of resource policy statements added by
+	// earlier deploys, which ResourcePolicyCommand replaces. It is set by
+	// [PrepareLambdaAction], and when empty no earlier statements are
+	// replaced.
+	StatementPrefix string
+	// Runtime is the function's current runtime, which updates keep.
+	Runtime types.Runtime
+	// CodeUnchanged is set when the deployed code is identical to the
+	// package, so that the upload and code update are skipped, and only tags,
+	// permissions and configuration are brought up to date.
+	CodeUnchanged bool
+	// FunctionARN is the ARN of the function, as it was before the update.
+	FunctionARN string
+	// Timeout is the function's current timeout, which updates keep unless
+	// [WithTimeout] is given.
+	Timeout time.Duration
 }
 
 // NewLambdaUpdateAction is a constructor function that creates a new [LambdaUpdateAction].
 func NewLambdaUpdateAction(client LambdaClient, l Lambda, pkg []byte) LambdaUpdateAction {
-	return LambdaUpdateAction{
-		client:                client,
-		UpdateLambdaCommand:   UpdateLambdaCommand(l.Name, pkg),
-		ResourcePolicyCommand: l.CreateLambdaResourcePolicy(),
+	action := LambdaUpdateAction{
+		client:                     client,
+		UpdateLambdaCommand:        updateLambdaCommand(l, pkg),
+		ResourcePolicyCommand:      l.CreateLambdaResourcePolicy(),
+		Tags:                       functionTags(pkg, l.Tags),
+		UpdateConfigurationCommand: UpdateConfigurationCommand(l),
 	}
+	if l.S3Upload != nil {
+		action.Upload = NewPackageUpload(l.s3API(), *l.S3Upload, l.Name, pkg)
+		action.UpdateLambdaCommand.ZipFile = nil
+		action.UpdateLambdaCommand.S3Bucket = aws.String(action.Upload.Bucket)
+		action.UpdateLambdaCommand.S3Key = aws.String(action.Upload.Key)
+	}
+	return action
 }
 
 // Client returns the required client type. In this case [LambdaClient].
@@ -170,9 +433,60 @@ func (a LambdaUpdateAction) Client() LambdaClient {
 // Updating a lambda function in this context will mean updating the packaged zip file
 // that contains the lambda function code. It may also optionally require updating the
 // resource policy attached to the lambda function, if one was provided.
-func (a LambdaUpdateAction) Do() error {
+func (a LambdaUpdateAction) Do(ctx context.Context) error {
 	client := a.Client()
-	_, err := client.UpdateFunctionCode(context.Background(), a.UpdateLambdaCommand)
+	functionARN := a.FunctionARN
+	if !a.CodeUnchanged {
+		if a.Upload != nil {
+			err := a.Upload.Do(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		pkg := a.UpdateLambdaCommand.ZipFile
+		if a.Upload != nil {
+			pkg = a.Upload.Package
+		}
+		err := retryUpload(ctx, func() error {
+			resp, err := client.UpdateFunctionCode(ctx, a.UpdateLambdaCommand)
+			if err == nil && resp.FunctionArn != nil {
+				functionARN = *resp.FunctionArn
+			}
+			return err
+		}, func() (bool, error) {
+			// The retry conflicted with the update an earlier attempt
+			// started, so the function's ARN comes from the function.
+			arn, ok, err := codeLanded(ctx, client, aws.ToString(a.UpdateLambdaCommand.FunctionName), CodeSHA256(pkg))
+			if ok && arn != "" {
+				functionARN = arn
+			}
+			return ok, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	if a.Tags != nil && functionARN != "" {
+		err = reconcileTags(ctx, client, functionARN, a.Tags)
+		if err != nil {
+			return err
+		}
+	}
+	err = replacePermission(ctx, client, aws.ToString(a.UpdateLambdaCommand.FunctionName), a.ResourcePolicyCommand, a.StatementPrefix)
+	if err != nil {
+		return err
+	}
+	if a.UpdateConfigurationCommand == nil {
+		return nil
+	}
+	// Lambda rejects a configuration change while the code update is still
+	// being applied.
+	err = WaitForUpdate(ctx, client, *a.UpdateLambdaCommand.FunctionName)
+	if err != nil {
+		return err
+	}
+	_, err = client.UpdateFunctionConfiguration(ctx, a.UpdateConfigurationCommand)
 	return err
 }
 
@@ -181,7 +495,7 @@ func (a LambdaUpdateAction) Do() error {
 // Lambda's execution role.
 type RoleAction interface {
 	Client() IAMClient
-	Do() error
+	Do(ctx context.Context) error
 }
 
 // NewRoleCreateOrUpdateAction is a constructor function that creates a new [RoleCreateOrUpdate].
@@ -203,10 +517,18 @@ func NewRoleCreateOrUpdateAction(client IAMClient) RoleCreateOrUpdate {
 // the users perspective, the goal is the same. They want to ensure that the role
 // exists and has the correct policies attached to it.
 type RoleCreateOrUpdate struct {
-	client          IAMClient
-	CreateRole      *iam.CreateRoleInput
+	client     IAMClient
+	CreateRole *iam.CreateRoleInput
+	// Owner describes the tool that manages the existing role, if any, see
+	// [ResourceOwner].
+	Owner           string
 	ManagedPolicies []iam.AttachRolePolicyInput
 	InlinePolicies  []iam.PutRolePolicyInput
+	// TagRole and UntagRole reconcile the tags of an existing role that
+	// glambda manages with those given to [WithTags]. They are nil when the
+	// tags are already up to date.
+	TagRole   *iam.TagRoleInput
+	UntagRole *iam.UntagRoleInput
 }
 
 // Client returns the required client type. In this case [IAMClient].
@@ -218,23 +540,35 @@ func (a RoleCreateOrUpdate) Client() IAMClient {
 // it was determined that it didn't exist at Action construction time (see [PrepareRoleAction]).
 // It will then execute the attach role policy and put role policy commands in that order
 // as provided at Action construction time.
-func (a RoleCreateOrUpdate) Do() error {
+func (a RoleCreateOrUpdate) Do(ctx context.Context) error {
 	var err error
 	client := a.Client()
 	if a.CreateRole != nil {
-		_, err := client.CreateRole(context.Background(), a.CreateRole)
+		_, err := client.CreateRole(ctx, a.CreateRole)
+		if err != nil {
+			return err
+		}
+	}
+	if a.UntagRole != nil {
+		_, err = client.UntagRole(ctx, a.UntagRole)
+		if err != nil {
+			return err
+		}
+	}
+	if a.TagRole != nil {
+		_, err = client.TagRole(ctx, a.TagRole)
 		if err != nil {
 			return err
 		}
 	}
 	for _, cmd := range a.ManagedPolicies {
-		_, err = client.AttachRolePolicy(context.Background(), &cmd)
+		_, err = client.AttachRolePolicy(ctx, &cmd)
 		if err != nil {
 			return err
 		}
 	}
 	for _, cmd := range a.InlinePolicies {
-		_, err = client.PutRolePolicy(context.Background(), &cmd)
+		_, err = client.PutRolePolicy(ctx, &cmd)
 		if err != nil {
 			return err
 		}
@@ -250,7 +584,7 @@ func (a RoleCreateOrUpdate) Do() error {
 // This function does make live API calls to AWS IAM to determine if the role already exists.
 // If not, it will create a new [CreateRoleCommand] to be executed by the [RoleCreateOrUpdate].
 // The [PutRolePolicyCommand] and [AttachManagedPolicyCommand] created here for deferred execution.
-func PrepareRoleAction(role ExecutionRole, iamClient IAMClient) (RoleAction, error) {
+func PrepareRoleAction(ctx context.Context, role ExecutionRole, iamClient IAMClient) (RoleAction, error) {
 	action := RoleCreateOrUpdate{
 		client:         iamClient,
 		InlinePolicies: []iam.PutRolePolicyInput{},
@@ -261,7 +595,7 @@ func PrepareRoleAction(role ExecutionRole, iamClient IAMClient) (RoleAction, err
 			},
 		},
 	}
-	_, err := iamClient.GetRole(context.Background(), &iam.GetRoleInput{
+	existing, err := iamClient.GetRole(ctx, &iam.GetRoleInput{
 		RoleName: aws.String(role.RoleName),
 	})
 	if err != nil {
@@ -270,10 +604,27 @@ func PrepareRoleAction(role ExecutionRole, iamClient IAMClient) (RoleAction, err
 			return nil, err
 		}
 		action.CreateRole = CreateRoleCommand(role.RoleName, role.AssumeRolePolicyDocument)
+		action.CreateRole.Tags = append(action.CreateRole.Tags, iamTags(withTagRecord(nil, role.Tags))...)
+		if role.Function != "" {
+			action.CreateRole.Tags = append(action.CreateRole.Tags, iTypes.Tag{Key: aws.String(FunctionTagKey), Value: aws.String(role.Function)})
+		}
+		if description := role.description(time.Now()); description != "" {
+			action.CreateRole.Description = aws.String(description)
+		}
+	} else if existing.Role != nil {
+		tags := roleTags(existing.Role.Tags)
+		action.Owner = ResourceOwner(tags)
+		// A role glambda didn't create may be shared, so its tags are left alone.
+		if IsManagedRole(existing.Role) {
+			action.TagRole, action.UntagRole = roleTagChanges(role.RoleName, tags, role.Tags)
+		}
 	}
 	for _, policy := range role.ManagedPolicies {
 		action.ManagedPolicies = append(action.ManagedPolicies, AttachManagedPolicyCommand(role.RoleName, policy))
 	}
+	if role.VPCAccess && !slices.Contains(role.ManagedPolicies, VPCAccessPolicyARN) {
+		action.ManagedPolicies = append(action.ManagedPolicies, AttachManagedPolicyCommand(role.RoleName, VPCAccessPolicyARN))
+	}
 	action.InlinePolicies = PutRolePolicyCommand(role)
 	return action, nil
 }
@@ -281,22 +632,64 @@ func PrepareRoleAction(role ExecutionRole, iamClient IAMClient) (RoleAction, err
 // PrepareLambdaAction is a function that creates a new [LambdaAction] struct.
 // It will create the deployment package, and then determine if the lambda function
 // needs to be created. It will branch out into either a [LambdaCreateAction] or
-// a [LambdaUpdateAction] depending on the current state in AWS.
-func PrepareLambdaAction(l Lambda, c LambdaClient) (LambdaAction, error) {
-	pkg, err := Package(l.HandlerPath)
+// a [LambdaUpdateAction] depending on the current state in AWS. If [WithVulnCheck]
+// was given, the handler is checked for reachable vulnerabilities first. The
+// function is tagged with the commit its code was built from, and an update
+// to code built from an older commit fails with a [DowngradeError].
+func PrepareLambdaAction(ctx context.Context, l Lambda, c LambdaClient) (LambdaAction, error) {
+	pkg, err := l.Build()
 	if err != nil {
 		return nil, err
 	}
-	exists, err := lambdaExists(c, l.Name)
+	source := l.sourceTags(time.Now())
+	fn, err := deployedFunction(ctx, c, l.Name)
 	if err != nil {
 		return nil, err
 	}
 
 	var action LambdaAction
-	if exists {
-		action = NewLambdaUpdateAction(c, l, pkg)
+	if fn != nil {
+		owner := ResourceOwner(fn.Tags)
+		switch {
+		case owner != "" && !l.adopt:
+			return nil, &OwnedResourceError{Kind: "function", Name: l.Name, Owner: owner, Override: "--adopt"}
+		case owner != "":
+			l.report("adopt", "deploying over function %s, which is managed by %s", l.Name, owner)
+		case !IsManagedFunction(fn) && !l.adopt:
+			return nil, &UnmanagedFunctionError{Name: l.Name}
+		case !IsManagedFunction(fn):
+			l.report("adopt", "adopting function %s, which glambda didn't deploy", l.Name)
+		}
+		update := NewLambdaUpdateAction(c, l, pkg)
+		update.StatementPrefix, err = l.statementPrefix()
+		if err != nil {
+			return nil, err
+		}
+		if fn.Configuration != nil {
+			update.Runtime = fn.Configuration.Runtime
+			update.FunctionARN = aws.ToString(fn.Configuration.FunctionArn)
+			update.Timeout = time.Duration(aws.ToInt32(fn.Configuration.Timeout)) * time.Second
+			update.CodeUnchanged = aws.ToString(fn.Configuration.CodeSha256) == CodeSHA256(pkg)
+		}
+		if update.CodeUnchanged {
+			// The live code is still the code these tags describe.
+			for _, k := range []string{CommitTagKey, BuiltTagKey} {
+				if v, ok := fn.Tags[k]; ok {
+					update.Tags[k] = v
+				}
+			}
+		} else {
+			err = l.checkDowngrade(source[CommitTagKey], fn.Tags)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(update.Tags, source)
+		}
+		action = update
 	} else {
-		action = NewLambdaCreateAction(c, l, pkg)
+		create := NewLambdaCreateAction(c, l, pkg)
+		maps.Copy(create.CreateLambdaCommand.Tags, source)
+		action = create
 	}
 	return action, nil
 }
@@ -318,6 +711,85 @@ func CreateLambdaCommand(name, roleARN string, pkg []byte) *lambda.CreateFunctio
 	}
 }
 
+// Build is a method on the [Lambda] struct that packages the handler exactly
+// as a deploy would, for its architecture and with its template data. The
+// handler is checked with [Validate] first, unless it is a template, and if
+// [WithVulnCheck] was given, for reachable vulnerabilities too. A
+// [WithBootstrap] executable is packaged as it is.
+func (l Lambda) Build() ([]byte, error) {
+	if l.Bootstrap == "" && l.TemplateData == nil {
+		err := validate(l.HandlerPath, l.CGO)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.vulnCheck && l.Bootstrap == "" {
+		findings, err := VulnCheck(l.HandlerPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(findings) > 0 {
+			return nil, &VulnerabilityError{Findings: findings}
+		}
+	}
+	return l.packageHandler()
+}
+
+// updateLambdaCommand switches the function to the architecture the package
+// was built for along with the code.
+func updateLambdaCommand(l Lambda, pkg []byte) *lambda.UpdateFunctionCodeInput {
+	cmd := UpdateLambdaCommand(l.Name, pkg)
+	cmd.Architectures = []types.Architecture{l.architecture()}
+	return cmd
+}
+
+// architecture defaults to arm64, as Graviton is cheaper for most workloads.
+func (l Lambda) architecture() types.Architecture {
+	if l.Architecture == "" {
+		return types.ArchitectureArm64
+	}
+	return l.Architecture
+}
+
+// UpdateConfigurationCommand is a paperwork reducer that translates the
+// configuration of a [Lambda] into the smithy autogenerated AWS Lambda SDKv2
+// format of [lambda.UpdateFunctionConfigurationInput]. It returns nil if no
+// configuration was set, so that deploys without configuration options leave
+// the existing configuration of a function untouched.
+func UpdateConfigurationCommand(l Lambda) *lambda.UpdateFunctionConfigurationInput {
+	if l.Environment == nil && l.MemorySize == 0 && l.Timeout == 0 && l.KMSKeyARN == "" && l.DeadLetterQueue == "" && l.VPC == nil {
+		return nil
+	}
+	cmd := &lambda.UpdateFunctionConfigurationInput{
+		FunctionName:     aws.String(l.Name),
+		MemorySize:       memorySize(l),
+		Timeout:          timeoutSeconds(l),
+		DeadLetterConfig: deadLetterConfig(l),
+		VpcConfig:        vpcConfig(l),
+	}
+	if l.Environment != nil {
+		cmd.Environment = &types.Environment{Variables: l.Environment}
+	}
+	if l.KMSKeyARN != "" {
+		cmd.KMSKeyArn = aws.String(l.KMSKeyARN)
+	}
+	return cmd
+}
+
+func memorySize(l Lambda) *int32 {
+	if l.MemorySize == 0 {
+		return nil
+	}
+	return aws.Int32(int32(l.MemorySize))
+}
+
+func timeoutSeconds(l Lambda) *int32 {
+	if l.Timeout == 0 {
+		return nil
+	}
+	return aws.Int32(int32(l.Timeout / time.Second))
+}
+
 // UpdateLambdaCommand is a paperwork reducer that translates parameters into
 // the smithy autogenerated AWS Lambda SDKv2 format of [lambda.UpdateFunctionCodeInput]
 func UpdateLambdaCommand(name string, pkg []byte) *lambda.UpdateFunctionCodeInput {
@@ -377,9 +849,114 @@ func WithResourcePolicy(policy string) DeployOptions {
 	}
 }
 
+// WithEnvironment is a deploy option that sets the environment variables of
+// the lambda function. On update, the given variables replace the function's
+// existing environment entirely. Keys must start with a letter, contain only
+// letters, digits and underscores, and not be one of the variables reserved by
+// the Lambda runtime.
+func WithEnvironment(env map[string]string) DeployOptions {
+	return func(l *Lambda) error {
+		err := ValidateEnvironment(env)
+		if err != nil {
+			return err
+		}
+		l.Environment = maps.Clone(env)
+		if l.Environment == nil {
+			l.Environment = map[string]string{}
+		}
+		return nil
+	}
+}
+
+// ValidateEnvironment checks that every key of env may be set on a lambda
+// function, see [WithEnvironment].
+func ValidateEnvironment(env map[string]string) error {
+	for _, k := range sortedKeys(env) {
+		if !environmentKeyRegex.MatchString(k) {
+			return fmt.Errorf("invalid environment variable name %q", k)
+		}
+		if slices.Contains(reservedEnvironmentKeys, k) {
+			return fmt.Errorf("environment variable %s is reserved by the Lambda runtime", k)
+		}
+	}
+	return nil
+}
+
+// WithArchitecture is a deploy option that sets the instruction set the lambda
+// function runs on, either "arm64" (the default) or "x86_64". The handler is
+// built for the matching GOARCH.
+func WithArchitecture(arch string) DeployOptions {
+	return func(l *Lambda) error {
+		_, err := GOARCH(types.Architecture(arch))
+		if err != nil {
+			return err
+		}
+		l.Architecture = types.Architecture(arch)
+		return nil
+	}
+}
+
+// WithTemplateData is a deploy option that renders the handler's source files
+// as Go templates with the given data before building them. The source files
+// on disk are left untouched. In strict mode, a template that refers to a key
+// missing from data fails the deploy. See [RenderHandlerTemplate].
+func WithTemplateData(data map[string]string, strict bool) DeployOptions {
+	return func(l *Lambda) error {
+		l.TemplateData = maps.Clone(data)
+		if l.TemplateData == nil {
+			l.TemplateData = map[string]string{}
+		}
+		l.StrictTemplates = strict
+		return nil
+	}
+}
+
+// WithMemory is a deploy option that sets the memory, in MB, available to the
+// lambda function. Lambda allows between 128 and 10240 MB, and allocates CPU in
+// proportion to memory.
+func WithMemory(mb int) DeployOptions {
+	return func(l *Lambda) error {
+		if mb < 128 || mb > 10240 {
+			return fmt.Errorf("memory must be between 128 and 10240 MB, got %d", mb)
+		}
+		l.MemorySize = mb
+		return nil
+	}
+}
+
+// WithTimeout is a deploy option that sets how long the lambda function may
+// run for each invocation. Lambda allows whole seconds, between 1 second and 15
+// minutes.
+func WithTimeout(d time.Duration) DeployOptions {
+	return func(l *Lambda) error {
+		if d < time.Second || d > 15*time.Minute {
+			return fmt.Errorf("timeout must be between 1s and 15m, got %s", d)
+		}
+		if d%time.Second != 0 {
+			return fmt.Errorf("timeout must be a whole number of seconds, got %s", d)
+		}
+		l.Timeout = d
+		return nil
+	}
+}
+
+var environmentKeyRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// reservedEnvironmentKeys are set by the Lambda runtime and can't be
+// overridden, see https://docs.aws.amazon.com/lambda/latest/dg/configuration-envvars.html
+var reservedEnvironmentKeys = []string{
+	"_HANDLER", "_X_AMZN_TRACE_ID", "AWS_DEFAULT_REGION", "AWS_REGION",
+	"AWS_EXECUTION_ENV", "AWS_LAMBDA_FUNCTION_NAME", "AWS_LAMBDA_FUNCTION_MEMORY_SIZE",
+	"AWS_LAMBDA_FUNCTION_VERSION", "AWS_LAMBDA_INITIALIZATION_TYPE", "AWS_LAMBDA_LOG_GROUP_NAME",
+	"AWS_LAMBDA_LOG_STREAM_NAME", "AWS_ACCESS_KEY", "AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_LAMBDA_RUNTIME_API",
+	"LAMBDA_TASK_ROOT", "LAMBDA_RUNTIME_DIR",
+}
+
 // WithAWSConfig is a deploy option that allows the user to provide a custom
 // AWS Config to the [Lambda] struct. This is useful when you need more fine grained
-// control over the AWS SDK configuration.
+// control over the AWS SDK configuration. When passed to [NewLambda], the default
+// configuration is not loaded from the environment at all.
 func WithAWSConfig(cfg aws.Config) DeployOptions {
 	return func(l *Lambda) error {
 		l.cfg = cfg
@@ -387,118 +964,318 @@ func WithAWSConfig(cfg aws.Config) DeployOptions {
 	}
 }
 
-// Deploy is a method on the [Lambda] struct that will attempt to deploy the lambda
-// function to AWS. It will attempt to prepare, then deploy the execution role, and
-// if successful will repeat the process for the lambda function itself.
-func (l Lambda) Deploy() error {
+// WithLambdaClient is a deploy option that replaces the AWS Lambda client that
+// would otherwise be created from the AWS Config. This is useful for testing, or
+// for wrapping the client with additional behaviour.
+func WithLambdaClient(c LambdaClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.lambdaClient = c
+		return nil
+	}
+}
+
+// WithIAMClient is a deploy option that replaces the AWS IAM client that would
+// otherwise be created from the AWS Config.
+func WithIAMClient(c IAMClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.iamClient = c
+		return nil
+	}
+}
+
+// WithSTSClient is a deploy option that replaces the AWS STS client used to
+// determine the AWS account ID when the [Lambda] is constructed.
+func WithSTSClient(c STSClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.stsClient = c
+		return nil
+	}
+}
+
+// WithAPIGatewayClient is a deploy option that replaces the AWS API Gateway v2
+// client used to create an HTTP API, see [WithHTTPAPI].
+func WithAPIGatewayClient(c APIGatewayClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.apiClient = c
+		return nil
+	}
+}
+
+// WithS3Client is a deploy option that replaces the AWS S3 client used to
+// configure bucket notifications, see [WithS3Trigger].
+func WithS3Client(c S3Client) DeployOptions {
+	return func(l *Lambda) error {
+		l.s3Client = c
+		return nil
+	}
+}
+
+// WithKeepBuildDir is a deploy option that leaves the handler's build
+// workspace in place for debugging, writing its path and the go build command
+// used to w. See [BuildOptions].
+func WithKeepBuildDir(w io.Writer) DeployOptions {
+	return func(l *Lambda) error {
+		l.keepBuildDir = w
+		return nil
+	}
+}
+
+// WithCloudWatchClient is a deploy option that replaces the AWS CloudWatch
+// client used to watch a canary's error metrics, see [WithCanary].
+func WithCloudWatchClient(c CloudWatchClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.metricsClient = c
+		return nil
+	}
+}
+
+// WithCloudWatchLogsClient is a deploy option that replaces the AWS
+// CloudWatch Logs client used to query and tail a function's logs.
+func WithCloudWatchLogsClient(c CloudWatchLogsClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.logsClient = c
+		return nil
+	}
+}
+
+// WithDynamoDBClient is a deploy option that replaces the AWS DynamoDB client
+// used by an audit log kept in a table, see [OpenAuditLog].
+func WithDynamoDBClient(c DynamoDBClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.dynamoClient = c
+		return nil
+	}
+}
+
+// WithSQSClient is a deploy option that replaces the AWS SQS client used to
+// read the settings of queues a function uses.
+func WithSQSClient(c SQSClient) DeployOptions {
+	return func(l *Lambda) error {
+		l.sqsClient = c
+		return nil
+	}
+}
+
+func (l Lambda) lambdaAPI() LambdaClient {
+	if l.lambdaClient != nil {
+		return l.lambdaClient
+	}
 	l.cfg.Retryer = customRetryer
-	iamClient := iam.NewFromConfig(l.cfg)
-	roleAction, err := PrepareRoleAction(l.ExecutionRole, iamClient)
-	if err != nil {
-		return err
+	return lambda.NewFromConfig(l.cfg)
+}
+
+func (l Lambda) iamAPI() IAMClient {
+	if l.iamClient != nil {
+		return l.iamClient
 	}
-	err = roleAction.Do()
-	if err != nil {
-		return err
+	l.cfg.Retryer = customRetryer
+	return iam.NewFromConfig(l.cfg)
+}
+
+func (l Lambda) s3API() S3Client {
+	if l.s3Client != nil {
+		return l.s3Client
 	}
-	lambdaClient := lambda.NewFromConfig(l.cfg)
-	action, err := PrepareLambdaAction(l, lambdaClient)
-	if err != nil {
-		return err
+	l.cfg.Retryer = customRetryer
+	return s3.NewFromConfig(l.cfg)
+}
+
+func (l Lambda) cloudWatchAPI() CloudWatchClient {
+	if l.metricsClient != nil {
+		return l.metricsClient
 	}
-	return action.Do()
+	return cloudwatch.NewFromConfig(l.cfg)
 }
 
-// Test is a method on the [Lambda] struct that will attempt to invoke the newly
-// created lambda function in a dry run mode. This is useful for testing the lambda
-// function after deployment. As per AWS documentation, the dry run mode should not
-// execute the lambda function, but will rather 'validate parameter values and verify that the user or role has permission to invoke the function'.
-func (l Lambda) Test() error {
-	lambdaClient := lambda.NewFromConfig(l.cfg)
-	version, err := WaitForConsistency(lambdaClient, l.Name)
-	if err != nil {
-		return err
+func (l Lambda) cloudWatchLogsAPI() CloudWatchLogsClient {
+	if l.logsClient != nil {
+		return l.logsClient
 	}
-	_, err = lambdaClient.Invoke(context.Background(), &lambda.InvokeInput{
-		FunctionName:   aws.String(l.Name),
-		Qualifier:      aws.String(version),
-		InvocationType: types.InvocationTypeDryRun,
-	})
-	return err
+	return cloudwatchlogs.NewFromConfig(l.cfg)
 }
 
-// Deploy is a convenience function that will handle the paperwork that would
-// otherwise fall to the user to manage. It will create a new [Lambda] struct
-// and attempt to deploy it to AWS. It will also test the lambda function after
-// deployment. It is a high level abstraction that should represent the majority
-// of use cases for this library.
-func Deploy(name, source string, opts ...DeployOptions) error {
-	l, err := NewLambda(name, source)
+func (l Lambda) dynamoDBAPI() DynamoDBClient {
+	if l.dynamoClient != nil {
+		return l.dynamoClient
+	}
+	return dynamodb.NewFromConfig(l.cfg)
+}
+
+func (l Lambda) sqsAPI() SQSClient {
+	if l.sqsClient != nil {
+		return l.sqsClient
+	}
+	return sqs.NewFromConfig(l.cfg)
+}
+
+func (l Lambda) functionARN() string {
+	return fmt.Sprintf("arn:aws:lambda:%s:%s:function:%s", l.cfg.Region, l.AWSAccountID, l.Name)
+}
+
+func (l Lambda) apiGatewayAPI() APIGatewayClient {
+	if l.apiClient != nil {
+		return l.apiClient
+	}
+	l.cfg.Retryer = customRetryer
+	return apigatewayv2.NewFromConfig(l.cfg)
+}
+
+// Deploy is a method on the [Lambda] struct that will attempt to deploy the lambda
+// function to AWS. Both the execution role and the lambda function actions are
+// prepared before either is executed, so that a handler that fails to build
+// doesn't leave a freshly created role behind. The role is then deployed, and if
+// successful, the lambda function itself.
+// To see what a deploy would do without doing it, use [Lambda.Plan].
+func (l Lambda) Deploy(ctx context.Context) error {
+	l.reportBuild()
+	roleAction, action, err := l.prepare(ctx)
 	if err != nil {
 		return err
 	}
-	for _, opt := range opts {
-		err := opt(l)
+	if l.policyBundle != "" {
+		l.report("policy", "checking the plan against %s", l.policyBundle)
+		err = l.checkPolicies(ctx, roleAction, action)
 		if err != nil {
 			return err
 		}
 	}
-	err = l.Deploy()
-	if err != nil {
-		return err
+	return l.apply(ctx, roleAction, action)
+}
+
+func (l Lambda) reportBuild() {
+	l.report("deploy", "deploy ID %s, in the user agent of each AWS call", l.deployID)
+	if l.Bootstrap != "" {
+		l.report("build", "packaging prebuilt %s", l.Bootstrap)
+	} else {
+		l.report("build", "building %s", l.HandlerPath)
 	}
-	return l.Test()
 }
 
-// Delete is a convenience function that will delete a lambda function and the
-// associated IAM Role. Deletion is actually more complex than it might seem at
-// first glance and requires a specific unwinding of various resources.
-//
-// It should be noted that it is A) a destructive operation and B) allows for the
-// possibility of deleting resources that are not managed by this library. The usual
-// care and due dillgence should be taken before deleting.
-//
-// It will also detach any managed policies that were attached
-// to the role. It is a high level abstraction that should represent the majority
-// of use cases for this library.
-func Delete(name string) error {
-	l, err := NewLambda(name, "")
-	if err != nil {
-		return err
+// apply executes prepared actions, role first, and then configures the
+// function's URL, HTTP API, destinations and triggers.
+func (l Lambda) apply(ctx context.Context, roleAction RoleAction, action LambdaAction) error {
+	if role, ok := roleAction.(RoleCreateOrUpdate); ok && role.CreateRole != nil {
+		l.report("role", "creating role %s", l.ExecutionRole.RoleName)
+	} else {
+		l.report("role", "updating role %s", l.ExecutionRole.RoleName)
 	}
-	lambdaClient := lambda.NewFromConfig(l.cfg)
-	fnInfo, err := lambdaClient.GetFunction(context.Background(), &lambda.GetFunctionInput{
-		FunctionName: aws.String(name),
-	})
+	err := roleAction.Do(ctx)
 	if err != nil {
 		return err
 	}
-	roleArn := *fnInfo.Configuration.Role
-	_, err = lambdaClient.DeleteFunction(context.Background(), &lambda.DeleteFunctionInput{
-		FunctionName: aws.String(name),
-	})
-	if err != nil {
-		return err
+	switch a := action.(type) {
+	case LambdaCreateAction:
+		l.reportUpload(a.Upload, len(a.CreateLambdaCommand.Code.ZipFile), "creating function")
+	case LambdaUpdateAction:
+		if a.CodeUnchanged {
+			l.report("upload", "no changes to the code of %s, skipping the upload", l.Name)
+			break
+		}
+		l.reportUpload(a.Upload, len(a.UpdateLambdaCommand.ZipFile), "updating function")
 	}
-	iamClient := iam.NewFromConfig(l.cfg)
-	roleName := strings.Split(roleArn, "/")[1]
-	attachedPolicies, err := iamClient.ListAttachedRolePolicies(context.Background(), &iam.ListAttachedRolePoliciesInput{
-		RoleName: aws.String(roleName),
-	})
+	err = action.Do(ctx)
 	if err != nil {
-		return err
+		return l.explainArchitecture(err)
 	}
-	for _, policy := range attachedPolicies.AttachedPolicies {
-		_, err = iamClient.DetachRolePolicy(context.Background(), &iam.DetachRolePolicyInput{
-			PolicyArn: policy.PolicyArn,
-			RoleName:  aws.String(roleName),
-		})
+	if l.Destinations != nil {
+		l.report("destinations", "configuring asynchronous invocation destinations")
+		err = NewEventInvokeConfigAction(l.lambdaAPI(), l.Name, *l.Destinations).Do(ctx)
 		if err != nil {
 			return err
 		}
 	}
-	_, err = iamClient.DeleteRole(context.Background(), &iam.DeleteRoleInput{
-		RoleName: aws.String(roleName),
-	})
+	if l.FunctionURL != nil {
+		l.report("function-url", "configuring function URL")
+		err = NewFunctionURLAction(l.lambdaAPI(), l.Name, *l.FunctionURL).Do(ctx)
+		if err != nil {
+			return l.explainFunctionURL(err)
+		}
+	}
+	if l.HTTPAPI != nil {
+		l.report("http-api", "configuring HTTP API")
+		err = NewHTTPAPIAction(l.apiGatewayAPI(), l.lambdaAPI(), l).Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if _, ok := action.(LambdaUpdateAction); ok {
+		err = l.removeStaleS3Triggers(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	for _, trigger := range l.S3Triggers {
+		l.report("s3-trigger", "configuring notifications from bucket %s", trigger.Bucket)
+		err = NewS3TriggerAction(l.s3API(), l.lambdaAPI(), l, trigger).Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if l.fixQueue {
+		// Lambda won't map a queue whose visibility timeout is shorter
+		// than the function's timeout, so the queue is fixed first.
+		err = l.fixQueues(ctx, action)
+		if err != nil {
+			return err
+		}
+	}
+	for _, source := range l.EventSources {
+		l.report("event-source", "mapping events from %s", source.ARN)
+		err = NewEventSourceMappingAction(l.lambdaAPI(), l.Name, source).Do(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l Lambda) reportUpload(upload *PackageUpload, size int, purpose string) {
+	if upload != nil {
+		l.report("upload", "uploading %s to s3://%s/%s", megabytes(len(upload.Package)), upload.Bucket, upload.Key)
+		l.detail("upload", "%s from s3://%s/%s", purpose, upload.Bucket, upload.Key)
+		return
+	}
+	l.report("upload", "uploading %s, %s", megabytes(size), purpose)
+	l.detail("upload", "package is %d bytes", size)
+}
+
+// Destination is a method on the [Lambda] struct that describes where the
+// lambda function would be deployed, e.g. "prod-payments (123456789012) in
+// us-east-1", so that users can check they are in the account they expect.
+// The account alias is left out if the account has none, or if the current
+// credentials aren't allowed to read it.
+func (l Lambda) Destination(ctx context.Context) string {
+	account := l.AWSAccountID
+	alias, err := GetAccountAlias(ctx, l.iamAPI())
+	if err == nil && alias != "" {
+		account = fmt.Sprintf("%s (%s)", alias, l.AWSAccountID)
+	}
+	return fmt.Sprintf("%s in %s", account, l.cfg.Region)
+}
+
+// Test is a method on the [Lambda] struct that publishes the deployed code and
+// smoke tests the new version, in a dry run mode unless [WithSmokeTest] says
+// otherwise. As per AWS documentation, the dry run mode should not execute
+// the lambda function, but will rather 'validate parameter values and verify
+// that the user or role has permission to invoke the function'.
+func (l Lambda) Test(ctx context.Context) error {
+	_, err := l.Publish(ctx)
+	return err
+}
+
+// Deploy is a convenience function that will handle the paperwork that would
+// otherwise fall to the user to manage. It will create a new [Lambda] struct
+// and attempt to deploy it to AWS. It will also test the lambda function after
+// deployment. It is a high level abstraction that should represent the majority
+// of use cases for this library.
+func Deploy(name, source string, opts ...DeployOptions) error {
+	return DeployWithContext(context.Background(), name, source, opts...)
+}
+
+// DeployWithContext is [Deploy] with a context that bounds every call it makes
+// to AWS. Cancelling ctx stops the deploy at the next call, leaving behind
+// whatever had been deployed by then.
+func DeployWithContext(ctx context.Context, name, source string, opts ...DeployOptions) error {
+	_, err := DeployAndPublish(ctx, name, source, opts...)
 	return err
 }
@@ -3,22 +3,30 @@ package glambda_test
 import (
 	"archive/zip"
 	"bytes"
+	"context"
+	"debug/elf"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 	"unicode"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/smithy-go"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/mr-joshcrane/glambda"
-	mock "github.com/mr-joshcrane/glambda/testdata/mock_clients"
+	"github.com/mr-joshcrane/glambda/glambdatest"
 )
 
 func init() {
@@ -27,20 +35,24 @@ func init() {
 	glambda.UUID = func() string {
 		return "DEADBEEF"
 	}
-	glambda.AWSAccountID = func(client glambda.STSClient) (string, error) {
-		return "123456789012", nil
+	glambda.AWSAccountID = func(ctx context.Context, client glambda.STSClient) (string, error) {
+		return "123456789012", ctx.Err()
 	}
 	glambda.DefaultRetryWaitingPeriod = func() {
 		// No need to wait in tests
 	}
+	glambda.UploadBackoff = func(context.Context, int) error { return nil }
+	glambda.CanaryWait = func(context.Context, time.Duration) error { return nil }
+	glambda.ProvisionedConcurrencyPollInterval = time.Millisecond
+	glambda.InsightsQueryPollInterval = time.Millisecond
 }
 
 func TestGetAWSAccountID(t *testing.T) {
 	t.Parallel()
-	client := mock.DummySTSClient{
+	client := glambdatest.DummySTSClient{
 		AccountID: "123456789012",
 	}
-	got, err := glambda.GetAWSAccountID(client)
+	got, err := glambda.GetAWSAccountID(context.Background(), client)
 	if err != nil {
 		t.Error(err)
 	}
@@ -51,10 +63,10 @@ func TestGetAWSAccountID(t *testing.T) {
 
 func TestGetAWSAccountID_ErrorCase(t *testing.T) {
 	t.Parallel()
-	client := mock.DummySTSClient{
+	client := glambdatest.DummySTSClient{
 		Err: fmt.Errorf("some error"),
 	}
-	_, err := glambda.GetAWSAccountID(client)
+	_, err := glambda.GetAWSAccountID(context.Background(), client)
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -77,6 +89,7 @@ func TestNewLambda(t *testing.T) {
 		RoleARN:                  "arn:aws:iam::123456789012:role/glambda_exec_role_test",
 		AssumeRolePolicyDocument: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
 		ManagedPolicies:          []string{"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+		Function:                 "test",
 	}
 	if !cmp.Equal(want, l.ExecutionRole) {
 		t.Error(cmp.Diff(want, l.ExecutionRole))
@@ -90,8 +103,11 @@ func TestExecutionRole_CreateRoleCommand(t *testing.T) {
 	want := &iam.CreateRoleInput{
 		RoleName:                 aws.String("testRole"),
 		AssumeRolePolicyDocument: aws.String(assumePolicy),
+		Tags: []iTypes.Tag{
+			{Key: aws.String("glambda:managed"), Value: aws.String("true")},
+		},
 	}
-	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{})
+	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iTypes.Tag{})
 	if !cmp.Equal(roleCmd, want, ignore) {
 		t.Error(cmp.Diff(roleCmd, want, ignore))
 	}
@@ -129,7 +145,7 @@ func TestExecutionRole_AttachInLinePolicyCommand(t *testing.T) {
 func TestPrepareAction_CreateFunction(t *testing.T) {
 	t.Parallel()
 
-	client := mock.DummyLambdaClient{
+	client := glambdatest.DummyLambdaClient{
 		FuncExists: false,
 		Err:        nil,
 	}
@@ -139,7 +155,7 @@ func TestPrepareAction_CreateFunction(t *testing.T) {
 		HandlerPath:   handler,
 		ExecutionRole: glambda.ExecutionRole{RoleName: "lambda-role"},
 	}
-	action, err := glambda.PrepareLambdaAction(l, client)
+	action, err := glambda.PrepareLambdaAction(context.Background(), l, client)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -152,7 +168,7 @@ func TestPrepareAction_CreateFunction(t *testing.T) {
 
 func TestPrepareAction_UpdateFunction(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyLambdaClient{
+	client := glambdatest.DummyLambdaClient{
 		FuncExists: true,
 		Err:        nil,
 	}
@@ -163,7 +179,7 @@ func TestPrepareAction_UpdateFunction(t *testing.T) {
 		ExecutionRole: glambda.ExecutionRole{RoleName: "lambda-role"},
 	}
 
-	action, err := glambda.PrepareLambdaAction(l, client)
+	action, err := glambda.PrepareLambdaAction(context.Background(), l, client)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -175,7 +191,7 @@ func TestPrepareAction_UpdateFunction(t *testing.T) {
 
 func TestPrepareAction_ErrorCase(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyLambdaClient{
+	client := glambdatest.DummyLambdaClient{
 		FuncExists: false,
 		Err:        fmt.Errorf("some client error"),
 	}
@@ -185,7 +201,7 @@ func TestPrepareAction_ErrorCase(t *testing.T) {
 		HandlerPath:   handler,
 		ExecutionRole: glambda.ExecutionRole{RoleName: "lambda-role"},
 	}
-	_, err := glambda.PrepareLambdaAction(l, client)
+	_, err := glambda.PrepareLambdaAction(context.Background(), l, client)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -226,6 +242,10 @@ func TestValidate_RejectsIncorrectlySetupLambdaSourceFiles(t *testing.T) {
 			description: "invalid handler signature",
 			filename:    "testdata/invalid_handler_signature.go",
 		},
+		{
+			description: "imports C",
+			filename:    "testdata/import_c.go",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.description, func(t *testing.T) {
@@ -316,6 +336,23 @@ func TestPutRolePolicyCommand_WhereCommandExists(t *testing.T) {
 	}
 }
 
+func TestPutRolePolicyCommand_NamesThePolicyAsTheNamingConventionDoes(t *testing.T) {
+	t.Parallel()
+	role := glambda.ExecutionRole{
+		RoleName:     "glambda_exec_role_myfunction",
+		Function:     "MyFunction",
+		InLinePolicy: `some inline policy`,
+	}
+	want, err := glambda.DefaultNamingConvention.PolicyName("MyFunction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := glambda.PutRolePolicyCommand(role)
+	if len(cmds) != 1 || aws.ToString(cmds[0].PolicyName) != want {
+		t.Errorf("want policy %s, got %v", want, cmds)
+	}
+}
+
 func TestPutRolePolicyCommand_WhereCommandDoesNotExist(t *testing.T) {
 	t.Parallel()
 	role := glambda.ExecutionRole{
@@ -329,10 +366,10 @@ func TestPutRolePolicyCommand_WhereCommandDoesNotExist(t *testing.T) {
 
 func TestPrepareRoleAction_CreatesRoleWhenRoleDoesNotExist(t *testing.T) {
 	t.Parallel()
-	got, err := glambda.PrepareRoleAction(glambda.ExecutionRole{
+	got, err := glambda.PrepareRoleAction(context.Background(), glambda.ExecutionRole{
 		RoleName:                 "aRoleName",
 		AssumeRolePolicyDocument: glambda.DefaultAssumeRolePolicy,
-	}, mock.DummyIAMClient{
+	}, glambdatest.DummyIAMClient{
 		RoleExists: false,
 	})
 	if err != nil {
@@ -342,6 +379,9 @@ func TestPrepareRoleAction_CreatesRoleWhenRoleDoesNotExist(t *testing.T) {
 		CreateRole: &iam.CreateRoleInput{
 			RoleName:                 aws.String("aRoleName"),
 			AssumeRolePolicyDocument: aws.String(glambda.DefaultAssumeRolePolicy),
+			Tags: []iTypes.Tag{
+				{Key: aws.String("glambda:managed"), Value: aws.String("true")},
+			},
 		},
 		ManagedPolicies: []iam.AttachRolePolicyInput{
 			{
@@ -350,7 +390,7 @@ func TestPrepareRoleAction_CreatesRoleWhenRoleDoesNotExist(t *testing.T) {
 			},
 		},
 	}
-	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iam.AttachRolePolicyInput{}, glambda.RoleCreateOrUpdate{})
+	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iam.AttachRolePolicyInput{}, glambda.RoleCreateOrUpdate{}, iTypes.Tag{})
 	if !cmp.Equal(want, got, ignore) {
 		t.Error(cmp.Diff(want, got, ignore))
 	}
@@ -358,10 +398,10 @@ func TestPrepareRoleAction_CreatesRoleWhenRoleDoesNotExist(t *testing.T) {
 
 func TestPrepareRoleAction_DoesNotCreateRoleWhenRoleExists(t *testing.T) {
 	t.Parallel()
-	got, err := glambda.PrepareRoleAction(glambda.ExecutionRole{
+	got, err := glambda.PrepareRoleAction(context.Background(), glambda.ExecutionRole{
 		RoleName:                 "aRoleName",
 		AssumeRolePolicyDocument: glambda.DefaultAssumeRolePolicy,
-	}, mock.DummyIAMClient{
+	}, glambdatest.DummyIAMClient{
 		RoleExists: true,
 	})
 	if err != nil {
@@ -375,7 +415,7 @@ func TestPrepareRoleAction_DoesNotCreateRoleWhenRoleExists(t *testing.T) {
 			},
 		},
 	}
-	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iam.AttachRolePolicyInput{}, glambda.RoleCreateOrUpdate{})
+	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iam.AttachRolePolicyInput{}, glambda.RoleCreateOrUpdate{}, iTypes.Tag{})
 	if !cmp.Equal(want, got, ignore) {
 		t.Error(cmp.Diff(want, got, ignore))
 	}
@@ -383,11 +423,11 @@ func TestPrepareRoleAction_DoesNotCreateRoleWhenRoleExists(t *testing.T) {
 
 func TestPrepareRoleAction_AttachesMultipleManagedPolicies(t *testing.T) {
 	t.Parallel()
-	got, err := glambda.PrepareRoleAction(glambda.ExecutionRole{
+	got, err := glambda.PrepareRoleAction(context.Background(), glambda.ExecutionRole{
 		RoleName:                 "aRoleName",
 		AssumeRolePolicyDocument: glambda.DefaultAssumeRolePolicy,
 		ManagedPolicies:          []string{"arn:aws:iam::aws:policy/IAMFullAccess", "arn:aws:iam::aws:policy/AmazonDynamoDBReadOnlyAccess"},
-	}, mock.DummyIAMClient{
+	}, glambdatest.DummyIAMClient{
 		RoleExists: false,
 	})
 	if err != nil {
@@ -397,6 +437,9 @@ func TestPrepareRoleAction_AttachesMultipleManagedPolicies(t *testing.T) {
 		CreateRole: &iam.CreateRoleInput{
 			RoleName:                 aws.String("aRoleName"),
 			AssumeRolePolicyDocument: aws.String(glambda.DefaultAssumeRolePolicy),
+			Tags: []iTypes.Tag{
+				{Key: aws.String("glambda:managed"), Value: aws.String("true")},
+			},
 		},
 		ManagedPolicies: []iam.AttachRolePolicyInput{
 			{
@@ -413,7 +456,7 @@ func TestPrepareRoleAction_AttachesMultipleManagedPolicies(t *testing.T) {
 			},
 		},
 	}
-	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iam.AttachRolePolicyInput{}, glambda.RoleCreateOrUpdate{})
+	ignore := cmpopts.IgnoreUnexported(iam.CreateRoleInput{}, iam.AttachRolePolicyInput{}, glambda.RoleCreateOrUpdate{}, iTypes.Tag{})
 	if !cmp.Equal(want, got, ignore) {
 		t.Error(cmp.Diff(want, got, ignore))
 	}
@@ -421,10 +464,10 @@ func TestPrepareRoleAction_AttachesMultipleManagedPolicies(t *testing.T) {
 
 func TestWaitForConsistency_PassesForConsistentVersion(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyLambdaClient{
+	client := glambdatest.DummyLambdaClient{
 		ConsistantAfterXRetries: aws.Int(8),
 	}
-	_, err := glambda.WaitForConsistency(client, "testLambda")
+	_, err := glambda.WaitForConsistency(context.Background(), client, "testLambda")
 	if err != nil {
 		t.Error(err)
 	}
@@ -432,8 +475,8 @@ func TestWaitForConsistency_PassesForConsistentVersion(t *testing.T) {
 
 func TestWaitForConsistency_FailsForInconsistentVersion(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyLambdaClient{}
-	_, err := glambda.WaitForConsistency(client, "testLambda")
+	client := glambdatest.DummyLambdaClient{}
+	_, err := glambda.WaitForConsistency(context.Background(), client, "testLambda")
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -441,11 +484,11 @@ func TestWaitForConsistency_FailsForInconsistentVersion(t *testing.T) {
 
 func TestUpdateLambdaActionDo(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyLambdaClient{
+	client := glambdatest.DummyLambdaClient{
 		FuncExists: true,
 	}
 	action := glambda.NewLambdaUpdateAction(client, glambda.Lambda{Name: "testLambda"}, []byte("some valid zip data"))
-	err := action.Do()
+	err := action.Do(context.Background())
 	if err != nil {
 		t.Error(err)
 	}
@@ -465,7 +508,7 @@ func TestRetryableErrors_ParameterErrorsAreRetried(t *testing.T) {
 
 func TestCreateLambdaActionDo(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyLambdaClient{
+	client := glambdatest.DummyLambdaClient{
 		FuncExists: false,
 	}
 	l := glambda.Lambda{
@@ -484,7 +527,7 @@ func TestCreateLambdaActionDo(t *testing.T) {
 	}
 
 	action := glambda.NewLambdaCreateAction(client, l, []byte("some valid zip data"))
-	err := action.Do()
+	err := action.Do(context.Background())
 	if err != nil {
 		t.Error(err)
 	}
@@ -492,7 +535,7 @@ func TestCreateLambdaActionDo(t *testing.T) {
 
 func TestCreateRoleActionDo_IfRoleDoesNotExist(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyIAMClient{
+	client := glambdatest.DummyIAMClient{
 		RoleExists: false,
 	}
 	action := glambda.NewRoleCreateOrUpdateAction(client)
@@ -500,7 +543,7 @@ func TestCreateRoleActionDo_IfRoleDoesNotExist(t *testing.T) {
 		RoleName:                 aws.String("aRoleName"),
 		AssumeRolePolicyDocument: aws.String(glambda.DefaultAssumeRolePolicy),
 	}
-	err := action.Do()
+	err := action.Do(context.Background())
 	if err != nil {
 		t.Error(err)
 	}
@@ -508,7 +551,7 @@ func TestCreateRoleActionDo_IfRoleDoesNotExist(t *testing.T) {
 
 func TestCreateRoleActionDo_FailsIfRoleExists(t *testing.T) {
 	t.Parallel()
-	client := mock.DummyIAMClient{
+	client := glambdatest.DummyIAMClient{
 		RoleExists: true,
 	}
 	action := glambda.NewRoleCreateOrUpdateAction(client)
@@ -516,7 +559,7 @@ func TestCreateRoleActionDo_FailsIfRoleExists(t *testing.T) {
 		RoleName:                 aws.String("aRoleName"),
 		AssumeRolePolicyDocument: aws.String(glambda.DefaultAssumeRolePolicy),
 	}
-	err := action.Do()
+	err := action.Do(context.Background())
 	if err == nil {
 		t.Error("expected error, got nil")
 	}
@@ -525,7 +568,7 @@ func TestCreateRoleActionDo_FailsIfRoleExists(t *testing.T) {
 func TestCreateRoleActionDo_AttachesManagedPolicies(t *testing.T) {
 	t.Parallel()
 	var clientCallCounter int32
-	client := mock.DummyIAMClient{
+	client := glambdatest.DummyIAMClient{
 		RoleExists: false,
 		Counter:    &clientCallCounter,
 	}
@@ -550,7 +593,7 @@ func TestCreateRoleActionDo_AttachesManagedPolicies(t *testing.T) {
 			PolicyDocument: aws.String(`some inline policy`),
 		},
 	}
-	err := action.Do()
+	err := action.Do(context.Background())
 	if err != nil {
 		t.Error(err)
 	}
@@ -625,7 +668,7 @@ func TestCreateLambdaResourcePolicy_NoConditions(t *testing.T) {
 		Action:       aws.String("lambda:InvokeFunction"),
 		FunctionName: aws.String("testLambda"),
 		Principal:    aws.String("123456789012"),
-		StatementId:  aws.String("glambda_invoke_permission_DEADBEEF"),
+		StatementId:  aws.String("glambda_invoke_permission_070fef59"),
 	}
 	ignore := cmpopts.IgnoreUnexported(lambda.AddPermissionInput{})
 	if !cmp.Equal(got, want, ignore) {
@@ -649,7 +692,7 @@ func TestCreateLambdaResourcePolicy_WithConditions(t *testing.T) {
 		Action:         aws.String("lambda:InvokeFunction"),
 		FunctionName:   aws.String("testLambda"),
 		Principal:      aws.String("s3.amazonaws.com"),
-		StatementId:    aws.String("glambda_invoke_permission_DEADBEEF"),
+		StatementId:    aws.String("glambda_invoke_permission_4f6999ca"),
 		SourceAccount:  aws.String("123456789012"),
 		SourceArn:      aws.String("arn:aws:s3:::mybucket"),
 		PrincipalOrgID: aws.String("o-123456"),
@@ -752,3 +795,658 @@ func TestWithInlinePolicy_CanDetectInvalidPolicyCases(t *testing.T) {
 		})
 	}
 }
+
+func TestNewLambda_AppliesOptionsBeforeResolvingAccount(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("sandboxed", "testdata/correct_test_handler/main.go", glambdatest.Sandbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.AWSAccountID != glambdatest.AccountID {
+		t.Errorf("expected account %s, got %s", glambdatest.AccountID, l.AWSAccountID)
+	}
+	want := "arn:aws:iam::" + glambdatest.AccountID + ":role/glambda_exec_role_sandboxed"
+	if l.ExecutionRole.RoleARN != want {
+		t.Errorf("expected role ARN %s, got %s", want, l.ExecutionRole.RoleARN)
+	}
+}
+
+func TestNewLambdaWithContext_StopsLookingUpTheAccountWhenCancelled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := glambda.NewLambdaWithContext(ctx, "sandboxed", "", glambdatest.Sandbox())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestDeploy_RunsFullPipelineInSandbox(t *testing.T) {
+	t.Parallel()
+	err := glambda.Deploy("sandboxed", "testdata/correct_test_handler/main.go",
+		glambda.WithManagedPolicies("AmazonS3ReadOnlyAccess"),
+		glambdatest.Sandbox(),
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithFaultInjection_ConsistencyRetriesRecoverFromTransientFailures(t *testing.T) {
+	t.Parallel()
+	err := glambda.Deploy("faulty", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithFaultInjection("PublishVersion", 3),
+	)
+	if err != nil {
+		t.Errorf("expected deploy to recover from transient PublishVersion failures, got %v", err)
+	}
+}
+
+func TestWithFaultInjection_FailedOperationFailsDeploy(t *testing.T) {
+	t.Parallel()
+	err := glambda.Deploy("faulty", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithFaultInjection("CreateRole", 1),
+	)
+	if !errors.Is(err, glambda.ErrInjectedFault) {
+		t.Errorf("expected injected fault to fail the deploy, got %v", err)
+	}
+}
+
+func TestWithFaultInjection_RefusesToRunAgainstRealClients(t *testing.T) {
+	t.Parallel()
+	l := glambda.Lambda{Name: "real"}
+	err := glambda.WithFaultInjection("CreateRole", 1)(&l)
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestPackage_PackagesMultiFileHandlers(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		description string
+		path        string
+	}{
+		{
+			description: "package directory",
+			path:        "testdata/multi_file_handler",
+		},
+		{
+			description: "file with siblings in the same package",
+			path:        "testdata/multi_file_handler/main.go",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			data, err := glambda.Package(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("failed to create zip reader, %v", err)
+			}
+			if len(zipReader.File) != 1 || zipReader.File[0].Name != "bootstrap" {
+				t.Errorf("expected a single bootstrap file in zip, got %v", zipReader.File)
+			}
+		})
+	}
+}
+
+func TestValidate_RejectsCgoOnlyPackageWithAlternative(t *testing.T) {
+	t.Parallel()
+	err := glambda.Validate("testdata/cgo_handler")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "github.com/mattn/go-sqlite3") || !strings.Contains(err.Error(), "modernc.org/sqlite") {
+		t.Errorf("expected guidance naming the package and alternative, got %v", err)
+	}
+}
+
+func TestPackage_FailsEarlyOnCgoImports(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.Package("testdata/cgo_handler")
+	if err == nil || !strings.Contains(err.Error(), "needs cgo") {
+		t.Errorf("expected cgo error, got %v", err)
+	}
+}
+
+func TestPackage_FailsEarlyOnDependenciesThatNeedCgo(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.Package("testdata/cgo_dependency/needs_cgo")
+	if err == nil || !strings.Contains(err.Error(), "depends on github.com/mr-joshcrane/glambda/testdata/cgo_dependency/clib, which needs cgo") {
+		t.Errorf("expected an error naming the dependency that needs cgo, got %v", err)
+	}
+}
+
+func TestPackage_BuildsDependenciesWithAPureGoFallback(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.Package("testdata/cgo_dependency/has_fallback")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidate_AcceptsMultiFileHandlerPackage(t *testing.T) {
+	t.Parallel()
+	err := glambda.Validate("testdata/multi_file_handler")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuild_ValidatesTheHandlerBeforeBuildingIt(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "testdata/missing_lambda_start.go", glambdatest.Sandbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Build()
+	if err == nil || !strings.Contains(err.Error(), "does not call lambda.Start") {
+		t.Errorf("expected the handler to fail validation, got %v", err)
+	}
+}
+
+func TestCheckHandler_ReportsCompilerErrorsAtTheHandlersOwnPath(t *testing.T) {
+	t.Parallel()
+	// The handler is outside any module, so it is built in a temporary copy.
+	dir := t.TempDir()
+	handler := filepath.Join(dir, "main.go")
+	src := "package main\n\ntype runtime struct{}\n\nfunc (runtime) Start() {}\n\nfunc main() {\n\tvar n int = \"one\"\n\truntime{}.Start()\n}\n"
+	err := os.WriteFile(handler, []byte(src), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.CheckHandler(handler, glambda.BuildOptions{})
+	if err == nil {
+		t.Fatal("expected a compiler error, got nil")
+	}
+	if !strings.Contains(err.Error(), handler+":8:") || strings.Contains(err.Error(), "glambda-module") {
+		t.Errorf("expected the error to point at %s line 8, got %v", handler, err)
+	}
+}
+
+func TestResolveHandler(t *testing.T) {
+	t.Parallel()
+	single, err := glambda.ResolveHandler("testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single.IsPackage {
+		t.Error("expected a lone handler file to be built as a single file")
+	}
+	multi, err := glambda.ResolveHandler("testdata/multi_file_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join("testdata", "multi_file_handler", "handler.go"),
+		filepath.Join("testdata", "multi_file_handler", "main.go"),
+	}
+	if !multi.IsPackage || !cmp.Equal(want, multi.Files) {
+		t.Errorf("expected package build of %v, got %+v", want, multi)
+	}
+}
+
+func TestPackage_BuildsWithinHandlersOwnModule(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":               "module example.com/handler\n\ngo 1.22\n\nrequire example.com/greeting v0.0.0\n\nreplace example.com/greeting => ./greeting\n",
+		"main.go":              "package main\n\nimport \"example.com/greeting\"\n\nfunc main() {\n\tprintln(greeting.Hello())\n}\n",
+		"greeting/go.mod":      "module example.com/greeting\n\ngo 1.22\n",
+		"greeting/greeting.go": "package greeting\n\nfunc Hello() string { return \"hello\" }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := glambda.Package(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatalf("expected handler using a replace directive to build, got %v", err)
+	}
+	modules, err := glambda.PackageModules(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []glambda.Module{{Path: "./greeting", Version: "devel"}}
+	if !cmp.Equal(want, modules) {
+		t.Error(cmp.Diff(want, modules))
+	}
+}
+
+func TestPackage_ConcurrentBuildsOfOneModuleLeaveItUntouched(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	goMod := "module example.com/handlers\n\ngo 1.22\n"
+	err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handlers []string
+	for _, name := range []string{"orders", "invoices", "refunds", "payouts"} {
+		handler := filepath.Join(dir, name, "main.go")
+		err := os.MkdirAll(filepath.Dir(handler), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(handler, []byte("package main\n\nfunc main() {\n\tprintln(\""+name+"\")\n}\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handlers = append(handlers, handler)
+	}
+	errs := make(chan error, len(handlers))
+	for _, handler := range handlers {
+		go func() {
+			_, err := glambda.Package(handler)
+			errs <- err
+		}()
+	}
+	for range handlers {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != goMod {
+		t.Errorf("expected go.mod to be left as it was, got %q", got)
+	}
+}
+
+func TestPackageWith_KeepBuildDirLeavesWorkspaceAndReportsCommand(t *testing.T) {
+	t.Parallel()
+	log := new(bytes.Buffer)
+	_, err := glambda.PackageWith("testdata/correct_test_handler/main.go", glambda.BuildOptions{KeepBuildDir: log})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, rest, _ := strings.Cut(log.String(), "\n")
+	dir, ok := strings.CutPrefix(first, "keeping build directory ")
+	if !ok {
+		t.Fatalf("expected build directory to be reported, got %q", log.String())
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	_, err = os.Stat(filepath.Join(dir, "bootstrap"))
+	if err != nil {
+		t.Errorf("expected bootstrap to be kept, got %v", err)
+	}
+	if !strings.Contains(rest, "GOARCH=arm64") || !strings.Contains(rest, "go build -trimpath -tags lambda.norpc -o "+filepath.Join(dir, "bootstrap")) {
+		t.Errorf("expected go build command, got %q", rest)
+	}
+}
+
+func TestPackage_SynthesizesModuleForHandlerOutsideAnyModule(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if _, ok := glambda.FindModuleRoot(dir); ok {
+		t.Skip("temporary directory is inside a Go module")
+	}
+	handler := filepath.Join(dir, "main.go")
+	err := os.WriteFile(handler, []byte("package main\n\nfunc main() {}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = glambda.Package(handler)
+	if err != nil {
+		t.Errorf("expected handler outside of a module to build, got %v", err)
+	}
+}
+
+func TestPackageWith_EmitModuleCopiesTheHandlersModuleFiles(t *testing.T) {
+	t.Parallel()
+	out := filepath.Join(t.TempDir(), "out")
+	_, err := glambda.PackageWith("testdata/correct_test_handler/main.go", glambda.BuildOptions{EmitModule: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		want, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(want, got) {
+			t.Errorf("expected emitted %s to match the module's", name)
+		}
+	}
+}
+
+func TestPackageWith_EmitModuleCopiesTheSynthesizedModuleFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if _, ok := glambda.FindModuleRoot(dir); ok {
+		t.Skip("temporary directory is inside a Go module")
+	}
+	handler := filepath.Join(dir, "main.go")
+	err := os.WriteFile(handler, []byte("package main\n\nfunc main() {}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	_, err = glambda.PackageWith(handler, glambda.BuildOptions{EmitModule: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "module main\n") {
+		t.Errorf("expected the synthesized go.mod, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(out, "go.sum")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no go.sum for a handler without dependencies, got %v", err)
+	}
+}
+
+func TestFindModuleRoot(t *testing.T) {
+	t.Parallel()
+	root, ok := glambda.FindModuleRoot(filepath.Join("testdata", "multi_file_handler"))
+	if !ok {
+		t.Fatal("expected to find the repository's module root")
+	}
+	want, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != want {
+		t.Errorf("expected module root %s, got %s", want, root)
+	}
+}
+
+func TestWithEnvironment_SetsEnvironmentOnCreate(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("testLambda", "", glambdatest.Sandbox(), glambda.WithEnvironment(map[string]string{"LOG_LEVEL": "debug"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data"))
+	want := &types.Environment{Variables: map[string]string{"LOG_LEVEL": "debug"}}
+	got := action.CreateLambdaCommand.Environment
+	if !cmp.Equal(want, got, cmpopts.IgnoreUnexported(types.Environment{})) {
+		t.Error(cmp.Diff(want, got, cmpopts.IgnoreUnexported(types.Environment{})))
+	}
+}
+
+func TestWithEnvironment_RejectsInvalidAndReservedKeys(t *testing.T) {
+	t.Parallel()
+	for _, key := range []string{"1BAD", "HAS-DASH", "AWS_REGION", "_HANDLER"} {
+		_, err := glambda.NewLambda("testLambda", "", glambdatest.Sandbox(), glambda.WithEnvironment(map[string]string{key: "x"}))
+		if err == nil {
+			t.Errorf("expected environment variable %s to be rejected", key)
+		}
+	}
+}
+
+func TestUpdateLambdaActionDo_UpdatesConfigurationAfterCode(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}
+	l := glambda.Lambda{Name: "testLambda", Environment: map[string]string{"LOG_LEVEL": "debug"}}
+	err := glambda.NewLambdaUpdateAction(client, l, []byte("some valid zip data")).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"UpdateFunctionCode", "ListTags", "TagResource", "GetFunctionConfiguration", "UpdateFunctionConfiguration"}
+	got := recorder.Operations()
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	input := recorder.Calls("UpdateFunctionConfiguration")[0].Input.(*lambda.UpdateFunctionConfigurationInput)
+	if input.Environment.Variables["LOG_LEVEL"] != "debug" {
+		t.Errorf("expected LOG_LEVEL to be set, got %v", input.Environment.Variables)
+	}
+}
+
+func TestUpdateLambdaActionDo_LeavesConfigurationAloneByDefault(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}
+	err := glambda.NewLambdaUpdateAction(client, glambda.Lambda{Name: "testLambda"}, []byte("some valid zip data")).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := recorder.Calls("UpdateFunctionConfiguration"); len(calls) != 0 {
+		t.Errorf("expected no configuration update, got %d", len(calls))
+	}
+}
+
+func TestWithMemoryAndTimeout_SetOnCreateAndUpdate(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("testLambda", "", glambdatest.Sandbox(), glambda.WithMemory(512), glambda.WithTimeout(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data")).CreateLambdaCommand
+	if aws.ToInt32(create.MemorySize) != 512 || aws.ToInt32(create.Timeout) != 30 {
+		t.Errorf("expected 512MB and 30s on create, got %v and %v", aws.ToInt32(create.MemorySize), aws.ToInt32(create.Timeout))
+	}
+	update := glambda.UpdateConfigurationCommand(*l)
+	if aws.ToInt32(update.MemorySize) != 512 || aws.ToInt32(update.Timeout) != 30 {
+		t.Errorf("expected 512MB and 30s on update, got %v and %v", aws.ToInt32(update.MemorySize), aws.ToInt32(update.Timeout))
+	}
+	if update.Environment != nil {
+		t.Errorf("expected environment to be left alone, got %v", update.Environment)
+	}
+}
+
+func TestWithMemoryAndTimeout_RejectOutOfRangeValues(t *testing.T) {
+	t.Parallel()
+	opts := map[string]glambda.DeployOptions{
+		"memory too small":   glambda.WithMemory(64),
+		"memory too large":   glambda.WithMemory(20000),
+		"timeout too short":  glambda.WithTimeout(500 * time.Millisecond),
+		"timeout too long":   glambda.WithTimeout(time.Hour),
+		"fractional timeout": glambda.WithTimeout(1500 * time.Millisecond),
+	}
+	for description, opt := range opts {
+		_, err := glambda.NewLambda("testLambda", "", glambdatest.Sandbox(), opt)
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", description)
+		}
+	}
+}
+
+func TestPackageFor_BuildsForRequestedArchitecture(t *testing.T) {
+	t.Parallel()
+	tc := map[types.Architecture]elf.Machine{
+		types.ArchitectureArm64: elf.EM_AARCH64,
+		types.ArchitectureX8664: elf.EM_X86_64,
+	}
+	for arch, want := range tc {
+		data, err := glambda.PackageFor("testdata/correct_test_handler/main.go", arch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := zipReader.File[0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		binary, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exe, err := elf.NewFile(bytes.NewReader(binary))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exe.Machine != want {
+			t.Errorf("%s: expected %s binary, got %s", arch, want, exe.Machine)
+		}
+	}
+}
+
+func TestWithArchitecture_SetsArchitectureOnCreateAndUpdate(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("testLambda", "", glambdatest.Sandbox(), glambda.WithArchitecture("x86_64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.Architecture{types.ArchitectureX8664}
+	create := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data"))
+	if !cmp.Equal(want, create.CreateLambdaCommand.Architectures) {
+		t.Error(cmp.Diff(want, create.CreateLambdaCommand.Architectures))
+	}
+	update := glambda.NewLambdaUpdateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data"))
+	if !cmp.Equal(want, update.UpdateLambdaCommand.Architectures) {
+		t.Error(cmp.Diff(want, update.UpdateLambdaCommand.Architectures))
+	}
+}
+
+func TestWithArchitecture_RejectsUnknownArchitectures(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("testLambda", "", glambdatest.Sandbox(), glambda.WithArchitecture("amd64"))
+	if err == nil {
+		t.Error("expected error for unsupported architecture, got nil")
+	}
+}
+
+func TestDestination_IncludesAccountAliasWhenSet(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithIAMClient(glambdatest.DummyIAMClient{AccountAlias: "prod-payments"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "prod-payments (123456789012) in us-east-1"
+	if got := l.Destination(context.Background()); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestDestination_FallsBackToAccountID(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "123456789012 in us-east-1"
+	if got := l.Destination(context.Background()); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+// deployedWithCode is a sandbox whose function is already deployed, with the
+// given CodeSha256.
+func deployedWithCode(recorder *glambdatest.Recorder, name, sha string) {
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String(name),
+			FunctionArn:  aws.String("arn:aws:lambda:us-east-1:123456789012:function:" + name),
+			CodeSha256:   aws.String(sha),
+		},
+		Tags: map[string]string{glambda.ManagedTagKey: "true"},
+	})
+}
+
+func TestDeploy_SkipsCodeUpdateWhenDeployedCodeIsIdentical(t *testing.T) {
+	t.Parallel()
+	handler := "testdata/correct_test_handler/main.go"
+	pkg, err := glambda.Package(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := glambdatest.NewRecorder()
+	deployedWithCode(recorder, "unchanged", glambda.CodeSHA256(pkg))
+	var messages []string
+	l, err := glambda.NewLambda("unchanged", handler,
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithProgress(func(e glambda.Event) { messages = append(messages, e.Message) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := recorder.Calls("UpdateFunctionCode"); len(calls) != 0 {
+		t.Errorf("want no code update, got %d", len(calls))
+	}
+	if calls := recorder.Calls("TagResource"); len(calls) != 1 {
+		t.Errorf("want tags reconciled without a code update, got %d TagResource calls", len(calls))
+	}
+	want := "no changes to the code of unchanged, skipping the upload"
+	if !slices.Contains(messages, want) {
+		t.Errorf("want progress %q, got %q", want, messages)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range plan.Changes {
+		if c.Operation == "UpdateFunctionCode" {
+			t.Errorf("want no UpdateFunctionCode in the plan, got %+v", c)
+		}
+	}
+}
+
+func TestDeploy_SkipsCodeUpdateOfIdenticalHandlerOutsideAnyModule(t *testing.T) {
+	t.Parallel()
+	// Each build of a handler outside any module happens in a new temporary
+	// module, which must not change the binary.
+	handler := filepath.Join(t.TempDir(), "main.go")
+	src := "package main\n\ntype runtime struct{}\n\nfunc (runtime) Start() {}\n\nfunc main() {\n\truntime{}.Start()\n}\n"
+	err := os.WriteFile(handler, []byte(src), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := glambda.Package(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := glambdatest.NewRecorder()
+	deployedWithCode(recorder, "standalone", glambda.CodeSHA256(pkg))
+	l, err := glambda.NewLambda("standalone", handler, glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := recorder.Calls("UpdateFunctionCode"); len(calls) != 0 {
+		t.Errorf("want no code update for a rebuild of the same handler, got %d", len(calls))
+	}
+}
+
+func TestDeploy_UpdatesCodeWhenDeployedCodeDiffers(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	deployedWithCode(recorder, "changed", "c3RhbGU=")
+	l, err := glambda.NewLambda("changed", "testdata/correct_test_handler/main.go", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := recorder.Calls("UpdateFunctionCode"); len(calls) != 1 {
+		t.Errorf("want one code update, got %d", len(calls))
+	}
+}
@@ -0,0 +1,902 @@
+package glambdatest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	gTypes "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	qTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DummyLambdaClient is a fake [glambda.LambdaClient]. By default functions do
+// not exist, so a deploy will take the create path.
+type DummyLambdaClient struct {
+	Recorder                *Recorder
+	ConsistantAfterXRetries *int
+	FuncExists              bool
+	Err                     error
+	Environment             map[string]string
+	ConfigUpdates           *int32
+	Tags                    map[string]string
+	// FunctionURLs holds the Function URLs created through the client, by
+	// function name. When nil, functions have no URL and none can be created.
+	FunctionURLs map[string]string
+}
+
+func (d DummyLambdaClient) GetFunction(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	if out, err, ok := intercept[*lambda.GetFunctionOutput](ctx, d.Recorder, "GetFunction", input); ok {
+		return out, err
+	}
+	if d.FuncExists {
+		return &lambda.GetFunctionOutput{
+			Configuration: &types.FunctionConfiguration{
+				FunctionName: input.FunctionName,
+				FunctionArn:  aws.String(functionARN(aws.ToString(input.FunctionName))),
+				Role:         aws.String("arn:aws:iam::" + AccountID + ":role/glambda_exec_role_" + strings.ToLower(aws.ToString(input.FunctionName))),
+			},
+			Tags: d.Tags,
+		}, nil
+	}
+	if !d.FuncExists && d.Err == nil {
+		return &lambda.GetFunctionOutput{}, new(types.ResourceNotFoundException)
+	}
+	if d.Err != nil {
+		return &lambda.GetFunctionOutput{}, d.Err
+	}
+	return &lambda.GetFunctionOutput{}, d.Err
+}
+
+func (d DummyLambdaClient) CreateFunction(ctx context.Context, input *lambda.CreateFunctionInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+	if out, err, ok := intercept[*lambda.CreateFunctionOutput](ctx, d.Recorder, "CreateFunction", input); ok {
+		return out, err
+	}
+	return &lambda.CreateFunctionOutput{}, nil
+}
+
+func (d DummyLambdaClient) UpdateFunctionCode(ctx context.Context, input *lambda.UpdateFunctionCodeInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+	if out, err, ok := intercept[*lambda.UpdateFunctionCodeOutput](ctx, d.Recorder, "UpdateFunctionCode", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return &lambda.UpdateFunctionCodeOutput{
+		FunctionName: input.FunctionName,
+		FunctionArn:  aws.String(functionARN(aws.ToString(input.FunctionName))),
+	}, nil
+}
+
+func (d DummyLambdaClient) Invoke(ctx context.Context, input *lambda.InvokeInput, opts ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	if out, err, ok := intercept[*lambda.InvokeOutput](ctx, d.Recorder, "Invoke", input); ok {
+		return out, err
+	}
+	report := "START RequestId: 8f5d0a3c Version: $LATEST\n" +
+		"END RequestId: 8f5d0a3c\n" +
+		"REPORT RequestId: 8f5d0a3c\tDuration: 1.50 ms\tBilled Duration: 2 ms\tMemory Size: 128 MB\tMax Memory Used: 20 MB\tInit Duration: 60.25 ms\t\n"
+	return &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte("all good"),
+		LogResult:  aws.String(base64.StdEncoding.EncodeToString([]byte(report))),
+	}, nil
+}
+
+func (d DummyLambdaClient) PublishVersion(ctx context.Context, input *lambda.PublishVersionInput, opts ...func(*lambda.Options)) (*lambda.PublishVersionOutput, error) {
+	if out, err, ok := intercept[*lambda.PublishVersionOutput](ctx, d.Recorder, "PublishVersion", input); ok {
+		return out, err
+	}
+	if d.ConsistantAfterXRetries == nil {
+		return &lambda.PublishVersionOutput{}, fmt.Errorf("this lambda never becomes consistent")
+	}
+	if *d.ConsistantAfterXRetries > 0 {
+		*d.ConsistantAfterXRetries--
+		return &lambda.PublishVersionOutput{}, fmt.Errorf("not yet consistent")
+	}
+	return &lambda.PublishVersionOutput{
+		Version: aws.String("1"),
+	}, nil
+}
+
+func (d DummyLambdaClient) AddPermission(ctx context.Context, input *lambda.AddPermissionInput, opts ...func(*lambda.Options)) (*lambda.AddPermissionOutput, error) {
+	if out, err, ok := intercept[*lambda.AddPermissionOutput](ctx, d.Recorder, "AddPermission", input); ok {
+		return out, err
+	}
+	return &lambda.AddPermissionOutput{}, nil
+}
+
+func (d DummyLambdaClient) RemovePermission(ctx context.Context, input *lambda.RemovePermissionInput, opts ...func(*lambda.Options)) (*lambda.RemovePermissionOutput, error) {
+	if out, err, ok := intercept[*lambda.RemovePermissionOutput](ctx, d.Recorder, "RemovePermission", input); ok {
+		return out, err
+	}
+	return &lambda.RemovePermissionOutput{}, nil
+}
+
+func (d DummyLambdaClient) DeleteFunction(ctx context.Context, input *lambda.DeleteFunctionInput, opts ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error) {
+	if out, err, ok := intercept[*lambda.DeleteFunctionOutput](ctx, d.Recorder, "DeleteFunction", input); ok {
+		return out, err
+	}
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+func (d DummyLambdaClient) DeleteFunctionUrlConfig(ctx context.Context, input *lambda.DeleteFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.DeleteFunctionUrlConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.DeleteFunctionUrlConfigOutput](ctx, d.Recorder, "DeleteFunctionUrlConfig", input); ok {
+		return out, err
+	}
+	if _, ok := d.FunctionURLs[aws.ToString(input.FunctionName)]; !ok {
+		return nil, new(types.ResourceNotFoundException)
+	}
+	delete(d.FunctionURLs, aws.ToString(input.FunctionName))
+	return &lambda.DeleteFunctionUrlConfigOutput{}, nil
+}
+
+func (d DummyLambdaClient) GetFunctionConfiguration(ctx context.Context, input *lambda.GetFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	if out, err, ok := intercept[*lambda.GetFunctionConfigurationOutput](ctx, d.Recorder, "GetFunctionConfiguration", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	out := &lambda.GetFunctionConfigurationOutput{
+		FunctionName:     input.FunctionName,
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}
+	if d.Environment != nil {
+		out.Environment = &types.EnvironmentResponse{Variables: d.Environment}
+	}
+	return out, nil
+}
+
+func (d DummyLambdaClient) UpdateFunctionConfiguration(ctx context.Context, input *lambda.UpdateFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	if out, err, ok := intercept[*lambda.UpdateFunctionConfigurationOutput](ctx, d.Recorder, "UpdateFunctionConfiguration", input); ok {
+		return out, err
+	}
+	if d.ConfigUpdates != nil {
+		atomic.AddInt32(d.ConfigUpdates, 1)
+	}
+	return &lambda.UpdateFunctionConfigurationOutput{}, d.Err
+}
+
+func (d DummyLambdaClient) ListTags(ctx context.Context, input *lambda.ListTagsInput, opts ...func(*lambda.Options)) (*lambda.ListTagsOutput, error) {
+	if out, err, ok := intercept[*lambda.ListTagsOutput](ctx, d.Recorder, "ListTags", input); ok {
+		return out, err
+	}
+	return &lambda.ListTagsOutput{Tags: d.Tags}, d.Err
+}
+
+func (d DummyLambdaClient) TagResource(ctx context.Context, input *lambda.TagResourceInput, opts ...func(*lambda.Options)) (*lambda.TagResourceOutput, error) {
+	if out, err, ok := intercept[*lambda.TagResourceOutput](ctx, d.Recorder, "TagResource", input); ok {
+		return out, err
+	}
+	return &lambda.TagResourceOutput{}, d.Err
+}
+
+func (d DummyLambdaClient) UntagResource(ctx context.Context, input *lambda.UntagResourceInput, opts ...func(*lambda.Options)) (*lambda.UntagResourceOutput, error) {
+	if out, err, ok := intercept[*lambda.UntagResourceOutput](ctx, d.Recorder, "UntagResource", input); ok {
+		return out, err
+	}
+	return &lambda.UntagResourceOutput{}, d.Err
+}
+
+func (d DummyLambdaClient) GetFunctionUrlConfig(ctx context.Context, input *lambda.GetFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionUrlConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.GetFunctionUrlConfigOutput](ctx, d.Recorder, "GetFunctionUrlConfig", input); ok {
+		return out, err
+	}
+	url, ok := d.FunctionURLs[aws.ToString(input.FunctionName)]
+	if !ok {
+		return nil, new(types.ResourceNotFoundException)
+	}
+	return &lambda.GetFunctionUrlConfigOutput{FunctionUrl: aws.String(url)}, nil
+}
+
+func (d DummyLambdaClient) CreateFunctionUrlConfig(ctx context.Context, input *lambda.CreateFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionUrlConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.CreateFunctionUrlConfigOutput](ctx, d.Recorder, "CreateFunctionUrlConfig", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	url := "https://" + strings.ToLower(aws.ToString(input.FunctionName)) + ".lambda-url." + Region + ".on.aws/"
+	if d.FunctionURLs != nil {
+		d.FunctionURLs[aws.ToString(input.FunctionName)] = url
+	}
+	return &lambda.CreateFunctionUrlConfigOutput{FunctionUrl: aws.String(url), AuthType: input.AuthType}, nil
+}
+
+func (d DummyLambdaClient) UpdateFunctionUrlConfig(ctx context.Context, input *lambda.UpdateFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionUrlConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.UpdateFunctionUrlConfigOutput](ctx, d.Recorder, "UpdateFunctionUrlConfig", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return &lambda.UpdateFunctionUrlConfigOutput{
+		FunctionUrl: aws.String(d.FunctionURLs[aws.ToString(input.FunctionName)]),
+		AuthType:    input.AuthType,
+	}, nil
+}
+
+func (d DummyLambdaClient) ListEventSourceMappings(ctx context.Context, input *lambda.ListEventSourceMappingsInput, opts ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+	if out, err, ok := intercept[*lambda.ListEventSourceMappingsOutput](ctx, d.Recorder, "ListEventSourceMappings", input); ok {
+		return out, err
+	}
+	return &lambda.ListEventSourceMappingsOutput{}, nil
+}
+
+func (d DummyLambdaClient) CreateEventSourceMapping(ctx context.Context, input *lambda.CreateEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error) {
+	if out, err, ok := intercept[*lambda.CreateEventSourceMappingOutput](ctx, d.Recorder, "CreateEventSourceMapping", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return &lambda.CreateEventSourceMappingOutput{
+		UUID:           aws.String("mapping-" + strings.ToLower(aws.ToString(input.FunctionName))),
+		EventSourceArn: input.EventSourceArn,
+		BatchSize:      input.BatchSize,
+	}, nil
+}
+
+func (d DummyLambdaClient) UpdateEventSourceMapping(ctx context.Context, input *lambda.UpdateEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error) {
+	if out, err, ok := intercept[*lambda.UpdateEventSourceMappingOutput](ctx, d.Recorder, "UpdateEventSourceMapping", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return &lambda.UpdateEventSourceMappingOutput{UUID: input.UUID, BatchSize: input.BatchSize}, nil
+}
+
+func (d DummyLambdaClient) DeleteEventSourceMapping(ctx context.Context, input *lambda.DeleteEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error) {
+	if out, err, ok := intercept[*lambda.DeleteEventSourceMappingOutput](ctx, d.Recorder, "DeleteEventSourceMapping", input); ok {
+		return out, err
+	}
+	return &lambda.DeleteEventSourceMappingOutput{UUID: input.UUID}, nil
+}
+
+// GetAlias reports that no alias exists, unless programmed with the
+// [Recorder].
+func (d DummyLambdaClient) GetAlias(ctx context.Context, input *lambda.GetAliasInput, opts ...func(*lambda.Options)) (*lambda.GetAliasOutput, error) {
+	if out, err, ok := intercept[*lambda.GetAliasOutput](ctx, d.Recorder, "GetAlias", input); ok {
+		return out, err
+	}
+	return nil, new(types.ResourceNotFoundException)
+}
+
+func (d DummyLambdaClient) CreateAlias(ctx context.Context, input *lambda.CreateAliasInput, opts ...func(*lambda.Options)) (*lambda.CreateAliasOutput, error) {
+	if out, err, ok := intercept[*lambda.CreateAliasOutput](ctx, d.Recorder, "CreateAlias", input); ok {
+		return out, err
+	}
+	return &lambda.CreateAliasOutput{
+		Name:            input.Name,
+		FunctionVersion: input.FunctionVersion,
+		AliasArn:        aws.String(functionARN(aws.ToString(input.FunctionName)) + ":" + aws.ToString(input.Name)),
+	}, nil
+}
+
+func (d DummyLambdaClient) UpdateAlias(ctx context.Context, input *lambda.UpdateAliasInput, opts ...func(*lambda.Options)) (*lambda.UpdateAliasOutput, error) {
+	if out, err, ok := intercept[*lambda.UpdateAliasOutput](ctx, d.Recorder, "UpdateAlias", input); ok {
+		return out, err
+	}
+	return &lambda.UpdateAliasOutput{
+		Name:            input.Name,
+		FunctionVersion: input.FunctionVersion,
+		RoutingConfig:   input.RoutingConfig,
+		AliasArn:        aws.String(functionARN(aws.ToString(input.FunctionName)) + ":" + aws.ToString(input.Name)),
+	}, nil
+}
+
+func (d DummyLambdaClient) DeleteAlias(ctx context.Context, input *lambda.DeleteAliasInput, opts ...func(*lambda.Options)) (*lambda.DeleteAliasOutput, error) {
+	if out, err, ok := intercept[*lambda.DeleteAliasOutput](ctx, d.Recorder, "DeleteAlias", input); ok {
+		return out, err
+	}
+	return &lambda.DeleteAliasOutput{}, nil
+}
+
+// GetPolicy reports that the function has no resource policy, unless
+// programmed with the [Recorder].
+func (d DummyLambdaClient) GetPolicy(ctx context.Context, input *lambda.GetPolicyInput, opts ...func(*lambda.Options)) (*lambda.GetPolicyOutput, error) {
+	if out, err, ok := intercept[*lambda.GetPolicyOutput](ctx, d.Recorder, "GetPolicy", input); ok {
+		return out, err
+	}
+	return nil, new(types.ResourceNotFoundException)
+}
+
+// GetFunctionEventInvokeConfig reports that the function has no asynchronous
+// invocation config, unless programmed with the [Recorder].
+func (d DummyLambdaClient) GetFunctionEventInvokeConfig(ctx context.Context, input *lambda.GetFunctionEventInvokeConfigInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionEventInvokeConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.GetFunctionEventInvokeConfigOutput](ctx, d.Recorder, "GetFunctionEventInvokeConfig", input); ok {
+		return out, err
+	}
+	return nil, new(types.ResourceNotFoundException)
+}
+
+func (d DummyLambdaClient) PutFunctionEventInvokeConfig(ctx context.Context, input *lambda.PutFunctionEventInvokeConfigInput, opts ...func(*lambda.Options)) (*lambda.PutFunctionEventInvokeConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.PutFunctionEventInvokeConfigOutput](ctx, d.Recorder, "PutFunctionEventInvokeConfig", input); ok {
+		return out, err
+	}
+	return &lambda.PutFunctionEventInvokeConfigOutput{
+		FunctionArn:       aws.String(functionARN(aws.ToString(input.FunctionName))),
+		DestinationConfig: input.DestinationConfig,
+	}, nil
+}
+
+func (d DummyLambdaClient) PutFunctionConcurrency(ctx context.Context, input *lambda.PutFunctionConcurrencyInput, opts ...func(*lambda.Options)) (*lambda.PutFunctionConcurrencyOutput, error) {
+	if out, err, ok := intercept[*lambda.PutFunctionConcurrencyOutput](ctx, d.Recorder, "PutFunctionConcurrency", input); ok {
+		return out, err
+	}
+	return &lambda.PutFunctionConcurrencyOutput{ReservedConcurrentExecutions: input.ReservedConcurrentExecutions}, nil
+}
+
+func (d DummyLambdaClient) PutProvisionedConcurrencyConfig(ctx context.Context, input *lambda.PutProvisionedConcurrencyConfigInput, opts ...func(*lambda.Options)) (*lambda.PutProvisionedConcurrencyConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.PutProvisionedConcurrencyConfigOutput](ctx, d.Recorder, "PutProvisionedConcurrencyConfig", input); ok {
+		return out, err
+	}
+	return &lambda.PutProvisionedConcurrencyConfigOutput{
+		RequestedProvisionedConcurrentExecutions: input.ProvisionedConcurrentExecutions,
+		Status:                                   types.ProvisionedConcurrencyStatusEnumInProgress,
+	}, nil
+}
+
+// GetProvisionedConcurrencyConfig reports provisioned concurrency as ready,
+// unless programmed with the [Recorder].
+func (d DummyLambdaClient) GetProvisionedConcurrencyConfig(ctx context.Context, input *lambda.GetProvisionedConcurrencyConfigInput, opts ...func(*lambda.Options)) (*lambda.GetProvisionedConcurrencyConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.GetProvisionedConcurrencyConfigOutput](ctx, d.Recorder, "GetProvisionedConcurrencyConfig", input); ok {
+		return out, err
+	}
+	return &lambda.GetProvisionedConcurrencyConfigOutput{Status: types.ProvisionedConcurrencyStatusEnumReady}, nil
+}
+
+// DeleteProvisionedConcurrencyConfig reports that the alias has no
+// provisioned concurrency, unless programmed with the [Recorder].
+func (d DummyLambdaClient) DeleteProvisionedConcurrencyConfig(ctx context.Context, input *lambda.DeleteProvisionedConcurrencyConfigInput, opts ...func(*lambda.Options)) (*lambda.DeleteProvisionedConcurrencyConfigOutput, error) {
+	if out, err, ok := intercept[*lambda.DeleteProvisionedConcurrencyConfigOutput](ctx, d.Recorder, "DeleteProvisionedConcurrencyConfig", input); ok {
+		return out, err
+	}
+	return nil, &types.ProvisionedConcurrencyConfigNotFoundException{}
+}
+
+func functionARN(name string) string {
+	return "arn:aws:lambda:" + Region + ":" + AccountID + ":function:" + name
+}
+
+// DummyIAMClient is a fake [glambda.IAMClient].
+type DummyIAMClient struct {
+	Recorder   *Recorder
+	RoleExists bool
+	RoleName   string
+	RoleTags   map[string]string
+	Counter    *int32
+	// AccountAlias is the alias of the account, if it has one.
+	AccountAlias string
+}
+
+// IncrementCounter is kept for existing tests, a [Recorder] gives a richer view of calls made.
+func (d DummyIAMClient) IncrementCounter() {
+	if d.Counter != nil {
+		atomic.AddInt32(d.Counter, 1)
+	}
+}
+
+func (d DummyIAMClient) CreateRole(ctx context.Context, input *iam.CreateRoleInput, opts ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
+	if out, err, ok := intercept[*iam.CreateRoleOutput](ctx, d.Recorder, "CreateRole", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	if d.RoleExists {
+		return &iam.CreateRoleOutput{}, new(iTypes.EntityAlreadyExistsException)
+	}
+	return &iam.CreateRoleOutput{}, nil
+}
+
+func (d DummyIAMClient) AttachRolePolicy(ctx context.Context, input *iam.AttachRolePolicyInput, opts ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error) {
+	if out, err, ok := intercept[*iam.AttachRolePolicyOutput](ctx, d.Recorder, "AttachRolePolicy", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (d DummyIAMClient) PutRolePolicy(ctx context.Context, input *iam.PutRolePolicyInput, opts ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error) {
+	if out, err, ok := intercept[*iam.PutRolePolicyOutput](ctx, d.Recorder, "PutRolePolicy", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	return &iam.PutRolePolicyOutput{}, nil
+}
+
+func (d DummyIAMClient) GetRole(ctx context.Context, input *iam.GetRoleInput, opts ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	if out, err, ok := intercept[*iam.GetRoleOutput](ctx, d.Recorder, "GetRole", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	if d.RoleExists {
+		role := &iTypes.Role{
+			RoleName: aws.String(d.RoleName),
+		}
+		for k, v := range d.RoleTags {
+			role.Tags = append(role.Tags, iTypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		return &iam.GetRoleOutput{Role: role}, nil
+	}
+	return &iam.GetRoleOutput{}, new(iTypes.NoSuchEntityException)
+}
+
+func (d DummyIAMClient) ListAttachedRolePolicies(ctx context.Context, input *iam.ListAttachedRolePoliciesInput, opts ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	if out, err, ok := intercept[*iam.ListAttachedRolePoliciesOutput](ctx, d.Recorder, "ListAttachedRolePolicies", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	return &iam.ListAttachedRolePoliciesOutput{
+		AttachedPolicies: []iTypes.AttachedPolicy{
+			{PolicyArn: aws.String("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole")},
+		},
+	}, nil
+}
+
+func (d DummyIAMClient) ListRolePolicies(ctx context.Context, input *iam.ListRolePoliciesInput, opts ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	if out, err, ok := intercept[*iam.ListRolePoliciesOutput](ctx, d.Recorder, "ListRolePolicies", input); ok {
+		return out, err
+	}
+	return &iam.ListRolePoliciesOutput{}, nil
+}
+
+func (d DummyIAMClient) TagRole(ctx context.Context, input *iam.TagRoleInput, opts ...func(*iam.Options)) (*iam.TagRoleOutput, error) {
+	if out, err, ok := intercept[*iam.TagRoleOutput](ctx, d.Recorder, "TagRole", input); ok {
+		return out, err
+	}
+	return &iam.TagRoleOutput{}, nil
+}
+
+func (d DummyIAMClient) UntagRole(ctx context.Context, input *iam.UntagRoleInput, opts ...func(*iam.Options)) (*iam.UntagRoleOutput, error) {
+	if out, err, ok := intercept[*iam.UntagRoleOutput](ctx, d.Recorder, "UntagRole", input); ok {
+		return out, err
+	}
+	return &iam.UntagRoleOutput{}, nil
+}
+
+func (d DummyIAMClient) DetachRolePolicy(ctx context.Context, input *iam.DetachRolePolicyInput, opts ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	if out, err, ok := intercept[*iam.DetachRolePolicyOutput](ctx, d.Recorder, "DetachRolePolicy", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+func (d DummyIAMClient) DeleteRolePolicy(ctx context.Context, input *iam.DeleteRolePolicyInput, opts ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error) {
+	if out, err, ok := intercept[*iam.DeleteRolePolicyOutput](ctx, d.Recorder, "DeleteRolePolicy", input); ok {
+		return out, err
+	}
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
+
+func (d DummyIAMClient) DeleteRole(ctx context.Context, input *iam.DeleteRoleInput, opts ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	if out, err, ok := intercept[*iam.DeleteRoleOutput](ctx, d.Recorder, "DeleteRole", input); ok {
+		return out, err
+	}
+	d.IncrementCounter()
+	if !d.RoleExists {
+		return nil, new(iTypes.NoSuchEntityException)
+	}
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+func (d DummyIAMClient) ListAccountAliases(ctx context.Context, input *iam.ListAccountAliasesInput, opts ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error) {
+	if out, err, ok := intercept[*iam.ListAccountAliasesOutput](ctx, d.Recorder, "ListAccountAliases", input); ok {
+		return out, err
+	}
+	var aliases []string
+	if d.AccountAlias != "" {
+		aliases = append(aliases, d.AccountAlias)
+	}
+	return &iam.ListAccountAliasesOutput{AccountAliases: aliases}, nil
+}
+
+// DummySTSClient is a fake [glambda.STSClient] reporting a fixed account ID.
+type DummySTSClient struct {
+	Recorder  *Recorder
+	AccountID string
+	Err       error
+}
+
+func (d DummySTSClient) GetCallerIdentity(ctx context.Context, input *sts.GetCallerIdentityInput, opts ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if out, err, ok := intercept[*sts.GetCallerIdentityOutput](ctx, d.Recorder, "GetCallerIdentity", input); ok {
+		return out, err
+	}
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(d.AccountID),
+		Arn:     aws.String("arn:aws:iam::" + d.AccountID + ":user/sandbox"),
+	}, nil
+
+}
+
+// DummyCloudWatchLogsClient is a fake [glambda.CloudWatchLogsClient] whose
+// Logs Insights queries return the configured Results.
+type DummyCloudWatchLogsClient struct {
+	Recorder     *Recorder
+	Results      [][]lTypes.ResultField
+	RunningPolls *int
+	Status       lTypes.QueryStatus
+	QueryString  *string
+}
+
+func (d DummyCloudWatchLogsClient) StartQuery(ctx context.Context, input *cloudwatchlogs.StartQueryInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.StartQueryOutput](ctx, d.Recorder, "StartQuery", input); ok {
+		return out, err
+	}
+	if d.QueryString != nil {
+		*d.QueryString = aws.ToString(input.QueryString)
+	}
+	return &cloudwatchlogs.StartQueryOutput{
+		QueryId: aws.String("query-1234"),
+	}, nil
+}
+
+func (d DummyCloudWatchLogsClient) GetQueryResults(ctx context.Context, input *cloudwatchlogs.GetQueryResultsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.GetQueryResultsOutput](ctx, d.Recorder, "GetQueryResults", input); ok {
+		return out, err
+	}
+	if d.RunningPolls != nil && *d.RunningPolls > 0 {
+		*d.RunningPolls--
+		return &cloudwatchlogs.GetQueryResultsOutput{Status: lTypes.QueryStatusRunning}, nil
+	}
+	status := d.Status
+	if status == "" {
+		status = lTypes.QueryStatusComplete
+	}
+	return &cloudwatchlogs.GetQueryResultsOutput{
+		Status:  status,
+		Results: d.Results,
+	}, nil
+}
+
+// DummyAPIGatewayClient is a fake [glambda.APIGatewayClient]. APIs have no
+// integrations or routes unless they are programmed with the [Recorder].
+type DummyAPIGatewayClient struct {
+	Recorder *Recorder
+	// APIs holds the HTTP APIs that exist, mapping name to API ID, all
+	// tagged as created by glambda. APIs created through the client are
+	// added to it, and deleted ones removed, when it isn't nil.
+	APIs map[string]string
+}
+
+func (d DummyAPIGatewayClient) GetApis(ctx context.Context, input *apigatewayv2.GetApisInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.GetApisOutput](ctx, d.Recorder, "GetApis", input); ok {
+		return out, err
+	}
+	var items []gTypes.Api
+	for name, id := range d.APIs {
+		items = append(items, gTypes.Api{
+			ApiId:        aws.String(id),
+			Name:         aws.String(name),
+			ProtocolType: gTypes.ProtocolTypeHttp,
+			ApiEndpoint:  aws.String(apiEndpoint(id)),
+			Tags:         map[string]string{"glambda:managed": "true"},
+		})
+	}
+	return &apigatewayv2.GetApisOutput{Items: items}, nil
+}
+
+func (d DummyAPIGatewayClient) CreateApi(ctx context.Context, input *apigatewayv2.CreateApiInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateApiOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.CreateApiOutput](ctx, d.Recorder, "CreateApi", input); ok {
+		return out, err
+	}
+	id := "api" + strings.ToLower(aws.ToString(input.Name))
+	if d.APIs != nil {
+		d.APIs[aws.ToString(input.Name)] = id
+	}
+	return &apigatewayv2.CreateApiOutput{
+		ApiId:       aws.String(id),
+		Name:        input.Name,
+		ApiEndpoint: aws.String(apiEndpoint(id)),
+	}, nil
+}
+
+func (d DummyAPIGatewayClient) GetIntegrations(ctx context.Context, input *apigatewayv2.GetIntegrationsInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.GetIntegrationsOutput](ctx, d.Recorder, "GetIntegrations", input); ok {
+		return out, err
+	}
+	return &apigatewayv2.GetIntegrationsOutput{}, nil
+}
+
+func (d DummyAPIGatewayClient) CreateIntegration(ctx context.Context, input *apigatewayv2.CreateIntegrationInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateIntegrationOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.CreateIntegrationOutput](ctx, d.Recorder, "CreateIntegration", input); ok {
+		return out, err
+	}
+	return &apigatewayv2.CreateIntegrationOutput{
+		IntegrationId:  aws.String("integration1"),
+		IntegrationUri: input.IntegrationUri,
+	}, nil
+}
+
+func (d DummyAPIGatewayClient) GetRoutes(ctx context.Context, input *apigatewayv2.GetRoutesInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.GetRoutesOutput](ctx, d.Recorder, "GetRoutes", input); ok {
+		return out, err
+	}
+	return &apigatewayv2.GetRoutesOutput{}, nil
+}
+
+func (d DummyAPIGatewayClient) CreateRoute(ctx context.Context, input *apigatewayv2.CreateRouteInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateRouteOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.CreateRouteOutput](ctx, d.Recorder, "CreateRoute", input); ok {
+		return out, err
+	}
+	return &apigatewayv2.CreateRouteOutput{RouteKey: input.RouteKey, Target: input.Target}, nil
+}
+
+func (d DummyAPIGatewayClient) CreateStage(ctx context.Context, input *apigatewayv2.CreateStageInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateStageOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.CreateStageOutput](ctx, d.Recorder, "CreateStage", input); ok {
+		return out, err
+	}
+	return &apigatewayv2.CreateStageOutput{StageName: input.StageName, AutoDeploy: input.AutoDeploy}, nil
+}
+
+func (d DummyAPIGatewayClient) DeleteApi(ctx context.Context, input *apigatewayv2.DeleteApiInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.DeleteApiOutput, error) {
+	if out, err, ok := intercept[*apigatewayv2.DeleteApiOutput](ctx, d.Recorder, "DeleteApi", input); ok {
+		return out, err
+	}
+	for name, id := range d.APIs {
+		if id == aws.ToString(input.ApiId) {
+			delete(d.APIs, name)
+		}
+	}
+	return &apigatewayv2.DeleteApiOutput{}, nil
+}
+
+func apiEndpoint(id string) string {
+	return "https://" + id + ".execute-api." + Region + ".amazonaws.com"
+}
+
+func (d DummyS3Client) PutObject(ctx context.Context, input *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if out, err, ok := intercept[*s3.PutObjectOutput](ctx, d.Recorder, "PutObject", input); ok {
+		return out, err
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (d DummyS3Client) CreateMultipartUpload(ctx context.Context, input *s3.CreateMultipartUploadInput, opts ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	if out, err, ok := intercept[*s3.CreateMultipartUploadOutput](ctx, d.Recorder, "CreateMultipartUpload", input); ok {
+		return out, err
+	}
+	return &s3.CreateMultipartUploadOutput{Bucket: input.Bucket, Key: input.Key, UploadId: aws.String("upload1")}, nil
+}
+
+func (d DummyS3Client) UploadPart(ctx context.Context, input *s3.UploadPartInput, opts ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if out, err, ok := intercept[*s3.UploadPartOutput](ctx, d.Recorder, "UploadPart", input); ok {
+		return out, err
+	}
+	return &s3.UploadPartOutput{ETag: aws.String(fmt.Sprintf("etag%d", aws.ToInt32(input.PartNumber)))}, nil
+}
+
+func (d DummyS3Client) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput, opts ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	if out, err, ok := intercept[*s3.CompleteMultipartUploadOutput](ctx, d.Recorder, "CompleteMultipartUpload", input); ok {
+		return out, err
+	}
+	return &s3.CompleteMultipartUploadOutput{Bucket: input.Bucket, Key: input.Key}, nil
+}
+
+func (d DummyS3Client) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput, opts ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	if out, err, ok := intercept[*s3.AbortMultipartUploadOutput](ctx, d.Recorder, "AbortMultipartUpload", input); ok {
+		return out, err
+	}
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// ListObjectsV2 lists no objects unless programmed with the [Recorder].
+func (d DummyS3Client) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	if out, err, ok := intercept[*s3.ListObjectsV2Output](ctx, d.Recorder, "ListObjectsV2", input); ok {
+		return out, err
+	}
+	return &s3.ListObjectsV2Output{}, nil
+}
+
+func (d DummyS3Client) GetObject(ctx context.Context, input *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if out, err, ok := intercept[*s3.GetObjectOutput](ctx, d.Recorder, "GetObject", input); ok {
+		return out, err
+	}
+	return nil, &sTypes.NoSuchKey{Message: aws.String("no such key " + aws.ToString(input.Key))}
+}
+
+// FilterLogEvents returns no log events unless programmed with the [Recorder].
+func (d DummyCloudWatchLogsClient) FilterLogEvents(ctx context.Context, input *cloudwatchlogs.FilterLogEventsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.FilterLogEventsOutput](ctx, d.Recorder, "FilterLogEvents", input); ok {
+		return out, err
+	}
+	return &cloudwatchlogs.FilterLogEventsOutput{}, nil
+}
+
+func (d DummyCloudWatchLogsClient) DeleteLogGroup(ctx context.Context, input *cloudwatchlogs.DeleteLogGroupInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.DeleteLogGroupOutput](ctx, d.Recorder, "DeleteLogGroup", input); ok {
+		return out, err
+	}
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
+
+func (d DummyCloudWatchLogsClient) CreateLogStream(ctx context.Context, input *cloudwatchlogs.CreateLogStreamInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.CreateLogStreamOutput](ctx, d.Recorder, "CreateLogStream", input); ok {
+		return out, err
+	}
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (d DummyCloudWatchLogsClient) PutLogEvents(ctx context.Context, input *cloudwatchlogs.PutLogEventsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.PutLogEventsOutput](ctx, d.Recorder, "PutLogEvents", input); ok {
+		return out, err
+	}
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+func (d DummyCloudWatchLogsClient) GetLogEvents(ctx context.Context, input *cloudwatchlogs.GetLogEventsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	if out, err, ok := intercept[*cloudwatchlogs.GetLogEventsOutput](ctx, d.Recorder, "GetLogEvents", input); ok {
+		return out, err
+	}
+	return &cloudwatchlogs.GetLogEventsOutput{}, nil
+}
+
+// DummyDynamoDBClient is a fake [glambda.DynamoDBClient]. Tables are empty
+// unless programmed with the [Recorder].
+type DummyDynamoDBClient struct {
+	Recorder *Recorder
+}
+
+func (d DummyDynamoDBClient) PutItem(ctx context.Context, input *dynamodb.PutItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if out, err, ok := intercept[*dynamodb.PutItemOutput](ctx, d.Recorder, "PutItem", input); ok {
+		return out, err
+	}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (d DummyDynamoDBClient) Query(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if out, err, ok := intercept[*dynamodb.QueryOutput](ctx, d.Recorder, "Query", input); ok {
+		return out, err
+	}
+	return &dynamodb.QueryOutput{}, nil
+}
+
+// DummyCloudWatchClient is a fake [glambda.CloudWatchClient]. Metrics have
+// no data points unless programmed with the [Recorder].
+type DummyCloudWatchClient struct {
+	Recorder *Recorder
+}
+
+func (d DummyCloudWatchClient) GetMetricData(ctx context.Context, input *cloudwatch.GetMetricDataInput, opts ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	if out, err, ok := intercept[*cloudwatch.GetMetricDataOutput](ctx, d.Recorder, "GetMetricData", input); ok {
+		return out, err
+	}
+	return &cloudwatch.GetMetricDataOutput{}, nil
+}
+
+// DummyS3Client is a fake [glambda.S3Client]. Buckets have no notifications
+// unless they are programmed with the [Recorder].
+type DummyS3Client struct {
+	Recorder *Recorder
+}
+
+func (d DummyS3Client) GetBucketNotificationConfiguration(ctx context.Context, input *s3.GetBucketNotificationConfigurationInput, opts ...func(*s3.Options)) (*s3.GetBucketNotificationConfigurationOutput, error) {
+	if out, err, ok := intercept[*s3.GetBucketNotificationConfigurationOutput](ctx, d.Recorder, "GetBucketNotificationConfiguration", input); ok {
+		return out, err
+	}
+	return &s3.GetBucketNotificationConfigurationOutput{}, nil
+}
+
+func (d DummyS3Client) PutBucketNotificationConfiguration(ctx context.Context, input *s3.PutBucketNotificationConfigurationInput, opts ...func(*s3.Options)) (*s3.PutBucketNotificationConfigurationOutput, error) {
+	if out, err, ok := intercept[*s3.PutBucketNotificationConfigurationOutput](ctx, d.Recorder, "PutBucketNotificationConfiguration", input); ok {
+		return out, err
+	}
+	return &s3.PutBucketNotificationConfigurationOutput{}, nil
+}
+
+// GetBucketEncryption reports the default SSE-S3 encryption that every bucket
+// has, unless programmed with the [Recorder].
+func (d DummyS3Client) GetBucketEncryption(ctx context.Context, input *s3.GetBucketEncryptionInput, opts ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	if out, err, ok := intercept[*s3.GetBucketEncryptionOutput](ctx, d.Recorder, "GetBucketEncryption", input); ok {
+		return out, err
+	}
+	return &s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: &sTypes.ServerSideEncryptionConfiguration{
+			Rules: []sTypes.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &sTypes.ServerSideEncryptionByDefault{
+					SSEAlgorithm: sTypes.ServerSideEncryptionAes256,
+				},
+			}},
+		},
+	}, nil
+}
+
+// DummySQSClient is a fake [glambda.SQSClient]. Every queue exists, in the
+// sandbox account and region, with SQS's default visibility timeout of 30
+// seconds and no other attributes set, unless programmed with the [Recorder].
+type DummySQSClient struct {
+	Recorder *Recorder
+}
+
+func (d DummySQSClient) GetQueueUrl(ctx context.Context, input *sqs.GetQueueUrlInput, opts ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	if out, err, ok := intercept[*sqs.GetQueueUrlOutput](ctx, d.Recorder, "GetQueueUrl", input); ok {
+		return out, err
+	}
+	account := aws.ToString(input.QueueOwnerAWSAccountId)
+	if account == "" {
+		account = AccountID
+	}
+	return &sqs.GetQueueUrlOutput{
+		QueueUrl: aws.String(fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/%s", Region, account, aws.ToString(input.QueueName))),
+	}, nil
+}
+
+func (d DummySQSClient) GetQueueAttributes(ctx context.Context, input *sqs.GetQueueAttributesInput, opts ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	if out, err, ok := intercept[*sqs.GetQueueAttributesOutput](ctx, d.Recorder, "GetQueueAttributes", input); ok {
+		return out, err
+	}
+	attributes := map[string]string{}
+	for _, name := range input.AttributeNames {
+		if name == qTypes.QueueAttributeNameVisibilityTimeout || name == qTypes.QueueAttributeNameAll {
+			attributes[string(qTypes.QueueAttributeNameVisibilityTimeout)] = "30"
+		}
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: attributes}, nil
+}
+
+func (d DummySQSClient) SetQueueAttributes(ctx context.Context, input *sqs.SetQueueAttributesInput, opts ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) {
+	if out, err, ok := intercept[*sqs.SetQueueAttributesOutput](ctx, d.Recorder, "SetQueueAttributes", input); ok {
+		return out, err
+	}
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummyLambdaClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummyIAMClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummySTSClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummyCloudWatchLogsClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummyAPIGatewayClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummyCloudWatchClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummyS3Client) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
+
+// InjectFault implements glambda.FaultInjector using the client's [Recorder].
+func (d DummySQSClient) InjectFault(operation string, err error, times int) bool {
+	return injectFault(d, d.Recorder, operation, err, times)
+}
@@ -0,0 +1,134 @@
+// Package glambdatest provides fake AWS clients for testing code that deploys
+// lambda functions with glambda, without needing AWS credentials.
+//
+// The Dummy clients implement the client interfaces from the glambda package
+// ([glambda.LambdaClient], [glambda.IAMClient] and friends). Each has a few
+// fields for the common behaviours, such as whether a function already exists.
+// For anything else, attach a [Recorder]. It records every call, can return
+// programmed responses for an operation, and can inject failures.
+package glambdatest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Call is a single recorded operation made against a Dummy client.
+type Call struct {
+	Operation string
+	Input     any
+}
+
+// Recorder records calls made against the Dummy clients it is attached to,
+// and lets tests program the response or failure for any operation. A single
+// Recorder can be shared between several clients, giving one ordered log of
+// every call made during a deploy.
+//
+// Operations are named after the AWS SDK method, e.g. "CreateFunction".
+type Recorder struct {
+	mu        sync.Mutex
+	calls     []Call
+	failures  map[string][]error
+	responses map[string]any
+}
+
+// NewRecorder returns an empty [Recorder].
+func NewRecorder() *Recorder {
+	return &Recorder{
+		failures:  map[string][]error{},
+		responses: map[string]any{},
+	}
+}
+
+// Calls returns the recorded calls in the order they were made. If any
+// operations are given, only calls to those operations are returned.
+func (r *Recorder) Calls(operations ...string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(operations) == 0 {
+		return append([]Call(nil), r.calls...)
+	}
+	var calls []Call
+	for _, c := range r.calls {
+		for _, op := range operations {
+			if c.Operation == op {
+				calls = append(calls, c)
+			}
+		}
+	}
+	return calls
+}
+
+// Operations returns just the names of the recorded operations, in order.
+func (r *Recorder) Operations() []string {
+	var ops []string
+	for _, c := range r.Calls() {
+		ops = append(ops, c.Operation)
+	}
+	return ops
+}
+
+// FailNext makes the next n calls to the operation return err. Calls after
+// that behave normally again, which makes it suitable for simulating
+// transient failures.
+func (r *Recorder) FailNext(operation string, err error, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := 0; i < n; i++ {
+		r.failures[operation] = append(r.failures[operation], err)
+	}
+}
+
+// Respond programs the output returned by every call to the operation. The
+// output must be the SDK output type for that operation, for example a
+// *lambda.GetFunctionOutput for "GetFunction".
+func (r *Recorder) Respond(operation string, output any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[operation] = output
+}
+
+// intercept records a call and reports whether a programmed failure or
+// response should be returned in place of the client's default behaviour.
+func intercept[T any](ctx context.Context, r *Recorder, operation string, input any) (T, error, bool) {
+	var zero T
+	// Like the SDK, a call made after its context is done fails unsent.
+	if ctx.Err() != nil {
+		return zero, ctx.Err(), true
+	}
+	if r == nil {
+		return zero, nil, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Operation: operation, Input: input})
+	if queued := r.failures[operation]; len(queued) > 0 {
+		r.failures[operation] = queued[1:]
+		return zero, queued[0], true
+	}
+	response, ok := r.responses[operation]
+	if !ok {
+		return zero, nil, false
+	}
+	output, ok := response.(T)
+	if !ok {
+		return zero, fmt.Errorf("glambdatest: programmed response for %s is %T, want %T", operation, response, zero), true
+	}
+	return output, nil, true
+}
+
+// injectFault implements [glambda.FaultInjector] for the Dummy clients. A
+// client only accepts faults for operations it has a method for, so that a
+// Recorder shared by several clients is only programmed once.
+func injectFault(client any, r *Recorder, operation string, err error, times int) bool {
+	if r == nil {
+		return false
+	}
+	if _, ok := reflect.TypeOf(client).MethodByName(operation); !ok {
+		return false
+	}
+	r.FailNext(operation, err, times)
+	return true
+}
@@ -0,0 +1,83 @@
+package glambdatest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestRecorder_RecordsEveryCallMadeDuringADeploy(t *testing.T) {
+	t.Parallel()
+	r := glambdatest.NewRecorder()
+	err := glambda.Deploy("recorded", "../testdata/correct_test_handler/main.go", glambdatest.SandboxWithRecorder(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GetCallerIdentity",
+		"GetRole",
+		"GetFunction",
+		"CreateRole",
+		"AttachRolePolicy",
+		"AttachRolePolicy",
+		"CreateFunction",
+		"PublishVersion",
+		"Invoke",
+	}
+	got := r.Operations()
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestRecorder_FailNextInjectsTransientFailures(t *testing.T) {
+	t.Parallel()
+	r := glambdatest.NewRecorder()
+	boom := errors.New("boom")
+	r.FailNext("Invoke", boom, 2)
+	client := glambdatest.DummyLambdaClient{Recorder: r}
+	for i := 0; i < 2; i++ {
+		_, err := client.Invoke(context.Background(), &lambda.InvokeInput{})
+		if !errors.Is(err, boom) {
+			t.Errorf("call %d: expected injected error, got %v", i, err)
+		}
+	}
+	_, err := client.Invoke(context.Background(), &lambda.InvokeInput{})
+	if err != nil {
+		t.Errorf("expected call after injected failures to succeed, got %v", err)
+	}
+	if len(r.Calls("Invoke")) != 3 {
+		t.Errorf("expected 3 recorded Invoke calls, got %d", len(r.Calls("Invoke")))
+	}
+}
+
+func TestRecorder_RespondProgramsOperationOutput(t *testing.T) {
+	t.Parallel()
+	r := glambdatest.NewRecorder()
+	r.Respond("PublishVersion", &lambda.PublishVersionOutput{Version: aws.String("42")})
+	client := glambdatest.DummyLambdaClient{Recorder: r}
+	version, err := glambda.WaitForConsistency(context.Background(), client, "programmed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "42" {
+		t.Errorf("expected programmed version 42, got %s", version)
+	}
+}
+
+func TestRecorder_RespondWithWrongTypeIsAnError(t *testing.T) {
+	t.Parallel()
+	r := glambdatest.NewRecorder()
+	r.Respond("Invoke", &lambda.PublishVersionOutput{})
+	client := glambdatest.DummyLambdaClient{Recorder: r}
+	_, err := client.Invoke(context.Background(), &lambda.InvokeInput{})
+	if err == nil {
+		t.Error("expected type mismatch error, got nil")
+	}
+}
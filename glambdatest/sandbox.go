@@ -0,0 +1,59 @@
+package glambdatest
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/mr-joshcrane/glambda"
+)
+
+// AccountID is the AWS account ID reported by the sandbox STS client.
+const AccountID = "123456789012"
+
+// Region is the AWS region the sandbox pretends to deploy to.
+const Region = "us-east-1"
+
+// Sandbox is a [glambda.DeployOptions] that swaps every AWS client used by the
+// deploy pipeline for the dummy clients in this package. The handler is still
+// validated and packaged for real, but no AWS credentials are needed and no
+// AWS resources are read or changed.
+//
+// Pass it to [glambda.NewLambda] or [glambda.Deploy] so that it is applied
+// before the AWS configuration would otherwise be resolved.
+func Sandbox() glambda.DeployOptions {
+	return SandboxWithRecorder(NewRecorder())
+}
+
+// SandboxWithRecorder is [Sandbox], with every client attached to the given
+// [Recorder] so that tests can inspect or program the calls a deploy makes.
+func SandboxWithRecorder(r *Recorder) glambda.DeployOptions {
+	return func(l *glambda.Lambda) error {
+		opts := []glambda.DeployOptions{
+			glambda.WithAWSConfig(aws.Config{
+				Region:      Region,
+				Credentials: aws.AnonymousCredentials{},
+			}),
+			glambda.WithSTSClient(DummySTSClient{AccountID: AccountID, Recorder: r}),
+			glambda.WithIAMClient(DummyIAMClient{Recorder: r}),
+			glambda.WithLambdaClient(DummyLambdaClient{
+				ConsistantAfterXRetries: aws.Int(0),
+				FunctionURLs:            map[string]string{},
+				Recorder:                r,
+			}),
+			glambda.WithAPIGatewayClient(DummyAPIGatewayClient{
+				APIs:     map[string]string{},
+				Recorder: r,
+			}),
+			glambda.WithS3Client(DummyS3Client{Recorder: r}),
+			glambda.WithCloudWatchClient(DummyCloudWatchClient{Recorder: r}),
+			glambda.WithCloudWatchLogsClient(DummyCloudWatchLogsClient{Recorder: r}),
+			glambda.WithDynamoDBClient(DummyDynamoDBClient{Recorder: r}),
+			glambda.WithSQSClient(DummySQSClient{Recorder: r}),
+		}
+		for _, opt := range opts {
+			err := opt(l)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
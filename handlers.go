@@ -0,0 +1,139 @@
+package glambda
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// IsHandlerPattern reports whether path is a pattern such as ./... that
+// matches every handler below a directory, rather than a single handler.
+func IsHandlerPattern(path string) bool {
+	return path == "..." || strings.HasSuffix(path, "/...")
+}
+
+// FindHandlers lists the directories below the root of a ./... style pattern
+// that hold a main package calling lambda.Start. Hidden directories, testdata
+// and vendor are skipped, as go build would.
+func FindHandlers(pattern string) ([]string, error) {
+	root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+	if root == "" {
+		root = "."
+	}
+	var handlers []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor") {
+			return filepath.SkipDir
+		}
+		if isHandlerDir(path) {
+			handlers = append(handlers, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return handlers, nil
+}
+
+func isHandlerDir(dir string) bool {
+	src, err := ResolveHandler(dir)
+	if err != nil {
+		return false
+	}
+	fileSet := token.NewFileSet()
+	for _, file := range src.Files {
+		node, err := parser.ParseFile(fileSet, file, nil, 0)
+		if err != nil {
+			return false
+		}
+		if containsLambdaStartFunctionCall(node) {
+			return true
+		}
+	}
+	return false
+}
+
+// AmbiguousHandlerError is returned by [SelectHandler] when a pattern matches
+// more than one handler and none was selected.
+type AmbiguousHandlerError struct {
+	Pattern    string
+	Candidates []string
+}
+
+func (e *AmbiguousHandlerError) Error() string {
+	return fmt.Sprintf("%s matches %d handlers, choose one with --select:\n  %s", e.Pattern, len(e.Candidates), strings.Join(e.Candidates, "\n  "))
+}
+
+// SelectHandler resolves a ./... style pattern to a single handler directory.
+// The selection is a path relative to the pattern's root, such as cmd/worker.
+// With no selection, the pattern must match exactly one handler, otherwise the
+// error is an [*AmbiguousHandlerError] listing the candidates.
+func SelectHandler(pattern, selection string) (string, error) {
+	candidates, err := FindHandlers(pattern)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no lambda handlers found in %s", pattern)
+	}
+	if selection == "" {
+		if len(candidates) > 1 {
+			return "", &AmbiguousHandlerError{Pattern: pattern, Candidates: candidates}
+		}
+		return candidates[0], nil
+	}
+	root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+	want := filepath.Join(root, selection)
+	for _, c := range candidates {
+		if filepath.Clean(c) == want {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("%s isn't one of the handlers in %s:\n  %s", selection, pattern, strings.Join(candidates, "\n  "))
+}
+
+// HandlerConfig describes every handler matched by a ./... style pattern as a
+// [Config], ready for [DeployAllAndPublish]. Each function is named after its
+// directory relative to the pattern's root, with slashes replaced by dashes,
+// so functions/orders/api becomes orders-api for the pattern ./functions/....
+func HandlerConfig(pattern string) (Config, error) {
+	handlers, err := FindHandlers(pattern)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(handlers) == 0 {
+		return Config{}, fmt.Errorf("no lambda handlers found in %s", pattern)
+	}
+	root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+	if root == "" {
+		root = "."
+	}
+	var cfg Config
+	for _, dir := range handlers {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return Config{}, err
+		}
+		name := strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+		if rel == "." {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return Config{}, err
+			}
+			name = filepath.Base(abs)
+		}
+		cfg.Functions = append(cfg.Functions, FunctionConfig{Name: name, Handler: dir})
+	}
+	return cfg, nil
+}
@@ -0,0 +1,67 @@
+package glambda_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestFindHandlers_ListsMainPackagesThatStartALambda(t *testing.T) {
+	t.Parallel()
+	got, err := glambda.FindHandlers("testdata/multi_handler_repo/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.FromSlash("testdata/multi_handler_repo/cmd/api"),
+		filepath.FromSlash("testdata/multi_handler_repo/cmd/worker"),
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestSelectHandler_PicksTheSelectedCandidate(t *testing.T) {
+	t.Parallel()
+	got, err := glambda.SelectHandler("testdata/multi_handler_repo/...", "cmd/worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.FromSlash("testdata/multi_handler_repo/cmd/worker"); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	_, err = glambda.SelectHandler("testdata/multi_handler_repo/...", "internal/greet")
+	if err == nil {
+		t.Error("expected error selecting a package that isn't a handler, got nil")
+	}
+}
+
+func TestSelectHandler_ListsCandidatesWhenAmbiguous(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.SelectHandler("testdata/multi_handler_repo/...", "")
+	var ambiguous *glambda.AmbiguousHandlerError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousHandlerError, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 {
+		t.Errorf("expected 2 candidates, got %v", ambiguous.Candidates)
+	}
+}
+
+func TestHandlerConfig_NamesFunctionsAfterTheirDirectories(t *testing.T) {
+	t.Parallel()
+	got, err := glambda.HandlerConfig("testdata/multi_handler_repo/...")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := glambda.Config{Functions: []glambda.FunctionConfig{
+		{Name: "cmd-api", Handler: filepath.FromSlash("testdata/multi_handler_repo/cmd/api")},
+		{Name: "cmd-worker", Handler: filepath.FromSlash("testdata/multi_handler_repo/cmd/worker")},
+	}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
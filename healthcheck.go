@@ -0,0 +1,148 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HealthCheckOK is the status a healthy function, and each of its checks,
+// reports in a [HealthCheckResponse].
+const HealthCheckOK = "ok"
+
+// HealthCheckEvent is the event [Lambda.HealthCheck] invokes a function with.
+// A function that follows the convention recognises it, checks whatever it
+// depends on, and returns a [HealthCheckResponse] instead of doing its usual
+// work. See [HealthCheckScaffold] for a handler that does so.
+type HealthCheckEvent struct {
+	HealthCheck bool `json:"glambda_health_check"`
+}
+
+// HealthCheckResponse is what a function returns for a [HealthCheckEvent].
+// Status is [HealthCheckOK] when the function is healthy. Checks optionally
+// names each dependency the function checked, with [HealthCheckOK] or the
+// reason it failed.
+type HealthCheckResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the outcome of a single health check.
+type HealthCheckResult struct {
+	Time     time.Time
+	Latency  time.Duration
+	Response HealthCheckResponse
+	// Err is set when the function couldn't be invoked, returned an error,
+	// or didn't return a [HealthCheckResponse].
+	Err error
+}
+
+// Healthy reports whether the function answered, with a status and checks
+// that are all [HealthCheckOK].
+func (r HealthCheckResult) Healthy() bool {
+	if r.Err != nil || r.Response.Status != HealthCheckOK {
+		return false
+	}
+	for _, status := range r.Response.Checks {
+		if status != HealthCheckOK {
+			return false
+		}
+	}
+	return true
+}
+
+// Problem describes why an unhealthy result failed, and is empty for a
+// healthy one.
+func (r HealthCheckResult) Problem() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	var failing []string
+	for _, name := range sortedKeys(r.Response.Checks) {
+		if status := r.Response.Checks[name]; status != HealthCheckOK {
+			failing = append(failing, name+": "+status)
+		}
+	}
+	if len(failing) > 0 {
+		return strings.Join(failing, ", ")
+	}
+	if r.Response.Status != HealthCheckOK {
+		return fmt.Sprintf("status %q", r.Response.Status)
+	}
+	return ""
+}
+
+// HealthCheck is a method on the [Lambda] struct that invokes the deployed
+// function with a [HealthCheckEvent] and reports whether it is healthy. It
+// doesn't return an error, failures are recorded in the result instead, so
+// that a monitor can keep checking.
+func (l Lambda) HealthCheck(ctx context.Context, opts ...InvokeOption) HealthCheckResult {
+	result := HealthCheckResult{Time: time.Now()}
+	err := l.Invoke(ctx, HealthCheckEvent{HealthCheck: true}, &result.Response, opts...)
+	result.Latency = time.Since(result.Time)
+	switch {
+	case err != nil:
+		result.Err = err
+	case result.Response.Status == "":
+		result.Err = fmt.Errorf("%s didn't return a health check response, see glambda healthcheck --scaffold", l.Name)
+	}
+	return result
+}
+
+// HealthCheckScaffold is the source of a handler that answers a
+// [HealthCheckEvent], ready to be filled in with the function's real work
+// and the checks of its dependencies. It depends only on aws-lambda-go, not on
+// glambda.
+const HealthCheckScaffold = `package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+// healthCheckEvent is sent by glambda healthcheck.
+type healthCheckEvent struct {
+	HealthCheck bool ` + "`json:\"glambda_health_check\"`" + `
+}
+
+type healthCheckResponse struct {
+	Status string            ` + "`json:\"status\"`" + `
+	Checks map[string]string ` + "`json:\"checks,omitempty\"`" + `
+}
+
+// checks are run on every health check. Add one for each database, queue or
+// API the function can't work without.
+var checks = map[string]func(context.Context) error{
+	// "database": pingDatabase,
+}
+
+func main() {
+	lambda.Start(handler)
+}
+
+func handler(ctx context.Context, event json.RawMessage) (any, error) {
+	var hc healthCheckEvent
+	if json.Unmarshal(event, &hc) == nil && hc.HealthCheck {
+		return checkHealth(ctx), nil
+	}
+	// Handle real events here.
+	return "Hello, World!", nil
+}
+
+func checkHealth(ctx context.Context) healthCheckResponse {
+	resp := healthCheckResponse{Status: "ok", Checks: map[string]string{}}
+	for name, check := range checks {
+		err := check(ctx)
+		if err != nil {
+			resp.Status = "failing"
+			resp.Checks[name] = err.Error()
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	return resp
+}
+`
@@ -0,0 +1,111 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"go/format"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestHealthCheck_SendsHealthCheckEventAndReportsHealthy(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("Invoke", &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(`{"status":"ok","checks":{"database":"ok"}}`),
+	})
+	l := invokableLambda(t, recorder)
+	result := l.HealthCheck(context.Background())
+	if !result.Healthy() {
+		t.Fatalf("expected healthy result, got %+v", result)
+	}
+	input := recorder.Calls("Invoke")[0].Input.(*lambda.InvokeInput)
+	if string(input.Payload) != `{"glambda_health_check":true}` {
+		t.Errorf("expected health check event, got %s", input.Payload)
+	}
+}
+
+func TestHealthCheck_ReportsFailingChecks(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("Invoke", &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(`{"status":"failing","checks":{"database":"connection refused","queue":"ok"}}`),
+	})
+	l := invokableLambda(t, recorder)
+	result := l.HealthCheck(context.Background())
+	if result.Healthy() {
+		t.Fatal("expected unhealthy result")
+	}
+	want := "database: connection refused"
+	if result.Problem() != want {
+		t.Errorf("want problem %q, got %q", want, result.Problem())
+	}
+}
+
+func TestHealthCheck_IsUnhealthyWhenFunctionFailsOrIgnoresConvention(t *testing.T) {
+	t.Parallel()
+	outputs := map[string]*lambda.InvokeOutput{
+		"function error": {
+			StatusCode:    200,
+			FunctionError: aws.String("Unhandled"),
+			Payload:       []byte(`{"errorMessage":"boom","errorType":"errorString"}`),
+		},
+		"no health check response": {
+			StatusCode: 200,
+			Payload:    []byte(`"Hello, World!"`),
+		},
+		"empty response": {
+			StatusCode: 200,
+			Payload:    []byte(`{}`),
+		},
+	}
+	for name, out := range outputs {
+		recorder := glambdatest.NewRecorder()
+		recorder.Respond("Invoke", out)
+		l := invokableLambda(t, recorder)
+		result := l.HealthCheck(context.Background())
+		if result.Healthy() || result.Err == nil {
+			t.Errorf("%s: expected unhealthy result with error, got %+v", name, result)
+		}
+	}
+}
+
+func TestHealthCheck_RecordsInvokeErrors(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	throttled := errors.New("throttled")
+	recorder.FailNext("Invoke", throttled, 1)
+	l := invokableLambda(t, recorder)
+	result := l.HealthCheck(context.Background(), glambda.WithQualifier(glambda.LiveAlias))
+	if !errors.Is(result.Err, throttled) {
+		t.Errorf("expected invoke error to be recorded, got %v", result.Err)
+	}
+}
+
+func TestHealthCheckScaffold_IsAFormattedValidHandler(t *testing.T) {
+	t.Parallel()
+	formatted, err := format.Source([]byte(glambda.HealthCheckScaffold))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != glambda.HealthCheckScaffold {
+		t.Error("expected scaffold to be gofmt formatted")
+	}
+	path := filepath.Join(t.TempDir(), "main.go")
+	err = os.WriteFile(path, []byte(glambda.HealthCheckScaffold), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.Validate(path)
+	if err != nil {
+		t.Errorf("expected scaffold to be a valid handler, got %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	gTypes "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// DefaultRoute is the HTTP API route that catches every request not matched
+// by a more specific route.
+const DefaultRoute = "$default"
+
+var routeMethods = []string{"ANY", "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// HTTPAPI describes an API Gateway HTTP API that routes requests to the
+// lambda function. The API shares the function's name.
+type HTTPAPI struct {
+	// Routes are route keys such as "GET /orders/{id}", or [DefaultRoute].
+	Routes []string `yaml:"routes"`
+}
+
+// WithHTTPAPI is a deploy option that puts an API Gateway HTTP API in front of
+// the lambda function, with each of the given routes sent to the function. With
+// no routes, every request is sent to the function through the [DefaultRoute].
+func WithHTTPAPI(routes ...string) DeployOptions {
+	return func(l *Lambda) error {
+		if len(routes) == 0 {
+			routes = []string{DefaultRoute}
+		}
+		for _, route := range routes {
+			err := validateRoute(route)
+			if err != nil {
+				return err
+			}
+		}
+		l.HTTPAPI = &HTTPAPI{Routes: routes}
+		return nil
+	}
+}
+
+func validateRoute(route string) error {
+	if route == DefaultRoute {
+		return nil
+	}
+	method, path, ok := strings.Cut(route, " ")
+	if !ok || !slices.Contains(routeMethods, method) || !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid route %q, expected METHOD /path (e.g. GET /orders) or %s", route, DefaultRoute)
+	}
+	return nil
+}
+
+// HTTPAPIAction is an [Action] that creates an HTTP API for a lambda function,
+// or adds any missing routes to the existing one. The function is allowed to
+// be invoked by the API with a resource policy statement scoped to its ARN.
+type HTTPAPIAction struct {
+	client       APIGatewayClient
+	lambdaClient LambdaClient
+	Name         string
+	FunctionARN  string
+	Region       string
+	AccountID    string
+	Routes       []string
+}
+
+// NewHTTPAPIAction is a constructor function that creates a new [HTTPAPIAction].
+func NewHTTPAPIAction(client APIGatewayClient, lambdaClient LambdaClient, l Lambda) HTTPAPIAction {
+	return HTTPAPIAction{
+		client:       client,
+		lambdaClient: lambdaClient,
+		Name:         l.Name,
+		FunctionARN:  l.functionARN(),
+		Region:       l.cfg.Region,
+		AccountID:    l.AWSAccountID,
+		Routes:       l.HTTPAPI.Routes,
+	}
+}
+
+// Client returns the required client type. In this case [APIGatewayClient].
+func (a HTTPAPIAction) Client() APIGatewayClient {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface. Routes that are no
+// longer wanted are left in place rather than deleted, as they may have been
+// added by hand.
+func (a HTTPAPIAction) Do(ctx context.Context) error {
+	client := a.Client()
+	api, err := findHTTPAPI(ctx, client, a.Name)
+	if err != nil {
+		return err
+	}
+	created := api == nil
+	if created {
+		resp, err := client.CreateApi(ctx, &apigatewayv2.CreateApiInput{
+			Name:         aws.String(a.Name),
+			ProtocolType: gTypes.ProtocolTypeHttp,
+			Tags:         map[string]string{ManagedTagKey: "true"},
+		})
+		if err != nil {
+			return err
+		}
+		api = &gTypes.Api{ApiId: resp.ApiId, ApiEndpoint: resp.ApiEndpoint}
+	}
+	integrationID, err := a.integration(ctx, aws.ToString(api.ApiId))
+	if err != nil {
+		return err
+	}
+	err = a.routes(ctx, aws.ToString(api.ApiId), integrationID)
+	if err != nil {
+		return err
+	}
+	if created {
+		_, err = client.CreateStage(ctx, &apigatewayv2.CreateStageInput{
+			ApiId:      api.ApiId,
+			StageName:  aws.String(DefaultRoute),
+			AutoDeploy: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = a.lambdaClient.AddPermission(ctx, &lambda.AddPermissionInput{
+		FunctionName: aws.String(a.Name),
+		StatementId:  aws.String("glambda_http_api_" + aws.ToString(api.ApiId)),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("apigateway.amazonaws.com"),
+		SourceArn:    aws.String(a.SourceARN(aws.ToString(api.ApiId))),
+	})
+	var conflict *types.ResourceConflictException
+	if errors.As(err, &conflict) {
+		return nil
+	}
+	return err
+}
+
+// SourceARN is the execute-api ARN covering every stage and route of the API,
+// which the function's resource policy is conditioned on.
+func (a HTTPAPIAction) SourceARN(apiID string) string {
+	return fmt.Sprintf("arn:aws:execute-api:%s:%s:%s/*", a.Region, a.AccountID, apiID)
+}
+
+func (a HTTPAPIAction) integration(ctx context.Context, apiID string) (string, error) {
+	resp, err := a.client.GetIntegrations(ctx, &apigatewayv2.GetIntegrationsInput{
+		ApiId: aws.String(apiID),
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, i := range resp.Items {
+		if aws.ToString(i.IntegrationUri) == a.FunctionARN {
+			return aws.ToString(i.IntegrationId), nil
+		}
+	}
+	created, err := a.client.CreateIntegration(ctx, &apigatewayv2.CreateIntegrationInput{
+		ApiId:                aws.String(apiID),
+		IntegrationType:      gTypes.IntegrationTypeAwsProxy,
+		IntegrationUri:       aws.String(a.FunctionARN),
+		PayloadFormatVersion: aws.String("2.0"),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(created.IntegrationId), nil
+}
+
+func (a HTTPAPIAction) routes(ctx context.Context, apiID, integrationID string) error {
+	resp, err := a.client.GetRoutes(ctx, &apigatewayv2.GetRoutesInput{
+		ApiId: aws.String(apiID),
+	})
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, r := range resp.Items {
+		existing[aws.ToString(r.RouteKey)] = true
+	}
+	for _, route := range a.Routes {
+		if existing[route] {
+			continue
+		}
+		_, err := a.client.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
+			ApiId:    aws.String(apiID),
+			RouteKey: aws.String(route),
+			Target:   aws.String("integrations/" + integrationID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findHTTPAPI(ctx context.Context, c APIGatewayClient, name string) (*gTypes.Api, error) {
+	input := &apigatewayv2.GetApisInput{}
+	for {
+		resp, err := c.GetApis(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, api := range resp.Items {
+			if aws.ToString(api.Name) == name && api.ProtocolType == gTypes.ProtocolTypeHttp {
+				return &api, nil
+			}
+		}
+		if resp.NextToken == nil {
+			return nil, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// HTTPAPIEndpoint returns the invoke URL of the HTTP API named after a
+// function.
+func HTTPAPIEndpoint(ctx context.Context, c APIGatewayClient, name string) (string, error) {
+	api, err := findHTTPAPI(ctx, c, name)
+	if err != nil {
+		return "", err
+	}
+	if api == nil {
+		return "", fmt.Errorf("no HTTP API found for %s", name)
+	}
+	return aws.ToString(api.ApiEndpoint), nil
+}
+
+// HTTPAPIEndpoint is a method on the [Lambda] struct that returns the invoke
+// URL of the function's HTTP API.
+func (l Lambda) HTTPAPIEndpoint(ctx context.Context) (string, error) {
+	return HTTPAPIEndpoint(ctx, l.apiGatewayAPI(), l.Name)
+}
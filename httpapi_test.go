@@ -0,0 +1,100 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	gTypes "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestWithHTTPAPI_ValidatesRoutes(t *testing.T) {
+	t.Parallel()
+	for _, route := range []string{"/orders", "FETCH /orders", "GET orders"} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithHTTPAPI(route))
+		if err == nil {
+			t.Errorf("%q: expected error, got nil", route)
+		}
+	}
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithHTTPAPI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal([]string{glambda.DefaultRoute}, l.HTTPAPI.Routes) {
+		t.Errorf("expected default route, got %v", l.HTTPAPI.Routes)
+	}
+}
+
+func TestHTTPAPIAction_CreatesAPIRoutedToFunction(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("orders", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithHTTPAPI("GET /orders", "POST /orders"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewHTTPAPIAction(
+		glambdatest.DummyAPIGatewayClient{Recorder: recorder},
+		glambdatest.DummyLambdaClient{Recorder: recorder},
+		*l,
+	).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetApis", "CreateApi", "GetIntegrations", "CreateIntegration", "GetRoutes", "CreateRoute", "CreateRoute", "CreateStage", "AddPermission"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Fatal(cmp.Diff(want, recorder.Operations()))
+	}
+	integration := recorder.Calls("CreateIntegration")[0].Input.(*apigatewayv2.CreateIntegrationInput)
+	if got := aws.ToString(integration.IntegrationUri); got != "arn:aws:lambda:us-east-1:123456789012:function:orders" {
+		t.Errorf("unexpected integration URI %q", got)
+	}
+	permission := recorder.Calls("AddPermission")[0].Input.(*lambda.AddPermissionInput)
+	if got := aws.ToString(permission.SourceArn); got != "arn:aws:execute-api:us-east-1:123456789012:apiorders/*" {
+		t.Errorf("unexpected source ARN %q", got)
+	}
+}
+
+func TestHTTPAPIAction_OnlyAddsMissingRoutesToExistingAPI(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetIntegrations", &apigatewayv2.GetIntegrationsOutput{
+		Items: []gTypes.Integration{{
+			IntegrationId:  aws.String("existing"),
+			IntegrationUri: aws.String("arn:aws:lambda:us-east-1:123456789012:function:orders"),
+		}},
+	})
+	recorder.Respond("GetRoutes", &apigatewayv2.GetRoutesOutput{
+		Items: []gTypes.Route{{RouteKey: aws.String("GET /orders")}},
+	})
+	l, err := glambda.NewLambda("orders", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithHTTPAPI("GET /orders", "POST /orders"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewHTTPAPIAction(
+		glambdatest.DummyAPIGatewayClient{Recorder: recorder, APIs: map[string]string{"orders": "abc123"}},
+		glambdatest.DummyLambdaClient{Recorder: recorder},
+		*l,
+	).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GetApis", "GetIntegrations", "GetRoutes", "CreateRoute", "AddPermission"}
+	if !cmp.Equal(want, recorder.Operations()) {
+		t.Fatal(cmp.Diff(want, recorder.Operations()))
+	}
+	route := recorder.Calls("CreateRoute")[0].Input.(*apigatewayv2.CreateRouteInput)
+	if aws.ToString(route.RouteKey) != "POST /orders" || aws.ToString(route.Target) != "integrations/existing" {
+		t.Errorf("unexpected route %+v", route)
+	}
+}
@@ -0,0 +1,117 @@
+// Package infra prepares lambda functions with glambda for infrastructure as
+// code tools such as Pulumi and CDK for Terraform, which create the AWS
+// resources themselves.
+//
+// [New] builds and validates a handler exactly as glambda deploy would, and
+// returns a [Function] holding the package and the settings a function and
+// its execution role resource need, as plain values. Nothing is read from or
+// changed in AWS, so no credentials are needed.
+//
+//	fn, err := infra.New("orders", "./orders/main.go", glambda.WithMemory(256))
+//	...
+//	err = fn.WriteZip("orders.zip")
+//	...
+//	lambda.NewFunction(ctx, fn.Name, &lambda.FunctionArgs{
+//		Code:           pulumi.NewFileArchive("orders.zip"),
+//		Handler:        pulumi.String(fn.Handler),
+//		Runtime:        pulumi.String(fn.Runtime),
+//		SourceCodeHash: pulumi.String(fn.SourceCodeHash),
+//		...
+//	})
+package infra
+
+import (
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/mr-joshcrane/glambda"
+)
+
+// DefaultInlinePolicyName is the name given to the policy from
+// [glambda.WithInlinePolicy] when no [glambda.WithNamingConvention] names it.
+// Unlike glambda deploy, which adds a random suffix, it is fixed so that the
+// IaC tool sees the same policy on every run.
+const DefaultInlinePolicyName = "glambda_inline_policy"
+
+// Function is a built lambda function and its settings, in the form IaC
+// resources take them. Zero values are unset, and leave the AWS default.
+type Function struct {
+	Name string
+	// Package is the zip file to upload as the function's code.
+	Package []byte
+	// SourceCodeHash is the base64 encoded SHA-256 of Package, which is what
+	// Terraform and Pulumi compare to decide whether the code changed.
+	SourceCodeHash  string
+	Handler         string
+	Runtime         string
+	Architecture    string
+	MemorySize      int
+	Timeout         int
+	Environment     map[string]string
+	KMSKeyARN       string
+	DeadLetterQueue string
+	Tags            map[string]string
+	Role            Role
+}
+
+// Role is the execution role a [Function] needs.
+type Role struct {
+	Name              string
+	AssumeRolePolicy  string
+	ManagedPolicyARNs []string
+	// InlinePolicies are policy documents by policy name. They include the
+	// policies glambda derives from event sources, destinations and KMS keys
+	// as well as the user's own.
+	InlinePolicies map[string]string
+}
+
+// New builds the handler at handlerPath, with the same deploy options as
+// [glambda.NewLambda], and returns the function and role to create. Options
+// that act on AWS after the function is created, such as triggers, function
+// URLs and canaries, are validated but are left to the IaC program.
+func New(name, handlerPath string, opts ...glambda.DeployOptions) (Function, error) {
+	l, err := glambda.NewOfflineLambda(name, handlerPath, opts...)
+	if err != nil {
+		return Function{}, err
+	}
+	pkg, err := l.Build()
+	if err != nil {
+		return Function{}, err
+	}
+	cmd := glambda.NewLambdaCreateAction(nil, *l, pkg).CreateLambdaCommand
+	fn := Function{
+		Name:            name,
+		Package:         pkg,
+		SourceCodeHash:  glambda.CodeSHA256(pkg),
+		Handler:         aws.ToString(cmd.Handler),
+		Runtime:         string(cmd.Runtime),
+		Architecture:    string(cmd.Architectures[0]),
+		MemorySize:      int(aws.ToInt32(cmd.MemorySize)),
+		Timeout:         int(l.Timeout / time.Second),
+		Environment:     l.Environment,
+		KMSKeyARN:       l.KMSKeyARN,
+		DeadLetterQueue: l.DeadLetterQueue,
+		Tags:            cmd.Tags,
+		Role: Role{
+			Name:              l.ExecutionRole.RoleName,
+			AssumeRolePolicy:  l.ExecutionRole.AssumeRolePolicyDocument,
+			ManagedPolicyARNs: l.ExecutionRole.ManagedPolicies,
+			InlinePolicies:    map[string]string{},
+		},
+	}
+	role := l.ExecutionRole
+	if role.InlinePolicyName == "" {
+		role.InlinePolicyName = DefaultInlinePolicyName
+	}
+	for _, p := range glambda.PutRolePolicyCommand(role) {
+		fn.Role.InlinePolicies[aws.ToString(p.PolicyName)] = aws.ToString(p.PolicyDocument)
+	}
+	return fn, nil
+}
+
+// WriteZip writes the function's package to path, for IaC tools that take
+// the code as a file rather than bytes.
+func (f Function) WriteZip(path string) error {
+	return os.WriteFile(path, f.Package, 0o644)
+}
@@ -0,0 +1,87 @@
+package infra_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/infra"
+)
+
+const queueARN = "arn:aws:sqs:us-east-1:123456789012:orders"
+
+func TestNew_BuildsPackageAndSettingsWithoutAWS(t *testing.T) {
+	t.Parallel()
+	fn, err := infra.New("orders", "../testdata/correct_test_handler/main.go",
+		glambda.WithMemory(256),
+		glambda.WithTimeout(30*time.Second),
+		glambda.WithArchitecture("x86_64"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = zip.NewReader(bytes.NewReader(fn.Package), int64(len(fn.Package)))
+	if err != nil {
+		t.Fatalf("expected package to be a zip, got %v", err)
+	}
+	if fn.Handler != "/var/task/bootstrap" || fn.Runtime != "provided.al2023" {
+		t.Errorf("unexpected handler %q and runtime %q", fn.Handler, fn.Runtime)
+	}
+	if fn.Architecture != "x86_64" || fn.MemorySize != 256 || fn.Timeout != 30 {
+		t.Errorf("unexpected settings %s, %dMB, %ds", fn.Architecture, fn.MemorySize, fn.Timeout)
+	}
+	if fn.SourceCodeHash == "" || fn.Tags[glambda.ManagedTagKey] != "true" {
+		t.Errorf("expected source code hash and managed tag, got %q and %v", fn.SourceCodeHash, fn.Tags)
+	}
+	if fn.Role.Name != "glambda_exec_role_orders" || fn.Role.AssumeRolePolicy != glambda.DefaultAssumeRolePolicy {
+		t.Errorf("unexpected role %+v", fn.Role)
+	}
+}
+
+func TestNew_NamesInlinePoliciesDeterministically(t *testing.T) {
+	t.Parallel()
+	fn, err := infra.New("orders", "../testdata/correct_test_handler/main.go",
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`),
+		glambda.WithSQSTrigger(queueARN, 10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{infra.DefaultInlinePolicyName, glambda.EventSourcePolicyName} {
+		if fn.Role.InlinePolicies[name] == "" {
+			t.Errorf("expected inline policy %s, got %v", name, fn.Role.InlinePolicies)
+		}
+	}
+	if len(fn.Role.InlinePolicies) != 2 {
+		t.Errorf("expected 2 inline policies, got %v", fn.Role.InlinePolicies)
+	}
+}
+
+func TestNew_RejectsInvalidHandler(t *testing.T) {
+	t.Parallel()
+	_, err := infra.New("orders", "../testdata/invalid_go_source.go")
+	if err == nil {
+		t.Error("expected error building handler that does not compile, got nil")
+	}
+}
+
+func TestWriteZip_WritesPackage(t *testing.T) {
+	t.Parallel()
+	fn := infra.Function{Package: []byte("some valid zip data")}
+	path := filepath.Join(t.TempDir(), "orders.zip")
+	err := fn.WriteZip(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, fn.Package) {
+		t.Errorf("want %q, got %q", fn.Package, got)
+	}
+}
@@ -0,0 +1,245 @@
+package glambda
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// FunctionError is returned by [Invoke] when the function ran, but returned
+// an error or failed, for example by panicking or timing out. The fields are
+// decoded from the error payload Lambda returns in place of a response.
+type FunctionError struct {
+	FunctionName string `json:"-"`
+	// Kind is "Handled" when the handler returned an error and "Unhandled"
+	// when the function failed, see the InvokeOutput.FunctionError field.
+	Kind         string   `json:"-"`
+	ErrorMessage string   `json:"errorMessage"`
+	ErrorType    string   `json:"errorType"`
+	StackTrace   []string `json:"stackTrace"`
+	// Payload is the error payload exactly as returned.
+	Payload []byte `json:"-"`
+}
+
+func (e *FunctionError) Error() string {
+	if e.ErrorType == "" && e.ErrorMessage == "" {
+		return fmt.Sprintf("%s returned an error: %s", e.FunctionName, e.Payload)
+	}
+	return fmt.Sprintf("%s returned %s: %s", e.FunctionName, e.ErrorType, e.ErrorMessage)
+}
+
+// InvokeOption configures a single invocation made by [Invoke].
+type InvokeOption func(*lambda.InvokeInput) error
+
+// WithQualifier is an [InvokeOption] that invokes a published version or an
+// alias of the function, such as [LiveAlias], rather than $LATEST.
+func WithQualifier(qualifier string) InvokeOption {
+	return func(input *lambda.InvokeInput) error {
+		if qualifier == "" {
+			return fmt.Errorf("qualifier must not be empty")
+		}
+		input.Qualifier = aws.String(qualifier)
+		return nil
+	}
+}
+
+// Invoke is a convenience function that invokes a deployed lambda function
+// and waits for its response, using the default AWS configuration. See
+// [Lambda.Invoke].
+func Invoke(ctx context.Context, name string, payload, out any, opts ...InvokeOption) error {
+	l, err := NewLambdaWithContext(ctx, name, "")
+	if err != nil {
+		return err
+	}
+	return l.Invoke(ctx, payload, out, opts...)
+}
+
+// Invoke is a method on the [Lambda] struct that invokes the deployed
+// function and waits for its response.
+//
+// The payload is sent as JSON. A []byte or [json.RawMessage] payload is sent
+// exactly as given, and a nil payload sends nothing. The response is decoded
+// as JSON into out, unless out is nil, or a *[]byte to receive it exactly as
+// returned. A function that returns an error is a [*FunctionError].
+func (l Lambda) Invoke(ctx context.Context, payload, out any, opts ...InvokeOption) error {
+	_, err := l.InvokeWithLog(ctx, payload, out, opts...)
+	return err
+}
+
+// InvokeResult describes a call made by [Lambda.InvokeWithLog].
+type InvokeResult struct {
+	// RequestID is the ID of the invocation, which its log lines carry.
+	RequestID string
+	// Started is when the invocation was made.
+	Started time.Time
+	// ExecutedVersion is the version of the function that ran.
+	ExecutedVersion string
+	// LogTail is the last 4 KB of the invocation's log output.
+	LogTail string
+}
+
+// InvokeWithLog is [Lambda.Invoke], also returning the invocation's request
+// ID and the tail of its log, which are returned when the function fails too.
+func (l Lambda) InvokeWithLog(ctx context.Context, payload, out any, opts ...InvokeOption) (InvokeResult, error) {
+	input := &lambda.InvokeInput{
+		FunctionName: aws.String(l.Name),
+		LogType:      types.LogTypeTail,
+	}
+	switch p := payload.(type) {
+	case nil:
+	case []byte:
+		input.Payload = p
+	case json.RawMessage:
+		input.Payload = p
+	default:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return InvokeResult{}, fmt.Errorf("error encoding payload for %s, %w", l.Name, err)
+		}
+		input.Payload = data
+	}
+	for _, opt := range opts {
+		err := opt(input)
+		if err != nil {
+			return InvokeResult{}, err
+		}
+	}
+	started := time.Now()
+	resp, err := l.lambdaAPI().Invoke(ctx, input)
+	if err != nil {
+		return InvokeResult{}, err
+	}
+	inv := InvokeResult{Started: started, ExecutedVersion: aws.ToString(resp.ExecutedVersion)}
+	inv.RequestID, _ = awsmiddleware.GetRequestIDMetadata(resp.ResultMetadata)
+	if resp.LogResult != nil {
+		tail, err := base64.StdEncoding.DecodeString(*resp.LogResult)
+		if err != nil {
+			return inv, fmt.Errorf("error decoding log of %s, %w", l.Name, err)
+		}
+		inv.LogTail = string(tail)
+	}
+	if resp.FunctionError != nil {
+		fnErr := &FunctionError{
+			FunctionName: l.Name,
+			Kind:         aws.ToString(resp.FunctionError),
+			Payload:      resp.Payload,
+		}
+		// A payload that isn't the usual error object is still in Payload.
+		_ = json.Unmarshal(resp.Payload, fnErr)
+		return inv, fnErr
+	}
+	switch o := out.(type) {
+	case nil:
+		return inv, nil
+	case *[]byte:
+		*o = resp.Payload
+		return inv, nil
+	}
+	if len(resp.Payload) == 0 {
+		return inv, nil
+	}
+	err = json.Unmarshal(resp.Payload, out)
+	if err != nil {
+		return inv, fmt.Errorf("error decoding response from %s, %w", l.Name, err)
+	}
+	return inv, nil
+}
+
+// TailTruncated reports whether the invocation logged more than its
+// [InvokeResult.LogTail] holds, so that its START line was cut off.
+// [Lambda.InvocationLogs] reads the whole of it.
+func (r InvokeResult) TailTruncated() bool {
+	return r.RequestID != "" && r.LogTail != "" && !strings.Contains(r.LogTail, "START RequestId: "+r.RequestID)
+}
+
+// invocationLogSkew is how long before an invocation was made its log is
+// looked for, in case the local clock is ahead of AWS's.
+const invocationLogSkew = time.Minute
+
+// errLogComplete stops paging through log events once an invocation's REPORT
+// line has been read.
+var errLogComplete = errors.New("log complete")
+
+// InvocationLogs is a method on the [Lambda] struct that reads every event
+// logged by the invocation made by [Lambda.InvokeWithLog], from its START line
+// to its REPORT line. An instance of a function handles one invocation at a
+// time, so the lines between them in that instance's log stream belong to the
+// invocation, even those that don't carry its request ID.
+//
+// Events reach CloudWatch Logs some seconds after they are written, so the
+// log is read again every interval until its REPORT line has arrived, or
+// until ctx is done, when the events read so far are returned with an error.
+func (l Lambda) InvocationLogs(ctx context.Context, inv InvokeResult, interval time.Duration) ([]LogEvent, error) {
+	if inv.RequestID == "" {
+		return nil, fmt.Errorf("invocation of %s has no request ID to find its log by", l.Name)
+	}
+	for {
+		events, complete, err := l.invocationEvents(ctx, inv)
+		if err != nil || complete {
+			return events, err
+		}
+		select {
+		case <-ctx.Done():
+			return events, fmt.Errorf("log of request %s is incomplete, %w", inv.RequestID, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// invocationEvents reads the events of an invocation logged so far, and
+// whether its REPORT line was among them.
+func (l Lambda) invocationEvents(ctx context.Context, inv InvokeResult) ([]LogEvent, bool, error) {
+	startLine := "START RequestId: " + inv.RequestID
+	var start *LogEvent
+	err := l.filterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:  aws.String(LogGroupName(l.Name)),
+		StartTime:     aws.Int64(inv.Started.Add(-invocationLogSkew).UnixMilli()),
+		FilterPattern: aws.String(strconv.Quote(startLine)),
+	}, 0, func(e LogEvent) error {
+		if !strings.HasPrefix(e.Message, startLine) {
+			return nil
+		}
+		start = &e
+		return errLogComplete
+	})
+	if err != nil && !errors.Is(err, errLogComplete) {
+		return nil, false, err
+	}
+	if start == nil {
+		return nil, false, nil
+	}
+	var events []LogEvent
+	complete := false
+	err = l.filterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:   aws.String(LogGroupName(l.Name)),
+		LogStreamNames: []string{start.Stream},
+		StartTime:      aws.Int64(start.Time.UnixMilli()),
+	}, 0, func(e LogEvent) error {
+		// Lines of the previous invocation may share the START line's
+		// millisecond.
+		if len(events) == 0 && !strings.HasPrefix(e.Message, startLine) {
+			return nil
+		}
+		events = append(events, e)
+		if strings.HasPrefix(e.Message, "REPORT RequestId: "+inv.RequestID) {
+			complete = true
+			return errLogComplete
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLogComplete) {
+		return events, false, err
+	}
+	return events, complete, nil
+}
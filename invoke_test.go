@@ -0,0 +1,198 @@
+package glambda_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func invokableLambda(t *testing.T, recorder *glambdatest.Recorder) *glambda.Lambda {
+	t.Helper()
+	l, err := glambda.NewLambda("greeter", "", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestInvoke_MarshalsPayloadAndDecodesResponse(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("Invoke", &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(`{"greeting":"hello, ada"}`),
+	})
+	l := invokableLambda(t, recorder)
+	var out struct{ Greeting string }
+	err := l.Invoke(context.Background(), map[string]string{"name": "ada"}, &out, glambda.WithQualifier(glambda.LiveAlias))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Greeting != "hello, ada" {
+		t.Errorf("expected decoded response, got %+v", out)
+	}
+	input := recorder.Calls("Invoke")[0].Input.(*lambda.InvokeInput)
+	if string(input.Payload) != `{"name":"ada"}` {
+		t.Errorf("expected JSON payload, got %s", input.Payload)
+	}
+	if aws.ToString(input.Qualifier) != glambda.LiveAlias {
+		t.Errorf("expected qualifier %q, got %q", glambda.LiveAlias, aws.ToString(input.Qualifier))
+	}
+}
+
+func TestInvoke_PassesRawPayloadsThrough(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := invokableLambda(t, recorder)
+	var out []byte
+	err := l.Invoke(context.Background(), []byte(`not json`), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "all good" {
+		t.Errorf("expected raw response, got %q", out)
+	}
+	input := recorder.Calls("Invoke")[0].Input.(*lambda.InvokeInput)
+	if string(input.Payload) != "not json" {
+		t.Errorf("expected raw payload, got %s", input.Payload)
+	}
+}
+
+func TestInvoke_ReturnsFunctionErrorWithDecodedPayload(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("Invoke", &lambda.InvokeOutput{
+		StatusCode:    200,
+		FunctionError: aws.String("Unhandled"),
+		Payload:       []byte(`{"errorMessage":"name is required","errorType":"errorString"}`),
+	})
+	l := invokableLambda(t, recorder)
+	err := l.Invoke(context.Background(), nil, nil)
+	var fnErr *glambda.FunctionError
+	if !errors.As(err, &fnErr) {
+		t.Fatalf("expected FunctionError, got %v", err)
+	}
+	if fnErr.Kind != "Unhandled" || fnErr.ErrorType != "errorString" || fnErr.ErrorMessage != "name is required" {
+		t.Errorf("expected decoded error payload, got %+v", fnErr)
+	}
+	if want := "greeter returned errorString: name is required"; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+}
+
+func TestInvoke_RejectsEmptyQualifier(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l := invokableLambda(t, recorder)
+	err := l.Invoke(context.Background(), nil, nil, glambda.WithQualifier(""))
+	if err == nil {
+		t.Fatal("expected error for empty qualifier")
+	}
+	if len(recorder.Calls("Invoke")) != 0 {
+		t.Errorf("expected no invocation, got %v", recorder.Operations())
+	}
+}
+
+func TestInvokeWithLog_ReturnsTheRequestIDAndDecodedLogTail(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	out := &lambda.InvokeOutput{
+		StatusCode:    200,
+		FunctionError: aws.String("Unhandled"),
+		Payload:       []byte(`{"errorMessage":"boom","errorType":"panic"}`),
+		LogResult:     aws.String(base64.StdEncoding.EncodeToString([]byte("panic: boom\n"))),
+	}
+	awsmiddleware.SetRequestIDMetadata(&out.ResultMetadata, "8f5d0a3c")
+	recorder.Respond("Invoke", out)
+	l := invokableLambda(t, recorder)
+	result, err := l.InvokeWithLog(context.Background(), nil, nil)
+	var fnErr *glambda.FunctionError
+	if !errors.As(err, &fnErr) {
+		t.Fatalf("expected FunctionError, got %v", err)
+	}
+	if result.RequestID != "8f5d0a3c" || result.LogTail != "panic: boom\n" {
+		t.Errorf("expected request ID and log tail of the failed invocation, got %+v", result)
+	}
+	input := recorder.Calls("Invoke")[0].Input.(*lambda.InvokeInput)
+	if input.LogType != types.LogTypeTail {
+		t.Errorf("expected the log tail to be requested, got %q", input.LogType)
+	}
+}
+
+func TestInvocationLogs_ReadsTheInvocationsLinesFromItsLogStream(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	group := &logGroup{}
+	group.writeStream(start, "a", "REPORT RequestId: earlier\tDuration: 1.00 ms")
+	group.writeStream(start, "a", "START RequestId: 8f5d0a3c Version: $LATEST")
+	group.writeStream(start, "b", "START RequestId: other Version: $LATEST")
+	group.writeStream(start.Add(time.Millisecond), "a", "loading 5000 orders")
+	group.writeStream(start.Add(time.Millisecond), "b", "another invocation's line")
+	group.writeStream(start.Add(2*time.Millisecond), "a", "END RequestId: 8f5d0a3c")
+	group.writeStream(start.Add(2*time.Millisecond), "a", "REPORT RequestId: 8f5d0a3c\tDuration: 2.00 ms")
+	group.writeStream(start.Add(3*time.Millisecond), "a", "START RequestId: later Version: $LATEST")
+	l, err := glambda.NewLambda("greeter", "", glambda.WithCloudWatchLogsClient(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := l.InvocationLogs(context.Background(), glambda.InvokeResult{RequestID: "8f5d0a3c", Started: start}, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Message)
+	}
+	want := []string{
+		"START RequestId: 8f5d0a3c Version: $LATEST",
+		"loading 5000 orders",
+		"END RequestId: 8f5d0a3c",
+		"REPORT RequestId: 8f5d0a3c\tDuration: 2.00 ms",
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestInvocationLogs_ReturnsWhatArrivedWhenTheReportLineDoesNot(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+	group := &logGroup{}
+	group.writeStream(start, "a", "START RequestId: 8f5d0a3c Version: $LATEST")
+	l, err := glambda.NewLambda("greeter", "", glambda.WithCloudWatchLogsClient(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	events, err := l.InvocationLogs(ctx, glambda.InvokeResult{RequestID: "8f5d0a3c", Started: start}, time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "incomplete") {
+		t.Errorf("want an incomplete log error, got %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("want the START line read so far, got %+v", events)
+	}
+}
+
+func TestInvokeResult_TailTruncatedWhenTheStartLineWasCutOff(t *testing.T) {
+	t.Parallel()
+	whole := glambda.InvokeResult{RequestID: "8f5d0a3c", LogTail: "START RequestId: 8f5d0a3c Version: $LATEST\nREPORT RequestId: 8f5d0a3c\n"}
+	if whole.TailTruncated() {
+		t.Error("want a tail with its START line to be whole")
+	}
+	cut := glambda.InvokeResult{RequestID: "8f5d0a3c", LogTail: "ng 5000 orders\nREPORT RequestId: 8f5d0a3c\n"}
+	if !cut.TailTruncated() {
+		t.Error("want a tail without its START line to be truncated")
+	}
+}
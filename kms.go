@@ -0,0 +1,65 @@
+package glambda
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// KMSPolicyName is the name of the inline policy that lets the execution role
+// decrypt the function's environment variables with the key given to
+// [WithKMSKey].
+const KMSPolicyName = "glambda_kms_decrypt"
+
+var kmsKeyARNRegex = regexp.MustCompile(`^arn:aws[a-zA-Z-]*:kms:[a-z0-9-]+:\d{12}:key/[a-zA-Z0-9-]+$`)
+
+// WithKMSKey is a deploy option that encrypts the function's environment
+// variables at rest with a customer managed KMS key, rather than the key
+// Lambda manages. The key must be given as a key ARN, aliases aren't accepted
+// by Lambda. An inline policy named [KMSPolicyName] allowing kms:Decrypt on
+// the key is added to the execution role, so the function can read its
+// environment.
+func WithKMSKey(arn string) DeployOptions {
+	return func(l *Lambda) error {
+		if !kmsKeyARNRegex.MatchString(arn) {
+			return fmt.Errorf("invalid KMS key ARN %q, expected arn:aws:kms:<region>:<account>:key/<id>", arn)
+		}
+		policy, err := KMSPolicy(arn)
+		if err != nil {
+			return err
+		}
+		policy, err = withStatementIDs(policy, l.Name, KMSPolicyName)
+		if err != nil {
+			return err
+		}
+		l.KMSKeyARN = arn
+		l.ExecutionRole.KMSPolicy = policy
+		return nil
+	}
+}
+
+// KMSPolicy returns an IAM policy document granting kms:Decrypt on the key
+// alone.
+func KMSPolicy(keyARN string) (string, error) {
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource string
+	}
+	doc := struct {
+		Version   string
+		Statement []statement
+	}{
+		Version: "2012-10-17",
+		Statement: []statement{{
+			Effect:   "Allow",
+			Action:   []string{"kms:Decrypt"},
+			Resource: keyARN,
+		}},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
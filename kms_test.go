@@ -0,0 +1,68 @@
+package glambda_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+const keyARN = "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestWithKMSKey_RejectsAliasesAndKeyIDs(t *testing.T) {
+	t.Parallel()
+	for _, key := range []string{
+		"",
+		"1234abcd-12ab-34cd-56ef-1234567890ab",
+		"alias/orders",
+		"arn:aws:kms:us-east-1:123456789012:alias/orders",
+	} {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithKMSKey(key))
+		if err == nil {
+			t.Errorf("expected error for KMS key %q, got nil", key)
+		}
+	}
+}
+
+func TestWithKMSKey_EncryptsEnvironmentOnCreateAndUpdate(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithKMSKey(keyARN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data")).CreateLambdaCommand
+	if aws.ToString(create.KMSKeyArn) != keyARN {
+		t.Errorf("expected key on create, got %q", aws.ToString(create.KMSKeyArn))
+	}
+	update := glambda.UpdateConfigurationCommand(*l)
+	if update == nil || aws.ToString(update.KMSKeyArn) != keyARN {
+		t.Fatalf("expected key on update, got %+v", update)
+	}
+	if update.Environment != nil {
+		t.Errorf("expected environment to be left alone, got %v", update.Environment)
+	}
+}
+
+func TestWithKMSKey_GrantsRoleDecryptOnKeyOnly(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.Sandbox(),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`),
+		glambda.WithKMSKey(keyARN),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range glambda.PutRolePolicyCommand(l.ExecutionRole) {
+		names = append(names, aws.ToString(p.PolicyName))
+	}
+	if len(names) != 2 || names[1] != glambda.KMSPolicyName {
+		t.Fatalf("expected user and KMS policies, got %v", names)
+	}
+	want := `{"Version":"2012-10-17","Statement":[{"Sid":"GlambdaFnKmsDecrypt1","Effect":"Allow","Action":["kms:Decrypt"],"Resource":"` + keyARN + `"}]}`
+	if l.ExecutionRole.KMSPolicy != want {
+		t.Errorf("want %s, got %s", want, l.ExecutionRole.KMSPolicy)
+	}
+}
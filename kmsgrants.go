@@ -0,0 +1,273 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	qTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+// KMSGrantsPolicyName is the name of the inline policy that lets the execution
+// role use the KMS keys encrypting the buckets and queues the function reads
+// and writes, see [WithKMSGrants].
+const KMSGrantsPolicyName = "glambda_kms_grants"
+
+// WithKMSGrants is a deploy option that looks up the customer managed KMS
+// keys encrypting the S3 buckets and SQS queues named in the function's
+// inline policy, event sources and destinations, and adds an inline policy
+// named [KMSGrantsPolicyName] to the execution role allowing kms:Decrypt on
+// them. Keys of resources the function writes to, with s3:PutObject or
+// sqs:SendMessage, also get kms:GenerateDataKey.
+//
+// Without it, reading an SSE-KMS object or receiving from an encrypted queue
+// fails at run time with an access denied error that doesn't mention KMS.
+// Resources encrypted with AWS managed keys need no grant and are skipped.
+// Keys that can't be looked up, or are only known by an alias, are reported
+// and left out of the policy.
+func WithKMSGrants() DeployOptions {
+	return func(l *Lambda) error {
+		l.kmsGrants = true
+		return nil
+	}
+}
+
+// kmsGrantTarget is a bucket or queue named in one of the execution role's
+// policies, and whether the role writes to it.
+type kmsGrantTarget struct {
+	service string
+	name    string
+	region  string
+	account string
+	writes  bool
+}
+
+// kmsGrantsPolicy looks up the KMS key of each bucket and queue the execution
+// role's policies give access to and returns the [KMSGrantsPolicyName]
+// policy, or "" if none of them are encrypted with a customer managed key.
+func (l Lambda) kmsGrantsPolicy(ctx context.Context) (string, error) {
+	role := l.ExecutionRole
+	var targets []kmsGrantTarget
+	for _, doc := range []string{role.InLinePolicy, role.EventSourcePolicy, role.DestinationPolicy} {
+		found, err := kmsGrantTargets(doc)
+		if err != nil {
+			return "", err
+		}
+		targets = append(targets, found...)
+	}
+	var decrypt, generate []string
+	for _, t := range targets {
+		key, err := l.targetKey(ctx, t)
+		if err != nil {
+			l.report("kms", "can't look up the KMS key of %s %s: %v", t.service, t.name, err)
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		if !kmsKeyARNRegex.MatchString(key) {
+			l.report("kms", "%s %s is encrypted with %s, which can't be granted by alias, grant its key ARN in the inline policy", t.service, t.name, key)
+			continue
+		}
+		l.detail("kms", "%s %s is encrypted with %s", t.service, t.name, key)
+		decrypt = append(decrypt, key)
+		if t.writes {
+			generate = append(generate, key)
+		}
+	}
+	if len(decrypt) == 0 {
+		return "", nil
+	}
+	policy, err := KMSGrantsPolicy(decrypt, generate)
+	if err != nil {
+		return "", err
+	}
+	return withStatementIDs(policy, l.Name, KMSGrantsPolicyName)
+}
+
+// KMSGrantsPolicy returns an IAM policy document granting kms:Decrypt on the
+// decrypt keys and kms:GenerateDataKey on the generate keys.
+func KMSGrantsPolicy(decrypt, generate []string) (string, error) {
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource []string
+	}
+	doc := struct {
+		Version   string
+		Statement []statement
+	}{Version: "2012-10-17"}
+	slices.Sort(decrypt)
+	doc.Statement = append(doc.Statement, statement{
+		Effect:   "Allow",
+		Action:   []string{"kms:Decrypt"},
+		Resource: slices.Compact(decrypt),
+	})
+	if len(generate) > 0 {
+		slices.Sort(generate)
+		doc.Statement = append(doc.Statement, statement{
+			Effect:   "Allow",
+			Action:   []string{"kms:GenerateDataKey"},
+			Resource: slices.Compact(generate),
+		})
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// kmsGrantTargets finds the buckets and queues an IAM policy allows access
+// to. Resources with wildcards in the bucket or queue name are skipped, as
+// there's no one key to look up.
+func kmsGrantTargets(doc string) ([]kmsGrantTarget, error) {
+	if doc == "" {
+		return nil, nil
+	}
+	var policy struct {
+		Statement []struct {
+			Effect   string
+			Action   json.RawMessage
+			Resource json.RawMessage
+		}
+	}
+	err := json.Unmarshal([]byte(doc), &policy)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing policy, %w", err)
+	}
+	var targets []kmsGrantTarget
+	for _, s := range policy.Statement {
+		if s.Effect != "Allow" {
+			continue
+		}
+		actions := policyStrings(s.Action)
+		for _, resource := range policyStrings(s.Resource) {
+			parts := strings.SplitN(resource, ":", 6)
+			if len(parts) != 6 || parts[0] != "arn" {
+				continue
+			}
+			t := kmsGrantTarget{service: parts[2], region: parts[3], account: parts[4]}
+			switch t.service {
+			case "s3":
+				t.name, _, _ = strings.Cut(parts[5], "/")
+				t.writes = grantsAction(actions, "s3", "PutObject")
+			case "sqs":
+				t.name = parts[5]
+				t.writes = grantsAction(actions, "sqs", "SendMessage")
+			default:
+				continue
+			}
+			if t.name == "" || strings.ContainsAny(t.name, "*?$") {
+				continue
+			}
+			i := slices.IndexFunc(targets, func(o kmsGrantTarget) bool {
+				return o.service == t.service && o.name == t.name && o.account == t.account
+			})
+			if i >= 0 {
+				targets[i].writes = targets[i].writes || t.writes
+				continue
+			}
+			targets = append(targets, t)
+		}
+	}
+	return targets, nil
+}
+
+// grantsAction reports whether a statement's actions include the given one,
+// directly or through a wildcard.
+func grantsAction(actions []string, service, action string) bool {
+	for _, a := range actions {
+		switch strings.ToLower(a) {
+		case "*", service + ":*", strings.ToLower(service + ":" + action):
+			return true
+		}
+	}
+	return false
+}
+
+// policyStrings reads a policy element that may be a single string or a list
+// of strings.
+func policyStrings(raw json.RawMessage) []string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []string{s}
+	}
+	var list []string
+	_ = json.Unmarshal(raw, &list)
+	return list
+}
+
+// targetKey is the ARN, or alias, of the customer managed KMS key that
+// encrypts a bucket or queue, or "" if it isn't encrypted with one.
+func (l Lambda) targetKey(ctx context.Context, t kmsGrantTarget) (string, error) {
+	region := t.region
+	if region == "" {
+		region = l.cfg.Region
+	}
+	account := t.account
+	if account == "" {
+		account = l.AWSAccountID
+	}
+	var key string
+	switch t.service {
+	case "s3":
+		out, err := l.s3API().GetBucketEncryption(ctx, &s3.GetBucketEncryptionInput{
+			Bucket: aws.String(t.name),
+		})
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ServerSideEncryptionConfigurationNotFoundError" {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if out.ServerSideEncryptionConfiguration == nil {
+			return "", nil
+		}
+		for _, rule := range out.ServerSideEncryptionConfiguration.Rules {
+			sse := rule.ApplyServerSideEncryptionByDefault
+			if sse != nil && sse.SSEAlgorithm != sTypes.ServerSideEncryptionAes256 && aws.ToString(sse.KMSMasterKeyID) != "" {
+				key = aws.ToString(sse.KMSMasterKeyID)
+			}
+		}
+	case "sqs":
+		url, err := l.sqsAPI().GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+			QueueName:              aws.String(t.name),
+			QueueOwnerAWSAccountId: aws.String(account),
+		})
+		if err != nil {
+			return "", err
+		}
+		out, err := l.sqsAPI().GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       url.QueueUrl,
+			AttributeNames: []qTypes.QueueAttributeName{qTypes.QueueAttributeNameKmsMasterKeyId},
+		})
+		if err != nil {
+			return "", err
+		}
+		key = out.Attributes[string(qTypes.QueueAttributeNameKmsMasterKeyId)]
+	}
+	return kmsKeyARN(key, region, account), nil
+}
+
+// kmsKeyARN turns the key ID a bucket or queue reports into a key ARN in its
+// region and account. AWS managed keys, such as alias/aws/sqs, need no grant
+// and become "". Other aliases are returned as they are.
+func kmsKeyARN(key, region, account string) string {
+	switch {
+	case key == "", strings.HasPrefix(key, "alias/aws/"), strings.Contains(key, ":alias/aws/"):
+		return ""
+	case strings.HasPrefix(key, "arn:"), strings.HasPrefix(key, "alias/"):
+		return key
+	}
+	return fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", region, account, key)
+}
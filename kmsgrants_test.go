@@ -0,0 +1,129 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestKMSGrantsPolicy_GrantsGenerateDataKeyOnlyOnWrittenKeys(t *testing.T) {
+	t.Parallel()
+	other := "arn:aws:kms:us-east-1:123456789012:key/other"
+	got, err := glambda.KMSGrantsPolicy([]string{other, keyARN, other}, []string{other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Version":"2012-10-17","Statement":[` +
+		`{"Effect":"Allow","Action":["kms:Decrypt"],"Resource":["` + keyARN + `","` + other + `"]},` +
+		`{"Effect":"Allow","Action":["kms:GenerateDataKey"],"Resource":["` + other + `"]}]}`
+	if got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
+
+func TestPlan_WithKMSGrantsGrantsKeysOfEncryptedBucketsAndQueues(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetBucketEncryption", &s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: &sTypes.ServerSideEncryptionConfiguration{
+			Rules: []sTypes.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &sTypes.ServerSideEncryptionByDefault{
+					SSEAlgorithm:   sTypes.ServerSideEncryptionAwsKms,
+					KMSMasterKeyID: aws.String("1234abcd-12ab-34cd-56ef-1234567890ab"),
+				},
+			}},
+		},
+	})
+	queueKey := "arn:aws:kms:us-east-1:123456789012:key/queue"
+	recorder.Respond("GetQueueAttributes", &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]string{"KmsMasterKeyId": queueKey},
+	})
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[`+
+			`{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::orders/*"},`+
+			`{"Effect":"Allow","Action":["sqs:SendMessage"],"Resource":"arn:aws:sqs:us-east-1:123456789012:invoices"}]}`),
+		glambda.WithKMSGrants(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `policy: {"Version":"2012-10-17","Statement":[` +
+		`{"Sid":"GlambdaFnKmsGrants1","Effect":"Allow","Action":["kms:Decrypt"],"Resource":["` + keyARN + `","` + queueKey + `"]},` +
+		`{"Sid":"GlambdaFnKmsGrants2","Effect":"Allow","Action":["kms:GenerateDataKey"],"Resource":["` + queueKey + `"]}]}`
+	if !strings.Contains(plan.String(), want) {
+		t.Errorf("expected plan to contain %s, got:\n%s", want, plan.String())
+	}
+	calls := recorder.Calls("GetBucketEncryption", "GetQueueUrl")
+	if len(calls) != 2 || aws.ToString(calls[0].Input.(*s3.GetBucketEncryptionInput).Bucket) != "orders" ||
+		aws.ToString(calls[1].Input.(*sqs.GetQueueUrlInput).QueueName) != "invoices" {
+		t.Errorf("expected lookups of bucket orders and queue invoices, got %+v", calls)
+	}
+}
+
+func TestPlan_WithKMSGrantsSkipsAWSManagedKeysAndWildcards(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetQueueAttributes", &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]string{"KmsMasterKeyId": "alias/aws/sqs"},
+	})
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[`+
+			`{"Effect":"Allow","Action":"s3:GetObject","Resource":["arn:aws:s3:::orders/*","arn:aws:s3:::logs-*/*"]},`+
+			`{"Effect":"Allow","Action":"sqs:ReceiveMessage","Resource":"arn:aws:sqs:us-east-1:123456789012:invoices"}]}`),
+		glambda.WithKMSGrants(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(plan.String(), glambda.KMSGrantsPolicyName) {
+		t.Errorf("expected no grants for SSE-S3 and AWS managed keys, got:\n%s", plan.String())
+	}
+	if calls := recorder.Calls("GetBucketEncryption"); len(calls) != 1 {
+		t.Errorf("expected the wildcard bucket to be skipped, got %+v", calls)
+	}
+}
+
+func TestPlan_WithKMSGrantsReportsAliasesInsteadOfGrantingThem(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetQueueAttributes", &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]string{"KmsMasterKeyId": "alias/invoices"},
+	})
+	var progress strings.Builder
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithProgress(glambda.TextReporter(&progress, false)),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"sqs:*","Resource":"arn:aws:sqs:us-east-1:123456789012:invoices"}]}`),
+		glambda.WithKMSGrants(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(plan.String(), glambda.KMSGrantsPolicyName) {
+		t.Errorf("expected no grant for an alias, got:\n%s", plan.String())
+	}
+	if !strings.Contains(progress.String(), "alias/invoices") {
+		t.Errorf("expected the alias to be reported, got %q", progress.String())
+	}
+}
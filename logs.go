@@ -0,0 +1,126 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Logs is a method on the [Lambda] struct that reads every event written to
+// the function's log group since the given time, oldest first.
+func (l Lambda) Logs(ctx context.Context, since time.Time) ([]LogEvent, error) {
+	var events []LogEvent
+	err := l.eachLogEvent(ctx, since, func(e LogEvent) {
+		events = append(events, e)
+	})
+	return events, err
+}
+
+// FollowLogs is a method on the [Lambda] struct that calls fn with each event
+// written to the function's log group since the given time, oldest first,
+// then checks for new events every interval until ctx is done.
+func (l Lambda) FollowLogs(ctx context.Context, since time.Time, interval time.Duration, fn func(LogEvent)) error {
+	for {
+		err := l.eachLogEvent(ctx, since, func(e LogEvent) {
+			fn(e)
+			// Events are only timestamped to the millisecond.
+			since = e.Time.Add(time.Millisecond)
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// DefaultLogExportRate is how many requests a second [Lambda.ExportLogs]
+// makes by default. CloudWatch Logs allows five FilterLogEvents requests a
+// second for a whole account and region, so an export leaves room for
+// dashboards and tailing running at the same time.
+const DefaultLogExportRate = 2
+
+// LogExport is what [Lambda.ExportLogs] wrote.
+type LogExport struct {
+	Events int
+	// Files are the paths of the files written, one for each UTC day.
+	Files []string
+}
+
+// ExportLogs is a method on the [Lambda] struct that writes every event in
+// the function's log group since the given time to dir, for analysing an
+// incident offline. Events are written as JSON lines holding the time, log
+// stream and message, to one file for each UTC day named after the function
+// and the day, e.g. orders-2024-05-01.jsonl. Existing files for the same
+// days are replaced.
+//
+// Pages of events are requested at most perSecond times a second, or
+// [DefaultLogExportRate] if it isn't positive, so that a long export doesn't
+// starve other callers of the account's CloudWatch Logs quota. Requests that
+// are throttled anyway are retried by the AWS SDK.
+func (l Lambda) ExportLogs(ctx context.Context, since time.Time, dir string, perSecond float64) (LogExport, error) {
+	if perSecond <= 0 {
+		perSecond = DefaultLogExportRate
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return LogExport{}, fmt.Errorf("error creating log export directory, %w", err)
+	}
+	var export LogExport
+	var f *os.File
+	var enc *json.Encoder
+	var day string
+	err = l.pacedLogEvents(ctx, since, time.Duration(float64(time.Second)/perSecond), func(e LogEvent) error {
+		t := e.Time.UTC()
+		if d := t.Format(time.DateOnly); d != day {
+			if f != nil {
+				err := f.Close()
+				if err != nil {
+					return err
+				}
+			}
+			path := filepath.Join(dir, l.Name+"-"+d+".jsonl")
+			// Events are mostly in order, but a day seen before is
+			// appended to rather than replaced.
+			flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if slices.Contains(export.Files, path) {
+				flags = os.O_WRONLY | os.O_APPEND
+			} else {
+				export.Files = append(export.Files, path)
+			}
+			var err error
+			f, err = os.OpenFile(path, flags, 0644)
+			if err != nil {
+				return err
+			}
+			enc = json.NewEncoder(f)
+			day = d
+		}
+		export.Events++
+		return enc.Encode(struct {
+			Time    time.Time `json:"time"`
+			Stream  string    `json:"stream"`
+			Message string    `json:"message"`
+		}{t, e.Stream, e.Message})
+	})
+	if f != nil {
+		closeErr := f.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return export, fmt.Errorf("error exporting logs of %s, %w", l.Name, err)
+	}
+	return export, nil
+}
@@ -0,0 +1,169 @@
+package glambda_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+// logGroup returns the events written since each query's start time, one
+// per page, and can be written to as a function would.
+type logGroup struct {
+	glambdatest.DummyCloudWatchLogsClient
+	mu     sync.Mutex
+	events []lTypes.FilteredLogEvent
+}
+
+func (g *logGroup) write(at time.Time, message string) {
+	g.writeStream(at, "", message)
+}
+
+func (g *logGroup) writeStream(at time.Time, stream, message string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e := lTypes.FilteredLogEvent{Timestamp: aws.Int64(at.UnixMilli()), Message: aws.String(message + "\n")}
+	if stream != "" {
+		e.LogStreamName = aws.String(stream)
+	}
+	g.events = append(g.events, e)
+}
+
+func (g *logGroup) FilterLogEvents(ctx context.Context, input *cloudwatchlogs.FilterLogEventsInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var matching []lTypes.FilteredLogEvent
+	for _, e := range g.events {
+		if len(input.LogStreamNames) > 0 && !slices.Contains(input.LogStreamNames, aws.ToString(e.LogStreamName)) {
+			continue
+		}
+		if aws.ToInt64(e.Timestamp) >= aws.ToInt64(input.StartTime) {
+			matching = append(matching, e)
+		}
+	}
+	page := 0
+	if input.NextToken != nil {
+		page = int(aws.ToString(input.NextToken)[0] - '0')
+	}
+	out := &cloudwatchlogs.FilterLogEventsOutput{}
+	if page < len(matching) {
+		out.Events = matching[page : page+1]
+	}
+	if page+1 < len(matching) {
+		out.NextToken = aws.String(string(rune('0' + page + 1)))
+	}
+	return out, nil
+}
+
+func TestLogs_ReadsEveryPageOfEventsSinceTheStart(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	group := &logGroup{}
+	group.write(start.Add(-time.Second), "too old")
+	group.write(start, "first")
+	group.write(start.Add(time.Second), "second")
+	l, err := glambda.NewLambda("logger", "", glambda.WithCloudWatchLogsClient(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := l.Logs(context.Background(), start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []glambda.LogEvent{
+		{Time: time.UnixMilli(start.UnixMilli()), Message: "first"},
+		{Time: time.UnixMilli(start.Add(time.Second).UnixMilli()), Message: "second"},
+	}
+	if !cmp.Equal(want, events) {
+		t.Error(cmp.Diff(want, events))
+	}
+}
+
+func TestFollowLogs_PrintsNewEventsOnceUntilCancelled(t *testing.T) {
+	t.Parallel()
+	start := time.Now().Add(-time.Minute)
+	group := &logGroup{}
+	group.write(start, "first")
+	l, err := glambda.NewLambda("logger", "", glambda.WithCloudWatchLogsClient(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var messages []string
+	err = l.FollowLogs(ctx, start, time.Millisecond, func(e glambda.LogEvent) {
+		messages = append(messages, e.Message)
+		if e.Message == "first" {
+			group.write(start.Add(time.Second), "second")
+			return
+		}
+		cancel()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"first", "second"}
+	if !cmp.Equal(want, messages) {
+		t.Error(cmp.Diff(want, messages))
+	}
+}
+
+func TestExportLogs_WritesAFileOfJSONLinesForEachDay(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2026, 1, 2, 23, 59, 0, 0, time.UTC)
+	group := &logGroup{}
+	group.writeStream(start.Add(-time.Second), "a", "too old")
+	group.writeStream(start, "2026/01/02/[$LATEST]a", "first")
+	group.writeStream(start.Add(2*time.Minute), "2026/01/02/[$LATEST]b", "second")
+	l, err := glambda.NewLambda("logger", "", glambda.WithCloudWatchLogsClient(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "logs")
+	export, err := l.ExportLogs(context.Background(), start, dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := []string{filepath.Join(dir, "logger-2026-01-02.jsonl"), filepath.Join(dir, "logger-2026-01-03.jsonl")}
+	if export.Events != 2 || !cmp.Equal(wantFiles, export.Files) {
+		t.Fatalf("expected 2 events in %v, got %+v", wantFiles, export)
+	}
+	data, err := os.ReadFile(wantFiles[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"2026-01-03T00:01:00Z","stream":"2026/01/02/[$LATEST]b","message":"second"}` + "\n"
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+}
+
+func TestExportLogs_PacesRequestsForPages(t *testing.T) {
+	t.Parallel()
+	start := time.Now().Add(-time.Hour)
+	group := &logGroup{}
+	for i := range 3 {
+		group.write(start.Add(time.Duration(i)*time.Second), "event")
+	}
+	l, err := glambda.NewLambda("logger", "", glambda.WithCloudWatchLogsClient(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	began := time.Now()
+	_, err = l.ExportLogs(context.Background(), start, t.TempDir(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(began); elapsed < 100*time.Millisecond {
+		t.Errorf("expected 3 pages at 20 a second to take at least 100ms, took %s", elapsed)
+	}
+}
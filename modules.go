@@ -0,0 +1,179 @@
+package glambda
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// ModuleTagPrefix is the prefix of the function tags that record the Go
+// modules compiled into the deployed handler. The list is split across
+// numbered tags, as a single tag value is limited to 256 characters.
+const ModuleTagPrefix = "glambda:modules:"
+
+const (
+	maxTagValueLength = 256
+	maxModuleTags     = 40
+)
+
+// Module is a Go module compiled into a handler binary.
+type Module struct {
+	Path    string
+	Version string
+}
+
+// String formats the module as path@version.
+func (m Module) String() string {
+	return m.Path + "@" + m.Version
+}
+
+// PackageModules reads the list of Go modules compiled into the bootstrap
+// binary of a deployment package, as recorded by the Go toolchain. Replaced
+// modules are reported as their replacement.
+func PackageModules(pkg []byte) ([]Module, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(pkg), int64(len(pkg)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read deployment package: %w", err)
+	}
+	f, err := zipReader.Open("bootstrap")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find bootstrap in deployment package: %w", err)
+	}
+	defer f.Close()
+	binary, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	info, err := buildinfo.Read(bytes.NewReader(binary))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read build information from bootstrap: %w", err)
+	}
+	var modules []Module
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		// Local replacements have no version, or the placeholder "(devel)",
+		// whose parentheses aren't allowed in tag values.
+		version := dep.Version
+		if version == "" || version == "(devel)" {
+			version = "devel"
+		}
+		modules = append(modules, Module{Path: dep.Path, Version: version})
+	}
+	slices.SortFunc(modules, func(a, b Module) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return modules, nil
+}
+
+// ModuleTags packs a module list into as few [ModuleTagPrefix] tags as
+// possible. If the list is too long to fit in the tags available, the last
+// tag records that the list was truncated.
+func ModuleTags(modules []Module) map[string]string {
+	return moduleTags(modules, maxModuleTags)
+}
+
+// moduleTags is [ModuleTags], using at most limit tags.
+func moduleTags(modules []Module, limit int) map[string]string {
+	tags := map[string]string{}
+	var chunk []string
+	length := 0
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		tags[ModuleTagPrefix+strconv.Itoa(len(tags))] = strings.Join(chunk, " ")
+		chunk, length = nil, 0
+	}
+	for _, m := range modules {
+		entry := m.String()
+		if length+len(entry)+1 > maxTagValueLength {
+			flush()
+		}
+		if len(tags) == limit-1 {
+			chunk = []string{"...truncated"}
+			break
+		}
+		chunk = append(chunk, entry)
+		length += len(entry) + 1
+	}
+	flush()
+	return tags
+}
+
+// packageModuleTags is a best effort version of [PackageModules] and
+// [ModuleTags], using at most limit tags. Recording modules is informational,
+// so a package whose build information can't be read is deployed without
+// module tags rather than failing the deploy.
+func packageModuleTags(pkg []byte, limit int) map[string]string {
+	modules, err := PackageModules(pkg)
+	if err != nil {
+		return nil
+	}
+	return moduleTags(modules, limit)
+}
+
+// functionTags are the tags of a function deployed with pkg: the tags given
+// to [WithTags], the module tags in whatever room the function has left, and
+// [ManagedTagKey] to mark the function as glambda's.
+func functionTags(pkg []byte, tags map[string]string) map[string]string {
+	limit := min(maxModuleTags, maxUserTags+1-len(tags))
+	all := withTagRecord(packageModuleTags(pkg, limit), tags)
+	all[ManagedTagKey] = "true"
+	return all
+}
+
+// ParseModuleTags reassembles the module list recorded by [ModuleTags] from
+// a function's tags. Tags that aren't module tags are ignored.
+func ParseModuleTags(tags map[string]string) []Module {
+	var keys []string
+	for k := range tags {
+		if strings.HasPrefix(k, ModuleTagPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(keys[i], ModuleTagPrefix))
+		b, _ := strconv.Atoi(strings.TrimPrefix(keys[j], ModuleTagPrefix))
+		return a < b
+	})
+	var modules []Module
+	for _, k := range keys {
+		for _, entry := range strings.Fields(tags[k]) {
+			path, version, found := strings.Cut(entry, "@")
+			if !found {
+				continue
+			}
+			modules = append(modules, Module{Path: path, Version: version})
+		}
+	}
+	return modules
+}
+
+// DeployedModules fetches the module list recorded on a deployed function.
+func DeployedModules(ctx context.Context, c LambdaClient, name string) ([]Module, error) {
+	resp, err := c.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParseModuleTags(resp.Tags), nil
+}
+
+// Modules is a method on the [Lambda] struct that returns the Go modules
+// recorded against the deployed function.
+func (l Lambda) Modules(ctx context.Context) ([]Module, error) {
+	return DeployedModules(ctx, l.lambdaAPI(), l.Name)
+}
@@ -0,0 +1,132 @@
+package glambda_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestModuleTags_RoundTripsThroughParseModuleTags(t *testing.T) {
+	t.Parallel()
+	var modules []glambda.Module
+	for i := 0; i < 30; i++ {
+		modules = append(modules, glambda.Module{
+			Path:    fmt.Sprintf("github.com/example/module%02d", i),
+			Version: "v1.2.3",
+		})
+	}
+	tags := glambda.ModuleTags(modules)
+	if len(tags) < 2 {
+		t.Fatalf("expected module list to be split across several tags, got %d", len(tags))
+	}
+	for k, v := range tags {
+		if len(v) > 256 {
+			t.Errorf("tag %s is %d characters, longer than the 256 allowed", k, len(v))
+		}
+	}
+	got := glambda.ParseModuleTags(tags)
+	if !cmp.Equal(modules, got) {
+		t.Error(cmp.Diff(modules, got))
+	}
+}
+
+func TestParseModuleTags_IgnoresOtherTags(t *testing.T) {
+	t.Parallel()
+	got := glambda.ParseModuleTags(map[string]string{
+		"team":              "payments",
+		"glambda:modules:0": "github.com/aws/aws-lambda-go@v1.47.0",
+	})
+	want := []glambda.Module{{Path: "github.com/aws/aws-lambda-go", Version: "v1.47.0"}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestPackageModules_ReadsModulesFromBuiltHandler(t *testing.T) {
+	t.Parallel()
+	data, err := glambda.Package("testdata/correct_test_handler/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules, err := glambda.PackageModules(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := slices.ContainsFunc(modules, func(m glambda.Module) bool {
+		return m.Path == "github.com/aws/aws-lambda-go" && strings.HasPrefix(m.Version, "v")
+	})
+	if !found {
+		t.Errorf("expected aws-lambda-go in module list, got %v", modules)
+	}
+}
+
+func TestPackageModules_ErrorsOnInvalidPackage(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.PackageModules([]byte("not a zip"))
+	if err == nil {
+		t.Error("expected error for invalid package, got nil")
+	}
+}
+
+func TestUpdateLambdaActionDo_ReplacesStaleModuleTags(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{
+		Recorder:   recorder,
+		FuncExists: true,
+		Tags: map[string]string{
+			"team":              "payments",
+			"glambda:modules:0": "github.com/old/module@v0.1.0",
+			"glambda:modules:1": "github.com/old/other@v0.1.0",
+		},
+	}
+	action := glambda.NewLambdaUpdateAction(client, glambda.Lambda{Name: "testLambda"}, []byte("some valid zip data"))
+	action.Tags = map[string]string{
+		"glambda:modules:0": "github.com/new/module@v1.0.0",
+	}
+	err := action.Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	untag := recorder.Calls("UntagResource")
+	if len(untag) != 1 {
+		t.Fatalf("expected 1 UntagResource call, got %d", len(untag))
+	}
+	keys := untag[0].Input.(*lambda.UntagResourceInput).TagKeys
+	if !cmp.Equal([]string{"glambda:modules:1"}, keys) {
+		t.Errorf("expected only stale module tag to be removed, got %v", keys)
+	}
+	tag := recorder.Calls("TagResource")
+	if len(tag) != 1 {
+		t.Fatalf("expected 1 TagResource call, got %d", len(tag))
+	}
+	got := tag[0].Input.(*lambda.TagResourceInput).Tags
+	if !cmp.Equal(action.Tags, got) {
+		t.Error(cmp.Diff(action.Tags, got))
+	}
+}
+
+func TestModules_ReadsModulesFromDeployedFunctionTags(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyLambdaClient{
+		FuncExists: true,
+		Tags: map[string]string{
+			"glambda:modules:0": "github.com/aws/aws-lambda-go@v1.47.0",
+		},
+	}
+	got, err := glambda.DeployedModules(context.Background(), client, "testLambda")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []glambda.Module{{Path: "github.com/aws/aws-lambda-go", Version: "v1.47.0"}}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
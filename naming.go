@@ -0,0 +1,149 @@
+package glambda
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// NamingConvention holds the templates used to generate names for the AWS
+// resources glambda creates on behalf of a function. Each is a [text/template]
+// executed with [NameData], and may use the lower and upper functions. For
+// example "{{.Function | lower}}-exec" or "{{.Function}}-inline-{{.Hash}}".
+//
+// Any template left empty falls back to glambda's default naming.
+type NamingConvention struct {
+	Role      string `yaml:"role"`
+	Policy    string `yaml:"policy"`
+	Statement string `yaml:"statement"`
+}
+
+// NameData is the data available to [NamingConvention] templates.
+type NameData struct {
+	// Function is the name of the lambda function.
+	Function string
+	// Hash is a short identifier, used to keep names unique. It is a hash of
+	// the function's name, so that every deploy of the function gives the
+	// same names, except in statement IDs, where it is a hash of the
+	// permission so that deploying the same resource policy again gives the
+	// same ID.
+	Hash string
+}
+
+// DefaultNamingConvention is the naming used where no template is given. Role
+// names are those glambda has always generated. Policy names and statement
+// IDs were once random, and are now hashes, so that deploying a function
+// again replaces its policy and permission rather than adding more.
+var DefaultNamingConvention = NamingConvention{
+	Role:      "glambda_exec_role_{{.Function | lower}}",
+	Policy:    "glambda_inline_policy_{{.Hash}}",
+	Statement: "glambda_invoke_permission_{{.Hash}}",
+}
+
+var (
+	roleNameRegex    = regexp.MustCompile(`^[\w+=,.@-]{1,64}$`)
+	policyNameRegex  = regexp.MustCompile(`^[\w+=,.@-]{1,128}$`)
+	statementIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,100}$`)
+)
+
+var namingFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+func renderName(kind, tmpl, fallback string, data NameData, valid *regexp.Regexp) (string, error) {
+	if tmpl == "" {
+		tmpl = fallback
+	}
+	name, err := executeName(kind, tmpl, fallback, data)
+	if err != nil {
+		return "", err
+	}
+	if !valid.MatchString(name) {
+		return "", fmt.Errorf("%s name %q generated from template %q is not a valid AWS name", kind, name, tmpl)
+	}
+	return name, nil
+}
+
+// executeName executes a naming template, or fallback if it is empty, with
+// data, without checking that the result is a valid name.
+func executeName(kind, tmpl, fallback string, data NameData) (string, error) {
+	if tmpl == "" {
+		tmpl = fallback
+	}
+	t, err := template.New(kind).Funcs(namingFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s name template %q: %w", kind, tmpl, err)
+	}
+	buf := new(bytes.Buffer)
+	err = t.Execute(buf, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s name template %q: %w", kind, tmpl, err)
+	}
+	return buf.String(), nil
+}
+
+// nameHash is the [NameData] Hash for names generated for the given function.
+func nameHash(function string) string {
+	sum := sha256.Sum256([]byte(function))
+	return hex.EncodeToString(sum[:4])
+}
+
+// RoleName renders the execution role name for the given function.
+func (n NamingConvention) RoleName(function string) (string, error) {
+	data := NameData{Function: function, Hash: nameHash(function)}
+	return renderName("role", n.Role, DefaultNamingConvention.Role, data, roleNameRegex)
+}
+
+// PolicyName renders the inline policy name for the given function.
+func (n NamingConvention) PolicyName(function string) (string, error) {
+	data := NameData{Function: function, Hash: nameHash(function)}
+	return renderName("policy", n.Policy, DefaultNamingConvention.Policy, data, policyNameRegex)
+}
+
+// StatementID renders the resource policy statement ID for the given function.
+func (n NamingConvention) StatementID(function string) (string, error) {
+	data := NameData{Function: function, Hash: nameHash(function)}
+	return renderName("statement", n.Statement, DefaultNamingConvention.Statement, data, statementIDRegex)
+}
+
+// WithNamingConvention is a deploy option that overrides how glambda names the
+// execution role, its inline policy and the resource policy statement, so that
+// organisations with strict naming standards can comply. It must be passed to
+// [NewLambda] (or [Deploy]) so the names are generated before the role ARN is
+// resolved.
+func WithNamingConvention(n NamingConvention) DeployOptions {
+	return func(l *Lambda) error {
+		// render once up front so that bad templates are reported immediately
+		for _, render := range []func(string) (string, error){n.RoleName, n.PolicyName, n.StatementID} {
+			_, err := render(l.Name)
+			if err != nil {
+				return err
+			}
+		}
+		l.naming = &n
+		return nil
+	}
+}
+
+func (l *Lambda) applyNamingConvention() error {
+	if l.naming == nil {
+		return nil
+	}
+	var err error
+	l.ExecutionRole.RoleName, err = l.naming.RoleName(l.Name)
+	if err != nil {
+		return err
+	}
+	l.ExecutionRole.InlinePolicyName, err = l.naming.PolicyName(l.Name)
+	if err != nil {
+		return err
+	}
+	data := NameData{Function: l.Name, Hash: permissionHash(l.ResourcePolicy)}
+	l.ResourcePolicy.StatementID, err = renderName("statement", l.naming.Statement, DefaultNamingConvention.Statement, data, statementIDRegex)
+	return err
+}
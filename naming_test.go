@@ -0,0 +1,113 @@
+package glambda_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestNamingConvention_DefaultNamesRoleAfterFunctionAndPolicyAfterItsHash(t *testing.T) {
+	t.Parallel()
+	n := glambda.NamingConvention{}
+	role, err := n.RoleName("MyFunction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role != "glambda_exec_role_myfunction" {
+		t.Errorf("expected glambda_exec_role_myfunction, got %s", role)
+	}
+	policy, err := n.PolicyName("MyFunction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != "glambda_inline_policy_03497482" {
+		t.Errorf("expected glambda_inline_policy_03497482, got %s", policy)
+	}
+}
+
+func TestNamingConvention_HashIsStableForEachFunction(t *testing.T) {
+	t.Parallel()
+	n := glambda.NamingConvention{Role: "{{.Function}}-{{.Hash}}"}
+	first, err := n.RoleName("Orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := n.RoleName("Orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := n.RoleName("Invoices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != again || first == other {
+		t.Errorf("expected the same role name on every deploy, and different ones per function, got %s, %s and %s", first, again, other)
+	}
+}
+
+func TestWithNamingConvention_NamesRolePolicyAndStatement(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("Orders", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[]}`),
+		glambda.WithResourcePolicy(`{"Principal":{"Service":"s3.amazonaws.com"}}`),
+		glambda.WithNamingConvention(glambda.NamingConvention{
+			Role:      "{{.Function | lower}}-exec",
+			Policy:    "{{.Function}}-inline-{{.Hash}}",
+			Statement: "{{.Function}}-invoke",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.ExecutionRole.RoleName != "orders-exec" {
+		t.Errorf("expected role name orders-exec, got %s", l.ExecutionRole.RoleName)
+	}
+	if l.ExecutionRole.RoleARN != "arn:aws:iam::123456789012:role/orders-exec" {
+		t.Errorf("expected role ARN to use templated name, got %s", l.ExecutionRole.RoleARN)
+	}
+	policies := glambda.PutRolePolicyCommand(l.ExecutionRole)
+	if len(policies) != 1 || aws.ToString(policies[0].PolicyName) != "Orders-inline-b7e8acdd" {
+		t.Errorf("expected inline policy named Orders-inline-b7e8acdd, got %v", policies)
+	}
+	permission := l.CreateLambdaResourcePolicy()
+	if aws.ToString(permission.StatementId) != "Orders-invoke" {
+		t.Errorf("expected statement ID Orders-invoke, got %s", aws.ToString(permission.StatementId))
+	}
+}
+
+func TestWithNamingConvention_RejectsInvalidTemplates(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		description string
+		naming      glambda.NamingConvention
+	}{
+		{
+			description: "unparseable template",
+			naming:      glambda.NamingConvention{Role: "{{.Function"},
+		},
+		{
+			description: "unknown field",
+			naming:      glambda.NamingConvention{Policy: "{{.Team}}-policy"},
+		},
+		{
+			description: "invalid characters in statement ID",
+			naming:      glambda.NamingConvention{Statement: "{{.Function}}:invoke"},
+		},
+		{
+			description: "role name too long",
+			naming:      glambda.NamingConvention{Role: "{{.Function}}-an-extremely-long-suffix-that-pushes-the-role-name-past-the-limit"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			l := glambda.Lambda{Name: "Orders"}
+			err := glambda.WithNamingConvention(tc.naming)(&l)
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
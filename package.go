@@ -3,48 +3,209 @@ package glambda
 import (
 	"archive/zip"
 	"bytes"
+	"errors"
 	"fmt"
+	"go/build"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 )
 
-// Package takes a path to a file, attempts to build it for the ARM64 architecture
+// Package takes a path to a handler, attempts to build it for the ARM64 architecture
 // and massages it into the format expected by AWS Lambda.
 //
+// The path may be a single Go source file, or a directory containing a main
+// package. If a file is given that has sibling files in the same main package,
+// the whole package is built so that handlers split across several files work.
+//
+// The handler is built within the Go module it belongs to, so the module's
+// go.mod and go.sum decide dependency versions, and replace directives and
+// private modules work as they would for go build. A handler that isn't part
+// of any module is built in a temporary module whose dependencies are resolved
+// with go mod tidy.
+//
 // The result is a zip file containing the executable binary within the context
 // of a file system.
 func Package(path string) ([]byte, error) {
-	data, err := buildBinary(path)
+	return PackageFor(path, types.ArchitectureArm64)
+}
+
+// PackageFor is [Package] for a specific Lambda architecture, either
+// arm64 or x86_64.
+func PackageFor(path string, arch types.Architecture) ([]byte, error) {
+	return PackageWith(path, BuildOptions{Architecture: arch})
+}
+
+// BuildOptions control how [PackageWith] builds a handler.
+type BuildOptions struct {
+	// Architecture is the Lambda architecture to build for. Defaults to arm64.
+	Architecture types.Architecture
+	// TemplateData, when not nil, turns on template rendering. Every source
+	// file of the handler is rendered as a text/template with this data
+	// before it is compiled, see [RenderHandlerTemplates].
+	TemplateData map[string]string
+	// StrictTemplates makes a reference to a key missing from TemplateData an
+	// error, rather than rendering as an empty string.
+	StrictTemplates bool
+	// KeepBuildDir, when not nil, leaves the build workspace in place rather
+	// than removing it, and is told its path and the exact go build command
+	// that ran, so that packaging failures can be reproduced by hand.
+	KeepBuildDir io.Writer
+	// Assets are files and directories added to the zip next to bootstrap,
+	// see [WithAssets].
+	Assets []string
+	// AssetDir is the directory that relative asset paths are read from, and
+	// kept relative to in the zip. Defaults to the working directory.
+	AssetDir string
+	// AllowSensitiveAssets allows .env files and .git directories among the
+	// assets, see [WithSensitiveAssets].
+	AllowSensitiveAssets bool
+	// Exclude leaves assets matching any of the patterns out of the zip, see
+	// [WithExcludes].
+	Exclude []string
+	// EmitModule, when set, is a directory that the go.mod and go.sum the
+	// handler was built with are copied to, so the dependency versions in
+	// the artifact can be audited. For a handler outside any module these
+	// are the files of the temporary module glambda synthesized.
+	EmitModule string
+	// Flags are extra flags passed to go build, see [WithBuildFlags].
+	Flags []string
+	// CGO builds the handler with cgo, using the C compiler CC, or zig if
+	// CC is empty, see [WithCGO].
+	CGO bool
+	CC  string
+}
+
+// PackageWith is [Package] with full control over the build.
+func PackageWith(path string, opts BuildOptions) ([]byte, error) {
+	assets, err := assetFiles(opts, path)
 	if err != nil {
 		return nil, err
 	}
-	return zipCode(data)
+	return packageWith(path, opts, assets)
 }
 
-func buildBinary(path string) ([]byte, error) {
-	err := os.Setenv("GOOS", "linux")
+// packageWith is [PackageWith] with the assets already listed.
+func packageWith(path string, opts BuildOptions, assets []assetFile) ([]byte, error) {
+	if opts.Architecture == "" {
+		opts.Architecture = types.ArchitectureArm64
+	}
+	goarch, err := GOARCH(opts.Architecture)
 	if err != nil {
 		return nil, err
 	}
-	err = os.Setenv("GOARCH", "arm64")
+	data, err := buildBinary(path, goarch, opts)
 	if err != nil {
 		return nil, err
 	}
-	tempBootstrap, err := os.MkdirTemp("", "bootstrap")
+	return zipCode(data, assets...)
+}
+
+// GOARCH translates a Lambda architecture into the equivalent GOARCH.
+func GOARCH(arch types.Architecture) (string, error) {
+	switch arch {
+	case types.ArchitectureArm64:
+		return "arm64", nil
+	case types.ArchitectureX8664:
+		return "amd64", nil
+	}
+	return "", fmt.Errorf("unsupported architecture %q, expected arm64 or x86_64", arch)
+}
+
+func buildBinary(path, goarch string, opts BuildOptions) ([]byte, error) {
+	// Each build gets a workspace of its own, named after the handler so
+	// that concurrent builds are easy to tell apart.
+	workDir, err := os.MkdirTemp("", "glambda-build-"+handlerName(path)+"-*")
 	if err != nil {
 		return nil, err
 	}
-	defer os.Remove(tempBootstrap)
-
-	tempBootstrap += "/bootstrap"
+	removeAll := os.RemoveAll
+	if opts.KeepBuildDir != nil {
+		removeAll = func(string) error { return nil }
+		fmt.Fprintf(opts.KeepBuildDir, "keeping build directory %s\n", workDir)
+	}
+	defer removeAll(workDir)
+	bootstrap := filepath.Join(workDir, "bootstrap")
 
-	cmd := exec.Command("go", "build", "-tags", "lambda.norpc", "-o", tempBootstrap, path)
+	src, err := resolveHandler(path, goarch)
+	if err != nil {
+		return nil, err
+	}
+	var cc string
+	if opts.CGO {
+		cc, err = cCompiler(opts.CC, goarch)
+	} else {
+		err = checkCgo(src.Files)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = checkGoToolchain(src, path, goarch, opts.Flags)
+	if err != nil {
+		return nil, err
+	}
+	dir, target := src.Dir, "./"+filepath.Base(path)
+	if src.IsPackage {
+		target = "."
+	}
+	moduleRoot, ok := FindModuleRoot(src.Dir)
+	if !ok {
+		dir, err = synthesizeModule(src)
+		if err != nil {
+			return nil, err
+		}
+		defer removeAll(dir)
+		moduleRoot = dir
+		target = "."
+		for i, f := range src.Files {
+			src.Files[i] = filepath.Join(dir, filepath.Base(f))
+		}
+	}
+	// Set per command rather than with os.Setenv, so that concurrent builds
+	// for different architectures don't interfere with each other.
+	vars := buildVars(os.Environ(), goarch, cc)
+	if !opts.CGO {
+		err = checkCgoDeps(path, dir, target, append(os.Environ(), vars...))
+		if err != nil {
+			return nil, err
+		}
+	}
+	args, err := goBuildArgs(bootstrap, opts.Flags)
+	if err != nil {
+		return nil, err
+	}
+	if opts.TemplateData != nil {
+		overlay, err := renderOverlay(src.Files, workDir, opts.TemplateData, opts.StrictTemplates)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "-overlay", overlay)
+	}
+	cmd := exec.Command("go", append(args, target)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), vars...)
+	if opts.KeepBuildDir != nil {
+		fmt.Fprintf(opts.KeepBuildDir, "cd %s && %s %s\n", dir, shellQuote(vars...), shellQuote(cmd.Args...))
+	}
 	msg, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("error building lambda function: %w, %s", err, msg)
+		return nil, fmt.Errorf("error building lambda function: %w, %s", err, sourcePositions(string(msg), dir, src.Dir))
+	}
+	if opts.EmitModule != "" {
+		err = emitModule(moduleRoot, opts.EmitModule)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	data, err := os.ReadFile(tempBootstrap)
+	data, err := os.ReadFile(bootstrap)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +213,232 @@ func buildBinary(path string) ([]byte, error) {
 	return data, nil
 }
 
-func zipCode(code []byte) ([]byte, error) {
+// goFilePosition matches the file:line at the start of a compiler error.
+var goFilePosition = regexp.MustCompile(`(?m)^(\s*)(\S+\.go)(:\d+)`)
+
+// sourcePositions rewrites the file:line positions of compiler errors from
+// go build, which are relative to buildDir, the directory it ran in, to
+// paths under srcDir, the handler's directory as the user gave it. For a
+// handler outside any module buildDir is a temporary copy, whose paths
+// would mean nothing to the user. Positions outside buildDir, such as in
+// the module cache, are left alone.
+func sourcePositions(output, buildDir, srcDir string) string {
+	return goFilePosition.ReplaceAllStringFunc(output, func(match string) string {
+		m := goFilePosition.FindStringSubmatch(match)
+		path := m[2]
+		if filepath.IsAbs(path) {
+			rel, err := filepath.Rel(buildDir, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return match
+			}
+			path = rel
+		}
+		return m[1] + filepath.Join(srcDir, path) + m[3]
+	})
+}
+
+// buildVars are the variables go build runs with, on top of the caller's
+// environment. Cgo is off, as handlers are cross-compiled, unless a C
+// compiler cc is given for a [WithCGO] build. The module's
+// go.mod and go.sum are never rewritten, so that many functions from one
+// module can be packaged at once without racing to update them; the module
+// cache has locking of its own. A vendor directory is still honoured.
+func buildVars(environ []string, goarch, cc string) []string {
+	var goflags []string
+	for _, kv := range environ {
+		value, ok := strings.CutPrefix(kv, "GOFLAGS=")
+		if !ok {
+			continue
+		}
+		for _, flag := range strings.Fields(value) {
+			if flag != "-mod=mod" {
+				goflags = append(goflags, flag)
+			}
+		}
+	}
+	if !slices.ContainsFunc(goflags, func(f string) bool { return strings.HasPrefix(f, "-mod=") }) {
+		goflags = append(goflags, "-mod=readonly")
+	}
+	cgo := []string{"CGO_ENABLED=0"}
+	if cc != "" {
+		cgo = cgoVars(cc)
+	}
+	vars := []string{"GOOS=linux", "GOARCH=" + goarch}
+	vars = append(vars, cgo...)
+	return append(vars, "GOFLAGS="+strings.Join(goflags, " "))
+}
+
+// shellQuote joins words into a line that can be pasted into a shell.
+func shellQuote(words ...string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w == "" || strings.ContainsAny(w, " \t\n'\"$\\*?;&|<>()") {
+			w = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+		}
+		quoted[i] = w
+	}
+	return strings.Join(quoted, " ")
+}
+
+// emitModule copies the go.mod and go.sum of the module in dir to out,
+// creating out if need be. A module without dependencies has no go.sum, so
+// none is written.
+func emitModule(dir, out string) error {
+	err := os.MkdirAll(out, 0755)
+	if err != nil {
+		return fmt.Errorf("error emitting module files, %w", err)
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) && name == "go.sum" {
+			continue
+		}
+		if err == nil {
+			err = os.WriteFile(filepath.Join(out, name), data, 0644)
+		}
+		if err != nil {
+			return fmt.Errorf("error emitting module files, %w", err)
+		}
+	}
+	return nil
+}
+
+// handlerName is a short name for the handler at path, for naming its
+// build workspace.
+func handlerName(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "handler"
+	}
+	name := strings.TrimSuffix(filepath.Base(abs), ".go")
+	if name == "main" {
+		name = filepath.Base(filepath.Dir(abs))
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '*' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// FindModuleRoot walks up from dir looking for the go.mod of the module that
+// dir belongs to, returning the directory that contains it.
+func FindModuleRoot(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		info, err := os.Stat(filepath.Join(dir, "go.mod"))
+		if err == nil && !info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// synthesizeModule copies a handler that isn't part of any Go module into a
+// temporary module of its own, resolving its dependencies with go mod tidy.
+// The caller is responsible for removing the returned directory.
+func synthesizeModule(src HandlerSource) (string, error) {
+	dir, err := os.MkdirTemp("", "glambda-module")
+	if err != nil {
+		return "", err
+	}
+	for _, f := range src.Files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		err = os.WriteFile(filepath.Join(dir, filepath.Base(f)), data, 0644)
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	for _, args := range [][]string{{"mod", "init", "main"}, {"mod", "tidy"}} {
+		cmd := exec.Command("go", args...)
+		cmd.Dir = dir
+		msg, err := cmd.CombinedOutput()
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("error creating module for handler outside of a Go module: %w, %s", err, msg)
+		}
+	}
+	return dir, nil
+}
+
+// HandlerSource describes the Go source files that make up a lambda handler.
+type HandlerSource struct {
+	// Dir is the directory containing the handler.
+	Dir string
+	// Files are the paths of the Go source files that will be compiled.
+	Files []string
+	// IsPackage is true when the handler is built as a whole package
+	// directory, rather than as a single file.
+	IsPackage bool
+}
+
+// ResolveHandler works out which Go files make up the handler at path. A
+// directory resolves to the main package within it. A file resolves to its
+// whole package if it has siblings in the same main package, otherwise to
+// just that file. Build constraints are evaluated for linux/arm64, the
+// platform the handler is built for.
+func ResolveHandler(path string) (HandlerSource, error) {
+	return resolveHandler(path, "arm64")
+}
+
+func resolveHandler(path, goarch string) (HandlerSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return HandlerSource{}, fmt.Errorf("failure in reading %s: %w", path, err)
+	}
+	ctx := build.Default
+	ctx.GOOS = "linux"
+	ctx.GOARCH = goarch
+	if info.IsDir() {
+		pkg, err := ctx.ImportDir(path, 0)
+		if err != nil {
+			return HandlerSource{}, fmt.Errorf("failure in reading package %s: %w", path, err)
+		}
+		if pkg.Name != "main" {
+			return HandlerSource{}, fmt.Errorf("package %s is %q, but a lambda handler must be package main", path, pkg.Name)
+		}
+		return HandlerSource{Dir: path, Files: packageFiles(path, pkg), IsPackage: true}, nil
+	}
+	single := HandlerSource{Dir: filepath.Dir(path), Files: []string{path}}
+	// A directory that doesn't form a single clean package (for example a
+	// folder of unrelated scripts) is treated as a collection of single files.
+	pkg, err := ctx.ImportDir(single.Dir, 0)
+	if err != nil || pkg.Name != "main" {
+		return single, nil
+	}
+	files := packageFiles(single.Dir, pkg)
+	if len(files) < 2 || !slices.Contains(files, filepath.Join(single.Dir, filepath.Base(path))) {
+		return single, nil
+	}
+	return HandlerSource{Dir: single.Dir, Files: files, IsPackage: true}, nil
+}
+
+func packageFiles(dir string, pkg *build.Package) []string {
+	var files []string
+	for _, f := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		files = append(files, filepath.Join(dir, f))
+	}
+	slices.Sort(files)
+	return files
+}
+
+// zipCode zips the executable as bootstrap, followed by any assets. Entries
+// carry no modification time, so the same inputs always give the same zip,
+// and an unchanged function isn't redeployed.
+func zipCode(code []byte, assets ...assetFile) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	zipWriter := zip.NewWriter(buf)
 	header := &zip.FileHeader{
@@ -70,9 +456,33 @@ func zipCode(code []byte) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to write code to zip file: %v", err)
 	}
+	for _, a := range assets {
+		err = zipAsset(zipWriter, a)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add asset %s to zip file: %v", a.path, err)
+		}
+	}
 	// Close the ZIP writer to finalize the archive
 	if err := zipWriter.Close(); err != nil {
 		return nil, fmt.Errorf("failed to close zip writer: %v", err)
 	}
 	return buf.Bytes(), nil
 }
+
+func zipAsset(w *zip.Writer, a assetFile) error {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+	header := &zip.FileHeader{
+		Name:   a.name,
+		Method: zip.Deflate,
+	}
+	header.SetMode(a.mode)
+	f, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
@@ -0,0 +1,121 @@
+package glambda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// defaultStatementPrefix starts the ID of the resource policy statement
+// given with [WithResourcePolicy], unless a [NamingConvention] says otherwise.
+const defaultStatementPrefix = "glambda_invoke_permission_"
+
+// permissionHash identifies a resource policy by its principal and
+// conditions, so that deploying the same policy again gives the same
+// statement ID rather than adding a duplicate statement.
+func permissionHash(p ResourcePolicy) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		p.Principal,
+		aws.ToString(p.SourceAccountCondition),
+		aws.ToString(p.SourceArnCondition),
+		aws.ToString(p.PrincipalOrgIdCondition),
+	}, "\n")))
+	return hex.EncodeToString(sum[:4])
+}
+
+// statementPrefix is what the IDs of every resource policy statement glambda
+// has added for [WithResourcePolicy] start with, whatever their permission.
+// It is empty if a naming convention gives IDs nothing in common, in which
+// case earlier statements can't be told apart from anyone else's.
+func (l Lambda) statementPrefix() (string, error) {
+	if l.naming == nil {
+		return defaultStatementPrefix, nil
+	}
+	// Rendered with two hashes that differ in every character, the IDs
+	// share only the part that doesn't depend on the permission.
+	var ids [2]string
+	for i, hash := range []string{"00000000", "ffffffff"} {
+		id, err := executeName("statement", l.naming.Statement, DefaultNamingConvention.Statement, NameData{Function: l.Name, Hash: hash})
+		if err != nil {
+			return "", err
+		}
+		ids[i] = id
+	}
+	n := 0
+	for n < len(ids[0]) && n < len(ids[1]) && ids[0][n] == ids[1][n] {
+		n++
+	}
+	return ids[0][:n], nil
+}
+
+// permissionChanges compares a function's resource policy with the statement
+// glambda is about to add, returning the IDs of statements glambda added for
+// an earlier resource policy, and whether the new statement is missing. With
+// no statement to add, every statement glambda added is returned.
+func permissionChanges(ctx context.Context, c LambdaClient, function string, cmd *lambda.AddPermissionInput, prefix string) ([]string, bool, error) {
+	if cmd == nil && prefix == "" {
+		return nil, false, nil
+	}
+	policy, err := c.GetPolicy(ctx, &lambda.GetPolicyInput{
+		FunctionName: aws.String(function),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, cmd != nil, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	statements, err := policyStatements(aws.ToString(policy.Policy))
+	if err != nil {
+		return nil, false, err
+	}
+	var id string
+	if cmd != nil {
+		id = aws.ToString(cmd.StatementId)
+	}
+	var stale []string
+	missing := cmd != nil
+	for _, s := range statements {
+		switch {
+		// An ID that is the whole prefix has no hash in it, so the
+		// statement may grant something else and is replaced.
+		case cmd != nil && s.Sid == id && id != prefix:
+			missing = false
+		case prefix != "" && strings.HasPrefix(s.Sid, prefix):
+			stale = append(stale, s.Sid)
+		}
+	}
+	return stale, missing, nil
+}
+
+// replacePermission brings the function's resource policy in line with cmd,
+// removing statements glambda added for earlier resource policies and adding
+// cmd's statement if it isn't already there. A nil cmd removes the statements
+// of a resource policy that is no longer wanted.
+func replacePermission(ctx context.Context, c LambdaClient, function string, cmd *lambda.AddPermissionInput, prefix string) error {
+	stale, missing, err := permissionChanges(ctx, c, function, cmd, prefix)
+	if err != nil {
+		return err
+	}
+	for _, sid := range stale {
+		_, err := c.RemovePermission(ctx, &lambda.RemovePermissionInput{
+			FunctionName: aws.String(function),
+			StatementId:  aws.String(sid),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !missing {
+		return nil
+	}
+	_, err = c.AddPermission(ctx, cmd)
+	return err
+}
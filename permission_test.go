@@ -0,0 +1,165 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+const s3InvokePolicy = `{"Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},"Action":"lambda:InvokeFunction","Resource":"*"}`
+
+func statementID(t *testing.T, policy string, opts ...glambda.DeployOptions) string {
+	t.Helper()
+	opts = append([]glambda.DeployOptions{glambdatest.Sandbox(), glambda.WithResourcePolicy(policy)}, opts...)
+	l, err := glambda.NewLambda("orders", "", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aws.ToString(l.CreateLambdaResourcePolicy().StatementId)
+}
+
+func TestResourcePolicyStatementID_IsDeterministic(t *testing.T) {
+	t.Parallel()
+	first, second := statementID(t, s3InvokePolicy), statementID(t, s3InvokePolicy)
+	if first != second {
+		t.Errorf("expected the same statement ID for the same policy, got %s and %s", first, second)
+	}
+	other := statementID(t, `{"Effect":"Allow","Principal":{"Service":"events.amazonaws.com"},"Action":"lambda:InvokeFunction","Resource":"*"}`)
+	if other == first {
+		t.Errorf("expected a different statement ID for a different principal, got %s for both", first)
+	}
+	named := statementID(t, s3InvokePolicy, glambda.WithNamingConvention(glambda.NamingConvention{Statement: "{{.Function}}-invoke-{{.Hash}}"}))
+	if named != "orders-invoke-"+first[len("glambda_invoke_permission_"):] {
+		t.Errorf("expected naming convention to use the permission hash, got %s", named)
+	}
+}
+
+// existingPolicy programs GetPolicy to return statements with the given IDs.
+func existingPolicy(recorder *glambdatest.Recorder, sids ...string) {
+	policy := `{"Version":"2012-10-17","Statement":[`
+	for i, sid := range sids {
+		if i > 0 {
+			policy += ","
+		}
+		policy += `{"Sid":"` + sid + `","Effect":"Allow","Principal":{"Service":"s3.amazonaws.com"},"Action":"lambda:InvokeFunction"}`
+	}
+	recorder.Respond("GetPolicy", &lambda.GetPolicyOutput{Policy: aws.String(policy + "]}")})
+}
+
+func deployUpdate(t *testing.T, recorder *glambdatest.Recorder, opts ...glambda.DeployOptions) *glambda.Lambda {
+	t.Helper()
+	opts = append([]glambda.DeployOptions{
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, ConsistantAfterXRetries: new(int), Tags: map[string]string{glambda.ManagedTagKey: "true"}}),
+		glambda.WithResourcePolicy(s3InvokePolicy),
+	}, opts...)
+	l, err := glambda.NewLambda("orders", "testdata/correct_test_handler/main.go", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestDeploy_LeavesIdenticalResourcePolicyStatementAlone(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	existingPolicy(recorder, statementID(t, s3InvokePolicy))
+	err := deployUpdate(t, recorder).Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"AddPermission", "RemovePermission"} {
+		if n := len(recorder.Calls(op)); n != 0 {
+			t.Errorf("expected no %s calls, got %d", op, n)
+		}
+	}
+}
+
+func TestDeploy_ReplacesStaleResourcePolicyStatements(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	existingPolicy(recorder,
+		"glambda_invoke_permission_0badf00d",
+		"glambda_invoke_permission_DEADBEEF",
+		glambda.FunctionURLStatementID,
+		"glambda_s3_uploads",
+		"hand-made",
+	)
+	err := deployUpdate(t, recorder).Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var removed []string
+	for _, call := range recorder.Calls("RemovePermission") {
+		removed = append(removed, aws.ToString(call.Input.(*lambda.RemovePermissionInput).StatementId))
+	}
+	if len(removed) != 2 || removed[0] != "glambda_invoke_permission_0badf00d" || removed[1] != "glambda_invoke_permission_DEADBEEF" {
+		t.Errorf("expected only stale glambda statements removed, got %v", removed)
+	}
+	calls := recorder.Calls("AddPermission")
+	if len(calls) != 1 || aws.ToString(calls[0].Input.(*lambda.AddPermissionInput).StatementId) != statementID(t, s3InvokePolicy) {
+		t.Errorf("expected the new statement to be added once, got %v", recorder.Operations())
+	}
+}
+
+func TestDeploy_ReplacesStaleStatementsNamedByANamingConvention(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	existingPolicy(recorder, "orders-invoke-0bad", "orders-hand-made")
+	naming := glambda.WithNamingConvention(glambda.NamingConvention{Statement: "{{.Function}}-invoke-{{slice .Hash 0 4}}"})
+	err := deployUpdate(t, recorder, naming).Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("RemovePermission")
+	if len(calls) != 1 || aws.ToString(calls[0].Input.(*lambda.RemovePermissionInput).StatementId) != "orders-invoke-0bad" {
+		t.Errorf("expected only the stale orders-invoke- statement removed, got %v", recorder.Operations())
+	}
+}
+
+func TestDeploy_RemovesGlambdaStatementsWhenNoResourcePolicyIsGiven(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	existingPolicy(recorder, "glambda_invoke_permission_0badf00d", glambda.FunctionURLStatementID, "hand-made")
+	l, err := glambda.NewLambda("orders", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, ConsistantAfterXRetries: new(int), Tags: map[string]string{glambda.ManagedTagKey: "true"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("RemovePermission")
+	if len(calls) != 1 || aws.ToString(calls[0].Input.(*lambda.RemovePermissionInput).StatementId) != "glambda_invoke_permission_0badf00d" {
+		t.Errorf("expected only the earlier resource policy statement removed, got %v", recorder.Operations())
+	}
+	if n := len(recorder.Calls("AddPermission")); n != 0 {
+		t.Errorf("expected no AddPermission calls, got %d", n)
+	}
+}
+
+func TestPlan_DescribesResourcePolicyStatementsReplacedOnUpdate(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	existingPolicy(recorder, "glambda_invoke_permission_0badf00d")
+	plan, err := deployUpdate(t, recorder).Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, c := range plan.Changes {
+		if c.ResourceType == "lambda_permission" {
+			actions = append(actions, c.Operation+" "+c.Action)
+		}
+	}
+	if len(actions) != 2 || actions[0] != "RemovePermission delete" || actions[1] != "AddPermission create" {
+		t.Errorf("expected stale statement removed and new one added, got %v", actions)
+	}
+}
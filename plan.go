@@ -0,0 +1,846 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	gTypes "github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// PlanFormatVersion is the version of the JSON document a [Plan] marshals to.
+// It changes only when a field is renamed or removed, so policies written
+// against the plan keep working as glambda gains features.
+const PlanFormatVersion = "1"
+
+// Change is a single AWS operation that a deploy would make.
+type Change struct {
+	// Operation is the AWS API operation, e.g. "CreateRole".
+	Operation string `json:"operation"`
+	// ResourceType is the kind of resource changed, e.g. "iam_role".
+	ResourceType string `json:"resource_type"`
+	// Resource is the name of the resource the operation acts on.
+	Resource string `json:"resource"`
+	// Action is "create" for a new resource, "update" for an existing one, or
+	// "delete" for one that is removed.
+	Action string `json:"action"`
+	// Before holds the settings of an updated resource that the change
+	// replaces. It is nil for created resources.
+	Before map[string]any `json:"before"`
+	// After holds the settings the resource will have once the change is
+	// made. Policy documents are included as parsed JSON, not strings.
+	After map[string]any `json:"after"`
+	// Details describe the change for humans, see [Plan.String].
+	Details []string `json:"-"`
+}
+
+// Plan is the set of changes that deploying a [Lambda] would make, in the
+// order they would be made. It marshals to a stable JSON document, suitable
+// for checking with policy engines such as OPA before deploying.
+type Plan struct {
+	FormatVersion string   `json:"format_version"`
+	Function      string   `json:"function"`
+	Changes       []Change `json:"changes"`
+	// Warnings are problems that don't stop the deploy, such as a runtime
+	// that is, or soon will be, deprecated.
+	Warnings []string `json:"warnings"`
+	// prepared holds the actions behind the changes, for [Reconciler.Apply]
+	// and [ApplyAll].
+	prepared *preparedDeploy
+	// dependsOn names the functions [ApplyAll] deploys first.
+	dependsOn []string
+	// release is the function's release group, see [FunctionConfig.Release].
+	release string
+}
+
+// String renders the plan for humans, one change per line.
+func (p Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d changes\n", p.Function, len(p.Changes))
+	for _, c := range p.Changes {
+		sign := "+"
+		if c.Action == "delete" {
+			sign = "-"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", sign, c.Operation, c.Resource)
+		for _, d := range c.Details {
+			fmt.Fprintf(&b, "      %s\n", d)
+		}
+	}
+	for _, w := range p.Warnings {
+		fmt.Fprintf(&b, "  ! warning: %s\n", w)
+	}
+	return b.String()
+}
+
+// Plan is a method on the [Lambda] struct that prepares a deploy exactly as
+// [Lambda.Deploy] would, but returns the actions as a [Plan] instead of
+// executing them. Preparing a deploy builds the handler and reads the current
+// state of the role and function from AWS, but changes nothing. The plan can
+// be carried out with [ApplyAll], without building the handler again.
+func (l Lambda) Plan(ctx context.Context) (Plan, error) {
+	return l.preparePlan(ctx)
+}
+
+// describe turns prepared actions into the [Plan] that executing them would
+// carry out.
+func (l Lambda) describe(ctx context.Context, roleAction RoleAction, action LambdaAction) (Plan, error) {
+	plan := Plan{FormatVersion: PlanFormatVersion, Function: l.Name, Changes: []Change{}}
+	if role, ok := roleAction.(RoleCreateOrUpdate); ok {
+		plan.Changes = append(plan.Changes, describeRoleAction(role)...)
+	}
+	switch a := action.(type) {
+	case LambdaCreateAction:
+		plan.Changes = append(plan.Changes, describeCreateAction(a)...)
+	case LambdaUpdateAction:
+		changes, err := describeUpdateAction(ctx, a)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	if l.Destinations != nil {
+		change, err := describeEventInvokeConfig(ctx, l.lambdaAPI(), l.Name, *l.Destinations)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	if l.FunctionURL != nil {
+		change, err := describeFunctionURL(ctx, l.lambdaAPI(), l.Name, *l.FunctionURL)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	if l.HTTPAPI != nil {
+		changes, err := describeHTTPAPI(ctx, l.apiGatewayAPI(), l.Name, *l.HTTPAPI)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	if _, ok := action.(LambdaUpdateAction); ok {
+		stale, err := l.staleS3Triggers(ctx)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Changes = append(plan.Changes, describeStaleS3Triggers(l.Name, stale)...)
+	}
+	for _, trigger := range l.S3Triggers {
+		plan.Changes = append(plan.Changes, describeS3Trigger(NewS3TriggerAction(nil, nil, l, trigger))...)
+	}
+	for _, source := range l.EventSources {
+		change, err := describeEventSource(ctx, l.lambdaAPI(), l.Name, source)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+	if l.Canary != nil {
+		plan.Changes = append(plan.Changes, describeCanary(l.Name, *l.Canary))
+	}
+	plan.Changes = append(plan.Changes, describeConcurrency(l.Name, l.ReservedConcurrency, l.ProvisionedConcurrency)...)
+	plan.Changes = append(plan.Changes, describeAliases(l.Name, l.Aliases)...)
+	plan.Warnings = l.runtimeWarnings(action)
+	return plan, nil
+}
+
+// checkPolicies fails with a [PolicyViolationError] if the plan for the
+// prepared actions breaks any rule in the lambda's policy bundle.
+func (l Lambda) checkPolicies(ctx context.Context, roleAction RoleAction, action LambdaAction) error {
+	plan, err := l.describe(ctx, roleAction, action)
+	if err != nil {
+		return err
+	}
+	return CheckPolicies(l.policyBundle, plan)
+}
+
+// prepare builds both of the actions that make up a deploy without executing
+// either of them.
+func (l Lambda) prepare(ctx context.Context) (RoleAction, LambdaAction, error) {
+	executionRole := l.ExecutionRole
+	if l.kmsGrants {
+		policy, err := l.kmsGrantsPolicy(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		executionRole.KMSGrantsPolicy = policy
+	}
+	roleAction, err := PrepareRoleAction(ctx, executionRole, l.iamAPI())
+	if err != nil {
+		return nil, nil, err
+	}
+	if role, ok := roleAction.(RoleCreateOrUpdate); ok && role.Owner != "" {
+		if !l.adopt {
+			return nil, nil, &OwnedResourceError{Kind: "role", Name: l.ExecutionRole.RoleName, Owner: role.Owner, Override: "--adopt"}
+		}
+		l.report("adopt", "updating role %s, which is managed by %s", l.ExecutionRole.RoleName, role.Owner)
+	}
+	action, err := PrepareLambdaAction(ctx, l, l.lambdaAPI())
+	if err != nil {
+		return nil, nil, err
+	}
+	return roleAction, action, nil
+}
+
+func describeRoleAction(a RoleCreateOrUpdate) []Change {
+	var changes []Change
+	if a.CreateRole != nil {
+		change := Change{
+			Operation:    "CreateRole",
+			ResourceType: "iam_role",
+			Resource:     aws.ToString(a.CreateRole.RoleName),
+			Action:       "create",
+			After: map[string]any{
+				"assume_role_policy": policyDocument(aws.ToString(a.CreateRole.AssumeRolePolicyDocument)),
+			},
+			Details: []string{"assume role policy: " + aws.ToString(a.CreateRole.AssumeRolePolicyDocument)},
+		}
+		if a.CreateRole.Description != nil {
+			change.After["description"] = aws.ToString(a.CreateRole.Description)
+			change.Details = append(change.Details, "description: "+aws.ToString(a.CreateRole.Description))
+		}
+		changes = append(changes, change)
+	}
+	if a.UntagRole != nil {
+		changes = append(changes, Change{
+			Operation:    "UntagRole",
+			ResourceType: "iam_role_tags",
+			Resource:     aws.ToString(a.UntagRole.RoleName),
+			Action:       "update",
+			Before:       map[string]any{"tag_keys": a.UntagRole.TagKeys},
+			Details:      []string{"remove tags: " + strings.Join(a.UntagRole.TagKeys, ", ")},
+		})
+	}
+	if a.TagRole != nil {
+		tags := map[string]string{}
+		for _, t := range a.TagRole.Tags {
+			tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+		}
+		changes = append(changes, Change{
+			Operation:    "TagRole",
+			ResourceType: "iam_role_tags",
+			Resource:     aws.ToString(a.TagRole.RoleName),
+			Action:       "update",
+			After:        map[string]any{"tags": userTags(tags)},
+			Details:      []string{"tags: " + strings.Join(userTags(tags), ", ")},
+		})
+	}
+	for _, p := range a.ManagedPolicies {
+		changes = append(changes, Change{
+			Operation:    "AttachRolePolicy",
+			ResourceType: "iam_role_policy_attachment",
+			Resource:     aws.ToString(p.RoleName),
+			Action:       "create",
+			After:        map[string]any{"policy_arn": aws.ToString(p.PolicyArn)},
+			Details:      []string{"policy: " + aws.ToString(p.PolicyArn)},
+		})
+	}
+	for _, p := range a.InlinePolicies {
+		changes = append(changes, Change{
+			Operation:    "PutRolePolicy",
+			ResourceType: "iam_role_policy",
+			Resource:     aws.ToString(p.RoleName),
+			Action:       "create",
+			After: map[string]any{
+				"policy_name":     aws.ToString(p.PolicyName),
+				"policy_document": policyDocument(aws.ToString(p.PolicyDocument)),
+			},
+			Details: []string{
+				"policy name: " + aws.ToString(p.PolicyName),
+				"policy: " + aws.ToString(p.PolicyDocument),
+			},
+		})
+	}
+	return changes
+}
+
+// policyDocument parses a policy so that policy engines can inspect its
+// statements. A document that isn't valid JSON is kept as a string.
+func policyDocument(doc string) any {
+	var parsed any
+	err := json.Unmarshal([]byte(doc), &parsed)
+	if err != nil {
+		return doc
+	}
+	return parsed
+}
+
+func packageSize(zipFile []byte, upload *PackageUpload) int {
+	if upload != nil {
+		return len(upload.Package)
+	}
+	return len(zipFile)
+}
+
+// describeUpload notes where a package is staged in S3, if it is.
+func describeUpload(after map[string]any, upload *PackageUpload) []string {
+	if upload == nil {
+		return nil
+	}
+	location := "s3://" + upload.Bucket + "/" + upload.Key
+	after["package_location"] = location
+	return []string{"upload: " + location}
+}
+
+func describeCreateAction(a LambdaCreateAction) []Change {
+	cmd := a.CreateLambdaCommand
+	after := map[string]any{
+		"role":         aws.ToString(cmd.Role),
+		"package_size": packageSize(cmd.Code.ZipFile, a.Upload),
+	}
+	details := []string{
+		"role: " + aws.ToString(cmd.Role),
+		fmt.Sprintf("package: %d bytes", packageSize(cmd.Code.ZipFile, a.Upload)),
+	}
+	details = append(details, describeUpload(after, a.Upload)...)
+	if len(cmd.Architectures) > 0 {
+		after["architecture"] = string(cmd.Architectures[0])
+		details = append(details, "architecture: "+string(cmd.Architectures[0]))
+	}
+	if cmd.MemorySize != nil {
+		after["memory_size"] = *cmd.MemorySize
+		details = append(details, fmt.Sprintf("memory: %d MB", *cmd.MemorySize))
+	}
+	if cmd.Timeout != nil {
+		after["timeout"] = *cmd.Timeout
+		details = append(details, fmt.Sprintf("timeout: %ds", *cmd.Timeout))
+	}
+	if cmd.Environment != nil {
+		after["environment_keys"] = environmentKeys(cmd.Environment.Variables)
+		details = append(details, "environment: "+strings.Join(environmentKeys(cmd.Environment.Variables), ", "))
+	}
+	if cmd.KMSKeyArn != nil {
+		after["kms_key_arn"] = aws.ToString(cmd.KMSKeyArn)
+		details = append(details, "kms key: "+aws.ToString(cmd.KMSKeyArn))
+	}
+	if cmd.DeadLetterConfig != nil {
+		after["dead_letter_queue"] = aws.ToString(cmd.DeadLetterConfig.TargetArn)
+		details = append(details, "dead-letter queue: "+aws.ToString(cmd.DeadLetterConfig.TargetArn))
+	}
+	if cmd.VpcConfig != nil {
+		after["vpc"] = map[string]any{"subnet_ids": cmd.VpcConfig.SubnetIds, "security_group_ids": cmd.VpcConfig.SecurityGroupIds}
+		details = append(details, "vpc: "+describeVPC(cmd.VpcConfig.SubnetIds, cmd.VpcConfig.SecurityGroupIds))
+	}
+	if tags := userTags(cmd.Tags); len(tags) > 0 {
+		after["tags"] = tags
+		details = append(details, "tags: "+strings.Join(tags, ", "))
+	}
+	changes := []Change{{
+		Operation:    "CreateFunction",
+		ResourceType: "lambda_function",
+		Resource:     aws.ToString(cmd.FunctionName),
+		Action:       "create",
+		After:        after,
+		Details:      details,
+	}}
+	if p := a.ResourcePolicyCommand; p != nil {
+		changes = append(changes, describeAddPermission(p))
+	}
+	return changes
+}
+
+func describeAddPermission(p *lambda.AddPermissionInput) Change {
+	after := map[string]any{
+		"statement_id": aws.ToString(p.StatementId),
+		"action":       aws.ToString(p.Action),
+		"principal":    aws.ToString(p.Principal),
+	}
+	if p.SourceAccount != nil {
+		after["source_account"] = aws.ToString(p.SourceAccount)
+	}
+	if p.SourceArn != nil {
+		after["source_arn"] = aws.ToString(p.SourceArn)
+	}
+	if p.PrincipalOrgID != nil {
+		after["principal_org_id"] = aws.ToString(p.PrincipalOrgID)
+	}
+	return Change{
+		Operation:    "AddPermission",
+		ResourceType: "lambda_permission",
+		Resource:     aws.ToString(p.FunctionName),
+		Action:       "create",
+		After:        after,
+		Details: []string{
+			"statement: " + aws.ToString(p.StatementId),
+			"principal: " + aws.ToString(p.Principal),
+		},
+	}
+}
+
+// describePermissionChanges lists the statements an update removes from the
+// function's resource policy, and the one it adds, if it isn't there yet.
+func describePermissionChanges(ctx context.Context, c LambdaClient, function string, p *lambda.AddPermissionInput, prefix string) ([]Change, error) {
+	stale, missing, err := permissionChanges(ctx, c, function, p, prefix)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, sid := range stale {
+		changes = append(changes, Change{
+			Operation:    "RemovePermission",
+			ResourceType: "lambda_permission",
+			Resource:     function,
+			Action:       "delete",
+			Before:       map[string]any{"statement_id": sid},
+			Details:      []string{"statement: " + sid},
+		})
+	}
+	if missing {
+		changes = append(changes, describeAddPermission(p))
+	}
+	return changes, nil
+}
+
+func describeUpdateAction(ctx context.Context, a LambdaUpdateAction) ([]Change, error) {
+	cmd := a.UpdateLambdaCommand
+	if a.CodeUnchanged {
+		return describeUnchangedCode(ctx, a)
+	}
+	after := map[string]any{"package_size": packageSize(cmd.ZipFile, a.Upload)}
+	details := []string{fmt.Sprintf("package: %d bytes", packageSize(cmd.ZipFile, a.Upload))}
+	details = append(details, describeUpload(after, a.Upload)...)
+	if len(cmd.Architectures) > 0 {
+		after["architecture"] = string(cmd.Architectures[0])
+		details = append(details, "architecture: "+string(cmd.Architectures[0]))
+	}
+	if tags := userTags(a.Tags); len(tags) > 0 {
+		after["tags"] = tags
+		details = append(details, "tags: "+strings.Join(tags, ", "))
+	}
+	changes := []Change{{
+		Operation:    "UpdateFunctionCode",
+		ResourceType: "lambda_function",
+		Resource:     aws.ToString(cmd.FunctionName),
+		Action:       "update",
+		After:        after,
+		Details:      details,
+	}}
+	return describeUpdateSettings(ctx, a, changes)
+}
+
+// describeUnchangedCode describes an update whose code is already deployed,
+// which only reconciles the function's tags and settings.
+func describeUnchangedCode(ctx context.Context, a LambdaUpdateAction) ([]Change, error) {
+	var changes []Change
+	if tags := userTags(a.Tags); len(tags) > 0 {
+		changes = append(changes, Change{
+			Operation:    "TagResource",
+			ResourceType: "lambda_function_tags",
+			Resource:     aws.ToString(a.UpdateLambdaCommand.FunctionName),
+			Action:       "update",
+			After:        map[string]any{"tags": tags},
+			Details:      []string{"tags: " + strings.Join(tags, ", ")},
+		})
+	}
+	return describeUpdateSettings(ctx, a, changes)
+}
+
+// describeUpdateSettings adds the permission and configuration changes of an
+// update to changes.
+func describeUpdateSettings(ctx context.Context, a LambdaUpdateAction, changes []Change) ([]Change, error) {
+	cmd := a.UpdateLambdaCommand
+	permissions, err := describePermissionChanges(ctx, a.Client(), aws.ToString(cmd.FunctionName), a.ResourcePolicyCommand, a.StatementPrefix)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, permissions...)
+	update := a.UpdateConfigurationCommand
+	if update == nil {
+		return changes, nil
+	}
+	current, err := a.Client().GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: cmd.FunctionName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	before, after := map[string]any{}, map[string]any{}
+	var diff []string
+	if update.MemorySize != nil && aws.ToInt32(update.MemorySize) != aws.ToInt32(current.MemorySize) {
+		before["memory_size"], after["memory_size"] = aws.ToInt32(current.MemorySize), *update.MemorySize
+		diff = append(diff, fmt.Sprintf("memory: %d MB -> %d MB", aws.ToInt32(current.MemorySize), *update.MemorySize))
+	}
+	if update.Timeout != nil && aws.ToInt32(update.Timeout) != aws.ToInt32(current.Timeout) {
+		before["timeout"], after["timeout"] = aws.ToInt32(current.Timeout), *update.Timeout
+		diff = append(diff, fmt.Sprintf("timeout: %ds -> %ds", aws.ToInt32(current.Timeout), *update.Timeout))
+	}
+	if update.Environment != nil {
+		var env map[string]string
+		if current.Environment != nil {
+			env = current.Environment.Variables
+		}
+		envDiff := environmentDiff(env, update.Environment.Variables)
+		if len(envDiff) > 0 {
+			before["environment_keys"] = environmentKeys(env)
+			after["environment_keys"] = environmentKeys(update.Environment.Variables)
+		}
+		diff = append(diff, envDiff...)
+	}
+	if update.KMSKeyArn != nil && aws.ToString(update.KMSKeyArn) != aws.ToString(current.KMSKeyArn) {
+		before["kms_key_arn"], after["kms_key_arn"] = aws.ToString(current.KMSKeyArn), aws.ToString(update.KMSKeyArn)
+		currentKey := aws.ToString(current.KMSKeyArn)
+		if currentKey == "" {
+			currentKey = "aws managed"
+		}
+		diff = append(diff, fmt.Sprintf("kms key: %s -> %s", currentKey, aws.ToString(update.KMSKeyArn)))
+	}
+	if update.DeadLetterConfig != nil {
+		var currentQueue string
+		if current.DeadLetterConfig != nil {
+			currentQueue = aws.ToString(current.DeadLetterConfig.TargetArn)
+		}
+		if queue := aws.ToString(update.DeadLetterConfig.TargetArn); queue != currentQueue {
+			before["dead_letter_queue"], after["dead_letter_queue"] = currentQueue, queue
+			if currentQueue == "" {
+				currentQueue = "none"
+			}
+			diff = append(diff, fmt.Sprintf("dead-letter queue: %s -> %s", currentQueue, queue))
+		}
+	}
+	if update.VpcConfig != nil {
+		var subnets, securityGroups []string
+		if current.VpcConfig != nil {
+			subnets, securityGroups = current.VpcConfig.SubnetIds, current.VpcConfig.SecurityGroupIds
+		}
+		if !sameIDs(subnets, update.VpcConfig.SubnetIds) || !sameIDs(securityGroups, update.VpcConfig.SecurityGroupIds) {
+			before["vpc"] = map[string]any{"subnet_ids": subnets, "security_group_ids": securityGroups}
+			after["vpc"] = map[string]any{"subnet_ids": update.VpcConfig.SubnetIds, "security_group_ids": update.VpcConfig.SecurityGroupIds}
+			diff = append(diff, fmt.Sprintf("vpc: %s -> %s", describeVPC(subnets, securityGroups), describeVPC(update.VpcConfig.SubnetIds, update.VpcConfig.SecurityGroupIds)))
+		}
+	}
+	if len(diff) > 0 {
+		changes = append(changes, Change{
+			Operation:    "UpdateFunctionConfiguration",
+			ResourceType: "lambda_function",
+			Resource:     aws.ToString(cmd.FunctionName),
+			Action:       "update",
+			Before:       before,
+			After:        after,
+			Details:      diff,
+		})
+	}
+	return changes, nil
+}
+
+func describeFunctionURL(ctx context.Context, c LambdaClient, name string, url FunctionURL) (Change, error) {
+	after := map[string]any{
+		"auth_type":   string(url.AuthType),
+		"invoke_mode": string(url.invokeMode()),
+	}
+	details := []string{
+		"auth type: " + string(url.AuthType),
+		"invoke mode: " + string(url.invokeMode()),
+	}
+	if url.Cors != nil {
+		after["cors_origins"] = url.Cors.AllowOrigins
+		details = append(details, "cors origins: "+strings.Join(url.Cors.AllowOrigins, ", "))
+	}
+	change := Change{
+		Operation:    "CreateFunctionUrlConfig",
+		ResourceType: "lambda_function_url",
+		Resource:     name,
+		Action:       "create",
+		After:        after,
+		Details:      details,
+	}
+	current, err := c.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String(name),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return change, nil
+	}
+	if err != nil {
+		return Change{}, err
+	}
+	change.Operation, change.Action = "UpdateFunctionUrlConfig", "update"
+	change.Before = map[string]any{
+		"auth_type":   string(current.AuthType),
+		"invoke_mode": string(current.InvokeMode),
+	}
+	if current.Cors != nil {
+		change.Before["cors_origins"] = current.Cors.AllowOrigins
+	}
+	return change, nil
+}
+
+func describeHTTPAPI(ctx context.Context, c APIGatewayClient, name string, api HTTPAPI) ([]Change, error) {
+	existing, err := findHTTPAPI(ctx, c, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return []Change{{
+			Operation:    "CreateApi",
+			ResourceType: "apigateway_http_api",
+			Resource:     name,
+			Action:       "create",
+			After:        map[string]any{"routes": api.Routes},
+			Details:      []string{"routes: " + strings.Join(api.Routes, ", ")},
+		}}, nil
+	}
+	resp, err := c.GetRoutes(ctx, &apigatewayv2.GetRoutesInput{ApiId: existing.ApiId})
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, route := range api.Routes {
+		if !slices.ContainsFunc(resp.Items, func(r gTypes.Route) bool { return aws.ToString(r.RouteKey) == route }) {
+			changes = append(changes, Change{
+				Operation:    "CreateRoute",
+				ResourceType: "apigateway_route",
+				Resource:     name,
+				Action:       "create",
+				After:        map[string]any{"route": route},
+				Details:      []string{"route: " + route},
+			})
+		}
+	}
+	return changes, nil
+}
+
+// describeStaleS3Triggers lists the removal of the function's notifications
+// and permissions for buckets it no longer has triggers on, by statement ID.
+func describeStaleS3Triggers(function string, stale map[string]string) []Change {
+	var changes []Change
+	for _, sid := range sortedKeys(stale) {
+		bucket := stale[sid]
+		changes = append(changes,
+			Change{
+				Operation:    "PutBucketNotificationConfiguration",
+				ResourceType: "s3_bucket_notification",
+				Resource:     bucket,
+				Action:       "delete",
+				Before:       map[string]any{"function": function},
+				Details:      []string{"notifications for " + function},
+			},
+			Change{
+				Operation:    "RemovePermission",
+				ResourceType: "lambda_permission",
+				Resource:     function,
+				Action:       "delete",
+				Before:       map[string]any{"statement_id": sid},
+				Details:      []string{"statement: " + sid, "source: arn:aws:s3:::" + bucket},
+			},
+		)
+	}
+	return changes
+}
+
+func describeS3Trigger(a S3TriggerAction) []Change {
+	bucket := a.Trigger.Bucket
+	after := map[string]any{
+		"id":     a.NotificationID(),
+		"events": a.Trigger.Events,
+	}
+	details := []string{"events: " + strings.Join(a.Trigger.Events, ", ")}
+	if a.Trigger.Prefix != "" {
+		after["prefix"] = a.Trigger.Prefix
+		details = append(details, "prefix: "+a.Trigger.Prefix)
+	}
+	if a.Trigger.Suffix != "" {
+		after["suffix"] = a.Trigger.Suffix
+		details = append(details, "suffix: "+a.Trigger.Suffix)
+	}
+	return []Change{
+		{
+			Operation:    "AddPermission",
+			ResourceType: "lambda_permission",
+			Resource:     a.Name,
+			Action:       "create",
+			After: map[string]any{
+				"action":         "lambda:InvokeFunction",
+				"principal":      "s3.amazonaws.com",
+				"source_arn":     "arn:aws:s3:::" + bucket,
+				"source_account": a.AccountID,
+			},
+			Details: []string{"principal: s3.amazonaws.com", "source: arn:aws:s3:::" + bucket},
+		},
+		{
+			Operation:    "PutBucketNotificationConfiguration",
+			ResourceType: "s3_bucket_notification",
+			Resource:     bucket,
+			Action:       "update",
+			After:        after,
+			Details:      details,
+		},
+	}
+}
+
+func describeEventSource(ctx context.Context, c LambdaClient, name string, source EventSource) (Change, error) {
+	after := map[string]any{"event_source_arn": source.ARN}
+	details := []string{"source: " + source.ARN}
+	if source.BatchSize != 0 {
+		after["batch_size"] = source.BatchSize
+		details = append(details, fmt.Sprintf("batch size: %d", source.BatchSize))
+	}
+	if source.StartingPosition != "" {
+		after["starting_position"] = string(source.StartingPosition)
+		details = append(details, "starting position: "+string(source.StartingPosition))
+	}
+	if source.ParallelizationFactor != 0 {
+		after["parallelization_factor"] = source.ParallelizationFactor
+		details = append(details, fmt.Sprintf("parallelization factor: %d", source.ParallelizationFactor))
+	}
+	if source.OnFailure != "" {
+		after["on_failure"] = source.OnFailure
+		details = append(details, "on failure: "+source.OnFailure)
+	}
+	if source.BatchingWindowSeconds != 0 {
+		after["batching_window_seconds"] = source.BatchingWindowSeconds
+		details = append(details, fmt.Sprintf("batching window: %ds", source.BatchingWindowSeconds))
+	}
+	if source.ReportBatchItemFailures {
+		after["report_batch_item_failures"] = true
+		details = append(details, "reports batch item failures")
+	}
+	if source.MaximumConcurrency != 0 {
+		after["maximum_concurrency"] = source.MaximumConcurrency
+		details = append(details, fmt.Sprintf("maximum concurrency: %d", source.MaximumConcurrency))
+	}
+	if len(source.Filters) > 0 {
+		after["filters"] = source.Filters
+		for _, pattern := range source.Filters {
+			details = append(details, "filter: "+pattern)
+		}
+	}
+	change := Change{
+		Operation:    "CreateEventSourceMapping",
+		ResourceType: "lambda_event_source_mapping",
+		Resource:     name,
+		Action:       "create",
+		After:        after,
+		Details:      details,
+	}
+	existing, err := findEventSourceMapping(ctx, c, name, source.ARN)
+	if err != nil {
+		return Change{}, err
+	}
+	if existing != nil {
+		change.Operation, change.Action = "UpdateEventSourceMapping", "update"
+		change.Before = map[string]any{
+			"event_source_arn": source.ARN,
+			"batch_size":       aws.ToInt32(existing.BatchSize),
+		}
+		if existing.StartingPosition != "" {
+			change.Before["starting_position"] = string(existing.StartingPosition)
+		}
+		if existing.ParallelizationFactor != nil {
+			change.Before["parallelization_factor"] = aws.ToInt32(existing.ParallelizationFactor)
+		}
+		if existing.DestinationConfig != nil && existing.DestinationConfig.OnFailure != nil {
+			change.Before["on_failure"] = aws.ToString(existing.DestinationConfig.OnFailure.Destination)
+		}
+		if existing.MaximumBatchingWindowInSeconds != nil {
+			change.Before["batching_window_seconds"] = aws.ToInt32(existing.MaximumBatchingWindowInSeconds)
+		}
+		if slices.Contains(existing.FunctionResponseTypes, types.FunctionResponseTypeReportBatchItemFailures) {
+			change.Before["report_batch_item_failures"] = true
+		}
+		if existing.ScalingConfig != nil && existing.ScalingConfig.MaximumConcurrency != nil {
+			change.Before["maximum_concurrency"] = aws.ToInt32(existing.ScalingConfig.MaximumConcurrency)
+		}
+		if existing.FilterCriteria != nil && len(existing.FilterCriteria.Filters) > 0 {
+			var filters []string
+			for _, f := range existing.FilterCriteria.Filters {
+				filters = append(filters, aws.ToString(f.Pattern))
+			}
+			change.Before["filters"] = filters
+		}
+	}
+	return change, nil
+}
+
+// describeCanary can't know the versions involved, as the new one is only
+// published by the deploy.
+func describeCanary(name string, canary Canary) Change {
+	return Change{
+		Operation:    "UpdateAlias",
+		ResourceType: "lambda_alias",
+		Resource:     name + ":" + LiveAlias,
+		Action:       "update",
+		After: map[string]any{
+			"canary_percent":    canary.Percent,
+			"bake_time_seconds": int(canary.BakeTime.Seconds()),
+		},
+		Details: []string{
+			fmt.Sprintf("canary: %d%% of traffic to the new version for %s, then promote or roll back", canary.Percent, canary.BakeTime),
+		},
+	}
+}
+
+// environmentKeys lists variable names only, as values are often secrets that
+// shouldn't end up in CI logs.
+func environmentKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func environmentDiff(before, after map[string]string) []string {
+	var diff []string
+	for _, change := range EnvironmentChanges(before, after) {
+		diff = append(diff, "environment: "+change)
+	}
+	return diff
+}
+
+// PlanConfig returns the [Plan] for every function in a [Config], without
+// deploying any of them.
+func PlanConfig(ctx context.Context, cfg Config, opts ...DeployOptions) ([]Plan, error) {
+	var plans []Plan
+	var errs []error
+	for _, fn := range cfg.Functions {
+		plan, err := planFunction(ctx, fn, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fn.Name, err))
+			continue
+		}
+		plans = append(plans, plan)
+	}
+	return plans, errors.Join(errs...)
+}
+
+// planFunction plans a function of a [Config], reporting a "failed" [Event]
+// if its handler doesn't build or its state can't be read.
+func planFunction(ctx context.Context, fn FunctionConfig, opts []DeployOptions) (Plan, error) {
+	fnOpts, err := fn.Options()
+	if err != nil {
+		return Plan{}, err
+	}
+	l, err := NewLambdaWithContext(ctx, fn.Name, fn.Handler, append(opts[:len(opts):len(opts)], fnOpts...)...)
+	if err != nil {
+		return Plan{}, err
+	}
+	l.reportBuild()
+	plan, err := l.preparePlan(ctx)
+	if err != nil {
+		l.report("failed", "plan failed: %v", err)
+		return Plan{}, err
+	}
+	return plan, nil
+}
+
+// userTags lists the tags given to [WithTags] among tags as key=value, leaving
+// out the tags glambda adds itself.
+func userTags(tags map[string]string) []string {
+	var user []string
+	for _, k := range sortedKeys(tags) {
+		if !strings.HasPrefix(k, "glambda:") {
+			user = append(user, k+"="+tags[k])
+		}
+	}
+	return user
+}
@@ -0,0 +1,152 @@
+package glambda_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestPlan_DescribesCreateWithoutChangingAnything(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("planned", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithResourcePolicy(`{"Principal": {"Service": "s3.amazonaws.com"}}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range plan.Changes {
+		got = append(got, c.Operation)
+	}
+	want := []string{"CreateRole", "AttachRolePolicy", "AttachRolePolicy", "CreateFunction", "AddPermission"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	wantCalls := []string{"GetRole", "GetFunction"}
+	if !cmp.Equal(wantCalls, recorder.Operations()) {
+		t.Errorf("expected only read operations, %s", cmp.Diff(wantCalls, recorder.Operations()))
+	}
+}
+
+func TestPlan_DiffsConfigurationOnUpdate(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyLambdaClient{
+		FuncExists:  true,
+		Environment: map[string]string{"KEEP": "1", "CHANGE": "old", "REMOVE": "1"},
+	}
+	l, err := glambda.NewLambda("planned", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithLambdaClient(client),
+		glambda.WithMemory(512),
+		glambda.WithEnvironment(map[string]string{"KEEP": "1", "CHANGE": "new", "ADD": "1"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := plan.Changes[len(plan.Changes)-1]
+	want := glambda.Change{
+		Operation:    "UpdateFunctionConfiguration",
+		ResourceType: "lambda_function",
+		Resource:     "planned",
+		Action:       "update",
+		Before: map[string]any{
+			"memory_size":      int32(0),
+			"environment_keys": []string{"CHANGE", "KEEP", "REMOVE"},
+		},
+		After: map[string]any{
+			"memory_size":      int32(512),
+			"environment_keys": []string{"ADD", "CHANGE", "KEEP"},
+		},
+		Details: []string{
+			"memory: 0 MB -> 512 MB",
+			"environment: + ADD",
+			"environment: ~ CHANGE",
+			"environment: - REMOVE",
+		},
+	}
+	if !cmp.Equal(want, last) {
+		t.Error(cmp.Diff(want, last))
+	}
+}
+
+func TestPlan_MarshalsToJSONWithParsedPolicies(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("planned", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithInlinePolicy(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*"}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		FormatVersion string `json:"format_version"`
+		Changes       []struct {
+			Operation    string         `json:"operation"`
+			ResourceType string         `json:"resource_type"`
+			Action       string         `json:"action"`
+			Before       map[string]any `json:"before"`
+			After        map[string]any `json:"after"`
+		} `json:"changes"`
+	}
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.FormatVersion != glambda.PlanFormatVersion {
+		t.Errorf("expected format version %q, got %q", glambda.PlanFormatVersion, doc.FormatVersion)
+	}
+	for _, c := range doc.Changes {
+		if c.Operation != "PutRolePolicy" {
+			continue
+		}
+		if c.ResourceType != "iam_role_policy" || c.Action != "create" || c.Before != nil {
+			t.Errorf("unexpected change %+v", c)
+		}
+		statements, ok := c.After["policy_document"].(map[string]any)["Statement"].([]any)
+		if !ok || statements[0].(map[string]any)["Action"] != "s3:*" {
+			t.Errorf("expected parsed policy document, got %v", c.After["policy_document"])
+		}
+		return
+	}
+	t.Errorf("expected a PutRolePolicy change, got %s", data)
+}
+
+func TestPlan_StringListsEachChange(t *testing.T) {
+	t.Parallel()
+	plan := glambda.Plan{
+		Function: "planned",
+		Changes: []glambda.Change{
+			{Operation: "CreateRole", Resource: "glambda_exec_role_planned"},
+			{Operation: "CreateFunction", Resource: "planned", Details: []string{"memory: 512 MB"}},
+		},
+	}
+	want := "planned: 2 changes\n" +
+		"  + CreateRole glambda_exec_role_planned\n" +
+		"  + CreateFunction planned\n" +
+		"      memory: 512 MB\n"
+	if plan.String() != want {
+		t.Errorf("expected %q, got %q", want, plan.String())
+	}
+}
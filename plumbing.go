@@ -4,14 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/google/uuid"
 )
@@ -27,7 +35,32 @@ type LambdaClient interface {
 	PublishVersion(ctx context.Context, params *lambda.PublishVersionInput, optFns ...func(*lambda.Options)) (*lambda.PublishVersionOutput, error)
 	Invoke(ctx context.Context, params *lambda.InvokeInput, optFns ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
 	AddPermission(ctx context.Context, params *lambda.AddPermissionInput, optFns ...func(*lambda.Options)) (*lambda.AddPermissionOutput, error)
+	RemovePermission(ctx context.Context, params *lambda.RemovePermissionInput, optFns ...func(*lambda.Options)) (*lambda.RemovePermissionOutput, error)
 	DeleteFunction(ctx context.Context, params *lambda.DeleteFunctionInput, optFns ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error)
+	DeleteFunctionUrlConfig(ctx context.Context, params *lambda.DeleteFunctionUrlConfigInput, optFns ...func(*lambda.Options)) (*lambda.DeleteFunctionUrlConfigOutput, error)
+	GetFunctionConfiguration(ctx context.Context, params *lambda.GetFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error)
+	UpdateFunctionConfiguration(ctx context.Context, params *lambda.UpdateFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error)
+	ListTags(ctx context.Context, params *lambda.ListTagsInput, optFns ...func(*lambda.Options)) (*lambda.ListTagsOutput, error)
+	TagResource(ctx context.Context, params *lambda.TagResourceInput, optFns ...func(*lambda.Options)) (*lambda.TagResourceOutput, error)
+	UntagResource(ctx context.Context, params *lambda.UntagResourceInput, optFns ...func(*lambda.Options)) (*lambda.UntagResourceOutput, error)
+	GetFunctionUrlConfig(ctx context.Context, params *lambda.GetFunctionUrlConfigInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionUrlConfigOutput, error)
+	CreateFunctionUrlConfig(ctx context.Context, params *lambda.CreateFunctionUrlConfigInput, optFns ...func(*lambda.Options)) (*lambda.CreateFunctionUrlConfigOutput, error)
+	UpdateFunctionUrlConfig(ctx context.Context, params *lambda.UpdateFunctionUrlConfigInput, optFns ...func(*lambda.Options)) (*lambda.UpdateFunctionUrlConfigOutput, error)
+	ListEventSourceMappings(ctx context.Context, params *lambda.ListEventSourceMappingsInput, optFns ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error)
+	CreateEventSourceMapping(ctx context.Context, params *lambda.CreateEventSourceMappingInput, optFns ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error)
+	UpdateEventSourceMapping(ctx context.Context, params *lambda.UpdateEventSourceMappingInput, optFns ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error)
+	DeleteEventSourceMapping(ctx context.Context, params *lambda.DeleteEventSourceMappingInput, optFns ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error)
+	GetAlias(ctx context.Context, params *lambda.GetAliasInput, optFns ...func(*lambda.Options)) (*lambda.GetAliasOutput, error)
+	CreateAlias(ctx context.Context, params *lambda.CreateAliasInput, optFns ...func(*lambda.Options)) (*lambda.CreateAliasOutput, error)
+	UpdateAlias(ctx context.Context, params *lambda.UpdateAliasInput, optFns ...func(*lambda.Options)) (*lambda.UpdateAliasOutput, error)
+	DeleteAlias(ctx context.Context, params *lambda.DeleteAliasInput, optFns ...func(*lambda.Options)) (*lambda.DeleteAliasOutput, error)
+	GetPolicy(ctx context.Context, params *lambda.GetPolicyInput, optFns ...func(*lambda.Options)) (*lambda.GetPolicyOutput, error)
+	GetFunctionEventInvokeConfig(ctx context.Context, params *lambda.GetFunctionEventInvokeConfigInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionEventInvokeConfigOutput, error)
+	PutFunctionEventInvokeConfig(ctx context.Context, params *lambda.PutFunctionEventInvokeConfigInput, optFns ...func(*lambda.Options)) (*lambda.PutFunctionEventInvokeConfigOutput, error)
+	PutFunctionConcurrency(ctx context.Context, params *lambda.PutFunctionConcurrencyInput, optFns ...func(*lambda.Options)) (*lambda.PutFunctionConcurrencyOutput, error)
+	PutProvisionedConcurrencyConfig(ctx context.Context, params *lambda.PutProvisionedConcurrencyConfigInput, optFns ...func(*lambda.Options)) (*lambda.PutProvisionedConcurrencyConfigOutput, error)
+	GetProvisionedConcurrencyConfig(ctx context.Context, params *lambda.GetProvisionedConcurrencyConfigInput, optFns ...func(*lambda.Options)) (*lambda.GetProvisionedConcurrencyConfigOutput, error)
+	DeleteProvisionedConcurrencyConfig(ctx context.Context, params *lambda.DeleteProvisionedConcurrencyConfigInput, optFns ...func(*lambda.Options)) (*lambda.DeleteProvisionedConcurrencyConfigOutput, error)
 }
 
 // IAMClient represents the interface that an iam client should implement.
@@ -39,6 +72,93 @@ type IAMClient interface {
 	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
 	AttachRolePolicy(ctx context.Context, params *iam.AttachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error)
 	PutRolePolicy(ctx context.Context, params *iam.PutRolePolicyInput, optFns ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error)
+	ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error)
+	ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error)
+	TagRole(ctx context.Context, params *iam.TagRoleInput, optFns ...func(*iam.Options)) (*iam.TagRoleOutput, error)
+	UntagRole(ctx context.Context, params *iam.UntagRoleInput, optFns ...func(*iam.Options)) (*iam.UntagRoleOutput, error)
+	DetachRolePolicy(ctx context.Context, params *iam.DetachRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error)
+	DeleteRolePolicy(ctx context.Context, params *iam.DeleteRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error)
+	DeleteRole(ctx context.Context, params *iam.DeleteRoleInput, optFns ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
+	ListAccountAliases(ctx context.Context, params *iam.ListAccountAliasesInput, optFns ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error)
+}
+
+// CloudWatchLogsClient represents the interface that a cloudwatch logs client
+// should implement.
+//
+// The most obvious implementation is the cloudwatchlogs.Client from the aws-sdk-go-v2
+// However we also use it for mock clients in tests
+type CloudWatchLogsClient interface {
+	StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+	DeleteLogGroup(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error)
+	CreateLogStream(ctx context.Context, params *cloudwatchlogs.CreateLogStreamInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+	GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error)
+}
+
+// CloudWatchClient represents the interface that a cloudwatch client should
+// implement.
+//
+// The most obvious implementation is the cloudwatch.Client from the aws-sdk-go-v2
+// However we also use it for mock clients in tests
+type CloudWatchClient interface {
+	GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// APIGatewayClient represents the interface that an API Gateway v2 client
+// should implement.
+//
+// The most obvious implementation is the apigatewayv2.Client from the aws-sdk-go-v2
+// However we also use it for mock clients in tests
+type APIGatewayClient interface {
+	GetApis(ctx context.Context, params *apigatewayv2.GetApisInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error)
+	CreateApi(ctx context.Context, params *apigatewayv2.CreateApiInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateApiOutput, error)
+	GetIntegrations(ctx context.Context, params *apigatewayv2.GetIntegrationsInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error)
+	CreateIntegration(ctx context.Context, params *apigatewayv2.CreateIntegrationInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateIntegrationOutput, error)
+	GetRoutes(ctx context.Context, params *apigatewayv2.GetRoutesInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error)
+	CreateRoute(ctx context.Context, params *apigatewayv2.CreateRouteInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateRouteOutput, error)
+	CreateStage(ctx context.Context, params *apigatewayv2.CreateStageInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateStageOutput, error)
+	DeleteApi(ctx context.Context, params *apigatewayv2.DeleteApiInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.DeleteApiOutput, error)
+}
+
+// S3Client represents the interface that an s3 client should implement.
+//
+// The most obvious implementation is the s3.Client from the aws-sdk-go-v2
+// However we also use it for mock clients in tests
+type S3Client interface {
+	GetBucketNotificationConfiguration(ctx context.Context, params *s3.GetBucketNotificationConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketNotificationConfigurationOutput, error)
+	PutBucketNotificationConfiguration(ctx context.Context, params *s3.PutBucketNotificationConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketNotificationConfigurationOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
+}
+
+// SQSClient represents the interface that an sqs client should implement, for
+// reading the settings of queues a function uses, see [WithKMSGrants], and
+// fixing those of its triggers, see [WithFixQueue].
+//
+// The most obvious implementation is the sqs.Client from the aws-sdk-go-v2
+// However we also use it for mock clients in tests
+type SQSClient interface {
+	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	SetQueueAttributes(ctx context.Context, params *sqs.SetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error)
+}
+
+// DynamoDBClient represents the interface that a dynamodb client should
+// implement, for keeping an audit log in a table, see [DynamoDBAuditLog].
+//
+// The most obvious implementation is the dynamodb.Client from the aws-sdk-go-v2
+// However we also use it for mock clients in tests
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
 }
 
 // STSClient represents the interface that an sts client should implement.
@@ -49,12 +169,20 @@ type STSClient interface {
 	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
 }
 
+// ManagedTagKey is the tag that marks a resource as created by glambda. Only
+// roles carrying it are removed by [Delete], unless deletion is forced, and
+// functions without it aren't updated unless adopted, see [WithAdopt].
+const ManagedTagKey = "glambda:managed"
+
 // CreateRoleCommand is a paperwork reducer that translates parameters into
 // the smithy autogenerated AWS IAM SDKv2 format of [iam.CreateRoleInput]
 func CreateRoleCommand(roleName string, assumePolicyDocument string) *iam.CreateRoleInput {
 	return &iam.CreateRoleInput{
 		RoleName:                 aws.String(roleName),
 		AssumeRolePolicyDocument: aws.String(assumePolicyDocument),
+		Tags: []iTypes.Tag{
+			{Key: aws.String(ManagedTagKey), Value: aws.String("true")},
+		},
 	}
 }
 
@@ -86,10 +214,14 @@ func (l Lambda) CreateLambdaResourcePolicy() *lambda.AddPermissionInput {
 	if l.ResourcePolicy.Principal == "" {
 		return nil
 	}
+	statementID := l.ResourcePolicy.StatementID
+	if statementID == "" {
+		statementID = defaultStatementPrefix + permissionHash(l.ResourcePolicy)
+	}
 	return &lambda.AddPermissionInput{
 		Action:         aws.String("lambda:InvokeFunction"),
 		FunctionName:   aws.String(l.Name),
-		StatementId:    aws.String("glambda_invoke_permission_" + UUID()),
+		StatementId:    aws.String(statementID),
 		Principal:      aws.String(l.ResourcePolicy.Principal),
 		SourceAccount:  l.ResourcePolicy.SourceAccountCondition,
 		SourceArn:      l.ResourcePolicy.SourceArnCondition,
@@ -104,15 +236,51 @@ func (l Lambda) CreateLambdaResourcePolicy() *lambda.AddPermissionInput {
 // Lambda Execution Role
 func PutRolePolicyCommand(role ExecutionRole) []iam.PutRolePolicyInput {
 	var inputs []iam.PutRolePolicyInput
-	if role.InLinePolicy == "" {
-		return inputs
+	if role.InLinePolicy != "" {
+		policyName := role.InlinePolicyName
+		switch {
+		case policyName != "":
+		case role.Function != "":
+			// The name [NamingConvention.PolicyName] gives, so that each
+			// deploy replaces the policy rather than adding another.
+			policyName = "glambda_inline_policy_" + nameHash(role.Function)
+		default:
+			policyName = "glambda_inline_policy_" + UUID()
+		}
+		inputs = append(inputs, iam.PutRolePolicyInput{
+			PolicyName:     aws.String(policyName),
+			PolicyDocument: aws.String(role.InLinePolicy),
+			RoleName:       aws.String(role.RoleName),
+		})
+	}
+	if role.EventSourcePolicy != "" {
+		inputs = append(inputs, iam.PutRolePolicyInput{
+			PolicyName:     aws.String(EventSourcePolicyName),
+			PolicyDocument: aws.String(role.EventSourcePolicy),
+			RoleName:       aws.String(role.RoleName),
+		})
 	}
-	cmd := iam.PutRolePolicyInput{
-		PolicyName:     aws.String("glambda_inline_policy_" + UUID()),
-		PolicyDocument: aws.String(role.InLinePolicy),
-		RoleName:       aws.String(role.RoleName),
+	if role.DestinationPolicy != "" {
+		inputs = append(inputs, iam.PutRolePolicyInput{
+			PolicyName:     aws.String(DestinationPolicyName),
+			PolicyDocument: aws.String(role.DestinationPolicy),
+			RoleName:       aws.String(role.RoleName),
+		})
+	}
+	if role.KMSPolicy != "" {
+		inputs = append(inputs, iam.PutRolePolicyInput{
+			PolicyName:     aws.String(KMSPolicyName),
+			PolicyDocument: aws.String(role.KMSPolicy),
+			RoleName:       aws.String(role.RoleName),
+		})
+	}
+	if role.KMSGrantsPolicy != "" {
+		inputs = append(inputs, iam.PutRolePolicyInput{
+			PolicyName:     aws.String(KMSGrantsPolicyName),
+			PolicyDocument: aws.String(role.KMSGrantsPolicy),
+			RoleName:       aws.String(role.RoleName),
+		})
 	}
-	inputs = append(inputs, cmd)
 	return inputs
 }
 
@@ -133,14 +301,27 @@ func GenerateUUID() string {
 
 // GetAWSAccountID calls the AWS STS API to get the user credentials that the user
 // is using to make the API call. This response contains the AWS Account ID of the IAM Principal
-func GetAWSAccountID(client STSClient) (string, error) {
-	resp, err := client.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+func GetAWSAccountID(ctx context.Context, client STSClient) (string, error) {
+	resp, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return "", err
+		return "", credentialsError(err, os.Getenv)
 	}
 	return *resp.Account, nil
 }
 
+// GetAccountAlias returns the IAM alias of the AWS account, or an empty string
+// if the account doesn't have one. An account has at most one alias.
+func GetAccountAlias(ctx context.Context, client IAMClient) (string, error) {
+	resp, err := client.ListAccountAliases(ctx, &iam.ListAccountAliasesInput{})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.AccountAliases) == 0 {
+		return "", nil
+	}
+	return resp.AccountAliases[0], nil
+}
+
 var DefaultRetryWaitingPeriod = func() {
 	time.Sleep(3 * time.Second)
 }
@@ -150,10 +331,10 @@ var DefaultRetryWaitingPeriod = func() {
 // of the previous version of the lambda function, which could mask deployment failures.
 // This function waits for the lambda function to become consistent by publishing a new version
 // which seems to wait on the backend until the lambda function is consistent.
-func WaitForConsistency(c LambdaClient, name string) (string, error) {
+func WaitForConsistency(ctx context.Context, c LambdaClient, name string) (string, error) {
 	retryLimit := 10
 	for i := 0; true; i++ {
-		resp, err := c.PublishVersion(context.Background(), &lambda.PublishVersionInput{
+		resp, err := c.PublishVersion(ctx, &lambda.PublishVersionInput{
 			FunctionName: aws.String(name),
 		})
 		if err == nil {
@@ -162,27 +343,40 @@ func WaitForConsistency(c LambdaClient, name string) (string, error) {
 			}
 			return *resp.Version, nil
 		}
-		DefaultRetryWaitingPeriod()
-		if i == retryLimit {
+		if i == retryLimit || ctx.Err() != nil {
 			break
 		}
+		DefaultRetryWaitingPeriod()
 	}
 	return "", fmt.Errorf("waited for lambda become consistent, but didn't after %d retries", retryLimit)
 }
 
-func lambdaExists(c LambdaClient, name string) (bool, error) {
+// WaitForUpdate blocks until the last update to the lambda function has
+// finished. Lambda rejects configuration changes while a previous update is
+// still in progress, so any operation that follows an update should call this.
+func WaitForUpdate(ctx context.Context, c LambdaClient, name string) error {
+	waiter := lambda.NewFunctionUpdatedWaiter(c, func(o *lambda.FunctionUpdatedWaiterOptions) {
+		o.MinDelay = time.Second
+	})
+	return waiter.Wait(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(name),
+	}, 5*time.Minute)
+}
+
+// deployedFunction fetches a function, returning nil if it doesn't exist.
+func deployedFunction(ctx context.Context, c LambdaClient, name string) (*lambda.GetFunctionOutput, error) {
 	input := &lambda.GetFunctionInput{
 		FunctionName: aws.String(name),
 	}
-	_, err := c.GetFunction(context.Background(), input)
+	fn, err := c.GetFunction(ctx, input)
 	if err != nil {
 		var resourceNotFound *types.ResourceNotFoundException
 		if errors.As(err, &resourceNotFound) {
-			return false, nil
+			return nil, nil
 		}
-		return false, err
+		return nil, err
 	}
-	return true, nil
+	return fn, nil
 }
 
 func customRetryer() aws.Retryer {
@@ -0,0 +1,144 @@
+package glambda
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Conftest is the conftest executable used by [CheckPolicies] to evaluate
+// Rego policies. See https://www.conftest.dev for installation instructions.
+var Conftest = "conftest"
+
+// PolicyViolation is a deny rule in a policy bundle that a [Plan] broke.
+type PolicyViolation struct {
+	Function  string
+	Namespace string
+	Message   string
+}
+
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Function, v.Message, v.Namespace)
+}
+
+// PolicyViolationError is returned when a deploy's plan breaks the rules of
+// its policy bundle, see [WithPolicyBundle].
+type PolicyViolationError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PolicyViolationError) Error() string {
+	lines := []string{fmt.Sprintf("deploy plan breaks %d policy rules:", len(e.Violations))}
+	for _, v := range e.Violations {
+		lines = append(lines, "  "+v.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckPolicies evaluates each plan against the Rego policies in the bundle
+// directory, using conftest, and returns a [PolicyViolationError] listing any
+// deny rules that matched. Every namespace in the bundle is evaluated, and
+// each plan is the input document, in the JSON format of [Plan]. For example,
+// to refuse wildcard actions in inline policies:
+//
+//	package main
+//
+//	deny contains msg if {
+//		some change in input.changes
+//		change.operation == "PutRolePolicy"
+//		some statement in change.after.policy_document.Statement
+//		statement.Action == "*"
+//		msg := sprintf("%s has an inline policy allowing every action", [change.resource])
+//	}
+func CheckPolicies(bundle string, plans ...Plan) error {
+	bin, err := exec.LookPath(Conftest)
+	if err != nil {
+		return fmt.Errorf("conftest is required to check policies, see https://www.conftest.dev/install: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "glambda-plan")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	args := []string{"test", "--policy", bundle, "--all-namespaces", "--no-fail", "--no-color", "--output", "json"}
+	for _, plan := range plans {
+		data, err := json.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, plan.Function+".json")
+		err = os.WriteFile(path, data, 0644)
+		if err != nil {
+			return err
+		}
+		args = append(args, path)
+	}
+	cmd := exec.Command(bin, args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("error running conftest: %w, %s", err, stderr)
+	}
+	violations, err := ParseConftest(bytes.NewReader(out))
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &PolicyViolationError{Violations: violations}
+	}
+	return nil
+}
+
+type conftestResult struct {
+	Filename  string `json:"filename"`
+	Namespace string `json:"namespace"`
+	Failures  []struct {
+		Msg string `json:"msg"`
+	} `json:"failures"`
+}
+
+// ParseConftest reads the output of `conftest test --output json` for plans
+// written by [CheckPolicies], and returns the failed rules. Warnings don't
+// fail a deploy, so they aren't returned.
+func ParseConftest(r io.Reader) ([]PolicyViolation, error) {
+	var results []conftestResult
+	err := json.NewDecoder(r).Decode(&results)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding conftest output: %w", err)
+	}
+	var violations []PolicyViolation
+	for _, result := range results {
+		function := strings.TrimSuffix(filepath.Base(result.Filename), ".json")
+		for _, f := range result.Failures {
+			violations = append(violations, PolicyViolation{
+				Function:  function,
+				Namespace: result.Namespace,
+				Message:   f.Msg,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// WithPolicyBundle is a deploy option that checks the deploy's [Plan] against
+// the Rego policies in the bundle directory before anything is changed. The
+// deploy fails with a [PolicyViolationError] if any deny rule matches.
+func WithPolicyBundle(bundle string) DeployOptions {
+	return func(l *Lambda) error {
+		info, err := os.Stat(bundle)
+		if err != nil {
+			return fmt.Errorf("failure in reading policy bundle: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("policy bundle %s must be a directory of Rego policies", bundle)
+		}
+		l.policyBundle = bundle
+		return nil
+	}
+}
@@ -0,0 +1,51 @@
+package glambda_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestParseConftest_ReportsFailuresOnly(t *testing.T) {
+	t.Parallel()
+	f, err := os.Open("testdata/conftest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := glambda.ParseConftest(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []glambda.PolicyViolation{
+		{
+			Function:  "orders",
+			Namespace: "main",
+			Message:   "glambda_exec_role_orders has an inline policy allowing every action",
+		},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestParseConftest_ErrorsOnMalformedOutput(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.ParseConftest(strings.NewReader("FAIL - orders.json"))
+	if err == nil {
+		t.Error("expected error for non JSON output, got nil")
+	}
+}
+
+func TestWithPolicyBundle_RequiresADirectory(t *testing.T) {
+	t.Parallel()
+	for _, bundle := range []string{"testdata/no_such_policies", "testdata/conftest.json"} {
+		_, err := glambda.NewLambda("fn", "", glambda.WithPolicyBundle(bundle))
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", bundle)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// HTTPAPITimeout is how long an API Gateway HTTP API waits for the
+	// function to respond before giving the client a 503.
+	HTTPAPITimeout = 30 * time.Second
+	// SyncPayloadLimitKB is the largest request a function invoked
+	// synchronously, as it is through a function URL or an HTTP API, can
+	// be sent.
+	SyncPayloadLimitKB = 6 * 1024
+	// SQSMessageLimitKB is the largest message an SQS queue accepts.
+	SQSMessageLimitKB = 1024
+	// defaultTimeout is the timeout Lambda gives a new function when none
+	// is set.
+	defaultTimeout = 3 * time.Second
+)
+
+// WithMaxPayload is a deploy option that sets the size, in KB, of the largest
+// request or message the function expects to be sent. Plans warn when it is
+// larger than one of the function's triggers can deliver.
+func WithMaxPayload(kb int) DeployOptions {
+	return func(l *Lambda) error {
+		if kb < 1 {
+			return fmt.Errorf("max payload must be at least 1KB, got %d", kb)
+		}
+		l.MaxPayloadKB = kb
+		return nil
+	}
+}
+
+// checkTriggers checks the timeout and expected payload the function will
+// have after the deploy against the limits of its triggers, so that a plan
+// warns about invocations that would time out or never arrive before they
+// happen in production. With [WithFixQueue], the changes to the queues of
+// SQS triggers are returned instead of warnings about their settings.
+func (l Lambda) checkTriggers(ctx context.Context, action LambdaAction) ([]string, []Change) {
+	timeout := l.effectiveTimeout(action)
+	var warnings []string
+	var changes []Change
+	if l.HTTPAPI != nil && timeout > HTTPAPITimeout {
+		warnings = append(warnings, fmt.Sprintf("timeout of %s is longer than the %s an HTTP API waits for a response, clients get a 503 from slower invocations", timeout, HTTPAPITimeout))
+	}
+	if (l.HTTPAPI != nil || l.FunctionURL != nil) && l.MaxPayloadKB > SyncPayloadLimitKB {
+		warnings = append(warnings, fmt.Sprintf("payloads of %dKB are larger than the %dKB a function URL or HTTP API can send to a function", l.MaxPayloadKB, SyncPayloadLimitKB))
+	}
+	for _, source := range l.EventSources {
+		if !strings.Contains(source.ARN, ":sqs:") {
+			continue
+		}
+		name := source.ARN[strings.LastIndex(source.ARN, ":")+1:]
+		if l.MaxPayloadKB > SQSMessageLimitKB {
+			warnings = append(warnings, fmt.Sprintf("payloads of %dKB are larger than the %dKB messages queue %s accepts", l.MaxPayloadKB, SQSMessageLimitKB, name))
+		}
+		q, err := l.readQueue(ctx, source.ARN)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("can't check the settings of queue %s: %v", name, err))
+			continue
+		}
+		want := visibilityTimeoutFor(timeout)
+		switch {
+		case q.visibility >= want:
+		case l.fixQueue:
+			changes = append(changes, describeQueueFix(q, want))
+		case q.visibility < timeout:
+			warnings = append(warnings, fmt.Sprintf("queue %s has a visibility timeout of %s, shorter than the function's timeout of %s, so messages are delivered again while they are still being processed and Lambda rejects the trigger, raise it to %s or pass --fix-queue", name, q.visibility, timeout, want))
+		default:
+			warnings = append(warnings, fmt.Sprintf("queue %s has a visibility timeout of %s, AWS recommends at least %d times the function's timeout, %s, so that retried batches aren't delivered again while they are being processed, raise it or pass --fix-queue", name, q.visibility, VisibilityTimeoutFactor, want))
+		}
+		if !q.redrive {
+			warnings = append(warnings, fmt.Sprintf("queue %s has no redrive policy, so messages the function keeps failing on are retried until they expire, give it a dead letter queue", name))
+		}
+	}
+	return warnings, changes
+}
+
+// effectiveTimeout is the timeout the function will have after the deploy.
+func (l Lambda) effectiveTimeout(action LambdaAction) time.Duration {
+	if l.Timeout != 0 {
+		return l.Timeout
+	}
+	if update, ok := action.(LambdaUpdateAction); ok && update.Timeout != 0 {
+		return update.Timeout
+	}
+	return defaultTimeout
+}
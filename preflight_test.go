@@ -0,0 +1,81 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestPlan_WarnsWhenTimeoutOrPayloadExceedTriggerLimits(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithHTTPAPI(),
+		glambda.WithTimeout(time.Minute),
+		glambda.WithMaxPayload(8*1024),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"timeout of 1m0s is longer than the 30s an HTTP API waits",
+		"payloads of 8192KB are larger than the 6144KB",
+	} {
+		if !strings.Contains(plan.String(), want) {
+			t.Errorf("expected plan to warn %q, got:\n%s", want, plan.String())
+		}
+	}
+}
+
+func TestPlan_WarnsWhenQueueVisibilityTimeoutIsShorterThanTimeout(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetQueueAttributes", &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]string{"VisibilityTimeout": "30"},
+	})
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithSQSTrigger("arn:aws:sqs:us-east-1:123456789012:orders", 10),
+		glambda.WithTimeout(2*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "queue orders has a visibility timeout of 30s, shorter than the function's timeout of 2m0s"
+	if !strings.Contains(plan.String(), want) {
+		t.Errorf("expected plan to warn %q, got:\n%s", want, plan.String())
+	}
+}
+
+func TestPlan_NoTriggerWarningsWithinLimits(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithHTTPAPI(),
+		glambda.WithTimeout(10*time.Second),
+		glambda.WithMaxPayload(512),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %q", plan.Warnings)
+	}
+}
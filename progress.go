@@ -0,0 +1,86 @@
+package glambda
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a step of a deploy, reported as it happens so that long deploys
+// aren't silent until they fail.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Function string    `json:"function"`
+	// Step is a stable name for the kind of step, such as "build" or
+	// "upload", for tools that follow a deploy.
+	Step    string `json:"step"`
+	Message string `json:"message"`
+	// Verbose marks detail that is only interesting when debugging.
+	Verbose bool `json:"verbose,omitempty"`
+}
+
+// Reporter receives deploy [Event]s. It is called from the deploying
+// goroutine, so it shouldn't block for long, and from several goroutines at
+// once when functions are deployed concurrently, see [DeployAllAndPublish].
+type Reporter func(Event)
+
+// WithProgress is a deploy option that reports each step of the deploy to r.
+// See [TextReporter] and [JSONReporter] for reporters that write to a stream.
+func WithProgress(r Reporter) DeployOptions {
+	return func(l *Lambda) error {
+		l.progress = r
+		return nil
+	}
+}
+
+// TextReporter writes events as lines of text for people to read, leaving
+// out verbose events unless verbose is set.
+func TextReporter(w io.Writer, verbose bool) Reporter {
+	var mu sync.Mutex
+	return func(e Event) {
+		if e.Verbose && !verbose {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "%s: %s\n", e.Function, e.Message)
+	}
+}
+
+// JSONReporter writes every event as a line of JSON, for CI systems and
+// other tools to follow.
+func JSONReporter(w io.Writer) Reporter {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(e)
+	}
+}
+
+// report sends an event to the lambda's reporter, if it has one.
+func (l Lambda) report(step, format string, args ...any) {
+	l.emit(Event{Step: step, Message: fmt.Sprintf(format, args...)})
+}
+
+// detail is [Lambda.report] for verbose events.
+func (l Lambda) detail(step, format string, args ...any) {
+	l.emit(Event{Step: step, Message: fmt.Sprintf(format, args...), Verbose: true})
+}
+
+func (l Lambda) emit(e Event) {
+	if l.progress == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Function = l.Name
+	l.progress(e)
+}
+
+// megabytes formats a size for progress messages, e.g. "14.2MB".
+func megabytes(n int) string {
+	return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+}
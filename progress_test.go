@@ -0,0 +1,105 @@
+package glambda_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestWithProgress_ReportsEachStepOfADeploy(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var steps []string
+	_, err := glambda.DeployAndPublish(context.Background(), "progress", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithProgress(func(e glambda.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			if e.Function != "progress" {
+				t.Errorf("want events for function progress, got %q", e.Function)
+			}
+			if !slices.Contains(steps, e.Step) {
+				steps = append(steps, e.Step)
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"build", "role", "upload", "publish"}
+	for _, step := range want {
+		if !slices.Contains(steps, step) {
+			t.Errorf("missing %s step, got %v", step, steps)
+		}
+	}
+}
+
+func TestTextReporter_LeavesOutVerboseEventsUnlessAsked(t *testing.T) {
+	t.Parallel()
+	events := []glambda.Event{
+		{Function: "fn", Step: "build", Message: "building binary"},
+		{Function: "fn", Step: "build", Message: "built with go1.22", Verbose: true},
+	}
+	quiet, verbose := new(bytes.Buffer), new(bytes.Buffer)
+	for _, e := range events {
+		glambda.TextReporter(quiet, false)(e)
+		glambda.TextReporter(verbose, true)(e)
+	}
+	if got, want := quiet.String(), "fn: building binary\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := verbose.String(); !strings.Contains(got, "fn: built with go1.22") {
+		t.Errorf("want verbose event, got %q", got)
+	}
+}
+
+func TestJSONReporter_WritesOneEventPerLine(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	r := glambda.JSONReporter(buf)
+	r(glambda.Event{Function: "fn", Step: "build", Message: "building binary"})
+	r(glambda.Event{Function: "fn", Step: "upload", Message: "uploading 1.0MB"})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines, got %q", buf.String())
+	}
+	var e glambda.Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Step != "upload" || e.Message != "uploading 1.0MB" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestDeployWithContext_StopsAtTheNextCallOnceCancelled(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := glambda.DeployWithContext(ctx, "cancelled", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithProgress(func(e glambda.Event) {
+			if e.Step == "upload" {
+				cancel()
+			}
+		}),
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if calls := recorder.Calls("CreateRole"); len(calls) != 1 {
+		t.Errorf("expected the role to be created before cancelling, got %d CreateRole calls", len(calls))
+	}
+	if calls := recorder.Calls("CreateFunction", "PublishVersion"); len(calls) != 0 {
+		t.Errorf("expected no calls after cancelling, got %v", calls)
+	}
+}
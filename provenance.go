@@ -0,0 +1,88 @@
+package glambda
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FunctionTagKey is the tag that names the function an execution role was
+// created for, so that a role found in IAM can be traced back to it.
+const FunctionTagKey = "glambda:function"
+
+const maxRoleDescriptionLength = 1000
+
+// WithDescription is a deploy option that sets the description of the
+// execution role, when glambda creates it. Without it, the description names
+// the function and the date the role was created.
+func WithDescription(description string) DeployOptions {
+	return func(l *Lambda) error {
+		if len(description) > maxRoleDescriptionLength {
+			return fmt.Errorf("role description must be at most %d characters, got %d", maxRoleDescriptionLength, len(description))
+		}
+		l.ExecutionRole.Description = description
+		return nil
+	}
+}
+
+// description is what the role is described as in IAM when it is created.
+func (r ExecutionRole) description(now time.Time) string {
+	if r.Description != "" || r.Function == "" {
+		return r.Description
+	}
+	return fmt.Sprintf("Execution role for lambda function %s, created by glambda deploy on %s", r.Function, now.UTC().Format(time.DateOnly))
+}
+
+// withStatementIDs gives each statement of a policy that glambda generated
+// for function a Sid naming the function and the policy's purpose, so that
+// anyone reading the role in IAM can trace every statement back to them. IAM
+// only allows letters and digits in a Sid, so glambda:<function>:<purpose>
+// is written as, for example, GlambdaOrdersApiEventSources1.
+func withStatementIDs(doc, function, policyName string) (string, error) {
+	if doc == "" {
+		return "", nil
+	}
+	var policy struct {
+		Version   string
+		Statement []struct {
+			Sid      string `json:",omitempty"`
+			Effect   string
+			Action   json.RawMessage
+			Resource json.RawMessage
+		}
+	}
+	err := json.Unmarshal([]byte(doc), &policy)
+	if err != nil {
+		return "", err
+	}
+	prefix := "Glambda" + sidWords(function) + sidWords(strings.TrimPrefix(policyName, "glambda_"))
+	for i := range policy.Statement {
+		policy.Statement[i].Sid = fmt.Sprintf("%s%d", prefix, i+1)
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sidWords capitalises each run of letters and digits in s and drops
+// everything else, so "orders-api" becomes "OrdersApi".
+func sidWords(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+		}
+		b.WriteRune(r)
+		upper = false
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func createRoleInput(t *testing.T, opts ...glambda.DeployOptions) *iam.CreateRoleInput {
+	t.Helper()
+	recorder := glambdatest.NewRecorder()
+	opts = append([]glambda.DeployOptions{glambdatest.SandboxWithRecorder(recorder)}, opts...)
+	err := glambda.Deploy("orders-api", "testdata/correct_test_handler/main.go", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("CreateRole")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 CreateRole call, got %v", recorder.Operations())
+	}
+	return calls[0].Input.(*iam.CreateRoleInput)
+}
+
+func TestDeploy_CreatesRoleTaggedAndDescribedWithItsFunction(t *testing.T) {
+	t.Parallel()
+	input := createRoleInput(t)
+	description := aws.ToString(input.Description)
+	if !strings.HasPrefix(description, "Execution role for lambda function orders-api, created by glambda deploy on ") {
+		t.Errorf("unexpected role description %q", description)
+	}
+	var function string
+	for _, tag := range input.Tags {
+		if aws.ToString(tag.Key) == glambda.FunctionTagKey {
+			function = aws.ToString(tag.Value)
+		}
+	}
+	if function != "orders-api" {
+		t.Errorf("expected %s tag orders-api, got %q", glambda.FunctionTagKey, function)
+	}
+}
+
+func TestWithDescription_DescribesCreatedRole(t *testing.T) {
+	t.Parallel()
+	input := createRoleInput(t, glambda.WithDescription("Reads the orders queue"))
+	if got := aws.ToString(input.Description); got != "Reads the orders queue" {
+		t.Errorf("want description %q, got %q", "Reads the orders queue", got)
+	}
+}
+
+func TestWithDescription_RejectsOverlongDescription(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithDescription(strings.Repeat("x", 1001)))
+	if err == nil {
+		t.Error("expected error for description over 1000 characters, got nil")
+	}
+}
+
+func TestGeneratedPolicies_NameFunctionAndPurposeInStatementIDs(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("orders-api", "",
+		glambdatest.Sandbox(),
+		glambda.WithSQSTrigger(queueARN, 0),
+		glambda.WithKMSKey(keyARN),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for policy, sid := range map[string]string{
+		l.ExecutionRole.EventSourcePolicy: `"Sid":"GlambdaOrdersApiEventSources1"`,
+		l.ExecutionRole.KMSPolicy:         `"Sid":"GlambdaOrdersApiKmsDecrypt1"`,
+	} {
+		if !strings.Contains(policy, sid) {
+			t.Errorf("expected %s in %s", sid, policy)
+		}
+	}
+}
+
+func TestDeploy_LeavesUserInlinePolicyStatementsAlone(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	policy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`
+	l, err := glambda.NewLambda("orders-api", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithInlinePolicy(policy),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range recorder.Calls("PutRolePolicy") {
+		if doc := aws.ToString(call.Input.(*iam.PutRolePolicyInput).PolicyDocument); strings.Contains(doc, "Sid") {
+			t.Errorf("expected user policy without Sids, got %s", doc)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// InsightsQueries are curated CloudWatch Logs Insights queries that answer the
+// questions most commonly asked of a lambda function's logs, so users don't
+// need to learn the Insights query syntax.
+var InsightsQueries = map[string]string{
+	"errors": `fields @message
+| filter @message like /(?i)(error|exception|panic|task timed out)/
+| stats count(*) as occurrences by @message
+| sort occurrences desc
+| limit 20`,
+	"slowest": `filter @type = "REPORT"
+| fields @timestamp, @requestId, @duration, @billedDuration, @maxMemoryUsed / 1000 / 1000 as maxMemoryUsedMB
+| sort @duration desc
+| limit 20`,
+	"memory": `filter @type = "REPORT"
+| stats max(@memorySize / 1000 / 1000) as provisionedMB,
+        max(@maxMemoryUsed / 1000 / 1000) as maxUsedMB,
+        avg(@maxMemoryUsed / 1000 / 1000) as avgUsedMB,
+        count(*) as invocations`,
+}
+
+// LogGroupName returns the name of the log group that Lambda writes the given
+// function's logs to.
+func LogGroupName(functionName string) string {
+	return "/aws/lambda/" + functionName
+}
+
+// QueryResult is a tabular representation of a Logs Insights query result. The
+// Fields are in the order they were returned by the query, and each row holds
+// one value per field.
+type QueryResult struct {
+	Fields []string
+	Rows   [][]string
+}
+
+// InsightsQueryPollInterval is how often a Logs Insights query is checked
+// while waiting for it to finish.
+var InsightsQueryPollInterval = time.Second
+
+// InsightsQueryTimeout is how long to wait for a Logs Insights query to
+// finish.
+var InsightsQueryTimeout = 5 * time.Minute
+
+// RunInsightsQuery starts a Logs Insights query against a log group, covering
+// the period from since ago until now, and polls until it has finished, ctx
+// is done or [InsightsQueryTimeout] has passed.
+func RunInsightsQuery(ctx context.Context, c CloudWatchLogsClient, logGroup, query string, since time.Duration) (QueryResult, error) {
+	now := time.Now()
+	resp, err := c.StartQuery(ctx, &cloudwatchlogs.StartQueryInput{
+		LogGroupName: aws.String(logGroup),
+		QueryString:  aws.String(query),
+		StartTime:    aws.Int64(now.Add(-since).Unix()),
+		EndTime:      aws.Int64(now.Unix()),
+	})
+	if err != nil {
+		return QueryResult{}, err
+	}
+	deadline := time.Now().Add(InsightsQueryTimeout)
+	for {
+		results, err := c.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{
+			QueryId: resp.QueryId,
+		})
+		if err != nil {
+			return QueryResult{}, err
+		}
+		switch results.Status {
+		case lTypes.QueryStatusComplete:
+			return toQueryResult(results.Results), nil
+		case lTypes.QueryStatusScheduled, lTypes.QueryStatusRunning:
+		default:
+			return QueryResult{}, fmt.Errorf("logs insights query did not complete, status %s", results.Status)
+		}
+		if time.Now().After(deadline) {
+			return QueryResult{}, fmt.Errorf("logs insights query didn't finish within %s, status %s", InsightsQueryTimeout, results.Status)
+		}
+		timer := time.NewTimer(InsightsQueryPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return QueryResult{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func toQueryResult(rows [][]lTypes.ResultField) QueryResult {
+	var result QueryResult
+	index := map[string]int{}
+	for _, row := range rows {
+		for _, f := range row {
+			field := aws.ToString(f.Field)
+			// @ptr is an opaque pointer to the underlying log event, not useful to display
+			if _, seen := index[field]; seen || field == "@ptr" {
+				continue
+			}
+			index[field] = len(result.Fields)
+			result.Fields = append(result.Fields, field)
+		}
+	}
+	for _, row := range rows {
+		values := make([]string, len(result.Fields))
+		for _, f := range row {
+			if i, ok := index[aws.ToString(f.Field)]; ok {
+				values[i] = aws.ToString(f.Value)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result
+}
+
+// Query is a method on the [Lambda] struct that runs one of the curated
+// [InsightsQueries] by name against the function's log group.
+func (l Lambda) Query(ctx context.Context, name string, since time.Duration) (QueryResult, error) {
+	query, ok := InsightsQueries[name]
+	if !ok {
+		return QueryResult{}, fmt.Errorf("unknown query %q", name)
+	}
+	return RunInsightsQuery(ctx, l.cloudWatchLogsAPI(), LogGroupName(l.Name), query, since)
+}
@@ -0,0 +1,97 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	lTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func resultRow(fields ...string) []lTypes.ResultField {
+	var row []lTypes.ResultField
+	for i := 0; i < len(fields); i += 2 {
+		row = append(row, lTypes.ResultField{Field: aws.String(fields[i]), Value: aws.String(fields[i+1])})
+	}
+	return row
+}
+
+func TestRunInsightsQuery_PollsUntilCompleteAndTabulatesResults(t *testing.T) {
+	t.Parallel()
+	var query string
+	client := glambdatest.DummyCloudWatchLogsClient{
+		RunningPolls: aws.Int(2),
+		QueryString:  &query,
+		Results: [][]lTypes.ResultField{
+			resultRow("@message", "panic: boom", "occurrences", "7", "@ptr", "abc"),
+			resultRow("@message", "task timed out", "occurrences", "2", "@ptr", "def"),
+		},
+	}
+	got, err := glambda.RunInsightsQuery(context.Background(), client, glambda.LogGroupName("testLambda"), glambda.InsightsQueries["errors"], 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := glambda.QueryResult{
+		Fields: []string{"@message", "occurrences"},
+		Rows: [][]string{
+			{"panic: boom", "7"},
+			{"task timed out", "2"},
+		},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+	if query != glambda.InsightsQueries["errors"] {
+		t.Errorf("expected curated errors query to be run, got %q", query)
+	}
+}
+
+func TestRunInsightsQuery_ErrorsOnFailedQuery(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyCloudWatchLogsClient{
+		Status: lTypes.QueryStatusFailed,
+	}
+	_, err := glambda.RunInsightsQuery(context.Background(), client, "/aws/lambda/testLambda", "fields @message", time.Hour)
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestRunInsightsQuery_ErrorsOnUnexpectedStatus(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyCloudWatchLogsClient{
+		Status: lTypes.QueryStatusUnknown,
+	}
+	_, err := glambda.RunInsightsQuery(context.Background(), client, "/aws/lambda/testLambda", "fields @message", time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "Unknown") {
+		t.Errorf("want an error naming the status, got %v", err)
+	}
+}
+
+func TestRunInsightsQuery_StopsWaitingWhenTheContextIsDone(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyCloudWatchLogsClient{
+		RunningPolls: aws.Int(math.MaxInt),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := glambda.RunInsightsQuery(ctx, client, "/aws/lambda/testLambda", "fields @message", time.Hour)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestLogGroupName(t *testing.T) {
+	t.Parallel()
+	got := glambda.LogGroupName("testLambda")
+	if got != "/aws/lambda/testLambda" {
+		t.Errorf("expected /aws/lambda/testLambda, got %s", got)
+	}
+}
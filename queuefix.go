@@ -0,0 +1,122 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	qTypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// VisibilityTimeoutFactor is how many times the function's timeout AWS
+// recommends the visibility timeout of an SQS trigger's queue to be, so that
+// a batch retried after the function was throttled isn't delivered again
+// while it is still being processed.
+const VisibilityTimeoutFactor = 6
+
+// WithFixQueue is a deploy option that raises the visibility timeout of each
+// SQS trigger's queue to [VisibilityTimeoutFactor] times the function's
+// timeout, if it is any shorter, before the trigger is wired up. Without it,
+// plans only warn about short visibility timeouts. Queues without a redrive
+// policy are warned about either way, as glambda doesn't create dead letter
+// queues.
+func WithFixQueue() DeployOptions {
+	return func(l *Lambda) error {
+		l.fixQueue = true
+		return nil
+	}
+}
+
+// sqsQueue is the state of an SQS trigger's queue.
+type sqsQueue struct {
+	name       string
+	url        string
+	visibility time.Duration
+	redrive    bool
+}
+
+// visibilityTimeoutFor is the visibility timeout AWS recommends for the queue
+// of a function with the given timeout.
+func visibilityTimeoutFor(timeout time.Duration) time.Duration {
+	return VisibilityTimeoutFactor * timeout
+}
+
+// readQueue reads the visibility timeout and redrive policy of the queue with
+// the given ARN.
+func (l Lambda) readQueue(ctx context.Context, queueARN string) (sqsQueue, error) {
+	parts := strings.SplitN(queueARN, ":", 6)
+	if len(parts) != 6 {
+		return sqsQueue{}, fmt.Errorf("invalid SQS queue ARN %q", queueARN)
+	}
+	q := sqsQueue{name: parts[5]}
+	url, err := l.sqsAPI().GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+		QueueName:              aws.String(q.name),
+		QueueOwnerAWSAccountId: aws.String(parts[4]),
+	})
+	if err != nil {
+		return sqsQueue{}, err
+	}
+	q.url = aws.ToString(url.QueueUrl)
+	out, err := l.sqsAPI().GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl: url.QueueUrl,
+		AttributeNames: []qTypes.QueueAttributeName{
+			qTypes.QueueAttributeNameVisibilityTimeout,
+			qTypes.QueueAttributeNameRedrivePolicy,
+		},
+	})
+	if err != nil {
+		return sqsQueue{}, err
+	}
+	seconds, err := strconv.Atoi(out.Attributes[string(qTypes.QueueAttributeNameVisibilityTimeout)])
+	if err != nil {
+		return sqsQueue{}, fmt.Errorf("queue %s didn't report its visibility timeout", q.name)
+	}
+	q.visibility = time.Duration(seconds) * time.Second
+	q.redrive = out.Attributes[string(qTypes.QueueAttributeNameRedrivePolicy)] != ""
+	return q, nil
+}
+
+// fixQueues raises the visibility timeout of each SQS trigger's queue that is
+// shorter than recommended, see [WithFixQueue].
+func (l Lambda) fixQueues(ctx context.Context, action LambdaAction) error {
+	want := visibilityTimeoutFor(l.effectiveTimeout(action))
+	for _, source := range l.EventSources {
+		if !strings.Contains(source.ARN, ":sqs:") {
+			continue
+		}
+		q, err := l.readQueue(ctx, source.ARN)
+		if err != nil {
+			return err
+		}
+		if q.visibility >= want {
+			continue
+		}
+		l.report("fix-queue", "raising the visibility timeout of queue %s from %s to %s", q.name, q.visibility, want)
+		_, err = l.sqsAPI().SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+			QueueUrl: aws.String(q.url),
+			Attributes: map[string]string{
+				string(qTypes.QueueAttributeNameVisibilityTimeout): strconv.Itoa(int(want / time.Second)),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("unable to raise the visibility timeout of queue %s: %w", q.name, err)
+		}
+	}
+	return nil
+}
+
+func describeQueueFix(q sqsQueue, want time.Duration) Change {
+	return Change{
+		Operation:    "SetQueueAttributes",
+		ResourceType: "sqs_queue",
+		Resource:     q.name,
+		Action:       "update",
+		Before:       map[string]any{"visibility_timeout": int(q.visibility / time.Second)},
+		After:        map[string]any{"visibility_timeout": int(want / time.Second)},
+		Details:      []string{fmt.Sprintf("visibility timeout: %s -> %s", q.visibility, want)},
+	}
+}
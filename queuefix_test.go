@@ -0,0 +1,98 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+const ordersQueue = "arn:aws:sqs:us-east-1:123456789012:orders"
+
+func TestPlan_RecommendsVisibilityTimeoutAndRedrivePolicyForQueueTriggers(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithSQSTrigger(ordersQueue, 10),
+		glambda.WithTimeout(10*time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"queue orders has a visibility timeout of 30s, AWS recommends at least 6 times the function's timeout, 1m0s",
+		"queue orders has no redrive policy",
+	} {
+		if !strings.Contains(plan.String(), want) {
+			t.Errorf("expected plan to warn %q, got:\n%s", want, plan.String())
+		}
+	}
+}
+
+func TestPlan_NoQueueWarningsForWellConfiguredQueue(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetQueueAttributes", &sqs.GetQueueAttributesOutput{
+		Attributes: map[string]string{
+			"VisibilityTimeout": "60",
+			"RedrivePolicy":     `{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123456789012:orders-dlq","maxReceiveCount":5}`,
+		},
+	})
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithSQSTrigger(ordersQueue, 10),
+		glambda.WithTimeout(10*time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %q", plan.Warnings)
+	}
+}
+
+func TestDeploy_WithFixQueueRaisesVisibilityTimeoutBeforeMappingTheQueue(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithSQSTrigger(ordersQueue, 10),
+		glambda.WithTimeout(10*time.Second),
+		glambda.WithFixQueue(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan.String(), "+ SetQueueAttributes orders") || strings.Contains(plan.String(), "AWS recommends") {
+		t.Errorf("expected the plan to fix the queue instead of warning, got:\n%s", plan.String())
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("SetQueueAttributes", "CreateEventSourceMapping")
+	if len(calls) != 2 || calls[0].Operation != "SetQueueAttributes" {
+		t.Fatalf("expected the queue to be fixed before it is mapped, got %+v", calls)
+	}
+	input := calls[0].Input.(*sqs.SetQueueAttributesInput)
+	if input.Attributes["VisibilityTimeout"] != "60" || !strings.HasSuffix(aws.ToString(input.QueueUrl), "/123456789012/orders") {
+		t.Errorf("expected the visibility timeout of orders to be raised to 60s, got %+v", input)
+	}
+}
@@ -0,0 +1,101 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// FunctionSpec is the desired state of a single lambda function: its handler,
+// configuration, policies and tags. It is the same shape as a function in a
+// glambda.yaml [Config], so a custom resource or any other source of desired
+// state can be translated to it once and reconciled with a [Reconciler].
+type FunctionSpec = FunctionConfig
+
+// Reconciler brings lambda functions in line with a [FunctionSpec]. It is the
+// engine behind glambda deploy, exposed for controllers, such as Kubernetes
+// operators, that reconcile functions in a loop. Reconcile compares the
+// desired state with the live state in AWS and returns the changes as a
+// [Plan], and Apply carries out exactly those changes.
+type Reconciler struct {
+	opts []DeployOptions
+}
+
+// NewReconciler is a constructor function that creates a new [Reconciler].
+// The options are applied to every function it reconciles, ahead of the
+// function's own settings, and are typically [WithAWSConfig], clients, or
+// [WithPolicyBundle].
+func NewReconciler(opts ...DeployOptions) Reconciler {
+	return Reconciler{opts: slices.Clone(opts)}
+}
+
+// preparedDeploy is what [Reconciler.Apply] needs to carry out a [Plan].
+type preparedDeploy struct {
+	lambda Lambda
+	role   RoleAction
+	action LambdaAction
+}
+
+// Reconcile builds the handler of the desired function and reads the live
+// state of its role and function, returning the changes needed to bring them
+// in line with desired. Nothing is changed. The plan can be inspected, checked
+// with [CheckPolicies], and then passed to [Reconciler.Apply].
+func (r Reconciler) Reconcile(ctx context.Context, desired FunctionSpec) (Plan, error) {
+	if desired.Name == "" || desired.Handler == "" {
+		return Plan{}, fmt.Errorf("function spec needs a name and a handler")
+	}
+	opts, err := desired.Options()
+	if err != nil {
+		return Plan{}, fmt.Errorf("%s: %w", desired.Name, err)
+	}
+	l, err := NewLambdaWithContext(ctx, desired.Name, desired.Handler, append(slices.Clone(r.opts), opts...)...)
+	if err != nil {
+		return Plan{}, err
+	}
+	return l.preparePlan(ctx)
+}
+
+// preparePlan prepares a deploy as [Lambda.Deploy] would and describes it,
+// keeping the prepared actions so that the plan can be applied later.
+func (l Lambda) preparePlan(ctx context.Context) (Plan, error) {
+	roleAction, action, err := l.prepare(ctx)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan, err := l.describe(ctx, roleAction, action)
+	if err != nil {
+		return Plan{}, err
+	}
+	warnings, changes := l.checkTriggers(ctx, action)
+	plan.Changes = append(plan.Changes, changes...)
+	plan.Warnings = append(plan.Warnings, warnings...)
+	plan.prepared = &preparedDeploy{lambda: l, role: roleAction, action: action}
+	return plan, nil
+}
+
+// apply carries out the prepared actions and publishes a new version.
+func (p preparedDeploy) apply(ctx context.Context) (DeployResult, error) {
+	err := p.lambda.apply(ctx, p.role, p.action)
+	if err != nil {
+		return DeployResult{}, err
+	}
+	return p.lambda.Publish(ctx)
+}
+
+// Apply carries out a plan returned by [Reconciler.Reconcile] and publishes a
+// new version, as glambda deploy does. A plan decoded from JSON can't be
+// applied, as it no longer holds the built handler. If the live state changed
+// since the plan was made, Reconcile again rather than applying a stale plan.
+func (r Reconciler) Apply(ctx context.Context, plan Plan) (DeployResult, error) {
+	p := plan.prepared
+	if p == nil {
+		return DeployResult{}, fmt.Errorf("plan for %s can't be applied, only plans returned by Reconcile can", plan.Function)
+	}
+	if p.lambda.policyBundle != "" {
+		err := CheckPolicies(p.lambda.policyBundle, plan)
+		if err != nil {
+			return DeployResult{}, err
+		}
+	}
+	return p.apply(ctx)
+}
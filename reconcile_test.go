@@ -0,0 +1,96 @@
+package glambda_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestReconcile_PlansChangesWithoutMakingThem(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	r := glambda.NewReconciler(glambdatest.SandboxWithRecorder(recorder))
+	plan, err := r.Reconcile(context.Background(), glambda.FunctionSpec{
+		Name:        "reconciled",
+		Handler:     "testdata/correct_test_handler/main.go",
+		Environment: map[string]string{"LOG_LEVEL": "debug"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operations []string
+	for _, c := range plan.Changes {
+		operations = append(operations, c.Operation)
+	}
+	if !slices.Contains(operations, "CreateRole") || !slices.Contains(operations, "CreateFunction") {
+		t.Errorf("expected role and function to be created, got %v", operations)
+	}
+	for _, op := range []string{"CreateRole", "CreateFunction", "PublishVersion"} {
+		if len(recorder.Calls(op)) > 0 {
+			t.Errorf("expected Reconcile not to call %s", op)
+		}
+	}
+}
+
+func TestReconcilerApply_CarriesOutThePlanAndPublishes(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	r := glambda.NewReconciler(glambdatest.SandboxWithRecorder(recorder))
+	plan, err := r.Reconcile(context.Background(), glambda.FunctionSpec{
+		Name:    "reconciled",
+		Handler: "testdata/correct_test_handler/main.go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := r.Apply(context.Background(), plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FunctionName != "reconciled" || result.Version == "" {
+		t.Errorf("expected published version of reconciled, got %+v", result)
+	}
+	for _, op := range []string{"CreateRole", "CreateFunction", "PublishVersion"} {
+		if len(recorder.Calls(op)) != 1 {
+			t.Errorf("expected Apply to call %s once, got %v", op, recorder.Operations())
+		}
+	}
+}
+
+func TestReconcilerApply_RejectsPlansNotReturnedByReconcile(t *testing.T) {
+	t.Parallel()
+	r := glambda.NewReconciler(glambdatest.Sandbox())
+	plan, err := r.Reconcile(context.Background(), glambda.FunctionSpec{
+		Name:    "reconciled",
+		Handler: "testdata/correct_test_handler/main.go",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded glambda.Plan
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Apply(context.Background(), decoded)
+	if err == nil {
+		t.Error("expected error applying a decoded plan, got nil")
+	}
+}
+
+func TestReconcile_RejectsIncompleteSpec(t *testing.T) {
+	t.Parallel()
+	r := glambda.NewReconciler(glambdatest.Sandbox())
+	_, err := r.Reconcile(context.Background(), glambda.FunctionSpec{Name: "reconciled"})
+	if err == nil {
+		t.Error("expected error for spec without a handler, got nil")
+	}
+}
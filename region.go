@@ -0,0 +1,86 @@
+package glambda
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/smithy-go"
+)
+
+var (
+	availabilityZoneRegex = regexp.MustCompile(`^([a-z]+(?:-[a-z]+)+-\d+)[a-z]$`)
+	localZoneRegex        = regexp.MustCompile(`^([a-z]+(?:-[a-z]+)+-\d+)-[a-z0-9-]+$`)
+	outpostRegex          = regexp.MustCompile(`^op-[0-9a-f]{17}$|^arn:aws[a-z-]*:outposts:`)
+)
+
+// ValidateRegion checks that region names a region, rather than one of the
+// locations within it. Lambda functions are deployed to a region and run
+// across its Availability Zones, and can't be placed in a particular zone,
+// a Local Zone, a Wavelength Zone or an Outpost.
+func ValidateRegion(region string) error {
+	if m := availabilityZoneRegex.FindStringSubmatch(region); m != nil {
+		return fmt.Errorf("%s is an Availability Zone, but Lambda functions are deployed to a whole region, use %s", region, m[1])
+	}
+	if m := localZoneRegex.FindStringSubmatch(region); m != nil {
+		return fmt.Errorf("%s is a Local Zone or Wavelength Zone, where Lambda can't run functions, deploy to its parent region %s instead", region, m[1])
+	}
+	if outpostRegex.MatchString(region) {
+		return fmt.Errorf("%s is an Outpost, where Lambda can't run functions, deploy to the region it is anchored to instead", region)
+	}
+	return nil
+}
+
+// UnavailableFeatureError is returned when a deploy asks for something that
+// Lambda doesn't offer in the target region, such as arm64 functions or
+// function URLs in some regions. Advice says how to deploy without it.
+type UnavailableFeatureError struct {
+	Feature string
+	Region  string
+	Advice  string
+	Err     error
+}
+
+func (e *UnavailableFeatureError) Error() string {
+	return fmt.Sprintf("%s aren't available in %s, %s: %v", e.Feature, e.Region, e.Advice, e.Err)
+}
+
+func (e *UnavailableFeatureError) Unwrap() error {
+	return e.Err
+}
+
+// explainArchitecture turns Lambda's refusal of an arm64 function into an
+// [UnavailableFeatureError].
+func (l Lambda) explainArchitecture(err error) error {
+	var invalid *types.InvalidParameterValueException
+	if l.architecture() != types.ArchitectureArm64 || !errors.As(err, &invalid) || !strings.Contains(strings.ToLower(invalid.ErrorMessage()), "architecture") {
+		return err
+	}
+	return &UnavailableFeatureError{Feature: "arm64 functions", Region: l.cfg.Region, Advice: "deploy for x86_64 with WithArchitecture or --arch x86_64", Err: err}
+}
+
+// explainFunctionURL turns a region's lack of function URLs into an
+// [UnavailableFeatureError].
+func (l Lambda) explainFunctionURL(err error) error {
+	if !unsupported(err) {
+		return err
+	}
+	return &UnavailableFeatureError{Feature: "function URLs", Region: l.cfg.Region, Advice: "put an HTTP API in front of the function instead, see WithHTTPAPI", Err: err}
+}
+
+// unsupported reports whether err says an operation isn't supported, which
+// is how Lambda answers calls it doesn't offer in a region.
+func unsupported(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "UnknownOperationException", "UnsupportedOperationException":
+		return true
+	}
+	message := strings.ToLower(apiErr.ErrorMessage())
+	return strings.Contains(message, "not supported") || strings.Contains(message, "not available")
+}
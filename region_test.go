@@ -0,0 +1,110 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/smithy-go"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestValidateRegion_AcceptsRegions(t *testing.T) {
+	t.Parallel()
+	for _, region := range []string{"us-east-1", "ap-southeast-2", "us-gov-west-1", "cn-north-1", "eu-central-2"} {
+		err := glambda.ValidateRegion(region)
+		if err != nil {
+			t.Errorf("%s: %v", region, err)
+		}
+	}
+}
+
+func TestValidateRegion_PointsLocationsWithinARegionAtTheRegion(t *testing.T) {
+	t.Parallel()
+	tc := map[string]string{
+		"us-east-1a":              "use us-east-1",
+		"us-west-2-lax-1a":        "parent region us-west-2",
+		"us-east-1-wl1-bos-wlz-1": "parent region us-east-1",
+		"op-0123456789abcdef0":    "region it is anchored to",
+		"arn:aws:outposts:us-west-2:123456789012:outpost/op-0123456789abcdef0": "region it is anchored to",
+	}
+	for region, want := range tc {
+		err := glambda.ValidateRegion(region)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: want an error containing %q, got %v", region, want, err)
+		}
+	}
+}
+
+func TestNewLambda_RejectsALocalZoneAsTheRegion(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("zoned", "", glambda.WithAWSConfig(aws.Config{Region: "us-west-2-lax-1a"}))
+	if err == nil || !strings.Contains(err.Error(), "Local Zone") {
+		t.Errorf("want a Local Zone error, got %v", err)
+	}
+}
+
+func TestDeploy_ExplainsArm64UnavailableInRegion(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", &types.InvalidParameterValueException{
+		Message: aws.String("Architecture arm64 is not supported in this region"),
+	}, 1)
+	l, err := glambda.NewLambda("armless", "testdata/correct_test_handler/main.go", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	var unavailable *glambda.UnavailableFeatureError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("want UnavailableFeatureError, got %v", err)
+	}
+	if unavailable.Feature != "arm64 functions" || unavailable.Region != glambdatest.Region {
+		t.Errorf("unexpected error %+v", unavailable)
+	}
+	if !strings.Contains(err.Error(), "--arch x86_64") {
+		t.Errorf("want advice to deploy for x86_64, got %q", err)
+	}
+}
+
+func TestDeploy_ExplainsFunctionURLsUnavailableInRegion(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunctionUrlConfig", &smithy.GenericAPIError{
+		Code:    "UnknownOperationException",
+		Message: "Unknown operation",
+	}, 1)
+	l, err := glambda.NewLambda("urlless", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithFunctionURL("AWS_IAM"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	var unavailable *glambda.UnavailableFeatureError
+	if !errors.As(err, &unavailable) || unavailable.Feature != "function URLs" {
+		t.Fatalf("want UnavailableFeatureError for function URLs, got %v", err)
+	}
+}
+
+func TestDeploy_LeavesOtherCreateFunctionErrorsAlone(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", &types.InvalidParameterValueException{
+		Message: aws.String("The role defined for the function cannot be assumed by Lambda."),
+	}, 1)
+	l, err := glambda.NewLambda("unassumable", "testdata/correct_test_handler/main.go", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Deploy(context.Background())
+	var unavailable *glambda.UnavailableFeatureError
+	if err == nil || errors.As(err, &unavailable) {
+		t.Errorf("want the original error, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// checkRelease rejects deploys that move the [LiveAlias] of the function
+// themselves, as the function's alias would then be switched before the rest
+// of its release group is published.
+func (l Lambda) checkRelease(group string) error {
+	if l.Canary != nil {
+		return fmt.Errorf("a canary shifts the %s alias itself, so it can't be part of release %s", LiveAlias, group)
+	}
+	if p := l.ProvisionedConcurrency; p != nil && p.Alias == LiveAlias {
+		return fmt.Errorf("provisioned concurrency on the %s alias moves it as soon as the function is published, so it can't be part of release %s", LiveAlias, group)
+	}
+	return nil
+}
+
+func describeRelease(name, group string) Change {
+	return Change{
+		Operation:    "UpdateAlias",
+		ResourceType: "lambda_alias",
+		Resource:     name + ":" + LiveAlias,
+		Action:       "update",
+		After:        map[string]any{"release": group},
+		Details:      []string{"release " + group + ", switched once every function in it is published"},
+	}
+}
+
+// releaseMember is a function of a release group, and the version it was
+// published as.
+type releaseMember struct {
+	lambda  Lambda
+	version string
+}
+
+// switchRelease points the [LiveAlias] of every member of a release group at
+// its new version. If one can't be switched, those already switched are put
+// back, so that the group's functions keep running versions from the same
+// release.
+func switchRelease(ctx context.Context, group string, members []releaseMember) error {
+	previous := make([]string, 0, len(members))
+	for i, m := range members {
+		m.lambda.report("release", "switching %s of release %s to version %s", LiveAlias, group, m.version)
+		before, err := pointAlias(ctx, m.lambda.lambdaAPI(), m.lambda.Name, LiveAlias, m.version)
+		if err == nil {
+			previous = append(previous, before)
+			continue
+		}
+		err = fmt.Errorf("%s: switching release %s: %w", m.lambda.Name, group, err)
+		for j := i - 1; j >= 0; j-- {
+			back := members[j]
+			back.lambda.report("release", "switching %s back, as release %s failed", LiveAlias, group)
+			err = errors.Join(err, restoreAlias(ctx, back.lambda.lambdaAPI(), back.lambda.Name, LiveAlias, previous[j]))
+		}
+		return err
+	}
+	return nil
+}
+
+// pointAlias creates the alias at version, or moves an existing one there,
+// returning the version it pointed at before, or empty if it was created.
+func pointAlias(ctx context.Context, c LambdaClient, name, alias, version string) (string, error) {
+	existing, err := c.GetAlias(ctx, &lambda.GetAliasInput{
+		FunctionName: aws.String(name),
+		Name:         aws.String(alias),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		_, err = c.CreateAlias(ctx, &lambda.CreateAliasInput{
+			FunctionName:    aws.String(name),
+			Name:            aws.String(alias),
+			FunctionVersion: aws.String(version),
+		})
+		return "", err
+	}
+	if err != nil {
+		return "", err
+	}
+	before := aws.ToString(existing.FunctionVersion)
+	if before == version {
+		return before, nil
+	}
+	_, err = c.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(name),
+		Name:            aws.String(alias),
+		FunctionVersion: aws.String(version),
+	})
+	return before, err
+}
+
+// restoreAlias undoes [pointAlias], deleting the alias if it was created.
+func restoreAlias(ctx context.Context, c LambdaClient, name, alias, previous string) error {
+	if previous == "" {
+		_, err := c.DeleteAlias(ctx, &lambda.DeleteAliasInput{
+			FunctionName: aws.String(name),
+			Name:         aws.String(alias),
+		})
+		return err
+	}
+	_, err := c.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(name),
+		Name:            aws.String(alias),
+		FunctionVersion: aws.String(previous),
+	})
+	return err
+}
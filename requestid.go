@@ -0,0 +1,96 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// DeployIDUserAgentKey is the User-Agent key under which every AWS call made
+// by a [Lambda] carries its deploy ID, e.g. "glambda-deploy/1a2b3c4d".
+// CloudTrail records the User-Agent of each call, so searching its userAgent
+// field for the deploy ID finds everything one deploy did.
+//
+// None of the Lambda, IAM, S3 or API Gateway operations glambda calls accept
+// a client token, so the deploy ID stands in for one.
+const DeployIDUserAgentKey = "glambda-deploy"
+
+// WithDeployID is a deploy option that sets the deploy ID sent with every AWS
+// call, instead of the one [NewLambda] generates. A CI system might use its
+// build number, so that CloudTrail events lead back to the build.
+func WithDeployID(id string) DeployOptions {
+	return func(l *Lambda) error {
+		if id == "" || strings.ContainsAny(id, " /()") {
+			return fmt.Errorf("deploy ID %q must be non-empty, without spaces, slashes or parentheses", id)
+		}
+		l.deployID = id
+		return nil
+	}
+}
+
+// DeployID returns the ID sent with every AWS call the lambda makes, see
+// [DeployIDUserAgentKey].
+func (l Lambda) DeployID() string {
+	return l.deployID
+}
+
+// RequestID returns the AWS request ID of the failed call that caused err,
+// or "" if err didn't come from an AWS response. Quote it when asking AWS
+// support about a failure, or find its event in CloudTrail.
+func RequestID(err error) string {
+	var re *awshttp.ResponseError
+	if errors.As(err, &re) {
+		return re.ServiceRequestID()
+	}
+	return ""
+}
+
+// traceRequests tags the lambda's AWS calls with its deploy ID and reports
+// their request IDs: as verbose detail for calls that change something, and
+// always for calls that fail.
+func (l *Lambda) traceRequests() {
+	reporter := *l
+	options := append([]func(*middleware.Stack) error{}, l.cfg.APIOptions...)
+	l.cfg.APIOptions = append(options,
+		awsmiddleware.AddUserAgentKeyValue(DeployIDUserAgentKey, l.deployID),
+		func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(reporter.requestReporter(), middleware.After)
+		},
+	)
+}
+
+func (l Lambda) requestReporter() middleware.InitializeMiddleware {
+	return middleware.InitializeMiddlewareFunc("GlambdaRequestReporter",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			call := awsmiddleware.GetServiceID(ctx) + " " + awsmiddleware.GetOperationName(ctx)
+			if err != nil {
+				if id := RequestID(err); id != "" {
+					l.report("aws-request", "%s failed, request ID %s, deploy ID %s", call, id, l.deployID)
+				}
+				return out, metadata, err
+			}
+			if readOnly(awsmiddleware.GetOperationName(ctx)) {
+				return out, metadata, err
+			}
+			if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+				l.detail("aws-request", "%s, request ID %s", call, id)
+			}
+			return out, metadata, err
+		})
+}
+
+// readOnly reports whether an operation only reads, going by AWS's naming.
+func readOnly(operation string) bool {
+	for _, prefix := range []string{"Get", "List", "Describe", "Head"} {
+		if strings.HasPrefix(operation, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package glambda_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+// fakeLambdaService answers every request with status and body, recording
+// the User-Agent it was sent.
+type fakeLambdaService struct {
+	status int
+	body   string
+
+	mu         sync.Mutex
+	userAgents []string
+}
+
+func (f *fakeLambdaService) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.userAgents = append(f.userAgents, req.Header.Get("User-Agent"))
+	f.mu.Unlock()
+	header := http.Header{}
+	header.Set("X-Amzn-RequestId", "11111111-2222-3333-4444-555555555555")
+	header.Set("X-Amzn-ErrorType", "AccessDeniedException")
+	return &http.Response{
+		StatusCode: f.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		Request:    req,
+	}, nil
+}
+
+func lambdaOverFakeService(t *testing.T, service *fakeLambdaService, events *[]glambda.Event, opts ...glambda.DeployOptions) *glambda.Lambda {
+	t.Helper()
+	opts = append([]glambda.DeployOptions{
+		glambda.WithAWSConfig(aws.Config{
+			Region:      glambdatest.Region,
+			Credentials: aws.AnonymousCredentials{},
+			HTTPClient:  service,
+		}),
+		glambda.WithSTSClient(glambdatest.DummySTSClient{AccountID: glambdatest.AccountID}),
+		glambda.WithProgress(func(e glambda.Event) { *events = append(*events, e) }),
+	}, opts...)
+	l, err := glambda.NewLambda("orders", "testdata/correct_test_handler/main.go", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestNewLambda_SendsDeployIDInUserAgent(t *testing.T) {
+	t.Parallel()
+	service := &fakeLambdaService{status: 200, body: `{}`}
+	var events []glambda.Event
+	l := lambdaOverFakeService(t, service, &events, glambda.WithDeployID("build-1234"))
+	if l.DeployID() != "build-1234" {
+		t.Fatalf("want deploy ID build-1234, got %q", l.DeployID())
+	}
+	err := l.Invoke(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(service.userAgents) != 1 || !strings.Contains(service.userAgents[0], "glambda-deploy/build-1234") {
+		t.Fatalf("want the deploy ID in the user agent, got %q", service.userAgents)
+	}
+	var found bool
+	for _, e := range events {
+		if e.Step == "aws-request" && e.Verbose && strings.Contains(e.Message, "Lambda Invoke, request ID 11111111-2222-3333-4444-555555555555") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("want a verbose event with the request ID of the invoke, got %+v", events)
+	}
+}
+
+func TestNewLambda_GeneratesDeployID(t *testing.T) {
+	t.Parallel()
+	var events []glambda.Event
+	l := lambdaOverFakeService(t, &fakeLambdaService{status: 200}, &events)
+	if l.DeployID() != "DEADBEEF" {
+		t.Errorf("want generated deploy ID DEADBEEF, got %q", l.DeployID())
+	}
+}
+
+func TestRequestID_IsReportedForFailedCalls(t *testing.T) {
+	t.Parallel()
+	service := &fakeLambdaService{status: 403, body: `{"message":"not allowed"}`}
+	var events []glambda.Event
+	l := lambdaOverFakeService(t, service, &events)
+	err := l.Invoke(context.Background(), nil, nil)
+	if err == nil {
+		t.Fatal("want an error from a refused call")
+	}
+	want := "11111111-2222-3333-4444-555555555555"
+	if got := glambda.RequestID(err); got != want {
+		t.Errorf("want request ID %s, got %q", want, got)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("want the request ID in the error message, got %q", err)
+	}
+	var found bool
+	for _, e := range events {
+		if e.Step == "aws-request" && !e.Verbose && e.Message == "Lambda Invoke failed, request ID "+want+", deploy ID DEADBEEF" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("want an event with the request ID of the failed call, got %+v", events)
+	}
+}
+
+func TestRequestID_IsEmptyForErrorsNotFromAWS(t *testing.T) {
+	t.Parallel()
+	if got := glambda.RequestID(io.EOF); got != "" {
+		t.Errorf("want no request ID, got %q", got)
+	}
+}
+
+func TestWithDeployID_RejectsIDsThatBreakTheUserAgent(t *testing.T) {
+	t.Parallel()
+	for _, id := range []string{"", "build 12", "a/b"} {
+		_, err := glambda.NewLambda("orders", "testdata/correct_test_handler/main.go", glambdatest.Sandbox(), glambda.WithDeployID(id))
+		if err == nil {
+			t.Errorf("want an error for deploy ID %q", id)
+		}
+	}
+}
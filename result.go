@@ -0,0 +1,137 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+)
+
+// DeployResult identifies what a deploy published, with fully qualified ARNs
+// that infrastructure as code tools can consume directly.
+type DeployResult struct {
+	// FunctionName is the name of the lambda function.
+	FunctionName string `json:"function_name"`
+	// FunctionARN is the unqualified ARN of the function, which always
+	// invokes $LATEST.
+	FunctionARN string `json:"function_arn"`
+	// Version is the version published by the deploy.
+	Version string `json:"version"`
+	// VersionARN is FunctionARN qualified with Version.
+	VersionARN string `json:"version_arn"`
+	// Alias is the alias traffic was shifted on, for a canary deploy.
+	Alias string `json:"alias,omitempty"`
+	// AliasARN is FunctionARN qualified with Alias.
+	AliasARN string `json:"alias_arn,omitempty"`
+	// FunctionURL is the function's URL, when it has one.
+	FunctionURL string `json:"function_url,omitempty"`
+	// HTTPAPIEndpoint is the endpoint of the function's HTTP API, when it
+	// has one.
+	HTTPAPIEndpoint string `json:"http_api_endpoint,omitempty"`
+	// SmokeTest is the outcome of checking the published version, or nil if
+	// the check was skipped, see [WithSmokeTest].
+	SmokeTest *SmokeTestResult `json:"smoke_test,omitempty"`
+}
+
+// QualifiedARN returns the ARN of a specific version or alias of a function.
+func QualifiedARN(functionARN, qualifier string) string {
+	return functionARN + ":" + qualifier
+}
+
+// Publish is a method on the [Lambda] struct that publishes a version of the
+// freshly deployed code, smoke tests that version, and reports what was
+// published. With [WithCanary], traffic is then shifted to the version, and a
+// rolled back canary is a [*CanaryRollbackError]. Reserved and provisioned
+// concurrency are applied last, waiting for provisioned instances to be ready,
+// and then any aliases given to [WithAlias] are brought up to date.
+//
+// A version that fails its smoke test is a [*SmokeTestError], returned along
+// with the result so far, since the deploy itself succeeded.
+func (l Lambda) Publish(ctx context.Context) (DeployResult, error) {
+	c := l.lambdaAPI()
+	l.report("publish", "publishing a new version")
+	version, err := WaitForConsistency(ctx, c, l.Name)
+	if err != nil {
+		return DeployResult{}, err
+	}
+	l.detail("publish", "published version %s", version)
+	result := DeployResult{
+		FunctionName: l.Name,
+		FunctionARN:  l.functionARN(),
+		Version:      version,
+		VersionARN:   QualifiedARN(l.functionARN(), version),
+	}
+	result.SmokeTest, err = l.runSmokeTest(ctx, version)
+	var failed *SmokeTestError
+	if errors.As(err, &failed) {
+		return result, err
+	}
+	if err != nil {
+		return DeployResult{}, err
+	}
+	if l.Canary != nil {
+		l.report("canary", "sending %d%% of traffic to version %s for %s", l.Canary.Percent, version, l.Canary.BakeTime)
+		err = NewCanaryAction(c, l.cloudWatchAPI(), l.Name, version, *l.Canary).Do(ctx)
+		if err != nil {
+			return DeployResult{}, err
+		}
+		result.Alias = LiveAlias
+		result.AliasARN = QualifiedARN(l.functionARN(), LiveAlias)
+	}
+	if l.ReservedConcurrency != nil || l.ProvisionedConcurrency != nil {
+		if p := l.ProvisionedConcurrency; p != nil {
+			l.report("concurrency", "provisioning %d instances on alias %s", p.Executions, p.Alias)
+		}
+		err = NewConcurrencyAction(c, l, version).Do(ctx)
+		if err != nil {
+			return DeployResult{}, err
+		}
+		if p := l.ProvisionedConcurrency; p != nil && result.Alias == "" {
+			result.Alias = p.Alias
+			result.AliasARN = QualifiedARN(l.functionARN(), p.Alias)
+		}
+	}
+	if len(l.Aliases) > 0 {
+		l.report("alias", "pointing %d aliases at the new code", len(l.Aliases))
+		err = NewAliasAction(c, l, version).Do(ctx)
+		if err != nil {
+			return DeployResult{}, err
+		}
+	}
+	if l.FunctionURL != nil {
+		result.FunctionURL, err = l.URL(ctx)
+		if err != nil {
+			return DeployResult{}, err
+		}
+	}
+	if l.HTTPAPI != nil {
+		result.HTTPAPIEndpoint, err = l.HTTPAPIEndpoint(ctx)
+		if err != nil {
+			return DeployResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// DeployAndPublish is [DeployWithContext], reporting what was published.
+func DeployAndPublish(ctx context.Context, name, source string, opts ...DeployOptions) (DeployResult, error) {
+	l, err := NewLambdaWithContext(ctx, name, source, opts...)
+	if err != nil {
+		return DeployResult{}, err
+	}
+	return l.publishDeploy(ctx)
+}
+
+// publishDeploy deploys the function and publishes the new version.
+func (l Lambda) publishDeploy(ctx context.Context) (DeployResult, error) {
+	err := l.Deploy(ctx)
+	if err != nil {
+		return DeployResult{}, err
+	}
+	return l.Publish(ctx)
+}
+
+// DeployConfigAndPublish is [DeployConfig], reporting what was published for
+// each function that deployed successfully. Functions are deployed one at a
+// time, see [DeployAllAndPublish] to deploy them concurrently.
+func DeployConfigAndPublish(ctx context.Context, cfg Config, opts ...DeployOptions) ([]DeployResult, error) {
+	return DeployAllAndPublish(ctx, cfg.Functions, 1, opts...)
+}
@@ -0,0 +1,32 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestDeployAndPublish_ReportsQualifiedARNs(t *testing.T) {
+	t.Parallel()
+	got, err := glambda.DeployAndPublish(context.Background(), "published", "testdata/correct_test_handler/main.go",
+		glambdatest.Sandbox(),
+		glambda.WithFunctionURL("NONE"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := glambda.DeployResult{
+		FunctionName: "published",
+		FunctionARN:  "arn:aws:lambda:us-east-1:123456789012:function:published",
+		Version:      "1",
+		VersionARN:   "arn:aws:lambda:us-east-1:123456789012:function:published:1",
+		FunctionURL:  "https://published.lambda-url.us-east-1.on.aws/",
+		SmokeTest:    &glambda.SmokeTestResult{Mode: glambda.SmokeTestDryRun, Passed: true},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,144 @@
+package glambda
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// RuntimeWarningDays is how many days before a runtime is deprecated that
+// plans start to warn about it, unless [WithRuntimeWarningDays] says
+// otherwise.
+const RuntimeWarningDays = 90
+
+//go:embed runtimes.json
+var bundledRuntimeCalendar []byte
+
+// RuntimeDeprecation is when AWS deprecates a lambda runtime, after which
+// functions on it no longer get security patches, and the runtime to move to.
+type RuntimeDeprecation struct {
+	Runtime string `json:"runtime"`
+	// Deprecated is the date of deprecation, as YYYY-MM-DD.
+	Deprecated string `json:"deprecated"`
+	Upgrade    string `json:"upgrade"`
+}
+
+// RuntimeCalendar is a list of runtime deprecations, as published in the
+// AWS Lambda runtime deprecation policy.
+type RuntimeCalendar []RuntimeDeprecation
+
+// DefaultRuntimeCalendar returns the calendar bundled with this version of
+// glambda. For a newer one, see [WithRuntimeCalendar].
+func DefaultRuntimeCalendar() RuntimeCalendar {
+	calendar, err := ParseRuntimeCalendar(bundledRuntimeCalendar)
+	if err != nil {
+		panic(err)
+	}
+	return calendar
+}
+
+// ParseRuntimeCalendar parses a JSON runtime calendar, in the format of
+// runtimes.json in the glambda repository.
+func ParseRuntimeCalendar(data []byte) (RuntimeCalendar, error) {
+	var calendar RuntimeCalendar
+	err := json.Unmarshal(data, &calendar)
+	if err != nil {
+		return nil, fmt.Errorf("invalid runtime calendar, %w", err)
+	}
+	for _, d := range calendar {
+		if d.Runtime == "" {
+			return nil, fmt.Errorf("invalid runtime calendar, entry without a runtime")
+		}
+		_, err := time.Parse(time.DateOnly, d.Deprecated)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deprecation date %q for runtime %s, expected YYYY-MM-DD", d.Deprecated, d.Runtime)
+		}
+	}
+	return calendar, nil
+}
+
+// Warning returns a warning if runtime is deprecated, or will be within
+// days of now, recommending the runtime to upgrade to. It returns an empty
+// string for runtimes that aren't in the calendar or are further out.
+func (c RuntimeCalendar) Warning(runtime string, now time.Time, days int) string {
+	for _, d := range c {
+		if d.Runtime != runtime {
+			continue
+		}
+		deprecated, err := time.Parse(time.DateOnly, d.Deprecated)
+		if err != nil {
+			return ""
+		}
+		upgrade := ""
+		if d.Upgrade != "" {
+			upgrade = ", upgrade to " + d.Upgrade
+		}
+		remaining := int(math.Ceil(deprecated.Sub(now).Hours() / 24))
+		switch {
+		case remaining <= 0:
+			return fmt.Sprintf("runtime %s was deprecated on %s and no longer gets security patches%s", runtime, d.Deprecated, upgrade)
+		case remaining <= days:
+			return fmt.Sprintf("runtime %s is deprecated on %s, in %d days%s", runtime, d.Deprecated, remaining, upgrade)
+		}
+		return ""
+	}
+	return ""
+}
+
+// WithRuntimeCalendar is a deploy option that checks runtimes against the
+// calendar in the JSON file at path, rather than the one bundled with
+// glambda, for deprecations announced since it was released.
+func WithRuntimeCalendar(path string) DeployOptions {
+	return func(l *Lambda) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		calendar, err := ParseRuntimeCalendar(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		l.runtimeCalendar = calendar
+		return nil
+	}
+}
+
+// WithRuntimeWarningDays is a deploy option that sets how many days before
+// its runtime is deprecated a plan starts to warn about it.
+func WithRuntimeWarningDays(days int) DeployOptions {
+	return func(l *Lambda) error {
+		if days < 0 {
+			return fmt.Errorf("runtime warning days must not be negative, got %d", days)
+		}
+		l.runtimeWarningDays = &days
+		return nil
+	}
+}
+
+// runtimeWarnings checks the runtime the function will run on after the
+// deploy: provided.al2023 for a new function, or for an existing one its
+// current runtime, which deploys don't change.
+func (l Lambda) runtimeWarnings(action LambdaAction) []string {
+	var runtime string
+	switch a := action.(type) {
+	case LambdaCreateAction:
+		runtime = string(a.CreateLambdaCommand.Runtime)
+	case LambdaUpdateAction:
+		runtime = string(a.Runtime)
+	}
+	calendar := l.runtimeCalendar
+	if calendar == nil {
+		calendar = DefaultRuntimeCalendar()
+	}
+	days := RuntimeWarningDays
+	if l.runtimeWarningDays != nil {
+		days = *l.runtimeWarningDays
+	}
+	if w := calendar.Warning(runtime, time.Now(), days); w != "" {
+		return []string{w}
+	}
+	return []string{}
+}
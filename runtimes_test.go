@@ -0,0 +1,126 @@
+package glambda_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestDefaultRuntimeCalendar_IsValidAndCoversGoRuntimes(t *testing.T) {
+	t.Parallel()
+	calendar := glambda.DefaultRuntimeCalendar()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, runtime := range []string{"go1.x", "provided", "provided.al2"} {
+		if !strings.Contains(calendar.Warning(runtime, now, 0), "upgrade to provided.al2023") {
+			t.Errorf("expected %s to be deprecated in favour of provided.al2023", runtime)
+		}
+	}
+}
+
+func TestRuntimeCalendarWarning(t *testing.T) {
+	t.Parallel()
+	calendar := glambda.RuntimeCalendar{{Runtime: "provided.al2", Deprecated: "2026-06-30", Upgrade: "provided.al2023"}}
+	tcs := map[string]struct {
+		runtime string
+		now     time.Time
+		want    string
+	}{
+		"deprecated": {
+			runtime: "provided.al2",
+			now:     time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+			want:    "runtime provided.al2 was deprecated on 2026-06-30 and no longer gets security patches, upgrade to provided.al2023",
+		},
+		"within window": {
+			runtime: "provided.al2",
+			now:     time.Date(2026, 6, 20, 0, 0, 0, 0, time.UTC),
+			want:    "runtime provided.al2 is deprecated on 2026-06-30, in 10 days, upgrade to provided.al2023",
+		},
+		"outside window": {
+			runtime: "provided.al2",
+			now:     time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"not in calendar": {
+			runtime: "provided.al2023",
+			now:     time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for name, tc := range tcs {
+		got := calendar.Warning(tc.runtime, tc.now, 30)
+		if got != tc.want {
+			t.Errorf("%s: want %q, got %q", name, tc.want, got)
+		}
+	}
+}
+
+func TestParseRuntimeCalendar_RejectsInvalidDates(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.ParseRuntimeCalendar([]byte(`[{"runtime":"go1.x","deprecated":"8 January 2024"}]`))
+	if err == nil {
+		t.Error("expected error for invalid date, got nil")
+	}
+}
+
+func TestPlan_WarnsAboutDeprecatedRuntimeOfExistingFunction(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{
+			FunctionName: aws.String("legacy"),
+			Runtime:      types.RuntimeGo1x,
+		},
+		Tags: map[string]string{glambda.ManagedTagKey: "true"},
+	})
+	l, err := glambda.NewLambda("legacy", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Warnings) != 1 || !strings.HasPrefix(plan.Warnings[0], "runtime go1.x was deprecated") {
+		t.Fatalf("expected go1.x deprecation warning, got %q", plan.Warnings)
+	}
+	if !strings.Contains(plan.String(), "! warning: runtime go1.x") {
+		t.Errorf("expected warning in rendered plan, got %s", plan)
+	}
+}
+
+func TestPlan_UsesRuntimeCalendarFromFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "runtimes.json")
+	soon := time.Now().AddDate(0, 0, 20).Format(time.DateOnly)
+	err := os.WriteFile(path, []byte(`[{"runtime":"provided.al2023","deprecated":"`+soon+`","upgrade":"provided.al2025"}]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for days, want := range map[int]int{30: 1, 7: 0} {
+		l, err := glambda.NewLambda("fresh", "testdata/correct_test_handler/main.go",
+			glambdatest.Sandbox(),
+			glambda.WithRuntimeCalendar(path),
+			glambda.WithRuntimeWarningDays(days),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan, err := l.Plan(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.Warnings) != want {
+			t.Errorf("warning within %d days: expected %d warnings, got %q", days, want, plan.Warnings)
+		}
+	}
+}
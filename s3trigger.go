@@ -0,0 +1,264 @@
+package glambda
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// DefaultS3Events are the bucket events that invoke a function when
+// [WithS3Trigger] is given no events.
+var DefaultS3Events = []string{"s3:ObjectCreated:*"}
+
+// S3Trigger describes an S3 bucket whose events invoke the lambda function.
+type S3Trigger struct {
+	Bucket string
+	// Events are S3 event types, such as "s3:ObjectCreated:*".
+	Events []string
+	// Prefix and Suffix, when set, limit the trigger to object keys that
+	// start or end with them, e.g. "uploads/" and ".jpg".
+	Prefix string
+	Suffix string
+}
+
+// WithS3Trigger is a deploy option that makes events in an S3 bucket invoke
+// the lambda function. The bucket's notification configuration is updated to
+// send the events to the function, and S3 is allowed to invoke the function
+// for that bucket only. Other notifications on the bucket are left alone.
+func WithS3Trigger(bucket string, events []string, prefix, suffix string) DeployOptions {
+	return func(l *Lambda) error {
+		if bucket == "" {
+			return fmt.Errorf("an S3 trigger requires a bucket name")
+		}
+		if len(events) == 0 {
+			events = DefaultS3Events
+		}
+		for _, e := range events {
+			if !strings.HasPrefix(e, "s3:") {
+				return fmt.Errorf("invalid S3 event %q, expected an event type such as s3:ObjectCreated:*", e)
+			}
+		}
+		l.S3Triggers = append(l.S3Triggers, S3Trigger{
+			Bucket: bucket,
+			Events: events,
+			Prefix: prefix,
+			Suffix: suffix,
+		})
+		return nil
+	}
+}
+
+// S3TriggerAction is an [Action] that wires an [S3Trigger] to a lambda
+// function. Each function has at most one notification per bucket, which is
+// replaced on every deploy. Buckets the function no longer has triggers on
+// are cleaned up by [Lambda.removeStaleS3Triggers].
+type S3TriggerAction struct {
+	client       S3Client
+	lambdaClient LambdaClient
+	Name         string
+	FunctionARN  string
+	AccountID    string
+	Trigger      S3Trigger
+	// Siblings are the IDs of the notifications for the function's other
+	// triggers on the same bucket, which are kept when stale ones are
+	// removed.
+	Siblings []string
+}
+
+// NewS3TriggerAction is a constructor function that creates a new [S3TriggerAction].
+func NewS3TriggerAction(client S3Client, lambdaClient LambdaClient, l Lambda, trigger S3Trigger) S3TriggerAction {
+	var siblings []string
+	for _, t := range l.S3Triggers {
+		if t.Bucket == trigger.Bucket {
+			siblings = append(siblings, notificationID(l.Name, t))
+		}
+	}
+	return S3TriggerAction{
+		client:       client,
+		lambdaClient: lambdaClient,
+		Name:         l.Name,
+		FunctionARN:  l.functionARN(),
+		AccountID:    l.AWSAccountID,
+		Trigger:      trigger,
+		Siblings:     siblings,
+	}
+}
+
+// Client returns the required client type. In this case [S3Client].
+func (a S3TriggerAction) Client() S3Client {
+	return a.client
+}
+
+// NotificationID identifies the function's notification within the bucket's
+// notification configuration. Triggers limited to a prefix or suffix have a
+// hash of them in their ID, so that a function can have several triggers on
+// one bucket.
+func (a S3TriggerAction) NotificationID() string {
+	return notificationID(a.Name, a.Trigger)
+}
+
+// notificationID is the ID of the notification for a function's trigger. The
+// hash follows a ".", which function names can't contain, so that the IDs of
+// one function never start with another's.
+func notificationID(function string, t S3Trigger) string {
+	id := "glambda-" + function
+	if t.Prefix == "" && t.Suffix == "" {
+		return id
+	}
+	sum := sha256.Sum256([]byte(t.Prefix + "\n" + t.Suffix))
+	return id + "." + hex.EncodeToString(sum[:4])
+}
+
+// ownsNotification reports whether a notification ID was given by
+// [S3TriggerAction] to one of the function's triggers.
+func ownsNotification(function, id string) bool {
+	base := "glambda-" + function
+	return id == base || strings.HasPrefix(id, base+".")
+}
+
+// Do is the implementation of the [Action] interface. S3 checks that it is
+// allowed to invoke the function when the notification is saved, so the
+// permission is added first.
+func (a S3TriggerAction) Do(ctx context.Context) error {
+	bucket := aws.String(a.Trigger.Bucket)
+	_, err := a.lambdaClient.AddPermission(ctx, &lambda.AddPermissionInput{
+		FunctionName:  aws.String(a.Name),
+		StatementId:   aws.String("glambda_s3_" + strings.ReplaceAll(a.Trigger.Bucket, ".", "_")),
+		Action:        aws.String("lambda:InvokeFunction"),
+		Principal:     aws.String("s3.amazonaws.com"),
+		SourceArn:     aws.String("arn:aws:s3:::" + a.Trigger.Bucket),
+		SourceAccount: aws.String(a.AccountID),
+	})
+	var conflict *types.ResourceConflictException
+	if err != nil && !errors.As(err, &conflict) {
+		return err
+	}
+	current, err := a.Client().GetBucketNotificationConfiguration(ctx, &s3.GetBucketNotificationConfigurationInput{
+		Bucket:              bucket,
+		ExpectedBucketOwner: aws.String(a.AccountID),
+	})
+	if err != nil {
+		return err
+	}
+	var functions []sTypes.LambdaFunctionConfiguration
+	// The function's notifications for triggers it no longer has are
+	// removed along with the one being replaced.
+	for _, f := range current.LambdaFunctionConfigurations {
+		id := aws.ToString(f.Id)
+		if id == a.NotificationID() || ownsNotification(a.Name, id) && !slices.Contains(a.Siblings, id) {
+			continue
+		}
+		functions = append(functions, f)
+	}
+	functions = append(functions, a.notification())
+	_, err = a.Client().PutBucketNotificationConfiguration(ctx, &s3.PutBucketNotificationConfigurationInput{
+		Bucket:              bucket,
+		ExpectedBucketOwner: aws.String(a.AccountID),
+		NotificationConfiguration: &sTypes.NotificationConfiguration{
+			EventBridgeConfiguration:     current.EventBridgeConfiguration,
+			LambdaFunctionConfigurations: functions,
+			QueueConfigurations:          current.QueueConfigurations,
+			TopicConfigurations:          current.TopicConfigurations,
+		},
+	})
+	return err
+}
+
+func (a S3TriggerAction) notification() sTypes.LambdaFunctionConfiguration {
+	n := sTypes.LambdaFunctionConfiguration{
+		Id:                aws.String(a.NotificationID()),
+		LambdaFunctionArn: aws.String(a.FunctionARN),
+	}
+	for _, e := range a.Trigger.Events {
+		n.Events = append(n.Events, sTypes.Event(e))
+	}
+	var rules []sTypes.FilterRule
+	if a.Trigger.Prefix != "" {
+		rules = append(rules, sTypes.FilterRule{Name: sTypes.FilterRuleNamePrefix, Value: aws.String(a.Trigger.Prefix)})
+	}
+	if a.Trigger.Suffix != "" {
+		rules = append(rules, sTypes.FilterRule{Name: sTypes.FilterRuleNameSuffix, Value: aws.String(a.Trigger.Suffix)})
+	}
+	if len(rules) > 0 {
+		n.Filter = &sTypes.NotificationConfigurationFilter{Key: &sTypes.S3KeyFilter{FilterRules: rules}}
+	}
+	return n
+}
+
+// staleS3Triggers finds the buckets an earlier deploy gave the function
+// triggers on that it no longer has, from the statements [S3TriggerAction]
+// added to its resource policy, by statement ID.
+func (l Lambda) staleS3Triggers(ctx context.Context) (map[string]string, error) {
+	policy, err := l.lambdaAPI().GetPolicy(ctx, &lambda.GetPolicyInput{
+		FunctionName: aws.String(l.Name),
+	})
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	statements, err := policyStatements(aws.ToString(policy.Policy))
+	if err != nil {
+		return nil, err
+	}
+	stale := map[string]string{}
+	for _, s := range statements {
+		bucket := s3TriggerBucket(s)
+		if bucket == "" || slices.ContainsFunc(l.S3Triggers, func(t S3Trigger) bool { return t.Bucket == bucket }) {
+			continue
+		}
+		stale[s.Sid] = bucket
+	}
+	return stale, nil
+}
+
+// removeStaleS3Triggers removes the function's notifications from buckets it
+// no longer has triggers on, and the permissions that let those buckets
+// invoke it. A bucket that has since been deleted has no notifications left
+// to remove.
+func (l Lambda) removeStaleS3Triggers(ctx context.Context) error {
+	stale, err := l.staleS3Triggers(ctx)
+	if err != nil {
+		return err
+	}
+	var notFound *types.ResourceNotFoundException
+	for _, sid := range sortedKeys(stale) {
+		bucket := stale[sid]
+		l.report("s3-trigger", "removing notifications from bucket %s", bucket)
+		notifications, err := l.s3Notifications(ctx, []string{bucket})
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchBucket" {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		if ids := notifications[bucket]; len(ids) > 0 {
+			err = l.removeS3Notifications(ctx, bucket, ids)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = l.lambdaAPI().RemovePermission(ctx, &lambda.RemovePermissionInput{
+			FunctionName: aws.String(l.Name),
+			StatementId:  aws.String(sid),
+		})
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package glambda_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	sTypes "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestWithS3Trigger_ValidatesBucketAndEvents(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithS3Trigger("", nil, "", ""))
+	if err == nil {
+		t.Error("expected error for missing bucket, got nil")
+	}
+	_, err = glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithS3Trigger("images", []string{"ObjectCreated:*"}, "", ""))
+	if err == nil {
+		t.Error("expected error for malformed event, got nil")
+	}
+}
+
+func TestS3TriggerAction_AddsPermissionThenKeepsOtherNotifications(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	other := sTypes.LambdaFunctionConfiguration{
+		Id:                aws.String("someone-else"),
+		LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:other"),
+		Events:            []sTypes.Event{"s3:ObjectRemoved:*"},
+	}
+	recorder.Respond("GetBucketNotificationConfiguration", &s3.GetBucketNotificationConfigurationOutput{
+		LambdaFunctionConfigurations: []sTypes.LambdaFunctionConfiguration{
+			other,
+			{Id: aws.String("glambda-thumbnailer"), LambdaFunctionArn: aws.String("stale")},
+		},
+	})
+	l, err := glambda.NewLambda("thumbnailer", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithS3Trigger("my.images", nil, "uploads/", ".jpg"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewS3TriggerAction(glambdatest.DummyS3Client{Recorder: recorder}, glambdatest.DummyLambdaClient{Recorder: recorder}, *l, l.S3Triggers[0]).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOps := []string{"AddPermission", "GetBucketNotificationConfiguration", "PutBucketNotificationConfiguration"}
+	if !cmp.Equal(wantOps, recorder.Operations()) {
+		t.Fatal(cmp.Diff(wantOps, recorder.Operations()))
+	}
+	permission := recorder.Calls("AddPermission")[0].Input.(*lambda.AddPermissionInput)
+	if aws.ToString(permission.SourceArn) != "arn:aws:s3:::my.images" || aws.ToString(permission.StatementId) != "glambda_s3_my_images" {
+		t.Errorf("unexpected permission %+v", permission)
+	}
+	put := recorder.Calls("PutBucketNotificationConfiguration")[0].Input.(*s3.PutBucketNotificationConfigurationInput)
+	want := []sTypes.LambdaFunctionConfiguration{
+		other,
+		{
+			Id:                aws.String("glambda-thumbnailer.686bd890"),
+			LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:thumbnailer"),
+			Events:            []sTypes.Event{"s3:ObjectCreated:*"},
+			Filter: &sTypes.NotificationConfigurationFilter{
+				Key: &sTypes.S3KeyFilter{
+					FilterRules: []sTypes.FilterRule{
+						{Name: sTypes.FilterRuleNamePrefix, Value: aws.String("uploads/")},
+						{Name: sTypes.FilterRuleNameSuffix, Value: aws.String(".jpg")},
+					},
+				},
+			},
+		},
+	}
+	got := put.NotificationConfiguration.LambdaFunctionConfigurations
+	ignore := cmpopts.IgnoreUnexported(sTypes.LambdaFunctionConfiguration{}, sTypes.NotificationConfigurationFilter{}, sTypes.S3KeyFilter{}, sTypes.FilterRule{})
+	if !cmp.Equal(want, got, ignore) {
+		t.Error(cmp.Diff(want, got, ignore))
+	}
+}
+
+func TestS3TriggerAction_KeepsTheFunctionsOtherTriggersOnTheBucket(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetBucketNotificationConfiguration", &s3.GetBucketNotificationConfigurationOutput{
+		LambdaFunctionConfigurations: []sTypes.LambdaFunctionConfiguration{
+			{Id: aws.String("glambda-thumbnailer.d035a5bc")},
+			{Id: aws.String("glambda-thumbnailer.0badf00d")},
+			{Id: aws.String("glambda-thumbnailer-v2")},
+		},
+	})
+	l, err := glambda.NewLambda("thumbnailer", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithS3Trigger("images", nil, "raw/", ""),
+		glambda.WithS3Trigger("images", nil, "thumbs/", ""),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = glambda.NewS3TriggerAction(glambdatest.DummyS3Client{Recorder: recorder}, glambdatest.DummyLambdaClient{Recorder: recorder}, *l, l.S3Triggers[0]).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := recorder.Calls("PutBucketNotificationConfiguration")[0].Input.(*s3.PutBucketNotificationConfigurationInput)
+	var got []string
+	for _, n := range put.NotificationConfiguration.LambdaFunctionConfigurations {
+		got = append(got, aws.ToString(n.Id))
+	}
+	want := []string{"glambda-thumbnailer.d035a5bc", "glambda-thumbnailer-v2", "glambda-thumbnailer.e138ca82"}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestDeploy_RemovesTriggersFromBucketsNoLongerGiven(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{FunctionName: aws.String("fn")},
+		Tags:          map[string]string{glambda.ManagedTagKey: "true"},
+	})
+	recorder.Respond("GetPolicy", &lambda.GetPolicyOutput{
+		Policy: aws.String(`{"Statement":[
+			{"Sid":"glambda_s3_uploads","Effect":"Allow","Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:s3:::uploads"}}},
+			{"Sid":"glambda_s3_images","Effect":"Allow","Condition":{"ArnLike":{"AWS:SourceArn":"arn:aws:s3:::images"}}}
+		]}`),
+	})
+	recorder.Respond("GetBucketNotificationConfiguration", &s3.GetBucketNotificationConfigurationOutput{
+		LambdaFunctionConfigurations: []sTypes.LambdaFunctionConfiguration{
+			{Id: aws.String("glambda-fn"), LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:fn")},
+			{Id: aws.String("hand-made"), LambdaFunctionArn: aws.String("arn:aws:lambda:us-east-1:123456789012:function:other")},
+		},
+	})
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, ConsistantAfterXRetries: new(int)}),
+		glambda.WithS3Trigger("images", nil, "", ""),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "  - PutBucketNotificationConfiguration uploads\n      notifications for fn\n  - RemovePermission fn\n      statement: glambda_s3_uploads\n"
+	if got := plan.String(); !strings.Contains(got, want) {
+		t.Errorf("want plan to contain:\n%s\ngot:\n%s", want, got)
+	}
+	err = l.Deploy(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := recorder.Calls("RemovePermission")
+	if len(removed) != 1 || aws.ToString(removed[0].Input.(*lambda.RemovePermissionInput).StatementId) != "glambda_s3_uploads" {
+		t.Fatalf("want the uploads permission removed, got %v", removed)
+	}
+	var uploads *s3.PutBucketNotificationConfigurationInput
+	for _, c := range recorder.Calls("PutBucketNotificationConfiguration") {
+		if input := c.Input.(*s3.PutBucketNotificationConfigurationInput); aws.ToString(input.Bucket) == "uploads" {
+			uploads = input
+		}
+	}
+	if uploads == nil {
+		t.Fatal("want the function's notification removed from uploads")
+	}
+	var ids []string
+	for _, f := range uploads.NotificationConfiguration.LambdaFunctionConfigurations {
+		ids = append(ids, aws.ToString(f.Id))
+	}
+	if !cmp.Equal([]string{"hand-made"}, ids) {
+		t.Error(cmp.Diff([]string{"hand-made"}, ids))
+	}
+}
@@ -0,0 +1,84 @@
+package glambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// UploadPartSize is the size of each part of a multipart upload of a
+// package to S3. Packages no larger than this are uploaded in one request.
+var UploadPartSize int64 = 16 << 20
+
+// S3Upload describes where packages are staged in S3 before Lambda fetches
+// them, see [WithS3Upload].
+type S3Upload struct {
+	Bucket    string
+	KeyPrefix string
+}
+
+// WithS3Upload is a deploy option that uploads the package to an S3 bucket,
+// and points Lambda at the object, rather than sending the zip inline. This
+// lifts the 50MB limit on inline uploads. Large packages are uploaded in
+// parts, so a dropped connection only costs the part in flight. The bucket
+// must be in the same region as the function.
+func WithS3Upload(bucket, keyPrefix string) DeployOptions {
+	return func(l *Lambda) error {
+		if bucket == "" || strings.ContainsAny(bucket, "/:") {
+			return fmt.Errorf("invalid S3 bucket name %q", bucket)
+		}
+		l.S3Upload = &S3Upload{Bucket: bucket, KeyPrefix: keyPrefix}
+		return nil
+	}
+}
+
+// Key is where the package for the named function is stored. Keys are named
+// after the package's content, so that a retried or repeated deploy of the
+// same code overwrites rather than accumulates objects.
+func (u S3Upload) Key(name string, pkg []byte) string {
+	sum := sha256.Sum256(pkg)
+	return u.KeyPrefix + name + "/" + hex.EncodeToString(sum[:]) + ".zip"
+}
+
+// PackageUpload is an [Action] that uploads a package to S3.
+type PackageUpload struct {
+	client  S3Client
+	Bucket  string
+	Key     string
+	Package []byte
+}
+
+// NewPackageUpload is a constructor function that creates a new [PackageUpload]
+// of pkg for the named function.
+func NewPackageUpload(client S3Client, u S3Upload, name string, pkg []byte) *PackageUpload {
+	return &PackageUpload{client: client, Bucket: u.Bucket, Key: u.Key(name, pkg), Package: pkg}
+}
+
+// Client returns the required client type. In this case [S3Client].
+func (a PackageUpload) Client() S3Client {
+	return a.client
+}
+
+// Do is the implementation of the [Action] interface. Packages larger than
+// [UploadPartSize] are uploaded in parts, each retried on its own, and an
+// upload that fails outright is aborted so no parts are left behind.
+func (a PackageUpload) Do(ctx context.Context) error {
+	uploader := manager.NewUploader(a.Client(), func(u *manager.Uploader) {
+		u.PartSize = UploadPartSize
+	})
+	return retryUpload(ctx, func() error {
+		_, err := uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(a.Bucket),
+			Key:    aws.String(a.Key),
+			Body:   bytes.NewReader(a.Package),
+		})
+		return err
+	}, nil)
+}
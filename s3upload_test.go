@@ -0,0 +1,70 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestDeploy_WithS3UploadPointsLambdaAtUploadedObject(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	err := glambda.Deploy("staged", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithS3Upload("artifacts", "lambda/"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	puts := recorder.Calls("PutObject")
+	if len(puts) != 1 {
+		t.Fatalf("expected one upload, got %d", len(puts))
+	}
+	put := puts[0].Input.(*s3.PutObjectInput)
+	create := recorder.Calls("CreateFunction")[0].Input.(*lambda.CreateFunctionInput)
+	if aws.ToString(create.Code.S3Bucket) != "artifacts" || aws.ToString(create.Code.S3Key) != aws.ToString(put.Key) {
+		t.Errorf("expected function code at s3://artifacts/%s, got %+v", aws.ToString(put.Key), create.Code)
+	}
+	if create.Code.ZipFile != nil {
+		t.Error("expected no inline package alongside the S3 object")
+	}
+}
+
+func TestPackageUpload_UploadsLargePackagesInParts(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyS3Client{Recorder: recorder}
+	pkg := make([]byte, glambda.UploadPartSize+1)
+	err := glambda.NewPackageUpload(client, glambda.S3Upload{Bucket: "artifacts"}, "big", pkg).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int{}
+	for _, op := range recorder.Operations() {
+		counts[op]++
+	}
+	if counts["CreateMultipartUpload"] != 1 || counts["UploadPart"] != 2 || counts["CompleteMultipartUpload"] != 1 || counts["PutObject"] != 0 {
+		t.Errorf("expected a two part upload, got %v", counts)
+	}
+}
+
+func TestPackageUpload_AbortsFailedMultipartUpload(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("UploadPart", errors.New("AccessDenied"), 2)
+	client := glambdatest.DummyS3Client{Recorder: recorder}
+	pkg := make([]byte, glambda.UploadPartSize+1)
+	err := glambda.NewPackageUpload(client, glambda.S3Upload{Bucket: "artifacts"}, "big", pkg).Do(context.Background())
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(recorder.Calls("AbortMultipartUpload")) != 1 {
+		t.Errorf("expected the upload to be aborted, got %v", recorder.Operations())
+	}
+}
@@ -0,0 +1,113 @@
+package glambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// SmokeTest is how [Lambda.Publish] checks a freshly published version before
+// shifting traffic to it, see [WithSmokeTest].
+type SmokeTest string
+
+const (
+	// SmokeTestDryRun asks Lambda to validate an invocation of the version,
+	// which checks that it exists and that the deployer may invoke it,
+	// without running any code. It is the default.
+	SmokeTestDryRun SmokeTest = "dry-run"
+	// SmokeTestInvoke invokes the version with a test payload, and fails if
+	// the function returns an error.
+	SmokeTestInvoke SmokeTest = "invoke"
+	// SmokeTestSkip doesn't check the version at all.
+	SmokeTestSkip SmokeTest = "skip"
+)
+
+// SmokeTestResult is the outcome of the smoke test of a published version.
+type SmokeTestResult struct {
+	Mode   SmokeTest `json:"mode"`
+	Passed bool      `json:"passed"`
+	// Error says why the test failed.
+	Error string `json:"error,omitempty"`
+}
+
+// SmokeTestError is returned by [Lambda.Publish] when the function deployed
+// and a version was published, but the version failed its smoke test. The
+// version isn't promoted: canary traffic shifting and concurrency settings
+// are skipped.
+type SmokeTestError struct {
+	FunctionName string
+	Version      string
+	Mode         SmokeTest
+	Err          error
+}
+
+func (e *SmokeTestError) Error() string {
+	return fmt.Sprintf("deployed %s version %s, but its %s smoke test failed: %v", e.FunctionName, e.Version, e.Mode, e.Err)
+}
+
+func (e *SmokeTestError) Unwrap() error {
+	return e.Err
+}
+
+// WithSmokeTest is a deploy option that chooses how a published version is
+// checked, see [SmokeTest]. The payload is only sent by [SmokeTestInvoke],
+// and defaults to an empty JSON object. A real invocation runs the
+// function's code, so it suits handlers without side effects, or that can
+// recognise a test payload.
+func WithSmokeTest(mode SmokeTest, payload []byte) DeployOptions {
+	return func(l *Lambda) error {
+		switch mode {
+		case SmokeTestDryRun, SmokeTestInvoke, SmokeTestSkip:
+		default:
+			return fmt.Errorf("invalid smoke test %q, expected dry-run, invoke or skip", mode)
+		}
+		if payload != nil && !json.Valid(payload) {
+			return fmt.Errorf("smoke test payload must be JSON, got %q", payload)
+		}
+		l.smokeTest = mode
+		l.smokeTestPayload = payload
+		return nil
+	}
+}
+
+// runSmokeTest checks a published version, returning nil when the test was
+// skipped.
+func (l Lambda) runSmokeTest(ctx context.Context, version string) (*SmokeTestResult, error) {
+	mode := l.smokeTest
+	if mode == "" {
+		mode = SmokeTestDryRun
+	}
+	var err error
+	switch mode {
+	case SmokeTestSkip:
+		l.detail("smoke-test", "skipping the smoke test of version %s", version)
+		return nil, nil
+	case SmokeTestDryRun:
+		l.detail("smoke-test", "checking version %s can be invoked", version)
+		_, err = l.lambdaAPI().Invoke(ctx, &lambda.InvokeInput{
+			FunctionName:   aws.String(l.Name),
+			Qualifier:      aws.String(version),
+			InvocationType: types.InvocationTypeDryRun,
+		})
+	case SmokeTestInvoke:
+		l.report("smoke-test", "invoking version %s", version)
+		payload := l.smokeTestPayload
+		if payload == nil {
+			payload = []byte("{}")
+		}
+		err = l.Invoke(ctx, payload, nil, WithQualifier(version))
+	}
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		l.report("smoke-test", "%s smoke test of version %s failed: %v", mode, version, err)
+		return &SmokeTestResult{Mode: mode, Error: err.Error()},
+			&SmokeTestError{FunctionName: l.Name, Version: version, Mode: mode, Err: err}
+	}
+	return &SmokeTestResult{Mode: mode, Passed: true}, nil
+}
@@ -0,0 +1,109 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func publishWithSmokeTest(t *testing.T, recorder *glambdatest.Recorder, opts ...glambda.DeployOptions) (glambda.DeployResult, error) {
+	t.Helper()
+	opts = append([]glambda.DeployOptions{glambdatest.SandboxWithRecorder(recorder)}, opts...)
+	l, err := glambda.NewLambda("smoked", "testdata/correct_test_handler/main.go", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l.Publish(context.Background())
+}
+
+func TestPublish_DryRunsTheVersionByDefault(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	result, err := publishWithSmokeTest(t, recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("Invoke")
+	if len(calls) != 1 || calls[0].Input.(*lambda.InvokeInput).InvocationType != types.InvocationTypeDryRun {
+		t.Errorf("want a single dry run invoke, got %v", recorder.Operations())
+	}
+	if result.SmokeTest == nil || result.SmokeTest.Mode != glambda.SmokeTestDryRun || !result.SmokeTest.Passed {
+		t.Errorf("want a passed dry run in the result, got %+v", result.SmokeTest)
+	}
+}
+
+func TestPublish_SkipsTheSmokeTestWhenAsked(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	result, err := publishWithSmokeTest(t, recorder, glambda.WithSmokeTest(glambda.SmokeTestSkip, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Calls("Invoke")) != 0 || result.SmokeTest != nil {
+		t.Errorf("want no smoke test, got %v and %+v", recorder.Operations(), result.SmokeTest)
+	}
+}
+
+func TestPublish_InvokesTheVersionWithTheTestPayload(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	_, err := publishWithSmokeTest(t, recorder, glambda.WithSmokeTest(glambda.SmokeTestInvoke, []byte(`{"ping":true}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("Invoke")
+	if len(calls) != 1 {
+		t.Fatalf("want a single invoke, got %v", recorder.Operations())
+	}
+	input := calls[0].Input.(*lambda.InvokeInput)
+	if input.InvocationType == types.InvocationTypeDryRun || string(input.Payload) != `{"ping":true}` || aws.ToString(input.Qualifier) != "1" {
+		t.Errorf("want version 1 invoked with the test payload, got %+v", input)
+	}
+}
+
+func TestPublish_ReportsAFailedSmokeTestApartFromTheDeploy(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("Invoke", &lambda.InvokeOutput{
+		FunctionError: aws.String("Unhandled"),
+		Payload:       []byte(`{"errorType":"Runtime.ExitError","errorMessage":"panic: missing TABLE_NAME"}`),
+	})
+	result, err := publishWithSmokeTest(t, recorder,
+		glambda.WithSmokeTest(glambda.SmokeTestInvoke, nil),
+		glambda.WithCanary(10, time.Minute),
+	)
+	var failed *glambda.SmokeTestError
+	if !errors.As(err, &failed) || failed.Version != "1" {
+		t.Fatalf("want a SmokeTestError for version 1, got %v", err)
+	}
+	var fnErr *glambda.FunctionError
+	if !errors.As(err, &fnErr) || fnErr.ErrorType != "Runtime.ExitError" {
+		t.Errorf("want the function's error, got %v", err)
+	}
+	if result.Version != "1" || result.SmokeTest == nil || result.SmokeTest.Passed || result.SmokeTest.Error == "" {
+		t.Errorf("want the published version with a failed smoke test, got %+v", result)
+	}
+	if len(recorder.Calls("CreateAlias", "UpdateAlias")) != 0 {
+		t.Errorf("want no traffic shifted to the failed version, got %v", recorder.Operations())
+	}
+}
+
+func TestWithSmokeTest_RejectsUnknownModesAndPayloadsThatArentJSON(t *testing.T) {
+	t.Parallel()
+	for _, opt := range []glambda.DeployOptions{
+		glambda.WithSmokeTest("sometimes", nil),
+		glambda.WithSmokeTest(glambda.SmokeTestInvoke, []byte("ping")),
+	} {
+		_, err := glambda.NewLambda("smoked", "", glambdatest.Sandbox(), opt)
+		if err == nil {
+			t.Error("want an error")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package glambda
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeploySummary describes a finished deploy for the people reviewing it, such
+// as in a pull request comment or a GitHub Actions job summary.
+type DeploySummary struct {
+	// Results are the versions that were published.
+	Results []DeployResult
+	// Plans are the changes the deploy applied, if they are known.
+	Plans []Plan
+	// Duration is how long the deploy took.
+	Duration time.Duration
+	// Sandbox is true when nothing in AWS was changed, see
+	// glambdatest.Sandbox.
+	Sandbox bool
+	// Err is why the deploy failed, or nil if it succeeded.
+	Err error
+}
+
+// Markdown renders the summary as GitHub flavoured markdown: a table of the
+// published versions, followed by the highlights of each function's plan.
+func (s DeploySummary) Markdown() string {
+	var b strings.Builder
+	b.WriteString("### glambda deploy\n\n")
+	switch {
+	case s.Err != nil:
+		fmt.Fprintf(&b, "**Failed** after %s, %d of %d functions published.\n\n", s.Duration.Round(time.Millisecond), len(s.Results), max(len(s.Plans), len(s.Results)))
+	case s.Sandbox:
+		fmt.Fprintf(&b, "Sandbox deploy of %d functions in %s, no AWS resources were changed.\n\n", len(s.Results), s.Duration.Round(time.Millisecond))
+	default:
+		fmt.Fprintf(&b, "Deployed %d functions in %s.\n\n", len(s.Results), s.Duration.Round(time.Millisecond))
+	}
+	if len(s.Results) > 0 {
+		b.WriteString("| Function | Version | ARN |\n|---|---|---|\n")
+		for _, r := range s.Results {
+			fmt.Fprintf(&b, "| %s | %s | `%s` |\n", r.FunctionName, r.Version, r.VersionARN)
+		}
+		b.WriteString("\n")
+	}
+	for _, p := range s.Plans {
+		fmt.Fprintf(&b, "<details><summary>%s: %d changes</summary>\n\n", p.Function, len(p.Changes))
+		for _, c := range p.Changes {
+			fmt.Fprintf(&b, "- %s %s `%s` (%s)\n", c.Action, c.ResourceType, c.Resource, c.Operation)
+		}
+		for _, w := range p.Warnings {
+			fmt.Fprintf(&b, "- :warning: %s\n", w)
+		}
+		b.WriteString("\n</details>\n\n")
+	}
+	if s.Err != nil {
+		fmt.Fprintf(&b, "```\n%s\n```\n", s.Err)
+	}
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package glambda_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestDeploySummary_MarkdownShowsWhatFailed(t *testing.T) {
+	t.Parallel()
+	summary := glambda.DeploySummary{
+		Results: []glambda.DeployResult{{
+			FunctionName: "orders",
+			Version:      "7",
+			VersionARN:   "arn:aws:lambda:us-east-1:123456789012:function:orders:7",
+		}},
+		Plans: []glambda.Plan{
+			{Function: "orders", Warnings: []string{"runtime provided.al2 is deprecated"}},
+			{Function: "invoices", Changes: []glambda.Change{{
+				Operation:    "UpdateFunctionCode",
+				ResourceType: "lambda_function",
+				Resource:     "invoices",
+				Action:       "update",
+			}}},
+		},
+		Duration: 12345 * time.Millisecond,
+		Err:      errors.New("invoices: access denied"),
+	}
+	got := summary.Markdown()
+	for _, want := range []string{
+		"**Failed** after 12.345s, 1 of 2 functions published.",
+		"| orders | 7 | `arn:aws:lambda:us-east-1:123456789012:function:orders:7` |",
+		"- :warning: runtime provided.al2 is deprecated",
+		"- update lambda_function `invoices` (UpdateFunctionCode)",
+		"```\ninvoices: access denied\n```",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package glambda
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// TagsKey is the tag that records the keys of the tags given with [WithTags].
+// A later deploy removes any recorded tags that are no longer given, without
+// touching tags that anyone else added to the function or role.
+const TagsKey = "glambda:tags"
+
+const (
+	maxTagKeyLength = 128
+	// maxResourceTags is the number of tags allowed on a lambda function or
+	// an IAM role.
+	maxResourceTags = 50
+	// maxUserTags leaves room on a function for [ManagedTagKey], [TagsKey],
+	// [CommitTagKey], [BuiltTagKey] and at least one module tag.
+	maxUserTags = maxResourceTags - 5
+)
+
+// WithTags is a deploy option that tags the function and, when glambda
+// manages it, the execution role. Tags are applied when the function and
+// role are created and reconciled on every later deploy, so changed values
+// are updated and tags no longer given are removed. Keys starting with
+// "glambda:" or "aws:" are reserved.
+func WithTags(tags map[string]string) DeployOptions {
+	return func(l *Lambda) error {
+		if len(tags) > maxUserTags {
+			return fmt.Errorf("at most %d tags can be given, got %d", maxUserTags, len(tags))
+		}
+		for k, v := range tags {
+			switch {
+			case k == "" || len(k) > maxTagKeyLength:
+				return fmt.Errorf("tag key %q must be between 1 and %d characters", k, maxTagKeyLength)
+			case strings.HasPrefix(k, "glambda:") || strings.HasPrefix(strings.ToLower(k), "aws:"):
+				return fmt.Errorf("tag key %q is reserved", k)
+			case len(v) > maxTagValueLength:
+				return fmt.Errorf("value of tag %q is longer than %d characters", k, maxTagValueLength)
+			}
+		}
+		if len(tagRecord(tags)) > maxTagValueLength {
+			return fmt.Errorf("tag keys are too long to record in the %s tag, use fewer or shorter keys", TagsKey)
+		}
+		l.Tags = maps.Clone(tags)
+		l.ExecutionRole.Tags = maps.Clone(tags)
+		return nil
+	}
+}
+
+// tagRecord is the value of the [TagsKey] tag for the given tags.
+func tagRecord(tags map[string]string) string {
+	return strings.Join(sortedKeys(tags), ",")
+}
+
+// recordedTags returns the keys listed by the [TagsKey] tag among tags.
+func recordedTags(tags map[string]string) []string {
+	if tags[TagsKey] == "" {
+		return nil
+	}
+	return strings.Split(tags[TagsKey], ",")
+}
+
+// withTagRecord adds tags, and the [TagsKey] tag recording them, to a copy
+// of base.
+func withTagRecord(base, tags map[string]string) map[string]string {
+	all := maps.Clone(base)
+	if all == nil {
+		all = map[string]string{}
+	}
+	if len(tags) == 0 {
+		return all
+	}
+	maps.Copy(all, tags)
+	all[TagsKey] = tagRecord(tags)
+	return all
+}
+
+// staleTags are the keys among existing that glambda added in an earlier
+// deploy, and that aren't among the tags it is about to apply.
+func staleTags(existing, tags map[string]string) []string {
+	var stale []string
+	for k := range existing {
+		if _, ok := tags[k]; !ok && (strings.HasPrefix(k, ModuleTagPrefix) || k == TagsKey || k == CommitTagKey) {
+			stale = append(stale, k)
+		}
+	}
+	for _, k := range recordedTags(existing) {
+		_, present := existing[k]
+		if _, ok := tags[k]; present && !ok {
+			stale = append(stale, k)
+		}
+	}
+	slices.Sort(stale)
+	return slices.Compact(stale)
+}
+
+// reconcileTags applies tags to an existing function, and removes any tags
+// left over from a previous deploy, such as module tags from a longer module
+// list or tags no longer given to [WithTags].
+func reconcileTags(ctx context.Context, c LambdaClient, functionARN string, tags map[string]string) error {
+	existing, err := c.ListTags(ctx, &lambda.ListTagsInput{
+		Resource: aws.String(functionARN),
+	})
+	if err != nil {
+		return err
+	}
+	stale := staleTags(existing.Tags, tags)
+	if len(stale) > 0 {
+		_, err = c.UntagResource(ctx, &lambda.UntagResourceInput{
+			Resource: aws.String(functionARN),
+			TagKeys:  stale,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	_, err = c.TagResource(ctx, &lambda.TagResourceInput{
+		Resource: aws.String(functionARN),
+		Tags:     tags,
+	})
+	return err
+}
+
+// iamTags converts tags to the form the IAM API takes, in key order.
+func iamTags(tags map[string]string) []iTypes.Tag {
+	var converted []iTypes.Tag
+	for _, k := range sortedKeys(tags) {
+		converted = append(converted, iTypes.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+	return converted
+}
+
+// roleTagChanges works out the calls that bring an existing role's tags in
+// line with the tags given, returning nil for either call that isn't needed.
+func roleTagChanges(roleName string, existing, tags map[string]string) (*iam.TagRoleInput, *iam.UntagRoleInput) {
+	want := withTagRecord(nil, tags)
+	var tag *iam.TagRoleInput
+	for k, v := range want {
+		if current, ok := existing[k]; !ok || current != v {
+			tag = &iam.TagRoleInput{RoleName: aws.String(roleName), Tags: iamTags(want)}
+			break
+		}
+	}
+	var untag *iam.UntagRoleInput
+	if stale := staleTags(existing, want); len(stale) > 0 {
+		untag = &iam.UntagRoleInput{RoleName: aws.String(roleName), TagKeys: stale}
+	}
+	return tag, untag
+}
@@ -0,0 +1,142 @@
+package glambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+func TestWithTags_RejectsReservedKeys(t *testing.T) {
+	t.Parallel()
+	for _, key := range []string{"glambda:managed", "aws:cloudformation:stack-name", "AWS:owner", ""} {
+		_, err := glambda.NewLambda("tagged", "", glambdatest.Sandbox(), glambda.WithTags(map[string]string{key: "x"}))
+		if err == nil {
+			t.Errorf("expected error for tag key %q", key)
+		}
+	}
+}
+
+func TestNewLambdaCreateAction_TagsFunctionWithUserTagsAndRecord(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("tagged", "", glambdatest.Sandbox(), glambda.WithTags(map[string]string{"team": "payments", "env": "prod"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, nil)
+	want := map[string]string{
+		"team":            "payments",
+		"env":             "prod",
+		glambda.TagsKey:   "env,team",
+		"glambda:managed": "true",
+	}
+	if !cmp.Equal(want, action.CreateLambdaCommand.Tags) {
+		t.Error(cmp.Diff(want, action.CreateLambdaCommand.Tags))
+	}
+}
+
+func TestUpdateLambdaActionDo_RemovesTagsNoLongerGivenAndKeepsOthers(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	client := glambdatest.DummyLambdaClient{
+		Recorder:   recorder,
+		FuncExists: true,
+		Tags: map[string]string{
+			"team":          "payments",
+			"owner":         "ada",
+			"cost-center":   "1234",
+			glambda.TagsKey: "owner,team",
+		},
+	}
+	l, err := glambda.NewLambda("tagged", "", glambdatest.Sandbox(), glambda.WithTags(map[string]string{"team": "orders"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := glambda.NewLambdaUpdateAction(client, *l, nil)
+	err = action.Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	untag := recorder.Calls("UntagResource")
+	if len(untag) != 1 {
+		t.Fatalf("expected 1 UntagResource call, got %v", recorder.Operations())
+	}
+	if keys := untag[0].Input.(*lambda.UntagResourceInput).TagKeys; !cmp.Equal([]string{"owner"}, keys) {
+		t.Errorf("expected only the dropped tag to be removed, got %v", keys)
+	}
+	got := recorder.Calls("TagResource")[0].Input.(*lambda.TagResourceInput).Tags
+	if got["team"] != "orders" || got[glambda.TagsKey] != "team" {
+		t.Errorf("expected updated tags and record, got %v", got)
+	}
+}
+
+func TestPrepareRoleAction_ReconcilesTagsOfManagedRole(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyIAMClient{
+		RoleExists: true,
+		RoleName:   "tagged-role",
+		RoleTags: map[string]string{
+			glambda.ManagedTagKey: "true",
+			glambda.TagsKey:       "owner,team",
+			"owner":               "ada",
+			"team":                "payments",
+		},
+	}
+	role := glambda.ExecutionRole{RoleName: "tagged-role", Tags: map[string]string{"team": "orders"}}
+	action, err := glambda.PrepareRoleAction(context.Background(), role, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := action.(glambda.RoleCreateOrUpdate)
+	if got.UntagRole == nil || !cmp.Equal([]string{"owner"}, got.UntagRole.TagKeys) {
+		t.Errorf("expected dropped tag to be removed, got %+v", got.UntagRole)
+	}
+	if got.TagRole == nil {
+		t.Fatal("expected changed tag to be applied")
+	}
+	tags := map[string]string{}
+	for _, tag := range got.TagRole.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	want := map[string]string{"team": "orders", glambda.TagsKey: "team"}
+	if !cmp.Equal(want, tags) {
+		t.Error(cmp.Diff(want, tags))
+	}
+}
+
+func TestPrepareRoleAction_LeavesTagsOfUnmanagedRoleAlone(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyIAMClient{RoleExists: true, RoleName: "shared-role"}
+	role := glambda.ExecutionRole{RoleName: "shared-role", Tags: map[string]string{"team": "orders"}}
+	action, err := glambda.PrepareRoleAction(context.Background(), role, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := action.(glambda.RoleCreateOrUpdate)
+	if got.TagRole != nil || got.UntagRole != nil {
+		t.Errorf("expected no tag changes on a role glambda didn't create, got %+v %+v", got.TagRole, got.UntagRole)
+	}
+}
+
+func TestPrepareRoleAction_TagsNewRole(t *testing.T) {
+	t.Parallel()
+	client := glambdatest.DummyIAMClient{}
+	role := glambda.ExecutionRole{RoleName: "new-role", Tags: map[string]string{"team": "orders"}}
+	action, err := glambda.PrepareRoleAction(context.Background(), role, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := action.(glambda.RoleCreateOrUpdate).CreateRole
+	var keys []string
+	for _, tag := range create.Tags {
+		keys = append(keys, aws.ToString(tag.Key))
+	}
+	want := []string{glambda.ManagedTagKey, glambda.TagsKey, "team"}
+	if !cmp.Equal(want, keys) {
+		t.Error(cmp.Diff(want, keys))
+	}
+}
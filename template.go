@@ -0,0 +1,70 @@
+package glambda
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// RenderHandlerTemplate renders a handler source file as a text/template,
+// which lets small lambdas bake stage specific constants into the binary
+// instead of reading them from the environment at runtime. For example:
+//
+//	const tableName = "{{ .TableName }}"
+//
+// In strict mode referencing a key that isn't in data is an error, otherwise
+// it renders as an empty string.
+func RenderHandlerTemplate(name string, source []byte, data map[string]string, strict bool) ([]byte, error) {
+	missingKey := "missingkey=zero"
+	if strict {
+		missingKey = "missingkey=error"
+	}
+	tmpl, err := template.New(name).Option(missingKey).Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template in %s: %w", name, err)
+	}
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering template in %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderOverlay renders each file into workDir and writes a go build -overlay
+// file that substitutes the rendered files for the originals. The user's
+// source files are never modified.
+func renderOverlay(files []string, workDir string, data map[string]string, strict bool) (string, error) {
+	overlay := struct {
+		Replace map[string]string
+	}{Replace: map[string]string{}}
+	for i, f := range files {
+		source, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		rendered, err := RenderHandlerTemplate(filepath.Base(f), source, data, strict)
+		if err != nil {
+			return "", err
+		}
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return "", err
+		}
+		out := filepath.Join(workDir, fmt.Sprintf("rendered_%d_%s", i, filepath.Base(f)))
+		err = os.WriteFile(out, rendered, 0644)
+		if err != nil {
+			return "", err
+		}
+		overlay.Replace[abs] = out
+	}
+	manifest, err := json.Marshal(overlay)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(workDir, "overlay.json")
+	return path, os.WriteFile(path, manifest, 0644)
+}
@@ -0,0 +1,90 @@
+package glambda_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestRenderHandlerTemplate_RendersData(t *testing.T) {
+	t.Parallel()
+	got, err := glambda.RenderHandlerTemplate("main.go", []byte(`const stage = "{{ .Stage }}"`), map[string]string{"Stage": "prod"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `const stage = "prod"`
+	if string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderHandlerTemplate_StrictModeRejectsMissingKeys(t *testing.T) {
+	t.Parallel()
+	source := []byte(`const stage = "{{ .Stage }}"`)
+	_, err := glambda.RenderHandlerTemplate("main.go", source, map[string]string{}, true)
+	if err == nil {
+		t.Error("expected error for missing key in strict mode, got nil")
+	}
+	got, err := glambda.RenderHandlerTemplate("main.go", source, map[string]string{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `const stage = ""` {
+		t.Errorf("expected missing key to render empty, got %q", got)
+	}
+}
+
+func TestPackageWith_BuildsRenderedHandlerWithoutChangingSource(t *testing.T) {
+	t.Parallel()
+	handler := "testdata/template_handler/main.go"
+	before, err := os.ReadFile(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := glambda.PackageWith(handler, glambda.BuildOptions{
+		TemplateData:    map[string]string{"Stage": "glambda-test-stage"},
+		StrictTemplates: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zipReader.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	binary, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(binary, []byte("glambda-test-stage")) {
+		t.Error("expected rendered stage to be compiled into the binary")
+	}
+	after, err := os.ReadFile(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("expected handler source to be left unchanged")
+	}
+}
+
+func TestPackageWith_StrictTemplatesFailTheBuild(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.PackageWith("testdata/template_handler/main.go", glambda.BuildOptions{
+		TemplateData:    map[string]string{},
+		StrictTemplates: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "Stage") {
+		t.Errorf("expected error naming the missing key, got %v", err)
+	}
+}
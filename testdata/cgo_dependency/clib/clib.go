@@ -0,0 +1,9 @@
+// Package clib only builds with cgo, like a binding to a C library.
+package clib
+
+// #include <stdlib.h>
+import "C"
+
+func Free() {
+	C.free(nil)
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mr-joshcrane/glambda/testdata/cgo_dependency/portable"
+)
+
+func handler() {
+	portable.Free()
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,5 @@
+//go:build !cgo
+
+package portable
+
+func Free() {}
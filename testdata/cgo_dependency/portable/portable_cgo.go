@@ -0,0 +1,11 @@
+//go:build cgo
+
+// Package portable uses cgo when it can, and pure Go otherwise.
+package portable
+
+// #include <stdlib.h>
+import "C"
+
+func Free() {
+	C.free(nil)
+}
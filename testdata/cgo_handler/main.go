@@ -0,0 +1,20 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func handler() error {
+	db, err := sql.Open("sqlite3", "/tmp/orders.db")
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func main() {
+	lambda.Start(handler)
+}
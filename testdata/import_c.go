@@ -0,0 +1,14 @@
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import "github.com/aws/aws-lambda-go/lambda"
+
+func handler() {
+	C.free(nil)
+}
+
+func main() {
+	lambda.Start(handler)
+}
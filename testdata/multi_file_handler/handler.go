@@ -0,0 +1,15 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func handler(ctx context.Context, s any) (any, error) {
+	fmt.Println(greeting())
+	return greeting(), nil
+}
+
+func greeting() string {
+	return "Hello from a multi-file handler!"
+}
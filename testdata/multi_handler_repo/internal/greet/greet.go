@@ -0,0 +1,5 @@
+package greet
+
+func Hello() string {
+	return "Hello, World!"
+}
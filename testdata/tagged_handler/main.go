@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+var version = "dev"
+
+func main() {
+	lambda.StartHandlerFunc(handler)
+}
+
+func handler(ctx context.Context, s any) (any, error) {
+	return greeting + " from " + version, nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+const stage = "{{ .Stage }}"
+
+func handler(ctx context.Context) (string, error) {
+	return fmt.Sprintf("Hello from %s", stage), nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
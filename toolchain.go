@@ -0,0 +1,75 @@
+package glambda
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// GoToolchainError is returned when a handler can't be built because the go
+// command isn't on PATH, as is common in minimal CI images. Docker, when
+// set, is a command that builds the handler in the official Go image
+// instead, as Docker was found on PATH; the bootstrap it writes can be
+// deployed with [WithBootstrap], or --bootstrap on the command line.
+type GoToolchainError struct {
+	Docker string
+	Err    error
+}
+
+func (e *GoToolchainError) Error() string {
+	msg := fmt.Sprintf("the go command is needed to build lambda handlers, but %v. Install Go from https://go.dev/dl/, or add its bin directory to PATH", e.Err)
+	if e.Docker == "" {
+		return msg + ", or build the handler elsewhere and deploy the executable with --bootstrap"
+	}
+	return msg + ". Alternatively, build the handler with Docker and deploy it with --bootstrap:\n  " + e.Docker
+}
+
+func (e *GoToolchainError) Unwrap() error {
+	return e.Err
+}
+
+// checkGoToolchain fails with a [GoToolchainError] if go isn't on PATH,
+// before a build gets as far as running it.
+func checkGoToolchain(src HandlerSource, path, goarch string, flags []string) error {
+	_, err := exec.LookPath("go")
+	if err == nil {
+		return nil
+	}
+	return &GoToolchainError{Docker: dockerBuild(src, path, goarch, flags), Err: err}
+}
+
+// dockerBuild is the docker command that builds the handler the way
+// buildBinary would, leaving the bootstrap next to it, or "" if docker isn't
+// on PATH or the handler isn't part of a module that can be mounted.
+func dockerBuild(src HandlerSource, path, goarch string, flags []string) string {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return ""
+	}
+	root, ok := FindModuleRoot(src.Dir)
+	if !ok {
+		return ""
+	}
+	dir, err := filepath.Abs(src.Dir)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return ""
+	}
+	target := "./" + filepath.Base(path)
+	if src.IsPackage {
+		target = "."
+	}
+	args := []string{"docker", "run", "--rm", "-v", root + ":/src", "-w", filepath.ToSlash(filepath.Join("/src", rel))}
+	for _, v := range buildVars(nil, goarch, "") {
+		args = append(args, "-e", v)
+	}
+	build, err := goBuildArgs("bootstrap", flags)
+	if err != nil {
+		return ""
+	}
+	args = append(args, "golang", "go")
+	args = append(args, build...)
+	return shellQuote(append(args, target)...)
+}
@@ -0,0 +1,55 @@
+package glambda_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestPackage_WithoutGoOnPATHReturnsGoToolchainError(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := glambda.Package("testdata/correct_test_handler/main.go")
+	var toolchainErr *glambda.GoToolchainError
+	if !errors.As(err, &toolchainErr) {
+		t.Fatalf("want GoToolchainError, got %v", err)
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("want error wrapping exec.ErrNotFound, got %v", err)
+	}
+	if toolchainErr.Docker != "" {
+		t.Errorf("want no docker command without docker on PATH, got %q", toolchainErr.Docker)
+	}
+	if !strings.Contains(err.Error(), "https://go.dev/dl/") {
+		t.Errorf("want installation guidance, got %q", err)
+	}
+}
+
+func TestPackage_WithoutGoOffersDockerBuildWhenDockerIsOnPATH(t *testing.T) {
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, "docker"), []byte("#!/bin/sh\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	_, buildErr := glambda.PackageFor("testdata/correct_test_handler/main.go", "x86_64")
+	var toolchainErr *glambda.GoToolchainError
+	if !errors.As(buildErr, &toolchainErr) {
+		t.Fatalf("want GoToolchainError, got %v", buildErr)
+	}
+	root, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "docker run --rm -v " + root + ":/src -w /src/testdata/correct_test_handler -e GOOS=linux -e GOARCH=amd64 -e CGO_ENABLED=0 -e GOFLAGS=-mod=readonly golang go build -trimpath -tags lambda.norpc -o bootstrap ./main.go"
+	if toolchainErr.Docker != want {
+		t.Errorf("want docker command\n  %s\ngot\n  %s", want, toolchainErr.Docker)
+	}
+	if !strings.Contains(buildErr.Error(), want) {
+		t.Errorf("want the docker command in the error, got %q", buildErr)
+	}
+}
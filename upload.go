@@ -0,0 +1,95 @@
+package glambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// UploadRetryLimit is how many times an upload of the deployment package is
+// retried after a network failure.
+const UploadRetryLimit = 5
+
+// UploadBackoff waits before the given retry of a failed upload, doubling
+// the wait on each attempt up to 30 seconds. It returns early with the
+// context's error if ctx is done first.
+var UploadBackoff = func(ctx context.Context, attempt int) error {
+	wait := time.Second << attempt
+	if wait > 30*time.Second {
+		wait = 30 * time.Second
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsTransportError reports whether err is a failure to send a request or
+// receive its response, such as a dropped connection, rather than an error
+// returned by AWS. Large uploads on flaky networks fail this way.
+func IsTransportError(err error) bool {
+	var send *smithyhttp.RequestSendError
+	var netErr net.Error
+	return errors.As(err, &send) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// retryUpload calls upload, retrying it with backoff for as long as it fails
+// with a transport error. An upload that failed in transit may still have
+// reached AWS, so when a retry conflicts with the resource, landed is asked
+// whether an earlier attempt made it through, and only if it did is the upload
+// treated as successful. A nil landed never accepts a conflict. Retries stop
+// once ctx is done.
+func retryUpload(ctx context.Context, upload func() error, landed func() (bool, error)) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = upload()
+		var conflict *types.ResourceConflictException
+		if attempt > 0 && landed != nil && errors.As(err, &conflict) {
+			ok, landedErr := landed()
+			if landedErr != nil {
+				return fmt.Errorf("%w, and checking whether an earlier attempt succeeded failed, %w", err, landedErr)
+			}
+			if ok {
+				return nil
+			}
+			return err
+		}
+		if !IsTransportError(err) || attempt == UploadRetryLimit || ctx.Err() != nil {
+			return err
+		}
+		if UploadBackoff(ctx, attempt) != nil {
+			return err
+		}
+	}
+}
+
+// codeLanded reports whether the function's deployed code is the package
+// with the given SHA-256, as it is when an upload that failed in transit
+// reached AWS anyway, along with the function's ARN.
+func codeLanded(ctx context.Context, client LambdaClient, name, sha string) (string, bool, error) {
+	out, err := client.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(name)})
+	if err != nil {
+		return "", false, err
+	}
+	if out.Configuration == nil {
+		return "", false, nil
+	}
+	return aws.ToString(out.Configuration.FunctionArn), aws.ToString(out.Configuration.CodeSha256) == sha, nil
+}
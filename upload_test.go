@@ -0,0 +1,139 @@
+package glambda_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+// uploadBackoff is the real backoff, which the tests otherwise replace so as
+// not to wait.
+var uploadBackoff = glambda.UploadBackoff
+
+func TestIsTransportError(t *testing.T) {
+	t.Parallel()
+	if !glambda.IsTransportError(&smithyhttp.RequestSendError{Err: io.ErrUnexpectedEOF}) {
+		t.Error("expected request send error to be a transport error")
+	}
+	if glambda.IsTransportError(&types.InvalidParameterValueException{}) {
+		t.Error("expected AWS API error not to be a transport error")
+	}
+}
+
+func TestDeploy_RetriesUploadAfterNetworkFailure(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", &smithyhttp.RequestSendError{Err: io.ErrUnexpectedEOF}, 2)
+	err := glambda.Deploy("flaky", "testdata/correct_test_handler/main.go", glambdatest.SandboxWithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(recorder.Calls("CreateFunction")); got != 3 {
+		t.Errorf("expected 2 retries of the upload, got %d calls", got)
+	}
+}
+
+func TestLambdaCreateActionDo_TreatsConflictOnRetryAsSuccessWhenTheCodeLanded(t *testing.T) {
+	t.Parallel()
+	pkg := []byte("some valid zip data")
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", &smithyhttp.RequestSendError{Err: io.ErrUnexpectedEOF}, 1)
+	recorder.FailNext("CreateFunction", &types.ResourceConflictException{}, 1)
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{CodeSha256: aws.String(glambda.CodeSHA256(pkg))},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	err := glambda.NewLambdaCreateAction(client, glambda.Lambda{Name: "flaky"}, pkg).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLambdaCreateActionDo_ReportsConflictOnRetryWhenOtherCodeIsDeployed(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("CreateFunction", &smithyhttp.RequestSendError{Err: io.ErrUnexpectedEOF}, 1)
+	recorder.FailNext("CreateFunction", &types.ResourceConflictException{}, 1)
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{CodeSha256: aws.String(glambda.CodeSHA256([]byte("someone else's code")))},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder}
+	err := glambda.NewLambdaCreateAction(client, glambda.Lambda{Name: "flaky"}, []byte("some valid zip data")).Do(context.Background())
+	var conflict *types.ResourceConflictException
+	if !errors.As(err, &conflict) {
+		t.Fatalf("want the conflict reported, got %v", err)
+	}
+}
+
+func TestLambdaUpdateActionDo_TagsTheFunctionAfterAConflictedRetry(t *testing.T) {
+	t.Parallel()
+	pkg := []byte("some valid zip data")
+	arn := "arn:aws:lambda:us-east-1:123456789012:function:flaky"
+	recorder := glambdatest.NewRecorder()
+	recorder.FailNext("UpdateFunctionCode", &smithyhttp.RequestSendError{Err: io.ErrUnexpectedEOF}, 1)
+	recorder.FailNext("UpdateFunctionCode", &types.ResourceConflictException{}, 1)
+	recorder.Respond("GetFunction", &lambda.GetFunctionOutput{
+		Configuration: &types.FunctionConfiguration{FunctionArn: aws.String(arn), CodeSha256: aws.String(glambda.CodeSHA256(pkg))},
+	})
+	client := glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}
+	err := glambda.NewLambdaUpdateAction(client, glambda.Lambda{Name: "flaky"}, pkg).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := recorder.Calls("ListTags")
+	if len(calls) != 1 || aws.ToString(calls[0].Input.(*lambda.ListTagsInput).Resource) != arn {
+		t.Errorf("want the function's tags reconciled by its ARN, got %v", calls)
+	}
+}
+
+func TestUploadBackoff_StopsWaitingWhenTheContextIsDone(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	err := uploadBackoff(ctx, 4)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want the context's error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("want the wait cut short, waited %s", time.Since(start))
+	}
+}
+
+func TestDeploy_DoesNotRetryAWSErrors(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	denied := errors.New("AccessDeniedException")
+	recorder.FailNext("UpdateFunctionCode", denied, 1)
+	err := glambda.Deploy("denied", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true}),
+	)
+	if !errors.Is(err, denied) {
+		t.Fatalf("expected access denied, got %v", err)
+	}
+	want := []string{"UpdateFunctionCode"}
+	if got := recorder.Operations(); !cmp.Equal(want, filter(got, "UpdateFunctionCode")) {
+		t.Error(cmp.Diff(want, filter(got, "UpdateFunctionCode")))
+	}
+}
+
+func filter(ops []string, op string) []string {
+	var matched []string
+	for _, o := range ops {
+		if o == op {
+			matched = append(matched, o)
+		}
+	}
+	return matched
+}
@@ -6,37 +6,78 @@ import (
 	"go/parser"
 	"go/token"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-// Validate takes a path to a Go source file.
-// A valid Go source file for the purposes of AWS Lambda will...
+// Validate takes a path to a Go source file, or a directory containing a main
+// package. See [ResolveHandler] for how the files making up the handler are found.
+// A valid handler for the purposes of AWS Lambda will...
 //
 // 1. Contain a main function.
 //
 // 2. Call one of the lambda Start... functions as seen here
 // https://pkg.go.dev/github.com/aws/aws-lambda-go/lambda#Start
+//
+// 3. Not import C, or any of the popular packages known to need cgo, see
+// [CgoPackages]. Handlers are cross-compiled with cgo disabled, and these
+// would otherwise fail late with obscure linker or runtime errors.
 func Validate(path string) error {
-	data, err := os.ReadFile(path)
+	return validate(path, false)
+}
+
+// validate is [Validate], leaving out the cgo check for a [WithCGO] build.
+func validate(path string, cgo bool) error {
+	src, err := ResolveHandler(path)
 	if err != nil {
-		return fmt.Errorf("failure in reading %s: %w", path, err)
+		return err
 	}
+	var mainFound, callsStart bool
 	fileSet := token.NewFileSet()
-	node, err := parser.ParseFile(fileSet, "main.go", string(data), parser.ParseComments)
-	if err != nil {
-		return fmt.Errorf("failure in parsing %s: %w", path, err)
+	for _, file := range src.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failure in reading %s: %w", file, err)
+		}
+		node, err := parser.ParseFile(fileSet, filepath.Base(file), string(data), parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("failure in parsing %s: %w", file, err)
+		}
+		if !cgo {
+			err = checkCgoImports(node, file)
+			if err != nil {
+				return err
+			}
+		}
+		mainFound = mainFound || containsMain(node)
+		callsStart = callsStart || containsLambdaStartFunctionCall(node)
 	}
-	mainFound := containsMain(node)
 	if !mainFound {
 		return fmt.Errorf("main function not found in packaged function")
 	}
-	callsStart := containsLambdaStartFunctionCall(node)
 	if !callsStart {
 		return fmt.Errorf("main function does not call lambda.Start(handler)")
 	}
 	return nil
 }
 
+// CheckHandler checks the handler at path with [Validate], unless it is a
+// template, and then runs the full build [PackageWith] would, throwing the
+// package away. Compiler errors point at the handler's source files as path
+// names them, rather than at the directories the build ran in.
+func CheckHandler(path string, opts BuildOptions) error {
+	if opts.TemplateData == nil {
+		err := validate(path, opts.CGO)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := PackageWith(path, opts)
+	return err
+}
+
 func containsMain(node ast.Node) bool {
 	var found bool
 	ast.Inspect(node, func(n ast.Node) bool {
@@ -70,3 +111,111 @@ func containsLambdaStartFunctionCall(node ast.Node) bool {
 	})
 	return found
 }
+
+// CgoPackages are the import paths of popular packages that only work with
+// cgo, mapped to pure Go alternatives. Subpackages of each are included.
+var CgoPackages = map[string]string{
+	"github.com/mattn/go-sqlite3":                "modernc.org/sqlite",
+	"github.com/confluentinc/confluent-kafka-go": "github.com/twmb/franz-go",
+	"github.com/linxGnu/grocksdb":                "github.com/cockroachdb/pebble",
+	"github.com/tecbot/gorocksdb":                "github.com/cockroachdb/pebble",
+	"github.com/h2non/bimg":                      "github.com/disintegration/imaging",
+	"github.com/davidbyttow/govips":              "github.com/disintegration/imaging",
+	"gopkg.in/gographics/imagick.v3":             "github.com/disintegration/imaging",
+	"github.com/google/gopacket/pcap":            "",
+	"github.com/godror/godror":                   "github.com/sijms/go-ora/v2",
+	"github.com/ibmdb/go_ibm_db":                 "",
+	"github.com/go-gl/glfw":                      "",
+}
+
+// checkCgo parses only the imports of files, so that a build can fail on cgo
+// before handing over to the compiler.
+func checkCgo(files []string) error {
+	fileSet := token.NewFileSet()
+	for _, file := range files {
+		node, err := parser.ParseFile(fileSet, file, nil, parser.ImportsOnly)
+		if err != nil {
+			return fmt.Errorf("failure in parsing %s: %w", file, err)
+		}
+		err = checkCgoImports(node, filepath.Base(file))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCgoImports finds an import of C, or of a package in [CgoPackages], and
+// explains how to get past it.
+func checkCgoImports(file *ast.File, filename string) error {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if path == "C" {
+			return fmt.Errorf("%s uses cgo (import \"C\"), which can't be cross-compiled for Lambda without a C compiler; build with --cgo, or in a Docker image matching the Lambda runtime instead", filename)
+		}
+		if alternative, ok := knownCgoPackage(path); ok {
+			return fmt.Errorf("%s imports %s, which needs cgo and can't be cross-compiled for Lambda without a C compiler; %s", filename, path, cgoAdvice(alternative))
+		}
+	}
+	return nil
+}
+
+// knownCgoPackage reports whether path is, or is a subpackage of, one of the
+// [CgoPackages], and its pure Go alternative.
+func knownCgoPackage(path string) (string, bool) {
+	for pkg, alternative := range CgoPackages {
+		if path == pkg || strings.HasPrefix(path, pkg+"/") {
+			return alternative, true
+		}
+	}
+	return "", false
+}
+
+func cgoAdvice(alternative string) string {
+	advice := "build with --cgo, or in a Docker image matching the Lambda runtime instead"
+	if alternative != "" {
+		advice = fmt.Sprintf("use the pure Go %s instead, or %s", alternative, advice)
+	}
+	return advice
+}
+
+// checkCgoDeps finds the packages the handler target depends on, directly or
+// not, that need cgo, which [checkCgo] can't see from the handler's own
+// imports, for the handler at path. A package with cgo files needs it unless it has pure Go files to
+// fall back on when cgo is off, though those of the [CgoPackages] are only
+// stubs. go list runs in dir with env, as go build would; if it fails, go
+// build is left to report why.
+func checkCgoDeps(path, dir, target string, env []string) error {
+	list := func(cgo string, args ...string) ([]string, error) {
+		cmd := exec.Command("go", append([]string{"list"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(env, "CGO_ENABLED="+cgo)
+		out, err := cmd.Output()
+		return strings.Fields(string(out)), err
+	}
+	withCgo, err := list("1", "-deps", "-f", "{{if and (not .Standard) .CgoFiles}}{{.ImportPath}}{{end}}", target)
+	if err != nil || len(withCgo) == 0 {
+		return nil
+	}
+	// Fields of the packages that still build with cgo off, with their
+	// number of Go files.
+	fallbacks, err := list("0", append([]string{"-e", "-f", "{{.ImportPath}} {{if .Error}}0{{else}}{{len .GoFiles}}{{end}}"}, withCgo...)...)
+	if err != nil {
+		return nil
+	}
+	portable := map[string]bool{}
+	for i := 0; i+1 < len(fallbacks); i += 2 {
+		portable[fallbacks[i]] = fallbacks[i+1] != "0"
+	}
+	for _, pkg := range withCgo {
+		alternative, known := knownCgoPackage(pkg)
+		if portable[pkg] && !known {
+			continue
+		}
+		return fmt.Errorf("%s depends on %s, which needs cgo and can't be cross-compiled for Lambda without a C compiler; %s", path, pkg, cgoAdvice(alternative))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package glambda
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// VPCAccessPolicyARN is the AWS managed policy that lets a function create
+// the network interfaces it needs to run in a VPC. It is attached to the
+// execution role of every function given [WithVPCConfig].
+const VPCAccessPolicyARN = "arn:aws:iam::aws:policy/service-role/AWSLambdaVPCAccessExecutionRole"
+
+const (
+	maxVPCSubnets        = 16
+	maxVPCSecurityGroups = 5
+)
+
+// VPCConfig is the VPC that a function is connected to, see [WithVPCConfig].
+type VPCConfig struct {
+	SubnetIDs        []string `yaml:"subnet_ids"`
+	SecurityGroupIDs []string `yaml:"security_group_ids"`
+}
+
+// WithVPCConfig is a deploy option that connects the function to a VPC,
+// through the given subnets and security groups, so that it can reach
+// resources such as databases that aren't on the internet. At least one of
+// each is needed. The [VPCAccessPolicyARN] policy is attached to the
+// execution role.
+func WithVPCConfig(subnetIDs, securityGroupIDs []string) DeployOptions {
+	return func(l *Lambda) error {
+		err := checkVPCIDs("subnet", "subnet-", subnetIDs, maxVPCSubnets)
+		if err != nil {
+			return err
+		}
+		err = checkVPCIDs("security group", "sg-", securityGroupIDs, maxVPCSecurityGroups)
+		if err != nil {
+			return err
+		}
+		l.VPC = &VPCConfig{
+			SubnetIDs:        slices.Clone(subnetIDs),
+			SecurityGroupIDs: slices.Clone(securityGroupIDs),
+		}
+		l.ExecutionRole.VPCAccess = true
+		return nil
+	}
+}
+
+func checkVPCIDs(kind, prefix string, ids []string, limit int) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("a function in a VPC needs at least one %s", kind)
+	}
+	if len(ids) > limit {
+		return fmt.Errorf("at most %d %ss can be given, got %d", limit, kind, len(ids))
+	}
+	for _, id := range ids {
+		if !strings.HasPrefix(id, prefix) || len(id) == len(prefix) {
+			return fmt.Errorf("invalid %s ID %q, expected %s<id>", kind, id, prefix)
+		}
+	}
+	return nil
+}
+
+// vpcConfig is the VPC configuration to send to Lambda, or nil if the
+// function isn't connected to a VPC.
+func vpcConfig(l Lambda) *types.VpcConfig {
+	if l.VPC == nil {
+		return nil
+	}
+	return &types.VpcConfig{
+		SubnetIds:        l.VPC.SubnetIDs,
+		SecurityGroupIds: l.VPC.SecurityGroupIDs,
+	}
+}
+
+// describeVPC summarises a VPC configuration for a plan.
+func describeVPC(subnets, securityGroups []string) string {
+	if len(subnets) == 0 && len(securityGroups) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("subnets %s, security groups %s", strings.Join(subnets, ", "), strings.Join(securityGroups, ", "))
+}
+
+// sameIDs reports whether two lists hold the same IDs, in any order.
+func sameIDs(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,144 @@
+package glambda_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/mr-joshcrane/glambda"
+	"github.com/mr-joshcrane/glambda/glambdatest"
+)
+
+var (
+	subnets        = []string{"subnet-0a1b2c", "subnet-3d4e5f"}
+	securityGroups = []string{"sg-0123abcd"}
+)
+
+func TestWithVPCConfig_RejectsMissingOrMalformedIDs(t *testing.T) {
+	t.Parallel()
+	var tooManySubnets []string
+	for i := range 17 {
+		tooManySubnets = append(tooManySubnets, fmt.Sprintf("subnet-%d", i))
+	}
+	cases := map[string][2][]string{
+		"no subnets":          {nil, securityGroups},
+		"no security groups":  {subnets, nil},
+		"bare subnet prefix":  {{"subnet-"}, securityGroups},
+		"security group ID":   {{"sg-0123abcd"}, securityGroups},
+		"subnet ID as sg":     {subnets, {"subnet-0a1b2c"}},
+		"too many subnets":    {tooManySubnets, securityGroups},
+		"too many sec groups": {subnets, {"sg-1", "sg-2", "sg-3", "sg-4", "sg-5", "sg-6"}},
+	}
+	for name, ids := range cases {
+		_, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithVPCConfig(ids[0], ids[1]))
+		if err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestWithVPCConfig_ConnectsTheFunctionOnCreateAndUpdate(t *testing.T) {
+	t.Parallel()
+	l, err := glambda.NewLambda("fn", "", glambdatest.Sandbox(), glambda.WithVPCConfig(subnets, securityGroups))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &types.VpcConfig{SubnetIds: subnets, SecurityGroupIds: securityGroups}
+	create := glambda.NewLambdaCreateAction(glambdatest.DummyLambdaClient{}, *l, []byte("some valid zip data")).CreateLambdaCommand
+	ignore := cmpopts.IgnoreUnexported(types.VpcConfig{})
+	if !cmp.Equal(want, create.VpcConfig, ignore) {
+		t.Error(cmp.Diff(want, create.VpcConfig, ignore))
+	}
+	update := glambda.UpdateConfigurationCommand(*l)
+	if update == nil || !cmp.Equal(want, update.VpcConfig, ignore) {
+		t.Fatalf("expected VPC config on update, got %+v", update)
+	}
+}
+
+func TestWithVPCConfig_AttachesTheVPCAccessPolicyWhateverTheOptionOrder(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	l, err := glambda.NewLambda("fn", "",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithVPCConfig(subnets, securityGroups),
+		glambda.WithManagedPolicies("AmazonS3ReadOnlyAccess"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	action, err := glambda.PrepareRoleAction(context.Background(), l.ExecutionRole, glambdatest.DummyIAMClient{Recorder: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attached []string
+	for _, p := range action.(glambda.RoleCreateOrUpdate).ManagedPolicies {
+		attached = append(attached, aws.ToString(p.PolicyArn))
+	}
+	want := []string{
+		"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
+		"arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess",
+		glambda.VPCAccessPolicyARN,
+	}
+	if !cmp.Equal(want, attached) {
+		t.Error(cmp.Diff(want, attached))
+	}
+}
+
+func TestPlan_ShowsAChangedVPC(t *testing.T) {
+	t.Parallel()
+	recorder := glambdatest.NewRecorder()
+	recorder.Respond("GetFunctionConfiguration", &lambda.GetFunctionConfigurationOutput{
+		VpcConfig:        &types.VpcConfigResponse{SubnetIds: []string{"subnet-old"}, SecurityGroupIds: securityGroups},
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	})
+	l, err := glambda.NewLambda("fn", "testdata/correct_test_handler/main.go",
+		glambdatest.SandboxWithRecorder(recorder),
+		glambda.WithLambdaClient(glambdatest.DummyLambdaClient{Recorder: recorder, FuncExists: true, ConsistantAfterXRetries: new(int)}),
+		glambda.WithVPCConfig(subnets, securityGroups),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := l.Plan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "vpc: subnets subnet-old, security groups sg-0123abcd -> subnets subnet-0a1b2c, subnet-3d4e5f, security groups sg-0123abcd"
+	if !strings.Contains(plan.String(), want) {
+		t.Errorf("expected plan to contain %q, got:\n%s", want, plan.String())
+	}
+}
+
+func TestFunctionConfig_OptionsConnectTheFunctionToAVPC(t *testing.T) {
+	t.Parallel()
+	cfg, err := glambda.ParseConfig(strings.NewReader(`
+functions:
+  - name: reader
+    handler: main.go
+    vpc:
+      subnet_ids: [subnet-0a1b2c, subnet-3d4e5f]
+      security_group_ids: [sg-0123abcd]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := cfg.Functions[0].Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := glambda.NewLambda("reader", "", append([]glambda.DeployOptions{glambdatest.Sandbox()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &glambda.VPCConfig{SubnetIDs: subnets, SecurityGroupIDs: securityGroups}
+	if !cmp.Equal(want, l.VPC) {
+		t.Error(cmp.Diff(want, l.VPC))
+	}
+}
@@ -0,0 +1,155 @@
+package glambda
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Govulncheck is the govulncheck executable used by [VulnCheck]. It can be
+// installed with `go install golang.org/x/vuln/cmd/govulncheck@latest`.
+var Govulncheck = "govulncheck"
+
+// VulnFinding is a known vulnerability whose affected code is reachable from
+// a handler's call graph.
+type VulnFinding struct {
+	ID           string
+	Summary      string
+	Module       string
+	FoundVersion string
+	FixedVersion string
+	// Function is the vulnerable function that is called, qualified by its
+	// package, and receiver for methods.
+	Function string
+}
+
+func (f VulnFinding) String() string {
+	fixed := "no fixed version"
+	if f.FixedVersion != "" {
+		fixed = "fixed in " + f.FixedVersion
+	}
+	return fmt.Sprintf("%s: %s (%s@%s, %s) reached via %s", f.ID, f.Summary, f.Module, f.FoundVersion, fixed, f.Function)
+}
+
+// VulnerabilityError is returned when a handler reaches known vulnerable code
+// and the deploy was configured to refuse it, see [WithVulnCheck].
+type VulnerabilityError struct {
+	Findings []VulnFinding
+}
+
+func (e *VulnerabilityError) Error() string {
+	lines := []string{fmt.Sprintf("handler calls code with %d known vulnerabilities:", len(e.Findings))}
+	for _, f := range e.Findings {
+		lines = append(lines, "  "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// VulnCheck runs govulncheck against the handler at path, as built for the
+// lambda's linux/arm64 platform. Only vulnerabilities whose affected functions
+// are reachable from the handler's call graph are reported; vulnerable modules
+// that are imported but never called are not.
+func VulnCheck(path string) ([]VulnFinding, error) {
+	bin, err := exec.LookPath(Govulncheck)
+	if err != nil {
+		return nil, fmt.Errorf("govulncheck is required for vulnerability checks, install it with `go install golang.org/x/vuln/cmd/govulncheck@latest`: %w", err)
+	}
+	src, err := ResolveHandler(path)
+	if err != nil {
+		return nil, err
+	}
+	target := "./" + filepath.Base(path)
+	if src.IsPackage {
+		target = "."
+	}
+	cmd := exec.Command(bin, "-json", target)
+	cmd.Dir = src.Dir
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH=arm64")
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running govulncheck: %w, %s", err, stderr)
+	}
+	return ParseVulnCheck(bytes.NewReader(out))
+}
+
+type govulncheckMessage struct {
+	OSV *struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"osv"`
+	Finding *struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module   string `json:"module"`
+			Version  string `json:"version"`
+			Package  string `json:"package"`
+			Function string `json:"function"`
+			Receiver string `json:"receiver"`
+		} `json:"trace"`
+	} `json:"finding"`
+}
+
+// ParseVulnCheck reads the JSON stream written by `govulncheck -json` and
+// returns the findings that reach vulnerable functions. Each vulnerability is
+// reported once, at the first vulnerable function found to be called.
+func ParseVulnCheck(r io.Reader) ([]VulnFinding, error) {
+	summaries := map[string]string{}
+	seen := map[string]bool{}
+	var findings []VulnFinding
+	dec := json.NewDecoder(r)
+	for {
+		var msg govulncheckMessage
+		err := dec.Decode(&msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding govulncheck output: %w", err)
+		}
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		f := msg.Finding
+		// Findings at module or package level have no function in the top
+		// frame of their trace, and don't mean the vulnerable code is called.
+		if f == nil || len(f.Trace) == 0 || f.Trace[0].Function == "" || seen[f.OSV] {
+			continue
+		}
+		seen[f.OSV] = true
+		frame := f.Trace[0]
+		function := frame.Function
+		if frame.Receiver != "" {
+			function = strings.TrimPrefix(frame.Receiver, "*") + "." + function
+		}
+		findings = append(findings, VulnFinding{
+			ID:           f.OSV,
+			Module:       frame.Module,
+			FoundVersion: frame.Version,
+			FixedVersion: f.FixedVersion,
+			Function:     frame.Package + "." + function,
+		})
+	}
+	for i := range findings {
+		findings[i].Summary = summaries[findings[i].ID]
+	}
+	return findings, nil
+}
+
+// WithVulnCheck is a deploy option that runs [VulnCheck] against the handler
+// before it is packaged. The deploy fails with a [VulnerabilityError] if the
+// handler reaches any known vulnerable code.
+func WithVulnCheck() DeployOptions {
+	return func(l *Lambda) error {
+		l.vulnCheck = true
+		return nil
+	}
+}
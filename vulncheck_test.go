@@ -0,0 +1,71 @@
+package glambda_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mr-joshcrane/glambda"
+)
+
+func TestParseVulnCheck_ReportsOnlyReachableVulnerabilities(t *testing.T) {
+	t.Parallel()
+	f, err := os.Open("testdata/govulncheck.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := glambda.ParseVulnCheck(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []glambda.VulnFinding{
+		{
+			ID:           "GO-2024-2687",
+			Summary:      "HTTP/2 CONTINUATION flood in net/http",
+			Module:       "golang.org/x/net",
+			FoundVersion: "v0.22.0",
+			FixedVersion: "v0.23.0",
+			Function:     "golang.org/x/net/http2.Framer.ReadFrameHeader",
+		},
+	}
+	if !cmp.Equal(want, got) {
+		t.Error(cmp.Diff(want, got))
+	}
+}
+
+func TestParseVulnCheck_ErrorsOnMalformedOutput(t *testing.T) {
+	t.Parallel()
+	_, err := glambda.ParseVulnCheck(strings.NewReader(`{"finding": `))
+	if err == nil {
+		t.Error("expected error for malformed output, got nil")
+	}
+}
+
+func TestVulnerabilityError_ListsFindings(t *testing.T) {
+	t.Parallel()
+	err := error(&glambda.VulnerabilityError{Findings: []glambda.VulnFinding{
+		{ID: "GO-2024-2687", Summary: "HTTP/2 CONTINUATION flood", Module: "golang.org/x/net", FoundVersion: "v0.22.0", FixedVersion: "v0.23.0", Function: "golang.org/x/net/http2.Framer.ReadFrameHeader"},
+	}})
+	want := "handler calls code with 1 known vulnerabilities:\n" +
+		"  GO-2024-2687: HTTP/2 CONTINUATION flood (golang.org/x/net@v0.22.0, fixed in v0.23.0) reached via golang.org/x/net/http2.Framer.ReadFrameHeader"
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	var vulnErr *glambda.VulnerabilityError
+	if !errors.As(err, &vulnErr) {
+		t.Error("expected error to be a VulnerabilityError")
+	}
+}
+
+func TestVulnCheck_ErrorsHelpfullyWhenGovulncheckIsMissing(t *testing.T) {
+	original := glambda.Govulncheck
+	glambda.Govulncheck = "glambda-test-no-such-govulncheck"
+	t.Cleanup(func() { glambda.Govulncheck = original })
+	_, err := glambda.VulnCheck("testdata/correct_test_handler/main.go")
+	if err == nil || !strings.Contains(err.Error(), "go install golang.org/x/vuln/cmd/govulncheck@latest") {
+		t.Errorf("expected install instructions in error, got %v", err)
+	}
+}